@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// addPayloadFields sets the fields of --migration.add-payload on every point, overwriting fields the point already has.
+func addPayloadFields(points []*qdrant.PointStruct, migration commons.MigrationConfig) error {
+	if len(migration.AddPayload) == 0 {
+		return nil
+	}
+	for _, point := range points {
+		if point.Payload == nil {
+			point.Payload = make(map[string]*qdrant.Value, len(migration.AddPayload))
+		}
+		id := pointIDToString(point.GetId())
+		for _, field := range migration.AddPayload {
+			value, err := field.Render(id)
+			if err != nil {
+				return err
+			}
+			point.Payload[field.Field] = qdrant.NewValueString(value)
+		}
+	}
+	return nil
+}
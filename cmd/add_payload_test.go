@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestAddPayloadFields(t *testing.T) {
+	_, cli, err := parseCommand([]string{"qdrant", "--source.collection", "a", "--target.collection", "b",
+		"--migration.add-payload", "source=qdrant, eu", "--migration.add-payload", "origin=a/{{.id}}"})
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	migration := cli.Qdrant.Migration
+	if len(migration.AddPayload) != 2 {
+		t.Fatalf("got %d payload fields, want 2: %v", len(migration.AddPayload), migration.AddPayload)
+	}
+
+	points := []*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(7), Payload: qdrant.NewValueMap(map[string]any{"source": "old", "title": "a"})},
+		{Id: qdrant.NewID("5c56c793-69f3-4fbf-87e6-c4bf54c28c26")},
+	}
+	if err := addPayloadFields(points, migration); err != nil {
+		t.Fatalf("addPayloadFields() error = %v", err)
+	}
+
+	first := points[0].GetPayload()
+	if first["source"].GetStringValue() != "qdrant, eu" || first["origin"].GetStringValue() != "a/7" || first["title"].GetStringValue() != "a" {
+		t.Errorf("payload = %v, want source overwritten, origin added and title kept", first)
+	}
+	if got := points[1].GetPayload()["origin"].GetStringValue(); got != "a/5c56c793-69f3-4fbf-87e6-c4bf54c28c26" {
+		t.Errorf("origin = %q, want the UUID of the point", got)
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// Number of batches written with every candidate setting during the calibration phase.
+const autoTuneSamples = 3
+
+type tuneSetting struct {
+	batchSize   int
+	concurrency int
+}
+
+type tuneStats struct {
+	points   int
+	elapsed  time.Duration
+	batches  int
+	failures int
+}
+
+func (s tuneStats) throughput() float64 {
+	if s.elapsed <= 0 {
+		return 0
+	}
+	return float64(s.points) / s.elapsed.Seconds()
+}
+
+// autoTuner tries a few batch sizes and writer concurrencies on the first batches of a migration,
+// and then locks in the one with the highest write throughput for the rest of it.
+// Settings that fail during calibration are ruled out, and their batches are written again with the base setting.
+type autoTuner struct {
+	base       tuneSetting
+	candidates []tuneSetting
+	stats      []tuneStats
+	current    int
+	locked     bool
+}
+
+func newAutoTuner(batchSize int) *autoTuner {
+	base := tuneSetting{batchSize: batchSize, concurrency: 1}
+	tuner := &autoTuner{base: base}
+
+	for _, factor := range []float64{0.5, 1, 2, 4} {
+		size := max(int(float64(batchSize)*factor), 1)
+		for _, concurrency := range []int{1, 2, 4} {
+			// Splitting a batch into more requests than it has points isn't meaningful.
+			if concurrency > size {
+				continue
+			}
+			tuner.candidates = append(tuner.candidates, tuneSetting{batchSize: size, concurrency: concurrency})
+		}
+	}
+	tuner.stats = make([]tuneStats, len(tuner.candidates))
+
+	return tuner
+}
+
+// setting returns the setting to read and write the next batch with.
+func (t *autoTuner) setting() tuneSetting {
+	return t.candidates[t.current]
+}
+
+// write wraps a function that upserts points, so that it's called concurrently on parts of the batch,
+// as many as the current setting asks for, and the outcome is recorded during calibration.
+func (t *autoTuner) write(upsert func(context.Context, []*qdrant.PointStruct) error) func(context.Context, []*qdrant.PointStruct) error {
+	return func(ctx context.Context, points []*qdrant.PointStruct) error {
+		setting := t.setting()
+
+		start := time.Now()
+		err := upsertConcurrently(ctx, points, setting.concurrency, upsert)
+		if t.locked {
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		stats := &t.stats[t.current]
+		stats.batches++
+		if err != nil {
+			stats.failures++
+			pterm.Warning.Printfln("Auto-tune: batch size %d with %d writer(s) failed, writing the batch again with batch size %d: %v", setting.batchSize, setting.concurrency, t.base.batchSize, err)
+			t.advance()
+			for start := 0; start < len(points); start += t.base.batchSize {
+				err = upsert(ctx, points[start:min(start+t.base.batchSize, len(points))])
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		stats.points += len(points)
+		stats.elapsed += time.Since(start)
+
+		if stats.batches >= autoTuneSamples {
+			t.advance()
+		}
+		return nil
+	}
+}
+
+func (t *autoTuner) advance() {
+	t.current++
+	if t.current < len(t.candidates) {
+		return
+	}
+
+	t.locked = true
+	best := -1
+	for i, stats := range t.stats {
+		if stats.failures > 0 || stats.points == 0 {
+			continue
+		}
+		if best < 0 || stats.throughput() > t.stats[best].throughput() {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		// Nothing worked reliably, so the settings given by the user are the safest bet.
+		t.candidates = append(t.candidates, t.base)
+		t.current = len(t.candidates) - 1
+		pterm.Warning.Printfln("Auto-tune: no setting wrote all its batches, using batch size %d", t.base.batchSize)
+		return
+	}
+
+	t.current = best
+	pterm.Info.Printfln("Auto-tune: using batch size %d with %d concurrent writer(s), %.0f points/s during calibration",
+		t.candidates[best].batchSize, t.candidates[best].concurrency, t.stats[best].throughput())
+}
+
+// upsertConcurrently splits the points into up to concurrency parts and upserts them in parallel.
+func upsertConcurrently(ctx context.Context, points []*qdrant.PointStruct, concurrency int, upsert func(context.Context, []*qdrant.PointStruct) error) error {
+	if concurrency <= 1 || len(points) <= 1 {
+		return upsert(ctx, points)
+	}
+
+	chunkSize := (len(points) + concurrency - 1) / concurrency
+	group, groupCtx := errgroup.WithContext(ctx)
+	for start := 0; start < len(points); start += chunkSize {
+		chunk := points[start:min(start+chunkSize, len(points))]
+		group.Go(func() error {
+			return upsert(groupCtx, chunk)
+		})
+	}
+	return group.Wait()
+}
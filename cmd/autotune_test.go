@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_autoTuner(t *testing.T) {
+	tuner := newAutoTuner(10)
+
+	// A target that rejects requests with more than 10 points, like a message size limit would.
+	var lock sync.Mutex
+	written := 0
+	write := tuner.write(func(_ context.Context, points []*qdrant.PointStruct) error {
+		if len(points) > 10 {
+			return fmt.Errorf("message too large")
+		}
+		lock.Lock()
+		written += len(points)
+		lock.Unlock()
+		return nil
+	})
+
+	total := 0
+	for i := 0; i < len(tuner.candidates)*autoTuneSamples && !tuner.locked; i++ {
+		points := make([]*qdrant.PointStruct, tuner.setting().batchSize)
+		total += len(points)
+		if err := write(context.Background(), points); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+	}
+
+	if !tuner.locked {
+		t.Fatalf("calibration did not finish")
+	}
+	if written != total {
+		t.Errorf("wrote %d points, want %d including the retried ones", written, total)
+	}
+
+	setting := tuner.setting()
+	if (setting.batchSize+setting.concurrency-1)/setting.concurrency > 10 {
+		t.Errorf("locked in %+v, which the target rejects", setting)
+	}
+}
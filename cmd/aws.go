@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// awsRoleSessionName is the name of the sessions of assumed roles, as seen in CloudTrail.
+const awsRoleSessionName = "qdrant-migration"
+
+// loadAWSConfig loads the AWS configuration of a source or destination, with the credentials of its profile if it has one,
+// or else the ones the AWS CLI would use, and assumes its role with them if it has one. Credentials are refreshed
+// before they expire, so short-lived ones like those of IRSA or an assumed role last for any length of run.
+func loadAWSConfig(ctx context.Context, awsConfig commons.AWSConfig) (aws.Config, error) {
+	var options []func(*config.LoadOptions) error
+	if awsConfig.Region != "" {
+		options = append(options, config.WithRegion(awsConfig.Region))
+	}
+	if awsConfig.Profile != "" {
+		options = append(options, config.WithSharedConfigProfile(awsConfig.Profile))
+	}
+	loaded, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	if awsConfig.RoleArn != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(loaded), awsConfig.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = awsRoleSessionName
+		})
+		loaded.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return loaded, nil
+}
+
+// sigv4Signer signs HTTP requests to an AWS service with SigV4, e.g. for Amazon OpenSearch Service.
+type sigv4Signer struct {
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	service     string
+	region      string
+}
+
+func newSigv4Signer(awsConfig aws.Config, service string) (*sigv4Signer, error) {
+	if awsConfig.Region == "" {
+		return nil, fmt.Errorf("signing requests for %s needs an AWS region, set one with the aws-region option or AWS_REGION", service)
+	}
+	if awsConfig.Credentials == nil {
+		return nil, fmt.Errorf("signing requests for %s needs AWS credentials, but none were found", service)
+	}
+	return &sigv4Signer{credentials: awsConfig.Credentials, signer: v4.NewSigner(), service: service, region: awsConfig.Region}, nil
+}
+
+// SignRequest signs a request, with the hash of its body, which OpenSearch Serverless requires in a header.
+func (s *sigv4Signer) SignRequest(req *http.Request) error {
+	body := []byte{}
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read request body to sign: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	credentials, err := s.credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+	err = s.signer.SignHTTP(req.Context(), credentials, req, payloadHash, s.service, s.region, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestSigv4Signer(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		service string
+		body    string
+		wantErr bool
+	}{
+		{name: "OpenSearch Service", region: "eu-west-1", service: "es"},
+		{name: "OpenSearch Serverless with body", region: "us-east-1", service: "aoss", body: `{"query":{"match_all":{}}}`},
+		{name: "no region", service: "es", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			awsConfig := aws.Config{Region: tt.region, Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", "")}
+			signer, err := newSigv4Signer(awsConfig, tt.service)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("newSigv4Signer() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newSigv4Signer() error = %v", err)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "https://search.example.com/docs/_search", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := signer.SignRequest(req); err != nil {
+				t.Fatalf("SignRequest() error = %v", err)
+			}
+
+			scope := fmt.Sprintf("/%s/%s/aws4_request", tt.region, tt.service)
+			if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, scope) {
+				t.Errorf("Authorization = %q, want a SigV4 signature for %s", auth, scope)
+			}
+			sum := sha256.Sum256([]byte(tt.body))
+			if got := req.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(sum[:]) {
+				t.Errorf("X-Amz-Content-Sha256 = %q, want the hash of the body", got)
+			}
+			body, _ := io.ReadAll(req.Body)
+			if string(body) != tt.body {
+				t.Errorf("body after signing = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestConnectToOpenSearchSigv4(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"count":3}`)
+	}))
+	defer server.Close()
+
+	cmd := &MigrateFromOpenSearchCmd{OpenSearch: commons.OpenSearchConfig{
+		Url:        server.URL,
+		Index:      "docs",
+		AwsSigv4:   true,
+		AwsService: "es",
+		AWS:        commons.AWSConfig{Region: "eu-central-1"},
+	}}
+	client, err := cmd.connectToOpenSearch(context.Background())
+	if err != nil {
+		t.Fatalf("connectToOpenSearch() error = %v", err)
+	}
+	count, err := cmd.countOpenSearchDocuments(context.Background(), client)
+	if err != nil {
+		t.Fatalf("countOpenSearchDocuments() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("countOpenSearchDocuments() = %d, want 3", count)
+	}
+	if !strings.Contains(authorization, "Credential=AKID/") || !strings.Contains(authorization, "/eu-central-1/es/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 signature with the credentials of the environment", authorization)
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/time/rate"
+)
+
+// The largest chunk of bytes a single read or write waits for at once.
+// It's the burst of the token bucket, so it also bounds how far transfers can exceed the limit momentarily.
+const bandwidthChunkSize = 64 * 1024
+
+func newBandwidthLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := bandwidthChunkSize
+	if bytesPerSecond < int64(burst) {
+		burst = int(bytesPerSecond)
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), burst)
+}
+
+// limitDialer wraps the connections of a dialer so all of them share the limiter.
+func limitDialer(dial contextDialer, limiter *rate.Limiter) contextDialer {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &limitedConn{Conn: conn, limiter: limiter}, nil
+	}
+}
+
+// limitedConn throttles the raw bytes read from and written to a connection.
+type limitedConn struct {
+	net.Conn
+	limiter *rate.Limiter
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	if len(p) > c.limiter.Burst() {
+		p = p[:c.limiter.Burst()]
+	}
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if waitErr := c.limiter.WaitN(context.Background(), n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (c *limitedConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := min(len(p)-written, c.limiter.Burst())
+		if err := c.limiter.WaitN(context.Background(), chunk); err != nil {
+			return written, err
+		}
+		n, err := c.Conn.Write(p[written : written+chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
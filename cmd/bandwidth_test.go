@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func Test_limitedConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// Larger than the burst, so writes and reads are split into several chunks.
+	sent := bytes.Repeat([]byte("qdrant"), bandwidthChunkSize)
+	conn := &limitedConn{Conn: client, limiter: newBandwidthLimiter(1 << 30)}
+
+	go func() {
+		_, _ = conn.Write(sent)
+		_ = conn.Close()
+	}()
+
+	got, err := io.ReadAll(&limitedConn{Conn: server, limiter: newBandwidthLimiter(1 << 30)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, sent) {
+		t.Errorf("received %d bytes, expected %d", len(got), len(sent))
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type BenchCmd struct {
+	Target commons.QdrantConfig `embed:"" prefix:"target."`
+	Bench  commons.BenchConfig  `embed:"" prefix:"bench."`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+}
+
+// benchResult is the outcome of writing for a while with a single combination of batch size and concurrency.
+type benchResult struct {
+	batchSize   int
+	concurrency int
+	points      uint64
+	errors      uint64
+	elapsed     time.Duration
+	p50         time.Duration
+	p99         time.Duration
+}
+
+func (b benchResult) throughput() float64 {
+	return float64(b.points) / b.elapsed.Seconds()
+}
+
+func (r *BenchCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Target.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BenchCmd) Validate() error {
+	if r.Bench.Dimension < 1 {
+		return fmt.Errorf("dimension must be greater than 0")
+	}
+	for _, batchSize := range r.Bench.BatchSizes {
+		if err := validateBatchSize(batchSize); err != nil {
+			return err
+		}
+	}
+	for _, concurrency := range r.Bench.Concurrencies {
+		if concurrency < 1 {
+			return fmt.Errorf("concurrency must be greater than 0")
+		}
+	}
+	if r.Bench.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	return nil
+}
+
+func (r *BenchCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Write Benchmark")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Target, r.targetTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+	defer targetClient.Close()
+
+	// The collection is deleted afterwards, so an existing one is never touched.
+	exists, err := targetClient.CollectionExists(ctx, r.Target.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("collection '%s' already exists, pick a name that is not in use for the benchmark", r.Target.Collection)
+	}
+
+	err = targetClient.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName: r.Target.Collection,
+		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+			Size:     uint64(r.Bench.Dimension),
+			Distance: qdrant.Distance_Cosine,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create benchmark collection: %w", err)
+	}
+	if !r.Bench.Keep {
+		defer func() {
+			// The run context may be cancelled already, the collection should be deleted anyway.
+			err := targetClient.DeleteCollection(context.Background(), r.Target.Collection)
+			if err != nil {
+				pterm.Warning.Printfln("Failed to delete benchmark collection '%s': %v", r.Target.Collection, err)
+			}
+		}()
+	}
+
+	pterm.Info.Printfln("Writing points with %d dimensions and %s of payload to '%s'", r.Bench.Dimension, r.Bench.PayloadSize, r.Target.Collection)
+
+	var nextId atomic.Uint64
+	results := make([]benchResult, 0, len(r.Bench.BatchSizes)*len(r.Bench.Concurrencies))
+	for _, batchSize := range r.Bench.BatchSizes {
+		for _, concurrency := range r.Bench.Concurrencies {
+			spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Batch size %d, %d writer(s)", batchSize, concurrency))
+			result, err := r.measure(ctx, targetClient, batchSize, concurrency, &nextId)
+			if err != nil {
+				spinner.Fail(err.Error())
+				return err
+			}
+			spinner.Success(fmt.Sprintf("Batch size %d, %d writer(s): %.0f points/s", batchSize, concurrency, result.throughput()))
+			results = append(results, result)
+		}
+	}
+
+	displayBenchResults(results)
+
+	best, ok := pickBestBenchResult(results)
+	if !ok {
+		return fmt.Errorf("no combination of batch size and concurrency wrote points without errors")
+	}
+	pterm.Success.Printfln("Best throughput with batch size %d and %d concurrent writer(s): %.0f points/s", best.batchSize, best.concurrency, best.throughput())
+	pterm.Info.Printfln("Use --migration.batch-size %d for the migration", best.batchSize)
+
+	return nil
+}
+
+// measure upserts synthetic points with the given batch size and concurrency for the configured duration.
+func (r *BenchCmd) measure(ctx context.Context, targetClient *qdrant.Client, batchSize, concurrency int, nextId *atomic.Uint64) (benchResult, error) {
+	result := benchResult{batchSize: batchSize, concurrency: concurrency}
+	payload := strings.Repeat("x", int(r.Bench.PayloadSize))
+
+	var (
+		lock      sync.Mutex
+		latencies []time.Duration
+		points    atomic.Uint64
+		failures  atomic.Uint64
+	)
+
+	runCtx, cancel := context.WithTimeout(ctx, r.Bench.Duration)
+	defer cancel()
+
+	start := time.Now()
+	group, groupCtx := errgroup.WithContext(runCtx)
+	for i := 0; i < concurrency; i++ {
+		group.Go(func() error {
+			// Consecutive failed calls of the worker, which back it off so a struggling target isn't flooded with retries.
+			failed := 0
+			for groupCtx.Err() == nil {
+				batch := make([]*qdrant.PointStruct, batchSize)
+				for j := range batch {
+					batch[j] = &qdrant.PointStruct{
+						Id:      qdrant.NewIDNum(nextId.Add(1)),
+						Vectors: qdrant.NewVectorsDense(randomVector(r.Bench.Dimension)),
+						Payload: qdrant.NewValueMap(map[string]any{"data": payload}),
+					}
+				}
+
+				callStart := time.Now()
+				_, err := targetClient.Upsert(groupCtx, &qdrant.UpsertPoints{
+					CollectionName: r.Target.Collection,
+					Points:         batch,
+					Wait:           qdrant.PtrOf(true),
+				})
+				if groupCtx.Err() != nil {
+					// Calls cut off by the end of the measurement count neither as writes nor errors.
+					return nil
+				}
+				if err != nil {
+					failures.Add(1)
+					select {
+					case <-time.After(retryDelay(err, failed)):
+					case <-groupCtx.Done():
+					}
+					failed++
+					continue
+				}
+				failed = 0
+
+				points.Add(uint64(batchSize))
+				lock.Lock()
+				latencies = append(latencies, time.Since(callStart))
+				lock.Unlock()
+			}
+			return nil
+		})
+	}
+
+	_ = group.Wait()
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+
+	result.elapsed = time.Since(start)
+	result.points = points.Load()
+	result.errors = failures.Load()
+	result.p50 = percentile(latencies, 0.5)
+	result.p99 = percentile(latencies, 0.99)
+
+	return result, nil
+}
+
+func randomVector(dimension int) []float32 {
+	vector := make([]float32, dimension)
+	for i := range vector {
+		vector[i] = rand.Float32()
+	}
+	return vector
+}
+
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+// pickBestBenchResult returns the result with the highest throughput among the ones without errors.
+func pickBestBenchResult(results []benchResult) (benchResult, bool) {
+	var best benchResult
+	found := false
+	for _, result := range results {
+		if result.errors > 0 || result.points == 0 {
+			continue
+		}
+		if !found || result.throughput() > best.throughput() {
+			best = result
+			found = true
+		}
+	}
+	return best, found
+}
+
+func displayBenchResults(results []benchResult) {
+	table := pterm.TableData{{"Batch size", "Writers", "Points/s", "p50 latency", "p99 latency", "Errors"}}
+	for _, result := range results {
+		table = append(table, []string{
+			strconv.Itoa(result.batchSize),
+			strconv.Itoa(result.concurrency),
+			fmt.Sprintf("%.0f", result.throughput()),
+			result.p50.Round(time.Millisecond).String(),
+			result.p99.Round(time.Millisecond).String(),
+			strconv.FormatUint(result.errors, 10),
+		})
+	}
+
+	pterm.Println()
+	_ = pterm.DefaultTable.
+		WithHasHeader(true).
+		WithBoxed(true).
+		WithData(table).
+		Render()
+	pterm.Println()
+}
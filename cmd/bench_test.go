@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_pickBestBenchResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		results   []benchResult
+		wantBatch int
+		wantOk    bool
+	}{
+		{
+			name: "highest throughput",
+			results: []benchResult{
+				{batchSize: 64, points: 1000, elapsed: time.Second},
+				{batchSize: 256, points: 3000, elapsed: time.Second},
+				{batchSize: 1024, points: 2000, elapsed: time.Second},
+			},
+			wantBatch: 256,
+			wantOk:    true,
+		},
+		{
+			name: "skips results with errors",
+			results: []benchResult{
+				{batchSize: 64, points: 1000, elapsed: time.Second},
+				{batchSize: 1024, points: 5000, errors: 1, elapsed: time.Second},
+			},
+			wantBatch: 64,
+			wantOk:    true,
+		},
+		{
+			name:    "no usable result",
+			results: []benchResult{{batchSize: 64, errors: 3, elapsed: time.Second}},
+			wantOk:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pickBestBenchResult(tt.results)
+			if ok != tt.wantOk || got.batchSize != tt.wantBatch {
+				t.Errorf("pickBestBenchResult() = %d, %v, want %d, %v", got.batchSize, ok, tt.wantBatch, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// blobStores caches the destination of every --migration.blob-store location, shared by all streams of a run.
+var blobStores sync.Map
+
+// handleBinaryFields applies the binary directives of the mapping file.
+func handleBinaryFields(ctx context.Context, points []*qdrant.PointStruct, migration commons.MigrationConfig) error {
+	for name, field := range migration.MappingFile.Fields {
+		if field.Binary == "" {
+			continue
+		}
+		if field.Binary == "offload" && migration.BlobStore == "" {
+			return fmt.Errorf("offloading the binary field '%s' requires --migration.blob-store", name)
+		}
+
+		for _, point := range points {
+			value := payloadField(point.GetPayload(), name)
+			data, ok := binaryValue(value)
+			if !ok {
+				continue
+			}
+
+			switch field.Binary {
+			case "skip":
+				deletePayloadField(point.GetPayload(), name)
+			case "base64":
+				setPayloadField(point.GetPayload(), name, qdrant.NewValueString(base64.StdEncoding.EncodeToString(data)))
+			case "offload":
+				blobUrl, err := offloadBlob(ctx, migration.BlobStore, data)
+				if err != nil {
+					return err
+				}
+				setPayloadField(point.GetPayload(), name, qdrant.NewValueString(blobUrl))
+			}
+		}
+	}
+	return nil
+}
+
+// binaryValue returns the bytes of a binary value. Byte slices arrive as base64 strings,
+// and Mongo binary data as extended JSON: {"$binary": {"base64": "...", "subType": "00"}}.
+// Strings that aren't base64 are taken as they are.
+func binaryValue(value *qdrant.Value) ([]byte, bool) {
+	if binary, ok := value.GetStructValue().GetFields()["$binary"]; ok {
+		encoded := binary.GetStructValue().GetFields()["base64"].GetStringValue()
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		return data, err == nil
+	}
+
+	s, ok := value.GetKind().(*qdrant.Value_StringValue)
+	if !ok {
+		return nil, false
+	}
+	if data, err := base64.StdEncoding.DecodeString(s.StringValue); err == nil {
+		return data, true
+	}
+	return []byte(s.StringValue), true
+}
+
+// offloadBlob uploads a binary value to the blob store and returns its URL.
+// Blobs are named by the hash of their content, so retried batches and duplicates don't create new ones.
+func offloadBlob(ctx context.Context, location string, data []byte) (string, error) {
+	store, ok := blobStores.Load(location)
+	if !ok {
+		destination, err := newExportDestination(ctx, location, commons.AWSConfig{})
+		if err != nil {
+			return "", fmt.Errorf("failed to open blob store: %w", err)
+		}
+		store, _ = blobStores.LoadOrStore(location, destination)
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+
+	writer, err := store.(exportDestination).Create(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create blob %s: %w", name, err)
+	}
+	_, err = writer.Write(data)
+	if err != nil {
+		_ = writer.Close()
+		return "", fmt.Errorf("failed to write blob %s: %w", name, err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to write blob %s: %w", name, err)
+	}
+
+	return strings.TrimSuffix(location, "/") + "/" + name, nil
+}
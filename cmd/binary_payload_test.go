@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_handleBinaryFields(t *testing.T) {
+	blob := []byte{0x89, 'P', 'N', 'G', 0x00}
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	points := []*qdrant.PointStruct{
+		{Payload: qdrant.NewValueMap(map[string]any{
+			"thumbnail": encoded,
+			"raw":       map[string]any{"$binary": map[string]any{"base64": encoded, "subType": "00"}},
+			"image":     encoded,
+			"title":     "ok",
+		})},
+	}
+	migration := commons.MigrationConfig{
+		BlobStore: t.TempDir(),
+		MappingFile: commons.PayloadMapping{Fields: map[string]commons.FieldMapping{
+			"thumbnail": {Binary: "skip"},
+			"raw":       {Binary: "base64"},
+			"image":     {Binary: "offload"},
+		}},
+	}
+
+	err := handleBinaryFields(context.Background(), points, migration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := points[0].Payload
+	if _, ok := payload["thumbnail"]; ok {
+		t.Errorf("thumbnail wasn't skipped")
+	}
+	if got := payload["raw"].GetStringValue(); got != encoded {
+		t.Errorf("raw = %q, want %q", got, encoded)
+	}
+	data, err := os.ReadFile(payload["image"].GetStringValue())
+	if err != nil {
+		t.Fatalf("failed to read offloaded image: %v", err)
+	}
+	if string(data) != string(blob) {
+		t.Errorf("offloaded image = %v, want %v", data, blob)
+	}
+	if got := payload["title"].GetStringValue(); got != "ok" {
+		t.Errorf("title = %q, want it unchanged", got)
+	}
+}
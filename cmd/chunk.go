@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// chunkPoints splits the text in --migration.embed.field of every point into chunks, and returns a point per chunk.
+// A chunk point is a copy of its source point with the text of the chunk, metadata about the chunk,
+// and an ID derived from the ID of the source point and the index of the chunk, so reruns overwrite the same points.
+// Points without the text are returned as they are.
+func chunkPoints(points []*qdrant.PointStruct, migration commons.MigrationConfig) []*qdrant.PointStruct {
+	config := migration.Chunk
+	field := migration.Embed.Field
+	if config.Size <= 0 || field == "" {
+		return points
+	}
+
+	result := make([]*qdrant.PointStruct, 0, len(points))
+	for _, point := range points {
+		text := payloadField(point.GetPayload(), field).GetStringValue()
+		if text == "" {
+			result = append(result, point)
+			continue
+		}
+
+		sourceID := pointIDToString(point.GetId())
+		chunks := chunkText(text, config)
+		for i, chunk := range chunks {
+			chunkPoint := proto.Clone(point).(*qdrant.PointStruct)
+			chunkPoint.Id = arbitraryIDToUUID(fmt.Sprintf("%s#%d", sourceID, i))
+			setPayloadField(chunkPoint.Payload, field, qdrant.NewValueString(chunk))
+			chunkPoint.Payload[config.MetadataField] = qdrant.NewValueStruct(&qdrant.Struct{Fields: map[string]*qdrant.Value{
+				"source_id": qdrant.NewValueString(sourceID),
+				"index":     qdrant.NewValueInt(int64(i)),
+				"count":     qdrant.NewValueInt(int64(len(chunks))),
+			}})
+			result = append(result, chunkPoint)
+		}
+	}
+	return result
+}
+
+// chunkText splits a text into chunks of at most config.Size characters, of which the last config.Overlap ones are repeated
+// at the start of the next chunk. With the sentence strategy, chunks end at the end of a sentence where possible,
+// and the overlap consists of whole sentences.
+func chunkText(text string, config commons.ChunkConfig) []string {
+	overlap := min(max(config.Overlap, 0), config.Size-1)
+	if config.Strategy != "sentence" {
+		return splitFixed([]rune(text), config.Size, overlap)
+	}
+
+	var chunks []string
+	var current []string
+	length := 0
+	// Whether the current sentences contain some that aren't in a chunk yet, rather than only the overlap.
+	fresh := false
+	emit := func() {
+		if !fresh {
+			return
+		}
+		chunks = append(chunks, strings.TrimSpace(strings.Join(current, "")))
+		fresh = false
+
+		// Keep the trailing sentences that fit in the overlap.
+		kept, keptLength := 0, 0
+		for i := len(current) - 1; i >= 0; i-- {
+			sentenceLength := len([]rune(current[i]))
+			if keptLength+sentenceLength > overlap {
+				break
+			}
+			keptLength += sentenceLength
+			kept++
+		}
+		current = append([]string(nil), current[len(current)-kept:]...)
+		length = keptLength
+	}
+
+	for _, sentence := range splitSentences(text) {
+		sentenceLength := len([]rune(sentence))
+		// The whitespace after the last sentence of a chunk is trimmed, so it doesn't count.
+		trimmedLength := len([]rune(strings.TrimRightFunc(sentence, unicode.IsSpace)))
+		if trimmedLength > config.Size {
+			// Sentences longer than a chunk are split like with the fixed strategy.
+			emit()
+			current, length = nil, 0
+			chunks = append(chunks, splitFixed([]rune(sentence), config.Size, overlap)...)
+			continue
+		}
+		if length+trimmedLength > config.Size {
+			emit()
+			// The overlap may leave no room for the sentence.
+			for length+trimmedLength > config.Size {
+				length -= len([]rune(current[0]))
+				current = current[1:]
+			}
+		}
+		current = append(current, sentence)
+		length += sentenceLength
+		fresh = true
+	}
+	emit()
+	return chunks
+}
+
+// splitFixed splits runes into windows of size, each starting overlap runes before the end of the previous one.
+func splitFixed(runes []rune, size, overlap int) []string {
+	var chunks []string
+	for start := 0; start < len(runes); start += size - overlap {
+		end := min(start+size, len(runes))
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+	}
+	return chunks
+}
+
+// splitSentences splits a text after every '.', '!' or '?' followed by whitespace, and after every blank line.
+// The sentences keep their trailing whitespace, so joining them gives the text back.
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		end := false
+		switch runes[i] {
+		case '.', '!', '?':
+			end = i+1 < len(runes) && unicode.IsSpace(runes[i+1])
+		case '\n':
+			end = i+1 < len(runes) && runes[i+1] == '\n'
+		}
+		if !end {
+			continue
+		}
+		for i+1 < len(runes) && unicode.IsSpace(runes[i+1]) {
+			i++
+		}
+		sentences = append(sentences, string(runes[start:i+1]))
+		start = i + 1
+	}
+	if start < len(runes) {
+		sentences = append(sentences, string(runes[start:]))
+	}
+	return sentences
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		config commons.ChunkConfig
+		want   []string
+	}{
+		{
+			name:   "fixed",
+			text:   "abcdefghij",
+			config: commons.ChunkConfig{Size: 4, Strategy: "fixed"},
+			want:   []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:   "fixed with overlap",
+			text:   "abcdefghij",
+			config: commons.ChunkConfig{Size: 4, Overlap: 2, Strategy: "fixed"},
+			want:   []string{"abcd", "cdef", "efgh", "ghij"},
+		},
+		{
+			name:   "short text",
+			text:   "Hello world.",
+			config: commons.ChunkConfig{Size: 100, Strategy: "sentence"},
+			want:   []string{"Hello world."},
+		},
+		{
+			name:   "sentences",
+			text:   "One two. Three four! Five six? Seven.",
+			config: commons.ChunkConfig{Size: 20, Strategy: "sentence"},
+			want:   []string{"One two. Three four!", "Five six? Seven."},
+		},
+		{
+			name:   "sentences with overlap",
+			text:   "One two. Three four! Five six? Seven.",
+			config: commons.ChunkConfig{Size: 25, Overlap: 12, Strategy: "sentence"},
+			want:   []string{"One two. Three four!", "Three four! Five six?", "Five six? Seven."},
+		},
+		{
+			name:   "long sentence",
+			text:   "Short. Averyveryverylongword",
+			config: commons.ChunkConfig{Size: 10, Strategy: "sentence"},
+			want:   []string{"Short.", "Averyveryv", "erylongwor", "d"},
+		},
+		{
+			name:   "paragraphs",
+			text:   "Title\n\nBody text",
+			config: commons.ChunkConfig{Size: 10, Strategy: "sentence"},
+			want:   []string{"Title", "Body text"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkText(tt.text, tt.config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkPoints(t *testing.T) {
+	migration := commons.MigrationConfig{
+		Embed: commons.EmbeddingConfig{Field: "content"},
+		Chunk: commons.ChunkConfig{Size: 12, Strategy: "sentence", MetadataField: "chunk"},
+	}
+	points := []*qdrant.PointStruct{
+		{
+			Id:      qdrant.NewIDNum(7),
+			Payload: qdrant.NewValueMap(map[string]any{"content": "First one. Second one.", "title": "doc"}),
+		},
+		{
+			Id:      qdrant.NewIDNum(8),
+			Payload: qdrant.NewValueMap(map[string]any{"title": "no content"}),
+		},
+	}
+
+	got := chunkPoints(points, migration)
+	if len(got) != 3 {
+		t.Fatalf("got %d points, want 3", len(got))
+	}
+
+	for i, want := range []string{"First one.", "Second one."} {
+		payload := got[i].GetPayload()
+		if content := payload["content"].GetStringValue(); content != want {
+			t.Errorf("chunk %d content = %q, want %q", i, content, want)
+		}
+		if title := payload["title"].GetStringValue(); title != "doc" {
+			t.Errorf("chunk %d title = %q, want doc", i, title)
+		}
+		metadata := payload["chunk"].GetStructValue().GetFields()
+		if metadata["source_id"].GetStringValue() != "7" || metadata["index"].GetIntegerValue() != int64(i) || metadata["count"].GetIntegerValue() != 2 {
+			t.Errorf("chunk %d metadata = %v", i, metadata)
+		}
+	}
+
+	if got[0].GetId().GetUuid() == got[1].GetId().GetUuid() {
+		t.Error("chunks should have different IDs")
+	}
+	if rerun := chunkPoints(points, migration); rerun[0].GetId().GetUuid() != got[0].GetId().GetUuid() {
+		t.Error("chunk IDs should be deterministic")
+	}
+	if points[0].GetPayload()["content"].GetStringValue() != "First one. Second one." {
+		t.Error("source point should not be changed")
+	}
+	if got[2] != points[1] {
+		t.Error("point without the text field should be kept as it is")
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// minSourceVersion is the oldest Qdrant version the migration reads from.
+// Older versions have a different API, e.g. vectors without names.
+var minSourceVersion = qdrantVersion{1, 0, 0}
+
+// sourceRequirement is a feature of the migration that only newer Qdrant sources support.
+type sourceRequirement struct {
+	feature    string
+	minVersion qdrantVersion
+}
+
+// checkSourceVersion checks that a Qdrant source is new enough for the features the migration uses on it.
+// Old versions silently ignore the fields of requests they don't know, like shard key selectors, and would return
+// the wrong points instead of failing, so such features are refused up front. Sources whose version can't be parsed,
+// like development builds, pass with a warning.
+func checkSourceVersion(ctx context.Context, client *qdrant.Client, requirements ...sourceRequirement) error {
+	health, err := client.HealthCheck(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get version of source: %w", err)
+	}
+	version, err := parseQdrantVersion(health.GetVersion())
+	if err != nil {
+		pterm.Warning.Printfln("Can't check the compatibility of the source: %v", err)
+		return nil
+	}
+	return sourceCompatibility(version, requirements)
+}
+
+func sourceCompatibility(version qdrantVersion, requirements []sourceRequirement) error {
+	if version.less(minSourceVersion) {
+		return fmt.Errorf("source runs Qdrant %s, but the migration needs Qdrant %s or newer, upgrade the source first", version, minSourceVersion)
+	}
+	var missing []string
+	for _, requirement := range requirements {
+		if version.less(requirement.minVersion) {
+			missing = append(missing, fmt.Sprintf("%s needs Qdrant %s or newer", requirement.feature, requirement.minVersion))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("source runs Qdrant %s: %s", version, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// sourceRequirements returns the features of a migration from Qdrant that need a newer source.
+func (r *MigrateFromQdrantCmd) sourceRequirements() []sourceRequirement {
+	var requirements []sourceRequirement
+	if r.ParallelShards {
+		requirements = append(requirements, sourceRequirement{"--source.parallel-shards", qdrantVersion{1, 7, 0}})
+	}
+	return requirements
+}
+
+// vectorOutputToVector converts a vector read from Qdrant into one to write. Older sources only return the values
+// of a vector, with the indices of sparse vectors and the number of vectors of multivectors next to them.
+// Newer ones return dense, sparse and multivectors of their own, which are converted into the same shape.
+func vectorOutputToVector(vector *qdrant.VectorOutput) *qdrant.Vector {
+	if vector == nil {
+		return nil
+	}
+	switch {
+	case vector.GetDense() != nil:
+		return &qdrant.Vector{Data: vector.GetDense().GetData()}
+	case vector.GetSparse() != nil:
+		return &qdrant.Vector{
+			Data:    vector.GetSparse().GetValues(),
+			Indices: &qdrant.SparseIndices{Data: vector.GetSparse().GetIndices()},
+		}
+	case vector.GetMultiDense() != nil:
+		var data []float32
+		for _, dense := range vector.GetMultiDense().GetVectors() {
+			data = append(data, dense.GetData()...)
+		}
+		return &qdrant.Vector{Data: data, VectorsCount: qdrant.PtrOf(uint32(len(vector.GetMultiDense().GetVectors())))}
+	default:
+		return &qdrant.Vector{
+			Data:         vector.GetData(),
+			Indices:      vector.GetIndices(),
+			VectorsCount: vector.VectorsCount,
+		}
+	}
+}
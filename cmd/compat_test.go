@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_sourceCompatibility(t *testing.T) {
+	shardKeys := []sourceRequirement{{"--source.parallel-shards", qdrantVersion{1, 7, 0}}}
+
+	tests := []struct {
+		name         string
+		version      qdrantVersion
+		requirements []sourceRequirement
+		wantErr      bool
+	}{
+		{"supported", qdrantVersion{1, 2, 0}, nil, false},
+		{"too old", qdrantVersion{0, 11, 7}, nil, true},
+		{"feature supported", qdrantVersion{1, 7, 0}, shardKeys, false},
+		{"feature unsupported", qdrantVersion{1, 6, 1}, shardKeys, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sourceCompatibility(tt.version, tt.requirements)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("sourceCompatibility() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_vectorOutputToVector(t *testing.T) {
+	tests := []struct {
+		name         string
+		vector       *qdrant.VectorOutput
+		data         []float32
+		indices      []uint32
+		vectorsCount uint32
+	}{
+		{
+			name:   "legacy dense",
+			vector: &qdrant.VectorOutput{Data: []float32{1, 2}},
+			data:   []float32{1, 2},
+		},
+		{
+			name:    "legacy sparse",
+			vector:  &qdrant.VectorOutput{Data: []float32{0.5}, Indices: &qdrant.SparseIndices{Data: []uint32{7}}},
+			data:    []float32{0.5},
+			indices: []uint32{7},
+		},
+		{
+			name:   "dense",
+			vector: &qdrant.VectorOutput{Vector: &qdrant.VectorOutput_Dense{Dense: &qdrant.DenseVector{Data: []float32{1, 2}}}},
+			data:   []float32{1, 2},
+		},
+		{
+			name:    "sparse",
+			vector:  &qdrant.VectorOutput{Vector: &qdrant.VectorOutput_Sparse{Sparse: &qdrant.SparseVector{Indices: []uint32{7}, Values: []float32{0.5}}}},
+			data:    []float32{0.5},
+			indices: []uint32{7},
+		},
+		{
+			name: "multivector",
+			vector: &qdrant.VectorOutput{Vector: &qdrant.VectorOutput_MultiDense{MultiDense: &qdrant.MultiDenseVector{
+				Vectors: []*qdrant.DenseVector{{Data: []float32{1, 2}}, {Data: []float32{3, 4}}},
+			}}},
+			data:         []float32{1, 2, 3, 4},
+			vectorsCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := vectorOutputToVector(tt.vector)
+			if !slices.Equal(got.GetData(), tt.data) || !slices.Equal(got.GetIndices().GetData(), tt.indices) || got.GetVectorsCount() != tt.vectorsCount {
+				t.Errorf("vectorOutputToVector() = %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Time to list the collections of an endpoint in, so that a slow or unreachable one doesn't block the shell.
+const completionTimeout = 3 * time.Second
+
+type CompletionCmd struct {
+	Shell string `arg:"" help:"Shell to print the completion script for." enum:"bash,zsh,fish"`
+}
+
+func (r *CompletionCmd) Run(_ *Globals) error {
+	scripts := map[string]string{
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+	}
+	fmt.Print(scripts[r.Shell])
+	return nil
+}
+
+// The scripts pass the words typed so far, including the one being completed, to the hidden __complete command.
+const bashCompletion = `_migration_completions() {
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(migration __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _migration_completions migration
+`
+
+const zshCompletion = `#compdef migration
+_migration() {
+    local -a candidates
+    candidates=("${(@f)$(migration __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if (( ${#candidates[@]} )) && [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _migration migration
+`
+
+const fishCompletion = `function __migration_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    migration __complete -- $tokens[2..-1] 2>/dev/null
+end
+complete -c migration -f -a '(__migration_complete)'
+`
+
+type CompleteCmd struct {
+	Words []string `arg:"" optional:"" passthrough:"" help:"Words typed so far, the last one being completed."`
+}
+
+func (r *CompleteCmd) Run(_ *Globals) error {
+	// Warnings, e.g. of parsing the URL, would end up as candidates.
+	pterm.DisableOutput()
+
+	parser, err := kong.New(&CLI{}, kong.Name("migration"))
+	if err != nil {
+		return err
+	}
+	for _, candidate := range completeWords(parser.Model.Node, r.Words, listCollectionsForCompletion) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// completeWords returns the candidates for the last of the words typed after the program name:
+// the values of a flag, the flags of the command, or its subcommands. Collection flags are completed with
+// the collections of their endpoint, as returned by listCollections.
+func completeWords(app *kong.Node, words []string, listCollections func(command, prefix string, values map[string]string) []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	node, command := app, ""
+	values := make(map[string]string)
+	var pending *kong.Flag
+	for _, word := range words[:len(words)-1] {
+		if pending != nil {
+			values[pending.Name] = word
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") {
+			name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+			flag := findFlag(node, name)
+			if flag == nil || flag.IsBool() {
+				continue
+			}
+			if hasValue {
+				values[flag.Name] = value
+			} else {
+				pending = flag
+			}
+			continue
+		}
+		if child := findCommand(node, word); child != nil {
+			if node == app {
+				command = child.Name
+			}
+			node = child
+		}
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		prefix, option, _ := strings.Cut(pending.Name, ".")
+		if option == "collection" && (prefix == "source" || prefix == "target" || prefix == "qdrant") {
+			candidates = listCollections(command, prefix, values)
+		} else {
+			candidates = pending.EnumSlice()
+		}
+	case strings.HasPrefix(current, "-") || len(commandChildren(node)) == 0:
+		for _, group := range node.AllFlags(true) {
+			for _, flag := range group {
+				candidates = append(candidates, "--"+flag.Name)
+			}
+		}
+	default:
+		for _, child := range commandChildren(node) {
+			candidates = append(candidates, child.Name)
+		}
+	}
+
+	var matching []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			matching = append(matching, candidate)
+		}
+	}
+	sort.Strings(matching)
+	return matching
+}
+
+func findFlag(node *kong.Node, name string) *kong.Flag {
+	for _, group := range node.AllFlags(false) {
+		for _, flag := range group {
+			if flag.Name == name || (len(name) == 1 && flag.Short == rune(name[0])) {
+				return flag
+			}
+		}
+	}
+	return nil
+}
+
+func findCommand(node *kong.Node, name string) *kong.Node {
+	for _, child := range commandChildren(node) {
+		if child.Name == name {
+			return child
+		}
+		for _, alias := range child.Aliases {
+			if alias == name {
+				return child
+			}
+		}
+	}
+	return nil
+}
+
+// commandChildren returns the visible subcommands of a command.
+func commandChildren(node *kong.Node) []*kong.Node {
+	var children []*kong.Node
+	for _, child := range node.Children {
+		if child.Type == kong.CommandNode && !child.Hidden {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// listCollectionsForCompletion lists the collections of the endpoint with the given flag prefix of a command,
+// connecting with the URL and API key typed so far, or with the ones of its profile. Any error results in no candidates.
+func listCollectionsForCompletion(command, prefix string, values map[string]string) []string {
+	config := commons.QdrantConfig{Url: values[prefix+".url"], APIKey: values[prefix+".api-key"]}
+	if config.Url == "" || config.APIKey == "" {
+		profile, store := completionProfile(command, prefix, values)
+		if profile != nil {
+			if config.Url == "" {
+				config.Url = profile.Url
+			}
+			if config.APIKey == "" && profile.APIKey != "" && store.unlock(os.Getenv(profilesPassphraseEnv)) == nil {
+				config.APIKey, _ = store.decrypt(profile.APIKey)
+			}
+		}
+	}
+	if config.Url == "" {
+		config.Url = "http://localhost:6334"
+	}
+
+	host, port, tls, err := parseQdrantUrl(config.Url)
+	if err != nil {
+		return nil
+	}
+	client, err := connectToQdrant(&Globals{}, host, port, config, tls)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	collections, err := client.ListCollections(ctx)
+	if err != nil {
+		return nil
+	}
+	return collections
+}
+
+// completionProfile returns the profile of an endpoint, given with --source-profile or --target-profile,
+// their environment variables, or the default profile for targets.
+func completionProfile(command, prefix string, values map[string]string) (*connectionProfile, *profileStore) {
+	store, err := loadProfileStore(values["profiles-file"])
+	if err != nil {
+		return nil, nil
+	}
+
+	var name string
+	if prefix == "source" || (prefix == "qdrant" && qdrantSourceCommands[command]) {
+		name = firstNonEmpty(values["source-profile"], os.Getenv("MIGRATION_SOURCE_PROFILE"))
+	} else {
+		name = firstNonEmpty(values["target-profile"], os.Getenv("MIGRATION_TARGET_PROFILE"), store.Default)
+	}
+	profile, err := store.get(name)
+	if err != nil {
+		return nil, nil
+	}
+	return profile, store
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alecthomas/kong"
+)
+
+func TestCompleteWords(t *testing.T) {
+	parser, err := kong.New(&CLI{}, kong.Name("migration"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotCommand, gotPrefix string
+	var gotValues map[string]string
+	listCollections := func(command, prefix string, values map[string]string) []string {
+		gotCommand, gotPrefix, gotValues = command, prefix, values
+		return []string{"articles", "docs", "docs_v2"}
+	}
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{name: "commands", words: []string{"to-p"}, want: []string{"to-pg", "to-pinecone"}},
+		{name: "subcommands", words: []string{"profile", ""}, want: []string{"add", "list", "remove", "use"}},
+		{name: "flags", words: []string{"qdrant", "--target.coll"}, want: []string{"--target.collection"}},
+		{name: "enum values", words: []string{"qdrant", "--strategy", "s"}, want: []string{"scroll", "snapshot"}},
+		{name: "collections", words: []string{"qdrant", "--source.url", "http://source:6334", "--source.collection", "do"}, want: []string{"docs", "docs_v2"}},
+		{name: "hidden command", words: []string{"__c"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := completeWords(parser.Model.Node, tt.words, listCollections)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("completeWords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if gotCommand != "qdrant" || gotPrefix != "source" || gotValues["source.url"] != "http://source:6334" {
+		t.Errorf("listCollections() called with %s, %s, %v", gotCommand, gotPrefix, gotValues)
+	}
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Tokens are refreshed this long before they expire, so that no request is sent with a token that expires on the way.
+const tokenRefreshMargin = time.Minute
+
+// credentialProvider hands out the access token of an API, and gets a new one before it expires, or when the API
+// rejected it, so that runs longer than the lifetime of a token don't fail at its expiry.
+type credentialProvider struct {
+	spec    commons.TokenSpec
+	fetch   func(ctx context.Context) (*oauth2.Token, error)
+	lock    sync.Mutex
+	current *oauth2.Token
+}
+
+// newCredentialProvider returns the provider of the tokens of a spec, or nil if there is none.
+func newCredentialProvider(spec *commons.TokenSpec) *credentialProvider {
+	if spec == nil {
+		return nil
+	}
+	provider := &credentialProvider{spec: *spec}
+	if spec.Google {
+		provider.fetch = googleToken(spec.Scopes)
+	} else if spec.TokenURL != "" {
+		config := clientcredentials.Config{
+			ClientID:     spec.ClientID,
+			ClientSecret: spec.ClientSecret,
+			TokenURL:     spec.TokenURL,
+			Scopes:       spec.Scopes,
+		}
+		provider.fetch = config.Token
+	} else {
+		provider.fetch = func(ctx context.Context) (*oauth2.Token, error) {
+			return commandToken(ctx, spec.Command)
+		}
+	}
+	return provider
+}
+
+// token returns the current token, after getting a new one if it expires within tokenRefreshMargin.
+// Tokens without an expiry are used until the API rejects them.
+func (p *credentialProvider) token(ctx context.Context) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.current != nil && (p.current.Expiry.IsZero() || time.Until(p.current.Expiry) > tokenRefreshMargin) {
+		return p.current.AccessToken, nil
+	}
+	token, err := p.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	if token.Expiry.IsZero() {
+		pterm.Debug.Printfln("Got a new access token")
+	} else {
+		pterm.Debug.Printfln("Got a new access token, valid until %s", token.Expiry.Format(time.RFC3339))
+	}
+	p.current = token
+	return token.AccessToken, nil
+}
+
+// invalidate drops a token the API rejected, unless another request already replaced it.
+func (p *credentialProvider) invalidate(rejected string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.current != nil && p.current.AccessToken == rejected {
+		p.current = nil
+	}
+}
+
+// value returns the value of the header or metadata a token is sent in.
+func (p *credentialProvider) value(token string) string {
+	return p.spec.Prefix + token
+}
+
+// googleToken returns a function that gets tokens with the Application Default Credentials of Google Cloud.
+// The credentials are looked up once, on the first token.
+func googleToken(scopes []string) func(ctx context.Context) (*oauth2.Token, error) {
+	var lock sync.Mutex
+	var source oauth2.TokenSource
+	return func(ctx context.Context) (*oauth2.Token, error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if source == nil {
+			// The token source refreshes tokens with the context it was created with, so it must outlive the request.
+			credentials, err := google.FindDefaultCredentials(context.WithoutCancel(ctx), scopes...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find Google Application Default Credentials: %w", err)
+			}
+			source = credentials.TokenSource
+		}
+		return source.Token()
+	}
+}
+
+// commandToken runs a command that prints an access token. The expiry of tokens that are JWTs is read from them.
+func commandToken(ctx context.Context, command string) (*oauth2.Token, error) {
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %q: %w", command, err)
+	}
+	token := &oauth2.Token{AccessToken: strings.TrimSpace(string(output))}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%q printed no token", command)
+	}
+	token.Expiry = jwtExpiry(token.AccessToken)
+	return token, nil
+}
+
+// jwtExpiry returns the expiry of a JWT, or the zero time if the token isn't a JWT with one.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// credentialTransport sends the token of a provider with every request, and sends a request the API rejected
+// with 401 once more with a new token.
+type credentialTransport struct {
+	base     http.RoundTripper
+	provider *credentialProvider
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.provider.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, token)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	t.provider.invalidate(token)
+	token, err = t.provider.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetBody != nil {
+		req = req.Clone(req.Context())
+		req.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t.send(req, token)
+}
+
+func (t *credentialTransport) send(req *http.Request, token string) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(t.provider.spec.Header, t.provider.value(token))
+	return t.base.RoundTrip(req)
+}
+
+// credentialInterceptors send the token of a provider with every call, and make a unary call the service rejected
+// as unauthenticated once more with a new token.
+func credentialInterceptors(provider *credentialProvider) []grpc.DialOption {
+	key := strings.ToLower(provider.spec.Header)
+	unary := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		token, err := provider.token(ctx)
+		if err != nil {
+			return err
+		}
+		err = invoker(metadata.AppendToOutgoingContext(ctx, key, provider.value(token)), method, req, reply, cc, opts...)
+		if status.Code(err) != codes.Unauthenticated {
+			return err
+		}
+		provider.invalidate(token)
+		token, err = provider.token(ctx)
+		if err != nil {
+			return err
+		}
+		return invoker(metadata.AppendToOutgoingContext(ctx, key, provider.value(token)), method, req, reply, cc, opts...)
+	}
+	stream := func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		token, err := provider.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return streamer(metadata.AppendToOutgoingContext(ctx, key, provider.value(token)), desc, cc, method, opts...)
+	}
+	return []grpc.DialOption{grpc.WithChainUnaryInterceptor(unary), grpc.WithChainStreamInterceptor(stream)}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestCredentialTransport(t *testing.T) {
+	var issued atomic.Int64
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" {
+			t.Errorf("grant_type = %q, want client_credentials", r.FormValue("grant_type"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":3600}`, issued.Add(1))
+	}))
+	defer tokenServer.Close()
+
+	// The API only accepts the latest token, as if the ones before it had expired.
+	var bodies []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token-%d", issued.Load()) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer api.Close()
+
+	provider := newCredentialProvider(&commons.TokenSpec{TokenURL: tokenServer.URL, ClientID: "migration", Header: "Authorization", Prefix: "Bearer "})
+	client := &http.Client{Transport: &credentialTransport{base: http.DefaultTransport, provider: provider}}
+	post := func(body string) int {
+		resp, err := client.Post(api.URL, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Post() error = %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := post("first"); got != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", got)
+	}
+	// Another client of the API rotates the token, so the one of the provider is rejected.
+	issued.Add(1)
+	if got := post("second"); got != http.StatusOK {
+		t.Fatalf("request with a rejected token status = %d, want 200", got)
+	}
+	if got := issued.Load(); got != 3 {
+		t.Errorf("tokens issued = %d, want 3", got)
+	}
+	if got := strings.Join(bodies, ","); got != "first,second" {
+		t.Errorf("bodies = %q, want the rejected request sent again with its body", got)
+	}
+}
+
+func TestCredentialProviderCommand(t *testing.T) {
+	payload := func(expiry time.Time) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiry.Unix())))
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantFetch int
+	}{
+		{"JWT valid for an hour", "h." + payload(time.Now().Add(time.Hour)) + ".s", 1},
+		{"JWT about to expire", "h." + payload(time.Now().Add(10*time.Second)) + ".s", 3},
+		{"opaque token", "opaque", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := 0
+			provider := newCredentialProvider(&commons.TokenSpec{Command: "echo " + tt.token})
+			fetch := provider.fetch
+			provider.fetch = func(ctx context.Context) (*oauth2.Token, error) {
+				fetched++
+				return fetch(ctx)
+			}
+			for range 3 {
+				token, err := provider.token(context.Background())
+				if err != nil {
+					t.Fatalf("token() error = %v", err)
+				}
+				if token != tt.token {
+					t.Fatalf("token() = %q, want %q", token, tt.token)
+				}
+			}
+			if fetched != tt.wantFetch {
+				t.Errorf("tokens fetched = %d, want %d", fetched, tt.wantFetch)
+			}
+		})
+	}
+}
+
+func TestCredentialProviderGoogle(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") != "refresh" {
+			t.Errorf("grant_type = %q, refresh_token = %q, want the refresh token of the credentials", r.FormValue("grant_type"), r.FormValue("refresh_token"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"ya29.token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer tokenServer.Close()
+
+	path := filepath.Join(t.TempDir(), "application_default_credentials.json")
+	credentials := fmt.Sprintf(`{"type":"authorized_user","client_id":"client","client_secret":"secret","refresh_token":"refresh","token_uri":%q}`, tokenServer.URL)
+	if err := os.WriteFile(path, []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	provider := newCredentialProvider(&commons.TokenSpec{Google: true, Scopes: []string{commons.GoogleCloudPlatformScope}, Header: "Authorization", Prefix: "Bearer "})
+	token, err := provider.token(context.Background())
+	if err != nil {
+		t.Fatalf("token() error = %v", err)
+	}
+	if token != "ya29.token" {
+		t.Errorf("token() = %q, want ya29.token", token)
+	}
+}
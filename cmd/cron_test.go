@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_cronScheduleNext(t *testing.T) {
+	// A Friday.
+	from := time.Date(2025, 1, 3, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{
+			name: "every 6 hours",
+			expr: "0 */6 * * *",
+			want: time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "every minute",
+			expr: "* * * * *",
+			want: time.Date(2025, 1, 3, 10, 18, 0, 0, time.UTC),
+		},
+		{
+			name: "daily alias",
+			expr: "@daily",
+			want: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekdays only",
+			expr: "30 9 * * 1-5",
+			want: time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "Sunday as 7",
+			expr: "0 0 * * 7",
+			want: time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month or day of week",
+			expr: "0 0 15 * 6",
+			want: time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "list and month",
+			expr: "5,45 8 1 3 *",
+			want: time.Date(2025, 3, 1, 8, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "never matches",
+			expr: "0 0 30 2 *",
+			want: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type CutoverCmd struct {
+	Qdrant  commons.QdrantConfig  `embed:"" prefix:"qdrant."`
+	Cutover commons.CutoverConfig `embed:"" prefix:"cutover."`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+}
+
+func (r *CutoverCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CutoverCmd) Validate() error {
+	if r.Cutover.OldCollection == r.Qdrant.Collection {
+		return fmt.Errorf("the old and the new collection must differ")
+	}
+	if r.Cutover.GracePeriod < 0 {
+		return fmt.Errorf("grace period must not be negative")
+	}
+	return nil
+}
+
+func (r *CutoverCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Collection Cutover")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant: %w", err)
+	}
+	defer client.Close()
+
+	current, err := getAliasCollection(ctx, client, r.Cutover.Alias)
+	if err != nil {
+		return err
+	}
+	if current == r.Qdrant.Collection {
+		pterm.Info.Printfln("Alias '%s' already points to '%s'", r.Cutover.Alias, r.Qdrant.Collection)
+		return nil
+	}
+
+	oldCollection := r.Cutover.OldCollection
+	if oldCollection == "" {
+		oldCollection = current
+	} else if current != "" && current != oldCollection {
+		return fmt.Errorf("alias '%s' points to '%s', not to the old collection '%s'", r.Cutover.Alias, current, oldCollection)
+	}
+	displayMigrationRoute("qdrant", oldCollection, "qdrant", r.Qdrant.Collection)
+
+	err = r.verify(ctx, client, oldCollection)
+	if err != nil {
+		return err
+	}
+
+	err = recordAliasSwitch(ctx, client, r.Cutover.StateCollection, r.Qdrant.Collection, r.Cutover.Alias, current)
+	if err != nil {
+		return err
+	}
+
+	// Deleting and creating the alias in one request switches it atomically, so no read finds it missing.
+	operations := []*qdrant.AliasOperations{qdrant.NewAliasCreate(r.Cutover.Alias, r.Qdrant.Collection)}
+	if current != "" {
+		operations = append([]*qdrant.AliasOperations{qdrant.NewAliasDelete(r.Cutover.Alias)}, operations...)
+	}
+	err = client.UpdateAliases(ctx, operations)
+	if err != nil {
+		return fmt.Errorf("failed to switch alias '%s': %w", r.Cutover.Alias, err)
+	}
+	pterm.Success.Printfln("Alias '%s' now points to '%s'", r.Cutover.Alias, r.Qdrant.Collection)
+
+	if oldCollection == "" || (!r.Cutover.SnapshotOld && !r.Cutover.DeleteOld) {
+		return nil
+	}
+	return r.retireOldCollection(ctx, client, oldCollection)
+}
+
+// verify checks that the new collection is ready to serve the traffic of the old one.
+func (r *CutoverCmd) verify(ctx context.Context, client *qdrant.Client, oldCollection string) error {
+	if r.Cutover.ReportFile != "" {
+		err := checkMigrationReport(r.Cutover.ReportFile, r.Qdrant.Collection)
+		if err != nil {
+			return err
+		}
+		pterm.Success.Printfln("All checks of the migration report passed")
+	}
+
+	info, err := client.GetCollectionInfo(ctx, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to get new collection information: %w", err)
+	}
+	if info.GetStatus() == qdrant.CollectionStatus_Red {
+		return fmt.Errorf("new collection '%s' is red", r.Qdrant.Collection)
+	}
+
+	if oldCollection == "" {
+		return nil
+	}
+	oldCount, err := client.Count(ctx, &qdrant.CountPoints{CollectionName: oldCollection, Exact: qdrant.PtrOf(true)})
+	if err != nil {
+		return fmt.Errorf("failed to count points in old collection: %w", err)
+	}
+	newCount, err := client.Count(ctx, &qdrant.CountPoints{CollectionName: r.Qdrant.Collection, Exact: qdrant.PtrOf(true)})
+	if err != nil {
+		return fmt.Errorf("failed to count points in new collection: %w", err)
+	}
+
+	err = verifyCutoverCounts(oldCount, newCount, r.Cutover.MaxMissingPoints)
+	currentReport.addCheck(reportCheck{
+		Name:    "cutover",
+		Target:  r.Qdrant.Collection,
+		Passed:  err == nil,
+		Details: fmt.Sprintf("old collection has %d points, new collection has %d", oldCount, newCount),
+	})
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("New collection has %d points, the old one %d", newCount, oldCount)
+	return nil
+}
+
+// retireOldCollection waits for the grace period, in which the cutover can be rolled back by pointing the alias back,
+// and then snapshots and deletes the old collection as requested. Nothing is done if the alias was moved in the meantime.
+func (r *CutoverCmd) retireOldCollection(ctx context.Context, client *qdrant.Client, oldCollection string) error {
+	if r.Cutover.GracePeriod > 0 {
+		pterm.Info.Printfln("Waiting %s before retiring '%s'. To roll back, point alias '%s' back to it.", r.Cutover.GracePeriod, oldCollection, r.Cutover.Alias)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted during the grace period, '%s' was kept: %w", oldCollection, ctx.Err())
+		case <-time.After(r.Cutover.GracePeriod):
+		}
+
+		current, err := getAliasCollection(ctx, client, r.Cutover.Alias)
+		if err != nil {
+			return err
+		}
+		if current != r.Qdrant.Collection {
+			pterm.Warning.Printfln("Alias '%s' no longer points to '%s', keeping '%s'", r.Cutover.Alias, r.Qdrant.Collection, oldCollection)
+			return nil
+		}
+	}
+
+	if r.Cutover.SnapshotOld {
+		snapshot, err := client.CreateSnapshot(ctx, oldCollection)
+		if err != nil {
+			return fmt.Errorf("failed to snapshot old collection: %w", err)
+		}
+		pterm.Success.Printfln("Created snapshot '%s' of '%s'", snapshot.GetName(), oldCollection)
+	}
+
+	if r.Cutover.DeleteOld {
+		err := client.DeleteCollection(ctx, oldCollection)
+		if err != nil {
+			return fmt.Errorf("failed to delete old collection: %w", err)
+		}
+		pterm.Success.Printfln("Deleted old collection '%s'", oldCollection)
+	}
+
+	return nil
+}
+
+// getAliasCollection returns the collection an alias points to, or an empty string if there's no such alias.
+func getAliasCollection(ctx context.Context, client *qdrant.Client, alias string) (string, error) {
+	aliases, err := client.ListAliases(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list aliases: %w", err)
+	}
+	for _, description := range aliases {
+		if description.GetAliasName() == alias {
+			return description.GetCollectionName(), nil
+		}
+	}
+	return "", nil
+}
+
+// verifyCutoverCounts checks that the new collection has no more than maxMissing points fewer than the old one.
+func verifyCutoverCounts(oldCount, newCount, maxMissing uint64) error {
+	if newCount+maxMissing < oldCount {
+		return fmt.Errorf("new collection has %d points, but the old one has %d, more than %d missing", newCount, oldCount, maxMissing)
+	}
+	return nil
+}
+
+// checkMigrationReport checks that the run report of a migration is of a successful run to the collection,
+// and that all its checks passed.
+func checkMigrationReport(path, collection string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read migration report: %w", err)
+	}
+	var report struct {
+		Target       *reportEndpoint `json:"target"`
+		Verification []reportCheck   `json:"verification"`
+		Error        string          `json:"error"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return fmt.Errorf("failed to parse migration report: %w", err)
+	}
+
+	if report.Error != "" {
+		return fmt.Errorf("the migration of the report failed: %s", report.Error)
+	}
+	if report.Target == nil || report.Target.Collection != collection {
+		return fmt.Errorf("the report isn't of a migration to '%s'", collection)
+	}
+	for _, check := range report.Verification {
+		if !check.Passed {
+			return fmt.Errorf("check '%s' of the migration report failed: %s", check.Name, check.Details)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyCutoverCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		oldCount   uint64
+		newCount   uint64
+		maxMissing uint64
+		wantErr    bool
+	}{
+		{name: "equal", oldCount: 100, newCount: 100},
+		{name: "more in new", oldCount: 100, newCount: 120},
+		{name: "missing", oldCount: 100, newCount: 99, wantErr: true},
+		{name: "missing within limit", oldCount: 100, newCount: 95, maxMissing: 5},
+		{name: "missing over limit", oldCount: 100, newCount: 94, maxMissing: 5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCutoverCounts(tt.oldCount, tt.newCount, tt.maxMissing)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyCutoverCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckMigrationReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		report  string
+		wantErr string
+	}{
+		{
+			name:   "passed",
+			report: `{"target": {"provider": "qdrant", "collection": "green"}, "verification": [{"name": "reconciliation", "passed": true}]}`,
+		},
+		{
+			name:    "failed run",
+			report:  `{"target": {"provider": "qdrant", "collection": "green"}, "error": "connection refused"}`,
+			wantErr: "connection refused",
+		},
+		{
+			name:    "failed check",
+			report:  `{"target": {"provider": "qdrant", "collection": "green"}, "verification": [{"name": "hash", "passed": false, "details": "3 mismatches"}]}`,
+			wantErr: "3 mismatches",
+		},
+		{
+			name:    "other collection",
+			report:  `{"target": {"provider": "qdrant", "collection": "blue"}}`,
+			wantErr: "isn't of a migration to 'green'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "report.json")
+			if err := os.WriteFile(path, []byte(tt.report), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkMigrationReport(path, "green")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkMigrationReport() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkMigrationReport() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
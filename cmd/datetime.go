@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Layouts tried by the "auto" directive, after RFC 3339. Layouts without a UTC offset are read in the time zone of the field.
+var datetimeLayouts = []string{
+	// SQL timestamps with time zone, e.g. as PostgreSQL prints them.
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02T15:04:05.999999999Z07",
+	// SQL timestamps without time zone.
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.DateOnly,
+}
+
+var epochUnits = map[string]time.Duration{
+	"seconds": time.Second,
+	"millis":  time.Millisecond,
+	"micros":  time.Microsecond,
+	"nanos":   time.Nanosecond,
+}
+
+// normalizeDatetimes converts the fields with a datetime directive into RFC 3339 timestamps in UTC,
+// the format Qdrant's datetime index reads. Values that can't be parsed are left as they are.
+func normalizeDatetimes(points []*qdrant.PointStruct, mapping commons.PayloadMapping) {
+	for name, field := range mapping.Fields {
+		if field.Datetime == "" {
+			continue
+		}
+		for _, point := range points {
+			value := payloadField(point.GetPayload(), name)
+			if value == nil {
+				continue
+			}
+			if t, ok := parseDatetime(value, field.Datetime, field.Location()); ok {
+				setPayloadField(point.GetPayload(), name, qdrant.NewValueString(t.UTC().Format(time.RFC3339Nano)))
+			}
+		}
+	}
+}
+
+func parseDatetime(value *qdrant.Value, directive string, location *time.Location) (time.Time, bool) {
+	// Mongo dates arrive as extended JSON: {"$date": "2024-05-01T10:00:00Z"} or {"$date": {"$numberLong": "1714557600000"}}.
+	if date, ok := value.GetStructValue().GetFields()["$date"]; ok {
+		if millis, ok := date.GetStructValue().GetFields()["$numberLong"]; ok {
+			return parseDatetime(millis, "millis", location)
+		}
+		return parseDatetime(date, "auto", location)
+	}
+
+	if unit, ok := epochUnits[directive]; ok {
+		number, ok := geoNumber(value)
+		if !ok {
+			return time.Time{}, false
+		}
+		return epochTime(number, unit), true
+	}
+
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_IntegerValue, *qdrant.Value_DoubleValue:
+		if directive != "auto" {
+			return time.Time{}, false
+		}
+		number, _ := geoNumber(value)
+		return epochTime(number, guessEpochUnit(number)), true
+	case *qdrant.Value_StringValue:
+		s := strings.TrimSpace(kind.StringValue)
+		if directive != "auto" {
+			t, err := time.ParseInLocation(directive, s, location)
+			return t, err == nil
+		}
+		if number, err := strconv.ParseFloat(s, 64); err == nil {
+			return epochTime(number, guessEpochUnit(number)), true
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t, true
+		}
+		for _, layout := range datetimeLayouts {
+			if t, err := time.ParseInLocation(layout, s, location); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// guessEpochUnit tells the unit of an epoch timestamp by its magnitude, which works for dates between 1973 and 5138.
+func guessEpochUnit(number float64) time.Duration {
+	switch number = math.Abs(number); {
+	case number < 1e11:
+		return time.Second
+	case number < 1e14:
+		return time.Millisecond
+	case number < 1e17:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+func epochTime(number float64, unit time.Duration) time.Time {
+	// Split off the whole seconds first, so large timestamps don't lose precision.
+	whole := math.Floor(number)
+	perSecond := int64(time.Second / unit)
+	seconds := int64(whole) / perSecond
+	nanos := int64(whole)%perSecond*int64(unit) + int64(math.Round((number-whole)*float64(unit)))
+	return time.Unix(seconds, nanos)
+}
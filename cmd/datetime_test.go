@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_parseDatetime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	tests := []struct {
+		name      string
+		value     any
+		directive string
+		location  *time.Location
+		want      string
+	}{
+		{name: "RFC 3339", value: "2024-05-01T12:00:00+02:00", directive: "auto", want: "2024-05-01T10:00:00Z"},
+		{name: "epoch seconds", value: 1714557600, directive: "auto", want: "2024-05-01T10:00:00Z"},
+		{name: "epoch millis", value: 1714557600123, directive: "auto", want: "2024-05-01T10:00:00.123Z"},
+		{name: "epoch millis string", value: "1714557600123", directive: "millis", want: "2024-05-01T10:00:00.123Z"},
+		{name: "fractional seconds", value: 1714557600.5, directive: "seconds", want: "2024-05-01T10:00:00.5Z"},
+		{name: "SQL timestamp with time zone", value: "2024-05-01 12:00:00+02", directive: "auto", want: "2024-05-01T10:00:00Z"},
+		{name: "SQL timestamp in time zone", value: "2024-05-01 12:00:00", directive: "auto", location: berlin, want: "2024-05-01T10:00:00Z"},
+		{name: "date", value: "2024-05-01", directive: "auto", want: "2024-05-01T00:00:00Z"},
+		{name: "layout", value: "01/05/2024 10:00", directive: "02/01/2006 15:04", want: "2024-05-01T10:00:00Z"},
+		{name: "Mongo date", value: map[string]any{"$date": "2024-05-01T10:00:00.123Z"}, directive: "auto", want: "2024-05-01T10:00:00.123Z"},
+		{name: "Mongo date as number", value: map[string]any{"$date": map[string]any{"$numberLong": "1714557600123"}}, directive: "auto", want: "2024-05-01T10:00:00.123Z"},
+		{name: "not a date", value: "yesterday", directive: "auto"},
+		{name: "number with layout", value: 1714557600, directive: "2006-01-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := qdrant.NewValue(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			location := tt.location
+			if location == nil {
+				location = time.UTC
+			}
+			got, ok := parseDatetime(value, tt.directive, location)
+			if ok != (tt.want != "") {
+				t.Fatalf("parseDatetime() ok = %v, want %v", ok, tt.want != "")
+			}
+			if ok && got.UTC().Format(time.RFC3339Nano) != tt.want {
+				t.Errorf("parseDatetime() = %s, want %s", got.UTC().Format(time.RFC3339Nano), tt.want)
+			}
+		})
+	}
+}
+
+func Test_normalizeDatetimes(t *testing.T) {
+	points := []*qdrant.PointStruct{
+		{Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"created": 1714557600}, "updated": "never"})},
+		{Payload: qdrant.NewValueMap(map[string]any{"other": 1})},
+	}
+	mapping := commons.PayloadMapping{Fields: map[string]commons.FieldMapping{
+		"meta.created": {Datetime: "auto"},
+		"updated":      {Datetime: "auto"},
+	}}
+
+	normalizeDatetimes(points, mapping)
+
+	if got := payloadField(points[0].Payload, "meta.created").GetStringValue(); got != "2024-05-01T10:00:00Z" {
+		t.Errorf("meta.created = %q, want 2024-05-01T10:00:00Z", got)
+	}
+	if got := points[0].Payload["updated"].GetStringValue(); got != "never" {
+		t.Errorf("updated = %q, want it unchanged", got)
+	}
+	if _, ok := points[1].Payload["meta"]; ok {
+		t.Errorf("meta was added to a point without it")
+	}
+}
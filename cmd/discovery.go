@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Schemes of Qdrant URLs that are resolved by DNS SRV records, e.g. http+srv://_grpc._tcp.qdrant.default.svc.cluster.local.
+const (
+	srvScheme    = "http+srv"
+	srvTLSScheme = "https+srv"
+)
+
+// unixScheme is the scheme of Qdrant URLs of unix domain sockets, e.g. unix:///var/run/qdrant/grpc.sock.
+const unixScheme = "unix"
+
+// isUnixSocket reports whether a host parsed from a Qdrant URL is the path of a unix domain socket.
+func isUnixSocket(host string) bool {
+	return strings.HasPrefix(host, "/")
+}
+
+// isSrvUrl reports whether the nodes of a Qdrant URL are resolved by DNS SRV records.
+func isSrvUrl(urlStr string) bool {
+	scheme, _, _ := strings.Cut(strings.ToLower(urlStr), "://")
+	return scheme == srvScheme || scheme == srvTLSScheme
+}
+
+// unixSocketDialer returns a dialer that connects to a unix domain socket, whatever the address, within the bandwidth limit.
+func unixSocketDialer(globals *Globals, path string) contextDialer {
+	var dialer contextDialer = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", path)
+	}
+	if limiter := globals.getBandwidthLimiter(); limiter != nil {
+		dialer = limitDialer(dialer, limiter)
+	}
+	return dialer
+}
+
+// checkUnixSocket checks that the settings of a Qdrant endpoint can be used with a unix domain socket.
+func checkUnixSocket(config commons.QdrantConfig) error {
+	if len(config.Endpoints) > 0 || config.ResolveNodes {
+		return fmt.Errorf("%s is a unix domain socket, which can't be combined with further endpoints or resolving nodes", config.Url)
+	}
+	return nil
+}
+
+// parseUnixSocketUrl returns the path of the socket of a Qdrant URL with the unix scheme.
+func parseUnixSocketUrl(urlStr string) (string, error) {
+	parsedUrl, err := url.Parse(urlStr)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if parsedUrl.Host != "" || !path.IsAbs(parsedUrl.Path) {
+		return "", fmt.Errorf("%s isn't the URL of a unix domain socket, which has an absolute path, e.g. unix:///var/run/qdrant/grpc.sock", urlStr)
+	}
+	return parsedUrl.Path, nil
+}
+
+// lookupSrvNodes returns the nodes of the DNS SRV records of a name.
+func lookupSrvNodes(ctx context.Context, name string) ([]endpoint, error) {
+	_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SRV records of %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s has no SRV records", name)
+	}
+	return srvNodes(records), nil
+}
+
+// srvNodes returns the nodes of SRV records by priority, and the highest weight first.
+func srvNodes(records []*net.SRV) []endpoint {
+	records = slices.Clone(records)
+	slices.SortStableFunc(records, func(a, b *net.SRV) int {
+		return cmp.Or(cmp.Compare(a.Priority, b.Priority), cmp.Compare(b.Weight, a.Weight))
+	})
+
+	nodes := make([]endpoint, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		nodes = append(nodes, endpoint{host: host, port: int(record.Port), serverName: host})
+	}
+	return nodes
+}
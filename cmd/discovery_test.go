@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestConnectToQdrantUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "grpc.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	node := &countingQdrantServer{}
+	qdrant.RegisterQdrantServer(server, node)
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	config := commons.QdrantConfig{Url: "unix://" + socket}
+	host, port, useTLS, err := parseQdrantUrl(config.Url)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+	client, err := connectToQdrant(&Globals{}, host, port, config, useTLS)
+	if err != nil {
+		t.Fatalf("connectToQdrant() error = %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck() error = %v", err)
+	}
+	if node.calls.Load() != 1 {
+		t.Errorf("node got %d calls, want 1", node.calls.Load())
+	}
+
+	config.Endpoints = []string{"http://localhost:6334"}
+	if _, err := connectToQdrant(&Globals{}, host, port, config, useTLS); err == nil {
+		t.Errorf("connectToQdrant() succeeded for a unix socket with further endpoints")
+	}
+}
+
+func Test_srvNodes(t *testing.T) {
+	records := []*net.SRV{
+		{Target: "backup.example.com.", Port: 6334, Priority: 20, Weight: 100},
+		{Target: "light.example.com.", Port: 6335, Priority: 10, Weight: 10},
+		{Target: "heavy.example.com.", Port: 6336, Priority: 10, Weight: 50},
+	}
+	want := []string{"heavy.example.com:6336", "light.example.com:6335", "backup.example.com:6334"}
+
+	nodes := srvNodes(records)
+	if len(nodes) != len(want) {
+		t.Fatalf("srvNodes() = %v, want %v", nodes, want)
+	}
+	for i, node := range nodes {
+		if node.address() != want[i] || node.serverName != node.host {
+			t.Errorf("srvNodes()[%d] = %s with server name %s, want %s", i, node.address(), node.serverName, want[i])
+		}
+	}
+}
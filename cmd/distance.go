@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// distanceTranslation is the Qdrant distance a distance metric of a source is migrated as.
+// The caveat tells how searches in Qdrant differ from those in the source, if they do.
+type distanceTranslation struct {
+	distance qdrant.Distance
+	caveat   string
+}
+
+const (
+	caveatSquaredL2 = "the source scores by the squared Euclidean distance, so neighbors are the same but score thresholds differ"
+	caveatBinary    = "it only matches for vectors of 0s and 1s, as Manhattan distance is the Hamming distance of bits"
+)
+
+// distanceTranslations maps the distance metrics of every source, in lower case, to Qdrant distances.
+var distanceTranslations = map[string]map[string]distanceTranslation{
+	"qdrant": {
+		"cosine":    {distance: qdrant.Distance_Cosine},
+		"dot":       {distance: qdrant.Distance_Dot},
+		"euclid":    {distance: qdrant.Distance_Euclid},
+		"manhattan": {distance: qdrant.Distance_Manhattan},
+	},
+	"pinecone": {
+		"cosine":     {distance: qdrant.Distance_Cosine},
+		"dotproduct": {distance: qdrant.Distance_Dot},
+		"euclidean":  {distance: qdrant.Distance_Euclid},
+	},
+	"opensearch": {
+		"cosinesimil":  {distance: qdrant.Distance_Cosine},
+		"innerproduct": {distance: qdrant.Distance_Dot},
+		"l2":           {distance: qdrant.Distance_Euclid},
+		"l1":           {distance: qdrant.Distance_Manhattan},
+		"linf":         {distance: qdrant.Distance_Euclid, caveat: "Qdrant has no Chebyshev distance, so nearest neighbors may differ"},
+		"hamming":      {distance: qdrant.Distance_Manhattan, caveat: caveatBinary},
+	},
+	"milvus": {
+		"cosine": {distance: qdrant.Distance_Cosine},
+		"ip":     {distance: qdrant.Distance_Dot},
+		"l2":     {distance: qdrant.Distance_Euclid, caveat: caveatSquaredL2},
+	},
+	"chroma": {
+		"cosine": {distance: qdrant.Distance_Cosine},
+		"ip":     {distance: qdrant.Distance_Dot, caveat: "the source scores by 1 minus the inner product, so neighbors are the same but score thresholds differ"},
+		"l2":     {distance: qdrant.Distance_Euclid, caveat: caveatSquaredL2},
+	},
+}
+
+// translateDistance returns the Qdrant distance of a distance metric of a source, to create the vector of a target collection with.
+// It warns when searches in Qdrant will differ from those in the source, and falls back to cosine for unknown metrics.
+func translateDistance(source, metric, vector string) qdrant.Distance {
+	translation, ok := distanceTranslations[source][strings.ToLower(metric)]
+	if !ok {
+		pterm.Warning.Printfln("Distance metric '%s' of vector '%s' has no equivalent in Qdrant, using Cosine. Searches may return different neighbors than in %s", metric, vector, source)
+		return qdrant.Distance_Cosine
+	}
+	if translation.caveat != "" {
+		pterm.Warning.Printfln("Distance metric '%s' of vector '%s' is migrated as %s, but %s", metric, vector, translation.distance, translation.caveat)
+	}
+	return translation.distance
+}
+
+// isQdrantDistance reports whether a distance given by flag, like --qdrant.distance-metric, is a Qdrant distance.
+func isQdrantDistance(metric string) bool {
+	_, ok := distanceTranslations["qdrant"][metric]
+	return ok
+}
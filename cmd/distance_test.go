@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_translateDistance(t *testing.T) {
+	tests := []struct {
+		source   string
+		metric   string
+		expected qdrant.Distance
+	}{
+		{source: "pinecone", metric: "dotproduct", expected: qdrant.Distance_Dot},
+		{source: "pinecone", metric: "euclidean", expected: qdrant.Distance_Euclid},
+		{source: "milvus", metric: "IP", expected: qdrant.Distance_Dot},
+		{source: "milvus", metric: "COSINE", expected: qdrant.Distance_Cosine},
+		{source: "milvus", metric: "L2", expected: qdrant.Distance_Euclid},
+		{source: "opensearch", metric: "cosinesimil", expected: qdrant.Distance_Cosine},
+		{source: "opensearch", metric: "l1", expected: qdrant.Distance_Manhattan},
+		{source: "chroma", metric: "l2", expected: qdrant.Distance_Euclid},
+		{source: "qdrant", metric: "manhattan", expected: qdrant.Distance_Manhattan},
+		{source: "milvus", metric: "SUBSTRUCTURE", expected: qdrant.Distance_Cosine},
+		{source: "unknown", metric: "cosine", expected: qdrant.Distance_Cosine},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source+"/"+tt.metric, func(t *testing.T) {
+			if got := translateDistance(tt.source, tt.metric, "vector"); got != tt.expected {
+				t.Errorf("translateDistance() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_distanceTranslations(t *testing.T) {
+	for source, metrics := range distanceTranslations {
+		for metric, translation := range metrics {
+			if translation.distance == qdrant.Distance_UnknownDistance {
+				t.Errorf("%s metric %q has no Qdrant distance", source, metric)
+			}
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type DoctorCmd struct {
+	Url        string        `help:"Qdrant gRPC URL to diagnose" default:"http://localhost:6334"`
+	APIKey     string        `help:"API key for authentication"`
+	Collection string        `help:"Collection to check the access of the API key on"`
+	CACert     string        `help:"Path to a PEM file with the CA certificates to verify the server with, instead of the system ones" type:"existingfile"`
+	Timeout    time.Duration `help:"Timeout of every check" default:"10s"`
+}
+
+// doctor runs the checks of a connection one after the other, and counts the problems found.
+type doctor struct {
+	problems int
+}
+
+func (d *doctor) pass(check, format string, args ...any) {
+	pterm.Success.Printfln("%s: %s", check, fmt.Sprintf(format, args...))
+}
+
+func (d *doctor) fail(check, format string, args ...any) {
+	d.problems++
+	pterm.Error.Printfln("%s: %s", check, fmt.Sprintf(format, args...))
+}
+
+func (r *DoctorCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Connection Doctor")
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	d := &doctor{}
+	r.diagnose(ctx, globals, d)
+	if d.problems > 0 {
+		return fmt.Errorf("found %d problem(s) with %s", d.problems, r.Url)
+	}
+	pterm.Success.Printfln("No problems found with %s", r.Url)
+	return nil
+}
+
+// diagnose checks the URL, the DNS, the TCP connection, TLS, the protocol on the port, the version and latency of Qdrant,
+// and the access of the API key. A check that fails stops the checks that need it.
+func (r *DoctorCmd) diagnose(ctx context.Context, globals *Globals, d *doctor) {
+	config := commons.QdrantConfig{
+		Url:    r.Url,
+		APIKey: r.APIKey,
+		CACert: r.CACert,
+	}
+
+	host, port, useTLS, err := parseQdrantUrl(r.Url)
+	if err != nil {
+		d.fail("URL", "%v", err)
+		return
+	}
+	switch {
+	case isUnixSocket(host):
+		d.pass("URL", "unix domain socket %s", host)
+		if _, err := os.Stat(host); err != nil {
+			d.fail("Socket", "%v", err)
+			return
+		}
+	case isSrvUrl(r.Url):
+		d.pass("URL", "nodes of the SRV records of %s", host)
+		lookupCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+		nodes, err := lookupSrvNodes(lookupCtx, host)
+		cancel()
+		if err != nil {
+			d.fail("DNS", "%v", err)
+			return
+		}
+		d.pass("DNS", "%s", describeEndpoints(nodes))
+	default:
+		if useTLS {
+			d.pass("URL", "%s, port %d, with TLS", host, port)
+		} else {
+			d.pass("URL", "%s, port %d, without TLS", host, port)
+		}
+		if !r.checkNetwork(ctx, globals, d, host, port, useTLS, config) {
+			return
+		}
+	}
+
+	client, err := connectToQdrant(globals, host, port, config, useTLS)
+	if err != nil {
+		d.fail("gRPC", "%v", err)
+		return
+	}
+	defer client.Close()
+
+	checkCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	health, err := client.HealthCheck(checkCtx)
+	cancel()
+	if err != nil {
+		d.fail("gRPC", "%s", diagnoseGrpcError(err))
+		return
+	}
+	version, err := parseQdrantVersion(health.GetVersion())
+	switch {
+	case err != nil:
+		d.pass("Version", "Qdrant %s, a version that can't be compared", health.GetVersion())
+	case version.less(minSourceVersion):
+		d.fail("Version", "Qdrant %s is older than %s, the oldest version migrations support", version, minSourceVersion)
+	default:
+		d.pass("Version", "Qdrant %s", version)
+	}
+
+	latency, err := measureLatency(ctx, client)
+	if err != nil {
+		d.fail("Latency", "%s", diagnoseGrpcError(err))
+		return
+	}
+	d.pass("Latency", "%s per health check", latency.Round(100*time.Microsecond))
+
+	r.checkAccess(ctx, d, client)
+}
+
+// checkNetwork checks that the host resolves, that the port accepts connections, whether it speaks TLS as the URL says,
+// and that it isn't the REST port. It returns whether connecting over gRPC is worth trying.
+func (r *DoctorCmd) checkNetwork(ctx context.Context, globals *Globals, d *doctor, host string, port int, useTLS bool, config commons.QdrantConfig) bool {
+	if net.ParseIP(host) == nil {
+		lookupCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+		addresses, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+		cancel()
+		if err != nil {
+			d.fail("DNS", "%s doesn't resolve, check the spelling of the host and the DNS of this machine: %v", host, err)
+			return false
+		}
+		d.pass("DNS", "%s resolves to %s", host, strings.Join(addresses, ", "))
+	}
+
+	dialer, err := getEndpointDialer(globals, config)
+	if err != nil {
+		d.fail("TCP", "%v", err)
+		return false
+	}
+	if dialer == nil {
+		dialer = (&net.Dialer{}).DialContext
+	}
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	dialCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := dialer(dialCtx, "tcp", address)
+	if err != nil {
+		d.fail("TCP", "%s", diagnoseDialError(err, address, r.Timeout))
+		return false
+	}
+	d.pass("TCP", "connected to %s in %s", address, time.Since(start).Round(100*time.Microsecond))
+
+	tlsConfig, err := getTLSConfig(globals, config, true)
+	if err != nil {
+		_ = conn.Close()
+		d.fail("TLS", "%v", err)
+		return false
+	}
+	tlsConfig.ServerName = host
+	tlsConn := tls.Client(conn, tlsConfig)
+	_ = conn.SetDeadline(time.Now().Add(r.Timeout))
+	err = tlsConn.HandshakeContext(dialCtx)
+	speaksTLS := err == nil || isCertificateError(err)
+	switch {
+	case useTLS && err == nil:
+		certificate := tlsConn.ConnectionState().PeerCertificates[0]
+		d.pass("TLS", "certificate for %s, valid until %s", strings.Join(certificateNames(certificate), ", "), certificate.NotAfter.Format(time.DateOnly))
+	case useTLS:
+		d.fail("TLS", "%s", diagnoseTLSError(err))
+	case speaksTLS:
+		d.fail("TLS", "the port speaks TLS, but the URL uses http: use https://%s", address)
+	}
+	_ = tlsConn.Close()
+	if useTLS != speaksTLS {
+		return false
+	}
+
+	if restVersion, ok := detectRestPort(dialCtx, dialer, address, useTLS); ok {
+		d.fail("Port", "%d is the REST port of Qdrant %s, migrations need the gRPC port, by default %d", port, restVersion, defaultQdrantGrpcPort)
+		return false
+	}
+	return true
+}
+
+// checkAccess checks that the API key can list collections, and read and write the collection, if one was given.
+// Writes are checked with a write that changes nothing.
+func (r *DoctorCmd) checkAccess(ctx context.Context, d *doctor, client *qdrant.Client) {
+	checkCtx, cancel := context.WithTimeout(ctx, r.Timeout)
+	defer cancel()
+
+	collections, err := client.ListCollections(checkCtx)
+	switch {
+	case err != nil:
+		d.fail("API key", "%s", diagnoseGrpcError(err))
+		return
+	case r.APIKey == "":
+		d.pass("API key", "none needed, %d collection(s) visible", len(collections))
+	default:
+		d.pass("API key", "valid, %d collection(s) visible", len(collections))
+	}
+	if r.Collection == "" {
+		return
+	}
+
+	exists, err := client.CollectionExists(checkCtx, r.Collection)
+	if err != nil {
+		d.fail("Collection", "%s", diagnoseGrpcError(err))
+		return
+	}
+	if !exists {
+		d.fail("Collection", "'%s' doesn't exist", r.Collection)
+		return
+	}
+	err = checkSourceAccess(checkCtx, client, r.Collection)
+	if status.Code(err) == codes.PermissionDenied {
+		d.fail("Read access", "the API key lacks read access on collection '%s': %s", r.Collection, status.Convert(err).Message())
+	} else if err != nil {
+		d.fail("Read access", "%s", diagnoseGrpcError(err))
+	} else {
+		d.pass("Read access", "the API key can read collection '%s'", r.Collection)
+	}
+	if access, ok := parseJWTAccess(r.APIKey); ok {
+		if !access.canWrite(r.Collection, false) {
+			d.fail("Write access", "the JWT lacks write access on collection '%s', which migrations to it need", r.Collection)
+			return
+		}
+	} else {
+		err = checkWriteAccess(checkCtx, client, r.Collection)
+		if status.Code(err) == codes.PermissionDenied {
+			d.fail("Write access", "the API key lacks write access on collection '%s', which migrations to it need: %s", r.Collection, status.Convert(err).Message())
+			return
+		}
+		if err != nil {
+			d.fail("Write access", "%s, which migrations to it need", diagnoseGrpcError(err))
+			return
+		}
+	}
+	d.pass("Write access", "the API key can write collection '%s'", r.Collection)
+}
+
+// detectRestPort reports whether the port answers HTTP requests like the REST API of Qdrant, and with which version.
+func detectRestPort(ctx context.Context, dialer contextDialer, address string, useTLS bool) (string, bool) {
+	scheme := "http"
+	if useTLS {
+		scheme = HTTPS
+	}
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: dialer,
+		// Only the kind of server is of interest here, its certificate is checked on its own.
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+address+"/", nil)
+	if err != nil {
+		return "", false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", false
+	}
+	defer resp.Body.Close()
+
+	var root struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&root) != nil {
+		return "", false
+	}
+	return root.Version, strings.Contains(strings.ToLower(root.Title), "qdrant")
+}
+
+// isCertificateError reports whether a TLS handshake failed on the certificate of the server, which means it speaks TLS.
+func isCertificateError(err error) bool {
+	var verificationErr *tls.CertificateVerificationError
+	return errors.As(err, &verificationErr)
+}
+
+// diagnoseTLSError explains why a TLS handshake failed.
+func diagnoseTLSError(err error) string {
+	var hostnameErr x509.HostnameError
+	var authorityErr x509.UnknownAuthorityError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.As(err, &hostnameErr):
+		return fmt.Sprintf("the TLS certificate is for %s, not for %s: use a URL with one of these names", strings.Join(certificateNames(hostnameErr.Certificate), ", "), hostnameErr.Host)
+	case errors.As(err, &authorityErr):
+		return "the TLS certificate is signed by an unknown authority: pass its CA certificate with --ca-cert, or skip the verification with --skip-tls-verification"
+	case errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired:
+		return fmt.Sprintf("the TLS certificate expired or isn't valid yet: %v", invalidErr)
+	case errors.As(err, &recordErr), errors.Is(err, io.EOF), errors.Is(err, syscall.ECONNRESET):
+		return "the port doesn't speak TLS, but the URL uses https: use an http URL"
+	default:
+		return fmt.Sprintf("the TLS handshake failed: %v", err)
+	}
+}
+
+// certificateNames returns the names a certificate is valid for.
+func certificateNames(certificate *x509.Certificate) []string {
+	names := append([]string{}, certificate.DNSNames...)
+	for _, ip := range certificate.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 && certificate.Subject.CommonName != "" {
+		names = append(names, certificate.Subject.CommonName)
+	}
+	return names
+}
+
+// diagnoseDialError explains why a TCP connection failed.
+func diagnoseDialError(err error, address string, timeout time.Duration) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Sprintf("nothing listens on %s, check the port, Qdrant listens for gRPC on %d by default", address, defaultQdrantGrpcPort)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Sprintf("%s didn't answer within %s, a firewall may drop the connections", address, timeout)
+	default:
+		return fmt.Sprintf("failed to connect to %s: %v", address, err)
+	}
+}
+
+// diagnoseGrpcError explains why a call to Qdrant failed.
+func diagnoseGrpcError(err error) string {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return fmt.Sprintf("the API key is missing or invalid: %s", status.Convert(err).Message())
+	case codes.PermissionDenied:
+		return status.Convert(err).Message()
+	case codes.DeadlineExceeded:
+		return "Qdrant didn't answer in time"
+	default:
+		if hint := errorHint(err); hint != "" {
+			return fmt.Sprintf("%v. %s", err, hint)
+		}
+		return err.Error()
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+type listingCollectionsServer struct {
+	qdrant.UnimplementedCollectionsServer
+}
+
+func (s *listingCollectionsServer) List(context.Context, *qdrant.ListCollectionsRequest) (*qdrant.ListCollectionsResponse, error) {
+	return &qdrant.ListCollectionsResponse{Collections: []*qdrant.CollectionDescription{{Name: "docs"}}}, nil
+}
+
+func TestDoctorCmd_diagnose(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	qdrant.RegisterQdrantServer(server, &countingQdrantServer{})
+	qdrant.RegisterCollectionsServer(server, &listingCollectionsServer{})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	rest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"title":"qdrant - vector search engine","version":"1.14.0"}`)
+	}))
+	defer rest.Close()
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	closedUrl := "http://" + closed.Addr().String()
+	closed.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		problems int
+	}{
+		{"healthy", "http://" + listener.Addr().String(), 0},
+		{"REST port", rest.URL, 1},
+		{"TLS on plain port", "https://" + listener.Addr().String(), 1},
+		{"nothing listening", closedUrl, 1},
+		{"invalid URL", "grpc://localhost:6334", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &doctor{}
+			cmd := &DoctorCmd{Url: tt.url, Timeout: 5 * time.Second}
+			cmd.diagnose(context.Background(), &Globals{}, d)
+			if d.problems != tt.problems {
+				t.Errorf("diagnose() found %d problem(s), want %d", d.problems, tt.problems)
+			}
+		})
+	}
+}
+
+func Test_diagnoseTLSError(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+	trusted := server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	tests := []struct {
+		name    string
+		address string
+		config  *tls.Config
+		want    string
+	}{
+		{"wrong hostname", server.Listener.Addr().String(), &tls.Config{RootCAs: trusted, ServerName: "qdrant.internal"}, "not for qdrant.internal"},
+		{"unknown authority", server.Listener.Addr().String(), &tls.Config{ServerName: "example.com"}, "unknown authority"},
+		{"plain port", plain.Listener.Addr().String(), &tls.Config{ServerName: "example.com"}, "doesn't speak TLS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", tt.address, tt.config)
+			if err == nil {
+				conn.Close()
+				t.Fatalf("tls.Dial() succeeded")
+			}
+			if got := diagnoseTLSError(err); !strings.Contains(got, tt.want) {
+				t.Errorf("diagnoseTLSError() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// embedder turns texts into vectors, in the same order.
+type embedder interface {
+	embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// embedders caches the embedder of every configuration, shared by all streams of a run.
+var embedders sync.Map
+
+// Default URLs and models of the providers.
+var embeddingProviderDefaults = map[string]struct{ url, model string }{
+	"openai": {url: "https://api.openai.com/v1", model: "text-embedding-3-small"},
+	"ollama": {url: "http://localhost:11434", model: "nomic-embed-text"},
+	"onnx":   {model: "BAAI/bge-small-en-v1.5"},
+}
+
+func getEmbedder(config commons.EmbeddingConfig) (embedder, error) {
+	if cached, ok := embedders.Load(config); ok {
+		return cached.(embedder), nil
+	}
+
+	defaults := embeddingProviderDefaults[config.Provider]
+	key := config
+	if config.Url == "" {
+		config.Url = defaults.url
+	}
+	if config.Model == "" {
+		config.Model = defaults.model
+	}
+
+	var e embedder
+	switch config.Provider {
+	case "openai":
+		e = newOpenAIEmbedder(config)
+	case "onnx":
+		onnx, err := newONNXEmbedder(config)
+		if err != nil {
+			return nil, err
+		}
+		e = onnx
+	case "ollama":
+		ollama, err := newOllamaEmbedder(config)
+		if err != nil {
+			return nil, err
+		}
+		e = ollama
+	default:
+		return nil, fmt.Errorf("unsupported embedding provider: %s", config.Provider)
+	}
+
+	if config.CacheFile != "" {
+		cache, err := newCachedEmbedder(e, config)
+		if err != nil {
+			return nil, err
+		}
+		e = cache
+	}
+
+	cached, _ := embedders.LoadOrStore(key, e)
+	return cached.(embedder), nil
+}
+
+// embedPoints replaces the vectors of points with embeddings of the text in --migration.embed.field.
+// Points without the text keep their vectors.
+func embedPoints(ctx context.Context, points []*qdrant.PointStruct, config commons.EmbeddingConfig) error {
+	if config.Field == "" {
+		return nil
+	}
+	e, err := getEmbedder(config)
+	if err != nil {
+		return err
+	}
+
+	var texts []string
+	var embedded []*qdrant.PointStruct
+	for _, point := range points {
+		text := payloadField(point.GetPayload(), config.Field).GetStringValue()
+		if text == "" {
+			continue
+		}
+		texts = append(texts, text)
+		embedded = append(embedded, point)
+	}
+
+	// Every request sets the vectors of its own points, so they can run in parallel.
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(max(config.Concurrency, 1))
+	batchSize := max(config.BatchSize, 1)
+	for start := 0; start < len(texts); start += batchSize {
+		end := min(start+batchSize, len(texts))
+		group.Go(func() error {
+			vectors, err := e.embed(groupCtx, texts[start:end])
+			if err != nil {
+				return fmt.Errorf("failed to embed texts: %w", err)
+			}
+			if len(vectors) != end-start {
+				return fmt.Errorf("failed to embed texts: got %d embeddings for %d texts", len(vectors), end-start)
+			}
+			for i, vector := range vectors {
+				setPointVector(embedded[start+i], config.Vector, vector)
+			}
+			return nil
+		})
+	}
+
+	return group.Wait()
+}
+
+// setPointVector sets a named vector of a point, keeping its other named vectors, or replaces all vectors with the unnamed one.
+func setPointVector(point *qdrant.PointStruct, name string, vector []float32) {
+	if name == "" {
+		point.Vectors = qdrant.NewVectorsDense(vector)
+		return
+	}
+	vectors := point.GetVectors().GetVectors().GetVectors()
+	if vectors == nil {
+		vectors = make(map[string]*qdrant.Vector)
+	}
+	vectors[name] = qdrant.NewVectorDense(vector)
+	point.Vectors = qdrant.NewVectorsMap(vectors)
+}
+
+// addPointVector adds a named vector to a point, keeping all vectors it has.
+// An unnamed vector is kept under the empty name, which Qdrant uses for the default vector.
+func addPointVector(point *qdrant.PointStruct, name string, vector *qdrant.Vector) {
+	vectors := point.GetVectors().GetVectors().GetVectors()
+	if vectors == nil {
+		vectors = make(map[string]*qdrant.Vector)
+		if unnamed := point.GetVectors().GetVector(); unnamed != nil {
+			vectors[""] = unnamed
+		}
+	}
+	vectors[name] = vector
+	point.Vectors = qdrant.NewVectorsMap(vectors)
+}
+
+// postEmbeddingRequest posts a JSON request to an embedding API and decodes the response.
+// Requests that were rate limited or failed on the server are retried, after the delay of Retry-After if there is one.
+func postEmbeddingRequest(ctx context.Context, client *http.Client, endpoint string, header http.Header, request, response any, maxRetries int) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryable, delay, err := postEmbeddingRequestOnce(ctx, client, endpoint, header, body, response)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= maxRetries {
+			return err
+		}
+		if delay == 0 {
+			delay = backoffDelay(attempt)
+		}
+		pterm.Warning.Printfln("%v, retrying in %s", err, delay)
+		currentReport.addRetry()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// postEmbeddingRequestOnce sends one request. It reports whether a failed request may be retried, and after how long.
+func postEmbeddingRequestOnce(ctx context.Context, client *http.Client, endpoint string, header http.Header, body []byte, response any) (bool, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, 0, fmt.Errorf("failed to call embedding API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, 0, fmt.Errorf("failed to read embedding response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, parseRetryAfter(resp.Header.Get("Retry-After")),
+			fmt.Errorf("embedding API returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	return false, 0, nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given in seconds or as an HTTP date, or 0 if there is none.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// backoffDelay is the delay before a retry without Retry-After: 1s, 2s, 4s, and so on, up to a minute.
+func backoffDelay(attempt int) time.Duration {
+	return min(time.Second<<attempt, time.Minute)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// cachedEmbedder keeps the embeddings of texts in a local bbolt file, with a bucket per model,
+// so interrupted or repeated runs don't embed the same texts again.
+type cachedEmbedder struct {
+	embedder
+	db     *bolt.DB
+	bucket []byte
+	model  string
+}
+
+func newCachedEmbedder(inner embedder, config commons.EmbeddingConfig) (*cachedEmbedder, error) {
+	db, err := bolt.Open(config.CacheFile, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedding cache %s: %w", config.CacheFile, err)
+	}
+
+	// Embeddings of the same model differ by provider, e.g. in quantization, and by the requested dimensions.
+	bucket := []byte(fmt.Sprintf("%s/%s/%d", config.Provider, config.Model, config.Dimensions))
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to prepare embedding cache: %w", err)
+	}
+
+	return &cachedEmbedder{embedder: inner, db: db, bucket: bucket, model: config.Model}, nil
+}
+
+func (c *cachedEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([][]byte, len(texts))
+	var missing []int
+
+	err := c.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(c.bucket)
+		for i, text := range texts {
+			sum := sha256.Sum256([]byte(text))
+			keys[i] = sum[:]
+			if value := bucket.Get(keys[i]); value != nil {
+				vectors[i] = decodeCachedVector(value)
+			} else {
+				missing = append(missing, i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedding cache: %w", err)
+	}
+	currentReport.addCachedEmbeddings(c.model, len(texts)-len(missing))
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	missingTexts := make([]string, len(missing))
+	for i, index := range missing {
+		missingTexts[i] = texts[index]
+	}
+	embedded, err := c.embedder.embed(ctx, missingTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embedded) != len(missing) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(embedded), len(missing))
+	}
+
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(c.bucket)
+		for i, index := range missing {
+			vectors[index] = embedded[i]
+			if err := bucket.Put(keys[index], encodeCachedVector(embedded[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+
+	return vectors, nil
+}
+
+func encodeCachedVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeCachedVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
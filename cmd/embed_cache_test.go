@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// countingEmbedder embeds every text as its length, and records the texts it was asked for.
+type countingEmbedder struct {
+	texts []string
+}
+
+func (e *countingEmbedder) embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.texts = append(e.texts, texts...)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text)), 0.5}
+	}
+	return vectors, nil
+}
+
+func Test_cachedEmbedder(t *testing.T) {
+	inner := &countingEmbedder{}
+	config := commons.EmbeddingConfig{Provider: "openai", Model: "model", CacheFile: filepath.Join(t.TempDir(), "embeddings.db")}
+	cache, err := newCachedEmbedder(inner, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.db.Close()
+
+	_, err = cache.embed(context.Background(), []string{"a", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := cache.embed(context.Background(), []string{"bb", "ccc", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "bb", "ccc"}; !reflect.DeepEqual(inner.texts, want) {
+		t.Errorf("embedded texts = %v, want %v", inner.texts, want)
+	}
+	if want := [][]float32{{2, 0.5}, {3, 0.5}, {1, 0.5}}; !reflect.DeepEqual(vectors, want) {
+		t.Errorf("embed() = %v, want %v", vectors, want)
+	}
+}
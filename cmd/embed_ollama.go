@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// ollamaEmbedder uses the embedding models of an Ollama server, for migrations that are fully local.
+type ollamaEmbedder struct {
+	config commons.EmbeddingConfig
+	client *http.Client
+}
+
+type ollamaEmbedRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Truncate   bool     `json:"truncate"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type ollamaEmbedResponse struct {
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+func newOllamaEmbedder(config commons.EmbeddingConfig) (*ollamaEmbedder, error) {
+	e := &ollamaEmbedder{config: config, client: &http.Client{Timeout: 5 * time.Minute}}
+	err := e.checkHealth()
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// checkHealth checks that the server is reachable and has the model, so a migration doesn't fail on its first batch.
+func (e *ollamaEmbedder) checkHealth() error {
+	resp, err := e.client.Get(strings.TrimSuffix(e.config.Url, "/") + "/api/tags")
+	if err != nil {
+		return fmt.Errorf("failed to reach Ollama at %s: %w", e.config.Url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to list the models of Ollama at %s: %s", e.config.Url, resp.Status)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&tags)
+	if err != nil {
+		return fmt.Errorf("failed to decode the models of Ollama: %w", err)
+	}
+
+	for _, model := range tags.Models {
+		// Models without a tag are the latest ones.
+		if model.Name == e.config.Model || model.Name == e.config.Model+":latest" {
+			return nil
+		}
+	}
+	return fmt.Errorf("the Ollama server at %s doesn't have the model %s, pull it with 'ollama pull %s'", e.config.Url, e.config.Model, e.config.Model)
+}
+
+func (e *ollamaEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var resp ollamaEmbedResponse
+	request := ollamaEmbedRequest{Model: e.config.Model, Input: texts, Truncate: true, Dimensions: e.config.Dimensions}
+	err := postEmbeddingRequest(ctx, e.client, strings.TrimSuffix(e.config.Url, "/")+"/api/embed", http.Header{}, request, &resp, e.config.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	currentReport.addEmbeddingUsage(e.config.Model, len(texts), resp.PromptEvalCount, 0)
+	return resp.Embeddings, nil
+}
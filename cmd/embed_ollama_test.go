@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_ollamaEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models": [{"name": "nomic-embed-text:latest"}]}`))
+		case "/api/embed":
+			var req ollamaEmbedRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			resp := ollamaEmbedResponse{PromptEvalCount: len(req.Input)}
+			for _, text := range req.Input {
+				resp.Embeddings = append(resp.Embeddings, []float32{float32(len(text))})
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	_, err := newOllamaEmbedder(commons.EmbeddingConfig{Url: server.URL, Model: "mxbai-embed-large"})
+	if err == nil {
+		t.Errorf("expected an error for a model that isn't pulled")
+	}
+
+	e, err := newOllamaEmbedder(commons.EmbeddingConfig{Url: server.URL, Model: "nomic-embed-text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := e.embed(context.Background(), []string{"a", "bb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][0] != 2 {
+		t.Errorf("embed() = %v, want [[1] [2]]", vectors)
+	}
+}
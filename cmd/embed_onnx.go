@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+	ort "github.com/yalue/onnxruntime_go"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// onnxModel is a model of the local embedding provider, downloaded from the ONNX exports FastEmbed uses.
+type onnxModel struct {
+	repo       string
+	file       string
+	dimensions int
+	// Pooling of the token embeddings: "cls" takes the one of the classification token, "mean" averages them.
+	pooling string
+}
+
+var onnxModels = map[string]onnxModel{
+	"BAAI/bge-small-en-v1.5":                 {repo: "qdrant/bge-small-en-v1.5-onnx-q", file: "model_optimized.onnx", dimensions: 384, pooling: "cls"},
+	"BAAI/bge-base-en-v1.5":                  {repo: "qdrant/bge-base-en-v1.5-onnx-q", file: "model_optimized.onnx", dimensions: 768, pooling: "cls"},
+	"sentence-transformers/all-MiniLM-L6-v2": {repo: "qdrant/all-MiniLM-L6-v2-onnx", file: "model.onnx", dimensions: 384, pooling: "mean"},
+}
+
+var huggingFaceUrl = "https://huggingface.co"
+
+// onnxEnvironment loads the ONNX Runtime library once per process.
+var onnxEnvironment struct {
+	once sync.Once
+	err  error
+}
+
+// onnxEmbedder runs an embedding model locally with ONNX Runtime, so no API key or per-token cost is needed.
+type onnxEmbedder struct {
+	model     onnxModel
+	name      string
+	tokenizer *wordPieceTokenizer
+	session   *ort.DynamicAdvancedSession
+	inputs    []string
+}
+
+func newONNXEmbedder(config commons.EmbeddingConfig) (*onnxEmbedder, error) {
+	model, ok := onnxModels[config.Model]
+	if !ok {
+		names := make([]string, 0, len(onnxModels))
+		for name := range onnxModels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown local embedding model %q, supported are: %s", config.Model, strings.Join(names, ", "))
+	}
+
+	onnxEnvironment.once.Do(func() {
+		library := config.OnnxRuntime
+		if library == "" {
+			library = defaultONNXRuntimeLibrary()
+		}
+		ort.SetSharedLibraryPath(library)
+		onnxEnvironment.err = ort.InitializeEnvironment()
+	})
+	if onnxEnvironment.err != nil {
+		return nil, fmt.Errorf("failed to load ONNX Runtime, install it or set --migration.embed.onnx-runtime: %w", onnxEnvironment.err)
+	}
+
+	dir, err := modelCacheDir(config.CacheDir, model.repo)
+	if err != nil {
+		return nil, err
+	}
+	modelPath, err := downloadModelFile(dir, model.repo, model.file)
+	if err != nil {
+		return nil, err
+	}
+	tokenizerPath, err := downloadModelFile(dir, model.repo, "tokenizer.json")
+	if err != nil {
+		return nil, err
+	}
+
+	tokenizer, err := loadWordPieceTokenizer(tokenizerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Not every model takes token type IDs.
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model inputs: %w", err)
+	}
+	var inputs []string
+	for _, info := range inputInfo {
+		inputs = append(inputs, info.Name)
+	}
+	session, err := ort.NewDynamicAdvancedSession(modelPath, inputs, []string{outputInfo[0].Name}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load model: %w", err)
+	}
+
+	return &onnxEmbedder{model: model, name: config.Model, tokenizer: tokenizer, session: session, inputs: inputs}, nil
+}
+
+func (e *onnxEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Texts are padded to the longest one of the batch.
+	encoded := make([][]int64, len(texts))
+	length, tokens := 0, 0
+	for i, text := range texts {
+		encoded[i] = e.tokenizer.encode(text)
+		length = max(length, len(encoded[i]))
+		tokens += len(encoded[i])
+	}
+
+	ids := make([]int64, 0, len(texts)*length)
+	mask := make([]int64, 0, len(texts)*length)
+	for _, tokenIDs := range encoded {
+		ids = append(ids, tokenIDs...)
+		mask = append(mask, slices.Repeat([]int64{1}, len(tokenIDs))...)
+		for range length - len(tokenIDs) {
+			ids = append(ids, e.tokenizer.padID)
+			mask = append(mask, 0)
+		}
+	}
+
+	shape := ort.NewShape(int64(len(texts)), int64(length))
+	inputs := make([]ort.Value, 0, len(e.inputs))
+	defer func() {
+		for _, input := range inputs {
+			_ = input.Destroy()
+		}
+	}()
+	for _, name := range e.inputs {
+		data := make([]int64, len(ids))
+		switch name {
+		case "input_ids":
+			copy(data, ids)
+		case "attention_mask":
+			copy(data, mask)
+		}
+		tensor, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create input tensor: %w", err)
+		}
+		inputs = append(inputs, tensor)
+	}
+
+	outputs := []ort.Value{nil}
+	err := e.session.Run(inputs, outputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run model: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	output, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("unexpected model output of type %T", outputs[0])
+	}
+
+	vectors := poolEmbeddings(output.GetData(), mask, len(texts), length, e.model.dimensions, e.model.pooling)
+	currentReport.addEmbeddingUsage(e.name, len(texts), tokens, 0)
+	return vectors, nil
+}
+
+// poolEmbeddings turns the embeddings of the tokens of every text into one normalized embedding per text.
+func poolEmbeddings(hidden []float32, mask []int64, batch, length, dimensions int, pooling string) [][]float32 {
+	vectors := make([][]float32, batch)
+	for i := range batch {
+		vector := make([]float32, dimensions)
+		if pooling == "cls" {
+			copy(vector, hidden[i*length*dimensions:])
+		} else {
+			var count float32
+			for token := range length {
+				if mask[i*length+token] == 0 {
+					continue
+				}
+				count++
+				offset := (i*length + token) * dimensions
+				for d := range dimensions {
+					vector[d] += hidden[offset+d]
+				}
+			}
+			for d := range vector {
+				vector[d] /= max(count, 1)
+			}
+		}
+
+		var norm float64
+		for _, v := range vector {
+			norm += float64(v) * float64(v)
+		}
+		if norm = math.Sqrt(norm); norm > 0 {
+			for d := range vector {
+				vector[d] = float32(float64(vector[d]) / norm)
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors
+}
+
+func defaultONNXRuntimeLibrary() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "onnxruntime.dll"
+	case "darwin":
+		return "libonnxruntime.dylib"
+	default:
+		return "libonnxruntime.so"
+	}
+}
+
+// modelCacheDir returns the directory the files of a model are kept in, by default in the cache directory of the user.
+func modelCacheDir(cacheDir, repo string) (string, error) {
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find cache directory, set --migration.embed.cache-dir: %w", err)
+		}
+		cacheDir = filepath.Join(userCacheDir, "qdrant-migration", "models")
+	}
+	dir := filepath.Join(cacheDir, filepath.FromSlash(repo))
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create model cache directory: %w", err)
+	}
+	return dir, nil
+}
+
+// downloadModelFile downloads a file of a model from Hugging Face, unless it's in the cache already.
+func downloadModelFile(dir, repo, file string) (string, error) {
+	path := filepath.Join(dir, file)
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+
+	pterm.Info.Printfln("Downloading %s of %s", file, repo)
+	resp, err := http.Get(fmt.Sprintf("%s/%s/resolve/main/%s", huggingFaceUrl, repo, file))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", file, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", file, resp.Status)
+	}
+
+	// Downloaded next to the final file first, so an interrupted download isn't taken for a complete one.
+	temp, err := os.CreateTemp(dir, file+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", file, err)
+	}
+	defer os.Remove(temp.Name())
+	_, err = io.Copy(temp, resp.Body)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", file, err)
+	}
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		return "", fmt.Errorf("failed to store %s: %w", file, err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_wordPieceTokenizer(t *testing.T) {
+	vocab := map[string]int64{"[PAD]": 0, "[UNK]": 1, "[CLS]": 2, "[SEP]": 3, "un": 4, "##aff": 5, "##able": 6, "cafe": 7, "!": 8, "世": 9}
+	data, err := json.Marshal(map[string]any{
+		"normalizer": map[string]any{"type": "BertNormalizer", "lowercase": true},
+		"model":      map[string]any{"type": "WordPiece", "unk_token": "[UNK]", "continuing_subword_prefix": "##", "vocab": vocab},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenizer, err := loadWordPieceTokenizer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want []int64
+	}{
+		{text: "Unaffable!", want: []int64{2, 4, 5, 6, 8, 3}},
+		{text: "  Café\tunknown ", want: []int64{2, 7, 1, 3}},
+		{text: "世界", want: []int64{2, 9, 1, 3}},
+		{text: "", want: []int64{2, 3}},
+	}
+	for _, tt := range tests {
+		if got := tokenizer.encode(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("encode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	tokenizer.maxLength = 4
+	if got := tokenizer.encode("unaffable unaffable"); !reflect.DeepEqual(got, []int64{2, 4, 5, 3}) {
+		t.Errorf("encode() = %v, want it truncated to [2 4 5 3]", got)
+	}
+}
+
+func Test_poolEmbeddings(t *testing.T) {
+	// Two texts of two tokens with two dimensions, the second token of the second text is padding.
+	hidden := []float32{3, 4, 1, 1, 0, 2, 9, 9}
+	mask := []int64{1, 1, 1, 0}
+
+	cls := poolEmbeddings(hidden, mask, 2, 2, 2, "cls")
+	mean := poolEmbeddings(hidden, mask, 2, 2, 2, "mean")
+
+	approx := func(got []float32, want ...float64) bool {
+		for i := range want {
+			if math.Abs(float64(got[i])-want[i]) > 1e-6 {
+				return false
+			}
+		}
+		return true
+	}
+	if !approx(cls[0], 0.6, 0.8) || !approx(cls[1], 0, 1) {
+		t.Errorf("cls pooling = %v", cls)
+	}
+	// The mean of (3, 4) and (1, 1) is (2, 2.5), the padding of the second text is ignored.
+	if !approx(mean[0], 2/math.Sqrt(10.25), 2.5/math.Sqrt(10.25)) || !approx(mean[1], 0, 1) {
+		t.Errorf("mean pooling = %v", mean)
+	}
+}
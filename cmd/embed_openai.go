@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// openAIEmbedder calls an OpenAI-compatible /embeddings endpoint, like the ones of OpenAI, Azure OpenAI, vLLM or LiteLLM.
+type openAIEmbedder struct {
+	config commons.EmbeddingConfig
+	client *http.Client
+}
+
+func newOpenAIEmbedder(config commons.EmbeddingConfig) *openAIEmbedder {
+	return &openAIEmbedder{config: config, client: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+type openAIEmbeddingRequest struct {
+	Model      string   `json:"model,omitempty"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+func (e *openAIEmbedder) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	endpoint := strings.TrimSuffix(e.config.Url, "/") + "/embeddings"
+	header := http.Header{}
+	if e.config.APIVersion != "" {
+		endpoint += "?api-version=" + url.QueryEscape(e.config.APIVersion)
+	}
+	if e.config.APIKey != "" {
+		// Azure OpenAI expects the key in its own header.
+		if e.config.APIVersion != "" {
+			header.Set("api-key", e.config.APIKey)
+		} else {
+			header.Set("Authorization", "Bearer "+e.config.APIKey)
+		}
+	}
+
+	var resp openAIEmbeddingResponse
+	request := openAIEmbeddingRequest{Model: e.config.Model, Input: texts, Dimensions: e.config.Dimensions}
+	err := postEmbeddingRequest(ctx, e.client, endpoint, header, request, &resp, e.config.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	// The embeddings are in the order of the input, but the index is authoritative.
+	sort.Slice(resp.Data, func(i, j int) bool { return resp.Data[i].Index < resp.Data[j].Index })
+	vectors := make([][]float32, len(resp.Data))
+	for i, data := range resp.Data {
+		vectors[i] = data.Embedding
+	}
+
+	currentReport.addEmbeddingUsage(e.config.Model, len(texts), resp.Usage.TotalTokens, e.config.PricePerMillionTokens)
+	return vectors, nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_embedPoints(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request to %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req openAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		resp := openAIEmbeddingResponse{}
+		resp.Usage.TotalTokens = len(req.Input)
+		// In reverse, to check that the index is honored.
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, []float32{float32(len(req.Input[i])), 1}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	points := []*qdrant.PointStruct{
+		{Payload: qdrant.NewValueMap(map[string]any{"text": "a"}), Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{"old": qdrant.NewVectorDense([]float32{0})})},
+		{Payload: qdrant.NewValueMap(map[string]any{"text": "bb"})},
+		{Payload: qdrant.NewValueMap(map[string]any{"text": "ccc"})},
+		{Payload: qdrant.NewValueMap(map[string]any{"other": "d"})},
+	}
+	config := commons.EmbeddingConfig{Field: "text", Vector: "new", Provider: "openai", Url: server.URL + "/v1", APIKey: "key", BatchSize: 2}
+
+	err := embedPoints(context.Background(), points, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("got %d requests, want 2 batches", requests)
+	}
+	for i, want := range []float32{1, 2, 3} {
+		got := points[i].GetVectors().GetVectors().GetVectors()["new"].GetData()
+		if len(got) != 2 || got[0] != want {
+			t.Errorf("point %d has embedding %v, want [%v 1]", i, got, want)
+		}
+	}
+	if points[0].GetVectors().GetVectors().GetVectors()["old"] == nil {
+		t.Errorf("point 0 lost its other vector")
+	}
+	if points[3].GetVectors() != nil {
+		t.Errorf("point without text got a vector")
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{header: "", want: 0},
+		{header: "3", want: 3 * time.Second},
+		{header: "soon", want: 0},
+		{header: "Mon, 01 Jan 2001 00:00:00 GMT", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// endpointsScheme is the gRPC scheme of connections that are spread over several nodes of a cluster.
+const endpointsScheme = "qdrant-endpoints"
+
+// Interval between measurements of the latency of the nodes of a connection with --*.endpoints or --*.resolve-nodes.
+var endpointCheckInterval = 30 * time.Second
+
+// Number of health checks the latency of a node is measured with. The fastest one counts, after one to connect.
+const endpointProbes = 3
+
+// endpoint is a node of a cluster, with the latency measured to it.
+type endpoint struct {
+	host string
+	port int
+	// serverName is the name the node is verified with over TLS, the host of its URL even if it was resolved to an IP.
+	serverName string
+	latency    time.Duration
+	err        error
+}
+
+func (e endpoint) address() string {
+	return net.JoinHostPort(e.host, strconv.Itoa(e.port))
+}
+
+// endpointPool measures the latency to the nodes of a cluster and tracks which of them answer.
+type endpointPool struct {
+	host         string
+	port         int
+	config       commons.QdrantConfig
+	useTLS       bool
+	tlsConfig    *tls.Config
+	probeOptions []grpc.DialOption
+	// probes are the clients that health checks are sent with, by address of the node.
+	probes map[string]*qdrant.Client
+	// down are the nodes that didn't answer the last health checks, by address.
+	down map[string]bool
+}
+
+// spreadOverEndpoints returns the host to connect to and the options to spread the calls of a connection
+// over the nodes with the lowest latency among the one of the URL, all of its addresses with --*.resolve-nodes,
+// and the ones of --*.endpoints. Calls go to the nodes round-robin, skipping the ones that are down,
+// and the nodes are resolved and measured again every endpointCheckInterval, so the calls move to other nodes
+// when the closest ones fail or slow down, and to new nodes as they appear.
+func spreadOverEndpoints(globals *Globals, host string, port int, config commons.QdrantConfig, useTLS bool, dialer contextDialer) (string, []grpc.DialOption, error) {
+	tlsConfig, err := getTLSConfig(globals, config, useTLS)
+	if err != nil {
+		return "", nil, err
+	}
+	pool := &endpointPool{
+		host:      host,
+		port:      port,
+		config:    config,
+		useTLS:    useTLS,
+		tlsConfig: tlsConfig,
+		probes:    map[string]*qdrant.Client{},
+		down:      map[string]bool{},
+	}
+	if dialer != nil {
+		pool.probeOptions = append(pool.probeOptions, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer(ctx, "tcp", addr)
+		}))
+	}
+
+	ctx := globals.baseContext()
+	nodes, err := pool.measure(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	closest := closestEndpoints(nodes, config.LatencyTolerance)
+	if len(closest) == 0 {
+		return "", nil, fmt.Errorf("none of the nodes of %s answered", config.Url)
+	}
+	pterm.Info.Printfln("Spreading calls to %s over %s", config.Url, describeEndpoints(closest))
+
+	endpoints := manual.NewBuilderWithScheme(endpointsScheme)
+	endpoints.InitialState(endpointsState(closest))
+	go func() {
+		ticker := time.NewTicker(endpointCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			nodes, err := pool.measure(ctx)
+			if err != nil {
+				pterm.Warning.Printfln("Can't check the nodes of %s, keeping %s: %v", config.Url, describeEndpoints(closest), err)
+				continue
+			}
+			next := closestEndpoints(nodes, config.LatencyTolerance)
+			switch {
+			case len(next) == 0:
+				pterm.Warning.Printfln("None of the nodes of %s answered, keeping %s", config.Url, describeEndpoints(closest))
+			case !sameEndpoints(next, closest):
+				pterm.Info.Printfln("Moving calls to %s over to %s", config.Url, describeEndpoints(next))
+				closest = next
+				endpoints.UpdateState(endpointsState(closest))
+			}
+		}
+	}()
+
+	options := []grpc.DialOption{
+		grpc.WithResolvers(endpoints),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingConfig": [{"round_robin": {}}]}`),
+	}
+	return endpointsScheme + ":///" + grpcHost(host), options, nil
+}
+
+// nodes returns the nodes of the cluster: the ones of the SRV records of the URL, or the one of the URL,
+// or all of its addresses with --*.resolve-nodes, and the ones of --*.endpoints.
+func (p *endpointPool) nodes(ctx context.Context) ([]endpoint, error) {
+	var nodes []endpoint
+	if isSrvUrl(p.config.Url) {
+		var err error
+		nodes, err = lookupSrvNodes(ctx, p.host)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.config.ResolveNodes {
+		addresses, err := net.DefaultResolver.LookupHost(ctx, p.host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve nodes of %s: %w", p.host, err)
+		}
+		slices.Sort(addresses)
+		for _, address := range addresses {
+			nodes = append(nodes, endpoint{host: address, port: p.port, serverName: p.host})
+		}
+	} else {
+		nodes = append(nodes, endpoint{host: p.host, port: p.port, serverName: p.host})
+	}
+
+	for _, endpointUrl := range p.config.Endpoints {
+		host, port, useTLS, err := parseQdrantUrl(endpointUrl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse endpoint URL %q: %w", endpointUrl, err)
+		}
+		if useTLS != p.useTLS || port == 0 {
+			return nil, fmt.Errorf("endpoint %q must be a node with the same scheme as %s", endpointUrl, p.config.Url)
+		}
+		nodes = append(nodes, endpoint{host: host, port: port, serverName: host})
+	}
+	return nodes, nil
+}
+
+// probe returns the client to send health checks to a node with.
+func (p *endpointPool) probe(node endpoint) (*qdrant.Client, error) {
+	if client, ok := p.probes[node.address()]; ok {
+		return client, nil
+	}
+	tlsConfig := p.tlsConfig
+	if node.serverName != node.host {
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = node.serverName
+	}
+	client, err := qdrant.NewClient(&qdrant.Config{
+		Host:                   grpcHost(node.host),
+		Port:                   node.port,
+		APIKey:                 p.config.APIKey,
+		UseTLS:                 p.useTLS,
+		TLSConfig:              tlsConfig,
+		GrpcOptions:            p.probeOptions,
+		SkipCompatibilityCheck: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client for node %s: %w", node.address(), err)
+	}
+	p.probes[node.address()] = client
+	return client, nil
+}
+
+// prune closes the probes of the nodes that are no longer part of the cluster, e.g. ones that no longer resolve,
+// and forgets whether they were down.
+func (p *endpointPool) prune(nodes []endpoint) {
+	current := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		current[node.address()] = true
+	}
+	for address, client := range p.probes {
+		if current[address] {
+			continue
+		}
+		_ = client.Close()
+		delete(p.probes, address)
+		if p.down[address] {
+			pterm.Info.Printfln("Node %s of %s is no longer part of the cluster", address, p.config.Url)
+			delete(p.down, address)
+		}
+	}
+}
+
+// measure measures the latency to every node of the cluster, and reports the nodes that stopped or started answering.
+func (p *endpointPool) measure(ctx context.Context) ([]endpoint, error) {
+	nodes, err := p.nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.prune(nodes)
+	clients := make([]*qdrant.Client, len(nodes))
+	for i, node := range nodes {
+		clients[i], err = p.probe(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nodes[i].latency, nodes[i].err = measureLatency(ctx, clients[i])
+		}()
+	}
+	wg.Wait()
+
+	for _, node := range nodes {
+		switch {
+		case node.err != nil && !p.down[node.address()]:
+			pterm.Warning.Printfln("Node %s of %s doesn't answer: %v", node.address(), p.config.Url, node.err)
+			p.down[node.address()] = true
+		case node.err == nil && p.down[node.address()]:
+			pterm.Info.Printfln("Node %s of %s answers again", node.address(), p.config.Url)
+			delete(p.down, node.address())
+		}
+	}
+	return nodes, nil
+}
+
+// measureLatency measures the latency to a node with a few health checks.
+func measureLatency(ctx context.Context, client *qdrant.Client) (time.Duration, error) {
+	latency := time.Duration(0)
+	for attempt := 0; attempt <= endpointProbes; attempt++ {
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		start := time.Now()
+		_, err := client.HealthCheck(checkCtx)
+		elapsed := time.Since(start)
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		// The first check also connects to the node.
+		if attempt > 0 && (latency == 0 || elapsed < latency) {
+			latency = elapsed
+		}
+	}
+	return latency, nil
+}
+
+// closestEndpoints returns the nodes that answered, with a latency of at most the tolerance over the one of the fastest,
+// from the fastest to the slowest.
+func closestEndpoints(endpoints []endpoint, tolerance time.Duration) []endpoint {
+	var healthy []endpoint
+	for _, endpoint := range endpoints {
+		if endpoint.err == nil {
+			healthy = append(healthy, endpoint)
+		}
+	}
+	slices.SortStableFunc(healthy, func(a, b endpoint) int {
+		return cmp.Compare(a.latency, b.latency)
+	})
+	for i, endpoint := range healthy {
+		if endpoint.latency > healthy[0].latency+tolerance {
+			return healthy[:i]
+		}
+	}
+	return healthy
+}
+
+// sameEndpoints reports whether two lists have the same nodes, in any order.
+func sameEndpoints(a, b []endpoint) bool {
+	addresses := func(endpoints []endpoint) []string {
+		var addresses []string
+		for _, endpoint := range endpoints {
+			addresses = append(addresses, endpoint.address())
+		}
+		slices.Sort(addresses)
+		return addresses
+	}
+	return slices.Equal(addresses(a), addresses(b))
+}
+
+func endpointsState(endpoints []endpoint) resolver.State {
+	var state resolver.State
+	for _, endpoint := range endpoints {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: endpoint.address(), ServerName: endpoint.serverName})
+	}
+	return state
+}
+
+// describeEndpoints lists nodes with their latencies.
+func describeEndpoints(endpoints []endpoint) string {
+	described := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		described = append(described, fmt.Sprintf("%s (%s)", endpoint.address(), endpoint.latency.Round(100*time.Microsecond)))
+	}
+	return strings.Join(described, ", ")
+}
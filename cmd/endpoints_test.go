@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type countingQdrantServer struct {
+	qdrant.UnimplementedQdrantServer
+	calls atomic.Int64
+}
+
+func (s *countingQdrantServer) HealthCheck(context.Context, *qdrant.HealthCheckRequest) (*qdrant.HealthCheckReply, error) {
+	s.calls.Add(1)
+	return &qdrant.HealthCheckReply{Title: "qdrant", Version: "1.14.0"}, nil
+}
+
+func startQdrantNode(t *testing.T) (string, *countingQdrantServer) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	node := &countingQdrantServer{}
+	qdrant.RegisterQdrantServer(server, node)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return "http://" + listener.Addr().String(), node
+}
+
+func TestConnectToQdrantEndpoints(t *testing.T) {
+	firstUrl, first := startQdrantNode(t)
+	secondUrl, second := startQdrantNode(t)
+
+	host, port, useTLS, err := parseQdrantUrl(firstUrl)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+	config := commons.QdrantConfig{Url: firstUrl, Endpoints: []string{secondUrl}, LatencyTolerance: time.Second}
+	client, err := connectToQdrant(&Globals{}, host, port, config, useTLS)
+	if err != nil {
+		t.Fatalf("connectToQdrant() error = %v", err)
+	}
+	defer client.Close()
+
+	firstBefore, secondBefore := first.calls.Load(), second.calls.Load()
+	for range 10 {
+		if _, err := client.HealthCheck(context.Background()); err != nil {
+			t.Fatalf("HealthCheck() error = %v", err)
+		}
+	}
+	if first.calls.Load() == firstBefore || second.calls.Load() == secondBefore {
+		t.Errorf("calls weren't spread over both nodes: %d and %d", first.calls.Load()-firstBefore, second.calls.Load()-secondBefore)
+	}
+}
+
+func TestConnectToQdrantResolveNodes(t *testing.T) {
+	nodeUrl, node := startQdrantNode(t)
+	_, port, _, err := parseQdrantUrl(nodeUrl)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+
+	config := commons.QdrantConfig{Url: fmt.Sprintf("http://localhost:%d", port), ResolveNodes: true, LatencyTolerance: time.Second}
+	client, err := connectToQdrant(&Globals{}, "localhost", port, config, false)
+	if err != nil {
+		t.Fatalf("connectToQdrant() error = %v", err)
+	}
+	defer client.Close()
+
+	// localhost may also resolve to ::1, which the node doesn't listen on, so calls only go to 127.0.0.1.
+	before := node.calls.Load()
+	for range 5 {
+		if _, err := client.HealthCheck(context.Background()); err != nil {
+			t.Fatalf("HealthCheck() error = %v", err)
+		}
+	}
+	if got := node.calls.Load() - before; got != 5 {
+		t.Errorf("node got %d calls, want 5", got)
+	}
+}
+
+func TestEndpointPoolPrune(t *testing.T) {
+	firstUrl, _ := startQdrantNode(t)
+	secondUrl, _ := startQdrantNode(t)
+	host, port, _, err := parseQdrantUrl(firstUrl)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+	_, secondPort, _, err := parseQdrantUrl(secondUrl)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+
+	pool := &endpointPool{
+		host:   host,
+		port:   port,
+		config: commons.QdrantConfig{Url: firstUrl, Endpoints: []string{secondUrl}},
+		probes: map[string]*qdrant.Client{},
+		down:   map[string]bool{},
+	}
+	if _, err := pool.measure(context.Background()); err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+	if len(pool.probes) != 2 {
+		t.Fatalf("got %d probes, want one per node", len(pool.probes))
+	}
+
+	// The second node is gone, e.g. because it no longer resolves.
+	pool.config.Endpoints = nil
+	pool.down[net.JoinHostPort(host, fmt.Sprint(secondPort))] = true
+	if _, err := pool.measure(context.Background()); err != nil {
+		t.Fatalf("measure() error = %v", err)
+	}
+	if len(pool.probes) != 1 || len(pool.down) != 0 {
+		t.Errorf("got probes %v and down nodes %v, want only the probe of the first node", pool.probes, pool.down)
+	}
+}
+
+func Test_closestEndpoints(t *testing.T) {
+	endpoints := []endpoint{
+		{host: "far", port: 6334, latency: 80 * time.Millisecond},
+		{host: "near", port: 6334, latency: 2 * time.Millisecond},
+		{host: "down", port: 6334, err: errors.New("connection refused")},
+		{host: "nearby", port: 6334, latency: 9 * time.Millisecond},
+	}
+
+	tests := []struct {
+		name      string
+		tolerance time.Duration
+		want      []string
+	}{
+		{"closest only", 0, []string{"near"}},
+		{"within tolerance", 10 * time.Millisecond, []string{"near", "nearby"}},
+		{"all healthy", time.Second, []string{"near", "nearby", "far"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, endpoint := range closestEndpoints(endpoints, tt.tolerance) {
+				got = append(got, endpoint.host)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("closestEndpoints() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("closestEndpoints() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func Test_sameEndpoints(t *testing.T) {
+	a := []endpoint{{host: "a", port: 6334}, {host: "b", port: 6334}}
+	if !sameEndpoints(a, []endpoint{a[1], a[0]}) {
+		t.Errorf("sameEndpoints() = false for the same nodes in another order")
+	}
+	if sameEndpoints(a, a[:1]) {
+		t.Errorf("sameEndpoints() = true for different nodes")
+	}
+}
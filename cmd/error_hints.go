@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// errorSignature matches the errors one hint is for: by gRPC status code if it's set, and by any of the snippets
+// of the message if there are any. Both are needed when both are set.
+type errorSignature struct {
+	code     codes.Code
+	snippets []string
+	hint     string
+}
+
+// errorSignatures are the common errors of connections to Qdrant and other services, with what usually causes them.
+// The first matching one counts, so more specific signatures come before more general ones.
+var errorSignatures = []errorSignature{
+	{
+		snippets: []string{"malformed header", "frame too large", "unexpected http status", `content-type "text/html`, `content-type "application/json`, "http2: failed reading the frame payload"},
+		hint:     "The port doesn't speak gRPC. It may be the REST port of Qdrant, 6333 by default, instead of the gRPC port, 6334 by default, or a proxy that doesn't forward gRPC. `migration doctor --url ...` tells which",
+	},
+	{
+		snippets: []string{"tls: first record does not look like", "server gave http response to https client", "tls: oversized record"},
+		hint:     "The URL uses https, but the port doesn't speak TLS. Use an http URL",
+	},
+	{
+		snippets: []string{"x509: certificate signed by unknown authority", "certificate is not trusted"},
+		hint:     "The TLS certificate of the server isn't signed by a trusted authority. Pass its CA certificate with --<prefix>.ca-cert, or skip the verification with --skip-tls-verification",
+	},
+	{
+		snippets: []string{"x509: certificate is valid for", "x509: certificate is not valid for"},
+		hint:     "The TLS certificate of the server is for another hostname. Use a URL with a name of the certificate",
+	},
+	{
+		snippets: []string{"x509: certificate has expired"},
+		hint:     "The TLS certificate of the server has expired, or the clock of this machine is off",
+	},
+	{
+		snippets: []string{"goaway"},
+		hint:     "The server closed the connection, e.g. on a restart, a rolling update or the idle timeout of a load balancer. Run the migration again to continue from its last checkpoint, or set --stall-timeout to reconnect on its own",
+	},
+	{
+		snippets: []string{"connection refused"},
+		hint:     "Nothing listens on the port. Check the host and port of the URL, Qdrant listens for gRPC on 6334 by default",
+	},
+	{
+		snippets: []string{"no such host", "server misbehaving"},
+		hint:     "The hostname doesn't resolve. Check the URL and the DNS of this machine",
+	},
+	{
+		snippets: []string{"connection reset by peer", "broken pipe", "unexpected eof", ": eof"},
+		hint:     "The connection was cut, e.g. by a restart of the server, a proxy or a firewall. Run the migration again to continue from its last checkpoint",
+	},
+	{
+		code:     codes.ResourceExhausted,
+		snippets: []string{"larger than max", "too large", "message size"},
+		hint:     "A message exceeded the size limit. Lower --migration.batch-size, or raise --<prefix>.max-message-size for responses",
+	},
+	{
+		code:     codes.ResourceExhausted,
+		snippets: []string{"rate limit"},
+		hint:     "The server limits the rate of requests, e.g. under strict mode. Lower --migration.batch-size, or pace writes with --migration.health-check-interval",
+	},
+	{
+		code: codes.ResourceExhausted,
+		hint: "The server ran out of resources, e.g. memory or disk, or hit a limit of strict mode. Check its resources, or slow writes down with --migration.health-check-interval",
+	},
+	{
+		code: codes.DeadlineExceeded,
+		hint: "A call didn't finish in time. The server may be overloaded or far away: lower --migration.batch-size, or raise --grpc-call-timeout",
+	},
+	{
+		snippets: []string{"context deadline exceeded", "i/o timeout"},
+		hint:     "The server didn't answer in time. A firewall may drop the connection, or the server may be overloaded",
+	},
+	{
+		code: codes.Unauthenticated,
+		hint: "The API key is missing or invalid. Check --<prefix>.api-key",
+	},
+	{
+		code: codes.PermissionDenied,
+		hint: "The API key lacks access. `migration doctor --url ... --api-key ... --collection ...` tells which",
+	},
+	{
+		code: codes.Unimplemented,
+		hint: "The server doesn't support the call. It may be an older version of Qdrant, or not Qdrant at all",
+	},
+	{
+		code: codes.Unavailable,
+		hint: "The server can't be reached. Check that it's running and that the URL is right, `migration doctor --url ...` tells more",
+	},
+}
+
+// errorHint returns what likely caused an error and how to fix it, or "" if it isn't a common one.
+func errorHint(err error) string {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ""
+	}
+	code := qdrantStatus(err).Code()
+	message := strings.ToLower(err.Error())
+	for _, signature := range errorSignatures {
+		if signature.code != codes.OK && signature.code != code {
+			continue
+		}
+		if len(signature.snippets) == 0 || containsAny(message, signature.snippets) {
+			return signature.hint
+		}
+	}
+	return ""
+}
+
+func containsAny(s string, snippets []string) bool {
+	for _, snippet := range snippets {
+		if strings.Contains(s, snippet) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func Test_errorHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"canceled", fmt.Errorf("failed to scroll: %w", context.Canceled), ""},
+		{"unknown error", errors.New("collection 'docs' has no vector 'image'"), ""},
+		{"REST port", status.Error(codes.Unavailable, `connection error: desc = "error reading server preface: http2: frame too large"`), "doesn't speak gRPC"},
+		{"REST port behind a proxy", status.Error(codes.Unknown, "malformed header: missing HTTP content-type"), "doesn't speak gRPC"},
+		{"plain port over TLS", status.Error(codes.Unavailable, "connection error: desc = \"transport: authentication handshake failed: tls: first record does not look like a TLS handshake\""), "Use an http URL"},
+		{"unknown authority", status.Error(codes.Unavailable, "x509: certificate signed by unknown authority"), "--<prefix>.ca-cert"},
+		{"GOAWAY", fmt.Errorf("failed to upsert: %w", status.Error(codes.Unavailable, "closing transport due to: connection error: desc = \"error reading from server: EOF\", received prior goaway: code: NO_ERROR")), "closed the connection"},
+		{"refused", status.Error(codes.Unavailable, "connection error: desc = \"transport: Error while dialing: dial tcp 127.0.0.1:6334: connect: connection refused\""), "Nothing listens"},
+		{"message too large", fmt.Errorf("failed to upsert: %w", status.Error(codes.ResourceExhausted, "grpc: received message larger than max (38000000 vs. 33554432)")), "--migration.batch-size"},
+		{"rate limited", status.Error(codes.ResourceExhausted, "Rate limiting exceeded: Write rate limit exceeded"), "limits the rate"},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "No space left on device"), "ran out of resources"},
+		{"deadline exceeded", fmt.Errorf("failed to scroll: %w", status.Error(codes.DeadlineExceeded, "context deadline exceeded")), "--grpc-call-timeout"},
+		{"unauthenticated", status.Error(codes.Unauthenticated, "Invalid api-key"), "--<prefix>.api-key"},
+		{"unavailable", status.Error(codes.Unavailable, "name resolver error"), "can't be reached"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorHint(tt.err)
+			if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+				t.Errorf("errorHint() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
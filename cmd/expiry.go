@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// applyExpiry writes the expiry time of every point in --migration.expiry-field to --migration.expiry-target-field
+// as an RFC 3339 timestamp in UTC, so expired points can be deleted from the target with a datetime range filter.
+// With --migration.skip-expired, points that expired before now are left out.
+// It returns the points to migrate. Points without an expiry time, or with one that can't be parsed, are kept as they are.
+func applyExpiry(points []*qdrant.PointStruct, migration commons.MigrationConfig, now time.Time) []*qdrant.PointStruct {
+	field := migration.ExpiryField
+	if field == "" {
+		return points
+	}
+	targetField := migration.ExpiryTargetField
+	if targetField == "" {
+		targetField = field
+	}
+
+	// A datetime directive of the mapping file tells how to read the field, like for any other timestamp.
+	directive, location := "auto", time.UTC
+	if mapping, ok := migration.MappingFile.Fields[field]; ok && mapping.Datetime != "" {
+		directive, location = mapping.Datetime, mapping.Location()
+	}
+
+	var expired uint64
+	result := points[:0:0]
+	for _, point := range points {
+		value := payloadField(point.GetPayload(), field)
+		if value == nil {
+			result = append(result, point)
+			continue
+		}
+		expiresAt, ok := parseDatetime(value, directive, location)
+		if !ok {
+			result = append(result, point)
+			continue
+		}
+		if migration.SkipExpired && !expiresAt.After(now) {
+			expired++
+			continue
+		}
+
+		timestamp := qdrant.NewValueString(expiresAt.UTC().Format(time.RFC3339Nano))
+		if targetField == field {
+			setPayloadField(point.GetPayload(), field, timestamp)
+		} else {
+			deletePayloadField(point.GetPayload(), field)
+			point.Payload[targetField] = timestamp
+		}
+		result = append(result, point)
+	}
+
+	currentReport.addExpired(expired)
+	return result
+}
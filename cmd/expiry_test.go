@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestApplyExpiry(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	newPoints := func() []*qdrant.PointStruct {
+		return []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"expires": "2025-05-01 00:00:00"}})},
+			{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"expires": now.Add(time.Hour).Unix()}})},
+			{Id: qdrant.NewIDNum(3), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"expires": "never"}})},
+			{Id: qdrant.NewIDNum(4), Payload: qdrant.NewValueMap(map[string]any{"other": 1})},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		migration   commons.MigrationConfig
+		wantIDs     []uint64
+		wantField   string
+		wantExpires string
+	}{
+		{
+			name:      "disabled",
+			migration: commons.MigrationConfig{SkipExpired: true},
+			wantIDs:   []uint64{1, 2, 3, 4},
+		},
+		{
+			name:        "normalize in place",
+			migration:   commons.MigrationConfig{ExpiryField: "meta.expires"},
+			wantIDs:     []uint64{1, 2, 3, 4},
+			wantField:   "meta.expires",
+			wantExpires: "2025-05-01T00:00:00Z",
+		},
+		{
+			name:        "skip expired into target field",
+			migration:   commons.MigrationConfig{ExpiryField: "meta.expires", ExpiryTargetField: "expires_at", SkipExpired: true},
+			wantIDs:     []uint64{2, 3, 4},
+			wantField:   "expires_at",
+			wantExpires: "2025-06-01T01:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyExpiry(newPoints(), tt.migration, now)
+
+			var ids []uint64
+			for _, point := range got {
+				ids = append(ids, point.GetId().GetNum())
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("got points %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("got points %v, want %v", ids, tt.wantIDs)
+				}
+			}
+
+			if tt.wantField == "" {
+				return
+			}
+			if got := payloadField(got[0].GetPayload(), tt.wantField).GetStringValue(); got != tt.wantExpires {
+				t.Errorf("%s = %q, want %q", tt.wantField, got, tt.wantExpires)
+			}
+			if tt.wantField != tt.migration.ExpiryField && payloadField(got[0].GetPayload(), tt.migration.ExpiryField) != nil {
+				t.Errorf("%s should be removed", tt.migration.ExpiryField)
+			}
+		})
+	}
+}
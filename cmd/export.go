@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type ExportCmd struct {
+	Qdrant commons.QdrantConfig `embed:"" prefix:"qdrant."`
+	Export commons.ExportConfig `embed:"" prefix:"export."`
+
+	sourceHost string
+	sourcePort int
+	sourceTLS  bool
+}
+
+func (r *ExportCmd) Parse() error {
+	var err error
+	r.sourceHost, r.sourcePort, r.sourceTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse source URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExportCmd) Validate() error {
+	if r.Export.MaxFileSize < 0 {
+		return fmt.Errorf("max file size must not be negative")
+	}
+	return validateBatchSize(r.Export.BatchSize)
+}
+
+func (r *ExportCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Collection Export")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	key, err := loadEncryptionKey(r.Export.EncryptionKey, r.Export.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Qdrant, r.sourceTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant source: %w", err)
+	}
+	defer sourceClient.Close()
+
+	err = checkSourceVersion(ctx, sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = checkSourceAccess(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant source: %w", err)
+	}
+
+	collectionInfo, err := sourceClient.GetCollectionInfo(ctx, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to get source collection info: %w", err)
+	}
+	collectionConfig, err := protojson.Marshal(collectionInfo.GetConfig())
+	if err != nil {
+		return fmt.Errorf("failed to encode collection config: %w", err)
+	}
+
+	destination, err := newExportDestination(ctx, r.Export.Path, r.Export.AWS)
+	if err != nil {
+		return err
+	}
+	// The manifest is written unencrypted, so a load can tell whether it needs a key.
+	fileDestination := destination
+	if key != nil {
+		fileDestination = encryptedDestination{destination: destination, key: key}
+	}
+
+	displayMigrationFromQdrantStart(r.Qdrant.Collection, r.Export.Format, r.Export.Path)
+
+	writer := newExportWriter(fileDestination, r.Qdrant.Collection, r.Export.Format, r.Export.Compression, int64(r.Export.MaxFileSize))
+
+	// Exports always start from scratch, so no offsets are tracked.
+	migration := commons.MigrationConfig{BatchSize: r.Export.BatchSize}
+	err = scrollQdrantSource(ctx, sourceClient, r.Qdrant.Collection, migration, nil, "", func(points []*qdrant.RetrievedPoint) error {
+		records := make([]exportRecord, 0, len(points))
+		for _, point := range points {
+			records = append(records, newExportRecord(point))
+		}
+		return writer.Write(ctx, records)
+	})
+	if err != nil {
+		_ = writer.Close()
+		return fmt.Errorf("failed to export data: %w", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	manifest := exportManifest{
+		Collection:  r.Qdrant.Collection,
+		Format:      r.Export.Format,
+		Compression: r.Export.Compression,
+		Encrypted:   key != nil,
+		Files:       writer.files,
+		Config:      collectionConfig,
+	}
+	for _, file := range writer.files {
+		manifest.PointsCount += file.Points
+	}
+	if key != nil {
+		manifest.MAC, err = manifestMAC(manifest, key)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate manifest: %w", err)
+		}
+	}
+	err = writeExportManifest(ctx, destination, manifest)
+	if err != nil {
+		return err
+	}
+
+	pterm.Info.Printfln("Wrote %d file(s) with %d points to %s\n", writer.part, manifest.PointsCount, r.Export.Path)
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Encrypted export files start with this header, followed by the random nonce prefix of the file.
+// The content is sealed with AES-256-GCM in segments, so files of any size are streamed:
+// every segment is the 4 byte length of its ciphertext and the ciphertext. The nonce of a segment is the prefix,
+// the number of the segment and a flag marking the last one, so reordered, dropped or truncated segments fail to decrypt.
+const (
+	encryptedFileHeader    = "QDRANT-EXPORT-AES256GCM-1\n"
+	encryptedFileExtension = ".enc"
+	encryptionSegmentSize  = 64 * 1024
+	noncePrefixSize        = 7
+)
+
+// loadEncryptionKey returns the 256 bit key given as 64 hex digits or in base64, directly or in a file,
+// or nil if neither is given.
+func loadEncryptionKey(key, keyFile string) ([]byte, error) {
+	if key != "" && keyFile != "" {
+		return nil, fmt.Errorf("only one of the encryption key and the encryption key file can be given")
+	}
+	if keyFile != "" {
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key file: %w", err)
+		}
+		key = string(data)
+	}
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, nil
+	}
+
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(key)
+	}
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, as 64 hex digits or in base64, e.g. generated with 'openssl rand -hex 32'")
+	}
+	return decoded, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func segmentNonce(prefix []byte, segment uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, segment)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// manifestMAC authenticates the manifest of an encrypted export, which is written unencrypted, so that it can't be
+// changed to load the files differently, or be marked unencrypted to load other files instead. It's computed over
+// the manifest without its MAC, with a key derived from the encryption key.
+func manifestMAC(manifest exportManifest, key []byte) (string, error) {
+	manifest.MAC = ""
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	derived := hmac.New(sha256.New, key)
+	derived.Write([]byte("qdrant-export-manifest"))
+	mac := hmac.New(sha256.New, derived.Sum(nil))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifyManifest checks that the manifest of an export was written with the key, and so wasn't modified.
+func verifyManifest(manifest exportManifest, key []byte) error {
+	if !manifest.Encrypted || manifest.MAC == "" {
+		return fmt.Errorf("the export isn't encrypted, but an encryption key was given")
+	}
+	want, err := manifestMAC(manifest, key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(manifest.MAC), []byte(want)) {
+		return fmt.Errorf("the manifest of the export doesn't match the key, the key is wrong or the manifest was modified")
+	}
+	return nil
+}
+
+// encryptedDestination encrypts the files it creates.
+type encryptedDestination struct {
+	destination exportDestination
+	key         []byte
+}
+
+func (d encryptedDestination) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	file, err := d.destination.Create(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := newEncryptingWriter(file, d.key)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+type encryptingWriter struct {
+	file    io.WriteCloser
+	gcm     cipher.AEAD
+	prefix  []byte
+	segment uint32
+	buffer  []byte
+}
+
+func newEncryptingWriter(file io.WriteCloser, key []byte) (*encryptingWriter, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up encryption: %w", err)
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := io.WriteString(file, encryptedFileHeader); err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{file: file, gcm: gcm, prefix: prefix, buffer: make([]byte, 0, encryptionSegmentSize)}, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data follows, since the last segment is sealed differently.
+		if len(w.buffer) == encryptionSegmentSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buffer[len(w.buffer):encryptionSegmentSize], p)
+		w.buffer = w.buffer[:len(w.buffer)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (w *encryptingWriter) seal(last bool) error {
+	ciphertext := w.gcm.Seal(nil, segmentNonce(w.prefix, w.segment, last), w.buffer, nil)
+	if _, err := w.file.Write(binary.BigEndian.AppendUint32(nil, uint32(len(ciphertext)))); err != nil {
+		return err
+	}
+	if _, err := w.file.Write(ciphertext); err != nil {
+		return err
+	}
+	w.segment++
+	w.buffer = w.buffer[:0]
+	return nil
+}
+
+func (w *encryptingWriter) Close() error {
+	if err := w.seal(true); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to encrypt file: %w", err)
+	}
+	return w.file.Close()
+}
+
+// encryptedSource decrypts the files it opens.
+type encryptedSource struct {
+	source exportSource
+	key    []byte
+}
+
+func (s encryptedSource) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	file, err := s.source.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newDecryptingReader(file, s.key)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to decrypt %s: %w", name, err)
+	}
+	return reader, nil
+}
+
+type decryptingReader struct {
+	file    io.ReadCloser
+	gcm     cipher.AEAD
+	prefix  []byte
+	segment uint32
+	pending []byte
+	done    bool
+}
+
+func newDecryptingReader(file io.ReadCloser, key []byte) (*decryptingReader, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(encryptedFileHeader)+noncePrefixSize)
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.HasPrefix(header, []byte(encryptedFileHeader)) {
+		return nil, fmt.Errorf("not an encrypted export file")
+	}
+	return &decryptingReader{file: file, gcm: gcm, prefix: header[len(encryptedFileHeader):]}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// open decrypts the next segment. Every segment but the last is tried as a middle segment first.
+func (r *decryptingReader) open() error {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(r.file, length); err != nil {
+		return fmt.Errorf("encrypted file is truncated: %w", err)
+	}
+	size := binary.BigEndian.Uint32(length)
+	if size > encryptionSegmentSize+uint32(r.gcm.Overhead()) {
+		return errors.New("encrypted file is corrupt")
+	}
+	ciphertext := make([]byte, size)
+	if _, err := io.ReadFull(r.file, ciphertext); err != nil {
+		return fmt.Errorf("encrypted file is truncated: %w", err)
+	}
+
+	plaintext, err := r.gcm.Open(nil, segmentNonce(r.prefix, r.segment, false), ciphertext, nil)
+	if err != nil {
+		plaintext, err = r.gcm.Open(nil, segmentNonce(r.prefix, r.segment, true), ciphertext, nil)
+		if err != nil {
+			return errors.New("failed to decrypt, the key is wrong or the file was modified")
+		}
+		r.done = true
+	}
+	r.segment++
+	r.pending = plaintext
+	return nil
+}
+
+func (r *decryptingReader) Close() error {
+	return r.file.Close()
+}
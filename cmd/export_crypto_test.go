@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func Test_loadEncryptionKey(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		keyFile string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "none"},
+		{name: "hex", key: strings.Repeat("0f", 32), wantLen: 32},
+		{name: "base64", key: "MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE=", wantLen: 32},
+		{name: "file", keyFile: keyFile, wantLen: 32},
+		{name: "too short", key: "0f0f", wantErr: true},
+		{name: "both", key: strings.Repeat("0f", 32), keyFile: keyFile, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := loadEncryptionKey(tt.key, tt.keyFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadEncryptionKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(key) != tt.wantLen {
+				t.Errorf("got a key of %d bytes, want %d", len(key), tt.wantLen)
+			}
+		})
+	}
+}
+
+func Test_encryption(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plaintext := bytes.Repeat([]byte("vectors and payloads "), 10000)
+
+	var encrypted bytes.Buffer
+	writer, err := newEncryptingWriter(nopWriteCloser{&encrypted}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("vectors")) {
+		t.Fatal("encrypted file contains the plaintext")
+	}
+
+	decrypt := func(data, key []byte) ([]byte, error) {
+		reader, err := newDecryptingReader(io.NopCloser(bytes.NewReader(data)), key)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+
+	decrypted, err := decrypt(encrypted.Bytes(), key)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("decrypted %d bytes, error %v, want the %d bytes of plaintext", len(decrypted), err, len(plaintext))
+	}
+
+	if _, err := decrypt(encrypted.Bytes(), bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Error("decrypting with the wrong key succeeded")
+	}
+	// A file cut off after its first segment must not pass as complete.
+	firstSegment := len(encryptedFileHeader) + noncePrefixSize + 4 + encryptionSegmentSize + 16
+	if _, err := decrypt(encrypted.Bytes()[:firstSegment], key); err == nil {
+		t.Error("decrypting a truncated file succeeded")
+	}
+}
+
+func Test_encryptedExport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+
+	writer := newExportWriter(encryptedDestination{destination: localDestination{dir: dir}, key: key}, "test", exportFormatJSONL, exportCompressionZstd, 0)
+	if err := writer.Write(ctx, testExportRecords(5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if name := writer.files[0].Name; name != "test-00000.jsonl.zst.enc" {
+		t.Errorf("got file %s, want test-00000.jsonl.zst.enc", name)
+	}
+
+	count := 0
+	err := readExportFile(ctx, encryptedSource{source: localSource{dir: dir}, key: key}, writer.files[0].Name, exportFormatJSONL, 10, 0, func(batch []exportRecord) error {
+		count += len(batch)
+		return nil
+	})
+	if err != nil || count != 5 {
+		t.Errorf("read %d records, error %v, want 5", count, err)
+	}
+}
+
+func Test_encryptedParquetExport(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+
+	writer := newExportWriter(encryptedDestination{destination: localDestination{dir: dir}, key: key}, "test", exportFormatParquet, exportCompressionZstd, 0)
+	if err := writer.Write(ctx, testExportRecords(5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	err := readExportFile(ctx, encryptedSource{source: localSource{dir: dir}, key: key}, writer.files[0].Name, exportFormatParquet, 10, 0, func(batch []exportRecord) error {
+		count += len(batch)
+		return nil
+	})
+	if err != nil || count != 5 {
+		t.Errorf("read %d records, error %v, want 5", count, err)
+	}
+}
+
+func Test_verifyManifest(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+
+	manifest := exportManifest{
+		Collection: "test",
+		Format:     exportFormatJSONL,
+		Encrypted:  true,
+		Files:      []exportManifestFile{{Name: "test-00000.jsonl.gz.enc", Points: 2, FirstID: uint64(1) << 60, LastID: "3fa85f64-5717-4562-b3fc-2c963f66afa6"}},
+		Config:     []byte(`{"params": {"shard_number": 1}}`),
+	}
+	var err error
+	manifest.MAC, err = manifestMAC(manifest, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeExportManifest(ctx, localDestination{dir: dir}, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readExportManifest(ctx, localSource{dir: dir}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyManifest(*read, key); err != nil {
+		t.Errorf("verifyManifest() error = %v for the manifest as written", err)
+	}
+	if err := verifyManifest(*read, bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Error("verifyManifest() succeeded with another key")
+	}
+
+	modified := *read
+	modified.Files = append([]exportManifestFile{}, read.Files...)
+	modified.Files[0].Name = "other-00000.jsonl.gz.enc"
+	if err := verifyManifest(modified, key); err == nil {
+		t.Error("verifyManifest() succeeded for a modified manifest")
+	}
+
+	unencrypted := *read
+	unencrypted.Encrypted = false
+	unencrypted.MAC = ""
+	if err := verifyManifest(unencrypted, key); err == nil {
+		t.Error("verifyManifest() succeeded for an unencrypted export given a key")
+	}
+}
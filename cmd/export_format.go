@@ -0,0 +1,415 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+	parquetzstd "github.com/parquet-go/parquet-go/compress/zstd"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+const (
+	exportFormatJSONL   = "jsonl"
+	exportFormatParquet = "parquet"
+
+	exportCompressionGzip = "gzip"
+	exportCompressionZstd = "zstd"
+)
+
+// exportRecord is a single point in the portable export format.
+// IDs are kept as numbers or UUID strings, so they round trip unchanged.
+type exportRecord struct {
+	ID            any                     `json:"id"`
+	Vectors       map[string][]float32    `json:"vectors,omitempty"`
+	SparseVectors map[string]sparseVector `json:"sparse_vectors,omitempty"`
+	Payload       map[string]any          `json:"payload,omitempty"`
+}
+
+// parquetRecord is the Parquet representation of an exportRecord.
+// Payloads are schemaless, so they are stored as JSON strings.
+type parquetRecord struct {
+	ID            string                  `parquet:"id"`
+	Vectors       map[string][]float32    `parquet:"vectors"`
+	SparseVectors map[string]sparseVector `parquet:"sparse_vectors"`
+	Payload       string                  `parquet:"payload"`
+}
+
+func newExportRecord(point *qdrant.RetrievedPoint) exportRecord {
+	dense, sparse := getPointVectors(point)
+
+	record := exportRecord{
+		Vectors:       dense,
+		SparseVectors: sparse,
+		Payload:       payloadToMap(point.Payload),
+	}
+	if uuid := point.GetId().GetUuid(); uuid != "" {
+		record.ID = uuid
+	} else {
+		record.ID = point.GetId().GetNum()
+	}
+
+	return record
+}
+
+// exportManifest describes a completed export. It's written after all files, so its presence marks the export as complete.
+type exportManifest struct {
+	Collection  string `json:"collection"`
+	Format      string `json:"format"`
+	Compression string `json:"compression,omitempty"`
+	Encrypted   bool   `json:"encrypted,omitempty"`
+	// MAC authenticates the manifest of an encrypted export, see manifestMAC.
+	MAC         string               `json:"mac,omitempty"`
+	PointsCount uint64               `json:"points_count"`
+	Files       []exportManifestFile `json:"files"`
+	// Config is the collection configuration in the JSON form of the Qdrant API, to recreate the collection when loading.
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// exportManifestFile indexes a single file of an export. Points are exported in the order of their IDs,
+// so the IDs of the first and last point tell which file has a point.
+type exportManifestFile struct {
+	Name    string `json:"name"`
+	Points  uint64 `json:"points"`
+	Size    int64  `json:"size,omitempty"`
+	FirstID any    `json:"first_id,omitempty"`
+	LastID  any    `json:"last_id,omitempty"`
+}
+
+func exportManifestName(collection string) string {
+	return collection + "-manifest.json"
+}
+
+func writeExportManifest(ctx context.Context, destination exportDestination, manifest exportManifest) error {
+	name := exportManifestName(manifest.Collection)
+	file, err := destination.Create(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// exportDestination creates the files of an export, either in a local directory or under an S3 prefix.
+type exportDestination interface {
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+}
+
+func newExportDestination(ctx context.Context, location string, awsConfig commons.AWSConfig) (exportDestination, error) {
+	if !isS3Location(location) {
+		if err := os.MkdirAll(location, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %w", err)
+		}
+		return localDestination{dir: location}, nil
+	}
+
+	client, bucket, prefix, err := newS3Client(ctx, location, awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3Destination{
+		uploader: manager.NewUploader(client),
+		bucket:   bucket,
+		prefix:   prefix,
+	}, nil
+}
+
+func isS3Location(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// newS3Client returns a client for the bucket of an s3://bucket/prefix location, along with the bucket and prefix.
+func newS3Client(ctx context.Context, location string, awsConfig commons.AWSConfig) (*s3.Client, string, string, error) {
+	parsedUrl, err := url.Parse(location)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to parse S3 URL: %w", err)
+	}
+
+	loaded, err := loadAWSConfig(ctx, awsConfig)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return s3.NewFromConfig(loaded), parsedUrl.Host, strings.TrimPrefix(parsedUrl.Path, "/"), nil
+}
+
+type localDestination struct {
+	dir string
+}
+
+func (d localDestination) Create(_ context.Context, name string) (io.WriteCloser, error) {
+	return os.Create(filepath.Join(d.dir, name))
+}
+
+type s3Destination struct {
+	uploader *manager.Uploader
+	bucket   string
+	prefix   string
+}
+
+func (d s3Destination) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	reader, writer := io.Pipe()
+	upload := &s3Upload{PipeWriter: writer, done: make(chan error, 1)}
+
+	go func() {
+		_, err := d.uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(d.bucket),
+			Key:    aws.String(path.Join(d.prefix, name)),
+			Body:   reader,
+		})
+		// Unblock the writer if the upload failed half way.
+		_ = reader.CloseWithError(err)
+		upload.done <- err
+	}()
+
+	return upload, nil
+}
+
+// s3Upload streams a file to S3 as it is written.
+// Close waits for the upload to complete.
+type s3Upload struct {
+	*io.PipeWriter
+	done chan error
+}
+
+func (u *s3Upload) Close() error {
+	if err := u.PipeWriter.Close(); err != nil {
+		return err
+	}
+	if err := <-u.done; err != nil {
+		return fmt.Errorf("failed to upload to S3: %w", err)
+	}
+	return nil
+}
+
+type countingWriter struct {
+	io.Writer
+	count int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.count += int64(n)
+	return n, err
+}
+
+type recordEncoder interface {
+	Encode(records []exportRecord) error
+	// Size returns the number of bytes written to the underlying file so far.
+	Size() int64
+	Close() error
+}
+
+// compressor is a gzip or zstd writer.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+type jsonlEncoder struct {
+	counter    *countingWriter
+	compressor compressor
+	encoder    *json.Encoder
+}
+
+func newJSONLEncoder(w io.Writer, compression string) (*jsonlEncoder, error) {
+	counter := &countingWriter{Writer: w}
+	var c compressor = gzip.NewWriter(counter)
+	if compression == exportCompressionZstd {
+		var err error
+		c, err = zstd.NewWriter(counter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &jsonlEncoder{counter: counter, compressor: c, encoder: json.NewEncoder(c)}, nil
+}
+
+func (e *jsonlEncoder) Encode(records []exportRecord) error {
+	for _, record := range records {
+		if err := e.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	// Flushing after every batch keeps the file size accurate for splitting.
+	return e.compressor.Flush()
+}
+
+func (e *jsonlEncoder) Size() int64 {
+	return e.counter.count
+}
+
+func (e *jsonlEncoder) Close() error {
+	return e.compressor.Close()
+}
+
+type parquetEncoder struct {
+	writer *parquet.GenericWriter[parquetRecord]
+}
+
+func (e *parquetEncoder) Encode(records []exportRecord) error {
+	rows := make([]parquetRecord, 0, len(records))
+	for _, record := range records {
+		payload, err := json.Marshal(record.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		rows = append(rows, parquetRecord{
+			ID:            fmt.Sprint(record.ID),
+			Vectors:       record.Vectors,
+			SparseVectors: record.SparseVectors,
+			Payload:       string(payload),
+		})
+	}
+	if _, err := e.writer.Write(rows); err != nil {
+		return err
+	}
+	// Every batch becomes a row group, which keeps the file size accurate for splitting.
+	return e.writer.Flush()
+}
+
+func (e *parquetEncoder) Size() int64 {
+	return e.writer.File().Size()
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.writer.Close()
+}
+
+// exportWriter writes records to numbered files, starting a new file once maxFileSize is reached.
+type exportWriter struct {
+	destination exportDestination
+	prefix      string
+	format      string
+	compression string
+	maxFileSize int64
+
+	part    int
+	file    io.WriteCloser
+	encoder recordEncoder
+	files   []exportManifestFile
+}
+
+func newExportWriter(destination exportDestination, prefix, format, compression string, maxFileSize int64) *exportWriter {
+	return &exportWriter{
+		destination: destination,
+		prefix:      prefix,
+		format:      format,
+		compression: compression,
+		maxFileSize: maxFileSize,
+	}
+}
+
+func (w *exportWriter) Write(ctx context.Context, records []exportRecord) error {
+	if w.file == nil {
+		if err := w.openPart(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := w.encoder.Encode(records); err != nil {
+		return fmt.Errorf("failed to write records: %w", err)
+	}
+	file := &w.files[len(w.files)-1]
+	if file.Points == 0 && len(records) > 0 {
+		file.FirstID = records[0].ID
+	}
+	if len(records) > 0 {
+		file.LastID = records[len(records)-1].ID
+	}
+	file.Points += uint64(len(records))
+
+	if w.maxFileSize > 0 && w.encoder.Size() >= w.maxFileSize {
+		return w.closePart()
+	}
+
+	return nil
+}
+
+func (w *exportWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.closePart()
+}
+
+func (w *exportWriter) openPart(ctx context.Context) error {
+	extension := "jsonl.gz"
+	switch {
+	case w.format == exportFormatParquet:
+		extension = "parquet"
+	case w.compression == exportCompressionZstd:
+		extension = "jsonl.zst"
+	}
+	name := fmt.Sprintf("%s-%05d.%s", w.prefix, w.part, extension)
+	if _, ok := w.destination.(encryptedDestination); ok {
+		name += encryptedFileExtension
+	}
+
+	file, err := w.destination.Create(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", name, err)
+	}
+
+	if w.format == exportFormatParquet {
+		var options []parquet.WriterOption
+		if w.compression == exportCompressionZstd {
+			options = append(options, parquet.Compression(&parquetzstd.Codec{}))
+		}
+		w.encoder = &parquetEncoder{writer: parquet.NewGenericWriter[parquetRecord](file, options...)}
+	} else {
+		w.encoder, err = newJSONLEncoder(file, w.compression)
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+	}
+	w.file = file
+	w.files = append(w.files, exportManifestFile{Name: name})
+	w.part++
+
+	return nil
+}
+
+func (w *exportWriter) closePart() error {
+	defer func() {
+		w.file = nil
+	}()
+
+	if err := w.encoder.Close(); err != nil {
+		_ = w.file.Close()
+		return fmt.Errorf("failed to finish file: %w", err)
+	}
+	w.files[len(w.files)-1].Size = w.encoder.Size()
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return nil
+}
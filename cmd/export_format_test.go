@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+)
+
+func testExportRecords(n int) []exportRecord {
+	records := make([]exportRecord, n)
+	for i := range records {
+		records[i] = exportRecord{
+			ID:            uint64(i),
+			Vectors:       map[string][]float32{"vector": {0.1, 0.2, 0.3}},
+			SparseVectors: map[string]sparseVector{"sparse": {Indices: []uint32{1, 5}, Values: []float32{0.5, 0.7}}},
+			Payload:       map[string]any{"title": "point"},
+		}
+	}
+	return records
+}
+
+func Test_exportWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		compression string
+		pattern     string
+		maxSize     int64
+		wantParts   int
+	}{
+		{name: "jsonl single file", format: exportFormatJSONL, compression: exportCompressionGzip, pattern: "*.jsonl.gz", maxSize: 0, wantParts: 1},
+		{name: "jsonl split", format: exportFormatJSONL, compression: exportCompressionGzip, pattern: "*.jsonl.gz", maxSize: 1, wantParts: 3},
+		{name: "jsonl zstd split", format: exportFormatJSONL, compression: exportCompressionZstd, pattern: "*.jsonl.zst", maxSize: 1, wantParts: 3},
+		{name: "parquet single file", format: exportFormatParquet, compression: exportCompressionGzip, pattern: "*.parquet", maxSize: 0, wantParts: 1},
+		{name: "parquet split", format: exportFormatParquet, compression: exportCompressionGzip, pattern: "*.parquet", maxSize: 1, wantParts: 3},
+		{name: "parquet zstd", format: exportFormatParquet, compression: exportCompressionZstd, pattern: "*.parquet", maxSize: 0, wantParts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writer := newExportWriter(localDestination{dir: dir}, "test", tt.format, tt.compression, tt.maxSize)
+
+			for i := 0; i < 3; i++ {
+				if err := writer.Write(context.Background(), testExportRecords(2)); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			files, _ := filepath.Glob(filepath.Join(dir, tt.pattern))
+			if len(files) != tt.wantParts {
+				t.Fatalf("got %d files, want %d", len(files), tt.wantParts)
+			}
+
+			total := 0
+			for _, file := range files {
+				total += countExportedRows(t, file, tt.format)
+			}
+			if total != 6 {
+				t.Errorf("got %d rows, want 6", total)
+			}
+
+			// The manifest indexes every file by its size and the IDs of its first and last point.
+			last := writer.files[len(writer.files)-1]
+			if last.Size == 0 || last.FirstID != uint64(0) || last.LastID != uint64(1) {
+				t.Errorf("got manifest file %+v, want its size and the IDs 0 to 1", last)
+			}
+		})
+	}
+}
+
+func countExportedRows(t *testing.T, file, format string) int {
+	t.Helper()
+
+	if format == exportFormatParquet {
+		rows, err := parquet.ReadFile[parquetRecord](file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if len(rows) > 0 && rows[0].SparseVectors["sparse"].Indices[1] != 5 {
+			t.Errorf("unexpected sparse vector %v", rows[0].SparseVectors)
+		}
+		return len(rows)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", file, err)
+	}
+	defer f.Close()
+
+	var reader io.Reader
+	if strings.HasSuffix(file, ".zst") {
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		defer zr.Close()
+		reader = zr
+	} else {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		reader = gz
+	}
+
+	count := 0
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var record exportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		count++
+	}
+	return count
+}
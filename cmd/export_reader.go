@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// exportSource opens the files of an export, either in a local directory or under an S3 prefix.
+type exportSource interface {
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+func newExportSource(ctx context.Context, location string, awsConfig commons.AWSConfig) (exportSource, error) {
+	if !isS3Location(location) {
+		return localSource{dir: location}, nil
+	}
+
+	client, bucket, prefix, err := newS3Client(ctx, location, awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3Source{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+type localSource struct {
+	dir string
+}
+
+func (s localSource) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(s.dir, name))
+}
+
+type s3Source struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (s s3Source) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, name)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+	return object.Body, nil
+}
+
+func readExportManifest(ctx context.Context, source exportSource, collection string) (*exportManifest, error) {
+	name := exportManifestName(collection)
+	file, err := source.Open(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s, make sure the export of '%s' completed: %w", name, collection, err)
+	}
+	defer file.Close()
+
+	// Point IDs are kept as numbers as written, so the manifest is encoded the same way again to authenticate it.
+	var manifest exportManifest
+	decoder := json.NewDecoder(file)
+	decoder.UseNumber()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return &manifest, nil
+}
+
+// readExportFile decodes the records of an export file and hands them to readBatch in batches of up to batchSize.
+// The first skip records are dropped, so an interrupted load can continue in the middle of a file.
+func readExportFile(ctx context.Context, source exportSource, name, format string, batchSize int, skip uint64, readBatch func([]exportRecord) error) error {
+	file, err := source.Open(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	batch := make([]exportRecord, 0, batchSize)
+	add := func(record exportRecord) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		batch = append(batch, record)
+		if len(batch) < batchSize {
+			return nil
+		}
+		err := readBatch(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	if format == exportFormatParquet {
+		err = decodeParquet(file, add)
+	} else {
+		err = decodeJSONL(file, add)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	if len(batch) > 0 {
+		return readBatch(batch)
+	}
+	return nil
+}
+
+// decodeJSONL decodes JSONL compressed with gzip or zstd, as written by export, or plain JSONL, as piped into the stdin source.
+func decodeJSONL(r io.Reader, add func(exportRecord) error) error {
+	buffered := bufio.NewReader(r)
+	var reader io.Reader = buffered
+	magic, _ := buffered.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		reader = zr
+	}
+
+	decoder := json.NewDecoder(reader)
+	// Numbers are kept as written, so integer IDs and payload values don't turn into floats.
+	decoder.UseNumber()
+	for {
+		var record exportRecord
+		err := decoder.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := add(record); err != nil {
+			return err
+		}
+	}
+}
+
+func decodeParquet(r io.Reader, add func(exportRecord) error) error {
+	// Parquet files are read from the end, so files that are not local are buffered in a temporary file first.
+	// Decrypted files are buffered in memory instead, so their plaintext never reaches the disk.
+	var file io.ReaderAt
+	switch source := r.(type) {
+	case *os.File:
+		file = source
+	case *decryptingReader:
+		data, err := io.ReadAll(source)
+		if err != nil {
+			return err
+		}
+		file = bytes.NewReader(data)
+	default:
+		tmp, err := os.CreateTemp("", "qdrant-load-*.parquet")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		if _, err := io.Copy(tmp, r); err != nil {
+			return err
+		}
+		file = tmp
+	}
+
+	reader := parquet.NewGenericReader[parquetRecord](file)
+	defer reader.Close()
+
+	rows := make([]parquetRecord, 100)
+	for {
+		n, err := reader.Read(rows)
+		for _, row := range rows[:n] {
+			record, decodeErr := parquetRowToRecord(row)
+			if decodeErr != nil {
+				return decodeErr
+			}
+			if addErr := add(record); addErr != nil {
+				return addErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func parquetRowToRecord(row parquetRecord) (exportRecord, error) {
+	record := exportRecord{
+		ID:            json.Number(row.ID),
+		Vectors:       row.Vectors,
+		SparseVectors: row.SparseVectors,
+	}
+	if _, err := strconv.ParseUint(row.ID, 10, 64); err != nil {
+		record.ID = row.ID
+	}
+
+	if row.Payload != "" {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(row.Payload)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record.Payload); err != nil {
+			return exportRecord{}, fmt.Errorf("failed to decode payload of point %s: %w", row.ID, err)
+		}
+	}
+
+	return record, nil
+}
+
+// toPoint converts a record back to a Qdrant point.
+// With unnamedVector, the vector exported under defaultVectorName becomes the unnamed vector of the point.
+func (record exportRecord) toPoint(unnamedVector bool) (*qdrant.PointStruct, error) {
+	point := &qdrant.PointStruct{}
+
+	switch id := record.ID.(type) {
+	case json.Number:
+		num, err := strconv.ParseUint(id.String(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid point ID %s: %w", id, err)
+		}
+		point.Id = qdrant.NewIDNum(num)
+	case uint64:
+		point.Id = qdrant.NewIDNum(id)
+	case string:
+		point.Id = qdrant.NewIDUUID(id)
+	default:
+		return nil, fmt.Errorf("invalid point ID %v", record.ID)
+	}
+
+	if unnamedVector && len(record.Vectors) == 1 && len(record.SparseVectors) == 0 {
+		point.Vectors = qdrant.NewVectorsDense(record.Vectors[defaultVectorName])
+	} else {
+		vectors := make(map[string]*qdrant.Vector, len(record.Vectors)+len(record.SparseVectors))
+		for name, vector := range record.Vectors {
+			// The unnamed vector is the one with an empty name, next to named sparse vectors.
+			if unnamedVector && name == defaultVectorName {
+				name = ""
+			}
+			vectors[name] = qdrant.NewVectorDense(vector)
+		}
+		for name, vector := range record.SparseVectors {
+			vectors[name] = qdrant.NewVectorSparse(vector.Indices, vector.Values)
+		}
+		point.Vectors = qdrant.NewVectorsMap(vectors)
+	}
+
+	payload, err := qdrant.TryValueMap(normalizeJSONMap(record.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload of point %v: %w", record.ID, err)
+	}
+	point.Payload = payload
+
+	return point, nil
+}
+
+func normalizeJSONMap(values map[string]any) map[string]any {
+	result := make(map[string]any, len(values))
+	for k, v := range values {
+		result[k] = normalizeJSONValue(v)
+	}
+	return result
+}
+
+// normalizeJSONValue converts the numbers of a value decoded with json.Decoder.UseNumber to integers where possible.
+func normalizeJSONValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		return normalizeJSONMap(v)
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = normalizeJSONValue(item)
+		}
+		return list
+	default:
+		return value
+	}
+}
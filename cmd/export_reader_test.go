@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_readExportFile(t *testing.T) {
+	tests := []struct {
+		format      string
+		compression string
+	}{
+		{format: exportFormatJSONL, compression: exportCompressionGzip},
+		{format: exportFormatJSONL, compression: exportCompressionZstd},
+		{format: exportFormatParquet, compression: exportCompressionGzip},
+		{format: exportFormatParquet, compression: exportCompressionZstd},
+	}
+	for _, tt := range tests {
+		format := tt.format
+		t.Run(format+"/"+tt.compression, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			records := testExportRecords(5)
+			records[0].ID = "5c56c793-69f3-4fbf-87e6-c4bf54c28c26"
+			records[1].Payload = map[string]any{"count": int64(3), "score": 0.5}
+
+			writer := newExportWriter(localDestination{dir: dir}, "test", format, tt.compression, 0)
+			if err := writer.Write(ctx, records); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			var loaded []exportRecord
+			err := readExportFile(ctx, localSource{dir: dir}, writer.files[0].Name, format, 2, 0, func(batch []exportRecord) error {
+				loaded = append(loaded, batch...)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readExportFile() error = %v", err)
+			}
+			if len(loaded) != 5 || writer.files[0].Points != 5 {
+				t.Fatalf("read %d records, manifest has %d, want 5", len(loaded), writer.files[0].Points)
+			}
+
+			uuidPoint, err := loaded[0].toPoint(false)
+			if err != nil {
+				t.Fatalf("toPoint() error = %v", err)
+			}
+			if uuidPoint.GetId().GetUuid() != "5c56c793-69f3-4fbf-87e6-c4bf54c28c26" {
+				t.Errorf("got ID %v, want the UUID", uuidPoint.GetId())
+			}
+
+			numPoint, err := loaded[1].toPoint(false)
+			if err != nil {
+				t.Fatalf("toPoint() error = %v", err)
+			}
+			if numPoint.GetId().GetNum() != 1 {
+				t.Errorf("got ID %v, want 1", numPoint.GetId())
+			}
+			if numPoint.GetPayload()["count"].GetIntegerValue() != 3 || numPoint.GetPayload()["score"].GetDoubleValue() != 0.5 {
+				t.Errorf("payload numbers did not round trip: %v", numPoint.GetPayload())
+			}
+			if len(numPoint.GetVectors().GetVectors().GetVectors()) != 2 {
+				t.Errorf("got vectors %v, want a dense and a sparse one", numPoint.GetVectors())
+			}
+
+			// Resuming in the middle of the file skips the records loaded before.
+			var resumed []exportRecord
+			err = readExportFile(ctx, localSource{dir: dir}, writer.files[0].Name, format, 2, 3, func(batch []exportRecord) error {
+				resumed = append(resumed, batch...)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readExportFile() error = %v", err)
+			}
+			if len(resumed) != 2 {
+				t.Errorf("read %d records after skipping 3, want 2", len(resumed))
+			}
+		})
+	}
+}
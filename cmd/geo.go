@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Matches WKT and EWKT points like "POINT(13.4 52.52)", "SRID=4326;POINT Z (13.4 52.52 34)". The first coordinate is the longitude.
+var wktPointPattern = regexp.MustCompile(`(?i)^\s*(?:SRID=\d+\s*;\s*)?POINT\s*(?:Z|M|ZM)?\s*\(\s*(\S+)\s+(\S+)(?:\s+\S+){0,2}\s*\)\s*$`)
+
+var (
+	latKeys = []string{"lat", "latitude"}
+	lonKeys = []string{"lon", "lng", "long", "longitude"}
+)
+
+// convertGeoPayloads rewrites the geo locations in the payloads of points into the {"lat": ..., "lon": ...} objects
+// Qdrant filters on. Values that aren't recognized or are out of range are left as they are.
+func convertGeoPayloads(points []*qdrant.PointStruct, migration commons.MigrationConfig) {
+	if !migration.ConvertGeo && len(migration.GeoPoint) == 0 {
+		return
+	}
+
+	for _, point := range points {
+		payload := point.GetPayload()
+		if migration.ConvertGeo {
+			for key, value := range payload {
+				if geo, ok := parseGeoValue(value); ok {
+					payload[key] = geo
+				}
+			}
+		}
+		for _, fields := range migration.GeoPoint {
+			lat, latOk := geoNumber(payload[fields.Lat])
+			lon, lonOk := geoNumber(payload[fields.Lon])
+			if latOk && lonOk && validGeoPoint(lat, lon) {
+				payload[fields.Field] = newGeoValue(lat, lon)
+			}
+		}
+	}
+}
+
+// parseGeoValue recognizes GeoJSON points, also as JSON strings, WKT points, and objects with latitude and longitude keys.
+func parseGeoValue(value *qdrant.Value) (*qdrant.Value, bool) {
+	var lat, lon float64
+	var ok bool
+
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_StructValue:
+		lat, lon, ok = parseGeoStruct(kind.StructValue.GetFields())
+	case *qdrant.Value_StringValue:
+		lat, lon, ok = parseGeoString(kind.StringValue)
+	}
+
+	if !ok || !validGeoPoint(lat, lon) {
+		return nil, false
+	}
+	return newGeoValue(lat, lon), true
+}
+
+func parseGeoStruct(fields map[string]*qdrant.Value) (lat, lon float64, ok bool) {
+	// GeoJSON: {"type": "Point", "coordinates": [lon, lat]}
+	if strings.EqualFold(fields["type"].GetStringValue(), "Point") {
+		coordinates := fields["coordinates"].GetListValue().GetValues()
+		if len(coordinates) < 2 {
+			return 0, 0, false
+		}
+		lon, lonOk := geoNumber(coordinates[0])
+		lat, latOk := geoNumber(coordinates[1])
+		return lat, lon, latOk && lonOk
+	}
+
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	var latOk, lonOk bool
+	for key, value := range fields {
+		switch key = strings.ToLower(key); {
+		case slices.Contains(latKeys, key):
+			lat, latOk = geoNumber(value)
+		case slices.Contains(lonKeys, key):
+			lon, lonOk = geoNumber(value)
+		}
+	}
+	return lat, lon, latOk && lonOk
+}
+
+func parseGeoString(s string) (lat, lon float64, ok bool) {
+	if match := wktPointPattern.FindStringSubmatch(s); match != nil {
+		lon, lonErr := strconv.ParseFloat(match[1], 64)
+		lat, latErr := strconv.ParseFloat(match[2], 64)
+		return lat, lon, lonErr == nil && latErr == nil
+	}
+
+	// GeoJSON stored as text, e.g. by PostGIS' ST_AsGeoJSON.
+	if !strings.HasPrefix(strings.TrimSpace(s), "{") {
+		return 0, 0, false
+	}
+	var geoJSON map[string]any
+	if err := json.Unmarshal([]byte(s), &geoJSON); err != nil {
+		return 0, 0, false
+	}
+	value, err := qdrant.NewValue(geoJSON)
+	if err != nil {
+		return 0, 0, false
+	}
+	return parseGeoStruct(value.GetStructValue().GetFields())
+}
+
+// geoNumber returns the value of a numeric field. Numeric strings are accepted, since SQL decimals are migrated as strings.
+func geoNumber(value *qdrant.Value) (float64, bool) {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_DoubleValue:
+		return kind.DoubleValue, true
+	case *qdrant.Value_IntegerValue:
+		return float64(kind.IntegerValue), true
+	case *qdrant.Value_StringValue:
+		number, err := strconv.ParseFloat(strings.TrimSpace(kind.StringValue), 64)
+		return number, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func validGeoPoint(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+func newGeoValue(lat, lon float64) *qdrant.Value {
+	return qdrant.NewValueStruct(&qdrant.Struct{Fields: map[string]*qdrant.Value{
+		"lat": qdrant.NewValueDouble(lat),
+		"lon": qdrant.NewValueDouble(lon),
+	}})
+}
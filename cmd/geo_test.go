@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_parseGeoValue(t *testing.T) {
+	berlin := newGeoValue(52.52, 13.4)
+
+	tests := []struct {
+		name  string
+		value any
+		want  *qdrant.Value
+	}{
+		{name: "GeoJSON", value: map[string]any{"type": "Point", "coordinates": []any{13.4, 52.52}}, want: berlin},
+		{name: "GeoJSON string", value: `{"type": "Point", "coordinates": [13.4, 52.52]}`, want: berlin},
+		{name: "WKT", value: "POINT(13.4 52.52)", want: berlin},
+		{name: "EWKT with Z", value: "SRID=4326;POINT Z (13.4 52.52 34)", want: berlin},
+		{name: "lat lng", value: map[string]any{"lat": 52.52, "lng": 13.4}, want: berlin},
+		{name: "latitude longitude strings", value: map[string]any{"Latitude": "52.52", "Longitude": "13.4"}, want: berlin},
+		{name: "GeoJSON polygon", value: map[string]any{"type": "Polygon", "coordinates": []any{}}},
+		{name: "out of range", value: "POINT(52.52 113.4)"},
+		{name: "extra keys", value: map[string]any{"lat": 52.52, "lon": 13.4, "name": "Berlin"}},
+		{name: "plain string", value: "Berlin"},
+		{name: "number", value: 52.52},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := qdrant.NewValue(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := parseGeoValue(value)
+			if ok != (tt.want != nil) {
+				t.Fatalf("parseGeoValue() ok = %v, want %v", ok, tt.want != nil)
+			}
+			if ok && !proto.Equal(got, tt.want) {
+				t.Errorf("parseGeoValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_convertGeoPayloads(t *testing.T) {
+	points := []*qdrant.PointStruct{
+		{Payload: qdrant.NewValueMap(map[string]any{"latitude": "52.52", "longitude": 13.4, "shape": "POINT(13.4 52.52)"})},
+		{Payload: qdrant.NewValueMap(map[string]any{"latitude": nil, "longitude": 13.4})},
+	}
+	geoPoint, err := commons.ParseGeoPointFields("location=latitude:longitude")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convertGeoPayloads(points, commons.MigrationConfig{ConvertGeo: true, GeoPoint: []commons.GeoPointFields{geoPoint}})
+
+	berlin := newGeoValue(52.52, 13.4)
+	if got := points[0].Payload["location"]; !proto.Equal(got, berlin) {
+		t.Errorf("location = %v, want %v", got, berlin)
+	}
+	if got := points[0].Payload["shape"]; !proto.Equal(got, berlin) {
+		t.Errorf("shape = %v, want %v", got, berlin)
+	}
+	if got := points[0].Payload["latitude"].GetStringValue(); got != "52.52" {
+		t.Errorf("latitude = %q, want it unchanged", got)
+	}
+	if _, ok := points[1].Payload["location"]; ok {
+		t.Errorf("location is set for a point without latitude")
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Number of mismatching point IDs printed by a verification.
+const maxReportedMismatches = 10
+
+// pointHash returns the hex SHA-256 of the vectors and payload of a point, ignoring the payload field the hash is stored in.
+// Map entries are encoded in a deterministic order, so the same point always has the same hash.
+func pointHash(point *qdrant.PointStruct, hashField string) (string, error) {
+	payload := point.GetPayload()
+	if _, ok := payload[hashField]; ok {
+		payload = make(map[string]*qdrant.Value, len(point.GetPayload()))
+		for k, v := range point.GetPayload() {
+			if k != hashField {
+				payload[k] = v
+			}
+		}
+	}
+
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(&qdrant.PointStruct{
+		Vectors: point.GetVectors(),
+		Payload: payload,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode point %s for hashing: %w", pointIDToString(point.GetId()), err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// addPointHashes stores the hash of every point in its hashField payload field.
+func addPointHashes(points []*qdrant.PointStruct, hashField string) error {
+	for _, point := range points {
+		hash, err := pointHash(point, hashField)
+		if err != nil {
+			return err
+		}
+		if point.Payload == nil {
+			point.Payload = make(map[string]*qdrant.Value)
+		}
+		point.Payload[hashField] = qdrant.NewValueString(hash)
+	}
+	return nil
+}
+
+// getStoredHashes returns the hashes stored in the hashField payload field of the given points, keyed by point ID.
+// Points that don't exist or have no hash are missing from the result.
+func getStoredHashes(ctx context.Context, client *qdrant.Client, collection string, ids []*qdrant.PointId, hashField string) (map[string]string, error) {
+	points, err := client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: collection,
+		Ids:            ids,
+		WithPayload:    qdrant.NewWithPayloadInclude(hashField),
+		WithVectors:    qdrant.NewWithVectors(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hashes from target: %w", err)
+	}
+
+	hashes := make(map[string]string, len(points))
+	for _, point := range points {
+		if hash := point.GetPayload()[hashField].GetStringValue(); hash != "" {
+			hashes[pointIDToString(point.GetId())] = hash
+		}
+	}
+	return hashes, nil
+}
+
+// verifyHashes compares the hash of every source point the migration copies, i.e. those of --migration.tenants and
+// --migration.sample, with the one stored in the target.
+// Only the hash field is read from the target, so verifying is much cheaper than comparing vectors.
+// It returns the number of points that are missing from the target or differ.
+func verifyHashes(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClient *qdrant.Client, targetCollection string, hashField string, migration commons.MigrationConfig) (int, error) {
+	var offset *qdrant.PointId
+	limit := uint32(migration.BatchSize)
+	mismatches := 0
+
+	for {
+		resp, err := sourceClient.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: sourceCollection,
+			Filter:         tenantFilter(migration),
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return mismatches, fmt.Errorf("failed to scroll data from source: %w", err)
+		}
+		offset = resp.GetNextPageOffset()
+
+		points := make([]*qdrant.PointStruct, 0, len(resp.GetResult()))
+		ids := make([]*qdrant.PointId, 0, len(resp.GetResult()))
+		for _, point := range retrievedToPointStructs(resp.GetResult()) {
+			if migration.Sample > 0 && !inSample(point.GetId(), migration.Sample) {
+				continue
+			}
+			points = append(points, point)
+			ids = append(ids, point.GetId())
+		}
+
+		if len(ids) > 0 {
+			stored, err := getStoredHashes(ctx, targetClient, targetCollection, ids, hashField)
+			if err != nil {
+				return mismatches, err
+			}
+
+			for _, point := range points {
+				hash, err := pointHash(point, hashField)
+				if err != nil {
+					return mismatches, err
+				}
+				id := pointIDToString(point.GetId())
+				if stored[id] == hash {
+					continue
+				}
+				mismatches++
+				if mismatches <= maxReportedMismatches {
+					pterm.Warning.Printfln("Point %s is missing or differs in the target", id)
+				}
+			}
+		}
+
+		if offset == nil {
+			break
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_pointHash(t *testing.T) {
+	newPoint := func(title string) *qdrant.PointStruct {
+		return &qdrant.PointStruct{
+			Id:      qdrant.NewIDNum(1),
+			Vectors: qdrant.NewVectorsDense([]float32{0.1, 0.2}),
+			Payload: qdrant.NewValueMap(map[string]any{"title": title, "tags": []any{"a", "b"}, "count": 3}),
+		}
+	}
+
+	hash, err := pointHash(newPoint("point"), "_hash")
+	if err != nil {
+		t.Fatalf("pointHash() error = %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		again, _ := pointHash(newPoint("point"), "_hash")
+		if again != hash {
+			t.Fatalf("got hash %s, then %s for the same point", hash, again)
+		}
+	}
+
+	stored := newPoint("point")
+	if err := addPointHashes([]*qdrant.PointStruct{stored}, "_hash"); err != nil {
+		t.Fatalf("addPointHashes() error = %v", err)
+	}
+	if got, _ := pointHash(stored, "_hash"); got != hash || stored.Payload["_hash"].GetStringValue() != hash {
+		t.Errorf("the stored hash field changed the hash of the point")
+	}
+
+	if other, _ := pointHash(newPoint("other"), "_hash"); other == hash {
+		t.Errorf("points with different payloads have the same hash")
+	}
+}
+
+// hashedPointsServer serves the points of a source collection, filtered by tenant like Qdrant would,
+// and the hashes stored in a target collection.
+type hashedPointsServer struct {
+	qdrant.UnimplementedPointsServer
+	source []*qdrant.RetrievedPoint
+	stored map[uint64]string
+}
+
+func (s *hashedPointsServer) Scroll(_ context.Context, req *qdrant.ScrollPoints) (*qdrant.ScrollResponse, error) {
+	var points []*qdrant.RetrievedPoint
+	for _, point := range s.source {
+		if req.GetFilter() != nil && !slices.Contains(req.GetFilter().GetShould()[0].GetField().GetMatch().GetKeywords().GetStrings(), point.GetPayload()["tenant"].GetStringValue()) {
+			continue
+		}
+		points = append(points, point)
+	}
+	return &qdrant.ScrollResponse{Result: points}, nil
+}
+
+func (s *hashedPointsServer) Get(_ context.Context, req *qdrant.GetPoints) (*qdrant.GetResponse, error) {
+	var points []*qdrant.RetrievedPoint
+	for _, id := range req.GetIds() {
+		if hash, ok := s.stored[id.GetNum()]; ok {
+			points = append(points, &qdrant.RetrievedPoint{Id: id, Payload: qdrant.NewValueMap(map[string]any{"_hash": hash})})
+		}
+	}
+	return &qdrant.GetResponse{Result: points}, nil
+}
+
+func TestVerifyHashesOfTenants(t *testing.T) {
+	source := []*qdrant.RetrievedPoint{
+		{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"tenant": "a"})},
+		{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"tenant": "b"})},
+	}
+	// Only the points of tenant a were migrated.
+	hash, err := pointHash(retrievedToPointStructs(source[:1])[0], "_hash")
+	if err != nil {
+		t.Fatalf("pointHash() error = %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	qdrant.RegisterPointsServer(server, &hashedPointsServer{source: source, stored: map[uint64]string{1: hash}})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	url := "http://" + listener.Addr().String()
+	host, port, useTLS, err := parseQdrantUrl(url)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+	client, err := connectToQdrant(&Globals{}, host, port, commons.QdrantConfig{Url: url}, useTLS)
+	if err != nil {
+		t.Fatalf("connectToQdrant() error = %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		name       string
+		tenants    []string
+		mismatches int
+	}{
+		{name: "migrated tenant", tenants: []string{"a"}, mismatches: 0},
+		{name: "all tenants", mismatches: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration := commons.MigrationConfig{BatchSize: 10, TenantField: "tenant", Tenants: tt.tenants}
+			mismatches, err := verifyHashes(context.Background(), client, "source", client, "target", "_hash", migration)
+			if err != nil {
+				t.Fatalf("verifyHashes() error = %v", err)
+			}
+			if mismatches != tt.mismatches {
+				t.Errorf("verifyHashes() = %d mismatches, want %d", mismatches, tt.mismatches)
+			}
+		})
+	}
+}
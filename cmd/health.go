@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Writes to a target under pressure are first spread over fewer concurrent requests, half as many on every check,
+// and once a single one is left, spaced out by a delay that doubles on every check, up to the check interval.
+// When the pressure is gone, every check takes one step back, until writes are as fast as before.
+const healthMinDelay = 100 * time.Millisecond
+
+// residentMemoryMetric is the metric of Qdrant with the bytes of memory the node holds.
+const residentMemoryMetric = "memory_resident_bytes"
+
+// healthWatchers holds the health watchers of target collections, by client and collection.
+var healthWatchers sync.Map
+
+// clientRestClients holds functions that return REST clients for the nodes gRPC clients are connected to, to read their metrics.
+var clientRestClients sync.Map
+
+// healthWatcher limits the writes to a target collection while the target is under pressure.
+type healthWatcher struct {
+	lock     sync.Mutex
+	inFlight int
+	peak     int
+	// limit is the number of concurrent writes, 0 if it's unlimited.
+	limit   int
+	delay   time.Duration
+	changed chan struct{}
+}
+
+// acquireWrite waits until a write to a target collection is allowed by its health, per --migration.health-check-interval,
+// and returns a function to call once the write is done.
+func acquireWrite(ctx context.Context, client *qdrant.Client, collection string, migration commons.MigrationConfig) (func(), error) {
+	if migration.HealthCheckInterval <= 0 {
+		return func() {}, nil
+	}
+	value, loaded := healthWatchers.LoadOrStore(targetCollectionKey{client: client, collection: collection}, &healthWatcher{changed: make(chan struct{})})
+	watcher := value.(*healthWatcher)
+	if !loaded {
+		go watcher.watch(client, collection, migration)
+	}
+	return watcher.acquire(ctx)
+}
+
+func (w *healthWatcher) acquire(ctx context.Context) (func(), error) {
+	for {
+		w.lock.Lock()
+		if w.limit == 0 || w.inFlight < w.limit {
+			w.inFlight++
+			w.peak = max(w.peak, w.inFlight)
+			delay := w.delay
+			w.lock.Unlock()
+
+			if delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					w.release()
+					return nil, ctx.Err()
+				}
+			}
+			return w.release, nil
+		}
+		changed := w.changed
+		w.lock.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (w *healthWatcher) release() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.inFlight--
+	w.notify()
+}
+
+// notify wakes up the writers waiting for a change. The lock must be held.
+func (w *healthWatcher) notify() {
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// adjust takes one step to slow writes down while the target is under pressure, or to speed them up again once it isn't.
+func (w *healthWatcher) adjust(pressure bool, interval time.Duration) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	switch {
+	case pressure && w.limit != 1:
+		current := w.limit
+		if current == 0 {
+			current = w.peak
+		}
+		w.limit = max(current/2, 1)
+	case pressure:
+		w.delay = min(max(w.delay*2, healthMinDelay), interval)
+	case w.delay > 0:
+		w.delay /= 2
+		if w.delay < healthMinDelay {
+			w.delay = 0
+		}
+	case w.limit > 0:
+		w.limit++
+		if w.limit > w.peak {
+			w.limit = 0
+		}
+	}
+	w.notify()
+}
+
+// pace describes how fast writes currently are.
+func (w *healthWatcher) pace() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	switch {
+	case w.delay > 0:
+		return fmt.Sprintf("a single write at a time, %s apart", w.delay)
+	case w.limit > 0:
+		return fmt.Sprintf("up to %d concurrent write(s)", w.limit)
+	default:
+		return "full speed"
+	}
+}
+
+// watch checks the health of the target every --migration.health-check-interval, until the client is closed.
+func (w *healthWatcher) watch(client *qdrant.Client, collection string, migration commons.MigrationConfig) {
+	var rest *qdrantRestClient
+	if newRest, ok := clientRestClients.Load(client); ok && migration.HealthMaxMemory > 0 {
+		var err error
+		rest, err = newRest.(func() (*qdrantRestClient, error))()
+		if err != nil {
+			pterm.Warning.Printfln("Can't check the memory of the target: %v", err)
+		}
+	}
+
+	ticker := time.NewTicker(migration.HealthCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), migration.HealthCheckInterval)
+		info, err := client.GetCollectionInfo(ctx, collection)
+		if status.Code(err) == codes.Canceled {
+			cancel()
+			return
+		}
+		if err != nil {
+			cancel()
+			pterm.Debug.Printfln("Failed to check the health of target collection '%s': %v", collection, err)
+			continue
+		}
+
+		residentMemory := int64(0)
+		if rest != nil {
+			residentMemory, err = rest.residentMemory(ctx)
+			if err != nil {
+				pterm.Warning.Printfln("Can't check the memory of the target, only checking its segments: %v", err)
+				rest = nil
+			}
+		}
+		cancel()
+
+		reasons := targetPressure(info, residentMemory, migration)
+		before := w.pace()
+		w.adjust(len(reasons) > 0, migration.HealthCheckInterval)
+		after := w.pace()
+		switch {
+		case after == before:
+		case len(reasons) > 0:
+			pterm.Warning.Printfln("Target collection '%s' is under pressure with %s, slowing down to %s", collection, strings.Join(reasons, " and "), after)
+		default:
+			pterm.Info.Printfln("Target collection '%s' is no longer under pressure, speeding up to %s", collection, after)
+		}
+	}
+}
+
+// targetPressure returns the signs of pressure on a target collection, per the thresholds of the health checks.
+// Every shard has segments of its own, so the segment threshold applies per shard.
+func targetPressure(info *qdrant.CollectionInfo, residentMemory int64, migration commons.MigrationConfig) []string {
+	var reasons []string
+	shards := uint64(max(info.GetConfig().GetParams().GetShardNumber(), 1))
+	if migration.HealthMaxSegments > 0 && info.GetSegmentsCount() > migration.HealthMaxSegments*shards {
+		reasons = append(reasons, fmt.Sprintf("%d segments in %d shard(s) waiting to be optimized", info.GetSegmentsCount(), shards))
+	}
+	if migration.HealthMaxMemory > 0 && residentMemory > int64(migration.HealthMaxMemory) {
+		reasons = append(reasons, fmt.Sprintf("%s of memory in use", commons.ByteSize(residentMemory)))
+	}
+	return reasons
+}
+
+// residentMemory returns the bytes of memory the node holds, from its Prometheus metrics.
+func (c *qdrantRestClient) residentMemory(ctx context.Context) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+"/metrics", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), residentMemoryMetric+" ")
+		if !ok {
+			continue
+		}
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse %s: %w", residentMemoryMetric, err)
+		}
+		return int64(number), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	return 0, fmt.Errorf("metrics have no %s", residentMemoryMetric)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_healthWatcher_adjust(t *testing.T) {
+	watcher := &healthWatcher{changed: make(chan struct{}), peak: 8}
+
+	steps := []struct {
+		pressure bool
+		want     string
+	}{
+		{true, "up to 4 concurrent write(s)"},
+		{true, "up to 2 concurrent write(s)"},
+		{true, "up to 1 concurrent write(s)"},
+		{true, "a single write at a time, 100ms apart"},
+		{true, "a single write at a time, 200ms apart"},
+		{true, "a single write at a time, 250ms apart"},
+		{false, "a single write at a time, 125ms apart"},
+		{false, "up to 1 concurrent write(s)"},
+		{false, "up to 2 concurrent write(s)"},
+		{true, "up to 1 concurrent write(s)"},
+		{false, "up to 2 concurrent write(s)"},
+	}
+	for i, step := range steps {
+		watcher.adjust(step.pressure, 250*time.Millisecond)
+		if got := watcher.pace(); got != step.want {
+			t.Fatalf("step %d: pace() = %q, want %q", i, got, step.want)
+		}
+	}
+
+	for range 7 {
+		watcher.adjust(false, time.Second)
+	}
+	if got := watcher.pace(); got != "full speed" {
+		t.Errorf("pace() = %q after recovering, want full speed", got)
+	}
+}
+
+func Test_healthWatcher_acquire(t *testing.T) {
+	watcher := &healthWatcher{changed: make(chan struct{}), limit: 1}
+
+	release, err := watcher.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := watcher.acquire(ctx); err == nil {
+		t.Fatalf("acquire() succeeded over the limit")
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := watcher.acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatalf("acquire() didn't proceed after release")
+	}
+}
+
+func Test_targetPressure(t *testing.T) {
+	migration := commons.MigrationConfig{HealthMaxSegments: 10, HealthMaxMemory: 1 << 30}
+
+	tests := []struct {
+		name           string
+		segments       uint64
+		shards         uint32
+		residentMemory int64
+		want           int
+	}{
+		{"healthy", 10, 0, 1 << 30, 0},
+		{"segments", 11, 0, 0, 1},
+		{"segments per shard", 30, 3, 0, 0},
+		{"segments above all shards", 31, 3, 0, 1},
+		{"memory", 2, 0, 2 << 30, 1},
+		{"both", 20, 0, 2 << 30, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &qdrant.CollectionInfo{
+				SegmentsCount: tt.segments,
+				Config:        &qdrant.CollectionConfig{Params: &qdrant.CollectionParams{ShardNumber: tt.shards}},
+			}
+			if got := targetPressure(info, tt.residentMemory, migration); len(got) != tt.want {
+				t.Errorf("targetPressure() = %v, want %d reason(s)", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_residentMemory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "# HELP memory_resident_bytes Total number of bytes in physically resident data pages mapped")
+		fmt.Fprintln(w, "# TYPE memory_resident_bytes gauge")
+		fmt.Fprintln(w, "memory_resident_bytes 123456789")
+	}))
+	defer server.Close()
+
+	client := &qdrantRestClient{baseUrl: server.URL, http: server.Client()}
+	got, err := client.residentMemory(context.Background())
+	if err != nil {
+		t.Fatalf("residentMemory() error = %v", err)
+	}
+	if got != 123456789 {
+		t.Errorf("residentMemory() = %d, want 123456789", got)
+	}
+}
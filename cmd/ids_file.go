@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// readPointIDs reads the IDs of the points to migrate from a file, or from stdin for "-".
+// Every line holds a numeric or UUID ID. Blank lines and lines starting with # are ignored, and so are repeated IDs.
+func readPointIDs(path string) ([]*qdrant.PointId, error) {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open IDs file: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	return parsePointIDs(reader)
+}
+
+func parsePointIDs(reader io.Reader) ([]*qdrant.PointId, error) {
+	var ids []*qdrant.PointId
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, err := parsePointID(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		key := pointIDToString(id)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		ids = append(ids, id)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IDs: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no point IDs given")
+	}
+
+	return ids, nil
+}
+
+// parsePointID parses a numeric or UUID point ID.
+func parsePointID(text string) (*qdrant.PointId, error) {
+	if num, err := strconv.ParseUint(text, 10, 64); err == nil {
+		return qdrant.NewIDNum(num), nil
+	}
+	parsed, err := uuid.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid point ID %q, expected an unsigned integer or a UUID", text)
+	}
+	return qdrant.NewIDUUID(parsed.String()), nil
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePointIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "numbers", input: "1\n2\n3\n", want: []string{"1", "2", "3"}},
+		{name: "uuids", input: "3FA85F64-5717-4562-B3FC-2C963F66AFA6\n", want: []string{"3fa85f64-5717-4562-b3fc-2c963f66afa6"}},
+		{name: "comments and blank lines", input: "# missing in target\n\n  42  \n", want: []string{"42"}},
+		{name: "duplicates", input: "7\n8\n7\n", want: []string{"7", "8"}},
+		{name: "negative", input: "-1\n", wantErr: true},
+		{name: "invalid", input: "1\nabc\n", wantErr: true},
+		{name: "empty", input: "# nothing\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, err := parsePointIDs(strings.NewReader(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePointIDs() = %v, want an error", ids)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePointIDs() error = %v", err)
+			}
+			var got []string
+			for _, id := range ids {
+				got = append(got, pointIDToString(id))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("parsePointIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
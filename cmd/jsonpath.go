@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathStep is a key of an object or an index of a list.
+type jsonPathStep struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// parseJSONPath parses the subset of JSONPath that addresses a single value:
+// $ for the document, .key or ['key'] for keys of objects, and [0] for elements of lists, e.g. $.data[0].embedding.
+func parseJSONPath(path string) ([]jsonPathStep, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid JSONPath %q, expected it to start with $", path)
+	}
+
+	var steps []jsonPathStep
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, expected a key after .", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[:end], isKey: true})
+			rest = rest[end:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, unterminated ['", path)
+			}
+			steps = append(steps, jsonPathStep{key: rest[2:end], isKey: true})
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, unterminated [", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q, expected a list index in []", path)
+			}
+			steps = append(steps, jsonPathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// evalJSONPath returns the value at a path of a decoded JSON document, and whether the document has it.
+func evalJSONPath(document any, steps []jsonPathStep) (any, bool) {
+	value := document
+	for _, step := range steps {
+		if step.isKey {
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			value, ok = object[step.key]
+			if !ok {
+				return nil, false
+			}
+			continue
+		}
+		list, ok := value.([]any)
+		if !ok || step.index >= len(list) {
+			return nil, false
+		}
+		value = list[step.index]
+	}
+	return value, true
+}
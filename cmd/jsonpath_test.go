@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []jsonPathStep
+		wantErr bool
+	}{
+		{path: "$", want: nil},
+		{path: "$.data", want: []jsonPathStep{{key: "data", isKey: true}}},
+		{path: "$.data[0].embedding", want: []jsonPathStep{{key: "data", isKey: true}, {index: 0}, {key: "embedding", isKey: true}}},
+		{path: "$['with.dot'][2]", want: []jsonPathStep{{key: "with.dot", isKey: true}, {index: 2}}},
+		{path: "data", wantErr: true},
+		{path: "$.", wantErr: true},
+		{path: "$[x]", wantErr: true},
+		{path: "$[-1]", wantErr: true},
+		{path: "$['open", wantErr: true},
+		{path: "$.a*", want: []jsonPathStep{{key: "a*", isKey: true}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseJSONPath(%q) = %v, want an error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) error = %v", tt.path, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseJSONPath(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPath(t *testing.T) {
+	document := map[string]any{
+		"data": []any{
+			map[string]any{"id": "a", "embedding": []any{1.0, 2.0}},
+		},
+		"next": nil,
+	}
+
+	tests := []struct {
+		path   string
+		want   any
+		wantOk bool
+	}{
+		{path: "$.data[0].id", want: "a", wantOk: true},
+		{path: "$.data[0].embedding[1]", want: 2.0, wantOk: true},
+		{path: "$.next", want: nil, wantOk: true},
+		{path: "$.data[1]", wantOk: false},
+		{path: "$.missing", wantOk: false},
+		{path: "$.data.id", wantOk: false},
+		{path: "$.data[0].id[0]", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			steps, err := parseJSONPath(tt.path)
+			if err != nil {
+				t.Fatalf("parseJSONPath(%q) error = %v", tt.path, err)
+			}
+			got, ok := evalJSONPath(document, steps)
+			if ok != tt.wantOk || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evalJSONPath(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alecthomas/kong"
+	"gopkg.in/yaml.v3"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type K8sManifestCmd struct {
+	Name      string   `help:"Name of the Job or CronJob. The ConfigMap and Secret are named after it." default:"qdrant-migration"`
+	Namespace string   `help:"Namespace of the resources. Defaults to the namespace of the kubectl context."`
+	Image     string   `help:"Container image of the migration tool." default:"registry.cloud.qdrant.io/library/qdrant-migration"`
+	Schedule  string   `help:"Cron expression to render a CronJob that runs the command on a schedule instead of a Job, e.g. '0 */6 * * *'."`
+	CPU       string   `help:"CPU request of the container." default:"1"`
+	Memory    string   `help:"Memory request and limit of the container, e.g. 2Gi. Defaults to twice --migration.max-memory of the command, or 1Gi."`
+	Output    string   `short:"o" help:"File to write the manifest to. Defaults to stdout."`
+	Command   []string `arg:"" passthrough:"" help:"Command to run in the container, with its flags, e.g. -- qdrant --source.url ..."`
+}
+
+// Memory request used when neither --memory nor --migration.max-memory is given.
+const defaultK8sMemory = "1Gi"
+
+// Directories the files of the command are mounted at in the container, and its embedding cache is written to.
+const (
+	k8sFilesDir = "/etc/qdrant-migration"
+	k8sCacheDir = "/var/cache/qdrant-migration"
+)
+
+type k8sMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type k8sObject struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   k8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+	Spec       any               `yaml:"spec,omitempty"`
+}
+
+type k8sCronJobSpec struct {
+	Schedule          string         `yaml:"schedule"`
+	ConcurrencyPolicy string         `yaml:"concurrencyPolicy"`
+	JobTemplate       map[string]any `yaml:"jobTemplate"`
+}
+
+type k8sJobSpec struct {
+	BackoffLimit int            `yaml:"backoffLimit"`
+	Template     k8sPodTemplate `yaml:"template"`
+}
+
+type k8sPodTemplate struct {
+	Spec k8sPodSpec `yaml:"spec"`
+}
+
+type k8sPodSpec struct {
+	RestartPolicy string           `yaml:"restartPolicy"`
+	Containers    []k8sContainer   `yaml:"containers"`
+	Volumes       []map[string]any `yaml:"volumes,omitempty"`
+}
+
+type k8sContainer struct {
+	Name         string            `yaml:"name"`
+	Image        string            `yaml:"image"`
+	Args         []string          `yaml:"args"`
+	EnvFrom      []map[string]any  `yaml:"envFrom"`
+	VolumeMounts []map[string]any  `yaml:"volumeMounts,omitempty"`
+	Resources    k8sResourceLimits `yaml:"resources"`
+}
+
+type k8sResourceLimits struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+func (r *K8sManifestCmd) Validate() error {
+	if len(r.Command) > 0 && r.Command[0] == "--" {
+		r.Command = r.Command[1:]
+	}
+	if len(r.Command) == 0 {
+		return errors.New("a command to run in the container is required")
+	}
+	if r.Schedule != "" {
+		if _, err := parseCron(r.Schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *K8sManifestCmd) Run(_ *Globals) error {
+	ctx, _, err := parseCommand(r.Command)
+	if err != nil {
+		return fmt.Errorf("invalid command: %w", err)
+	}
+	command := strings.Fields(ctx.Command())
+	if len(command) == 0 || command[0] == "serve" || command[0] == "schedule" || command[0] == "k8s-manifest" {
+		return fmt.Errorf("the command '%s' can't be run as a Kubernetes job", ctx.Command())
+	}
+
+	manifest, err := r.render(ctx)
+	if err != nil {
+		return err
+	}
+
+	if r.Output == "" {
+		_, err = os.Stdout.Write(manifest)
+		return err
+	}
+	err = os.WriteFile(r.Output, manifest, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// render returns the ConfigMap, the Secret if any flag holds a secret, and the Job or CronJob.
+// Flag values are passed through environment variables, which Kubernetes substitutes in the container arguments.
+// The files the command reads are embedded in a ConfigMap of their own, or a Secret if they may hold credentials,
+// and mounted into the container.
+func (r *K8sManifestCmd) render(ctx *kong.Context) ([]byte, error) {
+	config := make(map[string]string)
+	secrets := make(map[string]string)
+	files := make(map[string]string)
+	binaryFiles := make(map[string]string)
+	secretFiles := make(map[string]string)
+	var fileItems, secretItems []map[string]string
+	var cache bool
+	args := make([]string, 0, len(ctx.Path))
+	seen := make(map[string]bool)
+
+	// Only the command and the flags given on the command line, the defaults apply in the container too.
+	for _, path := range ctx.Path {
+		switch {
+		case path.Command != nil:
+			args = append(args, path.Command.Name)
+		case path.Flag != nil:
+			name := path.Flag.Name
+			// Repeated flags accumulate in their target, which holds all their values already.
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			if file, secret := k8sFileFlag(path.Flag); file {
+				value := formatFlagValue(path.Flag.Target)
+				if value == "" || value == "-" {
+					args = append(args, fmt.Sprintf("--%s=%s", name, value))
+					continue
+				}
+				content, err := os.ReadFile(value)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read file of --%s: %w", name, err)
+				}
+				// Every file gets a directory named after its flag, and keeps its name, whose extension may tell its format.
+				item := map[string]string{"key": name, "path": name + "/" + filepath.Base(value)}
+				switch {
+				case secret:
+					secretFiles[name] = base64.StdEncoding.EncodeToString(content)
+					secretItems = append(secretItems, item)
+				case utf8.Valid(content):
+					files[name] = string(content)
+					fileItems = append(fileItems, item)
+				default:
+					binaryFiles[name] = base64.StdEncoding.EncodeToString(content)
+					fileItems = append(fileItems, item)
+				}
+				args = append(args, fmt.Sprintf("--%s=%s/%s", name, k8sFilesDir, item["path"]))
+				continue
+			}
+			if name == "migration.embed.cache-file" {
+				// The cache is written to, so it lives in a volume of the pod, and starts empty in every pod.
+				cache = true
+				args = append(args, fmt.Sprintf("--%s=%s/%s", name, k8sCacheDir, filepath.Base(formatFlagValue(path.Flag.Target))))
+				continue
+			}
+
+			// Lists and maps are given one value per flag, so values that contain separators stay intact.
+			values := formatFlagValues(path.Flag.Target)
+			for i, value := range values {
+				env := k8sEnvName(name)
+				if len(values) > 1 {
+					env = fmt.Sprintf("%s_%d", env, i)
+				}
+				if redacted := redactFlagValue(name, value); redacted != value {
+					secrets[env] = value
+				} else {
+					config[env] = value
+				}
+				args = append(args, fmt.Sprintf("--%s=$(%s)", name, env))
+			}
+		}
+	}
+
+	memory := r.memoryRequest(ctx)
+
+	metadata := k8sMetadata{Name: r.Name, Namespace: r.Namespace}
+	configName := r.Name + "-config"
+	secretName := r.Name + "-secret"
+	filesName := r.Name + "-files"
+	secretFilesName := r.Name + "-secret-files"
+
+	envFrom := []map[string]any{{"configMapRef": map[string]string{"name": configName}}}
+	if len(secrets) > 0 {
+		envFrom = append(envFrom, map[string]any{"secretRef": map[string]string{"name": secretName}})
+	}
+
+	var volumes []map[string]any
+	var volumeMounts []map[string]any
+	if len(fileItems) > 0 || len(secretItems) > 0 {
+		var sources []map[string]any
+		if len(fileItems) > 0 {
+			sources = append(sources, map[string]any{"configMap": map[string]any{"name": filesName, "items": fileItems}})
+		}
+		if len(secretItems) > 0 {
+			sources = append(sources, map[string]any{"secret": map[string]any{"name": secretFilesName, "items": secretItems}})
+		}
+		volumes = append(volumes, map[string]any{"name": "files", "projected": map[string]any{"sources": sources}})
+		volumeMounts = append(volumeMounts, map[string]any{"name": "files", "mountPath": k8sFilesDir, "readOnly": true})
+	}
+	if cache {
+		volumes = append(volumes, map[string]any{"name": "cache", "emptyDir": map[string]any{}})
+		volumeMounts = append(volumeMounts, map[string]any{"name": "cache", "mountPath": k8sCacheDir})
+	}
+
+	jobSpec := k8sJobSpec{
+		// Retried runs continue from the offsets stored in the target.
+		BackoffLimit: 3,
+		Template: k8sPodTemplate{Spec: k8sPodSpec{
+			RestartPolicy: "Never",
+			Containers: []k8sContainer{{
+				Name:         "migration",
+				Image:        r.Image,
+				Args:         args,
+				EnvFrom:      envFrom,
+				VolumeMounts: volumeMounts,
+				Resources: k8sResourceLimits{
+					Requests: map[string]string{"cpu": r.CPU, "memory": memory},
+					Limits:   map[string]string{"memory": memory},
+				},
+			}},
+			Volumes: volumes,
+		}},
+	}
+
+	objects := []k8sObject{{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   k8sMetadata{Name: configName, Namespace: r.Namespace},
+		Data:       config,
+	}}
+	if len(secrets) > 0 {
+		objects = append(objects, k8sObject{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   k8sMetadata{Name: secretName, Namespace: r.Namespace},
+			Type:       "Opaque",
+			StringData: secrets,
+		})
+	}
+	// Files are kept apart from the flags, which are all passed as environment variables.
+	if len(fileItems) > 0 {
+		objects = append(objects, k8sObject{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata:   k8sMetadata{Name: filesName, Namespace: r.Namespace},
+			Data:       files,
+			BinaryData: binaryFiles,
+		})
+	}
+	if len(secretItems) > 0 {
+		objects = append(objects, k8sObject{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   k8sMetadata{Name: secretFilesName, Namespace: r.Namespace},
+			Type:       "Opaque",
+			Data:       secretFiles,
+		})
+	}
+	if r.Schedule != "" {
+		objects = append(objects, k8sObject{
+			APIVersion: "batch/v1",
+			Kind:       "CronJob",
+			Metadata:   metadata,
+			Spec: k8sCronJobSpec{
+				Schedule: r.Schedule,
+				// Like the schedule command, runs never overlap.
+				ConcurrencyPolicy: "Forbid",
+				JobTemplate:       map[string]any{"spec": jobSpec},
+			},
+		})
+	} else {
+		objects = append(objects, k8sObject{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Metadata:   metadata,
+			Spec:       jobSpec,
+		})
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, object := range objects {
+		err := encoder.Encode(object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode manifest: %w", err)
+		}
+	}
+	err := encoder.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// memoryRequest leaves as much memory again as the command may buffer for the runtime and the clients.
+func (r *K8sManifestCmd) memoryRequest(ctx *kong.Context) string {
+	if r.Memory != "" {
+		return r.Memory
+	}
+
+	for _, flag := range ctx.Flags() {
+		if flag.Name != "migration.max-memory" {
+			continue
+		}
+		if maxMemory, ok := flag.Target.Interface().(commons.ByteSize); ok && maxMemory > 0 {
+			mebibytes := (2*int64(maxMemory) + (1<<20 - 1)) >> 20
+			return fmt.Sprintf("%dMi", mebibytes)
+		}
+	}
+
+	return defaultK8sMemory
+}
+
+// k8sEnvName turns a flag name like source.api-key into an environment variable name like SOURCE_API_KEY.
+func k8sEnvName(flag string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(flag))
+}
+
+// k8sFileFlag reports whether a flag holds the path of a file the command reads, and whether the file may hold secrets.
+func k8sFileFlag(flag *kong.Flag) (file bool, secret bool) {
+	switch flag.Target.Interface().(type) {
+	case commons.PayloadMapping:
+		return true, false
+	case commons.RestSpec, commons.GrpcSourceSpec:
+		// Specs may hold credentials in their headers or metadata.
+		return true, true
+	}
+	switch {
+	case flag.Tag.Type == "existingfile":
+		return true, strings.HasSuffix(flag.Name, "client-key")
+	case flag.Name == "migration.ids-file":
+		return true, false
+	case strings.HasSuffix(flag.Name, "encryption-key-file"), flag.Name == "profiles-file":
+		return true, true
+	}
+	return false, false
+}
+
+// formatFlagValues formats the value of a flag the way it's given on the command line, one value for every item
+// of a list and every entry of a map, in the order of their keys.
+func formatFlagValues(value reflect.Value) []string {
+	switch value.Kind() {
+	case reflect.Slice:
+		values := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			values = append(values, formatFlagValue(value.Index(i)))
+		}
+		return values
+	case reflect.Map:
+		keys := value.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+		})
+		values := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values = append(values, fmt.Sprintf("%s=%s", formatFlagValue(key), formatFlagValue(value.MapIndex(key))))
+		}
+		return values
+	}
+	return []string{formatFlagValue(value)}
+}
+
+// formatFlagValue formats a single value of a flag the way it's given on the command line.
+func formatFlagValue(value reflect.Value) string {
+	// Byte sizes are printed rounded, so they are given in bytes.
+	switch v := value.Interface().(type) {
+	case commons.ByteSize:
+		return strconv.FormatInt(int64(v), 10)
+	case commons.Bandwidth:
+		return strconv.FormatInt(int64(v), 10) + "/s"
+	}
+	if stringer, ok := value.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
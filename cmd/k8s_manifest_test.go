@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_K8sManifestCmd_render(t *testing.T) {
+	ctx, _, err := parseCommand([]string{
+		"qdrant",
+		"--source.url", "http://source:6334",
+		"--source.collection", "source",
+		"--target.url", "http://target:6334",
+		"--target.api-key", "secret-key",
+		"--target.collection", "target",
+		"--migration.max-memory", "512MiB",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &K8sManifestCmd{Name: "sync", Image: "migration", CPU: "1", Schedule: "0 */6 * * *"}
+	manifest, err := cmd.render(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"kind: ConfigMap",
+		"SOURCE_URL: http://source:6334",
+		"kind: Secret",
+		"TARGET_API_KEY: secret-key",
+		"kind: CronJob",
+		"concurrencyPolicy: Forbid",
+		"- --target.api-key=$(TARGET_API_KEY)",
+		`MIGRATION_MAX_MEMORY: "536870912"`,
+		"memory: 1024Mi",
+	} {
+		if !strings.Contains(string(manifest), want) {
+			t.Errorf("manifest doesn't contain %q:\n%s", want, manifest)
+		}
+	}
+
+	// The secret must not end up in the ConfigMap.
+	configMap, _, _ := strings.Cut(string(manifest), "kind: Secret")
+	if strings.Contains(configMap, "secret-key") {
+		t.Errorf("ConfigMap contains the API key:\n%s", configMap)
+	}
+}
+
+func Test_K8sManifestCmd_renderFiles(t *testing.T) {
+	dir := t.TempDir()
+	mapping := filepath.Join(dir, "mapping.yaml")
+	if err := os.WriteFile(mapping, []byte("fields:\n  title:\n    rename: name\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	key := filepath.Join(dir, "client.key")
+	if err := os.WriteFile(key, []byte("PRIVATE KEY"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, _, err := parseCommand([]string{
+		"qdrant",
+		"--source.collection", "source",
+		"--target.collection", "target",
+		"--target.client-key", key,
+		"--migration.mapping-file", mapping,
+		"--migration.add-payload", "source=qdrant",
+		"--migration.add-payload", "tags={{.a}},{{.b}}",
+		"--migration.embed.cache-file", "/home/user/embeddings.cache",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &K8sManifestCmd{Name: "sync", Image: "migration", CPU: "1"}
+	manifest, err := cmd.render(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"name: sync-files",
+		"migration.mapping-file: |",
+		"name: sync-secret-files",
+		"- --migration.mapping-file=/etc/qdrant-migration/migration.mapping-file/mapping.yaml",
+		"target.client-key: " + base64.StdEncoding.EncodeToString([]byte("PRIVATE KEY")),
+		"- --target.client-key=/etc/qdrant-migration/target.client-key/client.key",
+		"mountPath: /etc/qdrant-migration",
+		"MIGRATION_ADD_PAYLOAD_0: source=qdrant",
+		"MIGRATION_ADD_PAYLOAD_1: tags={{.a}},{{.b}}",
+		"- --migration.add-payload=$(MIGRATION_ADD_PAYLOAD_1)",
+		"- --migration.embed.cache-file=/var/cache/qdrant-migration/embeddings.cache",
+		"emptyDir: {}",
+	} {
+		if !strings.Contains(string(manifest), want) {
+			t.Errorf("manifest doesn't contain %q:\n%s", want, manifest)
+		}
+	}
+
+	for _, unwanted := range []string{dir, "/home/user"} {
+		if strings.Contains(string(manifest), unwanted) {
+			t.Errorf("manifest contains the host path %q:\n%s", unwanted, manifest)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// keyToOffset converts the last key of a batch read by keyset pagination to the offset it's checkpointed as.
+// Integer keys are stored as the bits of the signed key, since offsets are unsigned point IDs, text and UUID keys as strings.
+func keyToOffset(key any) (*qdrant.PointId, error) {
+	switch k := key.(type) {
+	case string:
+		return qdrant.NewID(k), nil
+	case []byte:
+		if utf8.Valid(k) {
+			return qdrant.NewID(string(k)), nil
+		}
+	case [16]byte:
+		return qdrant.NewID(uuid.UUID(k).String()), nil
+	case uuid.UUID:
+		return qdrant.NewID(k.String()), nil
+	case uint64:
+		// offsetToKey reads the bits back as a signed key, which larger keys wouldn't survive.
+		if k > math.MaxInt64 {
+			return nil, fmt.Errorf("key %d is larger than the largest signed 64-bit integer, which keyset pagination can't checkpoint", k)
+		}
+		return qdrant.NewIDNum(k), nil
+	case uint32:
+		return qdrant.NewIDNum(uint64(k)), nil
+	default:
+		if number, err := toInt64(key); err == nil {
+			return qdrant.NewIDNum(uint64(number)), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported key type %T, keyset pagination requires an integer, text or UUID key column", key)
+}
+
+// offsetToKey converts a checkpointed offset back to the key to continue after, the reverse of keyToOffset.
+func offsetToKey(offset *qdrant.PointId) any {
+	if id, ok := offset.GetPointIdOptions().(*qdrant.PointId_Uuid); ok {
+		return id.Uuid
+	}
+	return int64(offset.GetNum())
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_keyToOffset(t *testing.T) {
+	id := uuid.MustParse("9a7a3b4c-5d6e-4f70-8192-a3b4c5d6e7f8")
+	tests := []struct {
+		name     string
+		key      any
+		expected any
+		wantErr  bool
+	}{
+		{name: "integer", key: int64(42), expected: int64(42)},
+		{name: "negative integer", key: int64(-7), expected: int64(-7)},
+		{name: "small integer", key: int32(3), expected: int64(3)},
+		{name: "text", key: "doc-1", expected: "doc-1"},
+		{name: "empty text", key: "", expected: ""},
+		{name: "text bytes", key: []byte("doc-2"), expected: "doc-2"},
+		{name: "uuid bytes", key: [16]byte(id), expected: id.String()},
+		{name: "uuid", key: id, expected: id.String()},
+		{name: "unsigned integer", key: uint64(42), expected: int64(42)},
+		{name: "unsigned integer above int64", key: uint64(math.MaxInt64) + 1, wantErr: true},
+		{name: "float", key: 1.5, wantErr: true},
+		{name: "binary", key: []byte{0xff, 0xfe}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := keyToOffset(tt.key)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("keyToOffset() got = %v, expected an error", offset)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("keyToOffset() error = %v", err)
+			}
+			if got := offsetToKey(offset); got != tt.expected {
+				t.Errorf("offsetToKey(keyToOffset()) got = %v (%T), expected %v (%T)", got, got, tt.expected, tt.expected)
+			}
+		})
+	}
+}
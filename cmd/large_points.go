@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// clientMessageSizes holds the largest request every client can send, to split requests that would exceed it.
+// It starts at --qdrant.max-send-message-size, and is lowered to the limit of the target once a request exceeds it.
+var clientMessageSizes sync.Map
+
+// Limits of the message size, as the gRPC servers of Qdrant and of Go, e.g. in proxies, report them.
+var messageLimitPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`the limit is: (\d+) bytes`),
+	regexp.MustCompile(`larger than max \(\d+ vs\. (\d+)\)`),
+}
+
+// Room left in a request for everything but the points or payload, like the collection name and the shard key selector.
+const requestOverhead = 64 * 1024
+
+var largePointWarning sync.Once
+
+// maxSendMessageSize returns the largest message to send to an endpoint, which is the largest one received from it unless set apart,
+// since a source that returns large scrolls and a constrained target need different limits.
+func maxSendMessageSize(config commons.QdrantConfig) int {
+	if config.MaxSendMessageSize != 0 {
+		return config.MaxSendMessageSize
+	}
+	return config.MaxMessageSize
+}
+
+// messageSizeLimit returns the largest size of the points or payload of a single request to the client, or 0 if it has no limit.
+func messageSizeLimit(client *qdrant.Client) int {
+	size, ok := clientMessageSizes.Load(client)
+	if !ok {
+		return 0
+	}
+	return max(size.(int)-requestOverhead, 1)
+}
+
+// learnMessageSizeLimit lowers the message size limit of a client after the target rejected a request of the given size as too large.
+// It reports whether the error was such a rejection.
+func learnMessageSizeLimit(client *qdrant.Client, err error, size int) bool {
+	s, ok := status.FromError(err)
+	if !ok || (s.Code() != codes.OutOfRange && s.Code() != codes.ResourceExhausted) {
+		return false
+	}
+	message := strings.ToLower(s.Message())
+	if !strings.Contains(message, "too large") && !strings.Contains(message, "larger than") {
+		return false
+	}
+
+	// Without a limit in the message, the next attempt is made with half the size.
+	limit := size / 2
+	for _, pattern := range messageLimitPatterns {
+		if match := pattern.FindStringSubmatch(message); match != nil {
+			limit, _ = strconv.Atoi(match[1])
+			break
+		}
+	}
+	// The request was rejected, so it's over the limit, whatever the message says.
+	if limit >= size {
+		limit = size / 2
+	}
+	if current, ok := clientMessageSizes.Load(client); !ok || limit < current.(int) {
+		clientMessageSizes.Store(client, limit)
+		pterm.Warning.Printfln("The target rejected a request of %s, so requests are kept below %s from now on", commons.ByteSize(size), commons.ByteSize(limit))
+	}
+	return true
+}
+
+// upsertSplittingLargePoints upserts the points of a request, in as many requests as needed to stay below the message size limit.
+// Points too large for a single request to the target, usually for their payload, are upserted with their vectors only,
+// and their payload is set in follow-up requests, so they don't fail the whole batch.
+// If the target rejects a request as too large, its limit is learned from the error, and the request is split to fit it.
+func upsertSplittingLargePoints(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints) error {
+	limit := messageSizeLimit(client)
+	size := proto.Size(request)
+	if limit == 0 || size <= limit {
+		_, err := client.Upsert(ctx, request)
+		if err != nil && learnMessageSizeLimit(client, err, size) {
+			return upsertSplittingLargePoints(ctx, client, request)
+		}
+		return err
+	}
+
+	var batch, large []*qdrant.PointStruct
+	batchSize := 0
+	for _, point := range request.GetPoints() {
+		pointSize := proto.Size(point)
+		if pointSize > limit {
+			large = append(large, point)
+			continue
+		}
+		if batchSize+pointSize > limit && len(batch) > 0 {
+			err := upsertSplittingLargePoints(ctx, client, withPoints(request, batch))
+			if err != nil {
+				return err
+			}
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, point)
+		batchSize += pointSize
+	}
+	if len(batch) > 0 {
+		err := upsertSplittingLargePoints(ctx, client, withPoints(request, batch))
+		if err != nil {
+			return err
+		}
+	}
+	for _, point := range large {
+		err := upsertLargePoint(ctx, client, request, point, limit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func upsertLargePoint(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints, point *qdrant.PointStruct, limit int) error {
+	id := pointIDToString(point.GetId())
+	largePointWarning.Do(func() {
+		pterm.Warning.Printfln("Point %s is %s, over the limit of %s of --qdrant.max-send-message-size, so its vectors and payload are written in separate requests. Further points like it aren't reported.",
+			id, commons.ByteSize(proto.Size(point)), commons.ByteSize(limit))
+	})
+
+	vectorsOnly := &qdrant.PointStruct{Id: point.GetId(), Vectors: point.GetVectors()}
+	if proto.Size(vectorsOnly) > limit {
+		return fmt.Errorf("the vectors of point %s are %s, over the limit of %s of --qdrant.max-send-message-size", id, commons.ByteSize(proto.Size(vectorsOnly)), commons.ByteSize(limit))
+	}
+	err := upsertSplittingLargePoints(ctx, client, withPoints(request, []*qdrant.PointStruct{vectorsOnly}))
+	if err != nil {
+		return err
+	}
+
+	chunks, err := payloadChunks(point.GetPayload(), limit)
+	if err != nil {
+		return fmt.Errorf("failed to split the payload of point %s: %w", id, err)
+	}
+	for _, chunk := range chunks {
+		_, err = client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+			CollectionName:   request.GetCollectionName(),
+			Wait:             request.Wait,
+			Payload:          chunk,
+			PointsSelector:   qdrant.NewPointsSelector(point.GetId()),
+			Ordering:         request.GetOrdering(),
+			ShardKeySelector: request.GetShardKeySelector(),
+		})
+		if err != nil && learnMessageSizeLimit(client, err, proto.Size(&qdrant.SetPayloadPoints{Payload: chunk})) {
+			return upsertLargePoint(ctx, client, request, point, messageSizeLimit(client))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set the payload of point %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// payloadChunks splits a payload into parts of whole top-level fields, each at most limit bytes.
+func payloadChunks(payload map[string]*qdrant.Value, limit int) ([]map[string]*qdrant.Value, error) {
+	keys := make([]string, 0, len(payload))
+	for key := range payload {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var chunks []map[string]*qdrant.Value
+	chunk := make(map[string]*qdrant.Value)
+	size := 0
+	for _, key := range keys {
+		fieldSize := proto.Size(&qdrant.SetPayloadPoints{Payload: map[string]*qdrant.Value{key: payload[key]}})
+		if fieldSize > limit {
+			return nil, fmt.Errorf("payload field '%s' is %s on its own, limit the size of payload values with --migration.max-payload-value-size", key, commons.ByteSize(fieldSize))
+		}
+		if size+fieldSize > limit && len(chunk) > 0 {
+			chunks = append(chunks, chunk)
+			chunk = make(map[string]*qdrant.Value)
+			size = 0
+		}
+		chunk[key] = payload[key]
+		size += fieldSize
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+func withPoints(request *qdrant.UpsertPoints, points []*qdrant.PointStruct) *qdrant.UpsertPoints {
+	return &qdrant.UpsertPoints{
+		CollectionName:   request.GetCollectionName(),
+		Wait:             request.Wait,
+		Points:           points,
+		Ordering:         request.GetOrdering(),
+		ShardKeySelector: request.GetShardKeySelector(),
+	}
+}
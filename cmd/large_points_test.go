@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_payloadChunks(t *testing.T) {
+	payload := qdrant.NewValueMap(map[string]any{
+		"a": strings.Repeat("x", 400),
+		"b": strings.Repeat("x", 400),
+		"c": strings.Repeat("x", 400),
+		"d": "small",
+	})
+
+	chunks, err := payloadChunks(payload, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	fields := 0
+	for _, chunk := range chunks {
+		fields += len(chunk)
+	}
+	if fields != 4 {
+		t.Errorf("chunks have %d fields, want all 4", fields)
+	}
+
+	_, err = payloadChunks(payload, 300)
+	if err == nil || !strings.Contains(err.Error(), "payload field 'a'") {
+		t.Errorf("got error %v, want one naming the field that can't be split", err)
+	}
+}
+
+func Test_learnMessageSizeLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+		learned  bool
+	}{
+		{name: "qdrant", err: status.Error(codes.OutOfRange, "Error, decoded message length too large: found 40000000 bytes, the limit is: 33554432 bytes"), expected: 33554432, learned: true},
+		{name: "go", err: status.Error(codes.ResourceExhausted, "grpc: received message larger than max (40000000 vs. 4194304)"), expected: 4194304, learned: true},
+		{name: "proxy without limit", err: status.Error(codes.ResourceExhausted, "request entity too large"), expected: 20000000, learned: true},
+		{name: "rate limit", err: status.Error(codes.ResourceExhausted, "rate limit exceeded"), learned: false},
+		{name: "other error", err: status.Error(codes.NotFound, "collection not found"), learned: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &qdrant.Client{}
+			if learned := learnMessageSizeLimit(client, tt.err, 40000000); learned != tt.learned {
+				t.Fatalf("learnMessageSizeLimit() = %v, expected %v", learned, tt.learned)
+			}
+			if !tt.learned {
+				return
+			}
+			limit, _ := clientMessageSizes.Load(client)
+			if limit != tt.expected {
+				t.Errorf("learned a limit of %v, expected %d", limit, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_maxSendMessageSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   commons.QdrantConfig
+		expected int
+	}{
+		{name: "receive size", config: commons.QdrantConfig{MaxMessageSize: 33554432}, expected: 33554432},
+		{name: "own send size", config: commons.QdrantConfig{MaxMessageSize: 268435456, MaxSendMessageSize: 4194304}, expected: 4194304},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := maxSendMessageSize(tt.config); got != tt.expected {
+				t.Errorf("maxSendMessageSize() got = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
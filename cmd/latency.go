@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Latencies are counted in buckets that grow by 10% from 100µs, so percentiles are exact to 10%,
+// and the memory doesn't grow with the number of batches.
+const (
+	latencyBucketBase   = 100 * time.Microsecond
+	latencyBucketGrowth = 1.1
+)
+
+// latencyHistogram is the distribution of the latencies of batches.
+type latencyHistogram struct {
+	buckets map[int]uint64
+	count   uint64
+	max     time.Duration
+}
+
+func latencyBucket(d time.Duration) int {
+	if d <= latencyBucketBase {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(d)/float64(latencyBucketBase)) / math.Log(latencyBucketGrowth)))
+}
+
+// latencyBucketBound returns the upper bound of a bucket.
+func latencyBucketBound(bucket int) time.Duration {
+	return time.Duration(float64(latencyBucketBase) * math.Pow(latencyBucketGrowth, float64(bucket)))
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make(map[int]uint64)
+	}
+	h.buckets[latencyBucket(d)]++
+	h.count++
+	h.max = max(h.max, d)
+}
+
+// percentile returns the latency that the given fraction of the batches took at most,
+// as the upper bound of its bucket, but never more than the maximum.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(h.count)))
+	seen := uint64(0)
+	for bucket := 0; seen < h.count; bucket++ {
+		seen += h.buckets[bucket]
+		if seen >= rank {
+			return min(latencyBucketBound(bucket), h.max)
+		}
+	}
+	return h.max
+}
+
+// reportLatencies are the percentiles of the latencies of batches, in milliseconds.
+type reportLatencies struct {
+	Batches uint64  `json:"batches"`
+	P50     float64 `json:"p50_ms"`
+	P95     float64 `json:"p95_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+func (h *latencyHistogram) summary() *reportLatencies {
+	if h.count == 0 {
+		return nil
+	}
+	ms := func(d time.Duration) float64 {
+		return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+	}
+	return &reportLatencies{
+		Batches: h.count,
+		P50:     ms(h.percentile(0.50)),
+		P95:     ms(h.percentile(0.95)),
+		P99:     ms(h.percentile(0.99)),
+		Max:     ms(h.max),
+	}
+}
+
+func (l *reportLatencies) String() string {
+	return fmt.Sprintf("p50 %.1fms, p95 %.1fms, p99 %.1fms, max %.1fms over %d batches", l.P50, l.P95, l.P99, l.Max, l.Batches)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if h.summary() != nil {
+		t.Fatal("summary() of an empty histogram isn't nil")
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.observe(time.Duration(i) * time.Millisecond)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0.50, 50 * time.Millisecond},
+		{0.95, 95 * time.Millisecond},
+		{0.99, 99 * time.Millisecond},
+		{1, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := h.percentile(tt.p)
+		// Buckets are 10% wide, and a percentile is the upper bound of its bucket.
+		if got < tt.want || got > tt.want*11/10 {
+			t.Errorf("percentile(%v) = %v, want %v to %v", tt.p, got, tt.want, tt.want*11/10)
+		}
+	}
+
+	summary := h.summary()
+	if summary.Batches != 100 || summary.Max != 100 {
+		t.Errorf("summary() = %+v, want 100 batches with a max of 100ms", summary)
+	}
+}
@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type LoadCmd struct {
+	Qdrant    commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Migration commons.MigrationConfig `embed:"" prefix:"migration."`
+	Load      commons.LoadConfig      `embed:"" prefix:"load."`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+}
+
+func (r *LoadCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	if r.Load.Collection == "" {
+		r.Load.Collection = r.Qdrant.Collection
+	}
+
+	return nil
+}
+
+func (r *LoadCmd) Validate() error {
+	if isSampled(r.Migration) {
+		return fmt.Errorf("--migration.sample and --migration.limit aren't supported by load, it writes all points of the export")
+	}
+	if isSinkTarget(r.Migration) {
+		return fmt.Errorf("--migration.target=%s isn't supported by load, the export files already have the points", r.Migration.Target)
+	}
+	if r.Load.Parallel < 1 {
+		return fmt.Errorf("parallel must be greater than 0")
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *LoadCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Collection Load")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	key, err := loadEncryptionKey(r.Load.EncryptionKey, r.Load.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	source, err := newExportSource(ctx, r.Load.Path, r.Load.AWS)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := readExportManifest(ctx, source, r.Load.Collection)
+	if err != nil {
+		return err
+	}
+	if manifest.Encrypted && key == nil {
+		return fmt.Errorf("the export is encrypted, set --load.encryption-key or --load.encryption-key-file")
+	}
+	if key != nil {
+		err = verifyManifest(*manifest, key)
+		if err != nil {
+			return err
+		}
+		source = encryptedSource{source: source, key: key}
+	}
+
+	var collectionConfig qdrant.CollectionConfig
+	if len(manifest.Config) > 0 {
+		err = protojson.Unmarshal(manifest.Config, &collectionConfig)
+		if err != nil {
+			return fmt.Errorf("failed to decode collection config: %w", err)
+		}
+	}
+
+	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+	defer targetClient.Close()
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	err = r.prepareTargetCollection(ctx, targetClient, &collectionConfig)
+	if err != nil {
+		return fmt.Errorf("error preparing target collection: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("export", r.Load.Path, r.Qdrant.Collection)
+
+	err = r.loadData(ctx, source, manifest, collectionConfig.GetParams().GetVectorsConfig().GetParams() != nil, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to load data: %w", err)
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, manifest.PointsCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(manifest.PointsCount, targetPointCount)
+
+	return nil
+}
+
+func (r *LoadCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client, config *qdrant.CollectionConfig) error {
+	if !r.Migration.CreateCollection {
+		return nil
+	}
+
+	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+
+	if targetCollectionExists {
+		pterm.Info.Printfln("Target collection '%s' already exists. Skipping creation.", r.Qdrant.Collection)
+		return nil
+	}
+
+	if config.GetParams() == nil {
+		return fmt.Errorf("the export has no collection config, create collection '%s' first", r.Qdrant.Collection)
+	}
+
+	request := &qdrant.CreateCollection{
+		CollectionName:         r.Qdrant.Collection,
+		HnswConfig:             config.GetHnswConfig(),
+		WalConfig:              config.GetWalConfig(),
+		OptimizersConfig:       config.GetOptimizerConfig(),
+		ShardNumber:            &config.GetParams().ShardNumber,
+		OnDiskPayload:          &config.GetParams().OnDiskPayload,
+		VectorsConfig:          config.GetParams().VectorsConfig,
+		ReplicationFactor:      config.GetParams().ReplicationFactor,
+		WriteConsistencyFactor: config.GetParams().WriteConsistencyFactor,
+		QuantizationConfig:     config.GetQuantizationConfig(),
+		ShardingMethod:         config.GetParams().ShardingMethod,
+		SparseVectorsConfig:    config.GetParams().SparseVectorsConfig,
+		StrictModeConfig:       config.GetStrictModeConfig(),
+	}
+	applyTopology(request, r.Migration)
+	err = targetClient.CreateCollection(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to create target collection: %w", err)
+	}
+
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Created target collection '%s'", r.Qdrant.Collection)
+	return nil
+}
+
+// loadData upserts the files of an export, up to --load.parallel of them at a time.
+// Every file has an offset with the number of its points loaded,
+// so an interrupted load continues in the middle of the files it stopped at.
+func (r *LoadCmd) loadData(ctx context.Context, source exportSource, manifest *exportManifest, unnamedVector bool, targetClient *qdrant.Client) error {
+	offsetKeys := make([]string, len(manifest.Files))
+	loaded := make([]uint64, len(manifest.Files))
+	totalLoaded := uint64(0)
+	for i, file := range manifest.Files {
+		offsetKeys[i] = path.Join(r.Load.Path, manifest.Collection, file.Name)
+		if r.Migration.Restart {
+			continue
+		}
+		_, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, offsetKeys[i])
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		loaded[i] = count
+		totalLoaded += count
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(manifest.PointsCount)).Start()
+	displayMigrationProgress(bar, totalLoaded)
+
+	var barLock sync.Mutex
+	budget := newMemoryBudget(r.Migration.MaxMemory)
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(r.Load.Parallel)
+	for i, file := range manifest.Files {
+		if loaded[i] >= file.Points {
+			continue
+		}
+		group.Go(func() error {
+			// The bytes of the next batch are reserved before its records are read, and once the batch before is written.
+			memory := budget.reader()
+			defer memory.release()
+			err := memory.reserve(groupCtx)
+			if err != nil {
+				return err
+			}
+			return readExportFile(groupCtx, source, file.Name, manifest.Format, r.Migration.BatchSize, loaded[i], func(records []exportRecord) error {
+				targetPoints := make([]*qdrant.PointStruct, 0, len(records))
+				for _, record := range records {
+					point, err := record.toPoint(unnamedVector)
+					if err != nil {
+						return err
+					}
+					targetPoints = append(targetPoints, point)
+				}
+
+				err := memory.fit(groupCtx, pointsSize(targetPoints))
+				if err != nil {
+					return err
+				}
+				writeStart := time.Now()
+				_, err = targetClient.Upsert(groupCtx, &qdrant.UpsertPoints{
+					CollectionName: r.Qdrant.Collection,
+					Points:         targetPoints,
+					Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to insert data into target: %w", err)
+				}
+				currentReport.observeWrite(time.Since(writeStart))
+				currentReport.addWritten(len(targetPoints))
+
+				loaded[i] += uint64(len(targetPoints))
+				err = commons.StoreStartOffset(groupCtx, r.Migration.OffsetsCollection, targetClient, offsetKeys[i], qdrant.NewIDNum(loaded[i]), loaded[i])
+				if err != nil {
+					return fmt.Errorf("failed to store offset: %w", err)
+				}
+
+				barLock.Lock()
+				bar.Add(len(targetPoints))
+				barLock.Unlock()
+				return memory.reserve(groupCtx)
+			})
+		})
+	}
+	err := group.Wait()
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+
+	return nil
+}
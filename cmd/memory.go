@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// memoryBudget caps the bytes of points that readers hold between reading and writing them.
+// A reader that would exceed it waits for the others to write their batches before reading more,
+// so memory stays bounded when the target is slower than the source.
+type memoryBudget struct {
+	size      int64
+	semaphore *semaphore.Weighted
+}
+
+// newMemoryBudget returns a budget of the given size, or nil if memory is unlimited.
+func newMemoryBudget(size commons.ByteSize) *memoryBudget {
+	if size <= 0 {
+		return nil
+	}
+	return &memoryBudget{size: int64(size), semaphore: semaphore.NewWeighted(int64(size))}
+}
+
+// pointsSize returns the bytes of points as they're sent and received.
+func pointsSize[T proto.Message](points []T) int64 {
+	n := int64(0)
+	for _, point := range points {
+		n += int64(proto.Size(point))
+	}
+	return n
+}
+
+// reader returns the share of the budget for a reader of batches, or nil if memory is unlimited.
+func (b *memoryBudget) reader() *memoryReader {
+	if b == nil {
+		return nil
+	}
+	// The size of a batch is only known once it's read, so the first one takes the whole budget.
+	return &memoryReader{budget: b, estimate: b.size}
+}
+
+// memoryReader takes the bytes of a batch from the budget before the batch is read, estimated by the size
+// of the batch before, and holds them until the next batch is read, by when the batch is written.
+type memoryReader struct {
+	budget   *memoryBudget
+	held     int64
+	estimate int64
+}
+
+// reserve gives back the bytes of the previous batch and waits until the next one fits in the budget.
+// It's called before every batch is read.
+func (r *memoryReader) reserve(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.release()
+	if err := r.budget.semaphore.Acquire(ctx, r.estimate); err != nil {
+		return err
+	}
+	r.held = r.estimate
+	return nil
+}
+
+// fit corrects the bytes held to the size of the batch that was read, which is the estimate for the next one.
+// A batch larger than estimated gives back what it holds before it waits for its size,
+// so readers that wait for more never hold parts of the budget the others wait for.
+// A batch larger than the whole budget takes all of it, so it's written alone instead of never.
+func (r *memoryReader) fit(ctx context.Context, size int64) error {
+	if r == nil {
+		return nil
+	}
+	n := min(size, r.budget.size)
+	r.estimate = n
+	if n > r.held {
+		r.release()
+		if err := r.budget.semaphore.Acquire(ctx, n); err != nil {
+			return err
+		}
+	} else {
+		r.budget.semaphore.Release(r.held - n)
+	}
+	r.held = n
+	return nil
+}
+
+// release gives back the bytes of the last batch, once it's written.
+func (r *memoryReader) release() {
+	if r == nil || r.held == 0 {
+		return
+	}
+	r.budget.semaphore.Release(r.held)
+	r.held = 0
+}
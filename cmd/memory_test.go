@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_memoryReader(t *testing.T) {
+	points := []*qdrant.PointStruct{{
+		Id:      qdrant.NewIDNum(1),
+		Vectors: qdrant.NewVectorsDense(make([]float32, 128)),
+	}}
+	size := pointsSize(points)
+
+	budget := newMemoryBudget(commons.ByteSize(size + size/2))
+	first, second := budget.reader(), budget.reader()
+
+	// Before its first batch is read, a reader takes the whole budget, so the other one waits.
+	if err := first.reserve(context.Background()); err != nil {
+		t.Fatalf("reserve() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := second.reserve(ctx); err == nil {
+		t.Fatalf("reserve() succeeded while the budget was exhausted")
+	}
+
+	// Once the batch is read, only its size is held, which leaves too little for another batch of the same size.
+	if err := first.fit(context.Background(), size); err != nil {
+		t.Fatalf("fit() error = %v", err)
+	}
+	if err := second.fit(context.Background(), size/4); err != nil {
+		t.Fatalf("fit() error = %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := second.fit(ctx, size); err == nil {
+		t.Fatalf("fit() succeeded while the budget was exhausted")
+	}
+
+	// A batch larger than the budget must still be admitted, alone.
+	first.release()
+	second.release()
+	if err := first.fit(context.Background(), 10*size); err != nil {
+		t.Fatalf("fit() error = %v for a batch larger than the budget", err)
+	}
+	first.release()
+
+	var unlimited *memoryReader
+	if err := unlimited.reserve(context.Background()); err != nil {
+		t.Fatalf("reserve() error = %v without a budget", err)
+	}
+	if err := unlimited.fit(context.Background(), size); err != nil {
+		t.Fatalf("fit() error = %v without a budget", err)
+	}
+	unlimited.release()
+}
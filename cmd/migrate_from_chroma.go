@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
 	"github.com/pterm/pterm"
@@ -24,7 +25,7 @@ type MigrateFromChromaCmd struct {
 	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
 	IdField        string                  `prefix:"qdrant." help:"Field storing Chroma IDs in Qdrant." default:"__id__"`
 	DenseVector    string                  `prefix:"qdrant." help:"Name of the dense vector in Qdrant" default:"dense_vector"`
-	DistanceMetric string                  `prefix:"qdrant." enum:"cosine,dot,euclid,manhattan" help:"Distance metric for the Qdrant collection" default:"euclid"`
+	DistanceMetric string                  `prefix:"qdrant." help:"Distance metric for the Qdrant collection (cosine,dot,euclid,manhattan). Defaults to the distance of the Chroma collection."`
 	DocumentField  string                  `prefix:"qdrant." help:"Field storing Chroma documents in Qdrant." default:"document"`
 
 	targetHost string
@@ -43,6 +44,9 @@ func (r *MigrateFromChromaCmd) Parse() error {
 }
 
 func (r *MigrateFromChromaCmd) Validate() error {
+	if r.DistanceMetric != "" && !isQdrantDistance(r.DistanceMetric) {
+		return fmt.Errorf("invalid distance metric '%s'", r.DistanceMetric)
+	}
 	return validateBatchSize(r.Migration.BatchSize)
 }
 
@@ -54,7 +58,7 @@ func (r *MigrateFromChromaCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	sourceClient, sourceCollection, err := r.connectToChroma(ctx)
@@ -64,11 +68,16 @@ func (r *MigrateFromChromaCmd) Run(globals *Globals) error {
 	defer sourceCollection.Close()
 	defer sourceClient.Close()
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
@@ -84,6 +93,11 @@ func (r *MigrateFromChromaCmd) Run(globals *Globals) error {
 		return fmt.Errorf("error preparing target collection: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("chroma", r.Chroma.Collection, r.Qdrant.Collection)
 
 	err = r.migrateData(ctx, sourceCollection, targetClient, sourcePointCount)
@@ -91,6 +105,28 @@ func (r *MigrateFromChromaCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -100,12 +136,13 @@ func (r *MigrateFromChromaCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
 
 func (r *MigrateFromChromaCmd) parseChromaOptions() ([]chroma.ClientOption, error) {
-	clientOptions := []chroma.ClientOption{chroma.WithBaseURL(r.Chroma.Url)}
+	clientOptions := []chroma.ClientOption{chroma.WithBaseURL(r.Chroma.Url), chroma.WithHTTPClient(newRateLimitedClient(nil))}
 
 	if r.Chroma.Database != "" && r.Chroma.Tenant != "" {
 		clientOptions = append(clientOptions, chroma.WithDatabaseAndTenant(r.Chroma.Database, r.Chroma.Tenant))
@@ -159,7 +196,7 @@ func (r *MigrateFromChromaCmd) countChromaVectors(ctx context.Context, collectio
 }
 
 func (r *MigrateFromChromaCmd) prepareTargetCollection(ctx context.Context, collection chroma.Collection, targetClient *qdrant.Client) error {
-	if !r.Migration.CreateCollection {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
 		return nil
 	}
 
@@ -173,11 +210,18 @@ func (r *MigrateFromChromaCmd) prepareTargetCollection(ctx context.Context, coll
 		return nil
 	}
 
-	distanceMapping := map[string]qdrant.Distance{
-		"euclid":    qdrant.Distance_Euclid,
-		"cosine":    qdrant.Distance_Cosine,
-		"dot":       qdrant.Distance_Dot,
-		"manhattan": qdrant.Distance_Manhattan,
+	var distance qdrant.Distance
+	if r.DistanceMetric != "" {
+		distance = translateDistance("qdrant", r.DistanceMetric, r.DenseVector)
+	} else {
+		// l2 is the default distance of Chroma.
+		space := "l2"
+		if metadata := collection.Metadata(); metadata != nil {
+			if configured, ok := metadata.GetString(chroma.HNSWSpace); ok {
+				space = configured
+			}
+		}
+		distance = translateDistance("chroma", space, r.DenseVector)
 	}
 
 	createReq := &qdrant.CreateCollection{
@@ -185,15 +229,27 @@ func (r *MigrateFromChromaCmd) prepareTargetCollection(ctx context.Context, coll
 		VectorsConfig: qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
 			r.DenseVector: {
 				Size:     uint64(collection.Dimension()),
-				Distance: distanceMapping[r.DistanceMetric],
+				Distance: distance,
 			},
 		}),
 	}
 
+	createReq.ShardingMethod = tenantShardingMethod(r.Migration, nil)
+	applyTopology(createReq, r.Migration)
+
 	if err := targetClient.CreateCollection(ctx, createReq); err != nil {
 		return fmt.Errorf("failed to create target collection: %w", err)
 	}
 
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	pterm.Success.Printfln("Created target collection '%s'", r.Qdrant.Collection)
 	return nil
 }
@@ -214,7 +270,15 @@ func (r *MigrateFromChromaCmd) migrateData(ctx context.Context, collection chrom
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
 	displayMigrationProgress(bar, currentOffset)
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		resp, err := collection.Get(
 			ctx,
 			chroma.WithLimitGet(int(batchSize)),
@@ -274,14 +338,26 @@ func (r *MigrateFromChromaCmd) migrateData(ctx context.Context, collection chrom
 			targetPoints = append(targetPoints, point)
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
 		currentOffset += uint64(count)
 		// Just a placeholder ID for offset tracking.
@@ -293,6 +369,10 @@ func (r *MigrateFromChromaCmd) migrateData(ctx context.Context, collection chrom
 		}
 
 		bar.Add(count)
+
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
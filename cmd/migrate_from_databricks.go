@@ -0,0 +1,575 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/oauth2"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// databricksOAuthScope is the scope of OAuth tokens of service principals for the REST APIs of a workspace.
+const databricksOAuthScope = "all-apis"
+
+type MigrateFromDatabricksCmd struct {
+	Databricks     commons.DatabricksConfig `embed:"" prefix:"databricks."`
+	Qdrant         commons.QdrantConfig     `embed:"" prefix:"qdrant."`
+	Migration      commons.MigrationConfig  `embed:"" prefix:"migration."`
+	IdField        string                   `prefix:"qdrant." help:"Field storing primary keys that aren't UUIDs or non-negative integers in Qdrant." default:"__id__"`
+	DistanceMetric map[string]string        `prefix:"qdrant." help:"Map of vector columns to distance metrics (cosine,dot,euclid,manhattan) to create the collection with. Default is euclid, the distance of Databricks Vector Search."`
+
+	index         databricksIndex
+	vectorColumns []string
+	targetHost    string
+	targetPort    int
+	targetTLS     bool
+}
+
+// databricksIndex is the description of a vector search index by the Databricks API.
+type databricksIndex struct {
+	Name                  string               `json:"name"`
+	PrimaryKey            string               `json:"primary_key"`
+	IndexType             string               `json:"index_type"`
+	DeltaSyncIndexSpec    *databricksIndexSpec `json:"delta_sync_index_spec"`
+	DirectAccessIndexSpec *databricksIndexSpec `json:"direct_access_index_spec"`
+	Status                struct {
+		IndexedRowCount uint64 `json:"indexed_row_count"`
+	} `json:"status"`
+}
+
+// databricksIndexSpec are the embedding columns of a Delta Sync or Direct Vector Access index. Indexes with vector
+// columns have embeddings of their own, indexes with source columns have them computed by Databricks.
+type databricksIndexSpec struct {
+	EmbeddingVectorColumns []databricksColumn `json:"embedding_vector_columns"`
+	EmbeddingSourceColumns []databricksColumn `json:"embedding_source_columns"`
+}
+
+type databricksColumn struct {
+	Name string `json:"name"`
+}
+
+func (i databricksIndex) spec() databricksIndexSpec {
+	if i.DeltaSyncIndexSpec != nil {
+		return *i.DeltaSyncIndexSpec
+	}
+	if i.DirectAccessIndexSpec != nil {
+		return *i.DirectAccessIndexSpec
+	}
+	return databricksIndexSpec{}
+}
+
+type databricksScanRequest struct {
+	NumResults     int    `json:"num_results"`
+	LastPrimaryKey string `json:"last_primary_key,omitempty"`
+}
+
+type databricksScanResponse struct {
+	LastPrimaryKey string             `json:"last_primary_key"`
+	Data           []databricksStruct `json:"data"`
+}
+
+// databricksStruct is a row of an index, or a struct column of one, as a list of columns with their values.
+type databricksStruct struct {
+	Fields []struct {
+		Key   string          `json:"key"`
+		Value databricksValue `json:"value"`
+	} `json:"fields"`
+}
+
+// databricksValue is a value of a column, with the one of its fields set that matches its type. None set is null.
+type databricksValue struct {
+	NumberValue *json.Number      `json:"number_value"`
+	StringValue *string           `json:"string_value"`
+	BoolValue   *bool             `json:"bool_value"`
+	ListValue   *databricksList   `json:"list_value"`
+	StructValue *databricksStruct `json:"struct_value"`
+}
+
+type databricksList struct {
+	Values []databricksValue `json:"values"`
+}
+
+// decode converts a value to what a JSON decoder with UseNumber would return for it.
+func (v databricksValue) decode() any {
+	switch {
+	case v.NumberValue != nil:
+		return *v.NumberValue
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case v.ListValue != nil:
+		list := make([]any, len(v.ListValue.Values))
+		for i, item := range v.ListValue.Values {
+			list[i] = item.decode()
+		}
+		return list
+	case v.StructValue != nil:
+		object := make(map[string]any, len(v.StructValue.Fields))
+		for _, field := range v.StructValue.Fields {
+			object[field.Key] = field.Value.decode()
+		}
+		return object
+	default:
+		return nil
+	}
+}
+
+func (r *MigrateFromDatabricksCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateFromDatabricksCmd) Validate() error {
+	if r.Databricks.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	switch {
+	case r.Databricks.Token != "" && r.Databricks.ClientID != "":
+		return fmt.Errorf("set either a personal access token or the client ID of a service principal, not both")
+	case r.Databricks.Token == "" && r.Databricks.ClientID == "":
+		return fmt.Errorf("a personal access token or the client ID and secret of a service principal are required")
+	case r.Databricks.ClientID != "" && r.Databricks.ClientSecret == "":
+		return fmt.Errorf("the client ID of a service principal requires its secret")
+	}
+	for name, distance := range r.DistanceMetric {
+		switch distance {
+		case "cosine", "dot", "euclid", "manhattan":
+		default:
+			return fmt.Errorf("invalid distance metric '%s' for vector '%s'", distance, name)
+		}
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateFromDatabricksCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Databricks Vector Search to Qdrant Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient := newDatabricksClient(r.Databricks)
+	r.index, err = sourceClient.describeIndex(ctx, r.Databricks.Index)
+	if err != nil {
+		return fmt.Errorf("failed to get Databricks index: %w", err)
+	}
+	err = r.checkIndex()
+	if err != nil {
+		return err
+	}
+
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("databricks", r.Databricks.Index, r.Qdrant.Collection)
+
+	sourcePointCount := r.index.Status.IndexedRowCount
+	err = r.migrateData(ctx, sourceClient, targetClient, sourcePointCount)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
+
+	return nil
+}
+
+// checkIndex takes the vector columns of the index. Embeddings Databricks computes itself aren't returned by the
+// API, so an index with only those needs the points to be re-embedded.
+func (r *MigrateFromDatabricksCmd) checkIndex() error {
+	if r.index.PrimaryKey == "" {
+		return fmt.Errorf("index '%s' has no primary key", r.Databricks.Index)
+	}
+	spec := r.index.spec()
+	r.vectorColumns = nil
+	for _, column := range spec.EmbeddingVectorColumns {
+		r.vectorColumns = append(r.vectorColumns, column.Name)
+	}
+	if len(r.vectorColumns) > 0 || r.Migration.Embed.Field != "" {
+		return nil
+	}
+	var sources []string
+	for _, column := range spec.EmbeddingSourceColumns {
+		sources = append(sources, column.Name)
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("index '%s' has no embedding columns", r.Databricks.Index)
+	}
+	return fmt.Errorf("the embeddings of index '%s' are computed by Databricks from %s, which the API doesn't return. Re-embed the points with --migration.embed.field=%s",
+		r.Databricks.Index, strings.Join(sources, ", "), sources[0])
+}
+
+// offsetKey is the key the last primary key read from the index is stored under.
+func (r *MigrateFromDatabricksCmd) offsetKey() string {
+	return "databricks:" + r.Databricks.Index
+}
+
+// migrateData scans the index in pages ordered by primary key, each continuing after the last key of the previous one.
+func (r *MigrateFromDatabricksCmd) migrateData(ctx context.Context, sourceClient *databricksClient, targetClient *qdrant.Client, sourcePointCount uint64) error {
+	lastKey := ""
+	offsetCount := uint64(0)
+	if !r.Migration.Restart {
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		if id != nil {
+			lastKey = id.GetUuid()
+			offsetCount = count
+		}
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	displayMigrationProgress(bar, offsetCount)
+
+	collectionReady := false
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
+	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
+		page, err := sourceClient.scanIndex(ctx, r.Databricks.Index, lastKey, r.Migration.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(page.Data) == 0 {
+			break
+		}
+
+		targetPoints := make([]*qdrant.PointStruct, 0, len(page.Data))
+		for _, row := range page.Data {
+			point, err := r.rowToPoint(row)
+			if err != nil {
+				return err
+			}
+			targetPoints = append(targetPoints, point)
+		}
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		if !collectionReady {
+			err = r.prepareTargetCollection(ctx, targetClient, targetPoints)
+			if err != nil {
+				return fmt.Errorf("error preparing target collection: %w", err)
+			}
+			collectionReady = true
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
+			CollectionName: r.Qdrant.Collection,
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
+		if err != nil {
+			return fmt.Errorf("failed to insert data into target: %w", err)
+		}
+		currentReport.addWritten(len(request.GetPoints()))
+
+		offsetCount += uint64(len(targetPoints))
+		lastKey = page.LastPrimaryKey
+		if lastKey != "" {
+			err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), qdrant.NewIDUUID(lastKey), offsetCount)
+			if err != nil {
+				return fmt.Errorf("failed to store offset: %w", err)
+			}
+		}
+
+		bar.Add(len(targetPoints))
+
+		if lastKey == "" || sampleComplete(r.Migration) {
+			break
+		}
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+// prepareTargetCollection creates the target collection, if it doesn't exist, with the vector columns of the index.
+// Points re-embedded from an index without them go to a collection that has to exist.
+func (r *MigrateFromDatabricksCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client, points []*qdrant.PointStruct) error {
+	if !r.Migration.CreateCollection || len(r.vectorColumns) == 0 {
+		return nil
+	}
+
+	vectors := make(map[string]commons.RestVector, len(r.vectorColumns))
+	for _, name := range r.vectorColumns {
+		distance := "euclid"
+		if specified, ok := r.DistanceMetric[name]; ok {
+			distance = specified
+		}
+		vectors[name] = commons.RestVector{Distance: distance}
+	}
+	return createCollectionFromPoints(ctx, targetClient, r.Qdrant.Collection, vectors, points, r.Migration)
+}
+
+// rowToPoint converts a row of the index into a point. Its primary key becomes the ID, its vector columns named
+// vectors and the other columns, or those of --databricks.columns, payload.
+func (r *MigrateFromDatabricksCmd) rowToPoint(row databricksStruct) (*qdrant.PointStruct, error) {
+	point := &qdrant.PointStruct{}
+	vectors := make(map[string]*qdrant.Vector)
+	payload := make(map[string]any, len(row.Fields))
+
+	for _, field := range row.Fields {
+		value := field.Value.decode()
+
+		switch {
+		case field.Key == r.index.PrimaryKey:
+			id, original := databricksPointID(value)
+			if id == nil {
+				return nil, fmt.Errorf("row has no primary key in column '%s'", field.Key)
+			}
+			point.Id = id
+			if original != "" {
+				payload[r.IdField] = original
+			}
+		case slices.Contains(r.vectorColumns, field.Key):
+			if value == nil {
+				continue
+			}
+			vector, err := restVector(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid vector in column '%s': %w", field.Key, err)
+			}
+			vectors[field.Key] = vector
+		case len(r.Databricks.Columns) > 0 && !slices.Contains(r.Databricks.Columns, field.Key):
+			// Columns that aren't selected aren't migrated.
+		default:
+			payload[field.Key] = normalizeJSONValue(value)
+		}
+	}
+
+	if point.Id == nil {
+		return nil, fmt.Errorf("row has no primary key in column '%s'", r.index.PrimaryKey)
+	}
+	if len(vectors) > 0 {
+		point.Vectors = qdrant.NewVectorsMap(vectors)
+	}
+	point.Payload = qdrant.NewValueMap(payload)
+
+	return point, nil
+}
+
+// databricksPointID converts a primary key to a point ID like sqlPointID does. Integer keys are returned as numbers.
+func databricksPointID(value any) (*qdrant.PointId, string) {
+	if number, ok := value.(json.Number); ok {
+		if i, err := number.Int64(); err == nil {
+			return sqlPointID(i)
+		}
+		return sqlPointID(number.String())
+	}
+	return sqlPointID(value)
+}
+
+// databricksClient calls the REST API of a Databricks workspace, with a personal access token or the OAuth tokens
+// of a service principal, which are refreshed before they expire.
+type databricksClient struct {
+	http       *http.Client
+	host       string
+	maxRetries int
+}
+
+func newDatabricksClient(config commons.DatabricksConfig) *databricksClient {
+	host := strings.TrimRight(config.Host, "/")
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	var provider *credentialProvider
+	if config.ClientID != "" {
+		provider = newCredentialProvider(&commons.TokenSpec{
+			TokenURL:     host + "/oidc/v1/token",
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			Scopes:       []string{databricksOAuthScope},
+			Header:       "Authorization",
+			Prefix:       "Bearer ",
+		})
+	} else {
+		provider = &credentialProvider{
+			spec: commons.TokenSpec{Header: "Authorization", Prefix: "Bearer "},
+			fetch: func(context.Context) (*oauth2.Token, error) {
+				return &oauth2.Token{AccessToken: config.Token}, nil
+			},
+		}
+	}
+
+	return &databricksClient{
+		http: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &credentialTransport{base: http.DefaultTransport, provider: provider},
+		},
+		host:       host,
+		maxRetries: config.MaxRetries,
+	}
+}
+
+func (c *databricksClient) describeIndex(ctx context.Context, name string) (databricksIndex, error) {
+	var index databricksIndex
+	err := c.call(ctx, http.MethodGet, "/api/2.0/vector-search/indexes/"+url.PathEscape(name), nil, &index)
+	return index, err
+}
+
+// scanIndex returns up to limit rows of an index ordered by primary key, after lastKey if it isn't empty.
+func (c *databricksClient) scanIndex(ctx context.Context, name, lastKey string, limit int) (databricksScanResponse, error) {
+	var page databricksScanResponse
+	request := databricksScanRequest{NumResults: limit, LastPrimaryKey: lastKey}
+	err := c.call(ctx, http.MethodPost, "/api/2.0/vector-search/indexes/"+url.PathEscape(name)+"/scan", request, &page)
+	return page, err
+}
+
+// call sends a request with a JSON body, if there is one, and decodes the JSON response. Requests that failed with
+// a network error, 429 or 5xx are retried, after the delay of Retry-After if the API sent one.
+func (c *databricksClient) call(ctx context.Context, method, path string, request, response any) error {
+	var body []byte
+	if request != nil {
+		var err error
+		body, err = json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryable, delay, err := c.callOnce(ctx, method, path, body, response)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= c.maxRetries {
+			return err
+		}
+		if delay == 0 {
+			delay = backoffDelay(attempt)
+		}
+		pterm.Warning.Printfln("%v, retrying in %s", err, delay)
+		currentReport.addRetry()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *databricksClient) callOnce(ctx context.Context, method, path string, body []byte, response any) (bool, time.Duration, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, reader)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, 0, fmt.Errorf("failed to call Databricks API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, 0, fmt.Errorf("failed to read Databricks API response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, parseRetryAfter(resp.Header.Get("Retry-After")),
+			fmt.Errorf("Databricks API returned %s for %s: %s", resp.Status, path, strings.TrimSpace(string(data)))
+	}
+
+	err = json.Unmarshal(data, response)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to decode Databricks API response: %w", err)
+	}
+	return false, 0, nil
+}
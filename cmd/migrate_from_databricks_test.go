@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestDatabricksClient(t *testing.T) {
+	var scans []databricksScanRequest
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer dapi-token" {
+			t.Errorf("got Authorization %q, want the personal access token", got)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/2.0/vector-search/indexes/main.default.docs":
+			_, _ = io.WriteString(w, `{"name": "main.default.docs", "primary_key": "id", "index_type": "DIRECT_ACCESS",
+				"direct_access_index_spec": {"embedding_vector_columns": [{"name": "embedding", "embedding_dimension": 2}]},
+				"status": {"indexed_row_count": 3}}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/2.0/vector-search/indexes/main.default.docs/scan":
+			if failures > 0 {
+				failures--
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			var scan databricksScanRequest
+			if err := json.NewDecoder(r.Body).Decode(&scan); err != nil {
+				t.Errorf("failed to decode scan request: %v", err)
+			}
+			scans = append(scans, scan)
+			_, _ = io.WriteString(w, `{"last_primary_key": "2", "data": [{"fields": [{"key": "id", "value": {"number_value": 2}}]}]}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := newDatabricksClient(commons.DatabricksConfig{Host: server.URL + "/", Token: "dapi-token", Timeout: time.Minute, MaxRetries: 1})
+
+	index, err := client.describeIndex(context.Background(), "main.default.docs")
+	if err != nil {
+		t.Fatalf("describeIndex: %v", err)
+	}
+	if index.PrimaryKey != "id" || index.Status.IndexedRowCount != 3 || len(index.spec().EmbeddingVectorColumns) != 1 {
+		t.Errorf("got index %+v", index)
+	}
+
+	page, err := client.scanIndex(context.Background(), "main.default.docs", "1", 10)
+	if err != nil {
+		t.Fatalf("scanIndex: %v", err)
+	}
+	if page.LastPrimaryKey != "2" || len(page.Data) != 1 {
+		t.Errorf("got page %+v", page)
+	}
+	if len(scans) != 1 || scans[0] != (databricksScanRequest{NumResults: 10, LastPrimaryKey: "1"}) {
+		t.Errorf("got scan requests %+v", scans)
+	}
+}
+
+func TestDatabricksClientOAuth(t *testing.T) {
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oidc/v1/token" {
+			_ = r.ParseForm()
+			if scope := r.Form.Get("scope"); scope != databricksOAuthScope {
+				t.Errorf("got scope %q, want %q", scope, databricksOAuthScope)
+			}
+			tokens++
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, `{"access_token": "oauth-token", "token_type": "Bearer", "expires_in": 3600}`)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer oauth-token" {
+			t.Errorf("got Authorization %q, want the OAuth token", got)
+		}
+		_, _ = io.WriteString(w, `{"name": "main.default.docs", "primary_key": "id"}`)
+	}))
+	defer server.Close()
+
+	client := newDatabricksClient(commons.DatabricksConfig{Host: server.URL, ClientID: "principal", ClientSecret: "secret", Timeout: time.Minute})
+	for range 2 {
+		if _, err := client.describeIndex(context.Background(), "main.default.docs"); err != nil {
+			t.Fatalf("describeIndex: %v", err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("got %d tokens, want 1 reused for both requests", tokens)
+	}
+}
+
+func TestDatabricksCheckIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		index       string
+		embedField  string
+		wantVectors []string
+		wantErr     string
+	}{
+		{
+			name:        "self-managed embeddings",
+			index:       `{"primary_key": "id", "delta_sync_index_spec": {"embedding_vector_columns": [{"name": "a"}, {"name": "b"}]}}`,
+			wantVectors: []string{"a", "b"},
+		},
+		{
+			name:    "embeddings computed by Databricks",
+			index:   `{"primary_key": "id", "delta_sync_index_spec": {"embedding_source_columns": [{"name": "text"}]}}`,
+			wantErr: "--migration.embed.field=text",
+		},
+		{
+			name:       "embeddings computed by Databricks, re-embedded",
+			index:      `{"primary_key": "id", "delta_sync_index_spec": {"embedding_source_columns": [{"name": "text"}]}}`,
+			embedField: "text",
+		},
+		{
+			name:    "no primary key",
+			index:   `{"direct_access_index_spec": {"embedding_vector_columns": [{"name": "a"}]}}`,
+			wantErr: "no primary key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MigrateFromDatabricksCmd{}
+			r.Databricks.Index = "main.default.docs"
+			r.Migration.Embed.Field = tt.embedField
+			if err := json.Unmarshal([]byte(tt.index), &r.index); err != nil {
+				t.Fatal(err)
+			}
+
+			err := r.checkIndex()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(r.vectorColumns, ",") != strings.Join(tt.wantVectors, ",") {
+				t.Errorf("got vector columns %v, want %v", r.vectorColumns, tt.wantVectors)
+			}
+		})
+	}
+}
+
+func TestDatabricksRowToPoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		columns     []string
+		row         string
+		wantID      *qdrant.PointId
+		wantPayload map[string]any
+		wantVector  []float32
+		wantErr     bool
+	}{
+		{
+			name: "integer key",
+			row: `{"fields": [
+				{"key": "id", "value": {"number_value": 7}},
+				{"key": "embedding", "value": {"list_value": {"values": [{"number_value": 0.5}, {"number_value": 1}]}}},
+				{"key": "title", "value": {"string_value": "Qdrant"}},
+				{"key": "views", "value": {"number_value": 12}},
+				{"key": "public", "value": {"bool_value": true}},
+				{"key": "tags", "value": {"list_value": {"values": [{"string_value": "db"}]}}},
+				{"key": "meta", "value": {"struct_value": {"fields": [{"key": "lang", "value": {"string_value": "en"}}]}}},
+				{"key": "deleted", "value": {"null_value": "NULL_VALUE"}}
+			]}`,
+			wantID: qdrant.NewIDNum(7),
+			wantPayload: map[string]any{
+				"title":   "Qdrant",
+				"views":   int64(12),
+				"public":  true,
+				"tags":    []any{"db"},
+				"meta":    map[string]any{"lang": "en"},
+				"deleted": nil,
+			},
+			wantVector: []float32{0.5, 1},
+		},
+		{
+			name:        "string key",
+			row:         `{"fields": [{"key": "id", "value": {"string_value": "doc-1"}}]}`,
+			wantID:      arbitraryIDToUUID("doc-1"),
+			wantPayload: map[string]any{"__id__": "doc-1"},
+		},
+		{
+			name:    "selected columns",
+			columns: []string{"title"},
+			row: `{"fields": [
+				{"key": "id", "value": {"number_value": 1}},
+				{"key": "title", "value": {"string_value": "Qdrant"}},
+				{"key": "body", "value": {"string_value": "..."}}
+			]}`,
+			wantID:      qdrant.NewIDNum(1),
+			wantPayload: map[string]any{"title": "Qdrant"},
+		},
+		{
+			name:    "no primary key",
+			row:     `{"fields": [{"key": "title", "value": {"string_value": "Qdrant"}}]}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MigrateFromDatabricksCmd{IdField: "__id__", vectorColumns: []string{"embedding"}}
+			r.index.PrimaryKey = "id"
+			r.Databricks.Columns = tt.columns
+
+			var row databricksStruct
+			if err := json.Unmarshal([]byte(tt.row), &row); err != nil {
+				t.Fatal(err)
+			}
+			point, err := r.rowToPoint(row)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if point.GetId().String() != tt.wantID.String() {
+				t.Errorf("got ID %v, want %v", point.GetId(), tt.wantID)
+			}
+			wantPayload := qdrant.NewValueMap(tt.wantPayload)
+			if len(point.GetPayload()) != len(wantPayload) {
+				t.Errorf("got payload %v, want %v", point.GetPayload(), wantPayload)
+			}
+			for key, want := range wantPayload {
+				if got := point.GetPayload()[key]; got.String() != want.String() {
+					t.Errorf("got %s = %v, want %v", key, got, want)
+				}
+			}
+			vector := point.GetVectors().GetVectors().GetVectors()["embedding"]
+			if got := vector.GetData(); len(got) != len(tt.wantVector) || (len(got) > 0 && (got[0] != tt.wantVector[0] || got[1] != tt.wantVector[1])) {
+				t.Errorf("got vector %v, want %v", got, tt.wantVector)
+			}
+		})
+	}
+}
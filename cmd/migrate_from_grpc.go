@@ -0,0 +1,679 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateFromGrpcCmd struct {
+	Grpc      commons.GrpcSourceConfig `embed:"" prefix:"grpc."`
+	Qdrant    commons.QdrantConfig     `embed:"" prefix:"qdrant."`
+	Migration commons.MigrationConfig  `embed:"" prefix:"migration."`
+	IdField   string                   `prefix:"qdrant." help:"Field storing the IDs of the gRPC service in Qdrant." default:"__id__"`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+	paths      restPaths
+}
+
+func (r *MigrateFromGrpcCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	// Items, IDs, vectors and payloads are found the same way as in the responses of a REST API.
+	spec := r.Grpc.Spec
+	r.paths, err = parseRestPaths(commons.RestSpec{
+		Items:      spec.Items,
+		ID:         spec.ID,
+		Vectors:    spec.Vectors,
+		Payload:    spec.Payload,
+		Pagination: spec.Pagination,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse gRPC spec %s: %w", spec.Path, err)
+	}
+
+	return nil
+}
+
+func (r *MigrateFromGrpcCmd) Validate() error {
+	if r.Grpc.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateFromGrpcCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("gRPC to Qdrant Data Migration")
+	pterm.Warning.Println("The gRPC source is experimental. Its flags and spec may change in future releases.")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceConn, err := connectToGrpcSource(globals, r.Grpc.Url, newCredentialProvider(r.Grpc.Spec.Auth))
+	if err != nil {
+		return fmt.Errorf("failed to connect to gRPC source: %w", err)
+	}
+	defer sourceConn.Close()
+
+	method, err := resolveGrpcMethod(grpcSourceContext(ctx, r.Grpc), sourceConn, r.Grpc.Spec.Method)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s through reflection: %w", r.Grpc.Spec.Method, err)
+	}
+	if method.IsStreamingClient() {
+		return fmt.Errorf("%s is a client streaming RPC, only unary and server streaming RPCs are supported", r.Grpc.Spec.Method)
+	}
+	if method.IsStreamingServer() && r.Grpc.Spec.Pagination.Style != "none" {
+		return fmt.Errorf("%s is a server streaming RPC, which is read to its end, so it can't be paginated", r.Grpc.Spec.Method)
+	}
+
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("grpc", r.offsetKey(), r.Qdrant.Collection)
+
+	if method.IsStreamingServer() {
+		err = r.migrateStream(ctx, sourceConn, method, targetClient)
+	} else {
+		err = r.migratePages(ctx, sourceConn, method, targetClient)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+
+	return nil
+}
+
+// offsetKey is the key the checkpoint of the RPC is stored under.
+func (r *MigrateFromGrpcCmd) offsetKey() string {
+	return strings.TrimSuffix(r.Grpc.Url, "/") + "/" + r.Grpc.Spec.Method
+}
+
+// grpcSourceContext adds the metadata of the spec to the calls to the source, but not to those to Qdrant.
+func grpcSourceContext(ctx context.Context, config commons.GrpcSourceConfig) context.Context {
+	return metadata.NewOutgoingContext(ctx, metadata.New(config.Spec.Metadata))
+}
+
+// connectToGrpcSource connects to a gRPC service, with TLS if its URL uses https, and the tokens of a provider if there is one.
+func connectToGrpcSource(globals *Globals, rawUrl string, provider *credentialProvider) (*grpc.ClientConn, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL: %w", err)
+	}
+	port, err := getPort(parsedUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse port: %w", err)
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if parsedUrl.Scheme == HTTPS {
+		transportCredentials = credentials.NewTLS(&tls.Config{InsecureSkipVerify: globals.SkipTlsVerification})
+	}
+
+	options := []grpc.DialOption{grpc.WithTransportCredentials(transportCredentials), grpc.WithChainUnaryInterceptor(retryAfterInterceptor())}
+	if provider != nil {
+		options = append(options, credentialInterceptors(provider)...)
+	}
+	conn, err := grpc.NewClient(net.JoinHostPort(parsedUrl.Hostname(), strconv.Itoa(port)), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return conn, nil
+}
+
+// resolveGrpcMethod looks up an RPC, given as package.Service/Method, through the reflection service of the server.
+// Files the server's files depend on are requested from it too, unless they're compiled into this binary.
+func resolveGrpcMethod(ctx context.Context, conn *grpc.ClientConn, fullMethod string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+
+	stream, err := grpc_reflection_v1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reflection stream: %w", err)
+	}
+	defer stream.CloseSend()
+
+	files := make(map[string]*descriptorpb.FileDescriptorProto)
+	var order []string
+	request := func(request *grpc_reflection_v1.ServerReflectionRequest) error {
+		if err := stream.Send(request); err != nil {
+			return fmt.Errorf("failed to send reflection request: %w", err)
+		}
+		response, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("failed to receive reflection response: %w", err)
+		}
+		if errorResponse := response.GetErrorResponse(); errorResponse != nil {
+			return fmt.Errorf("reflection failed: %s", errorResponse.GetErrorMessage())
+		}
+		for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(data, file); err != nil {
+				return fmt.Errorf("failed to decode file descriptor: %w", err)
+			}
+			if _, ok := files[file.GetName()]; !ok {
+				files[file.GetName()] = file
+				order = append(order, file.GetName())
+			}
+		}
+		return nil
+	}
+
+	err = request(&grpc_reflection_v1.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: serviceName},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(order); i++ {
+		for _, dependency := range files[order[i]].GetDependency() {
+			if _, ok := files[dependency]; ok {
+				continue
+			}
+			if known, err := protoregistry.GlobalFiles.FindFileByPath(dependency); err == nil {
+				files[dependency] = protodesc.ToFileDescriptorProto(known)
+				order = append(order, dependency)
+				continue
+			}
+			err = request(&grpc_reflection_v1.ServerReflectionRequest{
+				MessageRequest: &grpc_reflection_v1.ServerReflectionRequest_FileByFilename{FileByFilename: dependency},
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	fileSet := &descriptorpb.FileDescriptorSet{}
+	for _, name := range order {
+		fileSet.File = append(fileSet.File, files[name])
+	}
+	registry, err := protodesc.NewFiles(fileSet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors: %w", err)
+	}
+
+	descriptor, err := registry.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find service %s: %w", serviceName, err)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("service %s has no method %s", serviceName, methodName)
+	}
+	return method, nil
+}
+
+// newGrpcRequest builds the request message of an RPC from its JSON.
+func newGrpcRequest(method protoreflect.MethodDescriptor, body string) (proto.Message, error) {
+	request := dynamicpb.NewMessage(method.Input())
+	err := protojson.Unmarshal([]byte(body), request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request %s: %w", body, err)
+	}
+	return request, nil
+}
+
+// migratePages calls a unary RPC page after page, until a page has no items.
+func (r *MigrateFromGrpcCmd) migratePages(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, targetClient *qdrant.Client) error {
+	pages := newPager("", r.Grpc.Spec.Request, r.Grpc.Spec.Pagination, r.paths, r.Migration.BatchSize)
+	offsetCount := uint64(0)
+
+	if !r.Migration.Restart {
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		if id != nil {
+			pages.resume(id)
+			offsetCount = count
+		}
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(0).Start()
+	displayMigrationProgress(bar, offsetCount)
+	collectionReady := false
+
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
+	for !pages.done {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
+		_, body := pages.request()
+		request, err := newGrpcRequest(method, body)
+		if err != nil {
+			return err
+		}
+		response, err := callGrpcSource(ctx, conn, r.Grpc, method, request)
+		if err != nil {
+			return err
+		}
+
+		items, err := grpcItems(response, r.paths.items)
+		if err != nil {
+			return fmt.Errorf("items at %s of the response: %w", r.Grpc.Spec.Items, err)
+		}
+		points, err := r.itemsToPoints(items)
+		if err != nil {
+			return err
+		}
+		currentReport.observeRead(time.Since(batchStart), len(points))
+		if err := memory.fit(ctx, pointsSize(points)); err != nil {
+			return err
+		}
+
+		err = pages.advance(response, len(items), "")
+		if err != nil {
+			return err
+		}
+		if len(points) == 0 {
+			continue
+		}
+
+		err = r.writePoints(ctx, targetClient, points, &collectionReady)
+		if err != nil {
+			return err
+		}
+
+		offsetCount += uint64(len(points))
+		if offsetId := pages.checkpoint(); offsetId != nil {
+			err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), offsetId, offsetCount)
+			if err != nil {
+				return fmt.Errorf("failed to store offset: %w", err)
+			}
+		}
+
+		bar.Add(len(points))
+
+		if sampleComplete(r.Migration) {
+			break
+		}
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+// migrateStream reads a server streaming RPC to its end, writing its items in batches.
+// A stream can't be resumed where it stopped, so it's read again from its start and the items migrated before are skipped.
+func (r *MigrateFromGrpcCmd) migrateStream(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, targetClient *qdrant.Client) error {
+	offsetCount := uint64(0)
+	if !r.Migration.Restart {
+		_, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		offsetCount = count
+	}
+
+	_, body := newPager("", r.Grpc.Spec.Request, r.Grpc.Spec.Pagination, r.paths, r.Migration.BatchSize).request()
+	request, err := newGrpcRequest(method, body)
+	if err != nil {
+		return err
+	}
+
+	stream, err := conn.NewStream(grpcSourceContext(ctx, r.Grpc), &grpc.StreamDesc{ServerStreams: true}, "/"+r.Grpc.Spec.Method)
+	if err != nil {
+		return fmt.Errorf("failed to call %s: %w", r.Grpc.Spec.Method, err)
+	}
+	err = stream.SendMsg(request)
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s: %w", r.Grpc.Spec.Method, err)
+	}
+	err = stream.CloseSend()
+	if err != nil {
+		return fmt.Errorf("failed to send request to %s: %w", r.Grpc.Spec.Method, err)
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(0).Start()
+	displayMigrationProgress(bar, offsetCount)
+	collectionReady := false
+	skip := offsetCount
+	batch := make([]*qdrant.PointStruct, 0, r.Migration.BatchSize)
+	batchStart := time.Now()
+
+	// The bytes of the next batch are reserved before its messages are read, and once the batch before is written.
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+	err = memory.reserve(ctx)
+	if err != nil {
+		return err
+	}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		currentReport.observeRead(time.Since(batchStart), len(batch))
+		if err := memory.fit(ctx, pointsSize(batch)); err != nil {
+			return err
+		}
+		err := r.writePoints(ctx, targetClient, batch, &collectionReady)
+		if err != nil {
+			return err
+		}
+		offsetCount += uint64(len(batch))
+		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), qdrant.NewIDNum(offsetCount), offsetCount)
+		if err != nil {
+			return fmt.Errorf("failed to store offset: %w", err)
+		}
+		bar.Add(len(batch))
+		batch = batch[:0]
+		batchStart = time.Now()
+		return memory.reserve(ctx)
+	}
+
+	for !sampleComplete(r.Migration) {
+		message := dynamicpb.NewMessage(method.Output())
+		err := stream.RecvMsg(message)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read stream of %s: %w", r.Grpc.Spec.Method, err)
+		}
+
+		response, err := protoMessageToJSON(message)
+		if err != nil {
+			return err
+		}
+		items, err := grpcItems(response, r.paths.items)
+		if err != nil {
+			return fmt.Errorf("items at %s of the response: %w", r.Grpc.Spec.Items, err)
+		}
+		if skip >= uint64(len(items)) {
+			skip -= uint64(len(items))
+			continue
+		}
+		items, skip = items[skip:], 0
+
+		points, err := r.itemsToPoints(items)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, points...)
+		if len(batch) >= r.Migration.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+func (r *MigrateFromGrpcCmd) itemsToPoints(items []any) ([]*qdrant.PointStruct, error) {
+	points := make([]*qdrant.PointStruct, 0, len(items))
+	for _, item := range items {
+		point, err := restItemToPoint(item, r.paths, r.IdField)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// writePoints writes a batch to the target, creating the target collection with the first one.
+func (r *MigrateFromGrpcCmd) writePoints(ctx context.Context, targetClient *qdrant.Client, points []*qdrant.PointStruct, collectionReady *bool) error {
+	if !*collectionReady && r.Migration.CreateCollection {
+		err := createCollectionFromPoints(ctx, targetClient, r.Qdrant.Collection, r.Grpc.Spec.Vectors, points, r.Migration)
+		if err != nil {
+			return fmt.Errorf("error preparing target collection: %w", err)
+		}
+	}
+	*collectionReady = true
+
+	writePoints, err := transformPayloads(ctx, points, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	request := &qdrant.UpsertPoints{
+		CollectionName: r.Qdrant.Collection,
+		Points:         writePoints,
+		Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+	}
+	err = upsertPoints(ctx, targetClient, request, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to insert data into target: %w", err)
+	}
+	currentReport.addWritten(len(request.GetPoints()))
+	return nil
+}
+
+// callGrpcSource calls a unary RPC and converts its response. Calls that failed with a transient error are retried.
+func callGrpcSource(ctx context.Context, conn *grpc.ClientConn, config commons.GrpcSourceConfig, method protoreflect.MethodDescriptor, request proto.Message) (any, error) {
+	sourceCtx := grpcSourceContext(ctx, config)
+	for attempt := 0; ; attempt++ {
+		response := dynamicpb.NewMessage(method.Output())
+		callCtx, cancel := sourceCtx, context.CancelFunc(func() {})
+		if config.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(sourceCtx, config.Timeout)
+		}
+		err := conn.Invoke(callCtx, "/"+config.Spec.Method, request, response)
+		cancel()
+		if err == nil {
+			return protoMessageToJSON(response)
+		}
+		if !isTransientError(err) || attempt >= config.MaxRetries || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to call %s: %w", config.Spec.Method, err)
+		}
+		delay := retryDelay(err, attempt)
+		pterm.Warning.Printfln("Call of %s failed: %v, retrying in %s", config.Spec.Method, err, delay)
+		currentReport.addRetry()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// grpcItems returns the items at a path of a response. A message at the path is a single item,
+// so that a stream of items can be read with the path $.
+func grpcItems(response any, steps []jsonPathStep) ([]any, error) {
+	value, ok := evalJSONPath(response, steps)
+	if !ok || value == nil {
+		return nil, nil
+	}
+	switch v := value.(type) {
+	case []any:
+		return v, nil
+	case map[string]any:
+		return []any{v}, nil
+	default:
+		return nil, fmt.Errorf("expected a list or a message, got %T", value)
+	}
+}
+
+// protoMessageToJSON converts a message into a document like a decoded JSON response, keyed by the field names of the proto.
+// Unlike the JSON mapping of protobuf, 64-bit integers stay numbers, so that they can be used as point IDs.
+// Well-known types like Struct and Timestamp are converted by their JSON mapping.
+func protoMessageToJSON(message protoreflect.Message) (any, error) {
+	if strings.HasPrefix(string(message.Descriptor().FullName()), "google.protobuf.") {
+		data, err := protojson.Marshal(message.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", message.Descriptor().FullName(), err)
+		}
+		var document any
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+		err = decoder.Decode(&document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert %s: %w", message.Descriptor().FullName(), err)
+		}
+		return document, nil
+	}
+
+	document := make(map[string]any)
+	var err error
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		document[string(field.Name())], err = protoFieldToJSON(field, value)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func protoFieldToJSON(field protoreflect.FieldDescriptor, value protoreflect.Value) (any, error) {
+	switch {
+	case field.IsList():
+		list := value.List()
+		elements := make([]any, list.Len())
+		for i := range elements {
+			element, err := protoValueToJSON(field, list.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	case field.IsMap():
+		entries := make(map[string]any, value.Map().Len())
+		var err error
+		value.Map().Range(func(key protoreflect.MapKey, entry protoreflect.Value) bool {
+			entries[key.String()], err = protoValueToJSON(field.MapValue(), entry)
+			return err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return entries, nil
+	default:
+		return protoValueToJSON(field, value)
+	}
+}
+
+func protoValueToJSON(field protoreflect.FieldDescriptor, value protoreflect.Value) (any, error) {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessageToJSON(value.Message())
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name()), nil
+		}
+		return json.Number(strconv.Itoa(int(value.Enum()))), nil
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes()), nil
+	case protoreflect.StringKind:
+		return value.String(), nil
+	case protoreflect.BoolKind:
+		return value.Bool(), nil
+	case protoreflect.FloatKind:
+		return json.Number(strconv.FormatFloat(value.Float(), 'g', -1, 32)), nil
+	case protoreflect.DoubleKind:
+		return json.Number(strconv.FormatFloat(value.Float(), 'g', -1, 64)), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return json.Number(strconv.FormatInt(value.Int(), 10)), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return json.Number(strconv.FormatUint(value.Uint(), 10)), nil
+	default:
+		return nil, fmt.Errorf("unsupported kind %s of field %s", field.Kind(), field.FullName())
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func startReflectionServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	reflection.Register(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+	return "http://" + listener.Addr().String()
+}
+
+func TestResolveGrpcMethod(t *testing.T) {
+	conn, err := connectToGrpcSource(&Globals{}, startReflectionServer(t), nil)
+	if err != nil {
+		t.Fatalf("connectToGrpcSource() error = %v", err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		method        string
+		wantStreaming bool
+		wantErr       bool
+	}{
+		{method: "grpc.health.v1.Health/Check"},
+		{method: "/grpc.health.v1.Health/Watch", wantStreaming: true},
+		{method: "grpc.health.v1.Health/Missing", wantErr: true},
+		{method: "missing.v1.Service/Call", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			method, err := resolveGrpcMethod(ctx, conn, tt.method)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("resolveGrpcMethod(%q) = %v, want an error", tt.method, method.FullName())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveGrpcMethod(%q) error = %v", tt.method, err)
+			}
+			if method.IsStreamingServer() != tt.wantStreaming {
+				t.Errorf("IsStreamingServer() = %v, want %v", method.IsStreamingServer(), tt.wantStreaming)
+			}
+		})
+	}
+
+	method, err := resolveGrpcMethod(ctx, conn, "grpc.health.v1.Health/Check")
+	if err != nil {
+		t.Fatalf("resolveGrpcMethod() error = %v", err)
+	}
+	request, err := newGrpcRequest(method, `{"service": ""}`)
+	if err != nil {
+		t.Fatalf("newGrpcRequest() error = %v", err)
+	}
+	config := commons.GrpcSourceConfig{Spec: commons.GrpcSourceSpec{Method: "grpc.health.v1.Health/Check"}}
+	response, err := callGrpcSource(ctx, conn, config, method, request)
+	if err != nil {
+		t.Fatalf("callGrpcSource() error = %v", err)
+	}
+	if want := map[string]any{"status": "SERVING"}; !reflect.DeepEqual(response, want) {
+		t.Errorf("callGrpcSource() = %v, want %v", response, want)
+	}
+}
+
+func TestProtoMessageToJSON(t *testing.T) {
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("item.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/struct.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_UINT64.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("embedding"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_FLOAT.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()},
+				{Name: proto.String("title"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("attributes"), Number: proto.Int32(4), Type: descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(), TypeName: proto.String(".google.protobuf.Struct"), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("empty"), Number: proto.Int32(5), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("failed to build descriptor: %v", err)
+	}
+	descriptor := file.Messages().ByName("Item")
+
+	attributes, err := structpb.NewStruct(map[string]any{"lang": "en", "views": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	message.Set(descriptor.Fields().ByName("id"), protoreflect.ValueOfUint64(18446744073709551615))
+	embedding := message.Mutable(descriptor.Fields().ByName("embedding")).List()
+	embedding.Append(protoreflect.ValueOfFloat32(0.1))
+	embedding.Append(protoreflect.ValueOfFloat32(2))
+	message.Set(descriptor.Fields().ByName("title"), protoreflect.ValueOfString("a"))
+	message.Set(descriptor.Fields().ByName("attributes"), protoreflect.ValueOfMessage(attributes.ProtoReflect()))
+
+	document, err := protoMessageToJSON(message)
+	if err != nil {
+		t.Fatalf("protoMessageToJSON() error = %v", err)
+	}
+	want := decodeTestJSON(t, `{"id": 18446744073709551615, "embedding": [0.1, 2], "title": "a", "attributes": {"lang": "en", "views": 3}}`)
+	if !reflect.DeepEqual(document, want) {
+		t.Errorf("protoMessageToJSON() = %v, want %v", document, want)
+	}
+
+	paths, err := parseRestPaths(commons.RestSpec{ID: "$.id", Vectors: map[string]commons.RestVector{"dense": {Path: "$.embedding"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := restItemToPoint(document, paths, "__id__")
+	if err != nil {
+		t.Fatalf("restItemToPoint() error = %v", err)
+	}
+	if point.GetId().GetNum() != 18446744073709551615 {
+		t.Errorf("ID = %v, want the 64-bit ID as number", point.GetId())
+	}
+}
+
+func TestGrpcItems(t *testing.T) {
+	steps, _ := parseJSONPath("$.items")
+	tests := []struct {
+		name     string
+		response any
+		want     int
+		wantErr  bool
+	}{
+		{name: "list", response: map[string]any{"items": []any{map[string]any{}, map[string]any{}}}, want: 2},
+		{name: "message", response: map[string]any{"items": map[string]any{"id": "a"}}, want: 1},
+		{name: "missing", response: map[string]any{}, want: 0},
+		{name: "scalar", response: map[string]any{"items": "a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := grpcItems(tt.response, steps)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("grpcItems() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(items) != tt.want {
+				t.Errorf("grpcItems() = %v, want %d items", items, tt.want)
+			}
+		})
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/milvus-io/milvus/client/v2/column"
 	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/index"
 	"github.com/milvus-io/milvus/client/v2/milvusclient"
 	"github.com/pterm/pterm"
 
@@ -24,6 +26,7 @@ type MigrateFromMilvusCmd struct {
 	Qdrant         commons.QdrantConfig    `embed:"" prefix:"qdrant."`
 	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
 	DistanceMetric map[string]string       `prefix:"qdrant." help:"Map of vector field names to distance metrics (cosine,dot,euclid,manhattan). Default is cosine if not specified."`
+	PartitionField string                  `prefix:"milvus." help:"Payload field to store the partition of each point in. Set --migration.tenant-field to it as well for a shard key per partition."`
 
 	targetHost string
 	targetPort int
@@ -40,6 +43,14 @@ func (r *MigrateFromMilvusCmd) Parse() error {
 	return nil
 }
 
+// milvusPartitions are the partitions read by one pass over a Milvus collection.
+// With --milvus.partition-field every partition is read in a pass of its own, since query results don't name their partition.
+type milvusPartitions struct {
+	names     []string
+	offsetKey string
+	count     uint64
+}
+
 func (r *MigrateFromMilvusCmd) Validate() error {
 	return validateBatchSize(r.Migration.BatchSize)
 }
@@ -52,27 +63,37 @@ func (r *MigrateFromMilvusCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	sourceClient, err := r.connectToMilvus(ctx)
+	sourceClient, err := connectToMilvus(ctx, r.Milvus)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Milvus source: %w", err)
 	}
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
 	}
 
-	sourcePointCount, err := r.countMilvusVectors(ctx, sourceClient)
+	passes, err := r.resolvePartitions(ctx, sourceClient)
 	if err != nil {
-		return fmt.Errorf("failed to count points in source: %w", err)
+		return err
+	}
+
+	sourcePointCount := uint64(0)
+	for _, pass := range passes {
+		sourcePointCount += pass.count
 	}
 
 	err = r.prepareTargetCollection(ctx, sourceClient, targetClient)
@@ -80,11 +101,48 @@ func (r *MigrateFromMilvusCmd) Run(globals *Globals) error {
 		return fmt.Errorf("error preparing target collection: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("milvus", r.Milvus.Collection, r.Qdrant.Collection)
 
-	err = r.migrateData(ctx, sourceClient, targetClient, sourcePointCount)
+	for _, pass := range passes {
+		if r.PartitionField != "" {
+			pterm.Info.Printfln("Migrating partition '%s'", pass.names[0])
+		}
+
+		err = r.migrateData(ctx, sourceClient, targetClient, pass)
+		if err != nil {
+			return fmt.Errorf("failed to migrate data: %w", err)
+		}
+
+		if sampleComplete(r.Migration) {
+			break
+		}
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
 	if err != nil {
-		return fmt.Errorf("failed to migrate data: %w", err)
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
 	}
 
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
@@ -96,19 +154,20 @@ func (r *MigrateFromMilvusCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
 
-func (r *MigrateFromMilvusCmd) connectToMilvus(ctx context.Context) (*milvusclient.Client, error) {
+func connectToMilvus(ctx context.Context, config commons.MilvusConfig) (*milvusclient.Client, error) {
 	client, err := milvusclient.New(ctx, &milvusclient.ClientConfig{
-		Address:       r.Milvus.Url,
-		APIKey:        r.Milvus.APIKey,
-		EnableTLSAuth: r.Milvus.EnableTLSAuth,
-		Username:      r.Milvus.Username,
-		Password:      r.Milvus.Password,
-		DBName:        r.Milvus.DBName,
-		ServerVersion: r.Milvus.ServerVersion,
+		Address:       config.Url,
+		APIKey:        config.APIKey,
+		EnableTLSAuth: config.EnableTLSAuth,
+		Username:      config.Username,
+		Password:      config.Password,
+		DBName:        config.DBName,
+		ServerVersion: config.ServerVersion,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Milvus client: %w", err)
@@ -117,12 +176,64 @@ func (r *MigrateFromMilvusCmd) connectToMilvus(ctx context.Context) (*milvusclie
 	return client, nil
 }
 
-func (r *MigrateFromMilvusCmd) countMilvusVectors(ctx context.Context, client *milvusclient.Client) (uint64, error) {
-	stats, err := client.GetCollectionStats(ctx, milvusclient.NewGetCollectionStatsOption(r.Milvus.Collection))
-	if err != nil {
-		return 0, fmt.Errorf("failed to get collection statistics: %w", err)
+// resolvePartitions returns the passes to read the source collection in. Without --milvus.partition-field
+// it's a single pass over --milvus.partitions, or the whole collection if none were given.
+// With it, every partition gets a pass and an offset of its own, listing the partitions of the collection if none were given.
+func (r *MigrateFromMilvusCmd) resolvePartitions(ctx context.Context, client *milvusclient.Client) ([]milvusPartitions, error) {
+	if r.PartitionField == "" {
+		count, err := r.countMilvusVectors(ctx, client, r.Milvus.Partitions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count points in source: %w", err)
+		}
+		return []milvusPartitions{{names: r.Milvus.Partitions, offsetKey: r.Milvus.Collection, count: count}}, nil
+	}
+
+	names := r.Milvus.Partitions
+	if len(names) == 0 {
+		var err error
+		names, err = client.ListPartitions(ctx, milvusclient.NewListPartitionOption(r.Milvus.Collection))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Milvus partitions: %w", err)
+		}
+	}
+
+	passes := make([]milvusPartitions, 0, len(names))
+	for _, name := range names {
+		count, err := r.countMilvusVectors(ctx, client, []string{name})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count points in source: %w", err)
+		}
+		passes = append(passes, milvusPartitions{names: []string{name}, offsetKey: r.Milvus.Collection + "#" + name, count: count})
+	}
+	return passes, nil
+}
+
+// countMilvusVectors counts the rows of the given partitions, or of the whole collection if there are none.
+func (r *MigrateFromMilvusCmd) countMilvusVectors(ctx context.Context, client *milvusclient.Client, partitions []string) (uint64, error) {
+	if len(partitions) == 0 {
+		stats, err := client.GetCollectionStats(ctx, milvusclient.NewGetCollectionStatsOption(r.Milvus.Collection))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get collection statistics: %w", err)
+		}
+		return parseMilvusRowCount(stats)
 	}
 
+	total := uint64(0)
+	for _, partition := range partitions {
+		stats, err := client.GetPartitionStats(ctx, milvusclient.NewGetPartitionStatsOption(r.Milvus.Collection, partition))
+		if err != nil {
+			return 0, fmt.Errorf("failed to get statistics of partition '%s': %w", partition, err)
+		}
+		count, err := parseMilvusRowCount(stats)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func parseMilvusRowCount(stats map[string]string) (uint64, error) {
 	count, err := strconv.ParseUint(stats["row_count"], 10, 64)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse row count: %w", err)
@@ -132,7 +243,7 @@ func (r *MigrateFromMilvusCmd) countMilvusVectors(ctx context.Context, client *m
 }
 
 func (r *MigrateFromMilvusCmd) prepareTargetCollection(ctx context.Context, sourceClient *milvusclient.Client, targetClient *qdrant.Client) error {
-	if !r.Migration.CreateCollection {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
 		return nil
 	}
 
@@ -151,13 +262,6 @@ func (r *MigrateFromMilvusCmd) prepareTargetCollection(ctx context.Context, sour
 		return fmt.Errorf("failed to describe Milvus collection: %w", err)
 	}
 
-	distanceMapping := map[string]qdrant.Distance{
-		"euclid":    qdrant.Distance_Euclid,
-		"cosine":    qdrant.Distance_Cosine,
-		"dot":       qdrant.Distance_Dot,
-		"manhattan": qdrant.Distance_Manhattan,
-	}
-
 	vectorParamsMap := make(map[string]*qdrant.VectorParams)
 	for _, field := range schema.Schema.Fields {
 		if field.DataType == entity.FieldTypeFloatVector {
@@ -167,34 +271,71 @@ func (r *MigrateFromMilvusCmd) prepareTargetCollection(ctx context.Context, sour
 				return fmt.Errorf("failed to parse vector dimension: %w", err)
 			}
 
-			distanceMetric := "cosine"
-			if specifiedDistance, ok := r.DistanceMetric[field.Name]; ok {
-				if _, valid := distanceMapping[specifiedDistance]; !valid {
-					return fmt.Errorf("invalid distance metric '%s' for vector '%s'", specifiedDistance, field.Name)
-				}
-				distanceMetric = specifiedDistance
+			distance, err := r.vectorDistance(ctx, sourceClient, field.Name)
+			if err != nil {
+				return err
 			}
 
 			vectorParamsMap[field.Name] = &qdrant.VectorParams{
 				Size:     uint64(dimension),
-				Distance: distanceMapping[distanceMetric],
+				Distance: distance,
 			}
 		}
 	}
 
-	err = targetClient.CreateCollection(ctx, &qdrant.CreateCollection{
+	request := &qdrant.CreateCollection{
 		CollectionName: r.Qdrant.Collection,
 		VectorsConfig:  qdrant.NewVectorsConfigMap(vectorParamsMap),
-	})
+		ShardingMethod: tenantShardingMethod(r.Migration, nil),
+	}
+	applyTopology(request, r.Migration)
+	err = targetClient.CreateCollection(ctx, request)
 	if err != nil {
 		return fmt.Errorf("failed to create target collection: %w", err)
 	}
 
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	pterm.Success.Printfln("Created target collection %q", r.Qdrant.Collection)
 	return nil
 }
 
-func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *milvusclient.Client, targetClient *qdrant.Client, sourcePointCount uint64) error {
+// vectorDistance returns the distance of a vector field in Qdrant: the one given by --qdrant.distance-metric,
+// or the metric of the index of the field in Milvus. Fields without an index get cosine.
+func (r *MigrateFromMilvusCmd) vectorDistance(ctx context.Context, client *milvusclient.Client, field string) (qdrant.Distance, error) {
+	if specified, ok := r.DistanceMetric[field]; ok {
+		if !isQdrantDistance(specified) {
+			return 0, fmt.Errorf("invalid distance metric '%s' for vector '%s'", specified, field)
+		}
+		return translateDistance("qdrant", specified, field), nil
+	}
+
+	indexes, err := client.ListIndexes(ctx, milvusclient.NewListIndexOption(r.Milvus.Collection).WithFieldName(field))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list indexes of vector '%s': %w", field, err)
+	}
+	for _, name := range indexes {
+		description, err := client.DescribeIndex(ctx, milvusclient.NewDescribeIndexOption(r.Milvus.Collection, name))
+		if err != nil {
+			return 0, fmt.Errorf("failed to describe index '%s': %w", name, err)
+		}
+		if metric, ok := description.Params()[index.MetricTypeKey]; ok {
+			return translateDistance("milvus", metric, field), nil
+		}
+	}
+
+	pterm.Info.Printfln("Vector '%s' has no index to take its distance metric from, using Cosine", field)
+	return qdrant.Distance_Cosine, nil
+}
+
+func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *milvusclient.Client, targetClient *qdrant.Client, pass milvusPartitions) error {
 	batchSize := r.Migration.BatchSize
 
 	var offsetID *qdrant.PointId
@@ -202,7 +343,7 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 	var err error
 
 	if !r.Migration.Restart {
-		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.Milvus.Collection)
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, pass.offsetKey)
 		if err != nil {
 			return fmt.Errorf("failed to get start offset: %w", err)
 		}
@@ -210,7 +351,7 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 		offsetID = id
 	}
 
-	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(pass.count)).Start()
 	displayMigrationProgress(bar, offsetCount)
 
 	schema, err := sourceClient.DescribeCollection(ctx, milvusclient.NewDescribeCollectionOption(r.Milvus.Collection))
@@ -222,7 +363,15 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 	pkName := pkField.Name
 	pkType := pkField.DataType
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		filter := ""
 		if offsetID != nil {
 			switch pkType {
@@ -234,7 +383,7 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 		}
 
 		result, err := sourceClient.Query(ctx, milvusclient.NewQueryOption(r.Milvus.Collection).
-			WithPartitions(r.Milvus.Partitions...).
+			WithPartitions(pass.names...).
 			WithFilter(filter).
 			WithOutputFields("*").
 			WithLimit(batchSize))
@@ -284,6 +433,10 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 				}
 			}
 
+			if r.PartitionField != "" {
+				payload[r.PartitionField] = pass.names[0]
+			}
+
 			if len(vectors) > 0 {
 				point.Vectors = qdrant.NewVectorsMap(vectors)
 			}
@@ -291,23 +444,38 @@ func (r *MigrateFromMilvusCmd) migrateData(ctx context.Context, sourceClient *mi
 			targetPoints = append(targetPoints, point)
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
 		offsetCount += uint64(len(targetPoints))
-		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.Milvus.Collection, offsetID, offsetCount)
+		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, pass.offsetKey, offsetID, offsetCount)
 		if err != nil {
 			return fmt.Errorf("failed to store offset: %w", err)
 		}
 
 		bar.Add(len(targetPoints))
 
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
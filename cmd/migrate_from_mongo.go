@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/pterm/pterm"
 	"go.mongodb.org/mongo-driver/v2/bson"
@@ -51,7 +52,7 @@ func (r *MigrateFromMongoDBCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	sourceClient, err := r.connectToMongoDB()
@@ -64,11 +65,16 @@ func (r *MigrateFromMongoDBCmd) Run(globals *Globals) error {
 		}
 	}()
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
@@ -79,6 +85,11 @@ func (r *MigrateFromMongoDBCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to count documents in source: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("mongodb", r.MongoDB.Collection, r.Qdrant.Collection)
 
 	err = r.migrateData(ctx, sourceClient, targetClient, sourcePointCount)
@@ -86,6 +97,28 @@ func (r *MigrateFromMongoDBCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, uint64(sourcePointCount))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -95,6 +128,7 @@ func (r *MigrateFromMongoDBCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(uint64(sourcePointCount), targetPointCount)
 
 	return nil
 }
@@ -141,7 +175,15 @@ func (r *MigrateFromMongoDBCmd) migrateData(ctx context.Context, sourceClient *m
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
 	displayMigrationProgress(bar, uint64(offsetCount))
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		skip := page * batchSize
 		findOptions := options.Find().
 			SetLimit(int64(batchSize)).
@@ -211,14 +253,26 @@ func (r *MigrateFromMongoDBCmd) migrateData(ctx context.Context, sourceClient *m
 			targetPoints = append(targetPoints, point)
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
 		offsetCount += uint64(len(targetPoints))
 		offsetId := qdrant.NewIDNum(0)
@@ -229,6 +283,10 @@ func (r *MigrateFromMongoDBCmd) migrateData(ctx context.Context, sourceClient *m
 
 		bar.Add(len(targetPoints))
 		page++
+
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
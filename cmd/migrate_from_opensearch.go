@@ -5,11 +5,13 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/opensearch-project/opensearch-go"
 	"github.com/pterm/pterm"
@@ -20,14 +22,21 @@ import (
 )
 
 type MigrateFromOpenSearchCmd struct {
-	OpenSearch commons.OpenSearchConfig `embed:"" prefix:"opensearch."`
-	Qdrant     commons.QdrantConfig     `embed:"" prefix:"qdrant."`
-	Migration  commons.MigrationConfig  `embed:"" prefix:"migration."`
-	IdField    string                   `prefix:"qdrant." help:"Field storing OpenSearch IDs in Qdrant." default:"__id__"`
+	OpenSearch    commons.OpenSearchConfig `embed:"" prefix:"opensearch."`
+	Qdrant        commons.QdrantConfig     `embed:"" prefix:"qdrant."`
+	Migration     commons.MigrationConfig  `embed:"" prefix:"migration."`
+	IdField       string                   `prefix:"qdrant." help:"Field storing OpenSearch IDs in Qdrant." default:"__id__"`
+	VersionField  string                   `prefix:"qdrant." help:"Field to store the version of every document in OpenSearch in, e.g. for incremental syncs and audits. Not stored by default."`
+	ExplodeNested string                   `prefix:"opensearch." help:"Nested field with vectors, e.g. passages, whose objects are migrated as points of their own, with the ID of their document in --qdrant.parent-id-field."`
+	ParentIdField string                   `prefix:"qdrant." help:"Field storing the OpenSearch ID of the document of points of --opensearch.explode-nested." default:"parent_id"`
 
 	targetHost string
 	targetPort int
 	targetTLS  bool
+
+	// Vectors of the knn_vector fields of the index by their path, and the nested fields with any of them.
+	vectorFields map[string]*qdrant.VectorParams
+	nestedFields map[string]bool
 }
 
 func (r *MigrateFromOpenSearchCmd) Parse() error {
@@ -52,19 +61,24 @@ func (r *MigrateFromOpenSearchCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	sourceClient, err := r.connectToOpenSearch()
+	sourceClient, err := r.connectToOpenSearch(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to connect to OpenSearch source: %w", err)
 	}
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
@@ -75,11 +89,21 @@ func (r *MigrateFromOpenSearchCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to count documents in source: %w", err)
 	}
 
-	err = r.prepareTargetCollection(ctx, sourceClient, targetClient)
+	err = r.getVectorFields(sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = r.prepareTargetCollection(ctx, targetClient)
 	if err != nil {
 		return fmt.Errorf("error preparing target collection: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("opensearch", r.OpenSearch.Index, r.Qdrant.Collection)
 
 	err = r.migrateData(ctx, sourceClient, targetClient, sourcePointCount)
@@ -87,6 +111,28 @@ func (r *MigrateFromOpenSearchCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, uint64(sourcePointCount))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -96,21 +142,35 @@ func (r *MigrateFromOpenSearchCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(uint64(sourcePointCount), targetPointCount)
 
 	return nil
 }
 
-func (r *MigrateFromOpenSearchCmd) connectToOpenSearch() (*opensearch.Client, error) {
+func (r *MigrateFromOpenSearchCmd) connectToOpenSearch(ctx context.Context) (*opensearch.Client, error) {
 	config := opensearch.Config{
 		Addresses: []string{r.OpenSearch.Url},
 		Username:  r.OpenSearch.Username,
 		Password:  r.OpenSearch.Password,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: r.OpenSearch.InsecureSkipVerify,
+		Transport: &rateLimitedTransport{
+			base: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: r.OpenSearch.InsecureSkipVerify,
+				},
 			},
+			retries: sourceRateLimitRetries,
 		},
 	}
+	if r.OpenSearch.AwsSigv4 {
+		awsConfig, err := loadAWSConfig(ctx, r.OpenSearch.AWS)
+		if err != nil {
+			return nil, err
+		}
+		config.Signer, err = newSigv4Signer(awsConfig, r.OpenSearch.AwsService)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	client, err := opensearch.NewClient(config)
 	if err != nil {
@@ -143,21 +203,8 @@ func (r *MigrateFromOpenSearchCmd) countOpenSearchDocuments(ctx context.Context,
 	return int64(count), nil
 }
 
-func (r *MigrateFromOpenSearchCmd) prepareTargetCollection(ctx context.Context, sourceClient *opensearch.Client, targetClient *qdrant.Client) error {
-	if !r.Migration.CreateCollection {
-		return nil
-	}
-
-	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
-	if err != nil {
-		return fmt.Errorf("failed to check if collection exists: %w", err)
-	}
-
-	if targetCollectionExists {
-		pterm.Info.Printfln("Target collection %q already exists. Skipping creation.", r.Qdrant.Collection)
-		return nil
-	}
-
+// getVectorFields reads the knn_vector fields from the mapping of the index.
+func (r *MigrateFromOpenSearchCmd) getVectorFields(sourceClient *opensearch.Client) error {
 	mappingRes, err := sourceClient.Indices.GetMapping(
 		sourceClient.Indices.GetMapping.WithIndex(r.OpenSearch.Index),
 	)
@@ -171,56 +218,105 @@ func (r *MigrateFromOpenSearchCmd) prepareTargetCollection(ctx context.Context,
 		return fmt.Errorf("failed to decode mapping response: %w", err)
 	}
 
-	vectorParamsMap, err := r.extractVectorFields(mapping)
+	r.vectorFields, r.nestedFields, err = r.extractVectorFields(mapping)
 	if err != nil {
 		return fmt.Errorf("failed to extract vector fields: %w", err)
 	}
+	if r.ExplodeNested != "" && !r.nestedFields[r.ExplodeNested] {
+		return fmt.Errorf("--opensearch.explode-nested=%s isn't a nested field with knn_vector fields in index %s", r.ExplodeNested, r.OpenSearch.Index)
+	}
+	return nil
+}
+
+func (r *MigrateFromOpenSearchCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
 
-	err = targetClient.CreateCollection(ctx, &qdrant.CreateCollection{
+	if targetCollectionExists {
+		pterm.Info.Printfln("Target collection %q already exists. Skipping creation.", r.Qdrant.Collection)
+		return nil
+	}
+
+	request := &qdrant.CreateCollection{
 		CollectionName: r.Qdrant.Collection,
-		VectorsConfig:  qdrant.NewVectorsConfigMap(vectorParamsMap),
-	})
+		VectorsConfig:  qdrant.NewVectorsConfigMap(r.vectorFields),
+		ShardingMethod: tenantShardingMethod(r.Migration, nil),
+	}
+	applyTopology(request, r.Migration)
+	err = targetClient.CreateCollection(ctx, request)
 	if err != nil {
 		return fmt.Errorf("failed to create target collection: %w", err)
 	}
 
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	pterm.Success.Printfln("Created target collection %q", r.Qdrant.Collection)
 	return nil
 }
 
-func (r *MigrateFromOpenSearchCmd) extractVectorFields(mapping map[string]any) (map[string]*qdrant.VectorParams, error) {
-	vectorParamsMap := make(map[string]*qdrant.VectorParams)
-
+// extractVectorFields returns the knn_vector fields of the mapping of the index by their path, e.g. meta.embedding for
+// a field of an object or nested field, and the nested fields that have any of them.
+func (r *MigrateFromOpenSearchCmd) extractVectorFields(mapping map[string]any) (map[string]*qdrant.VectorParams, map[string]bool, error) {
 	indexMapping, ok := mapping[r.OpenSearch.Index].(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("invalid mapping structure: missing index")
+		return nil, nil, fmt.Errorf("invalid mapping structure: missing index")
 	}
 
 	mappings, ok := indexMapping["mappings"].(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("invalid mapping structure: missing mappings")
+		return nil, nil, fmt.Errorf("invalid mapping structure: missing mappings")
 	}
 
 	properties, ok := mappings["properties"].(map[string]any)
 	if !ok {
-		return nil, fmt.Errorf("invalid mapping structure: missing properties")
+		return nil, nil, fmt.Errorf("invalid mapping structure: missing properties")
 	}
 
-	distanceMapping := map[string]qdrant.Distance{
-		"l2":           qdrant.Distance_Euclid,
-		"cosinesimil":  qdrant.Distance_Cosine,
-		"innerproduct": qdrant.Distance_Dot,
-		"l1":           qdrant.Distance_Manhattan,
+	vectorParamsMap := make(map[string]*qdrant.VectorParams)
+	nestedFields := make(map[string]bool)
+	err := addOpenSearchVectorFields(properties, "", vectorParamsMap, nestedFields)
+	if err != nil {
+		return nil, nil, err
 	}
+	return vectorParamsMap, nestedFields, nil
+}
 
-	for fieldName, fieldDef := range properties {
+// addOpenSearchVectorFields adds the knn_vector fields of the properties of a mapping, and of the object and nested
+// fields within them, by their path with the given prefix.
+func addOpenSearchVectorFields(properties map[string]any, prefix string, vectorParamsMap map[string]*qdrant.VectorParams, nestedFields map[string]bool) error {
+	for name, fieldDef := range properties {
+		fieldName := prefix + name
 		fieldProps, ok := fieldDef.(map[string]any)
 		if !ok {
 			continue
 		}
 
-		fieldType, ok := fieldProps["type"].(string)
-		if !ok || fieldType != "knn_vector" {
+		fieldType, _ := fieldProps["type"].(string)
+		if children, ok := fieldProps["properties"].(map[string]any); ok {
+			count := len(vectorParamsMap)
+			err := addOpenSearchVectorFields(children, fieldName+".", vectorParamsMap, nestedFields)
+			if err != nil {
+				return err
+			}
+			if fieldType == "nested" && len(vectorParamsMap) > count {
+				nestedFields[fieldName] = true
+			}
+			continue
+		}
+		if fieldType != "knn_vector" {
 			continue
 		}
 
@@ -231,26 +327,21 @@ func (r *MigrateFromOpenSearchCmd) extractVectorFields(mapping map[string]any) (
 
 		dimension, ok := dimValue.(float64)
 		if !ok {
-			return nil, fmt.Errorf("invalid dimension type for field %s: expected int, got %T", fieldName, dimValue)
+			return fmt.Errorf("invalid dimension type for field %s: expected int, got %T", fieldName, dimValue)
 		}
 
-		var distance qdrant.Distance
-		if spaceType, ok := fieldProps["space_type"].(string); ok {
-			if mappedDistance, exists := distanceMapping[strings.ToLower(spaceType)]; exists {
-				distance = mappedDistance
-			} else {
-				distance = qdrant.Distance_Cosine
-				pterm.Warning.Printfln("Unsupported space type '%s' for field '%s', defaulting to cosine distance", spaceType, fieldName)
-			}
+		// l2 is the default space type of OpenSearch.
+		spaceType, ok := fieldProps["space_type"].(string)
+		if !ok {
+			spaceType = "l2"
 		}
 
 		vectorParamsMap[fieldName] = &qdrant.VectorParams{
 			Size:     uint64(dimension),
-			Distance: distance,
+			Distance: translateDistance("opensearch", spaceType, fieldName),
 		}
 	}
-
-	return vectorParamsMap, nil
+	return nil
 }
 
 func (r *MigrateFromOpenSearchCmd) migrateData(ctx context.Context, sourceClient *opensearch.Client, targetClient *qdrant.Client, sourcePointCount int64) error {
@@ -277,7 +368,15 @@ func (r *MigrateFromOpenSearchCmd) migrateData(ctx context.Context, sourceClient
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
 	displayMigrationProgress(bar, offsetCount)
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		hits, err := r.searchWithPagination(ctx, sourceClient, batchSize, lastSortValue)
 		if err != nil {
 			return fmt.Errorf("failed to search documents: %w", err)
@@ -289,45 +388,36 @@ func (r *MigrateFromOpenSearchCmd) migrateData(ctx context.Context, sourceClient
 
 		var targetPoints []*qdrant.PointStruct
 		for _, hit := range hits {
-			doc := hit.(map[string]any)
-			source := doc["_source"].(map[string]any)
-			docID := doc["_id"].(string)
-
-			point := &qdrant.PointStruct{}
-			vectors := make(map[string]*qdrant.Vector)
-			payload := make(map[string]any)
-
-			point.Id = arbitraryIDToUUID(docID)
-			payload[r.IdField] = docID
-
-			for fieldName, value := range source {
-				if vector, ok := extractOpenSearchVector(value); ok {
-					vectors[fieldName] = qdrant.NewVector(vector...)
-				} else {
-					payload[fieldName] = value
-				}
+			points, err := r.hitToPoints(hit.(map[string]any))
+			if err != nil {
+				return err
 			}
+			targetPoints = append(targetPoints, points...)
+		}
 
-			if len(vectors) > 0 {
-				point.Vectors = qdrant.NewVectorsMap(vectors)
-			} else {
-				point.Vectors = qdrant.NewVectorsMap(map[string]*qdrant.Vector{})
-			}
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
 
-			point.Payload = qdrant.NewValueMap(payload)
-			targetPoints = append(targetPoints, point)
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
-		offsetCount += uint64(len(targetPoints))
+		// Documents exploded into several points count once, like in the source.
+		offsetCount += uint64(len(hits))
 
 		lastDoc := hits[len(hits)-1].(map[string]any)
 		lastSortValue = lastDoc["_id"]
@@ -349,7 +439,11 @@ func (r *MigrateFromOpenSearchCmd) migrateData(ctx context.Context, sourceClient
 			return fmt.Errorf("failed to store offset: %w", err)
 		}
 
-		bar.Add(len(targetPoints))
+		bar.Add(len(hits))
+
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
@@ -370,6 +464,9 @@ func (r *MigrateFromOpenSearchCmd) searchWithPagination(ctx context.Context, cli
 	if searchAfter != nil {
 		searchRequest["search_after"] = []any{searchAfter}
 	}
+	if r.VersionField != "" {
+		searchRequest["version"] = true
+	}
 
 	requestBody, err := json.Marshal(searchRequest)
 	if err != nil {
@@ -408,6 +505,134 @@ func (r *MigrateFromOpenSearchCmd) searchWithPagination(ctx context.Context, cli
 	return hits, nil
 }
 
+// hitToPoints converts a document into a point, or with --opensearch.explode-nested, into a point for every object of
+// the nested field, each with the vectors and fields of its object and of the rest of the document.
+func (r *MigrateFromOpenSearchCmd) hitToPoints(doc map[string]any) ([]*qdrant.PointStruct, error) {
+	source, _ := doc["_source"].(map[string]any)
+	docID, _ := doc["_id"].(string)
+
+	var objects []map[string]any
+	if r.ExplodeNested != "" {
+		objects = nestedObjects(source[r.ExplodeNested])
+		delete(source, r.ExplodeNested)
+	}
+
+	vectors, err := r.takeVectors(source, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vectors of document %s: %w", docID, err)
+	}
+
+	payload := make(map[string]any, len(source)+2)
+	maps.Copy(payload, source)
+	if version, ok := doc["_version"].(float64); ok && r.VersionField != "" {
+		payload[r.VersionField] = int64(version)
+	}
+
+	if len(objects) == 0 {
+		payload[r.IdField] = docID
+		return []*qdrant.PointStruct{{
+			Id:      arbitraryIDToUUID(docID),
+			Vectors: qdrant.NewVectorsMap(vectors),
+			Payload: qdrant.NewValueMap(payload),
+		}}, nil
+	}
+
+	points := make([]*qdrant.PointStruct, 0, len(objects))
+	for i, object := range objects {
+		objectVectors, err := r.takeVectors(object, r.ExplodeNested+".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vectors of document %s: %w", docID, err)
+		}
+		maps.Copy(objectVectors, vectors)
+
+		id := fmt.Sprintf("%s#%d", docID, i)
+		objectPayload := maps.Clone(payload)
+		objectPayload[r.ExplodeNested] = object
+		objectPayload[r.IdField] = id
+		objectPayload[r.ParentIdField] = docID
+		points = append(points, &qdrant.PointStruct{
+			Id:      arbitraryIDToUUID(id),
+			Vectors: qdrant.NewVectorsMap(objectVectors),
+			Payload: qdrant.NewValueMap(objectPayload),
+		})
+	}
+	return points, nil
+}
+
+// takeVectors removes the vectors of the knn_vector fields under the given path prefix from a document, or an object
+// of a nested field, and returns them by their path. Without any knn_vector field in the mapping, top-level arrays of
+// numbers are taken as vectors.
+func (r *MigrateFromOpenSearchCmd) takeVectors(doc map[string]any, prefix string) (map[string]*qdrant.Vector, error) {
+	vectors := make(map[string]*qdrant.Vector)
+	if len(r.vectorFields) == 0 {
+		for name, value := range doc {
+			if vector, ok := extractOpenSearchVector(value); ok {
+				vectors[prefix+name] = qdrant.NewVector(vector...)
+				delete(doc, name)
+			}
+		}
+		return vectors, nil
+	}
+
+	for name := range r.vectorFields {
+		path, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		vector, err := takeOpenSearchVector(doc, path, prefix)
+		if err != nil {
+			return nil, err
+		}
+		if vector != nil {
+			vectors[name] = qdrant.NewVector(vector...)
+		}
+	}
+	return vectors, nil
+}
+
+// takeOpenSearchVector removes the vector at a path of a document, e.g. meta.embedding, and returns it, or nil if the
+// document doesn't have it. A point holds a single vector per name, so objects of nested fields are only walked into
+// if there's a single one.
+func takeOpenSearchVector(doc map[string]any, path string, walked string) ([]float32, error) {
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		vector, ok := extractOpenSearchVector(doc[key])
+		if !ok {
+			return nil, nil
+		}
+		delete(doc, key)
+		return vector, nil
+	}
+
+	objects := nestedObjects(doc[key])
+	switch len(objects) {
+	case 0:
+		return nil, nil
+	case 1:
+		return takeOpenSearchVector(objects[0], rest, walked+key+".")
+	default:
+		return nil, fmt.Errorf("field %s%s has %d objects with vectors, set --opensearch.explode-nested=%s%s to migrate them as points of their own", walked, key, len(objects), walked, key)
+	}
+}
+
+// nestedObjects returns the objects of an object or nested field, which has a single object or a list of them.
+func nestedObjects(value any) []map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		objects := make([]map[string]any, 0, len(v))
+		for _, item := range v {
+			if object, ok := item.(map[string]any); ok {
+				objects = append(objects, object)
+			}
+		}
+		return objects
+	default:
+		return nil
+	}
+}
+
 func extractOpenSearchVector(value any) ([]float32, bool) {
 	switch v := value.(type) {
 	case []any:
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const openSearchTestMapping = `{"docs": {"mappings": {"properties": {
+	"title": {"type": "text"},
+	"title_vector": {"type": "knn_vector", "dimension": 2, "space_type": "cosinesimil"},
+	"body_vector": {"type": "knn_vector", "dimension": 3},
+	"meta": {"properties": {"embedding": {"type": "knn_vector", "dimension": 2, "space_type": "innerproduct"}}},
+	"passages": {"type": "nested", "properties": {"text": {"type": "text"}, "embedding": {"type": "knn_vector", "dimension": 2}}},
+	"tags": {"type": "nested", "properties": {"name": {"type": "keyword"}}}
+}}}}`
+
+func newOpenSearchTestCmd(t *testing.T, explodeNested string) *MigrateFromOpenSearchCmd {
+	t.Helper()
+	r := &MigrateFromOpenSearchCmd{IdField: "__id__", ParentIdField: "parent_id", ExplodeNested: explodeNested}
+	r.OpenSearch.Index = "docs"
+
+	var mapping map[string]any
+	if err := json.Unmarshal([]byte(openSearchTestMapping), &mapping); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	r.vectorFields, r.nestedFields, err = r.extractVectorFields(mapping)
+	if err != nil {
+		t.Fatalf("extractVectorFields() error = %v", err)
+	}
+	return r
+}
+
+func TestOpenSearchExtractVectorFields(t *testing.T) {
+	r := newOpenSearchTestCmd(t, "")
+
+	want := map[string]struct {
+		size     uint64
+		distance qdrant.Distance
+	}{
+		"title_vector":       {2, qdrant.Distance_Cosine},
+		"body_vector":        {3, qdrant.Distance_Euclid},
+		"meta.embedding":     {2, qdrant.Distance_Dot},
+		"passages.embedding": {2, qdrant.Distance_Euclid},
+	}
+	if len(r.vectorFields) != len(want) {
+		t.Errorf("got vector fields %v, want %v", r.vectorFields, want)
+	}
+	for name, params := range want {
+		got := r.vectorFields[name]
+		if got.GetSize() != params.size || got.GetDistance() != params.distance {
+			t.Errorf("got %s = %v, want size %d and distance %s", name, got, params.size, params.distance)
+		}
+	}
+	if !r.nestedFields["passages"] || r.nestedFields["tags"] || r.nestedFields["meta"] {
+		t.Errorf("got nested fields with vectors %v, want only passages", r.nestedFields)
+	}
+}
+
+func TestOpenSearchHitToPoints(t *testing.T) {
+	tests := []struct {
+		name          string
+		explodeNested string
+		source        string
+		wantIDs       []string
+		wantVectors   [][]string
+		wantPayload   []map[string]string
+		wantErr       string
+	}{
+		{
+			name:        "multiple and nested vector fields",
+			source:      `{"title": "a", "title_vector": [1, 2], "body_vector": [1, 2, 3], "meta": {"lang": "en", "embedding": [3, 4]}, "passages": [{"text": "p", "embedding": [5, 6]}]}`,
+			wantIDs:     []string{"doc-1"},
+			wantVectors: [][]string{{"body_vector", "meta.embedding", "passages.embedding", "title_vector"}},
+			wantPayload: []map[string]string{{"__id__": "doc-1", "title": "a"}},
+		},
+		{
+			name:    "several nested objects",
+			source:  `{"title": "a", "passages": [{"embedding": [5, 6]}, {"embedding": [7, 8]}]}`,
+			wantErr: "--opensearch.explode-nested=passages",
+		},
+		{
+			name:          "exploded nested objects",
+			explodeNested: "passages",
+			source:        `{"title": "a", "title_vector": [1, 2], "passages": [{"text": "p1", "embedding": [5, 6]}, {"text": "p2", "embedding": [7, 8]}]}`,
+			wantIDs:       []string{"doc-1#0", "doc-1#1"},
+			wantVectors:   [][]string{{"passages.embedding", "title_vector"}, {"passages.embedding", "title_vector"}},
+			wantPayload: []map[string]string{
+				{"__id__": "doc-1#0", "parent_id": "doc-1", "title": "a"},
+				{"__id__": "doc-1#1", "parent_id": "doc-1", "title": "a"},
+			},
+		},
+		{
+			name:          "no nested objects to explode",
+			explodeNested: "passages",
+			source:        `{"title": "a", "title_vector": [1, 2]}`,
+			wantIDs:       []string{"doc-1"},
+			wantVectors:   [][]string{{"title_vector"}},
+			wantPayload:   []map[string]string{{"__id__": "doc-1", "title": "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newOpenSearchTestCmd(t, tt.explodeNested)
+
+			var source map[string]any
+			if err := json.Unmarshal([]byte(tt.source), &source); err != nil {
+				t.Fatal(err)
+			}
+			points, err := r.hitToPoints(map[string]any{"_id": "doc-1", "_source": source})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(points) != len(tt.wantIDs) {
+				t.Fatalf("got %d points, want %d", len(points), len(tt.wantIDs))
+			}
+			for i, point := range points {
+				if point.GetId().String() != arbitraryIDToUUID(tt.wantIDs[i]).String() {
+					t.Errorf("got ID %v, want the one of %s", point.GetId(), tt.wantIDs[i])
+				}
+
+				var names []string
+				for name := range point.GetVectors().GetVectors().GetVectors() {
+					names = append(names, name)
+				}
+				slices.Sort(names)
+				if !slices.Equal(names, tt.wantVectors[i]) {
+					t.Errorf("got vectors %v, want %v", names, tt.wantVectors[i])
+				}
+
+				for key, want := range tt.wantPayload[i] {
+					if got := point.GetPayload()[key].GetStringValue(); got != want {
+						t.Errorf("got payload %s = %q, want %q", key, got, want)
+					}
+				}
+				// Vectors are taken out of the payload, also from nested objects.
+				if strings.Contains(point.GetPayload()["meta"].String(), "embedding") || strings.Contains(point.GetPayload()["passages"].String(), "embedding") {
+					t.Errorf("got vectors in the payload %v", point.GetPayload())
+				}
+			}
+		})
+	}
+}
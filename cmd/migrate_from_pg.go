@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 	pgxvec "github.com/pgvector/pgvector-go/pgx"
 	"github.com/pterm/pterm"
@@ -24,6 +26,8 @@ type MigrateFromPGCmd struct {
 	Qdrant         commons.QdrantConfig    `embed:"" prefix:"qdrant."`
 	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
 	DistanceMetric map[string]string       `prefix:"qdrant." help:"Map of vector field names to distance metrics (cosine,dot,euclid,manhattan). Default is cosine if not specified."`
+	Partitions     int                     `prefix:"pg." help:"Number of key ranges to read in parallel, each with its own checkpoint. Values above 1 require an integer key column." default:"1"`
+	Connections    int                     `prefix:"pg." help:"Maximum number of connections to Postgres, shared by all reads. Partitions beyond it wait for a free connection. Defaults to one per partition." default:"0"`
 
 	targetHost string
 	targetPort int
@@ -41,6 +45,12 @@ func (r *MigrateFromPGCmd) Parse() error {
 }
 
 func (r *MigrateFromPGCmd) Validate() error {
+	if r.Partitions < 1 {
+		return fmt.Errorf("partitions must be greater than 0")
+	}
+	if r.Connections < 0 {
+		return fmt.Errorf("connections must not be negative")
+	}
 	return validateBatchSize(r.Migration.BatchSize)
 }
 
@@ -52,42 +62,78 @@ func (r *MigrateFromPGCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	sourceConn, err := r.connectToPG(ctx)
+	sourcePool, err := connectToPGPool(ctx, r.PG.Url, r.maxConnections())
 	if err != nil {
 		return fmt.Errorf("failed to connect to Postgres source: %w", err)
 	}
-	defer sourceConn.Close(ctx)
+	defer sourcePool.Close()
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
 	}
 
-	sourcePointCount, err := r.countPGRows(ctx, sourceConn)
+	sourcePointCount, err := r.countPGRows(ctx, sourcePool)
 	if err != nil {
 		return fmt.Errorf("failed to count points in source: %w", err)
 	}
 
-	err = r.prepareTargetCollection(ctx, sourceConn, targetClient)
+	err = r.prepareTargetCollection(ctx, sourcePool, targetClient)
 	if err != nil {
 		return fmt.Errorf("error preparing target collection: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("postgres", r.PG.Table, r.Qdrant.Collection)
 
-	err = r.migrateData(ctx, sourceConn, targetClient, sourcePointCount)
+	if r.Partitions > 1 {
+		err = r.migrateDataPartitioned(ctx, sourcePool, targetClient, sourcePointCount)
+	} else {
+		err = r.migrateData(ctx, sourcePool, targetClient, sourcePointCount)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -97,12 +143,13 @@ func (r *MigrateFromPGCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
 
-func (r *MigrateFromPGCmd) connectToPG(ctx context.Context) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(ctx, r.PG.Url)
+func connectToPG(ctx context.Context, url string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
 	}
@@ -115,9 +162,40 @@ func (r *MigrateFromPGCmd) connectToPG(ctx context.Context) (*pgx.Conn, error) {
 	return conn, nil
 }
 
-func (r *MigrateFromPGCmd) countPGRows(ctx context.Context, conn *pgx.Conn) (uint64, error) {
+// connectToPGPool opens a pool of at most maxConns connections to Postgres, with the pgvector types registered on every one.
+// The first connection is opened right away, so a wrong URL fails early.
+func connectToPGPool(ctx context.Context, url string, maxConns int) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Postgres URL: %w", err)
+	}
+	config.MaxConns = int32(maxConns)
+	config.AfterConnect = pgxvec.RegisterTypes
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+
+	return pool, nil
+}
+
+// maxConnections returns the size of the connection pool, --pg.connections or one connection per partition.
+func (r *MigrateFromPGCmd) maxConnections() int {
+	if r.Connections > 0 {
+		return r.Connections
+	}
+	return r.Partitions
+}
+
+func (r *MigrateFromPGCmd) countPGRows(ctx context.Context, pool *pgxpool.Pool) (uint64, error) {
 	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
-	row := conn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", tableIdent))
+	row := pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", tableIdent))
 
 	var count int64
 	err := row.Scan(&count)
@@ -128,11 +206,18 @@ func (r *MigrateFromPGCmd) countPGRows(ctx context.Context, conn *pgx.Conn) (uin
 	return uint64(count), nil
 }
 
-func getVectorColumns(ctx context.Context, conn *pgx.Conn, table string) (map[string]uint64, error) {
+// pgVectorColumn is a column of a pgvector type: vector, halfvec, sparsevec or bit.
+type pgVectorColumn struct {
+	kind       string
+	dimensions uint64
+}
+
+func getVectorColumns(ctx context.Context, pool *pgxpool.Pool, table string) (map[string]pgVectorColumn, error) {
 	tableIdent := pgx.Identifier{table}.Sanitize()
 	query := `
 	SELECT
 		attname AS column_name,
+		split_part(format_type(atttypid, atttypmod), '(', 1) AS column_type,
 		atttypmod AS dimensions
 	FROM
 		pg_attribute
@@ -140,26 +225,27 @@ func getVectorColumns(ctx context.Context, conn *pgx.Conn, table string) (map[st
 		attrelid = $1::regclass
 		AND attnum > 0
 		AND NOT attisdropped
-		AND format_type(atttypid, atttypmod) LIKE 'vector%';
+		AND split_part(format_type(atttypid, atttypmod), '(', 1) IN ('vector', 'halfvec', 'sparsevec', 'bit');
 	`
-	rows, err := conn.Query(ctx, query, tableIdent)
+	rows, err := pool.Query(ctx, query, tableIdent)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query vector columns: %w", err)
 	}
 	defer rows.Close()
 
-	vectorMap := make(map[string]uint64)
+	vectorMap := make(map[string]pgVectorColumn)
 	for rows.Next() {
-		var col string
+		var col, kind string
 		var dim int32
-		err := rows.Scan(&col, &dim)
+		err := rows.Scan(&col, &kind, &dim)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan vector column: %w", err)
 		}
-		if dim <= 0 {
+		// Sparse vectors need no dimension in Qdrant.
+		if dim <= 0 && kind != "sparsevec" {
 			return nil, fmt.Errorf("invalid dimension for column %s", col)
 		}
-		vectorMap[col] = uint64(dim)
+		vectorMap[col] = pgVectorColumn{kind: kind, dimensions: uint64(max(dim, 0))}
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error reading vector columns: %w", err)
@@ -167,8 +253,8 @@ func getVectorColumns(ctx context.Context, conn *pgx.Conn, table string) (map[st
 	return vectorMap, nil
 }
 
-func (r *MigrateFromPGCmd) prepareTargetCollection(ctx context.Context, sourceConn *pgx.Conn, targetClient *qdrant.Client) error {
-	if !r.Migration.CreateCollection {
+func (r *MigrateFromPGCmd) prepareTargetCollection(ctx context.Context, sourcePool *pgxpool.Pool, targetClient *qdrant.Client) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
 		return nil
 	}
 
@@ -182,7 +268,7 @@ func (r *MigrateFromPGCmd) prepareTargetCollection(ctx context.Context, sourceCo
 		return nil
 	}
 
-	vectorDims, err := getVectorColumns(ctx, sourceConn, r.PG.Table)
+	vectorColumns, err := getVectorColumns(ctx, sourcePool, r.PG.Table)
 	if err != nil {
 		return fmt.Errorf("failed to get vector columns: %w", err)
 	}
@@ -195,8 +281,18 @@ func (r *MigrateFromPGCmd) prepareTargetCollection(ctx context.Context, sourceCo
 	}
 
 	vectorParamsMap := make(map[string]*qdrant.VectorParams)
-	for field, dimension := range vectorDims {
+	sparseVectorParamsMap := make(map[string]*qdrant.SparseVectorParams)
+	for field, column := range vectorColumns {
+		if column.kind == "sparsevec" {
+			sparseVectorParamsMap[field] = &qdrant.SparseVectorParams{}
+			continue
+		}
+
+		// Bits are migrated as vectors of 0s and 1s, where the Manhattan distance is the Hamming distance pgvector uses for them.
 		distanceMetric := "cosine"
+		if column.kind == "bit" {
+			distanceMetric = "manhattan"
+		}
 		if specifiedDistance, ok := r.DistanceMetric[field]; ok {
 			distanceMetric = specifiedDistance
 		}
@@ -204,54 +300,119 @@ func (r *MigrateFromPGCmd) prepareTargetCollection(ctx context.Context, sourceCo
 			return fmt.Errorf("invalid distance metric '%s' for vector '%s'", distanceMetric, field)
 		}
 
-		vectorParamsMap[field] = &qdrant.VectorParams{
-			Size:     dimension,
+		params := &qdrant.VectorParams{
+			Size:     column.dimensions,
 			Distance: distanceMapping[distanceMetric],
 		}
+		switch column.kind {
+		case "halfvec":
+			params.Datatype = qdrant.Datatype_Float16.Enum()
+		case "bit":
+			params.Datatype = qdrant.Datatype_Uint8.Enum()
+		}
+		vectorParamsMap[field] = params
 	}
 
-	err = targetClient.CreateCollection(ctx, &qdrant.CreateCollection{
+	request := &qdrant.CreateCollection{
 		CollectionName: r.Qdrant.Collection,
 		VectorsConfig:  qdrant.NewVectorsConfigMap(vectorParamsMap),
-	})
+		ShardingMethod: tenantShardingMethod(r.Migration, nil),
+	}
+	if len(sparseVectorParamsMap) > 0 {
+		request.SparseVectorsConfig = qdrant.NewSparseVectorsConfig(sparseVectorParamsMap)
+	}
+	applyTopology(request, r.Migration)
+	err = targetClient.CreateCollection(ctx, request)
 	if err != nil {
 		return fmt.Errorf("failed to create target collection: %w", err)
 	}
 
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	pterm.Success.Printfln("Created target collection %q", r.Qdrant.Collection)
 	return nil
 }
 
-func (r *MigrateFromPGCmd) migrateData(ctx context.Context, sourceConn *pgx.Conn, targetClient *qdrant.Client, sourcePointCount uint64) error {
+// migrateData reads the table in pages ordered by the key column, each continuing after the last key of the previous one,
+// so reading a page doesn't get slower as the migration progresses, like with OFFSET. The last key is checkpointed.
+// Key columns of other types than keyToOffset can checkpoint, and migrations checkpointed before keys were, are read
+// with OFFSET, and only the number of rows read is checkpointed, under the table name.
+func (r *MigrateFromPGCmd) migrateData(ctx context.Context, sourcePool *pgxpool.Pool, targetClient *qdrant.Client, sourcePointCount uint64) error {
 	batchSize := r.Migration.BatchSize
 
+	keyset, err := r.keysetSupported(ctx, sourcePool)
+	if err != nil {
+		return err
+	}
+	if !keyset {
+		pterm.Warning.Printfln("Key column %q can't be checkpointed, reading the table with OFFSET, which gets slower as the migration progresses", r.PG.KeyColumn)
+	}
+
+	offsetKey := fmt.Sprintf("%s/key=%s", r.PG.Table, r.PG.KeyColumn)
 	offsetCount := uint64(0)
+	var lastKey any
 
-	if !r.Migration.Restart {
-		_, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.PG.Table)
+	if !r.Migration.Restart && keyset {
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, offsetKey)
 		if err != nil {
 			return fmt.Errorf("failed to get start offset: %w", err)
 		}
+		if id != nil {
+			lastKey = offsetToKey(id)
+		}
 		offsetCount = count
 	}
+	if !r.Migration.Restart && lastKey == nil {
+		_, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.PG.Table)
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		if count > 0 && keyset {
+			pterm.Info.Printfln("Continuing a migration checkpointed by row count, reading the rest of the table with OFFSET")
+		}
+		if count > 0 {
+			keyset = false
+			offsetCount = count
+		}
+	}
+	if !keyset {
+		offsetKey = r.PG.Table
+	}
 
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
 	displayMigrationProgress(bar, offsetCount)
 
+	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
+	keyIdent := pgx.Identifier{r.PG.KeyColumn}.Sanitize()
+	firstQuery := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT $1", r.selectColumns(), tableIdent, keyIdent)
+	nextQuery := fmt.Sprintf("SELECT %s FROM %s WHERE %s > $1 ORDER BY %s LIMIT $2", r.selectColumns(), tableIdent, keyIdent, keyIdent)
+	offsetQuery := fmt.Sprintf("SELECT %s FROM %s LIMIT $1 OFFSET $2", r.selectColumns(), tableIdent)
+
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
-		var selectColumns string
-		if len(r.PG.Columns) > 0 {
-			var quotedCols []string
-			for _, col := range r.PG.Columns {
-				quotedCols = append(quotedCols, pgx.Identifier{col}.Sanitize())
-			}
-			selectColumns = strings.Join(quotedCols, ", ")
-		} else {
-			selectColumns = "*"
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
+		var rows pgx.Rows
+		switch {
+		case !keyset:
+			rows, err = sourcePool.Query(ctx, offsetQuery, batchSize, offsetCount)
+		case lastKey == nil:
+			rows, err = sourcePool.Query(ctx, firstQuery, batchSize)
+		default:
+			rows, err = sourcePool.Query(ctx, nextQuery, lastKey, batchSize)
 		}
-		tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
-		query := fmt.Sprintf("SELECT %s FROM %s LIMIT $1 OFFSET $2", selectColumns, tableIdent)
-		rows, err := sourceConn.Query(ctx, query, batchSize, offsetCount)
 		if err != nil {
 			return fmt.Errorf("failed to query PG: %w", err)
 		}
@@ -267,50 +428,52 @@ func (r *MigrateFromPGCmd) migrateData(ctx context.Context, sourceConn *pgx.Conn
 
 		var targetPoints []*qdrant.PointStruct
 		for _, row := range batchRows {
-			point := &qdrant.PointStruct{}
-			vectors := make(map[string]*qdrant.Vector)
-			payload := make(map[string]interface{})
-
-			for col, val := range row {
-				if col == r.PG.KeyColumn {
-					idStr := fmt.Sprint(val)
-					point.Id = arbitraryIDToUUID(idStr)
-				}
-
-				switch v := val.(type) {
-				case pgvector.Vector:
-					vectors[col] = qdrant.NewVector(v.Slice()...)
-				default:
-					payload[col] = sanitizeValue(val)
-				}
-			}
+			targetPoints = append(targetPoints, r.rowToPoint(row))
+		}
+
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
 
-			if len(vectors) > 0 {
-				point.Vectors = qdrant.NewVectorsMap(vectors)
+		// The key is converted before the batch is written, so a key that can't be checkpointed stops the migration
+		// before points are written past the last checkpoint.
+		offsetCount += uint64(len(targetPoints))
+		offsetID := qdrant.NewIDNum(offsetCount)
+		if keyset {
+			offsetID, err = keyToOffset(batchRows[len(batchRows)-1][r.PG.KeyColumn])
+			if err != nil {
+				return fmt.Errorf("failed to read key column: %w", err)
 			}
-			point.Payload = qdrant.NewValueMap(payload)
-			targetPoints = append(targetPoints, point)
+			lastKey = offsetToKey(offsetID)
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
-		// Just a placeholder ID.
-		// We're only using the offset count
-		offsetID := qdrant.NewIDNum(0)
-		offsetCount += uint64(len(targetPoints))
-		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.PG.Table, offsetID, offsetCount)
+		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, offsetKey, offsetID, offsetCount)
 		if err != nil {
 			return fmt.Errorf("failed to store offset: %w", err)
 		}
 
 		bar.Add(len(targetPoints))
+
+		if len(batchRows) < batchSize || sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
@@ -318,6 +481,94 @@ func (r *MigrateFromPGCmd) migrateData(ctx context.Context, sourceConn *pgx.Conn
 	return nil
 }
 
+// keysetKeyTypes are the types of key columns whose keys keyToOffset can checkpoint.
+var keysetKeyTypes = map[uint32]bool{
+	pgtype.Int2OID:    true,
+	pgtype.Int4OID:    true,
+	pgtype.Int8OID:    true,
+	pgtype.TextOID:    true,
+	pgtype.VarcharOID: true,
+	pgtype.BPCharOID:  true,
+	pgtype.UUIDOID:    true,
+}
+
+// keysetSupported reports whether the key column has a type the table can be read in pages by.
+func (r *MigrateFromPGCmd) keysetSupported(ctx context.Context, pool *pgxpool.Pool) (bool, error) {
+	rows, err := pool.Query(ctx, fmt.Sprintf("SELECT %s FROM %s LIMIT 0", pgx.Identifier{r.PG.KeyColumn}.Sanitize(), pgx.Identifier{r.PG.Table}.Sanitize()))
+	if err != nil {
+		return false, fmt.Errorf("failed to get type of key column: %w", err)
+	}
+	defer rows.Close()
+
+	fields := rows.FieldDescriptions()
+	return len(fields) == 1 && keysetKeyTypes[fields[0].DataTypeOID], nil
+}
+
+func (r *MigrateFromPGCmd) selectColumns() string {
+	if len(r.PG.Columns) == 0 {
+		return "*"
+	}
+
+	var quotedCols []string
+	for _, col := range r.PG.Columns {
+		quotedCols = append(quotedCols, pgx.Identifier{col}.Sanitize())
+	}
+	return strings.Join(quotedCols, ", ")
+}
+
+func (r *MigrateFromPGCmd) rowToPoint(row map[string]any) *qdrant.PointStruct {
+	point := &qdrant.PointStruct{}
+	vectors := make(map[string]*qdrant.Vector)
+	payload := make(map[string]interface{})
+
+	for col, val := range row {
+		if col == r.PG.KeyColumn {
+			idStr := fmt.Sprint(val)
+			point.Id = arbitraryIDToUUID(idStr)
+		}
+
+		switch v := val.(type) {
+		case pgvector.Vector:
+			vectors[col] = qdrant.NewVector(v.Slice()...)
+		case pgvector.HalfVector:
+			vectors[col] = qdrant.NewVector(v.Slice()...)
+		case pgvector.SparseVector:
+			vectors[col] = qdrant.NewVectorSparse(pgSparseIndices(v.Indices()), v.Values())
+		case pgtype.Bits:
+			vectors[col] = qdrant.NewVector(pgBitsToVector(v)...)
+		default:
+			payload[col] = sanitizeValue(val)
+		}
+	}
+
+	if len(vectors) > 0 {
+		point.Vectors = qdrant.NewVectorsMap(vectors)
+	}
+	point.Payload = qdrant.NewValueMap(payload)
+
+	return point
+}
+
+// pgSparseIndices converts the indices of a pgvector sparse vector, which are never negative, to Qdrant ones.
+func pgSparseIndices(indices []int32) []uint32 {
+	result := make([]uint32, len(indices))
+	for i, index := range indices {
+		result[i] = uint32(index)
+	}
+	return result
+}
+
+// pgBitsToVector converts a bit string to a vector with a 0 or 1 per bit, most significant bit of every byte first.
+func pgBitsToVector(bits pgtype.Bits) []float32 {
+	vector := make([]float32, bits.Len)
+	for i := range vector {
+		if bits.Bytes[i/8]&(0x80>>(i%8)) != 0 {
+			vector[i] = 1
+		}
+	}
+	return vector
+}
+
 // Recursively converts value unsupported as payload in Qdrant to string.
 // Otherwise, it returns the value as is.
 func sanitizeValue(val any) any {
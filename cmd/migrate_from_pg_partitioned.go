@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// keyRange is an inclusive range of integer keys.
+type keyRange struct {
+	from int64
+	to   int64
+}
+
+// splitKeyRange splits [minKey, maxKey] into at most n contiguous, non-overlapping ranges of similar width.
+func splitKeyRange(minKey, maxKey int64, n int) []keyRange {
+	// Unsigned arithmetic, so the width of ranges spanning the full int64 domain doesn't overflow.
+	width := uint64(maxKey) - uint64(minKey)
+	step := width/uint64(n) + 1
+
+	ranges := make([]keyRange, 0, n)
+	from := minKey
+	for i := 0; i < n; i++ {
+		if i == n-1 || uint64(maxKey)-uint64(from) < step {
+			ranges = append(ranges, keyRange{from: from, to: maxKey})
+			break
+		}
+		to := int64(uint64(from) + step - 1)
+		ranges = append(ranges, keyRange{from: from, to: to})
+		from = to + 1
+	}
+
+	return ranges
+}
+
+// partitionOffsetKey is the checkpoint key of a key range. The ranges are split from the MIN and MAX of the key column
+// on every run, so the key holds the bounds of the range: once they change, the last key checkpointed for another range
+// isn't applied to it, which would skip the keys between them, and the range is read from its start.
+func partitionOffsetKey(table string, kr keyRange) string {
+	return fmt.Sprintf("%s/partition=%d..%d", table, kr.from, kr.to)
+}
+
+// migrateDataPartitioned splits the key column into ranges and reads them concurrently, each with a connection of the pool.
+// Every range is paged by key and checkpoints the last key it migrated, so an interrupted run resumes every range independently.
+func (r *MigrateFromPGCmd) migrateDataPartitioned(ctx context.Context, sourcePool *pgxpool.Pool, targetClient *qdrant.Client, sourcePointCount uint64) error {
+	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
+	keyIdent := pgx.Identifier{r.PG.KeyColumn}.Sanitize()
+
+	var minKey, maxKey *int64
+	err := sourcePool.QueryRow(ctx, fmt.Sprintf("SELECT MIN(%s)::bigint, MAX(%s)::bigint FROM %s", keyIdent, keyIdent, tableIdent)).Scan(&minKey, &maxKey)
+	if err != nil {
+		return fmt.Errorf("failed to get key range, partitioned reads require an integer key column: %w", err)
+	}
+	if minKey == nil || maxKey == nil {
+		pterm.Success.Printfln("Data migration finished successfully")
+		return nil
+	}
+
+	ranges := splitKeyRange(*minKey, *maxKey, r.Partitions)
+	pterm.Info.Printfln("Reading %d key ranges in parallel", len(ranges))
+
+	type partition struct {
+		keyRange
+		offsetKey   string
+		lastKey     *int64
+		offsetCount uint64
+	}
+
+	partitions := make([]*partition, 0, len(ranges))
+	totalOffsetCount := uint64(0)
+	for _, kr := range ranges {
+		p := &partition{
+			keyRange:  kr,
+			offsetKey: partitionOffsetKey(r.PG.Table, kr),
+		}
+		if !r.Migration.Restart {
+			id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, p.offsetKey)
+			if err != nil {
+				return fmt.Errorf("failed to get start offset: %w", err)
+			}
+			if id != nil {
+				// Keys are stored as the bits of the signed key, since offsets are unsigned point IDs.
+				lastKey := int64(id.GetNum())
+				p.lastKey = &lastKey
+			}
+			p.offsetCount = count
+		}
+		totalOffsetCount += p.offsetCount
+		partitions = append(partitions, p)
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	displayMigrationProgress(bar, totalOffsetCount)
+
+	var barLock sync.Mutex
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s >= $1 AND %s <= $2 ORDER BY %s LIMIT $3",
+		r.selectColumns(), tableIdent, keyIdent, keyIdent, keyIdent)
+
+	budget := newMemoryBudget(r.Migration.MaxMemory)
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, p := range partitions {
+		group.Go(func() error {
+			// The connection is held for the whole range, so the ranges beyond the size of the pool wait for one.
+			conn, err := sourcePool.Acquire(groupCtx)
+			if err != nil {
+				return fmt.Errorf("failed to acquire Postgres connection: %w", err)
+			}
+			defer conn.Release()
+
+			memory := budget.reader()
+			defer memory.release()
+
+			from := p.from
+			if p.lastKey != nil {
+				if *p.lastKey >= p.to {
+					return nil
+				}
+				from = *p.lastKey + 1
+			}
+
+			for from <= p.to {
+				err = memory.reserve(groupCtx)
+				if err != nil {
+					return err
+				}
+
+				batchStart := time.Now()
+				rows, err := conn.Query(groupCtx, query, from, p.to, r.Migration.BatchSize)
+				if err != nil {
+					return fmt.Errorf("failed to query PG: %w", err)
+				}
+
+				batchRows, err := pgx.CollectRows(rows, pgx.RowToMap)
+				if err != nil {
+					return fmt.Errorf("failed to collect rows: %w", err)
+				}
+
+				if len(batchRows) == 0 {
+					break
+				}
+
+				targetPoints := make([]*qdrant.PointStruct, 0, len(batchRows))
+				for _, row := range batchRows {
+					targetPoints = append(targetPoints, r.rowToPoint(row))
+				}
+				currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+				err = memory.fit(groupCtx, pointsSize(targetPoints))
+				if err != nil {
+					return err
+				}
+
+				writePoints, err := transformPayloads(groupCtx, targetPoints, r.Migration)
+				if err != nil {
+					return err
+				}
+
+				request := &qdrant.UpsertPoints{
+					CollectionName: r.Qdrant.Collection,
+					Points:         writePoints,
+					Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+				}
+				err = upsertPoints(groupCtx, targetClient, request, r.Migration)
+				if err != nil {
+					return fmt.Errorf("failed to insert data into target: %w", err)
+				}
+				currentReport.addWritten(len(request.GetPoints()))
+
+				lastKey, err := toInt64(batchRows[len(batchRows)-1][r.PG.KeyColumn])
+				if err != nil {
+					return fmt.Errorf("failed to read key column: %w", err)
+				}
+
+				p.offsetCount += uint64(len(targetPoints))
+				err = commons.StoreStartOffset(groupCtx, r.Migration.OffsetsCollection, targetClient, p.offsetKey, qdrant.NewIDNum(uint64(lastKey)), p.offsetCount)
+				if err != nil {
+					return fmt.Errorf("failed to store offset: %w", err)
+				}
+
+				barLock.Lock()
+				bar.Add(len(targetPoints))
+				barLock.Unlock()
+
+				if lastKey == math.MaxInt64 || sampleComplete(r.Migration) {
+					break
+				}
+				from = lastKey + 1
+			}
+
+			return nil
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+
+	return nil
+}
+
+func toInt64(value any) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case int:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("unsupported key type %T, expected an integer", value)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func Test_splitKeyRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		min      int64
+		max      int64
+		n        int
+		expected []keyRange
+	}{
+		{name: "even split", min: 1, max: 100, n: 4, expected: []keyRange{{1, 25}, {26, 50}, {51, 75}, {76, 100}}},
+		{name: "uneven split", min: 0, max: 9, n: 3, expected: []keyRange{{0, 3}, {4, 7}, {8, 9}}},
+		{name: "fewer keys than partitions", min: 5, max: 6, n: 4, expected: []keyRange{{5, 5}, {6, 6}}},
+		{name: "single key", min: 3, max: 3, n: 2, expected: []keyRange{{3, 3}}},
+		{name: "negative keys", min: -10, max: 9, n: 2, expected: []keyRange{{-10, -1}, {0, 9}}},
+		{name: "full domain", min: math.MinInt64, max: math.MaxInt64, n: 2, expected: []keyRange{{math.MinInt64, -1}, {0, math.MaxInt64}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitKeyRange(tt.min, tt.max, tt.n)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitKeyRange() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMaxConnections(t *testing.T) {
+	tests := []struct {
+		name        string
+		partitions  int
+		connections int
+		expected    int
+	}{
+		{name: "single read", partitions: 1, expected: 1},
+		{name: "one per partition", partitions: 8, expected: 8},
+		{name: "limited", partitions: 8, connections: 4, expected: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &MigrateFromPGCmd{Partitions: tt.partitions, Connections: tt.connections}
+			if got := r.maxConnections(); got != tt.expected {
+				t.Errorf("maxConnections() got = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_partitionOffsetKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		before [2]int64
+		after  [2]int64
+	}{
+		{name: "max shrinks", before: [2]int64{1, 100}, after: [2]int64{1, 60}},
+		{name: "max grows", before: [2]int64{1, 100}, after: [2]int64{1, 140}},
+		{name: "min moves", before: [2]int64{1, 100}, after: [2]int64{30, 100}},
+		{name: "unchanged", before: [2]int64{1, 100}, after: [2]int64{1, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkpointed := map[string]keyRange{}
+			for _, kr := range splitKeyRange(tt.before[0], tt.before[1], 4) {
+				checkpointed[partitionOffsetKey("items", kr)] = kr
+			}
+
+			// The checkpoint of a range of the interrupted run may only be resumed by a range with the same keys.
+			resumed := 0
+			for _, kr := range splitKeyRange(tt.after[0], tt.after[1], 4) {
+				previous, ok := checkpointed[partitionOffsetKey("items", kr)]
+				if !ok {
+					continue
+				}
+				if previous != kr {
+					t.Errorf("range %v resumes the checkpoint of range %v", kr, previous)
+				}
+				resumed++
+			}
+			if tt.before == tt.after && resumed != len(checkpointed) {
+				t.Errorf("resumed %d ranges, expected all %d", resumed, len(checkpointed))
+			}
+		})
+	}
+}
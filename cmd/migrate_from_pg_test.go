@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pgvector/pgvector-go"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_pgBitsToVector(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     pgtype.Bits
+		expected []float32
+	}{
+		{name: "full byte", bits: pgtype.Bits{Bytes: []byte{0b10100001}, Len: 8, Valid: true}, expected: []float32{1, 0, 1, 0, 0, 0, 0, 1}},
+		{name: "partial byte", bits: pgtype.Bits{Bytes: []byte{0b11000000}, Len: 3, Valid: true}, expected: []float32{1, 1, 0}},
+		{name: "several bytes", bits: pgtype.Bits{Bytes: []byte{0x00, 0x80}, Len: 9, Valid: true}, expected: []float32{0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		{name: "empty", bits: pgtype.Bits{Valid: true}, expected: []float32{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pgBitsToVector(tt.bits)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("pgBitsToVector() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPGRowToPointVectorTypes(t *testing.T) {
+	cmd := &MigrateFromPGCmd{PG: commons.PGConfig{KeyColumn: "id"}}
+	point := cmd.rowToPoint(map[string]any{
+		"id":        int64(1),
+		"embedding": pgvector.NewVector([]float32{1, 2}),
+		"half":      pgvector.NewHalfVector([]float32{0.5, 0.25}),
+		"sparse":    pgvector.NewSparseVectorFromMap(map[int32]float32{3: 1.5}, 10),
+		"binary":    pgtype.Bits{Bytes: []byte{0b01000000}, Len: 2, Valid: true},
+		"title":     "doc",
+	})
+
+	expected := map[string]*qdrant.Vector{
+		"embedding": qdrant.NewVector(1, 2),
+		"half":      qdrant.NewVector(0.5, 0.25),
+		"sparse":    qdrant.NewVectorSparse([]uint32{3}, []float32{1.5}),
+		"binary":    qdrant.NewVector(0, 1),
+	}
+	got := point.GetVectors().GetVectors().GetVectors()
+	if len(got) != len(expected) {
+		t.Fatalf("rowToPoint() got %d vectors, expected %d", len(got), len(expected))
+	}
+	for name, vector := range expected {
+		if !proto.Equal(got[name], vector) {
+			t.Errorf("rowToPoint() vector %q got = %v, expected %v", name, got[name], vector)
+		}
+	}
+	if _, ok := point.GetPayload()["title"]; !ok || len(point.GetPayload()) != 2 {
+		t.Errorf("rowToPoint() payload got = %v, expected the id and title", point.GetPayload())
+	}
+}
@@ -3,12 +3,17 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"maps"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/pinecone-io/go-pinecone/v3/pinecone"
 	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
 
 	"github.com/qdrant/go-client/qdrant"
 
@@ -22,6 +27,8 @@ type MigrateFromPineconeCmd struct {
 	IdField      string                  `prefix:"qdrant." help:"Field storing Pinecone IDs in Qdrant." default:"__id__"`
 	DenseVector  string                  `prefix:"qdrant." help:"Name of the dense vector in Qdrant" default:"dense_vector"`
 	SparseVector string                  `prefix:"qdrant." help:"Name of the sparse vector in Qdrant" default:"sparse_vector"`
+	Route        []commons.PineconeRoute `prefix:"pinecone." help:"Route a namespace to a collection and/or a payload tag, as namespace=collection[:tag]. Can be repeated, * routes the remaining namespaces." sep:"none"`
+	TagField     string                  `prefix:"pinecone." help:"Payload field storing the tags of --pinecone.route." default:"namespace"`
 
 	targetHost string
 	targetPort int
@@ -38,7 +45,37 @@ func (r *MigrateFromPineconeCmd) Parse() error {
 	return nil
 }
 
+// pineconeNamespace is a namespace of the source index and where its vectors are migrated to.
+type pineconeNamespace struct {
+	namespace  string
+	collection string
+	tag        string
+	offsetKey  string
+	count      uint64
+	// shared is set when other namespaces are migrated into the same collection.
+	shared bool
+}
+
+// pointID returns the ID of a Pinecone vector in Qdrant. Namespaces sharing a collection can have vectors with the
+// same ID, so their namespace is hashed into it too, and none of them overwrites another.
+func (ns pineconeNamespace) pointID(id string) *qdrant.PointId {
+	if !ns.shared {
+		return arbitraryIDToUUID(id)
+	}
+	return arbitraryIDToUUID(ns.namespace + "#" + id)
+}
+
 func (r *MigrateFromPineconeCmd) Validate() error {
+	if len(r.Route) > 0 && r.Pinecone.Namespace != "" {
+		return fmt.Errorf("--pinecone.namespace can't be combined with --pinecone.route")
+	}
+	routed := make(map[string]bool, len(r.Route))
+	for _, route := range r.Route {
+		if routed[route.Namespace] {
+			return fmt.Errorf("namespace '%s' is routed more than once", route.Namespace)
+		}
+		routed[route.Namespace] = true
+	}
 	return validateBatchSize(r.Migration.BatchSize)
 }
 
@@ -50,7 +87,7 @@ func (r *MigrateFromPineconeCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	sourceClient, sourceIndexConn, err := r.connectToPinecone()
@@ -58,87 +95,227 @@ func (r *MigrateFromPineconeCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to connect to Pinecone source: %w", err)
 	}
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
 
-	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	stats, err := sourceIndexConn.DescribeIndexStats(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+		return fmt.Errorf("failed to get index statistics: %w", err)
 	}
 
-	sourcePointCount, err := r.countPineconeVectors(ctx, sourceIndexConn)
+	namespaces, err := r.resolveNamespaces(stats)
 	if err != nil {
-		return fmt.Errorf("failed to count points in source: %w", err)
+		return err
 	}
 
-	err = r.prepareTargetCollection(ctx, sourceClient, targetClient)
-	if err != nil {
-		return fmt.Errorf("error preparing target collection: %w", err)
+	collections := make([]string, 0, len(namespaces))
+	collectionCounts := make(map[string]uint64, len(namespaces))
+	for _, ns := range namespaces {
+		if _, ok := collectionCounts[ns.collection]; !ok {
+			collections = append(collections, ns.collection)
+		}
+		collectionCounts[ns.collection] += ns.count
 	}
 
-	displayMigrationStart("pinecone", r.Pinecone.IndexHost, r.Qdrant.Collection)
+	for _, collection := range collections {
+		err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, collection, r.Migration, false)
+		if err != nil {
+			return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+		}
+	}
 
-	err = r.migrateData(ctx, sourceIndexConn, targetClient, sourcePointCount)
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
-		return fmt.Errorf("failed to migrate data: %w", err)
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
 	}
 
-	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
-		CollectionName: r.Qdrant.Collection,
-		Exact:          qdrant.PtrOf(true),
-	})
-	if err != nil {
-		return fmt.Errorf("failed to count points in target: %w", err)
+	for _, collection := range collections {
+		err = r.prepareTargetCollection(ctx, sourceClient, targetClient, collection)
+		if err != nil {
+			return fmt.Errorf("error preparing target collection: %w", err)
+		}
+	}
+
+	for _, collection := range collections {
+		err = backupTargetCollection(ctx, targetClient, collection, r.Migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	displayMigrationStart("pinecone", r.Pinecone.IndexHost, strings.Join(collections, ", "))
+
+	for _, ns := range namespaces {
+		indexConn := sourceIndexConn
+		if len(r.Route) > 0 {
+			pterm.Info.Printfln("Migrating namespace '%s' into '%s'", ns.namespace, ns.collection)
+			indexConn, err = r.indexConnection(sourceClient, ns.namespace)
+			if err != nil {
+				return fmt.Errorf("failed to connect to Pinecone source: %w", err)
+			}
+		}
+
+		err = r.migrateData(ctx, indexConn, targetClient, ns)
+		if err != nil {
+			return fmt.Errorf("failed to migrate data: %w", err)
+		}
+
+		if sampleComplete(r.Migration) {
+			break
+		}
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	sourcePointCount := uint64(0)
+	targetPointCount := uint64(0)
+	for _, collection := range collections {
+		if r.Migration.AsyncUpserts {
+			err = flushTarget(ctx, targetClient, collection, collectionCounts[collection])
+			if err != nil {
+				return err
+			}
+		}
+
+		err = createPayloadIndexes(ctx, targetClient, collection, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		err = waitForOptimizations(ctx, targetClient, collection, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		count, err := targetClient.Count(ctx, &qdrant.CountPoints{
+			CollectionName: collection,
+			Exact:          qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count points in target: %w", err)
+		}
+
+		if len(collections) > 1 {
+			pterm.Info.Printfln("Target collection '%s' has %d points\n", collection, count)
+		} else {
+			pterm.Info.Printfln("Target collection has %d points\n", count)
+		}
+		sourcePointCount += collectionCounts[collection]
+		targetPointCount += count
 	}
 
-	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
 
+// resolveNamespaces returns the namespaces to migrate. Without --pinecone.route it's only --pinecone.namespace,
+// migrated into --qdrant.collection. With routes, AllNamespaces expands to every namespace of the index without
+// a route of its own, and each namespace keeps its own offset, since Pinecone lists the vectors of one namespace at a time.
+func (r *MigrateFromPineconeCmd) resolveNamespaces(stats *pinecone.DescribeIndexStatsResponse) ([]pineconeNamespace, error) {
+	if len(r.Route) == 0 {
+		return []pineconeNamespace{{
+			namespace:  r.Pinecone.Namespace,
+			collection: r.Qdrant.Collection,
+			offsetKey:  r.Pinecone.IndexHost,
+			count:      uint64(stats.TotalVectorCount),
+		}}, nil
+	}
+
+	routed := make(map[string]bool, len(r.Route))
+	for _, route := range r.Route {
+		routed[route.Namespace] = true
+	}
+
+	var namespaces []pineconeNamespace
+	add := func(namespace string, route commons.PineconeRoute) {
+		ns := pineconeNamespace{
+			namespace:  namespace,
+			collection: route.Collection,
+			tag:        route.Tag,
+			offsetKey:  r.Pinecone.IndexHost + "#" + namespace,
+		}
+		if ns.collection == "" {
+			ns.collection = r.Qdrant.Collection
+		}
+		if summary, ok := stats.Namespaces[namespace]; ok && summary != nil {
+			ns.count = uint64(summary.VectorCount)
+		}
+		namespaces = append(namespaces, ns)
+	}
+
+	for _, route := range r.Route {
+		if route.Namespace == commons.AllNamespaces {
+			for _, namespace := range slices.Sorted(maps.Keys(stats.Namespaces)) {
+				if !routed[namespace] {
+					add(namespace, route)
+				}
+			}
+			continue
+		}
+		add(route.Namespace, route)
+	}
+
+	if len(namespaces) == 0 {
+		return nil, fmt.Errorf("no namespaces of the index match --pinecone.route")
+	}
+
+	perCollection := make(map[string]int, len(namespaces))
+	for _, ns := range namespaces {
+		perCollection[ns.collection]++
+	}
+	for i := range namespaces {
+		namespaces[i].shared = perCollection[namespaces[i].collection] > 1
+	}
+	return namespaces, nil
+}
+
 func (r *MigrateFromPineconeCmd) connectToPinecone() (*pinecone.Client, *pinecone.IndexConnection, error) {
 	client, err := pinecone.NewClient(pinecone.NewClientParams{
-		Host:   r.Pinecone.ServiceHost,
-		ApiKey: r.Pinecone.APIKey,
+		Host:       r.Pinecone.ServiceHost,
+		ApiKey:     r.Pinecone.APIKey,
+		RestClient: newRateLimitedClient(nil),
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create Pinecone client: %w", err)
 	}
 
-	indexConn, err := client.Index(pinecone.NewIndexConnParams{
-		Host:      r.Pinecone.IndexHost,
-		Namespace: r.Pinecone.Namespace,
-	})
+	indexConn, err := r.indexConnection(client, r.Pinecone.Namespace)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to connect to Pinecone index: %w", err)
+		return nil, nil, err
 	}
 
 	return client, indexConn, nil
 }
 
-func (r *MigrateFromPineconeCmd) countPineconeVectors(ctx context.Context, indexConn *pinecone.IndexConnection) (uint64, error) {
-	stats, err := indexConn.DescribeIndexStats(ctx)
+func (r *MigrateFromPineconeCmd) indexConnection(client *pinecone.Client, namespace string) (*pinecone.IndexConnection, error) {
+	indexConn, err := client.Index(pinecone.NewIndexConnParams{
+		Host:      r.Pinecone.IndexHost,
+		Namespace: namespace,
+	}, grpc.WithChainUnaryInterceptor(rateLimitRetryInterceptor(sourceRateLimitRetries)))
 	if err != nil {
-		return 0, fmt.Errorf("failed to get index statistics: %w", err)
+		return nil, fmt.Errorf("failed to connect to Pinecone index: %w", err)
 	}
-
-	return uint64(stats.TotalVectorCount), nil
+	return indexConn, nil
 }
 
-func (r *MigrateFromPineconeCmd) prepareTargetCollection(ctx context.Context, sourceClient *pinecone.Client, targetClient *qdrant.Client) error {
-	if !r.Migration.CreateCollection {
+func (r *MigrateFromPineconeCmd) prepareTargetCollection(ctx context.Context, sourceClient *pinecone.Client, targetClient *qdrant.Client, collection string) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
 		return nil
 	}
 
-	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
+	targetCollectionExists, err := targetClient.CollectionExists(ctx, collection)
 	if err != nil {
 		return fmt.Errorf("failed to check if collection exists: %w", err)
 	}
 
 	if targetCollectionExists {
-		pterm.Info.Printfln("Target collection '%s' already exists. Skipping creation.", r.Qdrant.Collection)
+		pterm.Info.Printfln("Target collection '%s' already exists. Skipping creation.", collection)
 		return nil
 	}
 
@@ -159,28 +336,22 @@ func (r *MigrateFromPineconeCmd) prepareTargetCollection(ctx context.Context, so
 		return fmt.Errorf("index %q not found in Pinecone", r.Pinecone.IndexName)
 	}
 
-	distanceMapping := map[pinecone.IndexMetric]qdrant.Distance{
-		pinecone.Cosine:     qdrant.Distance_Cosine,
-		pinecone.Euclidean:  qdrant.Distance_Euclid,
-		pinecone.Dotproduct: qdrant.Distance_Dot,
-	}
-
 	var createReq *qdrant.CreateCollection
 
 	switch foundIndex.VectorType {
 	case "dense":
 		createReq = &qdrant.CreateCollection{
-			CollectionName: r.Qdrant.Collection,
+			CollectionName: collection,
 			VectorsConfig: qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
 				r.DenseVector: {
 					Size:     uint64(*foundIndex.Dimension),
-					Distance: distanceMapping[foundIndex.Metric],
+					Distance: translateDistance("pinecone", string(foundIndex.Metric), r.DenseVector),
 				},
 			}),
 		}
 	case "sparse":
 		createReq = &qdrant.CreateCollection{
-			CollectionName: r.Qdrant.Collection,
+			CollectionName: collection,
 			SparseVectorsConfig: qdrant.NewSparseVectorsConfig(map[string]*qdrant.SparseVectorParams{
 				r.SparseVector: {},
 			}),
@@ -189,22 +360,34 @@ func (r *MigrateFromPineconeCmd) prepareTargetCollection(ctx context.Context, so
 		return fmt.Errorf("unsupported vector type: %s", foundIndex.VectorType)
 	}
 
+	createReq.ShardingMethod = tenantShardingMethod(r.Migration, nil)
+	applyTopology(createReq, r.Migration)
+
 	if err := targetClient.CreateCollection(ctx, createReq); err != nil {
 		return fmt.Errorf("failed to create target collection: %w", err)
 	}
 
-	pterm.Success.Printfln("Created target collection '%s'", r.Qdrant.Collection)
+	err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Created target collection '%s'", collection)
 	return nil
 }
 
-func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexConn *pinecone.IndexConnection, targetClient *qdrant.Client, sourcePointCount uint64) error {
+func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexConn *pinecone.IndexConnection, targetClient *qdrant.Client, ns pineconeNamespace) error {
 	batchSize := r.Migration.BatchSize
 
 	var offsetId *qdrant.PointId
 	offsetCount := uint64(0)
 
 	if !r.Migration.Restart {
-		id, offsetStored, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.Pinecone.IndexHost)
+		id, offsetStored, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, ns.offsetKey)
 		if err != nil {
 			return fmt.Errorf("failed to get start offset: %w", err)
 		}
@@ -212,10 +395,18 @@ func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexCon
 		offsetId = id
 	}
 
-	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(ns.count)).Start()
 	displayMigrationProgress(bar, offsetCount)
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		req := &pinecone.ListVectorsRequest{
 			Limit: qdrant.PtrOf(uint32(batchSize)),
 		}
@@ -251,7 +442,7 @@ func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexCon
 				// Ref: https://qdrant.tech/documentation/concepts/points/#point-ids
 				// So we create a deterministic UUID based on the original ID.
 				// A copy of the original ID is stored in the payload.
-				Id: arbitraryIDToUUID(id),
+				Id: ns.pointID(id),
 			}
 			vectorMap := make(map[string]*qdrant.Vector)
 
@@ -272,24 +463,39 @@ func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexCon
 				payload = qdrant.NewValueMap(vec.Metadata.AsMap())
 			}
 			payload[r.IdField] = qdrant.NewValueString(id)
+			if ns.tag != "" {
+				payload[r.TagField] = qdrant.NewValueString(ns.tag)
+			}
 			point.Payload = payload
 
 			targetPoints = append(targetPoints, point)
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
-			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
+			CollectionName: ns.collection,
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
 		if listRes.NextPaginationToken != nil {
 			offsetCount += uint64(len(targetPoints))
 			offsetId = qdrant.NewID(*listRes.NextPaginationToken)
-			err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.Pinecone.IndexHost, offsetId, offsetCount)
+			err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, ns.offsetKey, offsetId, offsetCount)
 			if err != nil {
 				return fmt.Errorf("failed to store offset: %w", err)
 			}
@@ -297,6 +503,10 @@ func (r *MigrateFromPineconeCmd) migrateData(ctx context.Context, sourceIndexCon
 
 		bar.Add(len(targetPoints))
 
+		if sampleComplete(r.Migration) {
+			break
+		}
+
 		if listRes.NextPaginationToken == nil {
 			break
 		}
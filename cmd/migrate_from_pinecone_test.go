@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pinecone-io/go-pinecone/v3/pinecone"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestResolvePineconeNamespaces(t *testing.T) {
+	stats := &pinecone.DescribeIndexStatsResponse{
+		TotalVectorCount: 60,
+		Namespaces: map[string]*pinecone.NamespaceSummary{
+			"products": {VectorCount: 10},
+			"reviews":  {VectorCount: 20},
+			"archive":  {VectorCount: 30},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		routes []commons.PineconeRoute
+		want   []pineconeNamespace
+	}{
+		{
+			name: "no routes",
+			want: []pineconeNamespace{{collection: "default", offsetKey: "host", count: 60}},
+		},
+		{
+			name: "split",
+			routes: []commons.PineconeRoute{
+				{Namespace: "products", Collection: "catalog"},
+				{Namespace: "reviews", Tag: "reviews"},
+			},
+			want: []pineconeNamespace{
+				{namespace: "products", collection: "catalog", offsetKey: "host#products", count: 10},
+				{namespace: "reviews", collection: "default", tag: "reviews", offsetKey: "host#reviews", count: 20},
+			},
+		},
+		{
+			name: "remaining namespaces",
+			routes: []commons.PineconeRoute{
+				{Namespace: commons.AllNamespaces, Collection: "merged", Tag: "other"},
+				{Namespace: "products", Collection: "catalog"},
+			},
+			want: []pineconeNamespace{
+				{namespace: "archive", collection: "merged", tag: "other", offsetKey: "host#archive", count: 30, shared: true},
+				{namespace: "reviews", collection: "merged", tag: "other", offsetKey: "host#reviews", count: 20, shared: true},
+				{namespace: "products", collection: "catalog", offsetKey: "host#products", count: 10},
+			},
+		},
+		{
+			name:   "unknown namespace",
+			routes: []commons.PineconeRoute{{Namespace: "missing", Collection: "catalog"}},
+			want:   []pineconeNamespace{{namespace: "missing", collection: "catalog", offsetKey: "host#missing"}},
+		},
+		{
+			name:   "all namespaces",
+			routes: []commons.PineconeRoute{{Namespace: commons.AllNamespaces, Collection: "merged"}},
+			want: []pineconeNamespace{
+				{namespace: "archive", collection: "merged", offsetKey: "host#archive", count: 30, shared: true},
+				{namespace: "products", collection: "merged", offsetKey: "host#products", count: 10, shared: true},
+				{namespace: "reviews", collection: "merged", offsetKey: "host#reviews", count: 20, shared: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &MigrateFromPineconeCmd{
+				Pinecone: commons.PineconeConfig{IndexHost: "host"},
+				Qdrant:   commons.QdrantConfig{Collection: "default"},
+				Route:    tt.routes,
+			}
+			got, err := cmd.resolveNamespaces(stats)
+			if err != nil {
+				t.Fatalf("resolveNamespaces() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveNamespaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePineconeNamespacesEmpty(t *testing.T) {
+	cmd := &MigrateFromPineconeCmd{Route: []commons.PineconeRoute{{Namespace: commons.AllNamespaces}}}
+	if _, err := cmd.resolveNamespaces(&pinecone.DescribeIndexStatsResponse{}); err == nil {
+		t.Error("resolveNamespaces() without namespaces to migrate should fail")
+	}
+}
+
+func TestPineconeNamespacePointID(t *testing.T) {
+	products := pineconeNamespace{namespace: "products", collection: "merged", shared: true}
+	reviews := pineconeNamespace{namespace: "reviews", collection: "merged", shared: true}
+	if products.pointID("doc-1").String() == reviews.pointID("doc-1").String() {
+		t.Error("vectors with the same ID in namespaces sharing a collection got the same point ID")
+	}
+
+	// A namespace with a collection of its own keeps the IDs earlier migrations gave its vectors.
+	single := pineconeNamespace{namespace: "products", collection: "catalog"}
+	if got, want := single.pointID("doc-1"), arbitraryIDToUUID("doc-1"); got.String() != want.String() {
+		t.Errorf("pointID() = %v, want %v", got, want)
+	}
+}
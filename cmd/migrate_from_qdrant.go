@@ -3,11 +3,17 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/pterm/pterm"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/qdrant/go-client/qdrant"
 
@@ -18,15 +24,51 @@ type MigrateFromQdrantCmd struct {
 	Source               commons.QdrantConfig    `embed:"" prefix:"source."`
 	Target               commons.QdrantConfig    `embed:"" prefix:"target."`
 	Migration            commons.MigrationConfig `embed:"" prefix:"migration."`
-	MaxMessageSize       int                     `help:"Maximum gRPC message size in bytes (default: 33554432 = 32MB)" default:"33554432" prefix:"source."`
 	EnsurePayloadIndexes bool                    `help:"Ensure payload indexes are created" default:"true" prefix:"target."`
+	ExtraUrls            []string                `help:"Additional Qdrant gRPC URLs to write the same data to, e.g. clusters in other regions." prefix:"target."`
+	ExtraAPIKeys         []string                `help:"API keys for the additional targets, in the same order as the URLs. A single key is used for all of them. Defaults to the target API key." prefix:"target."`
+	Strategy             string                  `help:"How to copy the data. 'scroll' reads and writes points in batches, 'snapshot' restores a snapshot of the source collection on the target." enum:"scroll,snapshot" default:"scroll"`
+	SourceRestUrl        string                  `name:"rest-url" help:"Source REST URL, used by the snapshot strategy. Defaults to the gRPC URL with port 6334 replaced by 6333." prefix:"source."`
+	TargetRestUrl        string                  `name:"rest-url" help:"Target REST URL, used by the snapshot strategy. Defaults to the gRPC URL with port 6334 replaced by 6333." prefix:"target."`
+	ExtraRestUrls        []string                `help:"REST URLs of the additional targets, in the same order as their gRPC URLs, used by the snapshot strategy. Default to each gRPC URL with port 6334 replaced by 6333." prefix:"target."`
+	ParallelShards       bool                    `help:"Scroll every shard key of the source collection in parallel, with a checkpoint per shard key. Only for collections with custom sharding, others fail, and the shard keys must exist in the target." prefix:"source."`
+	SnapshotBaseUrl      string                  `help:"Base URL the targets can download source snapshots from, e.g. shared object storage. The snapshot file name is appended. By default, snapshots are streamed through this tool." prefix:"source."`
+	StagingDir           string                  `help:"Directory to stage batches in between reading and writing them. Staged batches survive restarts, so a slow or unstable target doesn't require reading the source again." prefix:"migration."`
+	StagingMaxSize       commons.ByteSize        `help:"Limit of the bytes staged on disk per stream, e.g. 10GB. Reading waits for the target when it's reached. 0 disables the limit." default:"1GB" prefix:"migration."`
+	AutoTune             bool                    `help:"Try a few batch sizes and writer concurrencies on the first batches, then use the fastest for the rest of the migration." prefix:"migration."`
+	Reconcile            bool                    `help:"After the migration, compare the point counts of source and target range by range of IDs, and copy the ranges with missing points again." prefix:"migration."`
+	HashField            string                  `help:"Payload field to store a hash of the vectors and payload of every point in, on the target." prefix:"migration."`
+	VerifyHashes         bool                    `help:"After the migration, compare the hash of every source point with the one stored in the target. Requires --migration.hash-field." prefix:"migration."`
+	IdsFile              string                  `help:"File with the IDs of the points to migrate, one per line, or - to read them from stdin. Only these points are copied, e.g. to repair points that are missing or differ in the target." prefix:"migration."`
+	SchemaOnly           bool                    `help:"Only create the target collection with the configuration, payload indexes, shard keys and aliases of the source, without copying any points, e.g. to provision it ahead of a scheduled migration." prefix:"migration."`
+	SkipPreflight        bool                    `help:"Skip the checks of versions, API key permissions, collection features and disk size before the migration." prefix:"migration."`
+
+	sourceHost   string
+	sourcePort   int
+	sourceTLS    bool
+	targetHost   string
+	targetPort   int
+	targetTLS    bool
+	extraTargets []qdrantEndpoint
+	ids          []*qdrant.PointId
+}
+
+// qdrantEndpoint is a parsed Qdrant URL together with the API key to use for it.
+type qdrantEndpoint struct {
+	url     string
+	restUrl string
+	host    string
+	port    int
+	tls     bool
+	apiKey  string
+}
 
-	sourceHost string
-	sourcePort int
-	sourceTLS  bool
-	targetHost string
-	targetPort int
-	targetTLS  bool
+// config returns the connection settings of the endpoint, based on the ones of the main target.
+func (e qdrantEndpoint) config(target commons.QdrantConfig) commons.QdrantConfig {
+	target.Url = e.url
+	target.APIKey = e.apiKey
+	target.Endpoints = nil
+	return target
 }
 
 func (r *MigrateFromQdrantCmd) Parse() error {
@@ -41,10 +83,90 @@ func (r *MigrateFromQdrantCmd) Parse() error {
 		return fmt.Errorf("failed to parse target URL: %w", err)
 	}
 
+	r.extraTargets = make([]qdrantEndpoint, 0, len(r.ExtraUrls))
+	for i, extraUrl := range r.ExtraUrls {
+		endpoint := qdrantEndpoint{url: extraUrl, apiKey: r.Target.APIKey}
+		endpoint.host, endpoint.port, endpoint.tls, err = parseQdrantUrl(extraUrl)
+		if err != nil {
+			return fmt.Errorf("failed to parse extra target URL %q: %w", extraUrl, err)
+		}
+		if len(r.ExtraRestUrls) > 0 {
+			endpoint.restUrl = r.ExtraRestUrls[i]
+		}
+
+		switch len(r.ExtraAPIKeys) {
+		case 0:
+		case 1:
+			endpoint.apiKey = r.ExtraAPIKeys[0]
+		default:
+			endpoint.apiKey = r.ExtraAPIKeys[i]
+		}
+
+		r.extraTargets = append(r.extraTargets, endpoint)
+	}
+
+	if r.IdsFile != "" {
+		r.ids, err = readPointIDs(r.IdsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read point IDs: %w", err)
+		}
+	}
+
 	return nil
 }
 
 func (r *MigrateFromQdrantCmd) Validate() error {
+	if len(r.ExtraAPIKeys) > 1 && len(r.ExtraAPIKeys) != len(r.ExtraUrls) {
+		return fmt.Errorf("expected 1 or %d extra API keys, got %d", len(r.ExtraUrls), len(r.ExtraAPIKeys))
+	}
+	if len(r.ExtraRestUrls) > 0 && len(r.ExtraRestUrls) != len(r.ExtraUrls) {
+		return fmt.Errorf("expected %d extra REST URLs, got %d", len(r.ExtraUrls), len(r.ExtraRestUrls))
+	}
+	if r.VerifyHashes && r.HashField == "" {
+		return fmt.Errorf("verifying hashes requires --migration.hash-field")
+	}
+	if r.VerifyHashes && (r.Migration.PayloadOnly || r.Migration.VectorsOnly) {
+		return fmt.Errorf("--migration.verify-hashes can't be combined with --migration.payload-only or --migration.vectors-only, which don't write whole points")
+	}
+	if r.VerifyHashes && r.Migration.Limit > 0 {
+		return fmt.Errorf("--migration.limit can't be combined with --migration.verify-hashes, which would report the points beyond the limit as missing")
+	}
+	if r.Migration.PayloadOnly && r.Migration.VectorsOnly {
+		return fmt.Errorf("--migration.payload-only and --migration.vectors-only can't be combined, leave out both to write whole points")
+	}
+	if r.Migration.VectorsOnly && r.Strategy == "snapshot" {
+		return fmt.Errorf("--migration.vectors-only can't be combined with the snapshot strategy, which replaces the whole collection")
+	}
+	if r.Migration.VectorsOnly && r.Migration.SkipExisting {
+		return fmt.Errorf("--migration.vectors-only can't be combined with --migration.skip-existing, which skips the points whose vectors it updates")
+	}
+	if r.Migration.PayloadOnly && r.Strategy == "snapshot" {
+		return fmt.Errorf("--migration.payload-only can't be combined with the snapshot strategy, which replaces the whole collection")
+	}
+	if r.Migration.PayloadOnly && r.Migration.SkipExisting {
+		return fmt.Errorf("--migration.payload-only can't be combined with --migration.skip-existing, which skips the points whose payloads it updates")
+	}
+	if r.IdsFile != "" && r.Strategy == "snapshot" {
+		return fmt.Errorf("--migration.ids-file can't be combined with the snapshot strategy, which copies the whole collection")
+	}
+	if r.IdsFile != "" && (r.ParallelShards || r.Reconcile || r.VerifyHashes) {
+		return fmt.Errorf("--migration.ids-file can't be combined with --source.parallel-shards, --migration.reconcile or --migration.verify-hashes, which go through the whole collection")
+	}
+	if r.Reconcile && r.Migration.Limit > 0 {
+		return fmt.Errorf("--migration.limit can't be combined with --migration.reconcile, which would copy the points beyond the limit as missing ones")
+	}
+	if r.SchemaOnly && (r.Strategy == "snapshot" || r.IdsFile != "" || r.Reconcile || r.VerifyHashes) {
+		return fmt.Errorf("--migration.schema-only copies no points, so it can't be combined with the snapshot strategy, --migration.ids-file, --migration.reconcile or --migration.verify-hashes")
+	}
+	if r.Strategy == "snapshot" && (r.Migration.ShardNumber > 0 || r.Migration.ReplicationFactor > 0) {
+		return fmt.Errorf("--migration.shard-number and --migration.replication-factor can't be combined with the snapshot strategy, which restores the collection as the source has it")
+	}
+	if isSinkTarget(r.Migration) && (r.Strategy == "snapshot" || len(r.ExtraUrls) > 0 || r.Reconcile || r.VerifyHashes || r.Migration.SkipExisting || r.SchemaOnly) {
+		return fmt.Errorf("--migration.target=%s can't be combined with the snapshot strategy, --target.extra-urls, --migration.reconcile, --migration.verify-hashes, --migration.skip-existing or --migration.schema-only, which need the target in Qdrant", r.Migration.Target)
+	}
+	if r.AutoTune && r.StagingDir != "" {
+		return fmt.Errorf("auto-tune can't be combined with staging, since staged batches are written independently of reading")
+	}
 	return validateBatchSize(r.Migration.BatchSize)
 }
 
@@ -53,6 +175,15 @@ func (r *MigrateFromQdrantCmd) ValidateParsedValues() error {
 		return fmt.Errorf("source and target collections must be different")
 	}
 
+	for _, extra := range r.extraTargets {
+		if extra.host == r.sourceHost && extra.port == r.sourcePort && r.Source.Collection == r.Target.Collection {
+			return fmt.Errorf("extra target %q must not be the source", extra.url)
+		}
+		if extra.host == r.targetHost && extra.port == r.targetPort {
+			return fmt.Errorf("extra target %q must be different from the target", extra.url)
+		}
+	}
+
 	return nil
 }
 
@@ -68,57 +199,290 @@ func (r *MigrateFromQdrantCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to validate input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Source.APIKey, r.sourceTLS, r.MaxMessageSize)
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Source, r.sourceTLS)
 	if err != nil {
 		return fmt.Errorf("failed to connect to source: %w", err)
 	}
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Target.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Target, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to target: %w", err)
 	}
 
+	err = checkSourceVersion(ctx, sourceClient, r.sourceRequirements()...)
+	if err != nil {
+		return err
+	}
+
+	// All targets receive every batch. The offset is only tracked in the main target,
+	// and stored once all of them acknowledged the batch.
+	targetClients := []*qdrant.Client{targetClient}
+	for _, extra := range r.extraTargets {
+		extraClient, err := connectToQdrant(globals, extra.host, extra.port, extra.config(r.Target), extra.tls)
+		if err != nil {
+			return fmt.Errorf("failed to connect to extra target %q: %w", extra.url, err)
+		}
+		targetClients = append(targetClients, extraClient)
+	}
+
+	if !r.SkipPreflight && !isSinkTarget(r.Migration) {
+		err = r.preflight(ctx, globals, sourceClient, targetClients)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Strategy == "snapshot" {
+		return r.migrateViaSnapshot(ctx, globals, sourceClient, targetClients)
+	}
+
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
 	if err != nil {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
 	}
 
+	if r.SchemaOnly {
+		return r.migrateSchema(ctx, sourceClient, targetClients)
+	}
+
 	sourcePointCount, err := sourceClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Source.Collection,
+		Filter:         tenantFilter(r.Migration),
 		Exact:          qdrant.PtrOf(true),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to count points in source: %w", err)
 	}
 
-	err = r.perpareTargetCollection(ctx, sourceClient, r.Source.Collection, targetClient, r.Target.Collection)
-	if err != nil {
-		return fmt.Errorf("error preparing target collection: %w", err)
+	for _, client := range targetClients {
+		err = r.perpareTargetCollection(ctx, sourceClient, r.Source.Collection, client, r.Target.Collection)
+		if err != nil {
+			return fmt.Errorf("error preparing target collection: %w", err)
+		}
+		err = backupTargetCollection(ctx, client, r.Target.Collection, r.Migration)
+		if err != nil {
+			return err
+		}
 	}
 
 	displayMigrationStart("qdrant", r.Source.Collection, r.Target.Collection)
+	for _, extra := range r.extraTargets {
+		pterm.Info.Printfln("Also writing to %s", extra.url)
+	}
 
-	err = r.migrateData(ctx, sourceClient, r.Source.Collection, targetClient, r.Target.Collection, sourcePointCount)
+	err = r.migrateData(ctx, sourceClient, r.Source.Collection, targetClients, r.Target.Collection, sourcePointCount)
 	if err != nil {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
-	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
-		CollectionName: r.Target.Collection,
-		Exact:          qdrant.PtrOf(true),
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		for _, client := range targetClients {
+			err = flushTarget(ctx, client, r.Target.Collection, sourcePointCount)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, client := range targetClients {
+		err = createPayloadIndexes(ctx, client, r.Target.Collection, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		err = waitForOptimizations(ctx, client, r.Target.Collection, r.Migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Reconcile {
+		for i, client := range targetClients {
+			err = r.reconcile(ctx, sourceClient, client, i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.VerifyHashes {
+		for i, client := range targetClients {
+			err = r.verifyHashes(ctx, sourceClient, client, i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, client := range targetClients {
+		targetPointCount, err := client.Count(ctx, &qdrant.CountPoints{
+			CollectionName: r.Target.Collection,
+			Exact:          qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count points in target: %w", err)
+		}
+
+		if i == 0 {
+			pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+			currentReport.setPointCounts(sourcePointCount, targetPointCount)
+		} else {
+			pterm.Info.Printfln("Target collection at %s has %d points\n", r.extraTargets[i-1].url, targetPointCount)
+		}
+	}
+
+	return nil
+}
+
+// targetUrl returns the URL of the main target for index 0, and the one of an extra target otherwise.
+func (r *MigrateFromQdrantCmd) targetUrl(targetIndex int) string {
+	if targetIndex > 0 {
+		return r.extraTargets[targetIndex-1].url
+	}
+	return r.Target.Url
+}
+
+func (r *MigrateFromQdrantCmd) verifyHashes(ctx context.Context, sourceClient *qdrant.Client, targetClient *qdrant.Client, targetIndex int) error {
+	target := r.targetUrl(targetIndex)
+	pterm.Info.Printfln("Verifying the hashes of the points in the target collection at %s", target)
+
+	mismatches, err := verifyHashes(ctx, sourceClient, r.Source.Collection, targetClient, r.Target.Collection, r.HashField, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to verify hashes: %w", err)
+	}
+	currentReport.addCheck(reportCheck{
+		Name:    "hashes",
+		Target:  target,
+		Passed:  mismatches == 0,
+		Details: fmt.Sprintf("%d points missing or different", mismatches),
 	})
+	if mismatches > 0 {
+		return fmt.Errorf("%d points are missing or differ in the target at %s", mismatches, target)
+	}
+
+	pterm.Success.Printfln("All points match the source")
+	return nil
+}
+
+func (r *MigrateFromQdrantCmd) reconcile(ctx context.Context, sourceClient *qdrant.Client, targetClient *qdrant.Client, targetIndex int) error {
+	target := r.targetUrl(targetIndex)
+	pterm.Info.Printfln("Reconciling target collection at %s with the source", target)
+
+	result, err := reconcileTarget(ctx, sourceClient, r.Source.Collection, targetClient, r.Target.Collection, r.HashField, r.writeConfig())
 	if err != nil {
-		return fmt.Errorf("failed to count points in target: %w", err)
+		return fmt.Errorf("failed to reconcile target: %w", err)
 	}
+	currentReport.addCheck(reportCheck{
+		Name:    "reconciliation",
+		Target:  target,
+		Passed:  result.unresolved == 0,
+		Details: fmt.Sprintf("%d of %d ID ranges copied again with %d points, %d unresolved", result.recopied, result.ranges, result.points, result.unresolved),
+	})
 
-	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	if result.recopied == 0 {
+		pterm.Success.Printfln("All %d ID ranges match", result.ranges)
+		return nil
+	}
+	pterm.Info.Printfln("Copied %d points of %d out of %d ID ranges again", result.points, result.recopied, result.ranges)
+	if result.unresolved > 0 {
+		return fmt.Errorf("%d ID ranges still miss points in the target at %s after copying them again", result.unresolved, target)
+	}
+
+	return nil
+}
+
+// migrateViaSnapshot replaces the target collections with a snapshot of the source collection.
+// Collection settings, payload indexes and points are all part of the snapshot, so no other preparation is needed.
+func (r *MigrateFromQdrantCmd) migrateViaSnapshot(ctx context.Context, globals *Globals, sourceClient *qdrant.Client, targetClients []*qdrant.Client) error {
+	for _, client := range targetClients {
+		err := backupTargetCollection(ctx, client, r.Target.Collection, r.Migration)
+		if err != nil {
+			return err
+		}
+	}
+
+	displayMigrationStart("qdrant", r.Source.Collection, r.Target.Collection)
+
+	sourceRestUrl, err := getQdrantRestUrl(ctx, r.SourceRestUrl, r.sourceHost, r.sourcePort, r.sourceTLS)
+	if err != nil {
+		return err
+	}
+	sourceRest, err := newQdrantRestClient(globals, sourceRestUrl, r.Source)
+	if err != nil {
+		return err
+	}
+
+	targetRestUrl, err := getQdrantRestUrl(ctx, r.TargetRestUrl, r.targetHost, r.targetPort, r.targetTLS)
+	if err != nil {
+		return err
+	}
+	targetRest, err := newQdrantRestClient(globals, targetRestUrl, r.Target)
+	if err != nil {
+		return err
+	}
+	targetRests := []*qdrantRestClient{targetRest}
+	for _, extra := range r.extraTargets {
+		extraRestUrl, err := getQdrantRestUrl(ctx, extra.restUrl, extra.host, extra.port, extra.tls)
+		if err != nil {
+			return err
+		}
+		extraRest, err := newQdrantRestClient(globals, extraRestUrl, extra.config(r.Target))
+		if err != nil {
+			return err
+		}
+		targetRests = append(targetRests, extraRest)
+	}
+
+	err = migrateViaSnapshot(ctx, sourceClient, sourceRest, r.Source.Collection, targetRests, r.Target.Collection, r.SnapshotBaseUrl)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	if r.Reconcile {
+		for i, client := range targetClients {
+			err = r.reconcile(ctx, sourceClient, client, i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.VerifyHashes {
+		for i, client := range targetClients {
+			err = r.verifyHashes(ctx, sourceClient, client, i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, client := range targetClients {
+		targetPointCount, err := client.Count(ctx, &qdrant.CountPoints{
+			CollectionName: r.Target.Collection,
+			Exact:          qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to count points in target: %w", err)
+		}
+
+		pterm.Info.Printfln("Target collection at %s has %d points\n", targetRests[i].baseUrl, targetPointCount)
+	}
 
 	return nil
 }
 
 func (r *MigrateFromQdrantCmd) perpareTargetCollection(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClient *qdrant.Client, targetCollection string) error {
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
 	sourceCollectionInfo, err := sourceClient.GetCollectionInfo(ctx, sourceCollection)
 	if err != nil {
 		return fmt.Errorf("failed to get source collection info: %w", err)
@@ -134,7 +498,7 @@ func (r *MigrateFromQdrantCmd) perpareTargetCollection(ctx context.Context, sour
 			fmt.Print("\n")
 			pterm.Info.Printfln("Target collection '%s' already exists. Skipping creation.", targetCollection)
 		} else {
-			err = targetClient.CreateCollection(ctx, &qdrant.CreateCollection{
+			request := &qdrant.CreateCollection{
 				CollectionName:         targetCollection,
 				HnswConfig:             sourceCollectionInfo.Config.GetHnswConfig(),
 				WalConfig:              sourceCollectionInfo.Config.GetWalConfig(),
@@ -145,13 +509,23 @@ func (r *MigrateFromQdrantCmd) perpareTargetCollection(ctx context.Context, sour
 				ReplicationFactor:      sourceCollectionInfo.Config.GetParams().ReplicationFactor,
 				WriteConsistencyFactor: sourceCollectionInfo.Config.GetParams().WriteConsistencyFactor,
 				QuantizationConfig:     sourceCollectionInfo.Config.GetQuantizationConfig(),
-				ShardingMethod:         sourceCollectionInfo.Config.GetParams().ShardingMethod,
+				ShardingMethod:         tenantShardingMethod(r.Migration, sourceCollectionInfo.Config.GetParams().ShardingMethod),
 				SparseVectorsConfig:    sourceCollectionInfo.Config.GetParams().SparseVectorsConfig,
 				StrictModeConfig:       sourceCollectionInfo.Config.GetStrictModeConfig(),
-			})
+			}
+			applyTopology(request, r.Migration)
+			err = targetClient.CreateCollection(ctx, request)
 			if err != nil {
 				return fmt.Errorf("failed to create target collection: %w", err)
 			}
+			err = recordCreatedCollection(ctx, targetClient, r.Migration.OffsetsCollection, targetCollection)
+			if err != nil {
+				return err
+			}
+			err = waitForCollectionReady(ctx, targetClient, targetCollection, r.Migration)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -213,119 +587,410 @@ func getFieldType(dataType qdrant.PayloadSchemaType) *qdrant.FieldType {
 	return nil
 }
 
-func (r *MigrateFromQdrantCmd) migrateData(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClient *qdrant.Client, targetCollection string, sourcePointCount uint64) error {
-	limit := uint32(r.Migration.BatchSize)
-
-	var offsetId *qdrant.PointId
-	offsetCount := uint64(0)
+func (r *MigrateFromQdrantCmd) migrateData(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClients []*qdrant.Client, targetCollection string, sourcePointCount uint64) error {
+	targetClient := targetClients[0]
 
-	if !r.Migration.Restart {
-		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, sourceCollection)
+	// Without shard keys, a single stream scrolls the whole collection.
+	shardKeys := []*qdrant.ShardKey{nil}
+	if r.ParallelShards {
+		keys, err := getShardKeys(ctx, sourceClient, sourceCollection)
 		if err != nil {
-			return fmt.Errorf("failed to get start offset: %w", err)
+			return err
+		}
+		// Scrolls can only be restricted to shard keys, not to the shards of automatic sharding.
+		if len(keys) == 0 {
+			return fmt.Errorf("--source.parallel-shards needs custom sharding, but source collection '%s' has no shard keys: leave out the flag to scroll it as a single stream", sourceCollection)
+		}
+		shardKeys = keys
+		pterm.Info.Printfln("Scrolling %d shard keys in parallel", len(keys))
+	}
+
+	streams := make([]*scrollStream, 0, len(shardKeys))
+	totalOffsetCount := uint64(0)
+	for _, shardKey := range shardKeys {
+		stream := &scrollStream{shardKey: shardKey, offsetKey: getShardOffsetKey(sourceCollection, shardKey)}
+		// Listed points are read again on every run, they don't move the offset of the collection.
+		if !r.Migration.Restart && r.ids == nil {
+			id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, stream.offsetKey)
+			if err != nil {
+				return fmt.Errorf("failed to get start offset: %w", err)
+			}
+			stream.offsetId = id
+			stream.offsetCount = count
 		}
-		offsetId = id
-		offsetCount = count
+		totalOffsetCount += stream.offsetCount
+		streams = append(streams, stream)
+	}
+
+	if r.ids != nil {
+		sourcePointCount = uint64(len(r.ids))
+		pterm.Info.Printfln("Migrating %d listed points", len(r.ids))
 	}
 
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
-	displayMigrationProgress(bar, offsetCount)
+	displayMigrationProgress(bar, totalOffsetCount)
 
-	for {
-		resp, err := sourceClient.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
-			CollectionName: sourceCollection,
-			Offset:         offsetId,
-			Limit:          &limit,
-			WithPayload:    qdrant.NewWithPayload(true),
-			WithVectors:    qdrant.NewWithVectors(true),
+	var barLock sync.Mutex
+	progress := func(n int) {
+		barLock.Lock()
+		defer barLock.Unlock()
+		bar.Add(n)
+	}
+
+	budget := newMemoryBudget(r.Migration.MaxMemory)
+	group, groupCtx := errgroup.WithContext(ctx)
+	for _, stream := range streams {
+		group.Go(func() error {
+			stream.memory = budget.reader()
+			defer stream.memory.release()
+			return r.migrateStream(groupCtx, sourceClient, sourceCollection, targetClients, targetCollection, stream, progress)
 		})
-		if err != nil {
-			return fmt.Errorf("failed to scroll date from source: %w", err)
+	}
+
+	err := group.Wait()
+	if err != nil {
+		return err
+	}
+
+	if r.Migration.SkipExisting {
+		skipped := uint64(0)
+		for _, stream := range streams {
+			skipped += stream.skipped.Load()
 		}
+		pterm.Info.Printfln("Skipped %d points that the target already had", skipped)
+		currentReport.addSkipped(skipped)
+	}
 
-		points := resp.GetResult()
-		offsetId = resp.GetNextPageOffset()
+	pterm.Success.Printfln("Data migration finished successfully")
 
-		var targetPoints []*qdrant.PointStruct
-		getVector := func(vector *qdrant.VectorOutput) *qdrant.Vector {
-			if vector == nil {
-				return nil
-			}
-			return &qdrant.Vector{
-				Data:         vector.GetData(),
-				Indices:      vector.GetIndices(),
-				VectorsCount: vector.VectorsCount,
-			}
+	return nil
+}
+
+// writeConfig is the configuration points are written with. With --migration.skip-existing, batches are checked
+// against all targets before they're written, by their hashes too, and reconciliation copies points the target misses,
+// so upsertPoints doesn't check them again.
+func (r *MigrateFromQdrantCmd) writeConfig() commons.MigrationConfig {
+	migration := r.Migration
+	migration.SkipExisting = false
+	return migration
+}
+
+// scrollStream is one independently checkpointed scroll over the source collection,
+// either over the whole collection or over a single shard key.
+type scrollStream struct {
+	shardKey    *qdrant.ShardKey
+	offsetKey   string
+	offsetId    *qdrant.PointId
+	offsetCount uint64
+	tuner       *autoTuner
+	memory      *memoryReader
+	skipped     atomic.Uint64
+}
+
+// getShardKeys returns the user-defined shard keys of a collection.
+// Collections with automatic sharding have none.
+func getShardKeys(ctx context.Context, client *qdrant.Client, collection string) ([]*qdrant.ShardKey, error) {
+	info, err := client.GetCollectionsClient().CollectionClusterInfo(ctx, &qdrant.CollectionClusterInfoRequest{
+		CollectionName: collection,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster info of '%s': %w", collection, err)
+	}
+
+	seen := make(map[string]bool)
+	var keys []*qdrant.ShardKey
+	add := func(key *qdrant.ShardKey) {
+		id := getShardOffsetKey("", key)
+		if key == nil || seen[id] {
+			return
 		}
-		getNamedVectors := func(vectors map[string]*qdrant.VectorOutput) map[string]*qdrant.Vector {
-			result := make(map[string]*qdrant.Vector, len(vectors))
-			for k, v := range vectors {
-				result[k] = getVector(v)
-			}
-			return result
+		seen[id] = true
+		keys = append(keys, key)
+	}
+	for _, shard := range info.GetLocalShards() {
+		add(shard.GetShardKey())
+	}
+	for _, shard := range info.GetRemoteShards() {
+		add(shard.GetShardKey())
+	}
+
+	return keys, nil
+}
+
+// getShardOffsetKey returns the key the offset of a stream is stored under.
+// The whole-collection stream keeps using the collection name, so existing offsets stay valid.
+func getShardOffsetKey(collection string, shardKey *qdrant.ShardKey) string {
+	switch key := shardKey.GetKey().(type) {
+	case *qdrant.ShardKey_Keyword:
+		return fmt.Sprintf("%s/shard_key=%s", collection, key.Keyword)
+	case *qdrant.ShardKey_Number:
+		return fmt.Sprintf("%s/shard_key=%d", collection, key.Number)
+	default:
+		return collection
+	}
+}
+
+func (r *MigrateFromQdrantCmd) migrateStream(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClients []*qdrant.Client, targetCollection string, stream *scrollStream, progress func(int)) error {
+	var shardKeySelector *qdrant.ShardKeySelector
+	if stream.shardKey != nil {
+		shardKeySelector = &qdrant.ShardKeySelector{ShardKeys: []*qdrant.ShardKey{stream.shardKey}}
+	}
+
+	upsert := func(ctx context.Context, targetPoints []*qdrant.PointStruct) error {
+		targetPoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
 		}
-		getVectors := func(vectors *qdrant.NamedVectorsOutput) *qdrant.NamedVectors {
-			if vectors == nil {
-				return nil
-			}
-			return &qdrant.NamedVectors{
-				Vectors: getNamedVectors(vectors.GetVectors()),
+
+		// Every target gets the same points, so only the ones written to the first are counted.
+		requests := make([]*qdrant.UpsertPoints, len(targetClients))
+		group, groupCtx := errgroup.WithContext(ctx)
+		for i, client := range targetClients {
+			requests[i] = &qdrant.UpsertPoints{
+				CollectionName:   targetCollection,
+				Points:           targetPoints,
+				Wait:             qdrant.PtrOf(!r.Migration.AsyncUpserts),
+				ShardKeySelector: shardKeySelector,
 			}
+			group.Go(func() error {
+				return upsertPoints(groupCtx, client, requests[i], r.writeConfig())
+			})
 		}
-		getVectorsFromPoint := func(point *qdrant.RetrievedPoint) *qdrant.Vectors {
-			if point.Vectors == nil {
-				return nil
-			}
-			if vector := point.Vectors.GetVector(); vector != nil {
-				return &qdrant.Vectors{
-					VectorsOptions: &qdrant.Vectors_Vector{
-						Vector: getVector(vector),
-					},
-				}
+
+		err = group.Wait()
+		if err != nil {
+			return fmt.Errorf("failed to insert data into target: %w", err)
+		}
+		currentReport.addWritten(len(requests[0].GetPoints()))
+		return nil
+	}
+
+	if r.Migration.SkipExisting {
+		upsertAll := upsert
+		upsert = func(ctx context.Context, targetPoints []*qdrant.PointStruct) error {
+			missing, err := filterExistingPoints(ctx, targetClients, targetCollection, targetPoints, r.HashField)
+			if err != nil {
+				return err
 			}
-			if vectors := point.Vectors.GetVectors(); vectors != nil {
-				return &qdrant.Vectors{
-					VectorsOptions: &qdrant.Vectors_Vectors{
-						Vectors: getVectors(vectors),
-					},
-				}
+			stream.skipped.Add(uint64(len(targetPoints) - len(missing)))
+			if len(missing) == 0 {
+				return nil
 			}
-			return nil
+			return upsertAll(ctx, missing)
 		}
-		for _, point := range points {
-			targetPoints = append(targetPoints, &qdrant.PointStruct{
-				Id:      point.Id,
-				Payload: point.Payload,
-				Vectors: getVectorsFromPoint(point),
-			})
+	}
+
+	if r.StagingDir == "" {
+		write := upsert
+		if r.AutoTune {
+			// Every stream is tuned on its own, so its setting applies to the batches it reads and writes in turn.
+			stream.tuner = newAutoTuner(r.Migration.BatchSize)
+			write = stream.tuner.write(upsert)
 		}
+		return r.readStream(ctx, sourceClient, sourceCollection, targetClients[0], stream, shardKeySelector, write, progress)
+	}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
-			CollectionName: targetCollection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+	// Batches are staged on disk and written by a separate writer, so the source offset only depends on the staging.
+	queue, err := openStagingQueue(filepath.Join(r.StagingDir, url.PathEscape(stream.offsetKey)), int64(r.StagingMaxSize))
+	if err != nil {
+		return err
+	}
+	if r.Migration.Restart {
+		err = queue.clear()
+		if err != nil {
+			return err
+		}
+	} else if pending := queue.pending(); pending > 0 {
+		pterm.Info.Printfln("Writing %d batch(es) staged by a previous run", pending)
+	}
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return queue.drain(groupCtx, upsert)
+	})
+	group.Go(func() error {
+		defer queue.close()
+		return r.readStream(groupCtx, sourceClient, sourceCollection, targetClients[0], stream, shardKeySelector, queue.push, progress)
+	})
+
+	return group.Wait()
+}
+
+// readStream reads a stream of the source collection in batches, hands every batch to write and then stores the offset after it.
+func (r *MigrateFromQdrantCmd) readStream(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClient *qdrant.Client, stream *scrollStream, shardKeySelector *qdrant.ShardKeySelector, write func(context.Context, []*qdrant.PointStruct) error, progress func(int)) error {
+	if r.ids != nil {
+		return r.readListedPoints(ctx, sourceClient, sourceCollection, stream, shardKeySelector, write, progress)
+	}
+
+	limit := uint32(r.Migration.BatchSize)
+	offsetId := stream.offsetId
+	offsetCount := stream.offsetCount
+	withPayload, withVectors := readSelectors(r.Migration, r.HashField)
+
+	for {
+		if stream.tuner != nil {
+			limit = uint32(stream.tuner.setting().batchSize)
+		}
 
+		err := stream.memory.reserve(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to insert data into target: %w", err)
+			return err
+		}
+
+		readStart := time.Now()
+		resp, err := scrollWithRetry(ctx, sourceClient, &qdrant.ScrollPoints{
+			CollectionName:   sourceCollection,
+			Filter:           tenantFilter(r.Migration),
+			Offset:           offsetId,
+			Limit:            &limit,
+			WithPayload:      withPayload,
+			WithVectors:      withVectors,
+			ShardKeySelector: shardKeySelector,
+		}, r.Migration.ScrollRetries)
+		if err != nil {
+			return fmt.Errorf("failed to scroll date from source: %w", err)
+		}
+		currentReport.observeRead(time.Since(readStart), len(resp.GetResult()))
+
+		points := resp.GetResult()
+		offsetId = resp.GetNextPageOffset()
+
+		targetPoints := retrievedToPointStructs(points)
+		err = stream.memory.fit(ctx, pointsSize(targetPoints))
+		if err != nil {
+			return err
+		}
+		if r.HashField != "" {
+			err = addPointHashes(targetPoints, r.HashField)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = write(ctx, targetPoints)
+		if err != nil {
+			return err
 		}
 
 		offsetCount += uint64(len(points))
 
-		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, sourceCollection, offsetId, offsetCount)
+		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, stream.offsetKey, offsetId, offsetCount)
 		if err != nil {
 			return fmt.Errorf("failed to store offset: %w", err)
 		}
 
-		bar.Add(len(points))
+		progress(len(points))
 
-		if offsetId == nil {
+		if offsetId == nil || sampleComplete(r.Migration) {
 			break
 		}
 
 	}
 
-	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+// readListedPoints reads the points of --migration.ids-file from the source in batches and hands every batch to write.
+// IDs the source doesn't have are reported once all batches are written.
+func (r *MigrateFromQdrantCmd) readListedPoints(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, stream *scrollStream, shardKeySelector *qdrant.ShardKeySelector, write func(context.Context, []*qdrant.PointStruct) error, progress func(int)) error {
+	missing := 0
+	withPayload, withVectors := readSelectors(r.Migration, r.HashField)
+	for start := 0; start < len(r.ids); start += r.Migration.BatchSize {
+		ids := r.ids[start:min(start+r.Migration.BatchSize, len(r.ids))]
+
+		err := stream.memory.reserve(ctx)
+		if err != nil {
+			return err
+		}
+
+		readStart := time.Now()
+		points, err := sourceClient.Get(ctx, &qdrant.GetPoints{
+			CollectionName:   sourceCollection,
+			Ids:              ids,
+			WithPayload:      withPayload,
+			WithVectors:      withVectors,
+			ShardKeySelector: shardKeySelector,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get points from source: %w", err)
+		}
+		currentReport.observeRead(time.Since(readStart), len(points))
+		missing += len(ids) - len(points)
+
+		targetPoints := retrievedToPointStructs(points)
+		err = stream.memory.fit(ctx, pointsSize(targetPoints))
+		if err != nil {
+			return err
+		}
+		if r.HashField != "" {
+			err = addPointHashes(targetPoints, r.HashField)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(targetPoints) > 0 {
+			err = write(ctx, targetPoints)
+			if err != nil {
+				return err
+			}
+		}
+
+		progress(len(ids))
+	}
+
+	if missing > 0 {
+		pterm.Warning.Printfln("%d of the listed points don't exist in the source and were skipped", missing)
+		currentReport.addSkipped(uint64(missing))
+	}
 
 	return nil
 }
+
+// retrievedToPointStructs converts points read from the source into points to upsert, keeping their IDs, vectors and payloads.
+func retrievedToPointStructs(points []*qdrant.RetrievedPoint) []*qdrant.PointStruct {
+	var targetPoints []*qdrant.PointStruct
+	getNamedVectors := func(vectors map[string]*qdrant.VectorOutput) map[string]*qdrant.Vector {
+		result := make(map[string]*qdrant.Vector, len(vectors))
+		for k, v := range vectors {
+			result[k] = vectorOutputToVector(v)
+		}
+		return result
+	}
+	getVectors := func(vectors *qdrant.NamedVectorsOutput) *qdrant.NamedVectors {
+		if vectors == nil {
+			return nil
+		}
+		return &qdrant.NamedVectors{
+			Vectors: getNamedVectors(vectors.GetVectors()),
+		}
+	}
+	getVectorsFromPoint := func(point *qdrant.RetrievedPoint) *qdrant.Vectors {
+		if point.Vectors == nil {
+			return nil
+		}
+		if vector := point.Vectors.GetVector(); vector != nil {
+			return &qdrant.Vectors{
+				VectorsOptions: &qdrant.Vectors_Vector{
+					Vector: vectorOutputToVector(vector),
+				},
+			}
+		}
+		if vectors := point.Vectors.GetVectors(); vectors != nil {
+			return &qdrant.Vectors{
+				VectorsOptions: &qdrant.Vectors_Vectors{
+					Vectors: getVectors(vectors),
+				},
+			}
+		}
+		return nil
+	}
+	for _, point := range points {
+		targetPoints = append(targetPoints, &qdrant.PointStruct{
+			Id:      point.Id,
+			Payload: point.Payload,
+			Vectors: getVectorsFromPoint(point),
+		})
+	}
+	return targetPoints
+}
@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/redis/go-redis/v9"
@@ -52,7 +53,7 @@ func (r *MigrateFromRedisCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	rdb := redis.NewClient(&redis.Options{
@@ -66,18 +67,25 @@ func (r *MigrateFromRedisCmd) Run(globals *Globals) error {
 	})
 	defer rdb.Close()
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
-	defer targetClient.Close()
+	if !isSinkTarget(r.Migration) {
+		defer targetClient.Close()
 
-	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
-	if err != nil {
-		return fmt.Errorf("failed to check if collection exists: %w", err)
+		targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
+		if err != nil {
+			return fmt.Errorf("failed to check if collection exists: %w", err)
+		}
+		if !targetCollectionExists {
+			return fmt.Errorf("target collection '%s' does not exist in Qdrant", r.Qdrant.Collection)
+		}
 	}
-	if !targetCollectionExists {
-		return fmt.Errorf("target collection '%s' does not exist in Qdrant", r.Qdrant.Collection)
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
 	}
 
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
@@ -85,6 +93,11 @@ func (r *MigrateFromRedisCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("redis", r.Redis.Index, r.Qdrant.Collection)
 
 	sourcePointCount, err := r.countRedisDocuments(ctx, rdb)
@@ -97,6 +110,28 @@ func (r *MigrateFromRedisCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -106,6 +141,7 @@ func (r *MigrateFromRedisCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
@@ -146,7 +182,15 @@ func (r *MigrateFromRedisCmd) migrateData(ctx context.Context, rdb *redis.Client
 		attrTypes[attr.Identifier] = attr.Type
 	}
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		res, err := rdb.FTSearchWithArgs(ctx, r.Redis.Index, "*", &redis.FTSearchOptions{
 			LimitOffset: int(currentOffset),
 			Limit:       int(batchSize),
@@ -191,15 +235,27 @@ func (r *MigrateFromRedisCmd) migrateData(ctx context.Context, rdb *redis.Client
 			targetPoints = append(targetPoints, point)
 		}
 
-		if len(targetPoints) > 0 {
-			_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		if len(writePoints) > 0 {
+			request := &qdrant.UpsertPoints{
 				CollectionName: r.Qdrant.Collection,
-				Points:         targetPoints,
-				Wait:           qdrant.PtrOf(true),
-			})
+				Points:         writePoints,
+				Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+			}
+			err = upsertPoints(ctx, targetClient, request, r.Migration)
 			if err != nil {
 				return fmt.Errorf("failed to insert data into target: %w", err)
 			}
+			currentReport.addWritten(len(request.GetPoints()))
 		}
 
 		currentOffset += uint64(count)
@@ -212,6 +268,10 @@ func (r *MigrateFromRedisCmd) migrateData(ctx context.Context, rdb *redis.Client
 		}
 
 		bar.Add(count)
+
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
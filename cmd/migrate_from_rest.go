@@ -0,0 +1,685 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateFromRestCmd struct {
+	Rest      commons.RestConfig      `embed:"" prefix:"rest."`
+	Qdrant    commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Migration commons.MigrationConfig `embed:"" prefix:"migration."`
+	IdField   string                  `prefix:"qdrant." help:"Field storing the IDs of the REST API in Qdrant." default:"__id__"`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+	paths      restPaths
+}
+
+// restPaths are the parsed JSONPaths of a REST spec.
+type restPaths struct {
+	items   []jsonPathStep
+	total   []jsonPathStep
+	id      []jsonPathStep
+	payload []jsonPathStep
+	cursor  []jsonPathStep
+	next    []jsonPathStep
+	vectors map[string][]jsonPathStep
+}
+
+func (r *MigrateFromRestCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	r.paths, err = parseRestPaths(r.Rest.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to parse REST spec %s: %w", r.Rest.Spec.Path, err)
+	}
+
+	return nil
+}
+
+func parseRestPaths(spec commons.RestSpec) (restPaths, error) {
+	paths := restPaths{vectors: make(map[string][]jsonPathStep, len(spec.Vectors))}
+	for _, field := range []struct {
+		path  string
+		steps *[]jsonPathStep
+	}{
+		{spec.Items, &paths.items},
+		{spec.Total, &paths.total},
+		{spec.ID, &paths.id},
+		{spec.Payload, &paths.payload},
+		{spec.Pagination.Cursor, &paths.cursor},
+		{spec.Pagination.Next, &paths.next},
+	} {
+		if field.path == "" {
+			continue
+		}
+		steps, err := parseJSONPath(field.path)
+		if err != nil {
+			return restPaths{}, err
+		}
+		*field.steps = steps
+	}
+	for name, vector := range spec.Vectors {
+		steps, err := parseJSONPath(vector.Path)
+		if err != nil {
+			return restPaths{}, err
+		}
+		paths.vectors[name] = steps
+	}
+	return paths, nil
+}
+
+func (r *MigrateFromRestCmd) Validate() error {
+	if r.Rest.MaxRetries < 0 {
+		return fmt.Errorf("max retries must not be negative")
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateFromRestCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("REST API to Qdrant Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("rest", r.offsetKey(), r.Qdrant.Collection)
+
+	sourcePointCount, err := r.migrateData(ctx, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	// Without a total in the responses, the number of source points isn't known.
+	if r.paths.total != nil {
+		currentReport.setPointCounts(sourcePointCount, targetPointCount)
+	}
+
+	return nil
+}
+
+// offsetKey is the key the checkpoint of the API is stored under: the URL of its pages without the query.
+func (r *MigrateFromRestCmd) offsetKey() string {
+	key, _, _ := strings.Cut(r.Rest.Spec.URL, "?")
+	return key
+}
+
+// migrateData reads the pages of the API until it has no more, and returns the total number of items if the responses have it.
+func (r *MigrateFromRestCmd) migrateData(ctx context.Context, targetClient *qdrant.Client) (uint64, error) {
+	httpClient := &http.Client{Timeout: r.Rest.Timeout}
+	if provider := newCredentialProvider(r.Rest.Spec.Auth); provider != nil {
+		httpClient.Transport = &credentialTransport{base: http.DefaultTransport, provider: provider}
+	}
+	pages := newPager(r.Rest.Spec.URL, r.Rest.Spec.Body, r.Rest.Spec.Pagination, r.paths, r.Migration.BatchSize)
+	offsetCount := uint64(0)
+
+	if !r.Migration.Restart {
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return 0, fmt.Errorf("failed to get start offset: %w", err)
+		}
+		if id != nil {
+			pages.resume(id)
+			offsetCount = count
+		}
+	}
+
+	var bar *pterm.ProgressbarPrinter
+	var sourcePointCount uint64
+	collectionReady := false
+
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
+	for !pages.done {
+		if err := memory.reserve(ctx); err != nil {
+			return 0, err
+		}
+
+		batchStart := time.Now()
+		requestUrl, body := pages.request()
+		response, err := fetchRestPage(ctx, httpClient, r.Rest.Spec, requestUrl, body, r.Rest.MaxRetries)
+		if err != nil {
+			return 0, err
+		}
+
+		if bar == nil {
+			if total, ok := evalJSONPath(response, r.paths.total); ok && r.paths.total != nil {
+				sourcePointCount = uint64(jsonNumber(total))
+			}
+			bar, _ = pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+			displayMigrationProgress(bar, offsetCount)
+		}
+
+		items, _ := evalJSONPath(response, r.paths.items)
+		list, ok := items.([]any)
+		if items != nil && !ok {
+			return 0, fmt.Errorf("items at %s of the response are not a list", r.Rest.Spec.Items)
+		}
+
+		targetPoints := make([]*qdrant.PointStruct, 0, len(list))
+		for _, item := range list {
+			point, err := restItemToPoint(item, r.paths, r.IdField)
+			if err != nil {
+				return 0, err
+			}
+			targetPoints = append(targetPoints, point)
+		}
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return 0, err
+		}
+
+		err = pages.advance(response, len(list), requestUrl)
+		if err != nil {
+			return 0, err
+		}
+		if len(targetPoints) == 0 {
+			continue
+		}
+
+		if !collectionReady {
+			err = r.prepareTargetCollection(ctx, targetClient, targetPoints)
+			if err != nil {
+				return 0, fmt.Errorf("error preparing target collection: %w", err)
+			}
+			collectionReady = true
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return 0, err
+		}
+
+		request := &qdrant.UpsertPoints{
+			CollectionName: r.Qdrant.Collection,
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert data into target: %w", err)
+		}
+		currentReport.addWritten(len(request.GetPoints()))
+
+		offsetCount += uint64(len(targetPoints))
+		if offsetId := pages.checkpoint(); offsetId != nil {
+			err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), offsetId, offsetCount)
+			if err != nil {
+				return 0, fmt.Errorf("failed to store offset: %w", err)
+			}
+		}
+
+		bar.Add(len(targetPoints))
+
+		if sampleComplete(r.Migration) {
+			break
+		}
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return sourcePointCount, nil
+}
+
+// prepareTargetCollection creates the target collection, if it doesn't exist, with the vectors of the first points read.
+func (r *MigrateFromRestCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client, points []*qdrant.PointStruct) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
+		return nil
+	}
+	return createCollectionFromPoints(ctx, targetClient, r.Qdrant.Collection, r.Rest.Spec.Vectors, points, r.Migration)
+}
+
+// createCollectionFromPoints creates a collection, if it doesn't exist, for the vectors of a spec.
+// Dense vectors get the size they have in the given points, and every vector the distance of the spec.
+// Runs into a sink create no collection.
+func createCollectionFromPoints(ctx context.Context, targetClient *qdrant.Client, collection string, vectors map[string]commons.RestVector, points []*qdrant.PointStruct, migration commons.MigrationConfig) error {
+	if isSinkTarget(migration) {
+		return nil
+	}
+	targetCollectionExists, err := targetClient.CollectionExists(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+
+	if targetCollectionExists {
+		pterm.Info.Printfln("Target collection %q already exists. Skipping creation.", collection)
+		return nil
+	}
+
+	distanceMapping := map[string]qdrant.Distance{
+		"euclid":    qdrant.Distance_Euclid,
+		"cosine":    qdrant.Distance_Cosine,
+		"dot":       qdrant.Distance_Dot,
+		"manhattan": qdrant.Distance_Manhattan,
+	}
+
+	dense := make(map[string]*qdrant.VectorParams)
+	sparse := make(map[string]*qdrant.SparseVectorParams)
+	for name, spec := range vectors {
+		for _, point := range points {
+			vector, ok := point.GetVectors().GetVectors().GetVectors()[name]
+			if !ok {
+				continue
+			}
+			if vector.GetIndices() != nil {
+				sparse[name] = &qdrant.SparseVectorParams{}
+			} else {
+				dense[name] = &qdrant.VectorParams{Size: uint64(len(vector.GetData())), Distance: distanceMapping[spec.Distance]}
+			}
+			break
+		}
+		if dense[name] == nil && sparse[name] == nil {
+			return fmt.Errorf("vector '%s' isn't in the first page of the API, so its size is unknown. Create collection '%s' first", name, collection)
+		}
+	}
+
+	request := &qdrant.CreateCollection{
+		CollectionName: collection,
+		VectorsConfig:  qdrant.NewVectorsConfigMap(dense),
+		ShardingMethod: tenantShardingMethod(migration, nil),
+	}
+	if len(sparse) > 0 {
+		request.SparseVectorsConfig = qdrant.NewSparseVectorsConfig(sparse)
+	}
+	applyTopology(request, migration)
+	err = targetClient.CreateCollection(ctx, request)
+	if err != nil {
+		return fmt.Errorf("failed to create target collection: %w", err)
+	}
+
+	err = recordCreatedCollection(ctx, targetClient, migration.OffsetsCollection, collection)
+	if err != nil {
+		return err
+	}
+	err = waitForCollectionReady(ctx, targetClient, collection, migration)
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Created target collection '%s'", collection)
+	return nil
+}
+
+// pager keeps track of the next page to request from an API, by the URL and body of its requests.
+type pager struct {
+	url        string
+	body       string
+	pagination commons.RestPagination
+	paths      restPaths
+	limit      int
+	offset     int
+	page       int
+	cursor     string
+	next       string
+	done       bool
+}
+
+func newPager(url, body string, pagination commons.RestPagination, paths restPaths, limit int) *pager {
+	return &pager{
+		url:        url,
+		body:       body,
+		pagination: pagination,
+		paths:      paths,
+		limit:      limit,
+		offset:     pagination.Start,
+		page:       pagination.Start,
+	}
+}
+
+// request returns the URL and body of the next page.
+func (p *pager) request() (string, string) {
+	if p.next != "" {
+		return p.next, ""
+	}
+
+	values := map[string]string{
+		"limit":  strconv.Itoa(p.limit),
+		"offset": strconv.Itoa(p.offset),
+		"page":   strconv.Itoa(p.page),
+		"cursor": p.cursor,
+	}
+	requestUrl, body := p.url, p.body
+	for name, value := range values {
+		requestUrl = strings.ReplaceAll(requestUrl, "{"+name+"}", url.QueryEscape(value))
+		// Values are inserted into JSON strings or numbers of the body, so they're escaped as the content of a JSON string.
+		escaped, _ := json.Marshal(value)
+		body = strings.ReplaceAll(body, "{"+name+"}", string(escaped[1:len(escaped)-1]))
+	}
+	return requestUrl, body
+}
+
+// advance moves on to the page after the one read from requestUrl, which had the given number of items.
+// The API has no more pages after an empty one, or one without a cursor or link to the next.
+func (p *pager) advance(response any, items int, requestUrl string) error {
+	if items == 0 {
+		p.done = true
+		return nil
+	}
+
+	switch p.pagination.Style {
+	case "offset":
+		p.offset += items
+	case "page":
+		p.page++
+	case "cursor":
+		cursor, ok := evalJSONPath(response, p.paths.cursor)
+		if !ok || cursor == nil || cursor == "" {
+			p.done = true
+			return nil
+		}
+		p.cursor = fmt.Sprint(cursor)
+		if number, isNumber := cursor.(json.Number); isNumber {
+			p.cursor = number.String()
+		}
+	case "link":
+		next, ok := evalJSONPath(response, p.paths.next)
+		link, isString := next.(string)
+		if !ok || !isString || link == "" {
+			p.done = true
+			return nil
+		}
+		base, err := url.Parse(requestUrl)
+		if err != nil {
+			return fmt.Errorf("failed to parse URL %q: %w", requestUrl, err)
+		}
+		resolved, err := base.Parse(link)
+		if err != nil {
+			return fmt.Errorf("failed to parse next page URL %q: %w", link, err)
+		}
+		p.next = resolved.String()
+	default:
+		p.done = true
+	}
+	return nil
+}
+
+// checkpoint returns the position of the next page to store as offset, or nil if there's none to resume from.
+// Offsets and pages are stored as numbers, cursors and links as strings, like the pagination tokens of Pinecone.
+func (p *pager) checkpoint() *qdrant.PointId {
+	if p.done {
+		return nil
+	}
+	switch p.pagination.Style {
+	case "offset":
+		return qdrant.NewIDNum(uint64(p.offset))
+	case "page":
+		return qdrant.NewIDNum(uint64(p.page))
+	case "cursor":
+		return qdrant.NewID(p.cursor)
+	case "link":
+		return qdrant.NewID(p.next)
+	default:
+		return nil
+	}
+}
+
+// resume continues from a stored checkpoint.
+func (p *pager) resume(id *qdrant.PointId) {
+	switch p.pagination.Style {
+	case "offset":
+		p.offset = int(id.GetNum())
+	case "page":
+		p.page = int(id.GetNum())
+	case "cursor":
+		p.cursor = id.GetUuid()
+	case "link":
+		p.next = id.GetUuid()
+	}
+}
+
+// fetchRestPage requests a page and decodes its JSON. Requests that failed with a network error, 429 or 5xx are retried.
+func fetchRestPage(ctx context.Context, client *http.Client, spec commons.RestSpec, requestUrl, body string, maxRetries int) (any, error) {
+	for attempt := 0; ; attempt++ {
+		response, retryable, delay, err := fetchRestPageOnce(ctx, client, spec, requestUrl, body)
+		if err == nil {
+			return response, nil
+		}
+		if !retryable || attempt >= maxRetries {
+			return nil, err
+		}
+		if delay == 0 {
+			delay = backoffDelay(attempt)
+		}
+		pterm.Warning.Printfln("%v, retrying in %s", err, delay)
+		currentReport.addRetry()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// fetchRestPageOnce sends one request. It reports whether a failed request may be retried, and after how long.
+func fetchRestPageOnce(ctx context.Context, client *http.Client, spec commons.RestSpec, requestUrl, body string) (any, bool, time.Duration, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = bytes.NewReader([]byte(body))
+	}
+	req, err := http.NewRequestWithContext(ctx, spec.Method, requestUrl, reader)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range spec.Headers {
+		req.Header.Set(name, value)
+	}
+	if body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, 0, fmt.Errorf("failed to call REST API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, 0, fmt.Errorf("failed to read REST API response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, parseRetryAfter(resp.Header.Get("Retry-After")),
+			fmt.Errorf("REST API returned %s for %s: %s", resp.Status, requestUrl, strings.TrimSpace(string(data)))
+	}
+
+	// Numbers are decoded as json.Number, so large integer IDs keep their precision.
+	var response any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&response)
+	if err != nil {
+		return nil, false, 0, fmt.Errorf("failed to decode REST API response: %w", err)
+	}
+	return response, false, 0, nil
+}
+
+// restItemToPoint converts an item of a response into a point. Unsigned integer IDs are used as they are,
+// other IDs are converted into UUIDs. The original ID is stored in idField of the payload.
+func restItemToPoint(item any, paths restPaths, idField string) (*qdrant.PointStruct, error) {
+	rawId, ok := evalJSONPath(item, paths.id)
+	if !ok || rawId == nil {
+		return nil, fmt.Errorf("item has no ID: %v", item)
+	}
+
+	var id *qdrant.PointId
+	var idString string
+	switch value := rawId.(type) {
+	case json.Number:
+		idString = value.String()
+		if num, err := strconv.ParseUint(idString, 10, 64); err == nil {
+			id = qdrant.NewIDNum(num)
+		} else {
+			id = arbitraryIDToUUID(idString)
+		}
+	case string:
+		idString = value
+		id = arbitraryIDToUUID(value)
+	default:
+		return nil, fmt.Errorf("unsupported ID type %T of item %v", rawId, rawId)
+	}
+
+	vectors := make(map[string]*qdrant.Vector, len(paths.vectors))
+	for name, steps := range paths.vectors {
+		value, ok := evalJSONPath(item, steps)
+		if !ok || value == nil {
+			continue
+		}
+		vector, err := restVector(value)
+		if err != nil {
+			return nil, fmt.Errorf("vector '%s' of item %s: %w", name, idString, err)
+		}
+		vectors[name] = vector
+	}
+
+	payload := make(map[string]any)
+	if paths.payload != nil {
+		value, _ := evalJSONPath(item, paths.payload)
+		if object, ok := value.(map[string]any); ok {
+			for key, field := range object {
+				payload[key] = field
+			}
+		}
+	} else if object, ok := item.(map[string]any); ok {
+		for key, field := range object {
+			payload[key] = field
+		}
+		// Vectors at the top level of the item aren't stored in the payload again.
+		for _, steps := range paths.vectors {
+			if len(steps) == 1 && steps[0].isKey {
+				delete(payload, steps[0].key)
+			}
+		}
+	}
+	payload[idField] = idString
+
+	return &qdrant.PointStruct{
+		Id:      id,
+		Vectors: qdrant.NewVectorsMap(vectors),
+		Payload: qdrant.NewValueMap(normalizeJSONMap(payload)),
+	}, nil
+}
+
+// restVector converts a list of numbers into a dense vector, and an object with indices and values into a sparse one.
+func restVector(value any) (*qdrant.Vector, error) {
+	switch v := value.(type) {
+	case []any:
+		data := make([]float32, len(v))
+		for i, element := range v {
+			number, ok := element.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of numbers, got %T at index %d", element, i)
+			}
+			data[i] = float32(jsonNumber(number))
+		}
+		return qdrant.NewVectorDense(data), nil
+	case map[string]any:
+		indices, okIndices := v["indices"].([]any)
+		values, okValues := v["values"].([]any)
+		if !okIndices || !okValues || len(indices) != len(values) {
+			return nil, fmt.Errorf("expected an object with lists of indices and values of the same length")
+		}
+		sparseIndices := make([]uint32, len(indices))
+		sparseValues := make([]float32, len(values))
+		for i := range indices {
+			sparseIndices[i] = uint32(jsonNumber(indices[i]))
+			sparseValues[i] = float32(jsonNumber(values[i]))
+		}
+		return qdrant.NewVectorSparse(sparseIndices, sparseValues), nil
+	default:
+		return nil, fmt.Errorf("expected a list of numbers or an object with indices and values, got %T", value)
+	}
+}
+
+// jsonNumber returns a decoded JSON number as float64, and 0 for anything else.
+func jsonNumber(value any) float64 {
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case float64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func decodeTestJSON(t *testing.T, data string) any {
+	t.Helper()
+	var document any
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		t.Fatalf("failed to decode %s: %v", data, err)
+	}
+	return document
+}
+
+func TestRestPager(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       commons.RestSpec
+		responses  []string
+		wantUrls   []string
+		wantOffset *qdrant.PointId
+	}{
+		{
+			name: "offset",
+			spec: commons.RestSpec{
+				URL:        "https://api.example.com/items?limit={limit}&offset={offset}",
+				Pagination: commons.RestPagination{Style: "offset"},
+			},
+			responses: []string{`{"items": [1, 2]}`, `{"items": [3]}`, `{"items": []}`},
+			wantUrls: []string{
+				"https://api.example.com/items?limit=2&offset=0",
+				"https://api.example.com/items?limit=2&offset=2",
+				"https://api.example.com/items?limit=2&offset=3",
+			},
+		},
+		{
+			name: "page",
+			spec: commons.RestSpec{
+				URL:        "https://api.example.com/items?page={page}",
+				Pagination: commons.RestPagination{Style: "page", Start: 1},
+			},
+			responses:  []string{`{"items": [1, 2]}`, `{"items": [3, 4]}`},
+			wantUrls:   []string{"https://api.example.com/items?page=1", "https://api.example.com/items?page=2"},
+			wantOffset: qdrant.NewIDNum(3),
+		},
+		{
+			name: "cursor",
+			spec: commons.RestSpec{
+				URL:        "https://api.example.com/items?after={cursor}",
+				Pagination: commons.RestPagination{Style: "cursor", Cursor: "$.meta.next"},
+			},
+			responses: []string{`{"items": [1], "meta": {"next": "a b"}}`, `{"items": [2], "meta": {"next": null}}`},
+			wantUrls:  []string{"https://api.example.com/items?after=", "https://api.example.com/items?after=a+b"},
+		},
+		{
+			name: "link",
+			spec: commons.RestSpec{
+				URL:        "https://api.example.com/v1/items",
+				Pagination: commons.RestPagination{Style: "link", Next: "$.links.next"},
+			},
+			responses:  []string{`{"items": [1], "links": {"next": "/v1/items?page=2"}}`},
+			wantUrls:   []string{"https://api.example.com/v1/items"},
+			wantOffset: qdrant.NewID("https://api.example.com/v1/items?page=2"),
+		},
+		{
+			name: "none",
+			spec: commons.RestSpec{
+				URL:        "https://api.example.com/items",
+				Pagination: commons.RestPagination{Style: "none"},
+			},
+			responses: []string{`{"items": [1, 2]}`},
+			wantUrls:  []string{"https://api.example.com/items"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := parseRestPaths(tt.spec)
+			if err != nil {
+				t.Fatalf("parseRestPaths() error = %v", err)
+			}
+			items, _ := parseJSONPath("$.items")
+			pages := newPager(tt.spec.URL, tt.spec.Body, tt.spec.Pagination, paths, 2)
+
+			var urls []string
+			for _, data := range tt.responses {
+				if pages.done {
+					t.Fatalf("pages are done after %v, want %d requests", urls, len(tt.responses))
+				}
+				requestUrl, _ := pages.request()
+				urls = append(urls, requestUrl)
+				response := decodeTestJSON(t, data)
+				list, _ := evalJSONPath(response, items)
+				if err := pages.advance(response, len(list.([]any)), requestUrl); err != nil {
+					t.Fatalf("advance() error = %v", err)
+				}
+			}
+
+			if strings.Join(urls, " ") != strings.Join(tt.wantUrls, " ") {
+				t.Errorf("requested %v, want %v", urls, tt.wantUrls)
+			}
+			if got := pages.checkpoint(); got.String() != tt.wantOffset.String() {
+				t.Errorf("checkpoint() = %v, want %v", got, tt.wantOffset)
+			}
+			if (tt.wantOffset == nil) != pages.done {
+				t.Errorf("done = %v, want %v", pages.done, tt.wantOffset == nil)
+			}
+		})
+	}
+}
+
+func TestRestPagerResume(t *testing.T) {
+	spec := commons.RestSpec{
+		URL:        "https://api.example.com/items?cursor={cursor}",
+		Body:       `{"cursor": "{cursor}"}`,
+		Pagination: commons.RestPagination{Style: "cursor", Cursor: "$.next"},
+	}
+	pages := newPager(spec.URL, spec.Body, spec.Pagination, restPaths{}, 10)
+	pages.resume(qdrant.NewID(`a"b`))
+
+	requestUrl, body := pages.request()
+	if requestUrl != "https://api.example.com/items?cursor=a%22b" {
+		t.Errorf("request() URL = %s", requestUrl)
+	}
+	if body != `{"cursor": "a\"b"}` {
+		t.Errorf("request() body = %s", body)
+	}
+}
+
+func TestRestItemToPoint(t *testing.T) {
+	spec := commons.RestSpec{
+		ID: "$.id",
+		Vectors: map[string]commons.RestVector{
+			"dense":  {Path: "$.embedding"},
+			"sparse": {Path: "$.sparse"},
+		},
+	}
+	paths, err := parseRestPaths(spec)
+	if err != nil {
+		t.Fatalf("parseRestPaths() error = %v", err)
+	}
+
+	item := decodeTestJSON(t, `{"id": 7, "embedding": [0.5, 1], "sparse": {"indices": [3], "values": [0.25]}, "title": "a", "views": 12}`)
+	point, err := restItemToPoint(item, paths, "__id__")
+	if err != nil {
+		t.Fatalf("restItemToPoint() error = %v", err)
+	}
+	if point.GetId().GetNum() != 7 {
+		t.Errorf("ID = %v, want 7", point.GetId())
+	}
+	vectors := point.GetVectors().GetVectors().GetVectors()
+	if data := vectors["dense"].GetData(); len(data) != 2 || data[1] != 1 {
+		t.Errorf("dense vector = %v, want [0.5 1]", data)
+	}
+	if indices := vectors["sparse"].GetIndices().GetData(); len(indices) != 1 || indices[0] != 3 {
+		t.Errorf("sparse indices = %v, want [3]", indices)
+	}
+	payload := point.GetPayload()
+	if _, ok := payload["embedding"]; ok {
+		t.Errorf("payload has the vector: %v", payload)
+	}
+	if payload["views"].GetIntegerValue() != 12 || payload["title"].GetStringValue() != "a" || payload["__id__"].GetStringValue() != "7" {
+		t.Errorf("payload = %v", payload)
+	}
+
+	item = decodeTestJSON(t, `{"id": "doc-1", "embedding": "oops"}`)
+	if _, err := restItemToPoint(item, paths, "__id__"); err == nil {
+		t.Errorf("restItemToPoint() with an invalid vector succeeded, want an error")
+	}
+
+	item = decodeTestJSON(t, `{"id": "doc-1"}`)
+	point, err = restItemToPoint(item, paths, "__id__")
+	if err != nil {
+		t.Fatalf("restItemToPoint() error = %v", err)
+	}
+	if point.GetId().GetUuid() != arbitraryIDToUUID("doc-1").GetUuid() {
+		t.Errorf("ID = %v, want the UUID of doc-1", point.GetId())
+	}
+	if len(point.GetVectors().GetVectors().GetVectors()) != 0 {
+		t.Errorf("vectors = %v, want none", point.GetVectors())
+	}
+}
@@ -0,0 +1,634 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	_ "github.com/ClickHouse/clickhouse-go/v2"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateFromSQLCmd struct {
+	SQL            commons.SQLConfig       `embed:"" prefix:"sql."`
+	Qdrant         commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
+	IdField        string                  `prefix:"qdrant." help:"Field storing IDs that aren't UUIDs or non-negative integers in Qdrant." default:"__id__"`
+	DistanceMetric map[string]string       `prefix:"qdrant." help:"Map of vector columns to distance metrics (cosine,dot,euclid,manhattan) to create the collection with. Default is cosine if not specified."`
+
+	driver          string
+	dsn             string
+	query           string
+	collectionReady bool
+	targetHost      string
+	targetPort      int
+	targetTLS       bool
+}
+
+func (r *MigrateFromSQLCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	r.driver, r.dsn, err = sqlDriver(r.SQL.Url)
+	if err != nil {
+		return err
+	}
+	// The query is wrapped to count its rows, where a trailing semicolon would end the statement early.
+	r.query = strings.TrimRight(strings.TrimSpace(r.SQL.Query), "; \t\n")
+
+	return nil
+}
+
+func (r *MigrateFromSQLCmd) Validate() error {
+	if _, _, err := sqlDriver(r.SQL.Url); err != nil {
+		return err
+	}
+	if slices.Contains(r.SQL.VectorColumns, r.SQL.IdColumn) {
+		return fmt.Errorf("column '%s' can't be both the ID and a vector", r.SQL.IdColumn)
+	}
+	for name, distance := range r.DistanceMetric {
+		switch distance {
+		case "cosine", "dot", "euclid", "manhattan":
+		default:
+			return fmt.Errorf("invalid distance metric '%s' for vector '%s'", distance, name)
+		}
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+// sqlDriver returns the database/sql driver and data source name of a connection URL, by its scheme.
+// MySQL takes the DSN of its driver after the scheme, the other databases the URL itself.
+func sqlDriver(url string) (string, string, error) {
+	scheme, rest, _ := strings.Cut(url, "://")
+	switch scheme {
+	case "postgres", "postgresql":
+		return "pgx", url, nil
+	case "mysql":
+		return "mysql", rest, nil
+	case "clickhouse":
+		return "clickhouse", url, nil
+	default:
+		return "", "", fmt.Errorf("unsupported database URL %q, expected postgres://, mysql:// or clickhouse://", url)
+	}
+}
+
+func (r *MigrateFromSQLCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("SQL to Qdrant Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceDB, err := connectToSQL(ctx, r.driver, r.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SQL source: %w", err)
+	}
+	defer sourceDB.Close()
+
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	sourcePointCount, err := r.countSQLRows(ctx, sourceDB)
+	if err != nil {
+		return fmt.Errorf("failed to count rows in source: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("sql", r.driver, r.Qdrant.Collection)
+
+	err = r.migrateData(ctx, sourceDB, targetClient, sourcePointCount)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
+
+	return nil
+}
+
+func connectToSQL(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return db, nil
+}
+
+func (r *MigrateFromSQLCmd) countSQLRows(ctx context.Context, db *sql.DB) (uint64, error) {
+	var count int64
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS source", r.query)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return uint64(count), nil
+}
+
+// offsetKey is the key the number of rows read from the query, or the last key read, is stored under.
+// The database and the query are hashed into it, so another query migrated into the same collection starts
+// from its own beginning. They're hashed rather than stored, since the URL can contain a password.
+func (r *MigrateFromSQLCmd) offsetKey() string {
+	sum := sha256.Sum256([]byte(r.SQL.Url + "\n" + r.query))
+	key := "sql:" + r.Qdrant.Collection + "/" + hex.EncodeToString(sum[:8])
+	if r.SQL.KeyColumn != "" {
+		return key + "/key=" + r.SQL.KeyColumn
+	}
+	return key
+}
+
+// migrateData reads the rows of the query and writes them in batches, in pages by --sql.key-column if it's set.
+func (r *MigrateFromSQLCmd) migrateData(ctx context.Context, sourceDB *sql.DB, targetClient *qdrant.Client, sourcePointCount uint64) error {
+	var offset *qdrant.PointId
+	offsetCount := uint64(0)
+	if !r.Migration.Restart {
+		id, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		offset = id
+		offsetCount = count
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	displayMigrationProgress(bar, offsetCount)
+
+	var err error
+	if r.SQL.KeyColumn != "" {
+		err = r.migrateDataByKey(ctx, sourceDB, targetClient, bar, offset, offsetCount)
+	} else {
+		err = r.migrateDataInOnePass(ctx, sourceDB, targetClient, bar, offsetCount)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Once all rows were read, the next run reads the query again from its beginning, which may return other rows.
+	if !sampleComplete(r.Migration) {
+		err = commons.DeleteStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return err
+		}
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+// migrateDataInOnePass streams the rows of the query in a single pass. An arbitrary query can't be resumed at a key,
+// so a resumed run skips as many rows as were migrated before, which needs a stable ORDER BY.
+func (r *MigrateFromSQLCmd) migrateDataInOnePass(ctx context.Context, sourceDB *sql.DB, targetClient *qdrant.Client, bar *pterm.ProgressbarPrinter, offsetCount uint64) error {
+	rows, err := sourceDB.QueryContext(ctx, r.query)
+	if err != nil {
+		return fmt.Errorf("failed to query source: %w", err)
+	}
+	defer rows.Close()
+
+	skip := offsetCount
+	batch := make([]*qdrant.PointStruct, 0, r.Migration.BatchSize)
+	batchStart := time.Now()
+
+	// The bytes of the next batch are reserved before its rows are read, and once the batch before is written.
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+	err = memory.reserve(ctx)
+	if err != nil {
+		return err
+	}
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		currentReport.observeRead(time.Since(batchStart), len(batch))
+		if err := memory.fit(ctx, pointsSize(batch)); err != nil {
+			return err
+		}
+
+		offsetCount += uint64(len(batch))
+		err := r.writeBatch(ctx, targetClient, batch, qdrant.NewIDNum(offsetCount), offsetCount)
+		if err != nil {
+			return err
+		}
+
+		bar.Add(len(batch))
+		batch = batch[:0]
+		batchStart = time.Now()
+		return memory.reserve(ctx)
+	}
+
+	err = scanSQLRows(rows, r.SQL.IdColumn, func(row map[string]any) (bool, error) {
+		if skip > 0 {
+			skip--
+			return true, nil
+		}
+
+		point, err := r.rowToPoint(row)
+		if err != nil {
+			return false, err
+		}
+		batch = append(batch, point)
+
+		if len(batch) >= r.Migration.BatchSize {
+			if err := flush(); err != nil {
+				return false, err
+			}
+			if sampleComplete(r.Migration) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+// migrateDataByKey reads the query in pages ordered by --sql.key-column, each continuing after the last key of the previous one,
+// so reading a page doesn't get slower as the migration progresses, like with OFFSET. The last key is checkpointed.
+func (r *MigrateFromSQLCmd) migrateDataByKey(ctx context.Context, sourceDB *sql.DB, targetClient *qdrant.Client, bar *pterm.ProgressbarPrinter, offset *qdrant.PointId, offsetCount uint64) error {
+	firstQuery, nextQuery := r.keysetQueries()
+
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
+	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
+		var rows *sql.Rows
+		var err error
+		if offset == nil {
+			rows, err = sourceDB.QueryContext(ctx, firstQuery, r.Migration.BatchSize)
+		} else {
+			rows, err = sourceDB.QueryContext(ctx, nextQuery, offsetToKey(offset), r.Migration.BatchSize)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query source: %w", err)
+		}
+
+		batch := make([]*qdrant.PointStruct, 0, r.Migration.BatchSize)
+		var lastKey any
+		err = scanSQLRows(rows, r.SQL.KeyColumn, func(row map[string]any) (bool, error) {
+			point, err := r.rowToPoint(row)
+			if err != nil {
+				return false, err
+			}
+			batch = append(batch, point)
+			lastKey = row[r.SQL.KeyColumn]
+			return true, nil
+		})
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		currentReport.observeRead(time.Since(batchStart), len(batch))
+		if err := memory.fit(ctx, pointsSize(batch)); err != nil {
+			return err
+		}
+
+		offset, err = keyToOffset(lastKey)
+		if err != nil {
+			return fmt.Errorf("failed to read key column: %w", err)
+		}
+		offsetCount += uint64(len(batch))
+		err = r.writeBatch(ctx, targetClient, batch, offset, offsetCount)
+		if err != nil {
+			return err
+		}
+
+		bar.Add(len(batch))
+
+		if len(batch) < r.Migration.BatchSize || sampleComplete(r.Migration) {
+			return nil
+		}
+	}
+}
+
+// keysetQueries returns the queries for the first page of the query and for those after a key, wrapping the query of the user.
+func (r *MigrateFromSQLCmd) keysetQueries() (string, string) {
+	key := sqlQuoteIdent(r.driver, r.SQL.KeyColumn)
+	first := fmt.Sprintf("SELECT * FROM (%s) AS source ORDER BY %s LIMIT %s", r.query, key, sqlPlaceholder(r.driver, 1))
+	next := fmt.Sprintf("SELECT * FROM (%s) AS source WHERE %s > %s ORDER BY %s LIMIT %s", r.query, key, sqlPlaceholder(r.driver, 1), key, sqlPlaceholder(r.driver, 2))
+	return first, next
+}
+
+// sqlQuoteIdent quotes a column name in the dialect of a driver.
+func sqlQuoteIdent(driver, name string) string {
+	if driver == "pgx" {
+		return pgx.Identifier{name}.Sanitize()
+	}
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// sqlPlaceholder returns the placeholder of the n-th query argument in the dialect of a driver.
+func sqlPlaceholder(driver string, n int) string {
+	if driver == "pgx" {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// scanSQLRows calls handle with every row of a result, by column name, until it returns false or an error.
+// The result must have the column required.
+func scanSQLRows(rows *sql.Rows, required string, handle func(map[string]any) (bool, error)) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns of the query: %w", err)
+	}
+	if !slices.Contains(columns, required) {
+		return fmt.Errorf("the query has no column '%s'", required)
+	}
+
+	values := make([]any, len(columns))
+	scanArgs := make([]any, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		err := rows.Scan(scanArgs...)
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = values[i]
+		}
+		more, err := handle(row)
+		if err != nil || !more {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	return nil
+}
+
+// writeBatch writes a batch of points, creating the target collection with the first one, and checkpoints the offset after it.
+func (r *MigrateFromSQLCmd) writeBatch(ctx context.Context, targetClient *qdrant.Client, batch []*qdrant.PointStruct, offset *qdrant.PointId, offsetCount uint64) error {
+	if !r.collectionReady {
+		err := r.prepareTargetCollection(ctx, targetClient, batch)
+		if err != nil {
+			return fmt.Errorf("error preparing target collection: %w", err)
+		}
+		r.collectionReady = true
+	}
+
+	writePoints, err := transformPayloads(ctx, batch, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	request := &qdrant.UpsertPoints{
+		CollectionName: r.Qdrant.Collection,
+		Points:         writePoints,
+		Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+	}
+	err = upsertPoints(ctx, targetClient, request, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to insert data into target: %w", err)
+	}
+	currentReport.addWritten(len(request.GetPoints()))
+
+	err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), offset, offsetCount)
+	if err != nil {
+		return fmt.Errorf("failed to store offset: %w", err)
+	}
+	return nil
+}
+
+// prepareTargetCollection creates the target collection, if it doesn't exist, with the vectors of the first rows read.
+func (r *MigrateFromSQLCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client, points []*qdrant.PointStruct) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	vectors := make(map[string]commons.RestVector, len(r.SQL.VectorColumns))
+	for _, name := range r.SQL.VectorColumns {
+		distance := "cosine"
+		if specified, ok := r.DistanceMetric[name]; ok {
+			distance = specified
+		}
+		vectors[name] = commons.RestVector{Distance: distance}
+	}
+	return createCollectionFromPoints(ctx, targetClient, r.Qdrant.Collection, vectors, points, r.Migration)
+}
+
+// rowToPoint converts a row of the query into a point. Its vector columns become named vectors, the other columns payload.
+func (r *MigrateFromSQLCmd) rowToPoint(row map[string]any) (*qdrant.PointStruct, error) {
+	point := &qdrant.PointStruct{}
+	vectors := make(map[string]*qdrant.Vector)
+	payload := make(map[string]any, len(row))
+
+	for column, value := range row {
+		value = sqlBytesToString(value)
+
+		switch {
+		case column == r.SQL.IdColumn:
+			id, original := sqlPointID(value)
+			if id == nil {
+				return nil, fmt.Errorf("row has no ID in column '%s'", column)
+			}
+			point.Id = id
+			if original != "" {
+				payload[r.IdField] = original
+			}
+		case slices.Contains(r.SQL.VectorColumns, column):
+			vector, err := sqlVector(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid vector in column '%s': %w", column, err)
+			}
+			if vector != nil {
+				vectors[column] = qdrant.NewVector(vector...)
+			}
+		case slices.Contains(r.SQL.JsonColumns, column) && value != nil:
+			text, ok := value.(string)
+			if !ok {
+				payload[column] = sanitizeValue(value)
+				continue
+			}
+			var parsed any
+			if err := json.Unmarshal([]byte(text), &parsed); err != nil {
+				return nil, fmt.Errorf("invalid JSON in column '%s': %w", column, err)
+			}
+			payload[column] = parsed
+		default:
+			payload[column] = sanitizeValue(value)
+		}
+	}
+
+	if len(vectors) > 0 {
+		point.Vectors = qdrant.NewVectorsMap(vectors)
+	}
+	point.Payload = qdrant.NewValueMap(payload)
+
+	return point, nil
+}
+
+// sqlBytesToString converts text that drivers return as bytes, like MySQL does for strings, to a string.
+// Bytes that aren't valid UTF-8 are binary and kept as they are.
+func sqlBytesToString(value any) any {
+	if b, ok := value.([]byte); ok && utf8.Valid(b) {
+		return string(b)
+	}
+	return value
+}
+
+// sqlPointID converts the ID of a row to a point ID. Non-negative integers and UUIDs are used as they are,
+// any other value is hashed into a UUID and returned as a string too, to be stored in the payload.
+func sqlPointID(value any) (*qdrant.PointId, string) {
+	switch v := value.(type) {
+	case nil:
+		return nil, ""
+	case int64:
+		if v >= 0 {
+			return qdrant.NewIDNum(uint64(v)), ""
+		}
+	case int32:
+		if v >= 0 {
+			return qdrant.NewIDNum(uint64(v)), ""
+		}
+	case uint64:
+		return qdrant.NewIDNum(v), ""
+	case uint32:
+		return qdrant.NewIDNum(uint64(v)), ""
+	case string:
+		if _, err := uuid.Parse(v); err == nil {
+			return qdrant.NewIDUUID(v), ""
+		}
+	case uuid.UUID:
+		return qdrant.NewIDUUID(v.String()), ""
+	}
+	original := fmt.Sprint(value)
+	return arbitraryIDToUUID(original), original
+}
+
+// sqlVector converts a vector column to floats. Drivers return arrays as slices, or as text: JSON arrays,
+// or Postgres array literals like {1,2,3} and pgvector ones like [1,2,3]. NULL is no vector.
+func sqlVector(value any) ([]float32, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []float32:
+		return v, nil
+	case []float64:
+		vector := make([]float32, len(v))
+		for i, f := range v {
+			vector[i] = float32(f)
+		}
+		return vector, nil
+	case []any:
+		vector := make([]float32, len(v))
+		for i, item := range v {
+			switch n := item.(type) {
+			case float32:
+				vector[i] = n
+			case float64:
+				vector[i] = float32(n)
+			case int64:
+				vector[i] = float32(n)
+			case int32:
+				vector[i] = float32(n)
+			default:
+				return nil, fmt.Errorf("element %d is a %T, not a number", i, item)
+			}
+		}
+		return vector, nil
+	case string:
+		text := strings.TrimSpace(v)
+		if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+			text = "[" + text[1:len(text)-1] + "]"
+		}
+		var vector []float32
+		if err := json.Unmarshal([]byte(text), &vector); err != nil {
+			return nil, fmt.Errorf("failed to parse %q as an array: %w", v, err)
+		}
+		return vector, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", value)
+	}
+}
@@ -0,0 +1,282 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateFromStdinCmd struct {
+	Stdin          commons.StdinConfig     `embed:"" prefix:"stdin."`
+	Qdrant         commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
+	IdField        string                  `prefix:"qdrant." help:"Field storing string IDs that aren't UUIDs in Qdrant." default:"__id__"`
+	DistanceMetric map[string]string       `prefix:"qdrant." help:"Map of vector names to distance metrics (cosine,dot,euclid,manhattan) to create the collection with. Default is cosine if not specified."`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+	// input is read instead of stdin, in tests.
+	input io.Reader
+}
+
+// stdinSource reads the points piped into the command, as if they were a single export file.
+type stdinSource struct {
+	input io.Reader
+}
+
+func (s stdinSource) Open(_ context.Context, _ string) (io.ReadCloser, error) {
+	return io.NopCloser(s.input), nil
+}
+
+// errSampleComplete stops reading stdin once enough points were sampled.
+var errSampleComplete = errors.New("sample complete")
+
+func (r *MigrateFromStdinCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+	if r.input == nil {
+		r.input = os.Stdin
+	}
+
+	return nil
+}
+
+func (r *MigrateFromStdinCmd) Validate() error {
+	for name, distance := range r.DistanceMetric {
+		switch distance {
+		case "cosine", "dot", "euclid", "manhattan":
+		default:
+			return fmt.Errorf("invalid distance metric '%s' for vector '%s'", distance, name)
+		}
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateFromStdinCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Stdin to Qdrant Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
+	}
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
+	}
+
+	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationStart("stdin", r.Stdin.Format, r.Qdrant.Collection)
+
+	err = r.migrateData(ctx, targetClient)
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: r.Qdrant.Collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+
+	return nil
+}
+
+// offsetKey is the key the number of points read from stdin is stored under.
+func (r *MigrateFromStdinCmd) offsetKey() string {
+	return "stdin:" + r.Qdrant.Collection
+}
+
+// migrateData writes the points read from stdin in batches. A pipe can't be resumed where it stopped,
+// so a resumed run skips as many points as were migrated before, expecting them in the same order.
+// Once the stream was read to the end, its checkpoint is removed, so the next run migrates all points it's given.
+func (r *MigrateFromStdinCmd) migrateData(ctx context.Context, targetClient *qdrant.Client) error {
+	offsetCount := uint64(0)
+	if !r.Migration.Restart {
+		_, count, err := commons.GetStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		offsetCount = count
+	}
+	if offsetCount > 0 {
+		pterm.Info.Printfln("Skipping the first %d points, which were migrated before", offsetCount)
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(0).Start()
+	displayMigrationProgress(bar, offsetCount)
+	collectionReady := false
+	batchStart := time.Now()
+
+	// The bytes of the next batch are reserved before its lines are read, and once the batch before is written.
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+	err := memory.reserve(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = readExportFile(ctx, stdinSource{input: r.input}, "stdin", exportFormatJSONL, r.Migration.BatchSize, offsetCount, func(records []exportRecord) error {
+		targetPoints := make([]*qdrant.PointStruct, 0, len(records))
+		for _, record := range records {
+			point, err := stdinRecordToPoint(record, r.IdField)
+			if err != nil {
+				return err
+			}
+			targetPoints = append(targetPoints, point)
+		}
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		if !collectionReady {
+			err := r.prepareTargetCollection(ctx, targetClient, targetPoints)
+			if err != nil {
+				return fmt.Errorf("error preparing target collection: %w", err)
+			}
+			collectionReady = true
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
+			CollectionName: r.Qdrant.Collection,
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
+		if err != nil {
+			return fmt.Errorf("failed to insert data into target: %w", err)
+		}
+		currentReport.addWritten(len(request.GetPoints()))
+
+		offsetCount += uint64(len(targetPoints))
+		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey(), qdrant.NewIDNum(offsetCount), offsetCount)
+		if err != nil {
+			return fmt.Errorf("failed to store offset: %w", err)
+		}
+
+		bar.Add(len(targetPoints))
+		batchStart = time.Now()
+
+		if sampleComplete(r.Migration) {
+			return errSampleComplete
+		}
+		return memory.reserve(ctx)
+	})
+	if errors.Is(err, errSampleComplete) {
+		pterm.Success.Printfln("Data migration finished successfully")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The whole stream was read, so the next run gets a new one, which must not skip any of its points.
+	err = commons.DeleteStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.offsetKey())
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+	return nil
+}
+
+// prepareTargetCollection creates the target collection, if it doesn't exist, with the vectors of the first points read.
+func (r *MigrateFromStdinCmd) prepareTargetCollection(ctx context.Context, targetClient *qdrant.Client, points []*qdrant.PointStruct) error {
+	if !r.Migration.CreateCollection || isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	vectors := make(map[string]commons.RestVector)
+	for _, point := range points {
+		for name := range point.GetVectors().GetVectors().GetVectors() {
+			distance := "cosine"
+			if specified, ok := r.DistanceMetric[name]; ok {
+				distance = specified
+			}
+			vectors[name] = commons.RestVector{Distance: distance}
+		}
+	}
+	return createCollectionFromPoints(ctx, targetClient, r.Qdrant.Collection, vectors, points, r.Migration)
+}
+
+// stdinRecordToPoint converts a record piped into the command into a point. Unlike in export files,
+// string IDs don't have to be UUIDs: other strings are converted into UUIDs and stored in idField of the payload.
+func stdinRecordToPoint(record exportRecord, idField string) (*qdrant.PointStruct, error) {
+	if id, ok := record.ID.(string); ok {
+		if _, err := uuid.Parse(id); err != nil {
+			record.ID = arbitraryIDToUUID(id).GetUuid()
+			payload := make(map[string]any, len(record.Payload)+1)
+			for key, value := range record.Payload {
+				payload[key] = value
+			}
+			payload[idField] = id
+			record.Payload = payload
+		}
+	}
+	return record.toPoint(false)
+}
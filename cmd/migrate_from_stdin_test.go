@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStdinSource(t *testing.T) {
+	input := strings.NewReader(`{"id": 1, "vectors": {"dense": [0.1, 0.2]}, "payload": {"count": 3}}
+{"id": "doc-2", "sparse_vectors": {"text": {"indices": [4], "values": [0.5]}}}
+{"id": "5c56c793-69f3-4fbf-87e6-c4bf54c28c26"}
+`)
+
+	var records []exportRecord
+	err := readExportFile(context.Background(), stdinSource{input: input}, "stdin", exportFormatJSONL, 2, 0, func(batch []exportRecord) error {
+		records = append(records, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readExportFile() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+
+	numPoint, err := stdinRecordToPoint(records[0], "__id__")
+	if err != nil {
+		t.Fatalf("stdinRecordToPoint() error = %v", err)
+	}
+	if numPoint.GetId().GetNum() != 1 || numPoint.GetPayload()["count"].GetIntegerValue() != 3 {
+		t.Errorf("got point %v, want ID 1 with its payload", numPoint)
+	}
+	if _, ok := numPoint.GetPayload()["__id__"]; ok {
+		t.Errorf("payload of a numeric ID has the ID field: %v", numPoint.GetPayload())
+	}
+
+	stringPoint, err := stdinRecordToPoint(records[1], "__id__")
+	if err != nil {
+		t.Fatalf("stdinRecordToPoint() error = %v", err)
+	}
+	if stringPoint.GetId().GetUuid() != arbitraryIDToUUID("doc-2").GetUuid() {
+		t.Errorf("got ID %v, want the UUID of doc-2", stringPoint.GetId())
+	}
+	if stringPoint.GetPayload()["__id__"].GetStringValue() != "doc-2" {
+		t.Errorf("payload = %v, want the original ID in __id__", stringPoint.GetPayload())
+	}
+
+	uuidPoint, err := stdinRecordToPoint(records[2], "__id__")
+	if err != nil {
+		t.Fatalf("stdinRecordToPoint() error = %v", err)
+	}
+	if uuidPoint.GetId().GetUuid() != "5c56c793-69f3-4fbf-87e6-c4bf54c28c26" || len(uuidPoint.GetPayload()) != 0 {
+		t.Errorf("got point %v, want the UUID as it is", uuidPoint)
+	}
+}
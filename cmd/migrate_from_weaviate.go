@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/pterm/pterm"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/auth"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/connection"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
+	"golang.org/x/oauth2"
 
 	"github.com/qdrant/go-client/qdrant"
 
@@ -20,9 +26,10 @@ import (
 )
 
 type MigrateFromWeaviateCmd struct {
-	Weaviate  commons.WeaviateConfig  `embed:"" prefix:"weaviate."`
-	Qdrant    commons.QdrantConfig    `embed:"" prefix:"qdrant."`
-	Migration commons.MigrationConfig `embed:"" prefix:"migration."`
+	Weaviate       commons.WeaviateConfig  `embed:"" prefix:"weaviate."`
+	Qdrant         commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Migration      commons.MigrationConfig `embed:"" prefix:"migration."`
+	UpdatedAtField string                  `prefix:"qdrant." help:"Field to store the time every object was last updated in Weaviate in, as an RFC 3339 timestamp in UTC, e.g. for incremental syncs and audits. Not stored by default."`
 
 	targetHost string
 	targetPort int
@@ -51,7 +58,7 @@ func (r *MigrateFromWeaviateCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to parse input: %w", err)
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	sourceClient, err := r.connectToWeaviate()
@@ -59,18 +66,25 @@ func (r *MigrateFromWeaviateCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to connect to Weaviate source: %w", err)
 	}
 
-	targetClient, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant.APIKey, r.targetTLS, 0)
+	targetClient, err := connectToTarget(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS, r.Migration)
 	if err != nil {
 		return fmt.Errorf("failed to connect to Qdrant target: %w", err)
 	}
-	defer targetClient.Close()
+	if !isSinkTarget(r.Migration) {
+		defer targetClient.Close()
 
-	targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
-	if err != nil {
-		return fmt.Errorf("failed to check if collection exists: %w", err)
+		targetCollectionExists, err := targetClient.CollectionExists(ctx, r.Qdrant.Collection)
+		if err != nil {
+			return fmt.Errorf("failed to check if collection exists: %w", err)
+		}
+		if !targetCollectionExists {
+			return fmt.Errorf("target collection '%s' does not exist in Qdrant", r.Qdrant.Collection)
+		}
 	}
-	if !targetCollectionExists {
-		return fmt.Errorf("target collection '%s' does not exist in Qdrant", r.Qdrant.Collection)
+
+	err = checkTargetAccess(ctx, targetClient, r.Qdrant.APIKey, r.Qdrant.Collection, r.Migration, false)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant target: %w", err)
 	}
 
 	err = commons.PrepareOffsetsCollection(ctx, r.Migration.OffsetsCollection, targetClient)
@@ -83,6 +97,11 @@ func (r *MigrateFromWeaviateCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to count objects in source: %w", err)
 	}
 
+	err = backupTargetCollection(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	displayMigrationStart("weaviate", r.Weaviate.ClassName, r.Qdrant.Collection)
 
 	err = r.migrateData(ctx, sourceClient, targetClient, sourcePointCount)
@@ -90,6 +109,28 @@ func (r *MigrateFromWeaviateCmd) Run(globals *Globals) error {
 		return fmt.Errorf("failed to migrate data: %w", err)
 	}
 
+	// Nothing was written to the target, so there's nothing to wait for or count in it.
+	if isSinkTarget(r.Migration) {
+		return nil
+	}
+
+	if r.Migration.AsyncUpserts {
+		err = flushTarget(ctx, targetClient, r.Qdrant.Collection, sourcePointCount)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = createPayloadIndexes(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
+	err = waitForOptimizations(ctx, targetClient, r.Qdrant.Collection, r.Migration)
+	if err != nil {
+		return err
+	}
+
 	targetPointCount, err := targetClient.Count(ctx, &qdrant.CountPoints{
 		CollectionName: r.Qdrant.Collection,
 		Exact:          qdrant.PtrOf(true),
@@ -99,49 +140,50 @@ func (r *MigrateFromWeaviateCmd) Run(globals *Globals) error {
 	}
 
 	pterm.Info.Printfln("Target collection has %d points\n", targetPointCount)
+	currentReport.setPointCounts(sourcePointCount, targetPointCount)
 
 	return nil
 }
 
-func (r *MigrateFromWeaviateCmd) parseWeaviateOptions() (weaviate.Config, error) {
+func parseWeaviateOptions(config commons.WeaviateConfig) (weaviate.Config, error) {
 	cfg := weaviate.Config{
-		Host:   r.Weaviate.Host,
-		Scheme: r.Weaviate.Scheme,
+		Host:   config.Host,
+		Scheme: config.Scheme,
 	}
 
-	switch r.Weaviate.AuthType {
+	switch config.AuthType {
 	case "apiKey":
-		if r.Weaviate.APIKey == "" {
+		if config.APIKey == "" {
 			return cfg, errors.New("API key is required for apiKey authentication")
 		}
 		cfg.AuthConfig = auth.ApiKey{
-			Value: r.Weaviate.APIKey,
+			Value: config.APIKey,
 		}
 	case "password":
-		if r.Weaviate.Username == "" || r.Weaviate.Password == "" {
+		if config.Username == "" || config.Password == "" {
 			return cfg, errors.New("username and password are required for password authentication")
 		}
 		cfg.AuthConfig = auth.ResourceOwnerPasswordFlow{
-			Username: r.Weaviate.Username,
-			Password: r.Weaviate.Password,
-			Scopes:   r.Weaviate.Scopes,
+			Username: config.Username,
+			Password: config.Password,
+			Scopes:   config.Scopes,
 		}
 	case "client":
-		if r.Weaviate.ClientSecret == "" {
+		if config.ClientSecret == "" {
 			return cfg, errors.New("client secret is required for client credentials authentication")
 		}
 		cfg.AuthConfig = auth.ClientCredentials{
-			ClientSecret: r.Weaviate.ClientSecret,
-			Scopes:       r.Weaviate.Scopes,
+			ClientSecret: config.ClientSecret,
+			Scopes:       config.Scopes,
 		}
 	case "bearer":
-		if r.Weaviate.Token == "" {
+		if config.Token == "" {
 			return cfg, errors.New("token is required for bearer token authentication")
 		}
 		cfg.AuthConfig = auth.BearerToken{
-			AccessToken:  r.Weaviate.Token,
-			RefreshToken: r.Weaviate.RefreshToken,
-			ExpiresIn:    r.Weaviate.ExpiresIn,
+			AccessToken:  config.Token,
+			RefreshToken: config.RefreshToken,
+			ExpiresIn:    config.ExpiresIn,
 		}
 	}
 
@@ -149,10 +191,14 @@ func (r *MigrateFromWeaviateCmd) parseWeaviateOptions() (weaviate.Config, error)
 }
 
 func (r *MigrateFromWeaviateCmd) connectToWeaviate() (*weaviate.Client, error) {
-	cfg, err := r.parseWeaviateOptions()
+	cfg, err := parseWeaviateOptions(r.Weaviate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Weaviate options: %w", err)
 	}
+	cfg, err = withRateLimitRetries(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to Weaviate: %w", err)
+	}
 
 	client, err := weaviate.NewClient(cfg)
 	if err != nil {
@@ -162,6 +208,51 @@ func (r *MigrateFromWeaviateCmd) connectToWeaviate() (*weaviate.Client, error) {
 	return client, nil
 }
 
+// withRateLimitRetries makes the client retry rate limited requests. The Weaviate client only accepts an HTTP client
+// without authentication, so the authentication is resolved here as the client would, and the HTTP client it returns
+// is wrapped. The transport of OIDC clients stays an oauth2.Transport, so their tokens are still refreshed.
+func withRateLimitRetries(cfg weaviate.Config) (weaviate.Config, error) {
+	if cfg.AuthConfig == nil {
+		cfg.ConnectionClient = newRateLimitedClient(nil)
+		return cfg, nil
+	}
+
+	con := connection.NewConnection(cfg.Scheme, cfg.Host, nil, time.Minute, cfg.Headers)
+	if err := con.WaitForWeaviate(cfg.StartupTimeout); err != nil {
+		return cfg, err
+	}
+	httpClient, headers, err := cfg.AuthConfig.GetAuthInfo(con)
+	if err != nil {
+		return cfg, err
+	}
+
+	switch {
+	case httpClient == nil:
+		httpClient = newRateLimitedClient(nil)
+	case httpClient.Transport == nil:
+		httpClient.Transport = &rateLimitedTransport{base: http.DefaultTransport, retries: sourceRateLimitRetries}
+	default:
+		if transport, ok := httpClient.Transport.(*oauth2.Transport); ok {
+			base := transport.Base
+			if base == nil {
+				base = http.DefaultTransport
+			}
+			transport.Base = &rateLimitedTransport{base: base, retries: sourceRateLimitRetries}
+		} else {
+			httpClient.Transport = &rateLimitedTransport{base: httpClient.Transport, retries: sourceRateLimitRetries}
+		}
+	}
+
+	cfg.Headers = maps.Clone(cfg.Headers)
+	if cfg.Headers == nil {
+		cfg.Headers = map[string]string{}
+	}
+	maps.Copy(cfg.Headers, headers)
+	cfg.ConnectionClient = httpClient
+	cfg.AuthConfig = nil
+	return cfg, nil
+}
+
 func (r *MigrateFromWeaviateCmd) getClassSchema(ctx context.Context, client *weaviate.Client) (*models.Class, error) {
 	schema, err := client.Schema().Getter().Do(ctx)
 	if err != nil {
@@ -242,18 +333,30 @@ func (r *MigrateFromWeaviateCmd) migrateData(ctx context.Context, sourceClient *
 		fields = append(fields, graphql.Field{Name: prop.Name})
 	}
 
+	additionalFields := []graphql.Field{
+		{Name: "id"},
+		{Name: "vector"},
+	}
+	if r.UpdatedAtField != "" {
+		additionalFields = append(additionalFields, graphql.Field{Name: "lastUpdateTimeUnix"})
+	}
 	fields = append(fields, graphql.Field{
-		Name: "_additional",
-		Fields: []graphql.Field{
-			{Name: "id"},
-			{Name: "vector"},
-		},
+		Name:   "_additional",
+		Fields: additionalFields,
 	})
 
 	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
 	displayMigrationProgress(bar, offsetCount)
 
+	memory := newMemoryBudget(r.Migration.MaxMemory).reader()
+	defer memory.release()
+
 	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		batchStart := time.Now()
 		query := sourceClient.GraphQL().Get().
 			WithClassName(r.Weaviate.ClassName).
 			WithLimit(r.Migration.BatchSize).
@@ -326,6 +429,13 @@ func (r *MigrateFromWeaviateCmd) migrateData(ctx context.Context, sourceClient *
 					cleanObj[k] = v
 				}
 			}
+			if r.UpdatedAtField != "" {
+				updatedAt, err := weaviateTimestamp(additional["lastUpdateTimeUnix"])
+				if err != nil {
+					return fmt.Errorf("invalid update time of object %s: %w", id, err)
+				}
+				cleanObj[r.UpdatedAtField] = updatedAt
+			}
 			payload, err := qdrant.TryValueMap(cleanObj)
 			if err != nil {
 				return fmt.Errorf("failed to convert object to Qdrant payload: %w", err)
@@ -341,14 +451,26 @@ func (r *MigrateFromWeaviateCmd) migrateData(ctx context.Context, sourceClient *
 			offsetID = point.Id
 		}
 
-		_, err = targetClient.Upsert(ctx, &qdrant.UpsertPoints{
+		currentReport.observeRead(time.Since(batchStart), len(targetPoints))
+		if err := memory.fit(ctx, pointsSize(targetPoints)); err != nil {
+			return err
+		}
+
+		writePoints, err := transformPayloads(ctx, targetPoints, r.Migration)
+		if err != nil {
+			return err
+		}
+
+		request := &qdrant.UpsertPoints{
 			CollectionName: r.Qdrant.Collection,
-			Points:         targetPoints,
-			Wait:           qdrant.PtrOf(true),
-		})
+			Points:         writePoints,
+			Wait:           qdrant.PtrOf(!r.Migration.AsyncUpserts),
+		}
+		err = upsertPoints(ctx, targetClient, request, r.Migration)
 		if err != nil {
 			return fmt.Errorf("failed to insert data into target: %w", err)
 		}
+		currentReport.addWritten(len(request.GetPoints()))
 
 		offsetCount += uint64(count)
 		err = commons.StoreStartOffset(ctx, r.Migration.OffsetsCollection, targetClient, r.Weaviate.ClassName, offsetID, offsetCount)
@@ -357,8 +479,22 @@ func (r *MigrateFromWeaviateCmd) migrateData(ctx context.Context, sourceClient *
 		}
 
 		bar.Add(count)
+
+		if sampleComplete(r.Migration) {
+			break
+		}
 	}
 
 	pterm.Success.Printfln("Data migration finished successfully")
 	return nil
 }
+
+// weaviateTimestamp converts a time of Weaviate, in milliseconds since the epoch as a string, to an RFC 3339 timestamp in UTC.
+func weaviateTimestamp(value any) (string, error) {
+	text, _ := value.(string)
+	millis, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("expected milliseconds since the epoch, got %v", value)
+	}
+	return time.UnixMilli(millis).UTC().Format(time.RFC3339Nano), nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func Test_weaviateTimestamp(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    string
+		wantErr bool
+	}{
+		{"milliseconds", "1718000000123", "2024-06-10T06:13:20.123Z", false},
+		{"seconds", "1718000000000", "2024-06-10T06:13:20Z", false},
+		{"missing", nil, "", true},
+		{"invalid", "yesterday", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := weaviateTimestamp(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("weaviateTimestamp() = %q, %v, want %q, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/milvus-io/milvus/client/v2/column"
+	"github.com/milvus-io/milvus/client/v2/entity"
+	"github.com/milvus-io/milvus/client/v2/index"
+	"github.com/milvus-io/milvus/client/v2/milvusclient"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateToMilvusCmd struct {
+	Qdrant       commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Milvus       commons.MilvusConfig    `embed:"" prefix:"milvus."`
+	Migration    commons.MigrationConfig `embed:"" prefix:"migration."`
+	IdField      string                  `prefix:"milvus." help:"Primary key field storing Qdrant IDs in Milvus." default:"id"`
+	PayloadField string                  `prefix:"milvus." help:"JSON field storing Qdrant payloads in Milvus." default:"payload"`
+
+	sourceHost string
+	sourcePort int
+	sourceTLS  bool
+}
+
+func (r *MigrateToMilvusCmd) Parse() error {
+	var err error
+	r.sourceHost, r.sourcePort, r.sourceTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse source URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToMilvusCmd) Validate() error {
+	if len(r.Milvus.Partitions) > 1 {
+		return fmt.Errorf("at most one Milvus partition can be written to")
+	}
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateToMilvusCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant to Milvus Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Qdrant, r.sourceTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant source: %w", err)
+	}
+	defer sourceClient.Close()
+
+	err = checkSourceVersion(ctx, sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = checkSourceAccess(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant source: %w", err)
+	}
+
+	targetClient, err := connectToMilvus(ctx, r.Milvus)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Milvus target: %w", err)
+	}
+
+	vectorParams, _, err := getSourceVectorParams(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+
+	err = r.prepareTargetCollection(ctx, targetClient, vectorParams)
+	if err != nil {
+		return fmt.Errorf("error preparing target collection: %w", err)
+	}
+
+	displayMigrationFromQdrantStart(r.Qdrant.Collection, "milvus", r.Milvus.Collection)
+
+	offsetKey := fmt.Sprintf("%s->milvus:%s/%s", r.Qdrant.Collection, r.Milvus.Url, r.Milvus.Collection)
+	err = scrollQdrantSource(ctx, sourceClient, r.Qdrant.Collection, r.Migration, runOffsetStore{}, offsetKey, func(points []*qdrant.RetrievedPoint) error {
+		return r.upsertRows(ctx, targetClient, vectorParams, points)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToMilvusCmd) prepareTargetCollection(ctx context.Context, targetClient *milvusclient.Client, vectorParams map[string]*qdrant.VectorParams) error {
+	if !r.Migration.CreateCollection {
+		return nil
+	}
+
+	exists, err := targetClient.HasCollection(ctx, milvusclient.NewHasCollectionOption(r.Milvus.Collection))
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+
+	if exists {
+		pterm.Info.Printfln("Target collection %q already exists. Skipping creation.", r.Milvus.Collection)
+		return nil
+	}
+
+	metricMapping := map[qdrant.Distance]entity.MetricType{
+		qdrant.Distance_Cosine: entity.COSINE,
+		qdrant.Distance_Dot:    entity.IP,
+		qdrant.Distance_Euclid: entity.L2,
+	}
+
+	// Qdrant IDs are either unsigned integers or UUIDs.
+	// Both fit in a 36 character VarChar primary key.
+	schema := entity.NewSchema().
+		WithField(entity.NewField().WithName(r.IdField).WithDataType(entity.FieldTypeVarChar).WithMaxLength(36).WithIsPrimaryKey(true)).
+		WithField(entity.NewField().WithName(r.PayloadField).WithDataType(entity.FieldTypeJSON))
+
+	var indexOptions []milvusclient.CreateIndexOption
+	for name, params := range vectorParams {
+		metricType, ok := metricMapping[params.Distance]
+		if !ok {
+			return fmt.Errorf("distance %s of vector %q is not supported by Milvus", params.Distance, name)
+		}
+		schema = schema.WithField(entity.NewField().WithName(name).WithDataType(entity.FieldTypeFloatVector).WithDim(int64(params.Size)))
+		indexOptions = append(indexOptions, milvusclient.NewCreateIndexOption(r.Milvus.Collection, name, index.NewAutoIndex(metricType)))
+	}
+
+	err = targetClient.CreateCollection(ctx, milvusclient.NewCreateCollectionOption(r.Milvus.Collection, schema).WithIndexOptions(indexOptions...))
+	if err != nil {
+		return fmt.Errorf("failed to create target collection: %w", err)
+	}
+
+	pterm.Success.Printfln("Created target collection %q", r.Milvus.Collection)
+	return nil
+}
+
+func (r *MigrateToMilvusCmd) upsertRows(ctx context.Context, client *milvusclient.Client, vectorParams map[string]*qdrant.VectorParams, points []*qdrant.RetrievedPoint) error {
+	ids := make([]string, 0, len(points))
+	payloads := make([][]byte, 0, len(points))
+	vectors := make(map[string][][]float32, len(vectorParams))
+
+	for _, point := range points {
+		id := pointIDToString(point.Id)
+		dense, _ := getPointVectors(point)
+
+		payload, err := json.Marshal(payloadToMap(point.Payload))
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload of point %s: %w", id, err)
+		}
+
+		ids = append(ids, id)
+		payloads = append(payloads, payload)
+		for name := range vectorParams {
+			vector, ok := dense[name]
+			if !ok {
+				return fmt.Errorf("point %s has no %q vector, which is required by Milvus", id, name)
+			}
+			vectors[name] = append(vectors[name], vector)
+		}
+	}
+
+	columns := []column.Column{
+		column.NewColumnVarChar(r.IdField, ids),
+		column.NewColumnJSONBytes(r.PayloadField, payloads),
+	}
+	for name, params := range vectorParams {
+		columns = append(columns, column.NewColumnFloatVector(name, int(params.Size), vectors[name]))
+	}
+
+	option := milvusclient.NewColumnBasedInsertOption(r.Milvus.Collection, columns...)
+	if len(r.Milvus.Partitions) == 1 {
+		option = option.WithPartition(r.Milvus.Partitions[0])
+	}
+
+	writeStart := time.Now()
+	_, err := client.Upsert(ctx, option)
+	if err != nil {
+		return fmt.Errorf("failed to upsert rows into Milvus: %w", err)
+	}
+	currentReport.observeWrite(time.Since(writeStart))
+	currentReport.addWritten(len(points))
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateToPGCmd struct {
+	Qdrant    commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	PG        commons.PGTargetConfig  `embed:"" prefix:"pg."`
+	Migration commons.MigrationConfig `embed:"" prefix:"migration."`
+
+	sourceHost string
+	sourcePort int
+	sourceTLS  bool
+}
+
+func (r *MigrateToPGCmd) Parse() error {
+	var err error
+	r.sourceHost, r.sourcePort, r.sourceTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse source URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToPGCmd) Validate() error {
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateToPGCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant to Postgres Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Qdrant, r.sourceTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant source: %w", err)
+	}
+	defer sourceClient.Close()
+
+	err = checkSourceVersion(ctx, sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = checkSourceAccess(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant source: %w", err)
+	}
+
+	targetConn, err := connectToPG(ctx, r.PG.Url)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Postgres target: %w", err)
+	}
+	defer targetConn.Close(ctx)
+
+	err = r.prepareTargetTable(ctx, sourceClient, targetConn)
+	if err != nil {
+		return fmt.Errorf("error preparing target table: %w", err)
+	}
+
+	offsets, err := newPGOffsetStore(ctx, targetConn, r.Migration.OffsetsCollection)
+	if err != nil {
+		return err
+	}
+
+	displayMigrationFromQdrantStart(r.Qdrant.Collection, "postgres", r.PG.Table)
+
+	offsetKey := fmt.Sprintf("%s->postgres:%s", r.Qdrant.Collection, r.PG.Table)
+	err = scrollQdrantSource(ctx, sourceClient, r.Qdrant.Collection, r.Migration, offsets, offsetKey, func(points []*qdrant.RetrievedPoint) error {
+		return r.insertRows(ctx, targetConn, points)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	var targetRowCount int64
+	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
+	err = targetConn.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", tableIdent)).Scan(&targetRowCount)
+	if err != nil {
+		return fmt.Errorf("failed to count rows in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target table has %d rows\n", targetRowCount)
+
+	return nil
+}
+
+func (r *MigrateToPGCmd) prepareTargetTable(ctx context.Context, sourceClient *qdrant.Client, targetConn *pgx.Conn) error {
+	if !r.Migration.CreateCollection {
+		return nil
+	}
+
+	vectorParams, _, err := getSourceVectorParams(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(vectorParams))
+	for name := range vectorParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	columns := []string{
+		fmt.Sprintf("%s TEXT PRIMARY KEY", pgx.Identifier{r.PG.KeyColumn}.Sanitize()),
+	}
+	for _, name := range names {
+		columns = append(columns, fmt.Sprintf("%s vector(%d)", pgx.Identifier{name}.Sanitize(), vectorParams[name].Size))
+	}
+	columns = append(columns, fmt.Sprintf("%s JSONB", pgx.Identifier{r.PG.PayloadColumn}.Sanitize()))
+
+	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
+	_, err = targetConn.Exec(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tableIdent, strings.Join(columns, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to create target table: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToPGCmd) insertRows(ctx context.Context, conn *pgx.Conn, points []*qdrant.RetrievedPoint) error {
+	tableIdent := pgx.Identifier{r.PG.Table}.Sanitize()
+	keyIdent := pgx.Identifier{r.PG.KeyColumn}.Sanitize()
+
+	batch := &pgx.Batch{}
+	for _, point := range points {
+		dense, _ := getPointVectors(point)
+
+		names := make([]string, 0, len(dense))
+		for name := range dense {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		columns := []string{keyIdent, pgx.Identifier{r.PG.PayloadColumn}.Sanitize()}
+		args := []any{pointIDToString(point.Id), payloadToMap(point.Payload)}
+		for _, name := range names {
+			columns = append(columns, pgx.Identifier{name}.Sanitize())
+			args = append(args, pgvector.NewVector(dense[name]))
+		}
+
+		placeholders := make([]string, len(columns))
+		updates := make([]string, 0, len(columns)-1)
+		for i, col := range columns {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			if col != keyIdent {
+				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", col, col))
+			}
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s",
+			tableIdent, strings.Join(columns, ", "), strings.Join(placeholders, ", "), keyIdent, strings.Join(updates, ", "))
+		batch.Queue(query, args...)
+	}
+
+	writeStart := time.Now()
+	err := conn.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return fmt.Errorf("failed to insert rows into Postgres: %w", err)
+	}
+	currentReport.observeWrite(time.Since(writeStart))
+	currentReport.addWritten(len(points))
+
+	return nil
+}
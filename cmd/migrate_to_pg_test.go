@@ -0,0 +1,13 @@
+package cmd
+
+import "testing"
+
+func TestToPGVectorAlias(t *testing.T) {
+	ctx, cli, err := parseCommand([]string{"to-pgvector", "--qdrant.collection", "a", "--pg.url", "postgres://localhost", "--pg.table", "t"})
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if ctx.Command() != "to-pg" || cli.ToPG.PG.Table != "t" {
+		t.Errorf("parsed command %q with table %q, want to-pg with table t", ctx.Command(), cli.ToPG.PG.Table)
+	}
+}
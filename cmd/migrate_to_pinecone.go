@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pinecone-io/go-pinecone/v3/pinecone"
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateToPineconeCmd struct {
+	Qdrant       commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Pinecone     commons.PineconeConfig  `embed:"" prefix:"pinecone."`
+	Migration    commons.MigrationConfig `embed:"" prefix:"migration."`
+	DenseVector  string                  `prefix:"qdrant." help:"Name of the Qdrant dense vector to migrate. Defaults to the unnamed vector." default:"vector"`
+	SparseVector string                  `prefix:"qdrant." help:"Name of the Qdrant sparse vector to migrate." default:"sparse_vector"`
+
+	sourceHost string
+	sourcePort int
+	sourceTLS  bool
+}
+
+func (r *MigrateToPineconeCmd) Parse() error {
+	var err error
+	r.sourceHost, r.sourcePort, r.sourceTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse source URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToPineconeCmd) Validate() error {
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateToPineconeCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant to Pinecone Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Qdrant, r.sourceTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant source: %w", err)
+	}
+	defer sourceClient.Close()
+
+	err = checkSourceVersion(ctx, sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = checkSourceAccess(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant source: %w", err)
+	}
+
+	targetIndexConn, err := r.connectToPinecone()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Pinecone target: %w", err)
+	}
+	defer targetIndexConn.Close()
+
+	displayMigrationFromQdrantStart(r.Qdrant.Collection, "pinecone", r.Pinecone.IndexName)
+
+	offsetKey := fmt.Sprintf("%s->pinecone:%s", r.Qdrant.Collection, r.Pinecone.IndexHost)
+	err = scrollQdrantSource(ctx, sourceClient, r.Qdrant.Collection, r.Migration, runOffsetStore{}, offsetKey, func(points []*qdrant.RetrievedPoint) error {
+		return r.upsertVectors(ctx, targetIndexConn, points)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	stats, err := targetIndexConn.DescribeIndexStats(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to count vectors in target: %w", err)
+	}
+
+	pterm.Info.Printfln("Target index has %d vectors\n", stats.TotalVectorCount)
+
+	return nil
+}
+
+func (r *MigrateToPineconeCmd) connectToPinecone() (*pinecone.IndexConnection, error) {
+	client, err := pinecone.NewClient(pinecone.NewClientParams{
+		Host:   r.Pinecone.ServiceHost,
+		ApiKey: r.Pinecone.APIKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Pinecone client: %w", err)
+	}
+
+	indexConn, err := client.Index(pinecone.NewIndexConnParams{
+		Host:      r.Pinecone.IndexHost,
+		Namespace: r.Pinecone.Namespace,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Pinecone index: %w", err)
+	}
+
+	return indexConn, nil
+}
+
+func (r *MigrateToPineconeCmd) upsertVectors(ctx context.Context, indexConn *pinecone.IndexConnection, points []*qdrant.RetrievedPoint) error {
+	vectors := make([]*pinecone.Vector, 0, len(points))
+	for _, point := range points {
+		dense, sparse := getPointVectors(point)
+
+		vector := &pinecone.Vector{
+			Id: pointIDToString(point.Id),
+		}
+		if values, ok := dense[r.DenseVector]; ok {
+			vector.Values = &values
+		}
+		if values, ok := sparse[r.SparseVector]; ok {
+			vector.SparseValues = &pinecone.SparseValues{
+				Indices: values.Indices,
+				Values:  values.Values,
+			}
+		}
+		if vector.Values == nil && vector.SparseValues == nil {
+			return fmt.Errorf("point %s has neither a %q dense vector nor a %q sparse vector", vector.Id, r.DenseVector, r.SparseVector)
+		}
+
+		if len(point.Payload) > 0 {
+			metadata, err := structpb.NewStruct(payloadToMap(point.Payload))
+			if err != nil {
+				return fmt.Errorf("failed to convert payload of point %s to Pinecone metadata: %w", vector.Id, err)
+			}
+			vector.Metadata = metadata
+		}
+
+		vectors = append(vectors, vector)
+	}
+
+	writeStart := time.Now()
+	_, err := indexConn.UpsertVectors(ctx, vectors)
+	if err != nil {
+		return fmt.Errorf("failed to upsert vectors into Pinecone: %w", err)
+	}
+	currentReport.observeWrite(time.Since(writeStart))
+	currentReport.addWritten(len(points))
+
+	return nil
+}
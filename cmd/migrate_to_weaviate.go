@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/pterm/pterm"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type MigrateToWeaviateCmd struct {
+	Qdrant    commons.QdrantConfig    `embed:"" prefix:"qdrant."`
+	Weaviate  commons.WeaviateConfig  `embed:"" prefix:"weaviate."`
+	Migration commons.MigrationConfig `embed:"" prefix:"migration."`
+	IdField   string                  `prefix:"weaviate." help:"Property storing Qdrant IDs in Weaviate." default:"qdrant_id"`
+
+	sourceHost string
+	sourcePort int
+	sourceTLS  bool
+}
+
+func (r *MigrateToWeaviateCmd) Parse() error {
+	var err error
+	r.sourceHost, r.sourcePort, r.sourceTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse source URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToWeaviateCmd) Validate() error {
+	return validateBatchSize(r.Migration.BatchSize)
+}
+
+func (r *MigrateToWeaviateCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant to Weaviate Data Migration")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sourceClient, err := connectToQdrant(globals, r.sourceHost, r.sourcePort, r.Qdrant, r.sourceTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant source: %w", err)
+	}
+	defer sourceClient.Close()
+
+	err = checkSourceVersion(ctx, sourceClient)
+	if err != nil {
+		return err
+	}
+
+	err = checkSourceAccess(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to check access to Qdrant source: %w", err)
+	}
+
+	targetClient, err := r.connectToWeaviate()
+	if err != nil {
+		return fmt.Errorf("failed to connect to Weaviate target: %w", err)
+	}
+
+	err = r.prepareTargetClass(ctx, sourceClient, targetClient)
+	if err != nil {
+		return fmt.Errorf("error preparing target class: %w", err)
+	}
+
+	displayMigrationFromQdrantStart(r.Qdrant.Collection, "weaviate", r.Weaviate.ClassName)
+
+	offsetKey := fmt.Sprintf("%s->weaviate:%s/%s", r.Qdrant.Collection, r.Weaviate.Host, r.Weaviate.ClassName)
+	err = scrollQdrantSource(ctx, sourceClient, r.Qdrant.Collection, r.Migration, runOffsetStore{}, offsetKey, func(points []*qdrant.RetrievedPoint) error {
+		return r.insertObjects(ctx, targetClient, points)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate data: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MigrateToWeaviateCmd) connectToWeaviate() (*weaviate.Client, error) {
+	cfg, err := parseWeaviateOptions(r.Weaviate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Weaviate options: %w", err)
+	}
+
+	client, err := weaviate.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Weaviate client: %w", err)
+	}
+
+	return client, nil
+}
+
+func (r *MigrateToWeaviateCmd) prepareTargetClass(ctx context.Context, sourceClient *qdrant.Client, targetClient *weaviate.Client) error {
+	if !r.Migration.CreateCollection {
+		return nil
+	}
+
+	exists, err := targetClient.Schema().ClassExistenceChecker().WithClassName(r.Weaviate.ClassName).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if class exists: %w", err)
+	}
+
+	if exists {
+		pterm.Info.Printfln("Target class %q already exists. Skipping creation.", r.Weaviate.ClassName)
+		return nil
+	}
+
+	vectorParams, _, err := getSourceVectorParams(ctx, sourceClient, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+
+	distanceMapping := map[qdrant.Distance]string{
+		qdrant.Distance_Cosine:    "cosine",
+		qdrant.Distance_Dot:       "dot",
+		qdrant.Distance_Euclid:    "l2-squared",
+		qdrant.Distance_Manhattan: "manhattan",
+	}
+
+	class := &models.Class{
+		Class: r.Weaviate.ClassName,
+	}
+
+	if params, ok := vectorParams[defaultVectorName]; ok && len(vectorParams) == 1 {
+		class.Vectorizer = "none"
+		class.VectorIndexConfig = map[string]any{"distance": distanceMapping[params.Distance]}
+	} else {
+		class.VectorConfig = make(map[string]models.VectorConfig, len(vectorParams))
+		for name, params := range vectorParams {
+			class.VectorConfig[name] = models.VectorConfig{
+				Vectorizer:        map[string]any{"none": map[string]any{}},
+				VectorIndexType:   "hnsw",
+				VectorIndexConfig: map[string]any{"distance": distanceMapping[params.Distance]},
+			}
+		}
+	}
+
+	err = targetClient.Schema().ClassCreator().WithClass(class).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create target class: %w", err)
+	}
+
+	pterm.Success.Printfln("Created target class %q", r.Weaviate.ClassName)
+	return nil
+}
+
+func (r *MigrateToWeaviateCmd) insertObjects(ctx context.Context, client *weaviate.Client, points []*qdrant.RetrievedPoint) error {
+	batcher := client.Batch().ObjectsBatcher()
+	for _, point := range points {
+		dense, _ := getPointVectors(point)
+
+		id := pointIDToString(point.Id)
+		properties := payloadToMap(point.Payload)
+		properties[r.IdField] = id
+
+		object := &models.Object{
+			Class: r.Weaviate.ClassName,
+			// Weaviate only allows UUIDs as IDs.
+			// Numeric Qdrant IDs are converted to deterministic UUIDs.
+			ID:         strfmt.UUID(arbitraryIDToUUID(id).GetUuid()),
+			Properties: properties,
+			Tenant:     r.Weaviate.Tenant,
+		}
+
+		if vector, ok := dense[defaultVectorName]; ok && len(dense) == 1 {
+			object.Vector = vector
+		} else if len(dense) > 0 {
+			object.Vectors = make(models.Vectors, len(dense))
+			for name, vector := range dense {
+				object.Vectors[name] = vector
+			}
+		}
+
+		batcher = batcher.WithObjects(object)
+	}
+
+	writeStart := time.Now()
+	res, err := batcher.Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to insert objects into Weaviate: %w", err)
+	}
+	currentReport.observeWrite(time.Since(writeStart))
+
+	for _, obj := range res {
+		if obj.Result != nil && obj.Result.Errors != nil && len(obj.Result.Errors.Error) > 0 {
+			return errors.New(obj.Result.Errors.Error[0].Message)
+		}
+	}
+	currentReport.addWritten(len(points))
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// offsetStore keeps the scroll offsets of a migration out of Qdrant in its target, so the source is only read.
+type offsetStore interface {
+	getStartOffset(ctx context.Context, key string) (*qdrant.PointId, uint64, error)
+	storeStartOffset(ctx context.Context, key string, offset *qdrant.PointId, offsetCount uint64) error
+}
+
+// runOffsetStore keeps the offsets of targets that have no place for them only for the run. A failed run prints
+// them in its resume token, and continues from them when it's run again with --resume-token.
+type runOffsetStore struct{}
+
+func (runOffsetStore) getStartOffset(_ context.Context, key string) (*qdrant.PointId, uint64, error) {
+	offset, count, _ := commons.GetResumeOffset(key)
+	return offset, count, nil
+}
+
+func (runOffsetStore) storeStartOffset(_ context.Context, key string, offset *qdrant.PointId, offsetCount uint64) error {
+	if offset == nil {
+		return nil
+	}
+	commons.RecordCheckpoint(key, offset, offsetCount)
+	return nil
+}
+
+// pgOffsetStore keeps offsets in a table of a Postgres target, named like the offsets collection.
+type pgOffsetStore struct {
+	conn  *pgx.Conn
+	table string
+}
+
+func newPGOffsetStore(ctx context.Context, conn *pgx.Conn, table string) (*pgOffsetStore, error) {
+	_, err := conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ("key" TEXT PRIMARY KEY, "offset" TEXT NOT NULL, "offset_count" BIGINT NOT NULL, "last_upsert_at" TIMESTAMPTZ NOT NULL DEFAULT now())`,
+		pgx.Identifier{table}.Sanitize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create offsets table: %w", err)
+	}
+	return &pgOffsetStore{conn: conn, table: table}, nil
+}
+
+func (s *pgOffsetStore) getStartOffset(ctx context.Context, key string) (*qdrant.PointId, uint64, error) {
+	if offset, count, ok := commons.GetResumeOffset(key); ok {
+		return offset, count, nil
+	}
+
+	var offset string
+	var count int64
+	err := s.conn.QueryRow(ctx, fmt.Sprintf(`SELECT "offset", "offset_count" FROM %s WHERE "key" = $1`, pgx.Identifier{s.table}.Sanitize()), commons.OffsetKey(key)).Scan(&offset, &count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get start offset: %w", err)
+	}
+
+	id, err := parseOffsetID(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	commons.RecordCheckpoint(key, id, uint64(count))
+	return id, uint64(count), nil
+}
+
+func (s *pgOffsetStore) storeStartOffset(ctx context.Context, key string, offset *qdrant.PointId, offsetCount uint64) error {
+	if offset == nil {
+		return nil
+	}
+	_, err := s.conn.Exec(ctx, fmt.Sprintf(`INSERT INTO %s ("key", "offset", "offset_count", "last_upsert_at") VALUES ($1, $2, $3, now())
+		ON CONFLICT ("key") DO UPDATE SET "offset" = EXCLUDED."offset", "offset_count" = EXCLUDED."offset_count", "last_upsert_at" = EXCLUDED."last_upsert_at"`,
+		pgx.Identifier{s.table}.Sanitize()), commons.OffsetKey(key), pointIDToString(offset), int64(offsetCount))
+	if err != nil {
+		return fmt.Errorf("failed to store offset: %w", err)
+	}
+	commons.RecordCheckpoint(key, offset, offsetCount)
+	return nil
+}
+
+// parseOffsetID parses a point ID stored as text by pointIDToString.
+func parseOffsetID(offset string) (*qdrant.PointId, error) {
+	if _, err := uuid.Parse(offset); err == nil {
+		return qdrant.NewIDUUID(offset), nil
+	}
+	num, err := strconv.ParseUint(offset, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored offset %q", offset)
+	}
+	return qdrant.NewIDNum(num), nil
+}
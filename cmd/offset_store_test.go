@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_parseOffsetID(t *testing.T) {
+	for _, id := range []*qdrant.PointId{qdrant.NewIDNum(42), qdrant.NewIDUUID("3fa85f64-5717-4562-b3fc-2c963f66afa6")} {
+		got, err := parseOffsetID(pointIDToString(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != id.String() {
+			t.Errorf("parseOffsetID(%s) = %v, want %v", pointIDToString(id), got, id)
+		}
+	}
+	if _, err := parseOffsetID("not an id"); err == nil {
+		t.Error("expected an error for an invalid offset")
+	}
+}
+
+func TestRunOffsetStore(t *testing.T) {
+	key := "source->pinecone:test-run-offset-store"
+	store := runOffsetStore{}
+
+	offset, count, err := store.getStartOffset(context.Background(), key)
+	if err != nil || offset != nil || count != 0 {
+		t.Fatalf("getStartOffset() = %v, %d, %v, want no offset", offset, count, err)
+	}
+
+	commons.SetResumeCheckpoints([]commons.Checkpoint{{Key: key, Offset: qdrant.NewIDNum(7), Count: 100}})
+	offset, count, err = store.getStartOffset(context.Background(), key)
+	if err != nil || offset.GetNum() != 7 || count != 100 {
+		t.Fatalf("getStartOffset() = %v, %d, %v, want the resume checkpoint", offset, count, err)
+	}
+
+	err = store.storeStartOffset(context.Background(), key, qdrant.NewIDNum(9), 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, checkpoint := range commons.Checkpoints() {
+		if checkpoint.Key == key && (checkpoint.Offset.GetNum() != 9 || checkpoint.Count != 150) {
+			t.Errorf("recorded checkpoint %+v, want offset 9 and count 150", checkpoint)
+		}
+	}
+}
+
+func TestOffsetKeyScope(t *testing.T) {
+	key := "source->pinecone:test-offset-key-scope"
+	store := runOffsetStore{}
+	defer commons.SetOffsetKeyScope("")
+
+	commons.SetOffsetKeyScope("/tenants=a")
+	err := store.storeStartOffset(context.Background(), key, qdrant.NewIDNum(3), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commons.SetResumeCheckpoints(commons.Checkpoints())
+
+	commons.SetOffsetKeyScope("/tenants=b")
+	offset, _, err := store.getStartOffset(context.Background(), key)
+	if err != nil || offset != nil {
+		t.Fatalf("getStartOffset() = %v, %v, want no offset for other tenants", offset, err)
+	}
+
+	commons.SetOffsetKeyScope("/tenants=a")
+	offset, count, err := store.getStartOffset(context.Background(), key)
+	if err != nil || offset.GetNum() != 3 || count != 30 {
+		t.Fatalf("getStartOffset() = %v, %d, %v, want the checkpoint of the same tenants", offset, count, err)
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Interval between checks whether the optimizations of a target collection are done.
+var optimizationPollInterval = 5 * time.Second
+
+// waitForOptimizations waits until a target collection is green, i.e. its indexes are built and no optimizations are running
+// or pending, with --migration.wait-for-indexing, so traffic isn't cut over to a collection that is still slow to search.
+// Optimizations start shortly after writes, so the collection has to be green on two checks in a row.
+// Pending optimizations that weren't triggered, as after a restart of the target, are triggered. The migration fails
+// if the collection isn't green after --migration.indexing-timeout, or if an optimization failed.
+func waitForOptimizations(ctx context.Context, client *qdrant.Client, collection string, migration commons.MigrationConfig) error {
+	if !migration.WaitForIndexing {
+		return nil
+	}
+
+	deadline := time.Now().Add(migration.IndexingTimeout)
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Waiting for collection '%s' to be indexed", collection))
+	green := 0
+	triggered := false
+	for {
+		info, err := client.GetCollectionInfo(ctx, collection)
+		if err != nil {
+			_ = spinner.Stop()
+			return fmt.Errorf("failed to get target collection information: %w", err)
+		}
+
+		switch info.GetStatus() {
+		case qdrant.CollectionStatus_Green:
+			green++
+		case qdrant.CollectionStatus_Red:
+			spinner.Fail(fmt.Sprintf("Optimizations of collection '%s' failed", collection))
+			return fmt.Errorf("optimizations of target collection '%s' failed: %s", collection, info.GetOptimizerStatus().GetError())
+		case qdrant.CollectionStatus_Grey:
+			green = 0
+			if !triggered {
+				err = triggerOptimizations(ctx, client, collection)
+				if err != nil {
+					_ = spinner.Stop()
+					return err
+				}
+				triggered = true
+			}
+		default:
+			green = 0
+		}
+		if green >= 2 {
+			spinner.Success(fmt.Sprintf("Collection '%s' is indexed: %s", collection, indexingProgress(info)))
+			return nil
+		}
+
+		spinner.UpdateText(fmt.Sprintf("Waiting for collection '%s' to be indexed: %s", collection, indexingProgress(info)))
+		if time.Now().After(deadline) {
+			spinner.Fail(fmt.Sprintf("Collection '%s' is still not indexed after %s", collection, migration.IndexingTimeout))
+			return fmt.Errorf("target collection '%s' is still %s after %s of waiting for optimizations", collection, strings.ToLower(info.GetStatus().String()), migration.IndexingTimeout)
+		}
+
+		select {
+		case <-time.After(optimizationPollInterval):
+		case <-ctx.Done():
+			_ = spinner.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// triggerOptimizations starts the pending optimizations of a grey collection with an update of the collection that changes nothing.
+func triggerOptimizations(ctx context.Context, client *qdrant.Client, collection string) error {
+	err := client.UpdateCollection(ctx, &qdrant.UpdateCollection{
+		CollectionName:   collection,
+		OptimizersConfig: &qdrant.OptimizersConfigDiff{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to trigger optimizations of target collection '%s': %w", collection, err)
+	}
+	return nil
+}
+
+// indexingProgress describes the state of the optimizations of a collection.
+func indexingProgress(info *qdrant.CollectionInfo) string {
+	return fmt.Sprintf("%s, %d point(s) in %d segment(s), %d vector(s) indexed", strings.ToLower(info.GetStatus().String()),
+		info.GetPointsCount(), info.GetSegmentsCount(), info.GetIndexedVectorsCount())
+}
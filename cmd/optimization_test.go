@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_indexingProgress(t *testing.T) {
+	info := &qdrant.CollectionInfo{
+		Status:              qdrant.CollectionStatus_Yellow,
+		PointsCount:         qdrant.PtrOf(uint64(1000)),
+		SegmentsCount:       4,
+		IndexedVectorsCount: qdrant.PtrOf(uint64(600)),
+	}
+	want := "yellow, 1000 point(s) in 4 segment(s), 600 vector(s) indexed"
+	if got := indexingProgress(info); got != want {
+		t.Errorf("indexingProgress() = %q, want %q", got, want)
+	}
+}
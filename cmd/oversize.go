@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Fields that were already warned about, so every oversized field is only reported once per run.
+var oversizeWarned sync.Map
+
+// deadLetterLock serializes writes to the dead letter file, since streams write batches in parallel.
+var deadLetterLock sync.Mutex
+
+// guardPayloadSizes applies --migration.oversize-policy to payload values over --migration.max-payload-value-size,
+// so huge values, like raw documents, don't exceed the gRPC message size in the middle of a run.
+// It returns the points to write to the target.
+func guardPayloadSizes(points []*qdrant.PointStruct, migration commons.MigrationConfig) ([]*qdrant.PointStruct, error) {
+	limit := int(migration.MaxPayloadValueSize)
+	if limit <= 0 {
+		return points, nil
+	}
+
+	var truncated, dropped, deadLettered uint64
+	result := points[:0:0]
+	for _, point := range points {
+		oversized := ""
+		for key, value := range point.GetPayload() {
+			size := proto.Size(value)
+			if size <= limit {
+				continue
+			}
+			warnOversize(key, size, limit, migration.OversizePolicy)
+
+			switch migration.OversizePolicy {
+			case "dead-letter":
+				oversized = key
+			case "truncate":
+				if s, ok := value.GetKind().(*qdrant.Value_StringValue); ok {
+					point.Payload[key] = qdrant.NewValueString(truncateUTF8(s.StringValue, limit))
+					truncated++
+					continue
+				}
+				fallthrough
+			default:
+				delete(point.Payload, key)
+				dropped++
+			}
+		}
+
+		if oversized != "" {
+			err := writeDeadLetter(migration.DeadLetterFile, point, fmt.Sprintf("payload field '%s' is over %s", oversized, migration.MaxPayloadValueSize))
+			if err != nil {
+				return nil, err
+			}
+			deadLettered++
+			continue
+		}
+		result = append(result, point)
+	}
+
+	currentReport.addOversize(truncated, dropped, deadLettered)
+	return result, nil
+}
+
+func warnOversize(key string, size, limit int, policy string) {
+	if _, warned := oversizeWarned.LoadOrStore(key, true); warned {
+		return
+	}
+	pterm.Warning.Printfln("Payload field '%s' has a value of %s, over the limit of %s, applying the %s policy. Further values of it aren't reported.",
+		key, commons.ByteSize(size), commons.ByteSize(limit), policy)
+}
+
+// truncateUTF8 shortens s to at most n bytes, without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// writeDeadLetter appends a point to the dead letter file as a JSON line, together with the reason it wasn't written to the target.
+func writeDeadLetter(path string, point *qdrant.PointStruct, reason string) error {
+	pointJSON, err := protojson.Marshal(point)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter point: %w", err)
+	}
+	line, err := json.Marshal(struct {
+		Reason string          `json:"reason"`
+		Point  json.RawMessage `json:"point"`
+	}{reason, pointJSON})
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letter point: %w", err)
+	}
+
+	deadLetterLock.Lock()
+	defer deadLetterLock.Unlock()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write dead letter file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_guardPayloadSizes(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	list := make([]any, 50)
+	for i := range list {
+		list[i] = "item"
+	}
+	newPoints := func() []*qdrant.PointStruct {
+		return []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"text": long, "list": list, "title": "ok"})},
+			{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"title": "ok"})},
+		}
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		points, err := guardPayloadSizes(newPoints(), commons.MigrationConfig{MaxPayloadValueSize: 51, OversizePolicy: "truncate"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 2 {
+			t.Fatalf("got %d points, want 2", len(points))
+		}
+		if got := points[0].Payload["text"].GetStringValue(); got != strings.Repeat("é", 25) {
+			t.Errorf("text = %q, want 25 characters", got)
+		}
+		if _, ok := points[0].Payload["list"]; ok {
+			t.Errorf("list wasn't dropped")
+		}
+	})
+
+	t.Run("drop field", func(t *testing.T) {
+		points, err := guardPayloadSizes(newPoints(), commons.MigrationConfig{MaxPayloadValueSize: 51, OversizePolicy: "drop-field"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := points[0].Payload["text"]; ok {
+			t.Errorf("text wasn't dropped")
+		}
+		if got := points[0].Payload["title"].GetStringValue(); got != "ok" {
+			t.Errorf("title = %q, want it unchanged", got)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+		points, err := guardPayloadSizes(newPoints(), commons.MigrationConfig{MaxPayloadValueSize: 51, OversizePolicy: "dead-letter", DeadLetterFile: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 1 || points[0].Id.GetNum() != 2 {
+			t.Fatalf("got %v, want only point 2", points)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(string(data), "\n"); lines != 1 {
+			t.Errorf("dead letter file has %d lines, want 1", lines)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// sendPoints upserts the points of a request. With --migration.payload-only, it overwrites the payloads of the points
+// with the same IDs in the target instead, leaving their vectors as they are, and with --migration.vectors-only,
+// it updates their vectors, leaving their payloads as they are. Points the target doesn't have are skipped.
+// Writes are shaped to the strict mode of the target collection.
+func sendPoints(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints, migration commons.MigrationConfig) error {
+	return writeInStrictMode(ctx, client, request, func(request *qdrant.UpsertPoints) error {
+		switch {
+		case migration.PayloadOnly:
+			_, err := client.UpdateBatch(ctx, payloadUpdates(request))
+			return err
+		case migration.VectorsOnly:
+			return updateVectors(ctx, client, request)
+		default:
+			return upsertSplittingLargePoints(ctx, client, request)
+		}
+	})
+}
+
+// readSelectors returns what to read of the points of a Qdrant source. With --migration.payload-only, their vectors
+// aren't written, so they aren't read, and with --migration.vectors-only, only the payload fields that the vectors
+// or the shard keys of the points are derived from are read. Hashes of --hash-field cover the whole points, which
+// are read in full then.
+func readSelectors(migration commons.MigrationConfig, hashField string) (*qdrant.WithPayloadSelector, *qdrant.WithVectorsSelector) {
+	if hashField != "" {
+		return qdrant.NewWithPayload(true), qdrant.NewWithVectors(true)
+	}
+	switch {
+	case migration.PayloadOnly:
+		return qdrant.NewWithPayload(true), qdrant.NewWithVectors(false)
+	case migration.VectorsOnly:
+		var fields []string
+		for _, field := range []string{migration.Embed.Field, migration.Sparse.Field, migration.TenantField, migration.ExpiryField} {
+			if field != "" {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			return qdrant.NewWithPayload(false), qdrant.NewWithVectors(true)
+		}
+		return qdrant.NewWithPayloadInclude(fields...), qdrant.NewWithVectors(true)
+	default:
+		return qdrant.NewWithPayload(true), qdrant.NewWithVectors(true)
+	}
+}
+
+// payloadUpdates turns an upsert into a batch of payload overwrites, one for every point.
+// The points are selected by a filter on their ID, which, unlike a list of IDs, doesn't fail for missing points.
+func payloadUpdates(request *qdrant.UpsertPoints) *qdrant.UpdateBatchPoints {
+	operations := make([]*qdrant.PointsUpdateOperation, 0, len(request.GetPoints()))
+	for _, point := range request.GetPoints() {
+		operations = append(operations, &qdrant.PointsUpdateOperation{
+			Operation: &qdrant.PointsUpdateOperation_OverwritePayload_{
+				OverwritePayload: &qdrant.PointsUpdateOperation_OverwritePayload{
+					Payload: point.GetPayload(),
+					PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+						Must: []*qdrant.Condition{qdrant.NewHasID(point.GetId())},
+					}),
+					ShardKeySelector: request.GetShardKeySelector(),
+				},
+			},
+		})
+	}
+
+	return &qdrant.UpdateBatchPoints{
+		CollectionName: request.GetCollectionName(),
+		Wait:           request.Wait,
+		Operations:     operations,
+		Ordering:       request.GetOrdering(),
+	}
+}
+
+// updateVectors updates the vectors of the points of a request that the target has.
+// Qdrant fails the whole update if a point is missing, so the existing IDs are looked up first.
+func updateVectors(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints) error {
+	ids := make([]*qdrant.PointId, 0, len(request.GetPoints()))
+	for _, point := range request.GetPoints() {
+		ids = append(ids, point.GetId())
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	existing, err := client.Get(ctx, &qdrant.GetPoints{
+		CollectionName:   request.GetCollectionName(),
+		Ids:              ids,
+		WithPayload:      qdrant.NewWithPayload(false),
+		WithVectors:      qdrant.NewWithVectors(false),
+		ShardKeySelector: request.GetShardKeySelector(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get existing points from target: %w", err)
+	}
+	found := make(map[string]bool, len(existing))
+	for _, point := range existing {
+		found[pointIDToString(point.GetId())] = true
+	}
+
+	update := vectorUpdates(request, found)
+	if len(update.GetPoints()) == 0 {
+		return nil
+	}
+	_, err = client.UpdateVectors(ctx, update)
+	return err
+}
+
+// vectorUpdates turns an upsert into an update of the vectors of the points with the given IDs.
+// Points without vectors are left out, as there's nothing to update.
+func vectorUpdates(request *qdrant.UpsertPoints, ids map[string]bool) *qdrant.UpdatePointVectors {
+	points := make([]*qdrant.PointVectors, 0, len(request.GetPoints()))
+	for _, point := range request.GetPoints() {
+		if !ids[pointIDToString(point.GetId())] || point.GetVectors() == nil {
+			continue
+		}
+		points = append(points, &qdrant.PointVectors{Id: point.GetId(), Vectors: point.GetVectors()})
+	}
+
+	return &qdrant.UpdatePointVectors{
+		CollectionName:   request.GetCollectionName(),
+		Wait:             request.Wait,
+		Points:           points,
+		Ordering:         request.GetOrdering(),
+		ShardKeySelector: request.GetShardKeySelector(),
+	}
+}
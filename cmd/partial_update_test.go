@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestPayloadUpdates(t *testing.T) {
+	request := &qdrant.UpsertPoints{
+		CollectionName: "target",
+		Points: []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Vectors: qdrant.NewVectors(1, 2), Payload: qdrant.NewValueMap(map[string]any{"a": 1})},
+			{Id: qdrant.NewID("3fa85f64-5717-4562-b3fc-2c963f66afa6"), Payload: qdrant.NewValueMap(map[string]any{"b": "x"})},
+		},
+		ShardKeySelector: &qdrant.ShardKeySelector{ShardKeys: []*qdrant.ShardKey{qdrant.NewShardKey("acme")}},
+	}
+
+	batch := payloadUpdates(request)
+	if batch.GetCollectionName() != "target" {
+		t.Errorf("collection = %q, want %q", batch.GetCollectionName(), "target")
+	}
+	if len(batch.GetOperations()) != 2 {
+		t.Fatalf("got %d operations, want 2", len(batch.GetOperations()))
+	}
+
+	for i, operation := range batch.GetOperations() {
+		overwrite := operation.GetOverwritePayload()
+		if overwrite == nil {
+			t.Fatalf("operation %d isn't a payload overwrite", i)
+		}
+		point := request.GetPoints()[i]
+		if got := overwrite.GetPayload(); len(got) != len(point.GetPayload()) {
+			t.Errorf("operation %d has payload %v, want %v", i, got, point.GetPayload())
+		}
+		conditions := overwrite.GetPointsSelector().GetFilter().GetMust()
+		if len(conditions) != 1 || conditions[0].GetHasId().GetHasId()[0].String() != point.GetId().String() {
+			t.Errorf("operation %d selects %v, want the ID %v", i, conditions, point.GetId())
+		}
+		if overwrite.GetShardKeySelector() != request.GetShardKeySelector() {
+			t.Errorf("operation %d has shard key selector %v, want %v", i, overwrite.GetShardKeySelector(), request.GetShardKeySelector())
+		}
+	}
+}
+
+func TestVectorUpdates(t *testing.T) {
+	request := &qdrant.UpsertPoints{
+		CollectionName: "target",
+		Points: []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Vectors: qdrant.NewVectors(1, 2), Payload: qdrant.NewValueMap(map[string]any{"a": 1})},
+			{Id: qdrant.NewIDNum(2), Vectors: qdrant.NewVectors(3, 4)},
+			{Id: qdrant.NewIDNum(3)},
+		},
+	}
+
+	update := vectorUpdates(request, map[string]bool{"1": true, "3": true})
+	if update.GetCollectionName() != "target" {
+		t.Errorf("collection = %q, want %q", update.GetCollectionName(), "target")
+	}
+	// Point 2 is missing in the target and point 3 has no vectors.
+	if len(update.GetPoints()) != 1 {
+		t.Fatalf("got %d points, want 1", len(update.GetPoints()))
+	}
+	point := update.GetPoints()[0]
+	if point.GetId().GetNum() != 1 {
+		t.Errorf("id = %v, want 1", point.GetId())
+	}
+	if got := point.GetVectors().GetVector().GetData(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("vector = %v, want [1 2]", got)
+	}
+}
+
+func TestReadSelectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		migration     commons.MigrationConfig
+		hashField     string
+		wantPayload   bool
+		wantFields    []string
+		wantNoVectors bool
+	}{
+		{name: "full points", wantPayload: true},
+		{name: "payload only", migration: commons.MigrationConfig{PayloadOnly: true}, wantPayload: true, wantNoVectors: true},
+		{name: "vectors only", migration: commons.MigrationConfig{VectorsOnly: true}},
+		{
+			name:       "vectors only, re-embedded",
+			migration:  commons.MigrationConfig{VectorsOnly: true, TenantField: "tenant", Embed: commons.EmbeddingConfig{Field: "text"}},
+			wantFields: []string{"text", "tenant"},
+		},
+		{name: "payload only, hashed", migration: commons.MigrationConfig{PayloadOnly: true}, hashField: "hash", wantPayload: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withPayload, withVectors := readSelectors(tt.migration, tt.hashField)
+			if got := withPayload.GetEnable(); got != tt.wantPayload {
+				t.Errorf("got payload enabled %v, want %v", got, tt.wantPayload)
+			}
+			if got := withPayload.GetInclude().GetFields(); !slices.Equal(got, tt.wantFields) {
+				t.Errorf("got payload fields %v, want %v", got, tt.wantFields)
+			}
+			if got := withVectors.GetEnable(); got == tt.wantNoVectors {
+				t.Errorf("got vectors enabled %v, want %v", got, !tt.wantNoVectors)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc"
+)
+
+// pauseGate holds back new calls to Qdrant while a migration is paused.
+// Calls that are already in flight finish, so pausing lets the current batches drain before the migration idles.
+type pauseGate struct {
+	lock    sync.Mutex
+	paused  bool
+	resumed chan struct{}
+}
+
+func (g *pauseGate) pause() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.paused {
+		return false
+	}
+	g.paused = true
+	g.resumed = make(chan struct{})
+	return true
+}
+
+func (g *pauseGate) resume() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if !g.paused {
+		return false
+	}
+	g.paused = false
+	close(g.resumed)
+	return true
+}
+
+func (g *pauseGate) isPaused() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.paused
+}
+
+// wait blocks while the gate is paused, or until the context is done.
+func (g *pauseGate) wait(ctx context.Context) error {
+	g.lock.Lock()
+	if !g.paused {
+		g.lock.Unlock()
+		return nil
+	}
+	resumed := g.resumed
+	g.lock.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pauseInterceptor waits with every call to the points of a collection, i.e. every read and write of a migration, while paused.
+func pauseInterceptor(gate *pauseGate) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if strings.HasPrefix(method, "/qdrant.Points/") {
+			err := gate.wait(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// watchPauseKey toggles the gate every time Enter is pressed, if the input is a terminal.
+func watchPauseKey(gate *pauseGate) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if gate.isPaused() {
+				gate.resume()
+				pterm.Info.Println("Resumed the migration")
+			} else {
+				gate.pause()
+				pterm.Info.Println("Pausing the migration once the current batches are written. Press Enter to resume.")
+			}
+		}
+	}()
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_pauseGate(t *testing.T) {
+	gate := &pauseGate{}
+
+	if err := gate.wait(context.Background()); err != nil {
+		t.Fatalf("wait() on a gate that isn't paused = %v", err)
+	}
+
+	if !gate.pause() || gate.pause() {
+		t.Fatal("pause() should only report a change the first time")
+	}
+
+	waited := make(chan error)
+	go func() {
+		waited <- gate.wait(context.Background())
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("wait() returned while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	gate.resume()
+	if err := <-waited; err != nil {
+		t.Fatalf("wait() after resume() = %v", err)
+	}
+
+	gate.pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := gate.wait(ctx); err == nil {
+		t.Fatal("wait() with a cancelled context should fail while paused")
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Keys that were already reported as renamed or kept, so every key is only reported once per run.
+var keyRenameWarned sync.Map
+
+// renamePayloadKeys applies the rename directives of the mapping file. The field keeps its place, only its key changes.
+func renamePayloadKeys(points []*qdrant.PointStruct, mapping commons.PayloadMapping) {
+	for name, field := range mapping.Fields {
+		if field.Rename == "" {
+			continue
+		}
+		for _, point := range points {
+			renamePayloadField(point.GetPayload(), name, field.Rename)
+		}
+	}
+}
+
+// renamePayloadField changes the key of a field, addressed by its path, unless a field of the new name exists.
+func renamePayloadField(payload map[string]*qdrant.Value, path, key string) {
+	if value, ok := payload[path]; ok {
+		if _, exists := payload[key]; exists {
+			warnKeyCollision(path, key)
+			return
+		}
+		payload[key] = value
+		delete(payload, path)
+		return
+	}
+	parent, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return
+	}
+	if fields := payload[parent].GetStructValue().GetFields(); fields != nil {
+		renamePayloadField(fields, rest, key)
+	}
+}
+
+// sanitizePayloadKeys renames the payload keys that are hard to filter on or index, per --migration.payload-keys.
+func sanitizePayloadKeys(points []*qdrant.PointStruct, migration commons.MigrationConfig) {
+	if migration.PayloadKeys != "sanitize" {
+		return
+	}
+	// Flattened keys are joined by the separator on purpose.
+	keep := ""
+	if migration.NestedPayload == "flatten" {
+		keep = migration.NestedPayloadSeparator
+	}
+	for _, point := range points {
+		sanitizeFields(point.GetPayload(), "", keep, migration.PayloadKeysReplacement)
+	}
+}
+
+func sanitizeFields(fields map[string]*qdrant.Value, prefix, keep, replacement string) {
+	// The keys are renamed in place, so they're collected first.
+	for _, key := range slices.Collect(maps.Keys(fields)) {
+		value := fields[key]
+		path := prefix + key
+		sanitizeValueKeys(value, path, keep, replacement)
+
+		sanitized := sanitizeKey(key, keep, replacement)
+		if sanitized == key {
+			continue
+		}
+		if _, exists := fields[sanitized]; exists {
+			warnKeyCollision(path, sanitized)
+			continue
+		}
+		fields[sanitized] = value
+		delete(fields, key)
+		if _, warned := keyRenameWarned.LoadOrStore(path, true); !warned {
+			pterm.Info.Printfln("Renamed payload key '%s' to '%s'", path, prefix+sanitized)
+		}
+		currentReport.addRenamedKey(path, prefix+sanitized)
+	}
+}
+
+// sanitizeValueKeys sanitizes the keys of nested objects, also in lists. Geo points have no keys to sanitize.
+func sanitizeValueKeys(value *qdrant.Value, path, keep, replacement string) {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_StructValue:
+		if !isGeoPoint(value) {
+			sanitizeFields(kind.StructValue.GetFields(), path+".", keep, replacement)
+		}
+	case *qdrant.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			sanitizeValueKeys(item, path+"[]", keep, replacement)
+		}
+	}
+}
+
+// sanitizeKey normalizes a key to Unicode NFC, removes leading dollar signs, which are reserved by Mongo and JSON paths,
+// and replaces every run of dots, brackets, quotes, whitespace and control characters with the replacement.
+// Characters of keep are left as they are. Keys that would become empty are kept.
+func sanitizeKey(key, keep, replacement string) string {
+	normalized := strings.TrimLeft(norm.NFC.String(key), "$")
+
+	var sanitized strings.Builder
+	replaced := false
+	for _, r := range normalized {
+		problematic := strings.ContainsRune(".[]\"", r) || unicode.IsSpace(r) || unicode.IsControl(r)
+		if !problematic || (keep != "" && strings.ContainsRune(keep, r)) {
+			sanitized.WriteRune(r)
+			replaced = false
+			continue
+		}
+		if !replaced {
+			sanitized.WriteString(replacement)
+			replaced = true
+		}
+	}
+	if sanitized.Len() == 0 {
+		return key
+	}
+	return sanitized.String()
+}
+
+func warnKeyCollision(path, key string) {
+	if _, warned := keyRenameWarned.LoadOrStore(path, true); warned {
+		return
+	}
+	pterm.Warning.Printfln("Payload key '%s' isn't renamed to '%s', since a field of that name exists. Further values of it aren't reported.", path, key)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_sanitizeKey(t *testing.T) {
+	tests := []struct {
+		key      string
+		keep     string
+		expected string
+	}{
+		{key: "title", expected: "title"},
+		{key: "first name", expected: "first_name"},
+		{key: "meta.author", expected: "meta_author"},
+		{key: "meta.author", keep: ".", expected: "meta.author"},
+		{key: "$oid", expected: "oid"},
+		{key: "tags[0]", expected: "tags_0_"},
+		{key: "a \t\n b", expected: "a_b"},
+		{key: "café", expected: "café"},
+		{key: "$", expected: "$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := sanitizeKey(tt.key, tt.keep, "_"); got != tt.expected {
+				t.Errorf("sanitizeKey() got = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_sanitizePayloadKeys(t *testing.T) {
+	points := []*qdrant.PointStruct{{
+		Id: qdrant.NewIDNum(1),
+		Payload: qdrant.NewValueMap(map[string]any{
+			"first name": "Ada",
+			"first_name": "Grace",
+			"meta":       map[string]any{"$date": "2024-05-01", "page count": 3},
+			"items":      []any{map[string]any{"unit price": 1.5}},
+		}),
+	}}
+	sanitizePayloadKeys(points, commons.MigrationConfig{PayloadKeys: "sanitize", PayloadKeysReplacement: "_"})
+
+	payload := points[0].GetPayload()
+	if payload["first name"].GetStringValue() != "Ada" || payload["first_name"].GetStringValue() != "Grace" {
+		t.Errorf("colliding key was renamed: %v", payload)
+	}
+	meta := payload["meta"].GetStructValue().GetFields()
+	if _, ok := meta["date"]; !ok {
+		t.Errorf("meta.$date wasn't renamed: %v", meta)
+	}
+	if _, ok := meta["page_count"]; !ok {
+		t.Errorf("meta.page count wasn't renamed: %v", meta)
+	}
+	item := payload["items"].GetListValue().GetValues()[0].GetStructValue().GetFields()
+	if _, ok := item["unit_price"]; !ok {
+		t.Errorf("items[].unit price wasn't renamed: %v", item)
+	}
+}
+
+func Test_renamePayloadKeys(t *testing.T) {
+	points := []*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"Page Count": 3}})},
+		{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"title": "x"})},
+	}
+	renamePayloadKeys(points, commons.PayloadMapping{Fields: map[string]commons.FieldMapping{"meta.Page Count": {Rename: "pages"}}})
+
+	meta := points[0].GetPayload()["meta"].GetStructValue().GetFields()
+	if meta["pages"].GetIntegerValue() != 3 || len(meta) != 1 {
+		t.Errorf("meta = %v, want only pages", meta)
+	}
+	if len(points[1].GetPayload()) != 1 {
+		t.Errorf("payload of point 2 changed: %v", points[1].GetPayload())
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// reshapePayloads flattens or expands the nested fields of the payloads of points, per --migration.nested-payload.
+func reshapePayloads(points []*qdrant.PointStruct, migration commons.MigrationConfig) {
+	separator := migration.NestedPayloadSeparator
+	if separator == "" {
+		return
+	}
+
+	for _, point := range points {
+		switch migration.NestedPayload {
+		case "flatten":
+			point.Payload = flattenPayload(point.GetPayload(), separator)
+		case "expand":
+			point.Payload = expandPayload(point.GetPayload(), separator)
+		}
+	}
+}
+
+// flattenPayload turns nested objects into keys joined by the separator, e.g. {"meta": {"author": "x"}} into {"meta.author": "x"}.
+// Geo points stay objects, so they can still be filtered on. Lists are kept as they are.
+func flattenPayload(payload map[string]*qdrant.Value, separator string) map[string]*qdrant.Value {
+	if payload == nil {
+		return nil
+	}
+	flat := make(map[string]*qdrant.Value, len(payload))
+	var flatten func(prefix string, fields map[string]*qdrant.Value)
+	flatten = func(prefix string, fields map[string]*qdrant.Value) {
+		for key, value := range fields {
+			nested := value.GetStructValue()
+			if nested != nil && len(nested.GetFields()) > 0 && !isGeoPoint(value) {
+				flatten(prefix+key+separator, nested.GetFields())
+				continue
+			}
+			flat[prefix+key] = value
+		}
+	}
+	flatten("", payload)
+	return flat
+}
+
+// expandPayload turns keys containing the separator into nested objects, e.g. {"meta.author": "x"} into {"meta": {"author": "x"}}.
+// Keys that would replace a value that isn't an object are kept as they are.
+func expandPayload(payload map[string]*qdrant.Value, separator string) map[string]*qdrant.Value {
+	if payload == nil {
+		return nil
+	}
+	expanded := make(map[string]*qdrant.Value, len(payload))
+	var dotted []string
+	for key, value := range payload {
+		if strings.Contains(key, separator) {
+			dotted = append(dotted, key)
+			continue
+		}
+		expanded[key] = value
+	}
+
+	// Sorted, so conflicting keys are resolved the same way for every point.
+	sort.Strings(dotted)
+	for _, key := range dotted {
+		parts := strings.Split(key, separator)
+		fields := expanded
+		for _, part := range parts[:len(parts)-1] {
+			existing, ok := fields[part]
+			if !ok {
+				existing = qdrant.NewValueStruct(&qdrant.Struct{Fields: map[string]*qdrant.Value{}})
+				fields[part] = existing
+			}
+			fields = existing.GetStructValue().GetFields()
+			if fields == nil {
+				break
+			}
+		}
+		last := parts[len(parts)-1]
+		if _, taken := fields[last]; fields == nil || taken {
+			expanded[key] = payload[key]
+			continue
+		}
+		fields[last] = payload[key]
+	}
+
+	return expanded
+}
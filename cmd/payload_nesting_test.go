@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_flattenPayload(t *testing.T) {
+	payload := qdrant.NewValueMap(map[string]any{
+		"title":    "Post",
+		"meta":     map[string]any{"author": map[string]any{"name": "Ann"}, "tags": []any{"a", "b"}},
+		"location": map[string]any{"lat": 52.52, "lon": 13.4},
+		"empty":    map[string]any{},
+	})
+	want := qdrant.NewValueMap(map[string]any{
+		"title":            "Post",
+		"meta.author.name": "Ann",
+		"meta.tags":        []any{"a", "b"},
+		"location":         map[string]any{"lat": 52.52, "lon": 13.4},
+		"empty":            map[string]any{},
+	})
+
+	got := flattenPayload(payload, ".")
+	if !proto.Equal(&qdrant.Struct{Fields: got}, &qdrant.Struct{Fields: want}) {
+		t.Errorf("flattenPayload() = %v, want %v", got, want)
+	}
+}
+
+func Test_expandPayload(t *testing.T) {
+	payload := qdrant.NewValueMap(map[string]any{
+		"title":            "Post",
+		"meta.author.name": "Ann",
+		"meta.author.age":  30,
+		"meta":             map[string]any{"lang": "en"},
+		"count":            1,
+		"count.total":      2,
+	})
+	want := qdrant.NewValueMap(map[string]any{
+		"title":       "Post",
+		"meta":        map[string]any{"lang": "en", "author": map[string]any{"name": "Ann", "age": 30}},
+		"count":       1,
+		"count.total": 2,
+	})
+
+	got := expandPayload(payload, ".")
+	if !proto.Equal(&qdrant.Struct{Fields: got}, &qdrant.Struct{Fields: want}) {
+		t.Errorf("expandPayload() = %v, want %v", got, want)
+	}
+}
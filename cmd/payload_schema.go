@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Strings at least this long and containing whitespace are considered full text rather than keywords.
+const minTextLength = 32
+
+// payloadSchema collects the types seen for every payload field, keyed by the path Qdrant indexes the field with.
+type payloadSchema map[string]map[qdrant.FieldType]bool
+
+func (s payloadSchema) observe(path string, value *qdrant.Value) {
+	fieldType, ok := inferFieldType(value)
+	if ok {
+		if s[path] == nil {
+			s[path] = make(map[qdrant.FieldType]bool)
+		}
+		s[path][fieldType] = true
+		return
+	}
+
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_StructValue:
+		for key, nested := range kind.StructValue.GetFields() {
+			s.observe(path+"."+key, nested)
+		}
+	case *qdrant.Value_ListValue:
+		for _, item := range kind.ListValue.GetValues() {
+			// Arrays of values are indexed under the name of the field, arrays of objects by their keys.
+			if _, isStruct := item.GetKind().(*qdrant.Value_StructValue); isStruct && !isGeoPoint(item) {
+				s.observe(path+"[]", item)
+			} else {
+				s.observe(path, item)
+			}
+		}
+	}
+}
+
+// fields returns the index type of every field that has values of compatible types only.
+func (s payloadSchema) fields() map[string]qdrant.FieldType {
+	result := make(map[string]qdrant.FieldType)
+	for path, types := range s {
+		if fieldType, ok := resolveFieldType(types); ok {
+			result[path] = fieldType
+		}
+	}
+	return result
+}
+
+// inferFieldType returns the index type of a single value, or false for objects, lists and nulls.
+func inferFieldType(value *qdrant.Value) (qdrant.FieldType, bool) {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_IntegerValue:
+		return qdrant.FieldType_FieldTypeInteger, true
+	case *qdrant.Value_DoubleValue:
+		return qdrant.FieldType_FieldTypeFloat, true
+	case *qdrant.Value_BoolValue:
+		return qdrant.FieldType_FieldTypeBool, true
+	case *qdrant.Value_StringValue:
+		return inferStringFieldType(kind.StringValue), true
+	case *qdrant.Value_StructValue:
+		if isGeoPoint(value) {
+			return qdrant.FieldType_FieldTypeGeo, true
+		}
+	}
+	return 0, false
+}
+
+func inferStringFieldType(s string) qdrant.FieldType {
+	if _, err := time.Parse(time.RFC3339, s); err == nil {
+		return qdrant.FieldType_FieldTypeDatetime
+	}
+	if _, err := time.Parse(time.DateOnly, s); err == nil {
+		return qdrant.FieldType_FieldTypeDatetime
+	}
+	if len(s) == 36 {
+		if _, err := uuid.Parse(s); err == nil {
+			return qdrant.FieldType_FieldTypeUuid
+		}
+	}
+	if len(s) >= minTextLength && strings.ContainsAny(s, " \t\n") {
+		return qdrant.FieldType_FieldTypeText
+	}
+	return qdrant.FieldType_FieldTypeKeyword
+}
+
+// isGeoPoint reports whether a value is an object with only numeric lat and lon fields, like Qdrant expects geo points.
+func isGeoPoint(value *qdrant.Value) bool {
+	fields := value.GetStructValue().GetFields()
+	if len(fields) != 2 {
+		return false
+	}
+	for _, key := range []string{"lat", "lon"} {
+		switch fields[key].GetKind().(type) {
+		case *qdrant.Value_DoubleValue, *qdrant.Value_IntegerValue:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// resolveFieldType picks one index type for the types seen in a field.
+// Integers mixed with floats are indexed as floats, and mixed kinds of strings fall back to keywords or text.
+// Fields that mix strings with other types aren't indexed.
+func resolveFieldType(types map[qdrant.FieldType]bool) (qdrant.FieldType, bool) {
+	if len(types) == 1 {
+		for fieldType := range types {
+			return fieldType, true
+		}
+	}
+
+	numeric := map[qdrant.FieldType]bool{qdrant.FieldType_FieldTypeInteger: true, qdrant.FieldType_FieldTypeFloat: true}
+	strs := map[qdrant.FieldType]bool{
+		qdrant.FieldType_FieldTypeKeyword:  true,
+		qdrant.FieldType_FieldTypeText:     true,
+		qdrant.FieldType_FieldTypeDatetime: true,
+		qdrant.FieldType_FieldTypeUuid:     true,
+	}
+
+	allIn := func(set map[qdrant.FieldType]bool) bool {
+		for fieldType := range types {
+			if !set[fieldType] {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case allIn(numeric):
+		return qdrant.FieldType_FieldTypeFloat, true
+	case allIn(strs) && types[qdrant.FieldType_FieldTypeText]:
+		return qdrant.FieldType_FieldTypeText, true
+	case allIn(strs):
+		return qdrant.FieldType_FieldTypeKeyword, true
+	default:
+		return 0, false
+	}
+}
+
+// createPayloadIndexes samples the payloads in the target collection, infers the types of their fields,
+// and creates an index for every field that doesn't have one yet, or only for the listed fields.
+// It's done once all points are written, since building the indexes at once is faster than updating them with every batch.
+func createPayloadIndexes(ctx context.Context, targetClient *qdrant.Client, collection string, migration commons.MigrationConfig) error {
+	if !migration.CreatePayloadIndexes && len(migration.PayloadIndexFields) == 0 {
+		return nil
+	}
+
+	limit := uint32(migration.PayloadIndexSampleSize)
+	resp, err := targetClient.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+		CollectionName: collection,
+		Limit:          &limit,
+		WithPayload:    qdrant.NewWithPayload(true),
+		WithVectors:    qdrant.NewWithVectors(false),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sample payloads from target: %w", err)
+	}
+
+	schema := make(payloadSchema)
+	for _, point := range resp.GetResult() {
+		for key, value := range point.GetPayload() {
+			schema.observe(key, value)
+		}
+	}
+	fields := schema.fields()
+	if len(migration.PayloadIndexFields) > 0 {
+		var unknown []string
+		fields, unknown = selectIndexFields(fields, migration.PayloadIndexFields)
+		for _, name := range unknown {
+			pterm.Warning.Printfln("No payload index created on '%s', the sampled points have no values of one type in it", name)
+		}
+	}
+
+	info, err := targetClient.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to get target collection information: %w", err)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if _, ok := info.GetPayloadSchema()[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, err = targetClient.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: collection,
+			FieldName:      name,
+			FieldType:      qdrant.PtrOf(fields[name]),
+			Wait:           qdrant.PtrOf(true),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create payload index on '%s': %w", name, err)
+		}
+		pterm.Info.Printfln("Created %s payload index on '%s'", fieldTypeName(fields[name]), name)
+	}
+
+	return nil
+}
+
+// selectIndexFields returns the listed fields of the inferred ones, and the listed fields that weren't inferred.
+func selectIndexFields(fields map[string]qdrant.FieldType, listed []string) (map[string]qdrant.FieldType, []string) {
+	selected := make(map[string]qdrant.FieldType, len(listed))
+	var unknown []string
+	for _, name := range listed {
+		fieldType, ok := fields[name]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		selected[name] = fieldType
+	}
+	return selected, unknown
+}
+
+func fieldTypeName(fieldType qdrant.FieldType) string {
+	return strings.ToLower(strings.TrimPrefix(fieldType.String(), "FieldType"))
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_payloadSchema(t *testing.T) {
+	payloads := []map[string]any{
+		{
+			"category":   "books",
+			"price":      10,
+			"in_stock":   true,
+			"created_at": "2024-05-01T10:00:00Z",
+			"id":         "5c56c793-69f3-4fbf-87e6-c4bf54c28c26",
+			"summary":    "A long description of the item that should be searched as text",
+			"location":   map[string]any{"lat": 52.52, "lon": 13.4},
+			"address":    map[string]any{"city": "Berlin"},
+			"tags":       []any{"new", "sale"},
+			"items":      []any{map[string]any{"sku": "a1"}},
+			"mixed":      "value",
+		},
+		{
+			"category": "music",
+			"price":    12.5,
+			"mixed":    3,
+		},
+	}
+
+	schema := make(payloadSchema)
+	for _, payload := range payloads {
+		for key, value := range qdrant.NewValueMap(payload) {
+			schema.observe(key, value)
+		}
+	}
+
+	want := map[string]qdrant.FieldType{
+		"category":     qdrant.FieldType_FieldTypeKeyword,
+		"price":        qdrant.FieldType_FieldTypeFloat,
+		"in_stock":     qdrant.FieldType_FieldTypeBool,
+		"created_at":   qdrant.FieldType_FieldTypeDatetime,
+		"id":           qdrant.FieldType_FieldTypeUuid,
+		"summary":      qdrant.FieldType_FieldTypeText,
+		"location":     qdrant.FieldType_FieldTypeGeo,
+		"address.city": qdrant.FieldType_FieldTypeKeyword,
+		"tags":         qdrant.FieldType_FieldTypeKeyword,
+		"items[].sku":  qdrant.FieldType_FieldTypeKeyword,
+	}
+	if got := schema.fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("fields() = %v, want %v", got, want)
+	}
+}
+
+func Test_resolveFieldType(t *testing.T) {
+	tests := []struct {
+		name   string
+		types  []qdrant.FieldType
+		want   qdrant.FieldType
+		wantOk bool
+	}{
+		{name: "single", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeBool}, want: qdrant.FieldType_FieldTypeBool, wantOk: true},
+		{name: "numbers", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeInteger, qdrant.FieldType_FieldTypeFloat}, want: qdrant.FieldType_FieldTypeFloat, wantOk: true},
+		{name: "keywords and dates", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeKeyword, qdrant.FieldType_FieldTypeDatetime}, want: qdrant.FieldType_FieldTypeKeyword, wantOk: true},
+		{name: "keywords and text", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeKeyword, qdrant.FieldType_FieldTypeText}, want: qdrant.FieldType_FieldTypeText, wantOk: true},
+		{name: "strings and numbers", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeKeyword, qdrant.FieldType_FieldTypeInteger}, wantOk: false},
+		{name: "bools and numbers", types: []qdrant.FieldType{qdrant.FieldType_FieldTypeBool, qdrant.FieldType_FieldTypeInteger}, wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			types := make(map[qdrant.FieldType]bool)
+			for _, fieldType := range tt.types {
+				types[fieldType] = true
+			}
+			got, ok := resolveFieldType(types)
+			if ok != tt.wantOk {
+				t.Fatalf("resolveFieldType() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("resolveFieldType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_selectIndexFields(t *testing.T) {
+	fields := map[string]qdrant.FieldType{
+		"city":        qdrant.FieldType_FieldTypeKeyword,
+		"price":       qdrant.FieldType_FieldTypeFloat,
+		"items[].sku": qdrant.FieldType_FieldTypeKeyword,
+	}
+
+	selected, unknown := selectIndexFields(fields, []string{"city", "items[].sku", "missing"})
+	want := map[string]qdrant.FieldType{
+		"city":        qdrant.FieldType_FieldTypeKeyword,
+		"items[].sku": qdrant.FieldType_FieldTypeKeyword,
+	}
+	if !reflect.DeepEqual(selected, want) {
+		t.Errorf("selectIndexFields() selected = %v, want %v", selected, want)
+	}
+	if !reflect.DeepEqual(unknown, []string{"missing"}) {
+		t.Errorf("selectIndexFields() unknown = %v, want [missing]", unknown)
+	}
+}
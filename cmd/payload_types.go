@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// payloadTypes tracks the types of the values of every payload field across the run, to warn about fields with conflicting types.
+var payloadTypes = payloadTypeTracker{types: make(map[string][]string)}
+
+// Fields whose values couldn't be coerced per the type directive of the mapping file, so every field is only reported once per run.
+var coercionWarned sync.Map
+
+type payloadTypeTracker struct {
+	lock  sync.Mutex
+	types map[string][]string
+}
+
+// coercePayloadTypes converts the values of the fields with a type directive into the type of the directive.
+func coercePayloadTypes(points []*qdrant.PointStruct, mapping commons.PayloadMapping) {
+	for name, field := range mapping.Fields {
+		if field.Type == "" {
+			continue
+		}
+		for _, point := range points {
+			value := payloadField(point.GetPayload(), name)
+			if value == nil {
+				continue
+			}
+			coerced, ok := coerceValue(value, field.Type)
+			if !ok {
+				if _, warned := coercionWarned.LoadOrStore(name, true); !warned {
+					pterm.Warning.Printfln("Payload field '%s' of point %s can't be converted to %s, leaving it as it is. Further values of it aren't reported.", name, pointIDToString(point.GetId()), field.Type)
+				}
+				continue
+			}
+			setPayloadField(point.GetPayload(), name, coerced)
+		}
+	}
+}
+
+// coerceValue converts a value, or the items of a list, into the given type. Nulls are kept.
+func coerceValue(value *qdrant.Value, target string) (*qdrant.Value, bool) {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_NullValue:
+		return value, true
+	case *qdrant.Value_ListValue:
+		items := make([]*qdrant.Value, 0, len(kind.ListValue.GetValues()))
+		for _, item := range kind.ListValue.GetValues() {
+			coerced, ok := coerceValue(item, target)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, coerced)
+		}
+		return qdrant.NewValueList(&qdrant.ListValue{Values: items}), true
+	case *qdrant.Value_StringValue:
+		s := strings.TrimSpace(kind.StringValue)
+		switch target {
+		case "string":
+			return value, true
+		case "integer":
+			if number, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return qdrant.NewValueInt(number), true
+			}
+			if number, err := strconv.ParseFloat(s, 64); err == nil {
+				return coerceValue(qdrant.NewValueDouble(number), target)
+			}
+		case "float":
+			if number, err := strconv.ParseFloat(s, 64); err == nil {
+				return qdrant.NewValueDouble(number), true
+			}
+		case "bool":
+			if b, err := strconv.ParseBool(s); err == nil {
+				return qdrant.NewValueBool(b), true
+			}
+		}
+	case *qdrant.Value_IntegerValue:
+		switch target {
+		case "string":
+			return qdrant.NewValueString(strconv.FormatInt(kind.IntegerValue, 10)), true
+		case "integer":
+			return value, true
+		case "float":
+			return qdrant.NewValueDouble(float64(kind.IntegerValue)), true
+		case "bool":
+			if kind.IntegerValue == 0 || kind.IntegerValue == 1 {
+				return qdrant.NewValueBool(kind.IntegerValue == 1), true
+			}
+		}
+	case *qdrant.Value_DoubleValue:
+		switch target {
+		case "string":
+			return qdrant.NewValueString(strconv.FormatFloat(kind.DoubleValue, 'f', -1, 64)), true
+		case "integer":
+			// Only whole numbers, so no precision is lost silently.
+			if kind.DoubleValue == math.Trunc(kind.DoubleValue) && math.Abs(kind.DoubleValue) < math.MaxInt64 {
+				return qdrant.NewValueInt(int64(kind.DoubleValue)), true
+			}
+		case "float":
+			return value, true
+		}
+	case *qdrant.Value_BoolValue:
+		switch target {
+		case "string":
+			return qdrant.NewValueString(strconv.FormatBool(kind.BoolValue)), true
+		case "bool":
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// trackPayloadTypes records the types of the payload fields of a batch, and warns once about every field
+// that has values of a type it didn't have before, since a payload index only covers values of its own type.
+func trackPayloadTypes(points []*qdrant.PointStruct) {
+	payloadTypes.lock.Lock()
+	defer payloadTypes.lock.Unlock()
+	for _, point := range points {
+		for key, value := range point.GetPayload() {
+			payloadTypes.observe(key, value, point.GetId())
+		}
+	}
+}
+
+func (t *payloadTypeTracker) observe(path string, value *qdrant.Value, id *qdrant.PointId) {
+	valueType := ""
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_StringValue:
+		valueType = "string"
+	case *qdrant.Value_IntegerValue, *qdrant.Value_DoubleValue:
+		// Integers and floats can be mixed in a float index, so they don't conflict.
+		valueType = "number"
+	case *qdrant.Value_BoolValue:
+		valueType = "bool"
+	case *qdrant.Value_StructValue:
+		if isGeoPoint(value) {
+			valueType = "geo point"
+			break
+		}
+		valueType = "object"
+		for key, nested := range kind.StructValue.GetFields() {
+			t.observe(path+"."+key, nested, id)
+		}
+	case *qdrant.Value_ListValue:
+		// Lists are indexed by their items.
+		for _, item := range kind.ListValue.GetValues() {
+			t.observe(path, item, id)
+		}
+		return
+	default:
+		return
+	}
+
+	known := t.types[path]
+	if slices.Contains(known, valueType) {
+		return
+	}
+	t.types[path] = append(known, valueType)
+	if len(known) == 0 {
+		return
+	}
+	if len(known) == 1 {
+		pterm.Warning.Printfln("Payload field '%s' of point %s is a %s, but was a %s before. A payload index covers only one type, so add a type directive for it to the mapping file. Further conflicts of it aren't reported.",
+			path, pointIDToString(id), valueType, known[0])
+	}
+	currentReport.setPayloadTypeConflict(path, t.types[path])
+}
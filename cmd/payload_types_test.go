@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_coerceValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *qdrant.Value
+		target   string
+		expected *qdrant.Value
+	}{
+		{name: "integer to string", value: qdrant.NewValueInt(42), target: "string", expected: qdrant.NewValueString("42")},
+		{name: "float to string", value: qdrant.NewValueDouble(1.5), target: "string", expected: qdrant.NewValueString("1.5")},
+		{name: "string to integer", value: qdrant.NewValueString(" 42 "), target: "integer", expected: qdrant.NewValueInt(42)},
+		{name: "whole float string to integer", value: qdrant.NewValueString("42.0"), target: "integer", expected: qdrant.NewValueInt(42)},
+		{name: "fraction to integer", value: qdrant.NewValueDouble(1.5), target: "integer"},
+		{name: "string to float", value: qdrant.NewValueString("1.5"), target: "float", expected: qdrant.NewValueDouble(1.5)},
+		{name: "string to bool", value: qdrant.NewValueString("true"), target: "bool", expected: qdrant.NewValueBool(true)},
+		{name: "integer to bool", value: qdrant.NewValueInt(0), target: "bool", expected: qdrant.NewValueBool(false)},
+		{name: "word to float", value: qdrant.NewValueString("n/a"), target: "float"},
+		{name: "null", value: qdrant.NewValueNull(), target: "integer", expected: qdrant.NewValueNull()},
+		{
+			name:     "list",
+			value:    qdrant.NewValueList(&qdrant.ListValue{Values: []*qdrant.Value{qdrant.NewValueInt(1), qdrant.NewValueString("2")}}),
+			target:   "string",
+			expected: qdrant.NewValueList(&qdrant.ListValue{Values: []*qdrant.Value{qdrant.NewValueString("1"), qdrant.NewValueString("2")}}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := coerceValue(tt.value, tt.target)
+			if ok != (tt.expected != nil) {
+				t.Fatalf("coerceValue() ok = %v, expected %v", ok, tt.expected != nil)
+			}
+			if ok && !proto.Equal(got, tt.expected) {
+				t.Errorf("coerceValue() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_coercePayloadTypes(t *testing.T) {
+	points := []*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"zip": 10115}})},
+		{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"zip": "W1A"}})},
+	}
+	coercePayloadTypes(points, commons.PayloadMapping{Fields: map[string]commons.FieldMapping{"meta.zip": {Type: "string"}}})
+
+	for _, point := range points {
+		if _, ok := payloadField(point.GetPayload(), "meta.zip").GetKind().(*qdrant.Value_StringValue); !ok {
+			t.Errorf("zip of point %v is %v, want a string", point.GetId(), payloadField(point.GetPayload(), "meta.zip"))
+		}
+	}
+}
+
+func Test_trackPayloadTypes(t *testing.T) {
+	payloadTypes = payloadTypeTracker{types: make(map[string][]string)}
+	trackPayloadTypes([]*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"price": 10, "tags": []any{"a"}, "meta": map[string]any{"zip": "W1A"}})},
+	})
+	trackPayloadTypes([]*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"price": 9.5, "tags": []any{"b", 1}, "meta": map[string]any{"zip": 10115}})},
+	})
+
+	expected := map[string][]string{
+		"price":    {"number"},
+		"tags":     {"string", "number"},
+		"meta":     {"object"},
+		"meta.zip": {"string", "number"},
+	}
+	for path, types := range expected {
+		if got := payloadTypes.types[path]; !slices.Equal(got, types) {
+			t.Errorf("types of %s = %v, want %v", path, got, types)
+		}
+	}
+}
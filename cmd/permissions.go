@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Methods of the Qdrant gRPC API that only read. The other methods of the Points service write points,
+// and the other methods of the Collections and Snapshots services manage collections.
+var readMethods = map[string]bool{
+	"/qdrant.Qdrant/HealthCheck":                true,
+	"/qdrant.Collections/Get":                   true,
+	"/qdrant.Collections/List":                  true,
+	"/qdrant.Collections/CollectionExists":      true,
+	"/qdrant.Collections/CollectionClusterInfo": true,
+	"/qdrant.Collections/ListAliases":           true,
+	"/qdrant.Collections/ListCollectionAliases": true,
+	"/qdrant.Snapshots/List":                    true,
+	"/qdrant.Snapshots/ListFull":                true,
+	"/qdrant.Points/Get":                        true,
+	"/qdrant.Points/Scroll":                     true,
+	"/qdrant.Points/Count":                      true,
+	"/qdrant.Points/Search":                     true,
+	"/qdrant.Points/SearchBatch":                true,
+	"/qdrant.Points/SearchGroups":               true,
+	"/qdrant.Points/Recommend":                  true,
+	"/qdrant.Points/RecommendBatch":             true,
+	"/qdrant.Points/RecommendGroups":            true,
+	"/qdrant.Points/Discover":                   true,
+	"/qdrant.Points/DiscoverBatch":              true,
+	"/qdrant.Points/Query":                      true,
+	"/qdrant.Points/QueryBatch":                 true,
+	"/qdrant.Points/QueryGroups":                true,
+	"/qdrant.Points/Facet":                      true,
+	"/qdrant.Points/SearchMatrixPairs":          true,
+	"/qdrant.Points/SearchMatrixOffsets":        true,
+}
+
+// requiredAccess returns the access an API key needs for a gRPC method.
+func requiredAccess(method string) string {
+	switch {
+	case readMethods[method]:
+		return "read"
+	case strings.HasPrefix(method, "/qdrant.Points/"):
+		return "write"
+	default:
+		return "manage"
+	}
+}
+
+// permissionInterceptor turns the errors of calls Qdrant rejected for the API key into what the key lacks on which collection,
+// since the bare gRPC errors don't tell. The status code is kept.
+func permissionInterceptor(endpoint string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		s, ok := status.FromError(err)
+		if !ok {
+			return err
+		}
+
+		operation := method[strings.LastIndex(method, "/")+1:]
+		switch s.Code() {
+		case codes.Unauthenticated:
+			return status.Errorf(codes.Unauthenticated, "your API key for %s is missing or invalid (%s): %s", endpoint, operation, s.Message())
+		case codes.PermissionDenied:
+			resource := "the cluster"
+			if named, ok := req.(interface{ GetCollectionName() string }); ok && named.GetCollectionName() != "" {
+				resource = fmt.Sprintf("collection '%s'", named.GetCollectionName())
+			}
+			return status.Errorf(codes.PermissionDenied, "your API key for %s lacks %s access on %s (%s): %s", endpoint, requiredAccess(method), resource, operation, s.Message())
+		default:
+			return err
+		}
+	}
+}
+
+// isPermissionError reports whether an error is Qdrant rejecting the API key for an operation.
+func isPermissionError(err error) bool {
+	if s, ok := status.FromError(err); ok {
+		return s.Code() == codes.PermissionDenied || s.Code() == codes.Unauthenticated
+	}
+	return false
+}
+
+// checkSourceAccess checks at startup that the API key of a source can read its collection,
+// so a wrong key fails the run before anything is written.
+func checkSourceAccess(ctx context.Context, client *qdrant.Client, collection string) error {
+	limit := uint32(1)
+	_, err := client.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{CollectionName: collection, Limit: &limit})
+	if err != nil {
+		return fmt.Errorf("failed to read source collection: %w", err)
+	}
+	return nil
+}
+
+// checkTargetAccess checks at startup that the API key of a target can write the target and offsets collections,
+// and create them if needed. With replace, the collection is replaced, e.g. by a snapshot, which takes manage access.
+// JWTs are checked by their claims. Other keys are checked with writes that change nothing.
+// Runs into a sink don't write to the target, so they can't skip the points it has either.
+func checkTargetAccess(ctx context.Context, client *qdrant.Client, apiKey, collection string, migration commons.MigrationConfig, replace bool) error {
+	if migration.SkipExisting && (migration.PayloadOnly || migration.VectorsOnly) {
+		return fmt.Errorf("--migration.skip-existing can't be combined with --migration.payload-only or --migration.vectors-only, which only update the points the target has")
+	}
+	if isSinkTarget(migration) {
+		if migration.SkipExisting {
+			return fmt.Errorf("--migration.target=%s can't be combined with --migration.skip-existing, which needs the target in Qdrant", migration.Target)
+		}
+		return nil
+	}
+	exists, err := client.CollectionExists(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	if !exists && migration.PayloadOnly {
+		return fmt.Errorf("collection '%s' doesn't exist, --migration.payload-only only updates existing points", collection)
+	}
+	if !exists && migration.VectorsOnly {
+		return fmt.Errorf("collection '%s' doesn't exist, --migration.vectors-only only updates existing points", collection)
+	}
+	if !exists && !migration.CreateCollection && !replace {
+		return fmt.Errorf("collection '%s' doesn't exist and --migration.create-collection is disabled", collection)
+	}
+
+	if access, ok := parseJWTAccess(apiKey); ok {
+		offsetsExist, err := client.CollectionExists(ctx, migration.OffsetsCollection)
+		if err != nil {
+			return fmt.Errorf("failed to check if collection exists: %w", err)
+		}
+		if !access.canWrite(collection, !exists || replace) {
+			return fmt.Errorf("your JWT lacks %s access on collection '%s'", jwtRequiredAccess(!exists || replace), collection)
+		}
+		if !access.canWrite(migration.OffsetsCollection, !offsetsExist) {
+			return fmt.Errorf("your JWT lacks %s access on the offsets collection '%s'", jwtRequiredAccess(!offsetsExist), migration.OffsetsCollection)
+		}
+		return nil
+	}
+
+	if exists {
+		err = checkWriteAccess(ctx, client, collection)
+		if err != nil {
+			return fmt.Errorf("failed to write target collection: %w", err)
+		}
+	}
+	err = commons.PrepareOffsetsCollection(ctx, migration.OffsetsCollection, client)
+	if err != nil {
+		return fmt.Errorf("failed to prepare offsets collection: %w", err)
+	}
+	err = checkWriteAccess(ctx, client, migration.OffsetsCollection)
+	if err != nil {
+		return fmt.Errorf("failed to write offsets collection: %w", err)
+	}
+	return nil
+}
+
+// checkWriteAccess checks that the API key can write to an existing collection, with a write that changes nothing:
+// it sets a payload on the points of a filter that matches none.
+func checkWriteAccess(ctx context.Context, client *qdrant.Client, collection string) error {
+	marker := qdrant.NewID("00000000-0000-0000-0000-000000000000")
+	_, err := client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: collection,
+		Payload:        qdrant.NewValueMap(map[string]any{"_migration_access_check": true}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must:    []*qdrant.Condition{qdrant.NewHasID(marker)},
+			MustNot: []*qdrant.Condition{qdrant.NewHasID(marker)},
+		}),
+		Wait: qdrant.PtrOf(true),
+	})
+	return err
+}
+
+func jwtRequiredAccess(create bool) string {
+	if create {
+		return "manage (\"access\": \"m\")"
+	}
+	return "write (\"access\": \"rw\")"
+}
+
+// jwtAccess is the access claim of a Qdrant JWT: either "r" or "m" for all collections, or a list of collections.
+type jwtAccess struct {
+	global      string
+	collections map[string]string
+}
+
+// parseJWTAccess reads the access claim of an API key, if it's a JWT. The signature isn't verified, Qdrant does that.
+func parseJWTAccess(apiKey string) (*jwtAccess, bool) {
+	parts := strings.Split(apiKey, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, false
+	}
+	var claims struct {
+		Access json.RawMessage `json:"access"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Access == nil {
+		return nil, false
+	}
+
+	access := &jwtAccess{collections: make(map[string]string)}
+	if err := json.Unmarshal(claims.Access, &access.global); err == nil {
+		return access, true
+	}
+	var collections []struct {
+		Collection string `json:"collection"`
+		Access     string `json:"access"`
+	}
+	if err := json.Unmarshal(claims.Access, &collections); err != nil {
+		return nil, false
+	}
+	for _, collection := range collections {
+		access.collections[collection.Collection] = collection.Access
+	}
+	return access, true
+}
+
+// canWrite reports whether the access allows writing to a collection, and creating it if create is set.
+func (a *jwtAccess) canWrite(collection string, create bool) bool {
+	if a.global != "" {
+		return a.global == "m"
+	}
+	return !create && a.collections[collection] == "rw"
+}
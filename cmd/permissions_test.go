@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestRequiredAccess(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{"/qdrant.Points/Scroll", "read"},
+		{"/qdrant.Collections/Get", "read"},
+		{"/qdrant.Points/Upsert", "write"},
+		{"/qdrant.Points/CreateFieldIndex", "write"},
+		{"/qdrant.Collections/Create", "manage"},
+		{"/qdrant.Snapshots/Create", "manage"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if got := requiredAccess(tt.method); got != tt.want {
+				t.Errorf("requiredAccess() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermissionInterceptor(t *testing.T) {
+	interceptor := permissionInterceptor("https://qdrant.example.com:6334")
+	invoke := func(method string, req any, err error) error {
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return err
+		}
+		return interceptor(context.Background(), method, req, nil, nil, invoker)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		req      any
+		err      error
+		wantCode codes.Code
+		want     string
+	}{
+		{
+			name:     "write denied",
+			method:   "/qdrant.Points/Upsert",
+			req:      &qdrant.UpsertPoints{CollectionName: "target"},
+			err:      status.Error(codes.PermissionDenied, "Forbidden"),
+			wantCode: codes.PermissionDenied,
+			want:     "your API key for https://qdrant.example.com:6334 lacks write access on collection 'target' (Upsert): Forbidden",
+		},
+		{
+			name:     "cluster operation denied",
+			method:   "/qdrant.Collections/List",
+			req:      &qdrant.ListCollectionsRequest{},
+			err:      status.Error(codes.PermissionDenied, "Forbidden"),
+			wantCode: codes.PermissionDenied,
+			want:     "lacks read access on the cluster (List)",
+		},
+		{
+			name:     "invalid key",
+			method:   "/qdrant.Points/Scroll",
+			req:      &qdrant.ScrollPoints{CollectionName: "source"},
+			err:      status.Error(codes.Unauthenticated, "Invalid api-key"),
+			wantCode: codes.Unauthenticated,
+			want:     "is missing or invalid (Scroll)",
+		},
+		{
+			name:     "other error",
+			method:   "/qdrant.Points/Scroll",
+			req:      &qdrant.ScrollPoints{CollectionName: "source"},
+			err:      status.Error(codes.NotFound, "Collection source not found"),
+			wantCode: codes.NotFound,
+			want:     "Collection source not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := invoke(tt.method, tt.req, tt.err)
+			s, _ := status.FromError(err)
+			if s.Code() != tt.wantCode {
+				t.Errorf("code = %v, want %v", s.Code(), tt.wantCode)
+			}
+			if !strings.Contains(s.Message(), tt.want) {
+				t.Errorf("message = %q, want it to contain %q", s.Message(), tt.want)
+			}
+			if isPermissionError(err) != (tt.wantCode != codes.NotFound) {
+				t.Errorf("isPermissionError() = %v", isPermissionError(err))
+			}
+		})
+	}
+
+	if err := invoke("/qdrant.Points/Scroll", &qdrant.ScrollPoints{}, nil); err != nil {
+		t.Errorf("successful call returned %v", err)
+	}
+}
+
+func TestParseJWTAccess(t *testing.T) {
+	token := func(claims string) string {
+		return "eyJhbGciOiJIUzI1NiJ9." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+	}
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		isJWT     bool
+		canWrite  bool
+		canCreate bool
+	}{
+		{name: "plain key", apiKey: "secret"},
+		{name: "manage", apiKey: token(`{"access": "m"}`), isJWT: true, canWrite: true, canCreate: true},
+		{name: "read only", apiKey: token(`{"access": "r"}`), isJWT: true},
+		{name: "collection write", apiKey: token(`{"access": [{"collection": "target", "access": "rw"}]}`), isJWT: true, canWrite: true},
+		{name: "other collection", apiKey: token(`{"access": [{"collection": "other", "access": "rw"}]}`), isJWT: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access, ok := parseJWTAccess(tt.apiKey)
+			if ok != tt.isJWT {
+				t.Fatalf("parseJWTAccess() ok = %v, want %v", ok, tt.isJWT)
+			}
+			if !ok {
+				return
+			}
+			if got := access.canWrite("target", false); got != tt.canWrite {
+				t.Errorf("canWrite() = %v, want %v", got, tt.canWrite)
+			}
+			if got := access.canWrite("target", true); got != tt.canCreate {
+				t.Errorf("canWrite() with create = %v, want %v", got, tt.canCreate)
+			}
+		})
+	}
+}
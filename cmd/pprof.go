@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/pterm/pterm"
+)
+
+// startPprofServer serves the net/http/pprof endpoints on addr in the background, for as long as the process runs.
+func startPprofServer(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on pprof address: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		err := http.Serve(listener, mux)
+		if err != nil {
+			pterm.Warning.Printfln("pprof server stopped: %v", err)
+		}
+	}()
+
+	pterm.Info.Printfln("Serving pprof on http://%s/debug/pprof/", listener.Addr())
+	return nil
+}
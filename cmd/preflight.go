@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// qdrantFeature is a feature of a collection that a target must support to receive it.
+type qdrantFeature struct {
+	name       string
+	minVersion qdrantVersion
+}
+
+// qdrantVersion is a Qdrant release, compared by its major, minor and patch numbers.
+type qdrantVersion [3]int
+
+func (v qdrantVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+func (v qdrantVersion) less(other qdrantVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+// parseQdrantVersion parses a version like 1.14.1 or v1.14.1-dev. Missing parts are 0.
+func parseQdrantVersion(s string) (qdrantVersion, error) {
+	var version qdrantVersion
+	s, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(s), "v"), "-")
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return version, fmt.Errorf("invalid version %q", s)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return version, fmt.Errorf("invalid version %q", s)
+		}
+		version[i] = number
+	}
+	return version, nil
+}
+
+// requiredFeatures returns the features of a collection that older Qdrant versions don't support.
+func requiredFeatures(info *qdrant.CollectionInfo) []qdrantFeature {
+	var features []qdrantFeature
+	params := info.GetConfig().GetParams()
+
+	sparse := params.GetSparseVectorsConfig().GetMap()
+	if len(sparse) > 0 {
+		features = append(features, qdrantFeature{"sparse vectors", qdrantVersion{1, 7, 0}})
+	}
+	for _, sparseParams := range sparse {
+		if sparseParams.GetModifier() == qdrant.Modifier_Idf {
+			features = append(features, qdrantFeature{"IDF modifier of sparse vectors", qdrantVersion{1, 10, 0}})
+			break
+		}
+	}
+	if params.GetShardingMethod() == qdrant.ShardingMethod_Custom {
+		features = append(features, qdrantFeature{"custom sharding", qdrantVersion{1, 7, 0}})
+	}
+
+	vectors := []*qdrant.VectorParams{params.GetVectorsConfig().GetParams()}
+	for _, vectorParams := range params.GetVectorsConfig().GetParamsMap().GetMap() {
+		vectors = append(vectors, vectorParams)
+	}
+	multivector, datatype := false, false
+	for _, vectorParams := range vectors {
+		multivector = multivector || vectorParams.GetMultivectorConfig() != nil
+		datatype = datatype || vectorParams.GetDatatype() == qdrant.Datatype_Uint8 || vectorParams.GetDatatype() == qdrant.Datatype_Float16
+	}
+	if datatype {
+		features = append(features, qdrantFeature{"uint8 and float16 vectors", qdrantVersion{1, 9, 0}})
+	}
+	if multivector {
+		features = append(features, qdrantFeature{"multivectors", qdrantVersion{1, 10, 0}})
+	}
+
+	if info.GetConfig().GetStrictModeConfig().GetEnabled() {
+		features = append(features, qdrantFeature{"strict mode", qdrantVersion{1, 13, 0}})
+	}
+	return features
+}
+
+// estimateVectorBytes estimates the bytes the vectors of a collection take up on disk, which is a lower bound of its size.
+func estimateVectorBytes(info *qdrant.CollectionInfo) uint64 {
+	params := info.GetConfig().GetParams()
+	vectors := []*qdrant.VectorParams{params.GetVectorsConfig().GetParams()}
+	for _, vectorParams := range params.GetVectorsConfig().GetParamsMap().GetMap() {
+		vectors = append(vectors, vectorParams)
+	}
+
+	var bytesPerPoint uint64
+	for _, vectorParams := range vectors {
+		if vectorParams == nil {
+			continue
+		}
+		switch vectorParams.GetDatatype() {
+		case qdrant.Datatype_Uint8:
+			bytesPerPoint += vectorParams.GetSize()
+		case qdrant.Datatype_Float16:
+			bytesPerPoint += 2 * vectorParams.GetSize()
+		default:
+			bytesPerPoint += 4 * vectorParams.GetSize()
+		}
+	}
+	return bytesPerPoint * info.GetPointsCount()
+}
+
+// preflight checks that a migration can succeed before any data is copied: the versions of source and targets,
+// the permissions of their API keys, the features the source collection uses, and the disk size of the targets.
+// All checks run, and the ones that failed are returned as one error.
+func (r *MigrateFromQdrantCmd) preflight(ctx context.Context, globals *Globals, sourceClient *qdrant.Client, targetClients []*qdrant.Client) error {
+	pterm.DefaultSection.Println("Pre-flight Checks")
+	var failed []string
+	fail := func(format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		pterm.Error.Println(message)
+		failed = append(failed, message)
+	}
+
+	sourceVersion, err := getQdrantVersion(ctx, sourceClient)
+	if err != nil {
+		return fmt.Errorf("failed to reach source: %w", err)
+	}
+	pterm.Success.Printfln("Source runs Qdrant %s", sourceVersion)
+
+	sourceInfo, err := sourceClient.GetCollectionInfo(ctx, r.Source.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to get source collection info: %w", err)
+	}
+	err = checkSourceAccess(ctx, sourceClient, r.Source.Collection)
+	if err != nil {
+		fail("Source: %v", err)
+	} else {
+		pterm.Success.Printfln("Source API key can read '%s'", r.Source.Collection)
+	}
+
+	features := requiredFeatures(sourceInfo)
+	apiKeys := r.targetAPIKeys()
+	for i, client := range targetClients {
+		label := "Target"
+		if i > 0 {
+			label = fmt.Sprintf("Extra target %s", r.extraTargets[i-1].url)
+		}
+
+		targetVersion, err := getQdrantVersion(ctx, client)
+		if err != nil {
+			fail("%s can't be reached: %v", label, err)
+			continue
+		}
+		pterm.Success.Printfln("%s runs Qdrant %s", label, targetVersion)
+
+		if targetVersion.less(sourceVersion) {
+			if r.Strategy == "snapshot" {
+				fail("%s runs Qdrant %s, older than the source's %s. Snapshots can only be restored on the same or a newer version, upgrade the target or use --strategy scroll", label, targetVersion, sourceVersion)
+			} else {
+				pterm.Warning.Printfln("%s runs Qdrant %s, older than the source's %s", label, targetVersion, sourceVersion)
+			}
+		}
+		for _, feature := range features {
+			if targetVersion.less(feature.minVersion) {
+				fail("%s: the source collection uses %s, which needs Qdrant %s or newer, upgrade the target", label, feature.name, feature.minVersion)
+			}
+		}
+
+		err = checkTargetAccess(ctx, client, apiKeys[i], r.Target.Collection, r.Migration, r.Strategy == "snapshot")
+		if err != nil {
+			fail("%s: %v", label, err)
+		} else {
+			pterm.Success.Printfln("%s API key can write '%s'", label, r.Target.Collection)
+		}
+	}
+
+	r.checkTargetDisk(ctx, globals, targetClients[0], sourceInfo, fail)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d pre-flight check(s) failed: %s", len(failed), strings.Join(failed, "; "))
+	}
+	pterm.Println()
+	return nil
+}
+
+func (r *MigrateFromQdrantCmd) targetAPIKeys() []string {
+	keys := []string{r.Target.APIKey}
+	for _, extra := range r.extraTargets {
+		keys = append(keys, extra.apiKey)
+	}
+	return keys
+}
+
+// checkTargetDisk compares the estimated size of the vectors of the source collection with the disk space the target
+// has left. Qdrant only reports the size of its disk, in its telemetry, so the space the other collections of the target
+// take up is estimated the same way and subtracted. The check is skipped if the telemetry isn't accessible.
+func (r *MigrateFromQdrantCmd) checkTargetDisk(ctx context.Context, globals *Globals, targetClient *qdrant.Client, sourceInfo *qdrant.CollectionInfo, fail func(string, ...any)) {
+	restUrl, err := getQdrantRestUrl(ctx, r.TargetRestUrl, r.targetHost, r.targetPort, r.targetTLS)
+	if err != nil {
+		pterm.Warning.Printfln("Skipping the disk check: %v", err)
+		return
+	}
+	rest, err := newQdrantRestClient(globals, restUrl, r.Target)
+	if err != nil {
+		pterm.Warning.Printfln("Skipping the disk check: %v", err)
+		return
+	}
+	diskBytes, err := rest.diskSize(ctx)
+	if err != nil || diskBytes == 0 {
+		pterm.Info.Printfln("Skipping the disk check, the target doesn't report its disk size")
+		return
+	}
+	usedBytes, err := collectionsVectorBytes(ctx, targetClient, r.Target.Collection)
+	if err != nil {
+		pterm.Warning.Printfln("Skipping the disk check: %v", err)
+		return
+	}
+	availableBytes := uint64(0)
+	if diskBytes > usedBytes {
+		availableBytes = diskBytes - usedBytes
+	}
+
+	required := estimateVectorBytes(sourceInfo)
+	switch {
+	case required > availableBytes:
+		fail("The vectors of the source alone take about %s, more than the about %s left on the %s disk of the target", commons.ByteSize(required), commons.ByteSize(availableBytes), commons.ByteSize(diskBytes))
+	case required > availableBytes/2:
+		pterm.Warning.Printfln("The vectors of the source take about %s, more than half of the about %s left on the %s disk of the target", commons.ByteSize(required), commons.ByteSize(availableBytes), commons.ByteSize(diskBytes))
+	default:
+		pterm.Success.Printfln("Target has about %s left on its %s disk for about %s of vectors", commons.ByteSize(availableBytes), commons.ByteSize(diskBytes), commons.ByteSize(required))
+	}
+}
+
+// collectionsVectorBytes estimates the bytes the vectors of all collections of a node take up on disk,
+// except the given one, which the migration overwrites.
+func collectionsVectorBytes(ctx context.Context, client *qdrant.Client, exclude string) (uint64, error) {
+	collections, err := client.ListCollections(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list target collections: %w", err)
+	}
+	var total uint64
+	for _, collection := range collections {
+		if collection == exclude {
+			continue
+		}
+		info, err := client.GetCollectionInfo(ctx, collection)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get info of target collection '%s': %w", collection, err)
+		}
+		total += estimateVectorBytes(info)
+	}
+	return total, nil
+}
+
+func getQdrantVersion(ctx context.Context, client *qdrant.Client) (qdrantVersion, error) {
+	health, err := client.HealthCheck(ctx)
+	if err != nil {
+		return qdrantVersion{}, err
+	}
+	return parseQdrantVersion(health.GetVersion())
+}
+
+// diskSize returns the size of the disk of the node in bytes, as reported by the telemetry API in kilobytes.
+func (c *qdrantRestClient) diskSize(ctx context.Context) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseUrl+"/telemetry", nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get telemetry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var telemetry struct {
+		Result struct {
+			App struct {
+				System *struct {
+					DiskSize uint64 `json:"disk_size"`
+				} `json:"system"`
+			} `json:"app"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&telemetry); err != nil {
+		return 0, fmt.Errorf("failed to decode telemetry: %w", err)
+	}
+	if telemetry.Result.App.System == nil {
+		return 0, errors.New("telemetry has no system information")
+	}
+	return telemetry.Result.App.System.DiskSize * 1024, nil
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestParseQdrantVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    qdrantVersion
+		wantErr bool
+	}{
+		{input: "1.14.1", want: qdrantVersion{1, 14, 1}},
+		{input: "v1.9.0-dev", want: qdrantVersion{1, 9, 0}},
+		{input: "1.10", want: qdrantVersion{1, 10, 0}},
+		{input: "latest", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseQdrantVersion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQdrantVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseQdrantVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !(qdrantVersion{1, 9, 5}).less(qdrantVersion{1, 10, 0}) || (qdrantVersion{1, 10, 0}).less(qdrantVersion{1, 10, 0}) {
+		t.Error("versions should be compared by number")
+	}
+}
+
+func TestRequiredFeatures(t *testing.T) {
+	info := &qdrant.CollectionInfo{
+		PointsCount: qdrant.PtrOf(uint64(1000)),
+		Config: &qdrant.CollectionConfig{
+			Params: &qdrant.CollectionParams{
+				VectorsConfig: qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+					"dense": {Size: 128, Distance: qdrant.Distance_Cosine},
+					"colbert": {
+						Size:              64,
+						Distance:          qdrant.Distance_Dot,
+						Datatype:          qdrant.Datatype_Float16.Enum(),
+						MultivectorConfig: &qdrant.MultiVectorConfig{Comparator: qdrant.MultiVectorComparator_MaxSim},
+					},
+				}),
+				SparseVectorsConfig: qdrant.NewSparseVectorsConfig(map[string]*qdrant.SparseVectorParams{
+					"bm25": {Modifier: qdrant.Modifier_Idf.Enum()},
+				}),
+				ShardingMethod: qdrant.ShardingMethod_Custom.Enum(),
+			},
+		},
+	}
+
+	got := make(map[string]qdrantVersion)
+	for _, feature := range requiredFeatures(info) {
+		got[feature.name] = feature.minVersion
+	}
+	for _, name := range []string{"sparse vectors", "IDF modifier of sparse vectors", "custom sharding", "uint8 and float16 vectors", "multivectors"} {
+		if _, ok := got[name]; !ok {
+			t.Errorf("requiredFeatures() is missing %q", name)
+		}
+	}
+	if _, ok := got["strict mode"]; ok {
+		t.Error("requiredFeatures() shouldn't require strict mode")
+	}
+
+	// 128 float32 and 64 float16 dimensions per point.
+	if got, want := estimateVectorBytes(info), uint64(1000*(128*4+64*2)); got != want {
+		t.Errorf("estimateVectorBytes() = %d, want %d", got, want)
+	}
+}
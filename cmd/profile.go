@@ -0,0 +1,412 @@
+package cmd
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+)
+
+// Environment variable with the passphrase the API keys of profiles are encrypted with.
+const profilesPassphraseEnv = "MIGRATION_PROFILES_PASSPHRASE"
+
+// Iterations of PBKDF2 to derive the encryption key from the passphrase, as recommended by OWASP for SHA-256.
+const profileKeyIterations = 600_000
+
+// connectionProfile is a named Qdrant endpoint with its credentials. The API key is stored encrypted.
+type connectionProfile struct {
+	Url        string `json:"url"`
+	APIKey     string `json:"api_key,omitempty"`
+	CACert     string `json:"ca_cert,omitempty"`
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+}
+
+// profileStore is the file the profiles are stored in. The API keys are encrypted with AES-GCM,
+// with a key derived from a passphrase and the salt of the file.
+type profileStore struct {
+	Default  string                        `json:"default,omitempty"`
+	Salt     string                        `json:"salt"`
+	Profiles map[string]*connectionProfile `json:"profiles"`
+
+	path string
+	key  []byte
+}
+
+// defaultProfilesFile returns the path of the profiles file in the user's configuration directory.
+func defaultProfilesFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "qdrant-migration", "profiles.json"), nil
+}
+
+// loadProfileStore reads the profiles file, or returns an empty store if it doesn't exist yet.
+func loadProfileStore(path string) (*profileStore, error) {
+	if path == "" {
+		var err error
+		path, err = defaultProfilesFile()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store := &profileStore{path: path, Profiles: make(map[string]*connectionProfile)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles file %s: %w", path, err)
+	}
+	if store.Profiles == nil {
+		store.Profiles = make(map[string]*connectionProfile)
+	}
+	return store, nil
+}
+
+// save writes the store, readable only by the user, since it has the URLs and certificate paths in plain text.
+func (s *profileStore) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write profiles file: %w", err)
+	}
+	return nil
+}
+
+func (s *profileStore) get(name string) (*connectionProfile, error) {
+	profile, ok := s.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' doesn't exist, add it with 'migration profile add %s --url ...'", name, name)
+	}
+	return profile, nil
+}
+
+// unlock derives the encryption key from the passphrase, creating the salt of a new store.
+func (s *profileStore) unlock(passphrase string) error {
+	if s.key != nil {
+		return nil
+	}
+	if passphrase == "" {
+		return fmt.Errorf("the API keys of profiles are encrypted, set %s to the passphrase", profilesPassphraseEnv)
+	}
+
+	if s.Salt == "" {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		s.Salt = base64.StdEncoding.EncodeToString(salt)
+	}
+	salt, err := base64.StdEncoding.DecodeString(s.Salt)
+	if err != nil {
+		return fmt.Errorf("failed to decode salt of profiles file: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, profileKeyIterations, 32)
+	if err != nil {
+		return fmt.Errorf("failed to derive encryption key: %w", err)
+	}
+	s.key = key
+	return nil
+}
+
+func (s *profileStore) encrypt(plaintext string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func (s *profileStore) decrypt(ciphertext string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted API key in profiles file")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt API key, is %s the passphrase the profile was added with?", profilesPassphraseEnv)
+	}
+	return string(plaintext), nil
+}
+
+func (s *profileStore) cipher() (cipher.AEAD, error) {
+	if s.key == nil {
+		return nil, errors.New("profiles are locked")
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type ProfileCmd struct {
+	Add    ProfileAddCmd    `cmd:"" help:"Add or replace a profile."`
+	List   ProfileListCmd   `cmd:"" help:"List the profiles."`
+	Use    ProfileUseCmd    `cmd:"" help:"Set the profile used for Qdrant targets when --target-profile isn't given."`
+	Remove ProfileRemoveCmd `cmd:"" help:"Remove a profile."`
+}
+
+type ProfileAddCmd struct {
+	Name       string `arg:"" help:"Name of the profile."`
+	Url        string `help:"Qdrant gRPC URL." required:"true"`
+	APIKey     string `help:"API key. Encrypted with the passphrase in MIGRATION_PROFILES_PASSPHRASE."`
+	CACert     string `help:"Path to a PEM file with the CA certificates to verify the server with." type:"existingfile"`
+	ClientCert string `help:"Path to a PEM client certificate for mutual TLS." type:"existingfile"`
+	ClientKey  string `help:"Path to the PEM private key of the client certificate." type:"existingfile"`
+}
+
+func (r *ProfileAddCmd) Run(globals *Globals) error {
+	store, err := loadProfileStore(globals.ProfilesFile)
+	if err != nil {
+		return err
+	}
+
+	profile := &connectionProfile{Url: r.Url}
+	if r.APIKey != "" {
+		err = store.unlock(os.Getenv(profilesPassphraseEnv))
+		if err != nil {
+			return err
+		}
+		// Check the passphrase against the other keys, so that a store doesn't end up with keys of different passphrases.
+		for name, other := range store.Profiles {
+			if other.APIKey == "" {
+				continue
+			}
+			if _, err := store.decrypt(other.APIKey); err != nil {
+				return fmt.Errorf("failed to decrypt the API key of profile '%s' with the passphrase: %w", name, err)
+			}
+			break
+		}
+		profile.APIKey, err = store.encrypt(r.APIKey)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt API key: %w", err)
+		}
+	}
+	for _, path := range []*string{&r.CACert, &r.ClientCert, &r.ClientKey} {
+		if *path != "" {
+			*path, err = filepath.Abs(*path)
+			if err != nil {
+				return fmt.Errorf("failed to resolve path: %w", err)
+			}
+		}
+	}
+	profile.CACert, profile.ClientCert, profile.ClientKey = r.CACert, r.ClientCert, r.ClientKey
+
+	store.Profiles[r.Name] = profile
+	err = store.save()
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Saved profile '%s' to %s", r.Name, store.path)
+	return nil
+}
+
+type ProfileListCmd struct{}
+
+func (r *ProfileListCmd) Run(globals *Globals) error {
+	store, err := loadProfileStore(globals.ProfilesFile)
+	if err != nil {
+		return err
+	}
+	if len(store.Profiles) == 0 {
+		pterm.Info.Println("No profiles yet, add one with 'migration profile add <name> --url ...'")
+		return nil
+	}
+
+	table := pterm.TableData{{"Name", "URL", "API Key", "Certificates", "Default"}}
+	for _, name := range sortedProfileNames(store.Profiles) {
+		profile := store.Profiles[name]
+		apiKey, certificates, isDefault := "", "", ""
+		if profile.APIKey != "" {
+			apiKey = "encrypted"
+		}
+		if profile.CACert != "" || profile.ClientCert != "" {
+			certificates = "yes"
+		}
+		if name == store.Default {
+			isDefault = "target"
+		}
+		table = append(table, []string{name, profile.Url, apiKey, certificates, isDefault})
+	}
+	return pterm.DefaultTable.WithHasHeader(true).WithBoxed(true).WithData(table).Render()
+}
+
+type ProfileUseCmd struct {
+	Name string `arg:"" help:"Name of the profile."`
+}
+
+func (r *ProfileUseCmd) Run(globals *Globals) error {
+	store, err := loadProfileStore(globals.ProfilesFile)
+	if err != nil {
+		return err
+	}
+	if _, err := store.get(r.Name); err != nil {
+		return err
+	}
+
+	store.Default = r.Name
+	err = store.save()
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Qdrant targets use profile '%s' unless --target-profile is given", r.Name)
+	return nil
+}
+
+type ProfileRemoveCmd struct {
+	Name string `arg:"" help:"Name of the profile."`
+}
+
+func (r *ProfileRemoveCmd) Run(globals *Globals) error {
+	store, err := loadProfileStore(globals.ProfilesFile)
+	if err != nil {
+		return err
+	}
+	if _, err := store.get(r.Name); err != nil {
+		return err
+	}
+
+	delete(store.Profiles, r.Name)
+	if store.Default == r.Name {
+		store.Default = ""
+	}
+	err = store.save()
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Removed profile '%s'", r.Name)
+	return nil
+}
+
+// Commands that read from Qdrant through their qdrant flags. The qdrant flags of the other commands are targets.
+var qdrantSourceCommands = map[string]bool{
+	"to-pinecone": true,
+	"to-weaviate": true,
+	"to-pg":       true,
+	"to-milvus":   true,
+	"export":      true,
+}
+
+// Options of an endpoint that a profile sets, by the name of their flag without the prefix.
+var profileOptions = map[string]func(*connectionProfile) string{
+	"url":         func(p *connectionProfile) string { return p.Url },
+	"api-key":     func(p *connectionProfile) string { return p.APIKey },
+	"ca-cert":     func(p *connectionProfile) string { return p.CACert },
+	"client-cert": func(p *connectionProfile) string { return p.ClientCert },
+	"client-key":  func(p *connectionProfile) string { return p.ClientKey },
+}
+
+// profileResolver sets the connection options of Qdrant endpoints from the profiles given with --source-profile
+// and --target-profile, or from the default profile for targets. Options given on the command line take precedence.
+func profileResolver() kong.Resolver {
+	var store *profileStore
+	return kong.ResolverFunc(func(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (any, error) {
+		prefix, option, ok := strings.Cut(flag.Name, ".")
+		if !ok || profileOptions[option] == nil {
+			return nil, nil
+		}
+
+		if prefix != "source" && prefix != "target" && prefix != "qdrant" {
+			return nil, nil
+		}
+		source := prefix == "source" || (prefix == "qdrant" && qdrantSourceCommands[commandName(ctx)])
+
+		profilesFile, _ := globalFlagValue(ctx, "profiles-file").(string)
+		if store == nil {
+			var err error
+			store, err = loadProfileStore(profilesFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		name, _ := globalFlagValue(ctx, "target-profile").(string)
+		if source {
+			name, _ = globalFlagValue(ctx, "source-profile").(string)
+		} else if name == "" {
+			name = store.Default
+		}
+		if name == "" {
+			return nil, nil
+		}
+
+		profile, err := store.get(name)
+		if err != nil {
+			return nil, err
+		}
+		value := profileOptions[option](profile)
+		if value == "" {
+			return nil, nil
+		}
+		if option == "api-key" {
+			err = store.unlock(os.Getenv(profilesPassphraseEnv))
+			if err != nil {
+				return nil, err
+			}
+			return store.decrypt(value)
+		}
+		return value, nil
+	})
+}
+
+// commandName returns the name of the top-level command being parsed.
+func commandName(ctx *kong.Context) string {
+	for _, path := range ctx.Path {
+		if path.Command != nil {
+			return path.Command.Name
+		}
+	}
+	return ""
+}
+
+func globalFlagValue(ctx *kong.Context, name string) any {
+	for _, flag := range ctx.Flags() {
+		if flag.Name == name {
+			return ctx.FlagValue(flag)
+		}
+	}
+	return nil
+}
+
+// sortedProfileNames returns the names of the profiles in a stable order.
+func sortedProfileNames(profiles map[string]*connectionProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileStoreEncryption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	store, err := loadProfileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := store.encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Profiles["prod"] = &connectionProfile{Url: "https://prod:6334", APIKey: encrypted}
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := loadProfileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.unlock("wrong"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.decrypt(loaded.Profiles["prod"].APIKey); err == nil {
+		t.Error("decrypt() with the wrong passphrase succeeded")
+	}
+
+	loaded.key = nil
+	if err := loaded.unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	apiKey, err := loaded.decrypt(loaded.Profiles["prod"].APIKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "secret" {
+		t.Errorf("decrypt() = %q, want %q", apiKey, "secret")
+	}
+}
+
+func TestProfileResolver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	t.Setenv(profilesPassphraseEnv, "passphrase")
+	store, err := loadProfileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.unlock("passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := store.encrypt("prod-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Profiles["prod"] = &connectionProfile{Url: "https://prod:6334", APIKey: encrypted}
+	store.Profiles["cloud"] = &connectionProfile{Url: "https://cloud:6334"}
+	store.Default = "cloud"
+	if err := store.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cli, err := parseCommand([]string{"--profiles-file", path, "--source-profile", "prod", "qdrant", "--source.collection", "a", "--target.collection", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cli.Qdrant.Source.Url != "https://prod:6334" || cli.Qdrant.Source.APIKey != "prod-key" {
+		t.Errorf("source = %s with key %q, want the prod profile", cli.Qdrant.Source.Url, cli.Qdrant.Source.APIKey)
+	}
+	if cli.Qdrant.Target.Url != "https://cloud:6334" {
+		t.Errorf("target = %s, want the default profile", cli.Qdrant.Target.Url)
+	}
+
+	_, cli, err = parseCommand([]string{"--profiles-file", path, "--source-profile", "prod", "to-pg", "--qdrant.collection", "a", "--qdrant.api-key", "explicit", "--pg.url", "postgres://localhost", "--pg.table", "t"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cli.ToPG.Qdrant.Url != "https://prod:6334" || cli.ToPG.Qdrant.APIKey != "explicit" {
+		t.Errorf("qdrant source = %s with key %q, want the prod profile with the explicit key", cli.ToPG.Qdrant.Url, cli.ToPG.Qdrant.APIKey)
+	}
+
+	if _, _, err := parseCommand([]string{"--profiles-file", path, "--target-profile", "missing", "qdrant", "--source.collection", "a", "--target.collection", "b"}); err == nil {
+		t.Error("parseCommand() with a missing profile succeeded")
+	}
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// getProxyDialer returns a dialer for the proxy to connect to Qdrant through, or nil to connect directly.
+// An explicit proxy takes precedence over ALL_PROXY. HTTPS_PROXY is only considered with withHttpsProxy,
+// since gRPC and net/http already honor it when no custom dialer is set.
+func getProxyDialer(explicit string, withHttpsProxy bool) (contextDialer, error) {
+	proxyUrl, err := getProxyUrl(explicit, withHttpsProxy)
+	if err != nil || proxyUrl == nil {
+		return nil, err
+	}
+	return newProxyDialer(proxyUrl, explicit == "")
+}
+
+func getProxyUrl(explicit string, withHttpsProxy bool) (*url.URL, error) {
+	names := []string{"ALL_PROXY", "all_proxy"}
+	if withHttpsProxy {
+		names = append(names, "HTTPS_PROXY", "https_proxy")
+	}
+
+	rawUrl := explicit
+	for _, name := range names {
+		if rawUrl != "" {
+			break
+		}
+		rawUrl = os.Getenv(name)
+	}
+	if rawUrl == "" {
+		return nil, nil
+	}
+
+	proxyUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+	}
+
+	switch proxyUrl.Scheme {
+	case "http", HTTPS, "socks5", "socks5h":
+		return proxyUrl, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https, socks5 or socks5h", proxyUrl.Scheme)
+	}
+}
+
+type contextDialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newProxyDialer returns a dialer that tunnels TCP connections through the proxy,
+// with HTTP CONNECT for HTTP(S) proxies and the SOCKS5 protocol otherwise.
+// Proxies from the environment skip the hosts listed in NO_PROXY.
+func newProxyDialer(proxyUrl *url.URL, fromEnvironment bool) (contextDialer, error) {
+	var dialer proxy.Dialer
+	if proxyUrl.Scheme == "socks5" || proxyUrl.Scheme == "socks5h" {
+		socksDialer, err := proxy.FromURL(proxyUrl, proxy.Direct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create SOCKS5 dialer: %w", err)
+		}
+		dialer = socksDialer
+	} else {
+		dialer = httpConnectDialer{proxyUrl: proxyUrl}
+	}
+
+	if fromEnvironment {
+		perHost := proxy.NewPerHost(dialer, proxy.Direct)
+		perHost.AddFromString(os.Getenv("NO_PROXY"))
+		return perHost.DialContext, nil
+	}
+
+	return dialer.(proxy.ContextDialer).DialContext, nil
+}
+
+type httpConnectDialer struct {
+	proxyUrl *url.URL
+}
+
+func (d httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+func (d httpConnectDialer) DialContext(ctx context.Context, _, addr string) (net.Conn, error) {
+	return dialHTTPConnect(ctx, d.proxyUrl, addr)
+}
+
+func dialHTTPConnect(ctx context.Context, proxyUrl *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyUrl.Host
+	if proxyUrl.Port() == "" {
+		if proxyUrl.Scheme == HTTPS {
+			proxyAddr = net.JoinHostPort(proxyUrl.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyUrl.Hostname(), "80")
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+	}
+	if proxyUrl.Scheme == HTTPS {
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyUrl.Hostname()})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyUrl.User != nil {
+		password, _ := proxyUrl.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyUrl.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer func() { _ = conn.SetDeadline(time.Time{}) }()
+	}
+
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to send CONNECT request to proxy: %w", err)
+	}
+
+	resp, err := readConnectResponse(conn, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read CONNECT response from proxy: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+
+	return conn, nil
+}
+
+// readConnectResponse reads the proxy response to a CONNECT request one byte at a time,
+// since the tunnel starts right after the response headers and must not be buffered away.
+func readConnectResponse(conn net.Conn, req *http.Request) (*http.Response, error) {
+	var header []byte
+	b := make([]byte, 1)
+	for !bytes.HasSuffix(header, []byte("\r\n\r\n")) {
+		if len(header) > 64*1024 {
+			return nil, fmt.Errorf("response headers too long")
+		}
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return nil, err
+		}
+		header = append(header, b[0])
+	}
+
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(header)), req)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func Test_dialHTTPConnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	// A proxy that accepts a single CONNECT and then echoes everything back.
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil || req.Method != http.MethodConnect || req.Host != "qdrant:6334" || req.Header.Get("Proxy-Authorization") == "" {
+			_, _ = io.WriteString(conn, "HTTP/1.1 403 Forbidden\r\n\r\n")
+			return
+		}
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	proxyUrl := &url.URL{Scheme: "http", Host: listener.Addr().String(), User: url.UserPassword("user", "pass")}
+	conn, err := dialHTTPConnect(context.Background(), proxyUrl, "qdrant:6334")
+	if err != nil {
+		t.Fatalf("dialHTTPConnect() error = %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, "ping"); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 4)
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "ping" {
+		t.Errorf("got %q through the tunnel, expected %q", got, "ping")
+	}
+}
+
+func Test_getProxyUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxy   string
+		wantErr bool
+	}{
+		{name: "http", proxy: "http://proxy:3128"},
+		{name: "socks5", proxy: "socks5://proxy:1080"},
+		{name: "unsupported scheme", proxy: "ftp://proxy", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := getProxyUrl(tt.proxy, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getProxyUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+const (
+	defaultQdrantGrpcPort = 6334
+	defaultQdrantRestPort = 6333
+)
+
+// qdrantRestClient is a minimal client for the Qdrant REST endpoints that are not exposed over gRPC,
+// like downloading and uploading snapshot files.
+type qdrantRestClient struct {
+	baseUrl string
+	apiKey  string
+	http    *http.Client
+}
+
+func newQdrantRestClient(globals *Globals, restUrl string, config commons.QdrantConfig) (*qdrantRestClient, error) {
+	tlsConfig, err := getTLSConfig(globals, config, strings.HasPrefix(restUrl, HTTPS+"://"))
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	dialer, err := getEndpointDialer(globals, config)
+	if err != nil {
+		return nil, err
+	}
+	if dialer != nil {
+		transport.Proxy = nil
+		transport.DialContext = dialer
+	}
+
+	return &qdrantRestClient{
+		baseUrl: restUrl,
+		apiKey:  config.APIKey,
+		http:    &http.Client{Transport: transport},
+	}, nil
+}
+
+// getQdrantRestUrl returns the REST URL for a Qdrant instance, given its gRPC endpoint.
+// If restUrl is set, it's used as is. Otherwise, the default gRPC port is mapped to the default REST port,
+// and any other port is assumed to serve both, as is the case behind most load balancers.
+// For a URL resolved by DNS SRV records, it's the URL of the node of the first record, since the name of the records
+// isn't the name of a node.
+func getQdrantRestUrl(ctx context.Context, restUrl, host string, port int, useTLS bool) (string, error) {
+	if restUrl != "" {
+		return restUrl, nil
+	}
+	if port == 0 && !isUnixSocket(host) {
+		nodes, err := lookupSrvNodes(ctx, host)
+		if err != nil {
+			return "", fmt.Errorf("failed to find the REST endpoint of %s: %w", host, err)
+		}
+		host = nodes[0].host
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = HTTPS
+	}
+	// Without a port, the gRPC ports of the nodes were resolved by SRV records.
+	if port == defaultQdrantGrpcPort || port == 0 {
+		port = defaultQdrantRestPort
+	}
+
+	return (&url.URL{Scheme: scheme, Host: net.JoinHostPort(host, strconv.Itoa(port))}).String(), nil
+}
+
+func (c *qdrantRestClient) snapshotUrl(collection, snapshot string) string {
+	return fmt.Sprintf("%s/collections/%s/snapshots/%s", c.baseUrl, url.PathEscape(collection), url.PathEscape(snapshot))
+}
+
+func (c *qdrantRestClient) do(req *http.Request) (*http.Response, error) {
+	if c.apiKey != "" {
+		req.Header.Set("api-key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return resp, nil
+}
+
+// downloadSnapshot opens a stream of a collection snapshot file.
+func (c *qdrantRestClient) downloadSnapshot(ctx context.Context, collection, snapshot string) (io.ReadCloser, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.snapshotUrl(collection, snapshot), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download snapshot: %w", err)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// uploadSnapshot restores a collection from a snapshot file, replacing the collection if it exists.
+// The file is streamed, so it's never held in memory or on disk in full.
+func (c *qdrantRestClient) uploadSnapshot(ctx context.Context, collection string, snapshot io.Reader) error {
+	reader, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		part, err := form.CreateFormFile("snapshot", collection+".snapshot")
+		if err == nil {
+			_, err = io.Copy(part, snapshot)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+
+	uploadUrl := fmt.Sprintf("%s/collections/%s/snapshots/upload?wait=true&priority=snapshot", c.baseUrl, url.PathEscape(collection))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadUrl, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// recoverSnapshot restores a collection from a snapshot the target can download by itself,
+// e.g. from shared object storage.
+func (c *qdrantRestClient) recoverSnapshot(ctx context.Context, collection, location string) error {
+	body, err := json.Marshal(map[string]string{
+		"location": location,
+		"priority": "snapshot",
+	})
+	if err != nil {
+		return err
+	}
+
+	recoverUrl := fmt.Sprintf("%s/collections/%s/snapshots/recover?wait=true", c.baseUrl, url.PathEscape(collection))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, recoverUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to recover snapshot: %w", err)
+	}
+	return resp.Body.Close()
+}
+
+// progressReader advances a progress bar as bytes are read.
+type progressReader struct {
+	io.Reader
+	bar *pterm.ProgressbarPrinter
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.bar.Add(n)
+	return n, err
+}
+
+// checkSinglePeer returns an error if a collection has shards on other peers than the node the client is connected to.
+// A collection snapshot only has the shards of the node it's taken on, so it would silently miss the points of the others.
+func checkSinglePeer(ctx context.Context, client *qdrant.Client, collection string) error {
+	info, err := client.GetCollectionsClient().CollectionClusterInfo(ctx, &qdrant.CollectionClusterInfoRequest{
+		CollectionName: collection,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster info of '%s': %w", collection, err)
+	}
+	if peers := remotePeers(info); len(peers) > 0 {
+		return fmt.Errorf("collection '%s' has shards on %d other peer(s) of the cluster, and a snapshot only has the shards of the node it's taken on", collection, len(peers))
+	}
+	return nil
+}
+
+// remotePeers returns the IDs of the other peers that hold shards of a collection, in ascending order.
+func remotePeers(info *qdrant.CollectionClusterInfoResponse) []uint64 {
+	var peers []uint64
+	for _, shard := range info.GetRemoteShards() {
+		if !slices.Contains(peers, shard.GetPeerId()) {
+			peers = append(peers, shard.GetPeerId())
+		}
+	}
+	slices.Sort(peers)
+	return peers
+}
+
+// migrateViaSnapshot copies a collection by creating a snapshot on the source and restoring it on every target.
+// Snapshots are streamed from the source to the targets, unless snapshotBaseUrl is set,
+// in which case the targets download the snapshot from there by themselves, e.g. from shared object storage.
+func migrateViaSnapshot(ctx context.Context, sourceClient *qdrant.Client, sourceRest *qdrantRestClient, sourceCollection string, targetRests []*qdrantRestClient, targetCollection string, snapshotBaseUrl string) error {
+	err := checkSinglePeer(ctx, sourceClient, sourceCollection)
+	if err != nil {
+		return fmt.Errorf("the snapshot strategy can't copy the source: %w, use the scroll strategy instead", err)
+	}
+
+	pterm.Info.Printfln("Creating snapshot of source collection '%s'", sourceCollection)
+
+	snapshot, err := sourceClient.CreateSnapshot(ctx, sourceCollection)
+	if err != nil {
+		return fmt.Errorf("failed to create source snapshot: %w", err)
+	}
+	defer func() {
+		// The snapshot is only an intermediate artifact, so it's cleaned up even if the restore failed.
+		if err := sourceClient.DeleteSnapshot(context.Background(), sourceCollection, snapshot.GetName()); err != nil {
+			pterm.Warning.Printfln("Failed to delete source snapshot %s: %v", snapshot.GetName(), err)
+		}
+	}()
+
+	pterm.Success.Printfln("Created snapshot %s (%d bytes)", snapshot.GetName(), snapshot.GetSize())
+
+	for _, targetRest := range targetRests {
+		if snapshotBaseUrl != "" {
+			snapshotLocation := strings.TrimSuffix(snapshotBaseUrl, "/") + "/" + url.PathEscape(snapshot.GetName())
+			pterm.Info.Printfln("Recovering %s from %s", targetRest.baseUrl, snapshotLocation)
+			if err := targetRest.recoverSnapshot(ctx, targetCollection, snapshotLocation); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pterm.Info.Printfln("Streaming snapshot to %s", targetRest.baseUrl)
+
+		body, size, err := sourceRest.downloadSnapshot(ctx, sourceCollection, snapshot.GetName())
+		if err != nil {
+			return err
+		}
+		if size <= 0 {
+			size = snapshot.GetSize()
+		}
+
+		bar, _ := pterm.DefaultProgressbar.WithTotal(int(size)).WithTitle("Bytes").Start()
+		err = targetRest.uploadSnapshot(ctx, targetCollection, &progressReader{Reader: body, bar: bar})
+		_ = body.Close()
+		_, _ = bar.Stop()
+		if err != nil {
+			return err
+		}
+	}
+
+	pterm.Success.Printfln("Snapshot restored successfully")
+
+	return nil
+}
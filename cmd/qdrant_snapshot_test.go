@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_getQdrantRestUrl(t *testing.T) {
+	tests := []struct {
+		name     string
+		restUrl  string
+		host     string
+		port     int
+		tls      bool
+		expected string
+	}{
+		{name: "default gRPC port", host: "localhost", port: 6334, expected: "http://localhost:6333"},
+		{name: "tls, custom port", host: "example.com", port: 443, tls: true, expected: "https://example.com:443"},
+		{name: "ipv6", host: "::1", port: 6334, expected: "http://[::1]:6333"},
+		{name: "explicit", restUrl: "http://rest:8080", host: "localhost", port: 6334, expected: "http://rest:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getQdrantRestUrl(context.Background(), tt.restUrl, tt.host, tt.port, tt.tls)
+			if err != nil {
+				t.Fatalf("getQdrantRestUrl() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("getQdrantRestUrl() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_remotePeers(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     *qdrant.CollectionClusterInfoResponse
+		expected []uint64
+	}{
+		{
+			name: "single node",
+			info: &qdrant.CollectionClusterInfoResponse{LocalShards: []*qdrant.LocalShardInfo{{ShardId: 0}, {ShardId: 1}}},
+		},
+		{
+			name: "cluster",
+			info: &qdrant.CollectionClusterInfoResponse{
+				LocalShards:  []*qdrant.LocalShardInfo{{ShardId: 0}},
+				RemoteShards: []*qdrant.RemoteShardInfo{{ShardId: 1, PeerId: 7}, {ShardId: 2, PeerId: 3}, {ShardId: 0, PeerId: 7}},
+			},
+			expected: []uint64{3, 7},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remotePeers(tt.info)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("remotePeers() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/grpc/codes"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Unnamed Qdrant vectors have no name of their own.
+// Targets that require a name use this one instead.
+const defaultVectorName = "vector"
+
+type sparseVector struct {
+	Indices []uint32  `json:"indices" parquet:"indices"`
+	Values  []float32 `json:"values" parquet:"values"`
+}
+
+// scrollQdrantSource reads every point of a Qdrant collection in batches and hands them to writeBatch.
+// The scroll offset is stored under offsetKey in the offsets of the target, so the source only needs to be readable.
+// Without offsets, offset tracking is disabled.
+func scrollQdrantSource(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, migration commons.MigrationConfig, offsets offsetStore, offsetKey string, writeBatch func([]*qdrant.RetrievedPoint) error) error {
+	sourcePointCount, err := sourceClient.Count(ctx, &qdrant.CountPoints{
+		CollectionName: sourceCollection,
+		Filter:         tenantFilter(migration),
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in source: %w", err)
+	}
+
+	trackOffsets := offsets != nil
+	limit := uint32(migration.BatchSize)
+
+	var offsetId *qdrant.PointId
+	offsetCount := uint64(0)
+
+	if trackOffsets && !migration.Restart {
+		id, count, err := offsets.getStartOffset(ctx, offsetKey)
+		if err != nil {
+			return fmt.Errorf("failed to get start offset: %w", err)
+		}
+		offsetId = id
+		offsetCount = count
+	}
+
+	bar, _ := pterm.DefaultProgressbar.WithTotal(int(sourcePointCount)).Start()
+	displayMigrationProgress(bar, offsetCount)
+
+	memory := newMemoryBudget(migration.MaxMemory).reader()
+	defer memory.release()
+	withPayload, withVectors := readSelectors(migration, "")
+
+	for {
+		if err := memory.reserve(ctx); err != nil {
+			return err
+		}
+
+		readStart := time.Now()
+		resp, err := scrollWithRetry(ctx, sourceClient, &qdrant.ScrollPoints{
+			CollectionName: sourceCollection,
+			Filter:         tenantFilter(migration),
+			Offset:         offsetId,
+			Limit:          &limit,
+			WithPayload:    withPayload,
+			WithVectors:    withVectors,
+		}, migration.ScrollRetries)
+		if err != nil {
+			return fmt.Errorf("failed to scroll data from source: %w", err)
+		}
+		currentReport.observeRead(time.Since(readStart), len(resp.GetResult()))
+		if err := memory.fit(ctx, pointsSize(resp.GetResult())); err != nil {
+			return err
+		}
+
+		points := resp.GetResult()
+		offsetId = resp.GetNextPageOffset()
+
+		if sampled := samplePoints(points, migration); len(sampled) > 0 {
+			if err := writeBatch(sampled); err != nil {
+				return fmt.Errorf("failed to insert data into target: %w", err)
+			}
+		}
+
+		offsetCount += uint64(len(points))
+
+		if trackOffsets {
+			err = offsets.storeStartOffset(ctx, offsetKey, offsetId, offsetCount)
+			if err != nil {
+				return err
+			}
+		}
+
+		bar.Add(len(points))
+
+		if offsetId == nil || sampleComplete(migration) {
+			break
+		}
+	}
+
+	pterm.Success.Printfln("Data migration finished successfully")
+
+	return nil
+}
+
+// scrollWithRetry reads a batch of points. Qdrant keeps no state of a scroll, its offset is the ID of the next point,
+// so a scroll that failed on a transient error, e.g. of a node restarting or shards moving, is resumed by scrolling
+// from the same offset again.
+func scrollWithRetry(ctx context.Context, client *qdrant.Client, request *qdrant.ScrollPoints, retries int) (*qdrant.ScrollResponse, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := client.GetPointsClient().Scroll(ctx, request)
+		if err == nil || attempt >= retries || ctx.Err() != nil || !isTransientError(err) {
+			return resp, err
+		}
+
+		from := "the start"
+		if request.GetOffset() != nil {
+			from = "point " + pointIDToString(request.GetOffset())
+		}
+		delay := retryDelay(err, attempt)
+		pterm.Warning.Printfln("Scroll of '%s' failed: %v, resuming from %s in %s", request.GetCollectionName(), err, from, delay)
+		currentReport.addRetry()
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// isTransientError reports whether a call to Qdrant failed for a reason that may go away by itself,
+// like a node that is restarting or a shard that is being moved.
+func isTransientError(err error) bool {
+	switch qdrantStatus(err).Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
+
+// getSourceVectorParams returns the dense vector configurations of a Qdrant collection, keyed by vector name.
+// The unnamed vector, if any, is keyed by defaultVectorName.
+func getSourceVectorParams(ctx context.Context, client *qdrant.Client, collection string) (map[string]*qdrant.VectorParams, map[string]*qdrant.SparseVectorParams, error) {
+	info, err := client.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get source collection info: %w", err)
+	}
+
+	dense := make(map[string]*qdrant.VectorParams)
+	vectorsConfig := info.GetConfig().GetParams().GetVectorsConfig()
+	if params := vectorsConfig.GetParams(); params != nil {
+		dense[defaultVectorName] = params
+	}
+	for name, params := range vectorsConfig.GetParamsMap().GetMap() {
+		dense[name] = params
+	}
+
+	return dense, info.GetConfig().GetParams().GetSparseVectorsConfig().GetMap(), nil
+}
+
+// pointIDToString returns the string form of a Qdrant point ID.
+func pointIDToString(id *qdrant.PointId) string {
+	if uuid := id.GetUuid(); uuid != "" {
+		return uuid
+	}
+	return strconv.FormatUint(id.GetNum(), 10)
+}
+
+// getPointVectors splits the vectors of a retrieved point into dense and sparse ones, keyed by vector name.
+// The unnamed vector, if any, is keyed by defaultVectorName. Multi-vectors are not supported by any of the targets and are skipped.
+func getPointVectors(point *qdrant.RetrievedPoint) (map[string][]float32, map[string]sparseVector) {
+	dense := make(map[string][]float32)
+	sparse := make(map[string]sparseVector)
+
+	add := func(name string, vector *qdrant.VectorOutput) {
+		switch {
+		case vector == nil:
+		case vector.GetDense() != nil:
+			dense[name] = vector.GetDense().GetData()
+		case vector.GetSparse() != nil:
+			sparse[name] = sparseVector{Indices: vector.GetSparse().GetIndices(), Values: vector.GetSparse().GetValues()}
+		case vector.GetMultiDense() != nil, vector.GetVectorsCount() > 0:
+		case vector.GetIndices() != nil:
+			sparse[name] = sparseVector{Indices: vector.GetIndices().GetData(), Values: vector.GetData()}
+		default:
+			dense[name] = vector.GetData()
+		}
+	}
+
+	if vector := point.GetVectors().GetVector(); vector != nil {
+		add(defaultVectorName, vector)
+	}
+	for name, vector := range point.GetVectors().GetVectors().GetVectors() {
+		add(name, vector)
+	}
+
+	return dense, sparse
+}
+
+// payloadToMap converts a Qdrant payload to plain Go values.
+func payloadToMap(payload map[string]*qdrant.Value) map[string]any {
+	result := make(map[string]any, len(payload))
+	for k, v := range payload {
+		result[k] = valueToAny(v)
+	}
+	return result
+}
+
+func valueToAny(value *qdrant.Value) any {
+	switch v := value.GetKind().(type) {
+	case *qdrant.Value_BoolValue:
+		return v.BoolValue
+	case *qdrant.Value_IntegerValue:
+		return v.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return v.DoubleValue
+	case *qdrant.Value_StringValue:
+		return v.StringValue
+	case *qdrant.Value_StructValue:
+		return payloadToMap(v.StructValue.GetFields())
+	case *qdrant.Value_ListValue:
+		list := make([]any, len(v.ListValue.GetValues()))
+		for i, item := range v.ListValue.GetValues() {
+			list[i] = valueToAny(item)
+		}
+		return list
+	default:
+		return nil
+	}
+}
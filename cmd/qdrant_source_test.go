@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_payloadToMap(t *testing.T) {
+	payload := qdrant.NewValueMap(map[string]any{
+		"name":   "qdrant",
+		"count":  3,
+		"score":  0.5,
+		"active": true,
+		"tags":   []any{"a", "b"},
+		"nested": map[string]any{"key": "value"},
+	})
+
+	expected := map[string]any{
+		"name":   "qdrant",
+		"count":  int64(3),
+		"score":  0.5,
+		"active": true,
+		"tags":   []any{"a", "b"},
+		"nested": map[string]any{"key": "value"},
+	}
+
+	got := payloadToMap(payload)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("payloadToMap() got = %v, expected %v", got, expected)
+	}
+}
+
+func Test_getPointVectors(t *testing.T) {
+	point := &qdrant.RetrievedPoint{
+		Id: qdrant.NewIDNum(1),
+		Vectors: &qdrant.VectorsOutput{
+			VectorsOptions: &qdrant.VectorsOutput_Vectors{
+				Vectors: &qdrant.NamedVectorsOutput{
+					Vectors: map[string]*qdrant.VectorOutput{
+						"dense":  {Data: []float32{0.1, 0.2}},
+						"sparse": {Data: []float32{0.3}, Indices: &qdrant.SparseIndices{Data: []uint32{7}}},
+					},
+				},
+			},
+		},
+	}
+
+	dense, sparse := getPointVectors(point)
+	if !reflect.DeepEqual(dense, map[string][]float32{"dense": {0.1, 0.2}}) {
+		t.Errorf("getPointVectors() dense = %v", dense)
+	}
+	if !reflect.DeepEqual(sparse, map[string]sparseVector{"sparse": {Indices: []uint32{7}, Values: []float32{0.3}}}) {
+		t.Errorf("getPointVectors() sparse = %v", sparse)
+	}
+	if got := pointIDToString(point.Id); got != "1" {
+		t.Errorf("pointIDToString() got = %v, expected 1", got)
+	}
+}
+
+func Test_isTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unavailable", err: status.Error(codes.Unavailable, "connection reset"), want: true},
+		{name: "wrapped deadline", err: fmt.Errorf("failed to scroll: %w", status.Error(codes.DeadlineExceeded, "timeout")), want: true},
+		{name: "internal", err: status.Error(codes.Internal, "shard is being transferred"), want: true},
+		{name: "rate limited with retry-after", err: &qdrant.QdrantResourceExhaustedError{Reason: "Rate limiting exceeded", RetryAfterS: 2}, want: true},
+		{name: "not found", err: status.Error(codes.NotFound, "collection not found")},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "forbidden")},
+		{name: "not a status", err: errors.New("boom")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientError(tt.err); got != tt.want {
+				t.Errorf("isTransientError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
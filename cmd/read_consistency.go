@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// getReadConsistency returns the read consistency configured for an endpoint, or nil to use the server default.
+func getReadConsistency(config commons.QdrantConfig) (*qdrant.ReadConsistency, error) {
+	value := strings.ToLower(strings.TrimSpace(config.ReadConsistency))
+
+	switch value {
+	case "":
+		return nil, nil
+	case "all":
+		return qdrant.NewReadConsistencyType(qdrant.ReadConsistencyType_All), nil
+	case "majority":
+		return qdrant.NewReadConsistencyType(qdrant.ReadConsistencyType_Majority), nil
+	case "quorum":
+		return qdrant.NewReadConsistencyType(qdrant.ReadConsistencyType_Quorum), nil
+	}
+
+	factor, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || factor == 0 {
+		return nil, fmt.Errorf("invalid read consistency '%s', expected all, majority, quorum or a number of replicas", config.ReadConsistency)
+	}
+	return qdrant.NewReadConsistencyFactor(factor), nil
+}
+
+// readConsistencyInterceptor sets the read consistency of every scroll, get and count that doesn't have one.
+func readConsistencyInterceptor(consistency *qdrant.ReadConsistency) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		switch request := req.(type) {
+		case *qdrant.ScrollPoints:
+			if request.ReadConsistency == nil {
+				request.ReadConsistency = consistency
+			}
+		case *qdrant.GetPoints:
+			if request.ReadConsistency == nil {
+				request.ReadConsistency = consistency
+			}
+		case *qdrant.CountPoints:
+			if request.ReadConsistency == nil {
+				request.ReadConsistency = consistency
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_getReadConsistency(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  commons.QdrantConfig
+		want    *qdrant.ReadConsistency
+		wantErr bool
+	}{
+		{name: "default", config: commons.QdrantConfig{}, want: nil},
+		{name: "majority", config: commons.QdrantConfig{ReadConsistency: "Majority"}, want: qdrant.NewReadConsistencyType(qdrant.ReadConsistencyType_Majority)},
+		{name: "factor", config: commons.QdrantConfig{ReadConsistency: "2"}, want: qdrant.NewReadConsistencyFactor(2)},
+		{name: "zero", config: commons.QdrantConfig{ReadConsistency: "0"}, wantErr: true},
+		{name: "unknown", config: commons.QdrantConfig{ReadConsistency: "some"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getReadConsistency(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getReadConsistency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("getReadConsistency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Number of point IDs compared at once when reconciling.
+const reconcileRangeSize = 1000
+
+// reconcileResult sums up a reconciliation of a target with its source.
+type reconcileResult struct {
+	ranges     int
+	recopied   int
+	points     int
+	unresolved int
+}
+
+// reconcileTarget walks the IDs of the source in ranges and counts the points of every range in the target.
+// Only the points the migration copies are walked, i.e. those of --migration.tenants and --migration.sample.
+// The points a range misses in the target are read from the source and written to the target again, with their shard keys,
+// transformed and written like during the migration. Ranges that still don't match afterwards are reported as unresolved.
+// With a hashField, the copied points get their hash like during the migration.
+func reconcileTarget(ctx context.Context, sourceClient *qdrant.Client, sourceCollection string, targetClient *qdrant.Client, targetCollection string, hashField string, migration commons.MigrationConfig) (reconcileResult, error) {
+	var result reconcileResult
+	var offset *qdrant.PointId
+	limit := uint32(reconcileRangeSize)
+
+	for {
+		resp, err := sourceClient.GetPointsClient().Scroll(ctx, &qdrant.ScrollPoints{
+			CollectionName: sourceCollection,
+			Filter:         tenantFilter(migration),
+			Offset:         offset,
+			Limit:          &limit,
+			WithPayload:    qdrant.NewWithPayload(false),
+			WithVectors:    qdrant.NewWithVectors(false),
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to scroll source IDs: %w", err)
+		}
+
+		ids := make([]*qdrant.PointId, 0, len(resp.GetResult()))
+		for _, point := range resp.GetResult() {
+			if migration.Sample > 0 && !inSample(point.GetId(), migration.Sample) {
+				continue
+			}
+			ids = append(ids, point.GetId())
+		}
+		offset = resp.GetNextPageOffset()
+
+		if len(ids) > 0 {
+			result.ranges++
+
+			count, err := countPointsWithIds(ctx, targetClient, targetCollection, ids)
+			if err != nil {
+				return result, err
+			}
+
+			if count < uint64(len(ids)) {
+				pterm.Warning.Printfln("Target has %d of %d points with IDs from %s to %s, copying the missing ones again",
+					count, len(ids), pointIDToString(ids[0]), pointIDToString(ids[len(ids)-1]))
+
+				missing, err := missingPointIds(ctx, targetClient, targetCollection, ids)
+				if err != nil {
+					return result, err
+				}
+				withPayload, withVectors := readSelectors(migration, hashField)
+				points, err := sourceClient.Get(ctx, &qdrant.GetPoints{
+					CollectionName: sourceCollection,
+					Ids:            missing,
+					WithPayload:    withPayload,
+					WithVectors:    withVectors,
+				})
+				if err != nil {
+					return result, fmt.Errorf("failed to get points from source: %w", err)
+				}
+
+				err = upsertRetrievedPoints(ctx, targetClient, targetCollection, points, hashField, migration)
+				if err != nil {
+					return result, err
+				}
+				result.recopied++
+				result.points += len(points)
+
+				count, err = countPointsWithIds(ctx, targetClient, targetCollection, ids)
+				if err != nil {
+					return result, err
+				}
+				if count < uint64(len(ids)) {
+					result.unresolved++
+				}
+			}
+		}
+
+		if offset == nil {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func countPointsWithIds(ctx context.Context, client *qdrant.Client, collection string, ids []*qdrant.PointId) (uint64, error) {
+	count, err := client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: collection,
+		Filter: &qdrant.Filter{
+			Must: []*qdrant.Condition{qdrant.NewHasID(ids...)},
+		},
+		Exact: qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count points in target: %w", err)
+	}
+	return count, nil
+}
+
+// missingPointIds returns the IDs the collection has no points with.
+func missingPointIds(ctx context.Context, client *qdrant.Client, collection string, ids []*qdrant.PointId) ([]*qdrant.PointId, error) {
+	existing, err := client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: collection,
+		Ids:            ids,
+		WithPayload:    qdrant.NewWithPayload(false),
+		WithVectors:    qdrant.NewWithVectors(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get existing points from target: %w", err)
+	}
+
+	found := make(map[string]bool, len(existing))
+	for _, point := range existing {
+		found[pointIDToString(point.GetId())] = true
+	}
+	var missing []*qdrant.PointId
+	for _, id := range ids {
+		if !found[pointIDToString(id)] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// upsertRetrievedPoints writes points read from a source to the target, each with the shard key it has in the source.
+// They go through the same transformations and writes as the points of the migration, so they end up the same in the target.
+func upsertRetrievedPoints(ctx context.Context, client *qdrant.Client, collection string, points []*qdrant.RetrievedPoint, hashField string, migration commons.MigrationConfig) error {
+	var keys []*qdrant.ShardKey
+	groups := make(map[string][]*qdrant.RetrievedPoint)
+	for _, point := range points {
+		name := ""
+		if point.GetShardKey() != nil {
+			name = shardKeyName(point.GetShardKey())
+		}
+		if _, ok := groups[name]; !ok {
+			keys = append(keys, point.GetShardKey())
+		}
+		groups[name] = append(groups[name], point)
+	}
+
+	for _, key := range keys {
+		name := ""
+		var shardKeySelector *qdrant.ShardKeySelector
+		if key != nil {
+			name = shardKeyName(key)
+			shardKeySelector = &qdrant.ShardKeySelector{ShardKeys: []*qdrant.ShardKey{key}}
+		}
+
+		targetPoints := retrievedToPointStructs(groups[name])
+		if hashField != "" {
+			err := addPointHashes(targetPoints, hashField)
+			if err != nil {
+				return err
+			}
+		}
+
+		targetPoints, err := transformPayloads(ctx, targetPoints, migration)
+		if err != nil {
+			return err
+		}
+
+		err = upsertPoints(ctx, client, &qdrant.UpsertPoints{
+			CollectionName:   collection,
+			Points:           targetPoints,
+			Wait:             qdrant.PtrOf(true),
+			ShardKeySelector: shardKeySelector,
+		}, migration)
+		if err != nil {
+			return fmt.Errorf("failed to insert data into target: %w", err)
+		}
+	}
+	return nil
+}
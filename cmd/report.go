@@ -0,0 +1,464 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// runReport is the audit record of a single run, written to --report-file when the run ends.
+type runReport struct {
+	Version         string                 `json:"version"`
+	Build           string                 `json:"build"`
+	Command         string                 `json:"command"`
+	Config          map[string]any         `json:"config"`
+	Source          *reportEndpoint        `json:"source,omitempty"`
+	Target          *reportEndpoint        `json:"target,omitempty"`
+	StartedAt       time.Time              `json:"started_at"`
+	FinishedAt      time.Time              `json:"finished_at"`
+	DurationSeconds float64                `json:"duration_seconds"`
+	SourcePoints    *uint64                `json:"source_points,omitempty"`
+	TargetPoints    *uint64                `json:"target_points,omitempty"`
+	PointsPerSecond float64                `json:"points_per_second,omitempty"`
+	Verification    []reportCheck          `json:"verification,omitempty"`
+	Oversize        *reportOversize        `json:"oversize,omitempty"`
+	VectorAnomalies *reportVectorAnomalies `json:"vector_anomalies,omitempty"`
+	TypeConflicts   map[string][]string    `json:"payload_type_conflicts,omitempty"`
+	RenamedKeys     map[string]string      `json:"renamed_payload_keys,omitempty"`
+	Embedding       *reportEmbedding       `json:"embedding,omitempty"`
+	ExpiredPoints   uint64                 `json:"expired_points,omitempty"`
+	SkippedPoints   uint64                 `json:"skipped_points,omitempty"`
+	PointsRead      uint64                 `json:"points_read,omitempty"`
+	PointsWritten   uint64                 `json:"points_written,omitempty"`
+	BytesSent       uint64                 `json:"bytes_sent,omitempty"`
+	BytesReceived   uint64                 `json:"bytes_received,omitempty"`
+	Retries         uint64                 `json:"retries,omitempty"`
+	ReadLatency     *reportLatencies       `json:"source_read_latency,omitempty"`
+	WriteLatency    *reportLatencies       `json:"target_write_latency,omitempty"`
+	Error           string                 `json:"error,omitempty"`
+
+	reads  latencyHistogram
+	writes latencyHistogram
+	lock   sync.Mutex
+}
+
+type reportEndpoint struct {
+	Provider   string `json:"provider"`
+	Collection string `json:"collection"`
+}
+
+// reportCheck is the outcome of a check of the target after the migration, like a reconciliation.
+type reportCheck struct {
+	Name    string `json:"name"`
+	Target  string `json:"target"`
+	Passed  bool   `json:"passed"`
+	Details string `json:"details"`
+}
+
+// reportOversize counts the payload values over --migration.max-payload-value-size, by what was done with them.
+type reportOversize struct {
+	TruncatedValues  uint64 `json:"truncated_values"`
+	DroppedFields    uint64 `json:"dropped_fields"`
+	DeadLetterPoints uint64 `json:"dead_letter_points"`
+}
+
+// reportVectorAnomalies counts the vectors with NaN or infinite values or wrong dimensions, by anomaly,
+// and the points that were written to the dead letter file because of them.
+type reportVectorAnomalies struct {
+	Vectors          map[string]uint64 `json:"vectors"`
+	DeadLetterPoints uint64            `json:"dead_letter_points"`
+}
+
+// reportEmbedding accounts for the texts embedded by a re-embedding run, and what they cost.
+type reportEmbedding struct {
+	Model            string  `json:"model"`
+	Texts            uint64  `json:"texts"`
+	CachedTexts      uint64  `json:"cached_texts,omitempty"`
+	Tokens           uint64  `json:"tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// currentReport collects the outcome of the current run. Commands add to it as they go,
+// and once the run ends, its summary is printed and it's written to --report-file if set.
+var currentReport *runReport
+
+// Flag names containing one of these hold secrets, which are never written to reports.
+var secretFlagNames = []string{"api-key", "password", "token", "secret", "access-key", "encryption-key"}
+
+func newRunReport(ctx *kong.Context, version, build string) *runReport {
+	return &runReport{
+		Version:   version,
+		Build:     build,
+		Command:   ctx.Command(),
+		Config:    flagValues(ctx, "report-file", "resume-token"),
+		StartedAt: time.Now(),
+	}
+}
+
+// flagValues returns the redacted values of all flags of the command, except help, version and the skipped ones.
+func flagValues(ctx *kong.Context, skip ...string) map[string]any {
+	values := make(map[string]any)
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "help" || flag.Name == "version" || slices.Contains(skip, flag.Name) {
+			continue
+		}
+		values[flag.Name] = redactFlagValue(flag.Name, flag.Target.Interface())
+	}
+	return values
+}
+
+func redactFlagValue(name string, value any) any {
+	for _, secret := range secretFlagNames {
+		if strings.Contains(name, secret) {
+			if s, ok := value.(string); ok && s == "" {
+				return ""
+			}
+			return "REDACTED"
+		}
+	}
+
+	// Credentials in URLs, e.g. of databases or proxies.
+	if s, ok := value.(string); ok {
+		if parsed, err := url.Parse(s); err == nil && parsed.User != nil {
+			if _, hasPassword := parsed.User.Password(); hasPassword {
+				parsed.User = url.UserPassword(parsed.User.Username(), "REDACTED")
+				return parsed.String()
+			}
+		}
+	}
+
+	return value
+}
+
+func (r *runReport) setRoute(sourceProvider, sourceCollection, targetProvider, targetCollection string) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Source = &reportEndpoint{Provider: sourceProvider, Collection: sourceCollection}
+	r.Target = &reportEndpoint{Provider: targetProvider, Collection: targetCollection}
+}
+
+func (r *runReport) setPointCounts(source, target uint64) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.SourcePoints = &source
+	r.TargetPoints = &target
+}
+
+func (r *runReport) addCheck(check reportCheck) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Verification = append(r.Verification, check)
+}
+
+func (r *runReport) addOversize(truncated, dropped, deadLettered uint64) {
+	if r == nil || truncated+dropped+deadLettered == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Oversize == nil {
+		r.Oversize = &reportOversize{}
+	}
+	r.Oversize.TruncatedValues += truncated
+	r.Oversize.DroppedFields += dropped
+	r.Oversize.DeadLetterPoints += deadLettered
+}
+
+func (r *runReport) addVectorAnomalies(counts map[string]uint64, deadLettered uint64) {
+	if r == nil || len(counts) == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.VectorAnomalies == nil {
+		r.VectorAnomalies = &reportVectorAnomalies{Vectors: make(map[string]uint64)}
+	}
+	for anomaly, count := range counts {
+		r.VectorAnomalies.Vectors[anomaly] += count
+	}
+	r.VectorAnomalies.DeadLetterPoints += deadLettered
+}
+
+// setPayloadTypeConflict records the types of a payload field that has values of more than one type.
+func (r *runReport) setPayloadTypeConflict(path string, types []string) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.TypeConflicts == nil {
+		r.TypeConflicts = make(map[string][]string)
+	}
+	r.TypeConflicts[path] = slices.Clone(types)
+}
+
+// addRenamedKey records the new path of a payload key renamed by --migration.payload-keys.
+func (r *runReport) addRenamedKey(path, renamed string) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.RenamedKeys == nil {
+		r.RenamedKeys = make(map[string]string)
+	}
+	r.RenamedKeys[path] = renamed
+}
+
+func (r *runReport) addEmbeddingUsage(model string, texts, tokens int, pricePerMillionTokens float64) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Embedding == nil {
+		r.Embedding = &reportEmbedding{Model: model}
+	}
+	r.Embedding.Texts += uint64(texts)
+	r.Embedding.Tokens += uint64(tokens)
+	r.Embedding.EstimatedCostUSD = float64(r.Embedding.Tokens) / 1e6 * pricePerMillionTokens
+}
+
+func (r *runReport) addCachedEmbeddings(model string, texts int) {
+	if r == nil || texts == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Embedding == nil {
+		r.Embedding = &reportEmbedding{Model: model}
+	}
+	r.Embedding.CachedTexts += uint64(texts)
+}
+
+func (r *runReport) addExpired(points uint64) {
+	if r == nil || points == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ExpiredPoints += points
+}
+
+func (r *runReport) addSkipped(points uint64) {
+	if r == nil || points == 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.SkippedPoints += points
+}
+
+// addTransfer accounts for the sizes of the messages of a call to Qdrant.
+func (r *runReport) addTransfer(sent, received uint64) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.BytesSent += sent
+	r.BytesReceived += received
+}
+
+// addWritten accounts for points written to the target collection. It's called where a batch of the migration
+// is written, so offsets, rollback state and other bookkeeping aren't counted.
+func (r *runReport) addWritten(points int) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.PointsWritten += uint64(points)
+}
+
+func (r *runReport) addRetry() {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Retries++
+}
+
+// observeRead accounts for a batch read from the source: the time it took, and its points.
+func (r *runReport) observeRead(d time.Duration, points int) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.reads.observe(d)
+	r.PointsRead += uint64(points)
+}
+
+// observeWrite accounts for the time a batch took to be written to the target.
+func (r *runReport) observeWrite(d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.writes.observe(d)
+}
+
+// finish completes the report with the outcome of the run.
+func (r *runReport) finish(runErr error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.FinishedAt = time.Now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	if r.TargetPoints != nil && r.DurationSeconds > 0 {
+		r.PointsPerSecond = float64(*r.TargetPoints) / r.DurationSeconds
+	}
+	r.ReadLatency = r.reads.summary()
+	r.WriteLatency = r.writes.summary()
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+}
+
+// printSummary prints the outcome of a finished run as a table. Commands that don't migrate anything have no route,
+// and print no summary.
+func (r *runReport) printSummary() {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Source == nil {
+		return
+	}
+
+	pterm.DefaultSection.Println("Summary")
+	_ = pterm.DefaultTable.
+		WithHasHeader(false).
+		WithBoxed(true).
+		WithData(r.summaryRows()).
+		Render()
+	pterm.Println()
+}
+
+func (r *runReport) summaryRows() pterm.TableData {
+	count := func(n *uint64) string {
+		if n == nil {
+			return "-"
+		}
+		return strconv.FormatUint(*n, 10)
+	}
+
+	status := "succeeded"
+	if r.Error != "" {
+		status = "failed"
+	}
+	failed := uint64(0)
+	if r.Oversize != nil {
+		failed = r.Oversize.DeadLetterPoints
+	}
+	if r.VectorAnomalies != nil {
+		failed += r.VectorAnomalies.DeadLetterPoints
+	}
+	duration := time.Duration(r.DurationSeconds * float64(time.Second))
+	bytes := r.BytesSent + r.BytesReceived
+	throughput := fmt.Sprintf("%.0f points/s", r.PointsPerSecond)
+	if r.DurationSeconds > 0 && bytes > 0 {
+		throughput += fmt.Sprintf(", %s/s", commons.ByteSize(float64(bytes)/r.DurationSeconds))
+	}
+
+	rows := pterm.TableData{
+		{pterm.FgLightCyan.Sprint("Status:"), status},
+		{pterm.FgLightCyan.Sprint("From → To:"), fmt.Sprintf("%s@%s  →  %s@%s", r.Source.Collection, r.Source.Provider, r.Target.Collection, r.Target.Provider)},
+		{pterm.FgLightCyan.Sprint("Points read:"), strconv.FormatUint(r.PointsRead, 10)},
+		{pterm.FgLightCyan.Sprint("Points written:"), strconv.FormatUint(r.PointsWritten, 10)},
+		{pterm.FgLightCyan.Sprint("Points skipped:"), strconv.FormatUint(r.SkippedPoints+r.ExpiredPoints, 10)},
+		{pterm.FgLightCyan.Sprint("Points failed:"), strconv.FormatUint(failed, 10)},
+		{pterm.FgLightCyan.Sprint("Target points:"), count(r.TargetPoints)},
+		{pterm.FgLightCyan.Sprint("Transferred:"), fmt.Sprintf("%s sent, %s received", commons.ByteSize(r.BytesSent), commons.ByteSize(r.BytesReceived))},
+		{pterm.FgLightCyan.Sprint("Throughput:"), throughput},
+		{pterm.FgLightCyan.Sprint("Retries:"), strconv.FormatUint(r.Retries, 10)},
+		{pterm.FgLightCyan.Sprint("Elapsed:"), duration.Round(time.Second).String()},
+		{pterm.FgLightCyan.Sprint("Verification:"), r.verificationStatus()},
+	}
+	if r.VectorAnomalies != nil {
+		rows = append(rows, []string{pterm.FgLightCyan.Sprint("Vector anomalies:"), r.VectorAnomalies.String()})
+	}
+	if r.ReadLatency != nil {
+		rows = append(rows, []string{pterm.FgLightCyan.Sprint("Source reads:"), r.ReadLatency.String()})
+	}
+	if r.WriteLatency != nil {
+		rows = append(rows, []string{pterm.FgLightCyan.Sprint("Target writes:"), r.WriteLatency.String()})
+	}
+	return rows
+}
+
+func (a *reportVectorAnomalies) String() string {
+	anomalies := make([]string, 0, len(a.Vectors))
+	for _, anomaly := range []string{anomalyNaN, anomalyInf, anomalyDimensions} {
+		if count := a.Vectors[anomaly]; count > 0 {
+			anomalies = append(anomalies, fmt.Sprintf("%d with %s", count, anomaly))
+		}
+	}
+	return strings.Join(anomalies, ", ")
+}
+
+// verificationStatus sums up the checks of the target, or compares the point counts if there were none.
+func (r *runReport) verificationStatus() string {
+	if len(r.Verification) == 0 {
+		switch {
+		case r.SourcePoints == nil || r.TargetPoints == nil:
+			return "not verified"
+		case *r.SourcePoints == *r.TargetPoints:
+			return "point counts match"
+		default:
+			return fmt.Sprintf("point counts differ, %d in source and %d in target", *r.SourcePoints, *r.TargetPoints)
+		}
+	}
+
+	var failed []string
+	for _, check := range r.Verification {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("%s of %s", check.Name, check.Target))
+		}
+	}
+	if len(failed) > 0 {
+		return "failed: " + strings.Join(failed, ", ")
+	}
+	return fmt.Sprintf("passed %d check(s)", len(r.Verification))
+}
+
+// write writes the finished report to path.
+func (r *runReport) write(path string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	err = os.WriteFile(path, append(data, '\n'), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+const resumeTokenPrefix = "mig1."
+
+// Flags that don't change what is migrated, so they may differ between a failed run and its resumption.
+var resumeIgnoredFlags = []string{
+	"debug", "trace", "skip-tls-verification", "proxy",
+	"grpc-keepalive-time", "grpc-keepalive-timeout", "grpc-call-timeout", "grpc-idle-timeout",
+	"pprof-addr", "report-file", "max-bandwidth", "run-window", "resume-token",
+}
+
+// resumeToken is everything needed to continue a failed run: the command, a hash of its configuration,
+// and the positions its streams had reached.
+type resumeToken struct {
+	Command     string             `json:"cmd"`
+	ConfigHash  string             `json:"cfg"`
+	Checkpoints []resumeCheckpoint `json:"pos,omitempty"`
+}
+
+type resumeCheckpoint struct {
+	Key   string `json:"k"`
+	Num   uint64 `json:"n,omitempty"`
+	UUID  string `json:"u,omitempty"`
+	Count uint64 `json:"c"`
+}
+
+// configHash hashes the flags that determine what is migrated. Secrets are redacted first,
+// so rotating an API key doesn't invalidate a token.
+func configHash(ctx *kong.Context) (string, error) {
+	data, err := json.Marshal(flagValues(ctx, resumeIgnoredFlags...))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// newResumeToken returns the token for the current run, or an empty string if no stream has a position to resume from.
+// Sampled runs get no token, since they always start from the beginning of the source.
+func newResumeToken(ctx *kong.Context) (string, error) {
+	checkpoints := commons.Checkpoints()
+	if len(checkpoints) == 0 || sampledRun(ctx) {
+		return "", nil
+	}
+
+	hash, err := configHash(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	token := resumeToken{Command: ctx.Command(), ConfigHash: hash}
+	for _, checkpoint := range checkpoints {
+		token.Checkpoints = append(token.Checkpoints, resumeCheckpoint{
+			Key:   checkpoint.Key,
+			Num:   checkpoint.Offset.GetNum(),
+			UUID:  checkpoint.Offset.GetUuid(),
+			Count: checkpoint.Count,
+		})
+	}
+
+	return encodeResumeToken(token)
+}
+
+func encodeResumeToken(token resumeToken) (string, error) {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode resume token: %w", err)
+	}
+	return resumeTokenPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeResumeToken(encoded string) (resumeToken, error) {
+	var token resumeToken
+
+	data, ok := strings.CutPrefix(strings.TrimSpace(encoded), resumeTokenPrefix)
+	if !ok {
+		return token, errors.New("invalid resume token")
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return token, fmt.Errorf("invalid resume token: %w", err)
+	}
+	err = json.Unmarshal(decoded, &token)
+	if err != nil {
+		return token, fmt.Errorf("invalid resume token: %w", err)
+	}
+
+	return token, nil
+}
+
+// applyResumeToken checks that a token was issued for the same command and configuration,
+// and makes the migration continue from the positions in it. It's called after the flags are rewritten
+// for the mode of the run, like newResumeToken, so both hash the same configuration.
+func applyResumeToken(ctx *kong.Context, encoded string) error {
+	token, err := decodeResumeToken(encoded)
+	if err != nil {
+		return err
+	}
+
+	if sampledRun(ctx) {
+		return errors.New("--migration.sample and --migration.limit can't be combined with --resume-token, sampled runs always start from the beginning")
+	}
+
+	if token.Command != ctx.Command() {
+		return fmt.Errorf("the resume token is for the command '%s', not '%s'", token.Command, ctx.Command())
+	}
+	hash, err := configHash(ctx)
+	if err != nil {
+		return err
+	}
+	if token.ConfigHash != hash {
+		return errors.New("the resume token is for a different configuration, run the command with the same flags as the failed run")
+	}
+
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "migration.restart" && flag.Target.Bool() {
+			return errors.New("--migration.restart can't be combined with --resume-token")
+		}
+	}
+
+	checkpoints := make([]commons.Checkpoint, 0, len(token.Checkpoints))
+	for _, checkpoint := range token.Checkpoints {
+		offset := qdrant.NewIDNum(checkpoint.Num)
+		if checkpoint.UUID != "" {
+			offset = qdrant.NewIDUUID(checkpoint.UUID)
+		}
+		checkpoints = append(checkpoints, commons.Checkpoint{Key: checkpoint.Key, Offset: offset, Count: checkpoint.Count})
+	}
+	commons.SetResumeCheckpoints(checkpoints)
+
+	return nil
+}
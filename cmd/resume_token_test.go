@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_resumeTokenRoundTrip(t *testing.T) {
+	token := resumeToken{
+		Command:    "qdrant",
+		ConfigHash: "0123456789abcdef",
+		Checkpoints: []resumeCheckpoint{
+			{Key: "source-collection", Num: 42, Count: 1000},
+			{Key: "source-collection-range-1", UUID: "5c56c793-69f3-4fbf-87e6-c4bf54c28c26", Count: 500},
+		},
+	}
+
+	encoded, err := encodeResumeToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := decodeResumeToken(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, token) {
+		t.Errorf("decodeResumeToken() = %+v, want %+v", decoded, token)
+	}
+}
+
+func Test_decodeResumeTokenInvalid(t *testing.T) {
+	for _, encoded := range []string{"", "mig1.", "mig1.!!!", "other.eyJ9"} {
+		if _, err := decodeResumeToken(encoded); err == nil {
+			t.Errorf("decodeResumeToken(%q) succeeded, expected an error", encoded)
+		}
+	}
+}
+
+func Test_resumeTokenRoundTripOfRun(t *testing.T) {
+	args := []string{"qdrant", "--source.url", "http://localhost:6334", "--source.collection", "source",
+		"--target.url", "http://localhost:6334", "--target.collection", "target"}
+	commons.RecordCheckpoint("resume-token-round-trip", qdrant.NewIDNum(42), 1000)
+
+	t.Run("full run", func(t *testing.T) {
+		ctx, _, err := parseCommand(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := newResumeToken(ctx)
+		if err != nil || token == "" {
+			t.Fatalf("newResumeToken() = %q, %v, want a token", token, err)
+		}
+
+		ctx, _, err = parseCommand(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := applyResumeToken(ctx, token); err != nil {
+			t.Errorf("applyResumeToken() = %v, want the token of the same run to be accepted", err)
+		}
+	})
+
+	for _, flag := range []string{"--migration.sample=1%", "--migration.limit=100"} {
+		t.Run(flag, func(t *testing.T) {
+			ctx, _, err := parseCommand(append(args, flag))
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := newResumeToken(ctx)
+			if err != nil || token != "" {
+				t.Errorf("newResumeToken() = %q, %v, want no token for a sampled run", token, err)
+			}
+
+			full, _, err := parseCommand(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err = newResumeToken(full)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = applyResumeToken(ctx, token)
+			if err == nil || !strings.Contains(err.Error(), "sampled runs") {
+				t.Errorf("applyResumeToken() = %v, want sampled runs to be rejected", err)
+			}
+		})
+	}
+}
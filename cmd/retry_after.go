@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pterm/pterm"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// retryAfterMetadata is the metadata key servers like Qdrant tell rate limited clients when to retry with,
+// like the Retry-After header of HTTP.
+const retryAfterMetadata = "retry-after"
+
+// retryAfterError is an error of a call the server said when to retry. It keeps the status of the call.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+func (e *retryAfterError) GRPCStatus() *status.Status { return status.Convert(e.err) }
+
+// retryAfterInterceptor keeps the delay a server asks failed calls to be retried after, in the headers or trailers
+// of the call, or the RetryInfo details of its status, with the error.
+func retryAfterInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		var header, trailer metadata.MD
+		err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+		if err == nil {
+			return nil
+		}
+		if delay := grpcRetryAfter(err, header, trailer); delay > 0 {
+			return &retryAfterError{err: err, delay: delay}
+		}
+		return err
+	}
+}
+
+// grpcRetryAfter returns the delay a server asks a failed call to be retried after, or 0 if it doesn't.
+func grpcRetryAfter(err error, mds ...metadata.MD) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay().AsDuration() > 0 {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	for _, md := range mds {
+		for _, value := range md.Get(retryAfterMetadata) {
+			if delay := parseRetryAfter(value); delay > 0 {
+				return delay
+			}
+		}
+	}
+	return 0
+}
+
+// qdrantStatus returns the status of a failed call. The Qdrant client turns calls rate limited with a retry-after
+// trailer into an error of its own without the status, so it's restored for them.
+func qdrantStatus(err error) *status.Status {
+	var exhausted *qdrant.QdrantResourceExhaustedError
+	if errors.As(err, &exhausted) {
+		return status.New(codes.ResourceExhausted, exhausted.Reason)
+	}
+	return status.Convert(err)
+}
+
+// retryDelay is the delay before retrying a failed call: the one the server asked for, if it did, or else backoffDelay.
+func retryDelay(err error, attempt int) time.Duration {
+	var exhausted *qdrant.QdrantResourceExhaustedError
+	if errors.As(err, &exhausted) && exhausted.RetryAfterS > 0 {
+		return time.Duration(exhausted.RetryAfterS) * time.Second
+	}
+	var retryAfter *retryAfterError
+	if errors.As(err, &retryAfter) {
+		return retryAfter.delay
+	}
+	return backoffDelay(attempt)
+}
+
+// sourceRateLimitRetries is the number of times a request to the API of a source that was rate limited is retried.
+const sourceRateLimitRetries = 5
+
+// rateLimitedTransport retries requests that were rate limited with 429 Too Many Requests, after the delay of their
+// Retry-After header, or backoffDelay without one, for the clients of sources that don't retry them themselves.
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	retries int
+}
+
+// newRateLimitedClient returns an HTTP client that retries rate limited requests, sent with the given transport,
+// or the default one if it's nil.
+func newRateLimitedClient(base http.RoundTripper) *http.Client {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &http.Client{Transport: &rateLimitedTransport{base: base, retries: sourceRateLimitRetries}}
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= t.retries {
+			return resp, err
+		}
+		// A body that can't be read again can't be sent again.
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil
+		}
+
+		delay := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if delay == 0 {
+			delay = backoffDelay(attempt)
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		_ = resp.Body.Close()
+		pterm.Warning.Printfln("%s was rate limited, retrying in %s", req.URL.Redacted(), delay)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// rateLimitRetryInterceptor retries calls that were rate limited with RESOURCE_EXHAUSTED, after the delay the server
+// asks for, or backoffDelay if it doesn't, for the gRPC clients of sources that don't retry them themselves.
+func rateLimitRetryInterceptor(retries int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		for attempt := 0; ; attempt++ {
+			var header, trailer metadata.MD
+			err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+			if status.Code(err) != codes.ResourceExhausted || attempt >= retries {
+				return err
+			}
+
+			delay := grpcRetryAfter(err, header, trailer)
+			if delay == 0 {
+				delay = backoffDelay(attempt)
+			}
+			pterm.Warning.Printfln("Call of %s was rate limited, retrying in %s", method, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+type rateLimitedQdrantServer struct {
+	qdrant.UnimplementedQdrantServer
+	code       codes.Code
+	retryAfter string
+}
+
+func (s *rateLimitedQdrantServer) HealthCheck(ctx context.Context, _ *qdrant.HealthCheckRequest) (*qdrant.HealthCheckReply, error) {
+	if s.retryAfter != "" {
+		_ = grpc.SetTrailer(ctx, metadata.Pairs(retryAfterMetadata, s.retryAfter))
+	}
+	return nil, status.Error(s.code, "Rate limiting exceeded: Read rate limit exceeded")
+}
+
+func TestRetryAfterInterceptor(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       codes.Code
+		retryAfter string
+		want       time.Duration
+	}{
+		{"rate limited", codes.ResourceExhausted, "3", 3 * time.Second},
+		{"unavailable", codes.Unavailable, "4", 4 * time.Second},
+		{"no delay", codes.ResourceExhausted, "", backoffDelay(2)},
+		{"invalid delay", codes.Unavailable, "soon", backoffDelay(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			server := grpc.NewServer()
+			qdrant.RegisterQdrantServer(server, &rateLimitedQdrantServer{code: tt.code, retryAfter: tt.retryAfter})
+			go func() { _ = server.Serve(listener) }()
+			defer server.Stop()
+
+			url := "http://" + listener.Addr().String()
+			host, port, useTLS, err := parseQdrantUrl(url)
+			if err != nil {
+				t.Fatalf("parseQdrantUrl() error = %v", err)
+			}
+			client, err := connectToQdrant(&Globals{}, host, port, commons.QdrantConfig{Url: url}, useTLS)
+			if err != nil {
+				t.Fatalf("connectToQdrant() error = %v", err)
+			}
+			defer client.Close()
+
+			_, err = client.HealthCheck(context.Background())
+			if code := qdrantStatus(err).Code(); code != tt.code {
+				t.Fatalf("HealthCheck() error = %v, want %s", err, tt.code)
+			}
+			err = fmt.Errorf("failed to check health: %w", err)
+			if got := retryDelay(err, 2); got != tt.want {
+				t.Errorf("retryDelay() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_grpcRetryAfter(t *testing.T) {
+	withRetryInfo, err := status.New(codes.ResourceExhausted, "rate limited").WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)})
+	if err != nil {
+		t.Fatalf("WithDetails() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		mds  []metadata.MD
+		want time.Duration
+	}{
+		{"retry info", withRetryInfo.Err(), nil, 1500 * time.Millisecond},
+		{"trailer", status.Error(codes.ResourceExhausted, "rate limited"), []metadata.MD{nil, metadata.Pairs(retryAfterMetadata, "2")}, 2 * time.Second},
+		{"header", status.Error(codes.Unavailable, "overloaded"), []metadata.MD{metadata.Pairs(retryAfterMetadata, "5")}, 5 * time.Second},
+		{"none", status.Error(codes.ResourceExhausted, "rate limited"), []metadata.MD{metadata.Pairs("other", "1")}, 0},
+		{"not a status", errors.New("failed"), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := grpcRetryAfter(tt.err, tt.mds...); got != tt.want {
+				t.Errorf("grpcRetryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	resp, err := newRateLimitedClient(nil).Post(server.URL, "text/plain", strings.NewReader("query"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d after a retry", resp.StatusCode, http.StatusOK)
+	}
+	if len(bodies) != 2 || bodies[1] != "query" {
+		t.Errorf("got request bodies %q, want the body sent again", bodies)
+	}
+}
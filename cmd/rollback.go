@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// rollbackState is what a rollback of a target collection undoes: its creation by a migration,
+// and the collections that aliases pointed to before they were switched to it.
+type rollbackState struct {
+	Collection string
+	Created    bool
+	CreatedAt  string
+	// Aliases maps every switched alias to the collection it pointed to before, or to an empty string if it didn't exist.
+	Aliases map[string]string
+	// Snapshot is the name of the snapshot of the collection taken before the migration wrote to it, if any.
+	Snapshot   string
+	SnapshotAt string
+}
+
+// getRollbackStateId returns the ID of the point that holds the rollback state of a collection in the offsets collection.
+func getRollbackStateId(collection string) *qdrant.PointId {
+	return qdrant.NewIDUUID(uuid.NewSHA1(uuid.NameSpaceURL, []byte("rollback/"+collection)).String())
+}
+
+func getRollbackState(ctx context.Context, client *qdrant.Client, stateCollection, collection string) (*rollbackState, error) {
+	exists, err := client.CollectionExists(ctx, stateCollection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	state := &rollbackState{Collection: collection, Aliases: make(map[string]string)}
+	if !exists {
+		return state, nil
+	}
+
+	points, err := client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: stateCollection,
+		Ids:            []*qdrant.PointId{getRollbackStateId(collection)},
+		WithPayload:    qdrant.NewWithPayload(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rollback state: %w", err)
+	}
+	if len(points) == 0 {
+		return state, nil
+	}
+
+	payload := points[0].GetPayload()
+	state.Created = payload["created"].GetBoolValue()
+	state.CreatedAt = payload["created_at"].GetStringValue()
+	state.Snapshot = payload["snapshot"].GetStringValue()
+	state.SnapshotAt = payload["snapshot_at"].GetStringValue()
+	for alias, previous := range payload["aliases"].GetStructValue().GetFields() {
+		state.Aliases[alias] = previous.GetStringValue()
+	}
+	return state, nil
+}
+
+func storeRollbackState(ctx context.Context, client *qdrant.Client, stateCollection string, state *rollbackState) error {
+	err := commons.PrepareOffsetsCollection(ctx, stateCollection, client)
+	if err != nil {
+		return fmt.Errorf("failed to prepare migration marker collection: %w", err)
+	}
+
+	aliases := make(map[string]any, len(state.Aliases))
+	for alias, previous := range state.Aliases {
+		aliases[alias] = previous
+	}
+	_, err = client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: stateCollection,
+		Points: []*qdrant.PointStruct{
+			{
+				Id: getRollbackStateId(state.Collection),
+				Payload: qdrant.NewValueMap(map[string]any{
+					"collection":  state.Collection,
+					"created":     state.Created,
+					"created_at":  state.CreatedAt,
+					"aliases":     aliases,
+					"snapshot":    state.Snapshot,
+					"snapshot_at": state.SnapshotAt,
+				}),
+				Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{}),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store rollback state: %w", err)
+	}
+	return nil
+}
+
+// recordCreatedCollection records that a migration created a target collection, so a rollback deletes it.
+func recordCreatedCollection(ctx context.Context, client *qdrant.Client, stateCollection, collection string) error {
+	state, err := getRollbackState(ctx, client, stateCollection, collection)
+	if err != nil {
+		return err
+	}
+	state.Created = true
+	state.CreatedAt = time.Now().Format(time.RFC3339)
+	return storeRollbackState(ctx, client, stateCollection, state)
+}
+
+// backupTargetCollection snapshots a target collection that already has points before the migration writes to it,
+// with --migration.backup-target-first, and records the snapshot, so a rollback restores the collection from it.
+// Only the first snapshot is recorded, which is the state before the migration, so resumed runs don't take another one.
+func backupTargetCollection(ctx context.Context, client *qdrant.Client, collection string, migration commons.MigrationConfig) error {
+	if !migration.BackupTargetFirst || isSinkTarget(migration) {
+		return nil
+	}
+
+	exists, err := client.CollectionExists(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to check if collection exists: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	state, err := getRollbackState(ctx, client, migration.OffsetsCollection, collection)
+	if err != nil {
+		return err
+	}
+	if state.Created {
+		pterm.Info.Printfln("Target collection '%s' was created by the migration, a rollback deletes it instead of restoring a snapshot", collection)
+		return nil
+	}
+	if state.Snapshot != "" {
+		pterm.Info.Printfln("Target collection '%s' was backed up as snapshot %s at %s", collection, state.Snapshot, state.SnapshotAt)
+		return nil
+	}
+	count, err := client.Count(ctx, &qdrant.CountPoints{CollectionName: collection, Exact: qdrant.PtrOf(true)})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+	if count == 0 {
+		return nil
+	}
+	err = checkSinglePeer(ctx, client, collection)
+	if err != nil {
+		return fmt.Errorf("--migration.backup-target-first can't back up the target: %w", err)
+	}
+
+	pterm.Info.Printfln("Creating snapshot of target collection '%s' with %d points", collection, count)
+	snapshot, err := client.CreateSnapshot(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to create target snapshot: %w", err)
+	}
+	state.Snapshot = snapshot.GetName()
+	state.SnapshotAt = time.Now().Format(time.RFC3339)
+	err = storeRollbackState(ctx, client, migration.OffsetsCollection, state)
+	if err != nil {
+		return err
+	}
+	pterm.Success.Printfln("Created snapshot %s (%d bytes). 'rollback' restores the collection from it.", snapshot.GetName(), snapshot.GetSize())
+	return nil
+}
+
+// recordAliasSwitch records the collection an alias pointed to before it was switched to a collection,
+// so a rollback points it back. Only the first switch is recorded, which is the state before the migration.
+func recordAliasSwitch(ctx context.Context, client *qdrant.Client, stateCollection, collection, alias, previous string) error {
+	state, err := getRollbackState(ctx, client, stateCollection, collection)
+	if err != nil {
+		return err
+	}
+	if _, ok := state.Aliases[alias]; ok {
+		return nil
+	}
+	state.Aliases[alias] = previous
+	return storeRollbackState(ctx, client, stateCollection, state)
+}
+
+type RollbackCmd struct {
+	Qdrant   commons.QdrantConfig   `embed:"" prefix:"qdrant."`
+	Rollback commons.RollbackConfig `embed:"" prefix:"rollback."`
+
+	targetHost string
+	targetPort int
+	targetTLS  bool
+}
+
+func (r *RollbackCmd) Parse() error {
+	var err error
+	r.targetHost, r.targetPort, r.targetTLS, err = parseQdrantUrl(r.Qdrant.Url)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RollbackCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Qdrant Migration Rollback")
+
+	err := r.Parse()
+	if err != nil {
+		return fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := connectToQdrant(globals, r.targetHost, r.targetPort, r.Qdrant, r.targetTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Qdrant: %w", err)
+	}
+	defer client.Close()
+
+	state, err := getRollbackState(ctx, client, r.Rollback.StateCollection, r.Qdrant.Collection)
+	if err != nil {
+		return err
+	}
+	operations, err := planRollback(ctx, client, state)
+	if err != nil {
+		return err
+	}
+	if len(operations) == 0 && !state.Created && state.Snapshot == "" {
+		pterm.Info.Printfln("Nothing to roll back for '%s'", r.Qdrant.Collection)
+		return nil
+	}
+
+	aliases := sortedKeys(state.Aliases)
+	for _, alias := range aliases {
+		if previous := state.Aliases[alias]; previous != "" {
+			pterm.Info.Printfln("Alias '%s' will point to '%s' again", alias, previous)
+		} else {
+			pterm.Info.Printfln("Alias '%s' will be deleted", alias)
+		}
+	}
+	if state.Created {
+		pterm.Info.Printfln("Collection '%s', created at %s, will be deleted", r.Qdrant.Collection, state.CreatedAt)
+	} else if state.Snapshot != "" {
+		pterm.Info.Printfln("Collection '%s' will be restored from snapshot %s, taken at %s", r.Qdrant.Collection, state.Snapshot, state.SnapshotAt)
+	}
+	if r.Rollback.DryRun {
+		return nil
+	}
+
+	// Like the cutover, all aliases are switched in a single request.
+	if len(operations) > 0 {
+		err = client.UpdateAliases(ctx, operations)
+		if err != nil {
+			return fmt.Errorf("failed to restore aliases: %w", err)
+		}
+		pterm.Success.Printfln("Restored %d alias(es)", len(aliases))
+	}
+
+	if state.Created {
+		err = client.DeleteCollection(ctx, r.Qdrant.Collection)
+		if err != nil {
+			return fmt.Errorf("failed to delete collection: %w", err)
+		}
+		pterm.Success.Printfln("Deleted collection '%s'", r.Qdrant.Collection)
+	}
+	if !state.Created && state.Snapshot != "" {
+		err = r.restoreSnapshot(ctx, globals, state.Snapshot)
+		if err != nil {
+			return err
+		}
+		pterm.Success.Printfln("Restored collection '%s' from snapshot %s, which is kept", r.Qdrant.Collection, state.Snapshot)
+	}
+
+	_, err = client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: r.Rollback.StateCollection,
+		Points:         qdrant.NewPointsSelector(getRollbackStateId(r.Qdrant.Collection)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete rollback state: %w", err)
+	}
+	return nil
+}
+
+// restoreSnapshot replaces the collection with a snapshot of it that's stored on the node, by streaming the snapshot
+// through the REST API, since recovering from a snapshot isn't available over gRPC.
+func (r *RollbackCmd) restoreSnapshot(ctx context.Context, globals *Globals, snapshot string) error {
+	restUrl, err := getQdrantRestUrl(ctx, r.Rollback.RestUrl, r.targetHost, r.targetPort, r.targetTLS)
+	if err != nil {
+		return err
+	}
+	rest, err := newQdrantRestClient(globals, restUrl, r.Qdrant)
+	if err != nil {
+		return err
+	}
+	body, _, err := rest.downloadSnapshot(ctx, r.Qdrant.Collection, snapshot)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return rest.uploadSnapshot(ctx, r.Qdrant.Collection, body)
+}
+
+// planRollback returns the alias operations that restore the aliases of a rollback state.
+// Aliases that no longer point to the collection were moved since, and are left alone.
+func planRollback(ctx context.Context, client *qdrant.Client, state *rollbackState) ([]*qdrant.AliasOperations, error) {
+	var operations []*qdrant.AliasOperations
+	for _, alias := range sortedKeys(state.Aliases) {
+		current, err := getAliasCollection(ctx, client, alias)
+		if err != nil {
+			return nil, err
+		}
+		if current != state.Collection {
+			pterm.Warning.Printfln("Alias '%s' no longer points to '%s', leaving it as it is", alias, state.Collection)
+			delete(state.Aliases, alias)
+			continue
+		}
+
+		operations = append(operations, qdrant.NewAliasDelete(alias))
+		previous := state.Aliases[alias]
+		if previous == "" {
+			continue
+		}
+		exists, err := client.CollectionExists(ctx, previous)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if collection exists: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("alias '%s' can't be restored, collection '%s' no longer exists", alias, previous)
+		}
+		operations = append(operations, qdrant.NewAliasCreate(alias, previous))
+	}
+	return operations, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
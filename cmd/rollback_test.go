@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGetRollbackStateId(t *testing.T) {
+	id := getRollbackStateId("products-v2")
+	if id.GetUuid() != getRollbackStateId("products-v2").GetUuid() {
+		t.Error("rollback state ID should be deterministic")
+	}
+	if id.GetUuid() == getRollbackStateId("products-v3").GetUuid() {
+		t.Error("collections should have different rollback state IDs")
+	}
+	// Offsets are stored under the SHA-1 of the source collection name, which must not collide with the state.
+	if id.GetUuid() == uuid.NewSHA1(uuid.NameSpaceURL, []byte("products-v2")).String() {
+		t.Error("rollback state ID should differ from the offset ID of a collection with the same name")
+	}
+}
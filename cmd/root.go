@@ -1,17 +1,98 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/alecthomas/kong"
 	"github.com/pterm/pterm"
+	"golang.org/x/time/rate"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
 )
 
 type Globals struct {
-	Debug               bool             `help:"Enable debug mode."`
-	Trace               bool             `help:"Enable trace mode."`
-	SkipTlsVerification bool             `help:"Skip TLS verification."`
-	Version             kong.VersionFlag `name:"version" help:"Print version information and quit"`
+	Debug                bool               `help:"Enable debug mode."`
+	Trace                bool               `help:"Enable trace mode."`
+	SkipTlsVerification  bool               `help:"Skip TLS verification."`
+	Proxy                string             `help:"HTTP(S) or SOCKS5 proxy URL to connect to Qdrant through, e.g. socks5://proxy:1080. Defaults to HTTPS_PROXY or ALL_PROXY."`
+	GrpcKeepaliveTime    time.Duration      `help:"Interval of gRPC keepalive pings sent to Qdrant, e.g. 30s. Keeps idle connections through load balancers alive. 0 disables pings." default:"0s"`
+	GrpcKeepaliveTimeout time.Duration      `help:"Time to wait for a gRPC keepalive ping to be acknowledged before the connection is considered dead." default:"20s"`
+	GrpcCallTimeout      time.Duration      `help:"Deadline of every gRPC call to Qdrant, e.g. 2m. 0 means no deadline." default:"0s"`
+	GrpcIdleTimeout      time.Duration      `help:"Time after which an idle gRPC connection is closed. It's reopened on the next call. 0 uses the gRPC default." default:"0s"`
+	PprofAddr            string             `help:"Address to serve net/http/pprof on while running, e.g. localhost:6060, to profile long migrations."`
+	ReportFile           string             `help:"Path to write a JSON report of the run to once it ends, with the configuration, counts, duration and checks, for audits."`
+	ResumeToken          string             `help:"Token printed by a failed run, to continue it from where it stopped. The command and its flags must be the same as in the failed run."`
+	MaxBandwidth         commons.Bandwidth  `help:"Limit of the bytes read from and written to Qdrant per second, across all connections, e.g. 50MB/s. 0 disables the limit." default:"0"`
+	RunWindow            commons.TimeWindow `help:"Daily window of local time to run in, e.g. 22:00-06:00. The migration pauses outside of it and resumes once it opens again."`
+	StallTimeout         time.Duration      `help:"Time without any completed call to Qdrant reading or writing points after which a migration counts as stalled, e.g. 10m. Its connections are then reopened and it resumes from the last checkpoint. 0 disables the detection." default:"0s"`
+	StallRetries         int                `help:"Number of times a stalled migration is resumed before it fails." default:"3"`
+	SourceProfile        string             `help:"Profile to connect to the Qdrant source with, added with 'profile add'. Flags given on the command line take precedence." env:"MIGRATION_SOURCE_PROFILE"`
+	TargetProfile        string             `help:"Profile to connect to the Qdrant target with. Defaults to the profile set with 'profile use'." env:"MIGRATION_TARGET_PROFILE"`
+	ProfilesFile         string             `help:"File the profiles are stored in. Defaults to qdrant-migration/profiles.json in the user's configuration directory."`
+	Version              kong.VersionFlag   `name:"version" help:"Print version information and quit"`
+
+	bandwidthLimiter *rate.Limiter
+	pause            *pauseGate
+	clients          []*qdrant.Client
+	clientsLock      sync.Mutex
+	projectVersion   string
+	projectBuild     string
+	// ctx lets a command run by serve be stopped through the API.
+	ctx context.Context
+}
+
+// baseContext returns the context commands derive their own from.
+func (g *Globals) baseContext() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// getBandwidthLimiter returns the limiter shared by all Qdrant connections, or nil if bandwidth is unlimited.
+func (g *Globals) getBandwidthLimiter() *rate.Limiter {
+	if g.MaxBandwidth <= 0 {
+		return nil
+	}
+	if g.bandwidthLimiter == nil {
+		g.bandwidthLimiter = newBandwidthLimiter(int64(g.MaxBandwidth))
+	}
+	return g.bandwidthLimiter
+}
+
+// trackClient remembers a Qdrant client of the command, to close its connection if the command stalls.
+func (g *Globals) trackClient(client *qdrant.Client) {
+	g.clientsLock.Lock()
+	defer g.clientsLock.Unlock()
+	g.clients = append(g.clients, client)
+}
+
+// closeClients closes the connections of all clients of the command.
+func (g *Globals) closeClients() {
+	g.clientsLock.Lock()
+	defer g.clientsLock.Unlock()
+	for _, client := range g.clients {
+		_ = client.Close()
+	}
+	g.clients = nil
+}
+
+// getPauseGate returns the gate that pauses all Qdrant connections of the command.
+// With a run window, the gate is paused whenever the window is closed.
+func (g *Globals) getPauseGate() *pauseGate {
+	if g.pause == nil {
+		g.pause = &pauseGate{}
+		if g.RunWindow.Set {
+			watchRunWindow(g.baseContext(), g.RunWindow, g.pause)
+		}
+	}
+	return g.pause
 }
 
 type CLI struct {
@@ -26,6 +107,44 @@ type CLI struct {
 	Mongodb    MigrateFromMongoDBCmd    `cmd:"" help:"Migrate data from a Mongo database to Qdrant."`
 	OpenSearch MigrateFromOpenSearchCmd `cmd:"" name:"opensearch" help:"Migrate data from an OpenSearch database to Qdrant."`
 	PG         MigrateFromPGCmd         `cmd:"" name:"pg" help:"Migrate data from a PostgreSQL database to Qdrant."`
+	Rest       MigrateFromRestCmd       `cmd:"" name:"rest" aliases:"from-rest" help:"Migrate data from a REST API described by a YAML spec to Qdrant."`
+	Grpc       MigrateFromGrpcCmd       `cmd:"" name:"grpc" help:"Migrate data from a gRPC service with reflection to Qdrant (experimental)."`
+	Stdin      MigrateFromStdinCmd      `cmd:"" name:"stdin" aliases:"from-stdin" help:"Migrate points piped into stdin as JSONL to Qdrant."`
+	SQL        MigrateFromSQLCmd        `cmd:"" name:"sql" aliases:"from-sql" help:"Migrate the rows of a SELECT on Postgres, MySQL or ClickHouse to Qdrant."`
+	Databricks MigrateFromDatabricksCmd `cmd:"" name:"databricks" aliases:"from-databricks" help:"Migrate data from a Databricks Vector Search index to Qdrant."`
+
+	ToPinecone MigrateToPineconeCmd `cmd:"" name:"to-pinecone" help:"Migrate data from Qdrant to a Pinecone index."`
+	ToWeaviate MigrateToWeaviateCmd `cmd:"" name:"to-weaviate" help:"Migrate data from Qdrant to a Weaviate class."`
+	ToPG       MigrateToPGCmd       `cmd:"" name:"to-pg" aliases:"to-pgvector" help:"Migrate data from Qdrant to a PostgreSQL table."`
+	ToMilvus   MigrateToMilvusCmd   `cmd:"" name:"to-milvus" help:"Migrate data from Qdrant to a Milvus collection."`
+
+	Export ExportCmd `cmd:"" aliases:"extract" help:"Export a Qdrant collection to Parquet or gzipped JSONL files."`
+	Load   LoadCmd   `cmd:"" help:"Load Parquet or gzipped JSONL files written by export into a Qdrant collection."`
+
+	Bench BenchCmd `cmd:"" help:"Measure the write throughput of a Qdrant instance with synthetic points."`
+
+	Cutover  CutoverCmd  `cmd:"" help:"Verify a migrated collection and switch a production alias to it."`
+	Rollback RollbackCmd `cmd:"" help:"Restore the aliases switched to a collection and delete it if a migration created it."`
+
+	Schedule ScheduleCmd `cmd:"" help:"Run a migration command on a cron schedule in a long-lived process."`
+	Serve    ServeCmd    `cmd:"" help:"Serve an HTTP API to start, pause, resume and cancel migration jobs."`
+
+	K8sManifest K8sManifestCmd `cmd:"" name:"k8s-manifest" help:"Render a Kubernetes Job or CronJob manifest that runs a migration command."`
+
+	Profile ProfileCmd `cmd:"" help:"Manage named Qdrant endpoints with their credentials, to use with --source-profile and --target-profile."`
+	Doctor  DoctorCmd  `cmd:"" help:"Diagnose the connection to a Qdrant endpoint: its URL, DNS, TLS, version, latency and the access of the API key."`
+
+	Completion CompletionCmd `cmd:"" help:"Print the shell completion script for bash, zsh or fish."`
+	Complete   CompleteCmd   `cmd:"" name:"__complete" hidden:"" help:"Print the completion candidates for the words typed so far."`
+}
+
+// Commands that don't migrate anything, so they can't be paused.
+var offlineCommands = map[string]bool{
+	"k8s-manifest": true,
+	"profile":      true,
+	"doctor":       true,
+	"completion":   true,
+	"__complete":   true,
 }
 
 func Execute(projectVersion, projectBuild string) {
@@ -36,13 +155,64 @@ func Execute(projectVersion, projectBuild string) {
 		kong.Description("Migrate data to Qdrant from other sources."),
 		kong.Vars{
 			"version": version,
-		})
+		},
+		kong.Resolvers(profileResolver()))
+
+	cli.projectVersion = projectVersion
+	cli.projectBuild = projectBuild
+
+	if cli.PprofAddr != "" {
+		err := startPprofServer(cli.PprofAddr)
+		if err != nil {
+			pterm.Error.Println(err)
+			ctx.Exit(1)
+		}
+	}
+
+	applySampleMode(ctx)
+	applySinkMode(ctx)
+	applyTenantScope(ctx)
+
+	if cli.ResumeToken != "" {
+		err := applyResumeToken(ctx, cli.ResumeToken)
+		if err != nil {
+			pterm.Error.Println(err)
+			ctx.Exit(1)
+		}
+	}
+	currentReport = newRunReport(ctx, projectVersion, projectBuild)
+
+	// The API of serve pauses every job on its own, and the other commands don't connect to anything.
+	command, _, _ := strings.Cut(ctx.Command(), " ")
+	if command != "serve" && !offlineCommands[command] {
+		watchPauseKey(cli.getPauseGate())
+	}
+
+	var err error
+	// Jobs of serve and schedule are long-lived processes that are idle between runs.
+	if command == "serve" || command == "schedule" || offlineCommands[command] {
+		err = ctx.Run(&cli.Globals)
+	} else {
+		err = runWithStallRecovery(ctx, &cli.Globals)
+	}
 
-	err := ctx.Run(&cli.Globals)
+	currentReport.finish(err)
+	currentReport.printSummary()
+	if cli.ReportFile != "" {
+		if reportErr := currentReport.write(cli.ReportFile); reportErr != nil {
+			pterm.Warning.Println(reportErr)
+		}
+	}
 
 	if err != nil {
 		fmt.Print("\n")
 		pterm.Error.Println(err)
+		if hint := errorHint(err); hint != "" {
+			pterm.Info.Printfln("Hint: %s", hint)
+		}
+		if token, tokenErr := newResumeToken(ctx); tokenErr == nil && token != "" {
+			pterm.Info.Printfln("To continue from where the run stopped, run the same command again with --resume-token %s", token)
+		}
 		ctx.Exit(1)
 	}
 }
@@ -50,10 +220,30 @@ func Execute(projectVersion, projectBuild string) {
 func NewParser(args []string) (*kong.Context, error) {
 	cli := &CLI{}
 
-	parser, err := kong.New(cli, kong.Bind(&cli.Globals))
+	parser, err := kong.New(cli, kong.Bind(&cli.Globals), kong.Resolvers(profileResolver()))
 	if err != nil {
 		return nil, err
 	}
 
 	return parser.Parse(args)
 }
+
+// parseCommand parses the arguments of a command to run from within the process, like one on a schedule.
+func parseCommand(args []string) (*kong.Context, *CLI, error) {
+	cli := &CLI{}
+
+	parser, err := kong.New(cli, kong.Name("migration"), kong.Bind(&cli.Globals), kong.Resolvers(profileResolver()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	applySampleMode(ctx)
+	applySinkMode(ctx)
+	applyTenantScope(ctx)
+
+	return ctx, cli, nil
+}
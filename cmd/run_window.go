@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// watchRunWindow pauses the gate whenever the local time is outside the window and resumes it when the window opens,
+// until the context is done.
+func watchRunWindow(ctx context.Context, window commons.TimeWindow, gate *pauseGate) {
+	update := func(now time.Time) {
+		if window.Contains(now) {
+			if gate.resume() {
+				pterm.Info.Printfln("The run window %s is open, resuming the migration", window)
+			}
+			return
+		}
+		if gate.pause() {
+			pterm.Info.Printfln("Outside of the run window %s, pausing the migration until %s", window, window.NextChange(now).Format("15:04"))
+		}
+	}
+
+	update(time.Now())
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(window.NextChange(time.Now())))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case now := <-timer.C:
+				update(now)
+			}
+		}
+	}()
+}
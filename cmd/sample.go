@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Suffix of the offsets collection of sampled runs, so that their checkpoints don't move the ones of full runs.
+const sampleOffsetsSuffix = "_sample"
+
+// sampledPoints counts the points let through by --migration.limit across all streams of the run.
+var sampledPoints atomic.Uint64
+
+// isSampled reports whether --migration.sample or --migration.limit is set.
+func isSampled(migration commons.MigrationConfig) bool {
+	return migration.Sample > 0 || migration.Limit > 0
+}
+
+// samplePoints returns the points of a batch that belong to the sample. With --migration.sample, a point belongs to it
+// if the hash of its ID falls into the share, so every run picks the same points, spread evenly over the source.
+// With --migration.limit, only the points up to the limit are kept.
+func samplePoints[P interface{ GetId() *qdrant.PointId }](points []P, migration commons.MigrationConfig) []P {
+	if migration.Sample > 0 {
+		sampled := make([]P, 0, len(points))
+		for _, point := range points {
+			if inSample(point.GetId(), migration.Sample) {
+				sampled = append(sampled, point)
+			}
+		}
+		points = sampled
+	}
+	if migration.Limit > 0 {
+		points = points[:reserveSample(uint64(len(points)), migration.Limit)]
+	}
+	return points
+}
+
+// inSample reports whether a point belongs to a sample of the given share of all points.
+func inSample(id *qdrant.PointId, share commons.Percentage) bool {
+	hash := sha256.Sum256([]byte(pointIDToString(id)))
+	return float64(binary.BigEndian.Uint64(hash[:8])) < float64(share)*math.MaxUint64
+}
+
+// reserveSample takes up to n points from what's left of the limit, and returns how many it took.
+func reserveSample(n, limit uint64) uint64 {
+	for {
+		taken := sampledPoints.Load()
+		granted := min(n, limit-min(taken, limit))
+		if sampledPoints.CompareAndSwap(taken, taken+granted) {
+			return granted
+		}
+	}
+}
+
+// sampleComplete reports whether --migration.limit points were migrated, so reading the source can stop.
+func sampleComplete(migration commons.MigrationConfig) bool {
+	return migration.Limit > 0 && sampledPoints.Load() >= migration.Limit
+}
+
+// sampledRun reports whether --migration.sample or --migration.limit are set.
+func sampledRun(ctx *kong.Context) bool {
+	for _, flag := range ctx.Flags() {
+		switch {
+		case flag.Name == "migration.sample" && flag.Target.Kind() == reflect.Float64 && flag.Target.Float() > 0:
+			return true
+		case flag.Name == "migration.limit" && flag.Target.Kind() == reflect.Uint64 && flag.Target.Uint() > 0:
+			return true
+		}
+	}
+	return false
+}
+
+// applySampleMode makes sampled runs start from the beginning of the source and checkpoint into an offsets collection
+// of their own, so that a full run into the same target later isn't resumed from where the sample stopped.
+// It's called once per run, and starts counting the points of --migration.limit anew.
+func applySampleMode(ctx *kong.Context) {
+	sampledPoints.Store(0)
+
+	if !sampledRun(ctx) {
+		return
+	}
+
+	for _, flag := range ctx.Flags() {
+		switch {
+		case flag.Name == "migration.restart" && flag.Target.Kind() == reflect.Bool:
+			flag.Target.SetBool(true)
+		case flag.Name == "migration.offsets-collection" && flag.Target.Kind() == reflect.String:
+			flag.Target.SetString(flag.Target.String() + sampleOffsetsSuffix)
+		}
+	}
+}
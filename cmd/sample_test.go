@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestSamplePoints(t *testing.T) {
+	points := make([]*qdrant.PointStruct, 0, 10000)
+	for i := range 10000 {
+		points = append(points, &qdrant.PointStruct{Id: qdrant.NewIDNum(uint64(i))})
+	}
+
+	t.Run("share", func(t *testing.T) {
+		sampled := samplePoints(points, commons.MigrationConfig{Sample: 0.1})
+		if len(sampled) < 900 || len(sampled) > 1100 {
+			t.Errorf("sampled %d of %d points, want about 1000", len(sampled), len(points))
+		}
+		again := samplePoints(points, commons.MigrationConfig{Sample: 0.1})
+		if len(again) != len(sampled) || again[0] != sampled[0] {
+			t.Errorf("sampling again picked other points")
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		sampledPoints.Store(0)
+		migration := commons.MigrationConfig{Limit: 120}
+		first := samplePoints(points[:100], migration)
+		second := samplePoints(points[100:200], migration)
+		if len(first) != 100 || len(second) != 20 {
+			t.Errorf("sampled %d and %d points, want 100 and 20", len(first), len(second))
+		}
+		if second[0] != points[100] {
+			t.Errorf("limit didn't keep the leading points")
+		}
+		if !sampleComplete(migration) {
+			t.Errorf("sampleComplete() = false after the limit was reached")
+		}
+		if third := samplePoints(points[200:300], migration); len(third) != 0 {
+			t.Errorf("sampled %d points after the limit was reached", len(third))
+		}
+	})
+}
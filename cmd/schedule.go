@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+)
+
+type ScheduleCmd struct {
+	Cron      string   `help:"Cron expression with the fields minute, hour, day of month, month and day of week, e.g. '0 */6 * * *', or an alias like @hourly or @daily. Times are local." required:"true"`
+	ReportDir string   `help:"Directory to write a JSON report of every run to, named after the time the run started."`
+	RunNow    bool     `help:"Run the command once right away, before waiting for the first scheduled time." default:"false"`
+	Command   []string `arg:"" passthrough:"" help:"Command to run on the schedule, with its flags, e.g. -- qdrant --source.url ..."`
+
+	schedule *cronSchedule
+}
+
+func (r *ScheduleCmd) Validate() error {
+	var err error
+	r.schedule, err = parseCron(r.Cron)
+	if err != nil {
+		return err
+	}
+	if r.schedule.next(time.Now()).IsZero() {
+		return fmt.Errorf("cron expression '%s' never matches", r.Cron)
+	}
+
+	if len(r.Command) > 0 && r.Command[0] == "--" {
+		r.Command = r.Command[1:]
+	}
+	if len(r.Command) == 0 {
+		return errors.New("a command to run on the schedule is required")
+	}
+
+	// Fail right away instead of at the first scheduled time if the command is invalid.
+	ctx, _, err := parseCommand(r.Command)
+	if err != nil {
+		return fmt.Errorf("invalid scheduled command: %w", err)
+	}
+	if strings.HasPrefix(ctx.Command(), "schedule") {
+		return errors.New("the scheduled command can't be schedule itself")
+	}
+
+	return nil
+}
+
+func (r *ScheduleCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Scheduled Migration")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if r.ReportDir != "" {
+		err := os.MkdirAll(r.ReportDir, 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+	}
+
+	if r.RunNow {
+		r.runOnce(globals)
+	}
+
+	for {
+		next := r.schedule.next(time.Now())
+		pterm.Info.Printfln("Next run at %s", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			pterm.Info.Println("Stopped the schedule")
+			return nil
+		case <-timer.C:
+		}
+
+		r.runOnce(globals)
+
+		// Runs never overlap, the times that passed while a run was still going are skipped.
+		if missed := r.schedule.next(next); missed.Before(time.Now()) {
+			pterm.Warning.Printfln("The run took longer than the schedule interval, skipping the runs scheduled while it was going")
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// parseScheduledCommand parses the scheduled command with --migration.restart set. A run that finishes leaves its
+// checkpoint at the last page of the source, so a run continuing from it would only read the points added after it,
+// and miss those added with lower or random IDs. Every run reads the whole source instead.
+func parseScheduledCommand(args []string) (*kong.Context, *CLI, error) {
+	ctx, cli, err := parseCommand(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "migration.restart" && flag.Target.Kind() == reflect.Bool {
+			flag.Target.SetBool(true)
+		}
+	}
+	return ctx, cli, nil
+}
+
+// runOnce parses the scheduled command again, so every run starts with fresh state, and runs it.
+// A failed run is reported, and the schedule continues.
+func (r *ScheduleCmd) runOnce(globals *Globals) {
+	start := time.Now()
+	pterm.Info.Printfln("Starting scheduled run at %s", start.Format(time.RFC3339))
+
+	ctx, cli, err := parseScheduledCommand(r.Command)
+	if err != nil {
+		pterm.Error.Printfln("Failed to parse scheduled command: %v", err)
+		return
+	}
+
+	previousReport := currentReport
+	currentReport = newRunReport(ctx, globals.projectVersion, globals.projectBuild)
+	defer func() {
+		currentReport = previousReport
+	}()
+
+	// Pressing Enter pauses the current run like any other command.
+	cli.pause = globals.getPauseGate()
+	err = ctx.Run(&cli.Globals)
+
+	currentReport.finish(err)
+	currentReport.printSummary()
+	if r.ReportDir != "" {
+		path := filepath.Join(r.ReportDir, start.UTC().Format("20060102T150405Z")+".json")
+		if reportErr := currentReport.write(path); reportErr != nil {
+			pterm.Warning.Println(reportErr)
+		}
+	}
+
+	if err != nil {
+		pterm.Error.Printfln("Scheduled run failed after %s: %v", time.Since(start).Round(time.Second), err)
+		return
+	}
+	pterm.Success.Printfln("Scheduled run finished after %s", time.Since(start).Round(time.Second))
+}
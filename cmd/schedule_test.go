@@ -0,0 +1,25 @@
+package cmd
+
+import "testing"
+
+func TestParseScheduledCommand(t *testing.T) {
+	args := []string{"qdrant", "--source.collection", "a", "--target.collection", "b", "--migration.skip-existing"}
+
+	ctx, cli, err := parseScheduledCommand(args)
+	if err != nil {
+		t.Fatalf("parseScheduledCommand() error = %v", err)
+	}
+	if !cli.Qdrant.Migration.Restart {
+		t.Fatal("the first run continues from a checkpoint, expected it to read the source from the start")
+	}
+
+	// A run resumed after a stall continues from its checkpoints, which must not carry over to the next run.
+	disableRestart(ctx)
+	_, cli, err = parseScheduledCommand(args)
+	if err != nil {
+		t.Fatalf("parseScheduledCommand() error = %v", err)
+	}
+	if !cli.Qdrant.Migration.Restart {
+		t.Error("the second run continues from a checkpoint, expected it to read the source from the start")
+	}
+}
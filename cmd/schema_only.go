@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// migrateSchema creates the target collections with the configuration, payload indexes, shard keys and aliases
+// of the source collection, without copying any points.
+func (r *MigrateFromQdrantCmd) migrateSchema(ctx context.Context, sourceClient *qdrant.Client, targetClients []*qdrant.Client) error {
+	shardKeys, err := getShardKeys(ctx, sourceClient, r.Source.Collection)
+	if err != nil {
+		return err
+	}
+	sourceAliases, err := sourceClient.ListCollectionAliases(ctx, r.Source.Collection)
+	if err != nil {
+		return fmt.Errorf("failed to list aliases of source collection: %w", err)
+	}
+
+	for i, client := range targetClients {
+		err = r.perpareTargetCollection(ctx, sourceClient, r.Source.Collection, client, r.Target.Collection)
+		if err != nil {
+			return fmt.Errorf("error preparing target collection: %w", err)
+		}
+
+		for _, key := range shardKeys {
+			err = ensureShardKey(ctx, client, r.Target.Collection, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		targetAliases, err := client.ListAliases(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list aliases of target: %w", err)
+		}
+		create, conflicts := aliasesToCreate(sourceAliases, targetAliases, r.Target.Collection)
+		for _, alias := range create {
+			err = client.CreateAlias(ctx, alias, r.Target.Collection)
+			if err != nil {
+				return fmt.Errorf("failed to create alias '%s': %w", alias, err)
+			}
+			pterm.Info.Printfln("Created alias '%s' of collection '%s' at %s", alias, r.Target.Collection, r.targetUrl(i))
+		}
+		for _, alias := range conflicts {
+			pterm.Warning.Printfln("Alias '%s' already points to another collection at %s, it's left as it is. Switch it with cutover once the data is copied.", alias, r.targetUrl(i))
+		}
+	}
+
+	pterm.Success.Printfln("Created the schema of '%s' without copying any points", r.Target.Collection)
+	return nil
+}
+
+// aliasesToCreate returns the aliases of the source collection that the target doesn't have yet, and the ones
+// that already point to another collection in the target. Aliases are never moved, since they may serve production traffic.
+func aliasesToCreate(sourceAliases []string, targetAliases []*qdrant.AliasDescription, targetCollection string) (create []string, conflicts []string) {
+	existing := make(map[string]string, len(targetAliases))
+	for _, alias := range targetAliases {
+		existing[alias.GetAliasName()] = alias.GetCollectionName()
+	}
+
+	for _, alias := range sourceAliases {
+		collection, ok := existing[alias]
+		switch {
+		case !ok:
+			create = append(create, alias)
+		case collection != targetCollection:
+			conflicts = append(conflicts, alias)
+		}
+	}
+	return create, conflicts
+}
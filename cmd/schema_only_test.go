@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestAliasesToCreate(t *testing.T) {
+	targetAliases := []*qdrant.AliasDescription{
+		{AliasName: "products", CollectionName: "products-v1"},
+		{AliasName: "catalog", CollectionName: "products-v2"},
+	}
+
+	create, conflicts := aliasesToCreate([]string{"products", "catalog", "search"}, targetAliases, "products-v2")
+	if !slices.Equal(create, []string{"search"}) {
+		t.Errorf("create = %v, want [search]", create)
+	}
+	if !slices.Equal(conflicts, []string{"products"}) {
+		t.Errorf("conflicts = %v, want [products]", conflicts)
+	}
+}
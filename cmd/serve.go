@@ -0,0 +1,440 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pterm/pterm"
+)
+
+type ServeCmd struct {
+	Addr   string `help:"Address to serve the HTTP API on." default:"localhost:8080"`
+	APIKey string `help:"API key that requests must send as a bearer token. Strongly recommended if the API is reachable from other machines."`
+}
+
+func (r *ServeCmd) Run(globals *Globals) error {
+	pterm.DefaultHeader.WithFullWidth().Println("Migration API")
+
+	ctx, stop := signal.NotifyContext(globals.baseContext(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", r.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on API address: %w", err)
+	}
+
+	jobs := newJobManager(ctx, globals.projectVersion, globals.projectBuild)
+	server := &http.Server{Handler: r.authenticate(jobs.handler())}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	pterm.Info.Printfln("Serving the migration API on http://%s", listener.Addr())
+	err = server.Serve(listener)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to serve API: %w", err)
+	}
+
+	pterm.Info.Println("Waiting for running jobs to stop")
+	jobs.wait()
+
+	return nil
+}
+
+func (r *ServeCmd) authenticate(next http.Handler) http.Handler {
+	if r.APIKey == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(r.APIKey)) != 1 {
+			writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid API key"))
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+type jobState string
+
+const (
+	jobRunning   jobState = "running"
+	jobPaused    jobState = "paused"
+	jobCompleted jobState = "completed"
+	jobFailed    jobState = "failed"
+	jobCancelled jobState = "cancelled"
+)
+
+// jobDefinition is the body of a request to start a job.
+// The config has the same keys as the config in run reports, i.e. the names of the flags without the leading dashes.
+type jobDefinition struct {
+	Command string         `json:"command"`
+	Config  map[string]any `json:"config"`
+}
+
+type job struct {
+	ID         string        `json:"id"`
+	Definition jobDefinition `json:"definition"`
+	State      jobState      `json:"state"`
+	CreatedAt  time.Time     `json:"created_at"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// The report of the last run of the job, once it stopped.
+	Report *runReport `json:"report,omitempty"`
+
+	args   []string
+	gate   *pauseGate
+	cancel context.CancelFunc
+	// The state the job goes to when its run stops because it was cancelled.
+	stopAs jobState
+	done   chan struct{}
+}
+
+// jobManager runs migration commands as jobs in the background, one at a time, since runs share the state of the process,
+// like the run report and the checkpoints of the resume token. Every run of a job starts a report of its own.
+// Pausing a job holds back its calls to Qdrant once the current batches are written, and resuming it lets them continue.
+// Resuming a failed job starts the command again, which continues from the offsets stored in the target.
+type jobManager struct {
+	ctx     context.Context
+	version string
+	build   string
+	lock    sync.Mutex
+	jobs    map[string]*job
+	nextID  int
+	group   sync.WaitGroup
+}
+
+func newJobManager(ctx context.Context, version, build string) *jobManager {
+	return &jobManager{ctx: ctx, version: version, build: build, jobs: make(map[string]*job)}
+}
+
+func (m *jobManager) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", m.handleStart)
+	mux.HandleFunc("GET /jobs", m.handleList)
+	mux.HandleFunc("GET /jobs/{id}", m.handleGet)
+	mux.HandleFunc("POST /jobs/{id}/pause", m.handleAction(m.pause))
+	mux.HandleFunc("POST /jobs/{id}/resume", m.handleAction(m.resume))
+	mux.HandleFunc("POST /jobs/{id}/cancel", m.handleAction(m.cancel))
+	return mux
+}
+
+func (m *jobManager) handleStart(w http.ResponseWriter, req *http.Request) {
+	var definition jobDefinition
+	err := json.NewDecoder(req.Body).Decode(&definition)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid job definition: %w", err))
+		return
+	}
+
+	args, err := jobArgs(definition)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+	ctx, _, err := parseCommand(args)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid job definition: %w", err))
+		return
+	}
+	if command := strings.Fields(ctx.Command()); len(command) > 0 && slices.Contains([]string{"serve", "schedule", "bench"}, command[0]) {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("the command '%s' can't be run as a job", command[0]))
+		return
+	}
+
+	m.lock.Lock()
+	if active := m.activeJob(); active != nil {
+		m.lock.Unlock()
+		writeJSONError(w, http.StatusConflict, fmt.Errorf("job %s is %s, only one job runs at a time", active.ID, active.State))
+		return
+	}
+	m.nextID++
+	j := &job{
+		ID:         strconv.Itoa(m.nextID),
+		Definition: redactJobDefinition(definition),
+		CreatedAt:  time.Now(),
+		args:       args,
+	}
+	m.jobs[j.ID] = j
+	m.start(j)
+	m.lock.Unlock()
+
+	m.writeJob(w, http.StatusCreated, j)
+}
+
+func (m *jobManager) handleList(w http.ResponseWriter, _ *http.Request) {
+	m.lock.Lock()
+	jobs := make([]*job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].CreatedAt.Before(jobs[k].CreatedAt)
+	})
+	data, err := json.Marshal(map[string]any{"jobs": jobs})
+	m.lock.Unlock()
+
+	writeJSON(w, http.StatusOK, data, err)
+}
+
+func (m *jobManager) handleGet(w http.ResponseWriter, req *http.Request) {
+	m.lock.Lock()
+	j, ok := m.jobs[req.PathValue("id")]
+	m.lock.Unlock()
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+		return
+	}
+	m.writeJob(w, http.StatusOK, j)
+}
+
+func (m *jobManager) handleAction(action func(*job) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		m.lock.Lock()
+		j, ok := m.jobs[req.PathValue("id")]
+		m.lock.Unlock()
+		if !ok {
+			writeJSONError(w, http.StatusNotFound, errors.New("job not found"))
+			return
+		}
+
+		err := action(j)
+		if err != nil {
+			writeJSONError(w, http.StatusConflict, err)
+			return
+		}
+		m.writeJob(w, http.StatusOK, j)
+	}
+}
+
+// activeJob returns the job that is running or paused, if any. The lock must be held.
+func (m *jobManager) activeJob() *job {
+	for _, j := range m.jobs {
+		if j.State == jobRunning || j.State == jobPaused {
+			return j
+		}
+	}
+	return nil
+}
+
+// start runs the command of the job in the background. The lock must be held.
+func (m *jobManager) start(j *job) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	j.State = jobRunning
+	j.StartedAt = time.Now()
+	j.FinishedAt = nil
+	j.Error = ""
+	j.Report = nil
+	j.cancel = cancel
+	j.stopAs = ""
+	j.gate = &pauseGate{}
+	done := make(chan struct{})
+	j.done = done
+
+	m.group.Add(1)
+	go func() {
+		defer m.group.Done()
+		defer close(done)
+		defer cancel()
+
+		report, err := m.runJobCommand(ctx, j.args, j.gate)
+
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		finishedAt := time.Now()
+		j.FinishedAt = &finishedAt
+		j.Report = report
+		switch {
+		case j.stopAs != "":
+			j.State = j.stopAs
+		case err != nil:
+			j.State = jobFailed
+			j.Error = err.Error()
+		default:
+			j.State = jobCompleted
+		}
+	}()
+}
+
+// runJobCommand runs the command of a job with a report of its own, so its counters don't add up with the ones
+// of the jobs before, and returns the finished report.
+func (m *jobManager) runJobCommand(ctx context.Context, args []string, gate *pauseGate) (*runReport, error) {
+	kctx, cli, err := parseCommand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	previousReport := currentReport
+	report := newRunReport(kctx, m.version, m.build)
+	currentReport = report
+	defer func() {
+		currentReport = previousReport
+	}()
+
+	cli.Globals.ctx = ctx
+	cli.Globals.pause = gate
+	err = kctx.Run(&cli.Globals)
+	report.finish(err)
+	return report, err
+}
+
+// stop cancels the run of a job, waits for it to end, and leaves the job in the given state.
+func (m *jobManager) stop(j *job, state jobState) error {
+	m.lock.Lock()
+	if j.State != jobRunning && j.State != jobPaused {
+		current := j.State
+		m.lock.Unlock()
+		return fmt.Errorf("job %s is %s, not running", j.ID, current)
+	}
+	j.stopAs = state
+	j.cancel()
+	done := j.done
+	m.lock.Unlock()
+
+	<-done
+	return nil
+}
+
+func (m *jobManager) pause(j *job) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if j.State != jobRunning {
+		return fmt.Errorf("job %s is %s, not running", j.ID, j.State)
+	}
+	j.gate.pause()
+	j.State = jobPaused
+	return nil
+}
+
+func (m *jobManager) cancel(j *job) error {
+	return m.stop(j, jobCancelled)
+}
+
+func (m *jobManager) resume(j *job) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	switch j.State {
+	case jobPaused:
+		j.gate.resume()
+		j.State = jobRunning
+	case jobFailed:
+		if active := m.activeJob(); active != nil {
+			return fmt.Errorf("job %s is %s, only one job runs at a time", active.ID, active.State)
+		}
+		m.start(j)
+	default:
+		return fmt.Errorf("job %s is %s, only paused or failed jobs can be resumed", j.ID, j.State)
+	}
+	return nil
+}
+
+func (m *jobManager) wait() {
+	m.group.Wait()
+}
+
+func (m *jobManager) writeJob(w http.ResponseWriter, status int, j *job) {
+	m.lock.Lock()
+	data, err := json.Marshal(j)
+	m.lock.Unlock()
+
+	writeJSON(w, status, data, err)
+}
+
+// jobArgs turns a job definition into the command line arguments of the command.
+func jobArgs(definition jobDefinition) ([]string, error) {
+	if definition.Command == "" {
+		return nil, errors.New("the job definition has no command")
+	}
+
+	args := strings.Fields(definition.Command)
+	names := make([]string, 0, len(definition.Config))
+	for name := range definition.Config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "help" || name == "version" {
+			return nil, fmt.Errorf("'%s' can't be set in a job definition", name)
+		}
+		value, err := jobFlagValue(definition.Config[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %w", name, err)
+		}
+		args = append(args, "--"+name+"="+value)
+	}
+
+	return args, nil
+}
+
+// redactJobDefinition returns the definition with the values of secrets redacted like in run reports,
+// so they aren't returned by the API. The job keeps running with its arguments.
+func redactJobDefinition(definition jobDefinition) jobDefinition {
+	config := make(map[string]any, len(definition.Config))
+	for name, value := range definition.Config {
+		config[name] = redactFlagValue(name, value)
+	}
+	definition.Config = config
+	return definition
+}
+
+func jobFlagValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := jobFlagValue(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, s)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", value)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data []byte, err error) {
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(append(data, '\n'))
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_jobArgs(t *testing.T) {
+	args, err := jobArgs(jobDefinition{
+		Command: "qdrant",
+		Config: map[string]any{
+			"source.url":           "http://localhost:6334",
+			"migration.batch-size": float64(100),
+			"migration.restart":    true,
+			"target.extra-urls":    []any{"http://a:6334", "http://b:6334"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"qdrant",
+		"--migration.batch-size=100",
+		"--migration.restart=true",
+		"--source.url=http://localhost:6334",
+		"--target.extra-urls=http://a:6334,http://b:6334",
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("jobArgs() = %v, want %v", args, want)
+	}
+}
+
+func Test_jobArgsInvalid(t *testing.T) {
+	tests := []jobDefinition{
+		{Config: map[string]any{"source.url": "http://localhost:6334"}},
+		{Command: "qdrant", Config: map[string]any{"help": true}},
+		{Command: "qdrant", Config: map[string]any{"source.url": map[string]any{"host": "localhost"}}},
+	}
+
+	for _, definition := range tests {
+		if _, err := jobArgs(definition); err == nil {
+			t.Errorf("jobArgs(%+v) succeeded, expected an error", definition)
+		}
+	}
+}
+
+func Test_redactJobDefinition(t *testing.T) {
+	definition := jobDefinition{
+		Command: "pinecone",
+		Config: map[string]any{
+			"pinecone.api-key":             "pcsk-secret",
+			"qdrant.url":                   "http://localhost:6334",
+			"export.encryption-key":        "key",
+			"databricks.client-secret":     "secret",
+			"migration.offsets-collection": "_offsets",
+		},
+	}
+
+	redacted := redactJobDefinition(definition)
+	want := map[string]any{
+		"pinecone.api-key":             "REDACTED",
+		"qdrant.url":                   "http://localhost:6334",
+		"export.encryption-key":        "REDACTED",
+		"databricks.client-secret":     "REDACTED",
+		"migration.offsets-collection": "_offsets",
+	}
+	if !reflect.DeepEqual(redacted.Config, want) {
+		t.Errorf("redactJobDefinition() = %v, want %v", redacted.Config, want)
+	}
+	if definition.Config["pinecone.api-key"] != "pcsk-secret" {
+		t.Errorf("redactJobDefinition() changed the definition it was given")
+	}
+}
+
+func TestJobManagerOneJobAtATime(t *testing.T) {
+	m := newJobManager(context.Background(), "test", "test")
+	m.jobs["1"] = &job{ID: "1", State: jobPaused}
+	m.jobs["2"] = &job{ID: "2", State: jobFailed}
+
+	body := `{"command": "qdrant", "config": {"source.collection": "a", "target.collection": "b"}}`
+	recorder := httptest.NewRecorder()
+	m.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("POST /jobs got status %d, want %d", recorder.Code, http.StatusConflict)
+	}
+
+	recorder = httptest.NewRecorder()
+	m.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs/2/resume", nil))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("POST /jobs/2/resume got status %d, want %d", recorder.Code, http.StatusConflict)
+	}
+	if len(m.jobs) != 2 || m.jobs["2"].State != jobFailed {
+		t.Errorf("jobs changed while another job was paused: %+v", m.jobs)
+	}
+}
+
+func TestJobManagerReportPerJob(t *testing.T) {
+	dir := t.TempDir()
+	stdin := os.Stdin
+	defer func() { os.Stdin = stdin }()
+
+	m := newJobManager(context.Background(), "test", "test")
+	for i, points := range []int{2, 3} {
+		var lines strings.Builder
+		for id := 1; id <= points; id++ {
+			fmt.Fprintf(&lines, `{"id": %d, "vectors": {"dense": [0.1, 0.2]}}`+"\n", id)
+		}
+		input := filepath.Join(dir, fmt.Sprintf("points-%d.jsonl", i))
+		if err := os.WriteFile(input, []byte(lines.String()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		os.Stdin = file
+
+		body := fmt.Sprintf(`{"command": "stdin", "config": {"qdrant.collection": "target", "migration.target": "file", "migration.target-file": %q}}`,
+			filepath.Join(dir, fmt.Sprintf("written-%d.jsonl", i)))
+		recorder := httptest.NewRecorder()
+		m.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body)))
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("POST /jobs got status %d: %s", recorder.Code, recorder.Body)
+		}
+		m.wait()
+
+		j := m.jobs[strconv.Itoa(i+1)]
+		if j.State != jobCompleted {
+			t.Fatalf("job %s is %s: %s", j.ID, j.State, j.Error)
+		}
+		if j.Report == nil || j.Report.PointsWritten != uint64(points) {
+			t.Errorf("report of job %s = %+v, want %d points written", j.ID, j.Report, points)
+		}
+	}
+}
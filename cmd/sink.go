@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+const (
+	targetQdrant = "qdrant"
+	targetStdout = "stdout"
+	targetFile   = "file"
+)
+
+var (
+	// sinkLock serializes writes to the sink, since streams write batches in parallel.
+	sinkLock sync.Mutex
+	// sinkOpened is set once the sink file of the run was truncated.
+	sinkOpened bool
+	// sinkStdout is where points are written with --migration.target=stdout.
+	sinkStdout io.Writer = os.Stdout
+)
+
+// sinkRecord is a point written to the sink, with the request it would have been sent to Qdrant with.
+type sinkRecord struct {
+	Collection string          `json:"collection"`
+	Operation  string          `json:"operation"`
+	ShardKey   string          `json:"shard_key,omitempty"`
+	Point      json.RawMessage `json:"point"`
+}
+
+// isSinkTarget reports whether points are written to stdout or a file instead of Qdrant.
+func isSinkTarget(migration commons.MigrationConfig) bool {
+	return migration.Target != "" && migration.Target != targetQdrant
+}
+
+// connectToTarget connects to the Qdrant target, unless points are written to a sink: then nothing is written to Qdrant,
+// so it isn't connected to, and the client is nil. Offsets are only kept for the run without a client.
+func connectToTarget(globals *Globals, host string, port int, config commons.QdrantConfig, useTLS bool, migration commons.MigrationConfig) (*qdrant.Client, error) {
+	if isSinkTarget(migration) {
+		return nil, nil
+	}
+	return connectToQdrant(globals, host, port, config, useTLS)
+}
+
+// writeToSink writes the points of an upsert as JSON lines, as they would have been sent to Qdrant,
+// after all filters, transformations and the mapping of IDs.
+func writeToSink(request *qdrant.UpsertPoints, migration commons.MigrationConfig) error {
+	operation := "upsert"
+	switch {
+	case migration.PayloadOnly:
+		operation = "overwrite_payload"
+	case migration.VectorsOnly:
+		operation = "update_vectors"
+	}
+
+	var lines bytes.Buffer
+	for _, point := range request.GetPoints() {
+		pointJSON, err := protojson.Marshal(point)
+		if err != nil {
+			return fmt.Errorf("failed to encode point: %w", err)
+		}
+		record := sinkRecord{Collection: request.GetCollectionName(), Operation: operation, Point: pointJSON}
+		if migration.TenantField != "" {
+			if key := pointTenant(point, migration); key != nil {
+				record.ShardKey = shardKeyName(key)
+			}
+		}
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to encode point: %w", err)
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	sinkLock.Lock()
+	defer sinkLock.Unlock()
+
+	if migration.Target == targetStdout {
+		_, err := sinkStdout.Write(lines.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to write points to stdout: %w", err)
+		}
+		return nil
+	}
+
+	// The file is truncated by the first write of a run, so it only has the points of the last run.
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if !sinkOpened {
+		flags |= os.O_TRUNC
+	}
+	file, err := os.OpenFile(migration.TargetFile, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open target file: %w", err)
+	}
+	defer file.Close()
+	sinkOpened = true
+
+	_, err = file.Write(lines.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write target file: %w", err)
+	}
+	return nil
+}
+
+// applySinkMode makes runs into a sink start from the beginning of the source, since they keep no checkpoints in Qdrant.
+// With stdout as sink, messages are printed to stderr instead, to keep the points apart from them.
+func applySinkMode(ctx *kong.Context) {
+	sinkLock.Lock()
+	sinkOpened = false
+	sinkLock.Unlock()
+
+	target := targetQdrant
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "migration.target" && flag.Target.Kind() == reflect.String {
+			target = flag.Target.String()
+		}
+	}
+	if target == targetQdrant || target == "" {
+		return
+	}
+
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "migration.restart" && flag.Target.Kind() == reflect.Bool {
+			flag.Target.SetBool(true)
+		}
+	}
+	if target == targetStdout {
+		pterm.SetDefaultOutput(os.Stderr)
+	}
+}
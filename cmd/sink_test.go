@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestWriteToSink(t *testing.T) {
+	request := &qdrant.UpsertPoints{
+		CollectionName: "target",
+		Points: []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Vectors: qdrant.NewVectorsDense([]float32{0.5}), Payload: qdrant.NewValueMap(map[string]any{"tenant": "a"})},
+			{Id: qdrant.NewIDNum(2), Vectors: qdrant.NewVectorsDense([]float32{1}), Payload: qdrant.NewValueMap(map[string]any{"tenant": "b"})},
+		},
+	}
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "points.jsonl")
+		if err := os.WriteFile(path, []byte("from an earlier run\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		sinkOpened = false
+		migration := commons.MigrationConfig{Target: targetFile, TargetFile: path, TenantField: "tenant"}
+
+		for range 2 {
+			if err := writeToSink(request, migration); err != nil {
+				t.Fatalf("writeToSink() error = %v", err)
+			}
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("file has %d lines, want the 4 points of this run: %s", len(lines), data)
+		}
+		var record sinkRecord
+		if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+			t.Fatalf("failed to decode %s: %v", lines[1], err)
+		}
+		if record.Collection != "target" || record.Operation != "upsert" || record.ShardKey != "b" {
+			t.Errorf("got record %+v", record)
+		}
+		var point qdrant.PointStruct
+		if err := protojson.Unmarshal(record.Point, &point); err != nil {
+			t.Fatalf("failed to decode point %s: %v", record.Point, err)
+		}
+		if point.GetId().GetNum() != 2 || point.GetVectors().GetVector().GetData()[0] != 1 {
+			t.Errorf("point = %s, want the point with ID 2", record.Point)
+		}
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		var out bytes.Buffer
+		sinkStdout = &out
+		defer func() { sinkStdout = os.Stdout }()
+
+		if err := writeToSink(request, commons.MigrationConfig{Target: targetStdout, PayloadOnly: true}); err != nil {
+			t.Fatalf("writeToSink() error = %v", err)
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != 2 {
+			t.Errorf("wrote %d lines, want 2: %s", lines, out.String())
+		}
+		if !strings.Contains(out.String(), `"operation":"overwrite_payload"`) || strings.Contains(out.String(), "shard_key") {
+			t.Errorf("got %s, want payload overwrites without shard keys", out.String())
+		}
+	})
+}
+
+func TestApplySinkMode(t *testing.T) {
+	_, cli, err := parseCommand([]string{"qdrant", "--source.collection", "a", "--target.collection", "b", "--migration.target", "file"})
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if !cli.Qdrant.Migration.Restart {
+		t.Errorf("got restart %v, want a run from the beginning of the source", cli.Qdrant.Migration.Restart)
+	}
+
+	_, cli, err = parseCommand([]string{"qdrant", "--source.collection", "a", "--target.collection", "b"})
+	if err != nil {
+		t.Fatalf("parseCommand() error = %v", err)
+	}
+	if cli.Qdrant.Migration.Restart || cli.Qdrant.Migration.OffsetsCollection != "_migration_offsets" {
+		t.Errorf("runs into Qdrant changed: restart %v, offsets collection %q", cli.Qdrant.Migration.Restart, cli.Qdrant.Migration.OffsetsCollection)
+	}
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// filterExistingPoints drops the points that every target already has, so re-runs only write what's missing.
+// With a hashField, a point only counts as existing if its stored hash matches, so changed points are written again.
+// Otherwise, existing IDs are enough.
+func filterExistingPoints(ctx context.Context, targetClients []*qdrant.Client, collection string, points []*qdrant.PointStruct, hashField string) ([]*qdrant.PointStruct, error) {
+	if len(points) == 0 {
+		return points, nil
+	}
+
+	ids := make([]*qdrant.PointId, 0, len(points))
+	for _, point := range points {
+		ids = append(ids, point.GetId())
+	}
+
+	// How many targets have every point, in its current version if hashes are stored.
+	found := make(map[string]int, len(points))
+	for _, client := range targetClients {
+		if hashField != "" {
+			stored, err := getStoredHashes(ctx, client, collection, ids, hashField)
+			if err != nil {
+				return nil, err
+			}
+			for _, point := range points {
+				id := pointIDToString(point.GetId())
+				if hash := stored[id]; hash != "" && hash == point.GetPayload()[hashField].GetStringValue() {
+					found[id]++
+				}
+			}
+			continue
+		}
+
+		existing, err := client.Get(ctx, &qdrant.GetPoints{
+			CollectionName: collection,
+			Ids:            ids,
+			WithPayload:    qdrant.NewWithPayload(false),
+			WithVectors:    qdrant.NewWithVectors(false),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get existing points from target: %w", err)
+		}
+		for _, point := range existing {
+			found[pointIDToString(point.GetId())]++
+		}
+	}
+
+	missing := make([]*qdrant.PointStruct, 0, len(points))
+	for _, point := range points {
+		if found[pointIDToString(point.GetId())] < len(targetClients) {
+			missing = append(missing, point)
+		}
+	}
+
+	return missing, nil
+}
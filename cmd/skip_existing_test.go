@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// existingPointsServer is a target collection that has some points already, and records the ones upserted into it.
+type existingPointsServer struct {
+	qdrant.UnimplementedPointsServer
+	existing map[uint64]bool
+
+	lock     sync.Mutex
+	upserted []uint64
+}
+
+func (s *existingPointsServer) Get(_ context.Context, req *qdrant.GetPoints) (*qdrant.GetResponse, error) {
+	var points []*qdrant.RetrievedPoint
+	for _, id := range req.GetIds() {
+		if s.existing[id.GetNum()] {
+			points = append(points, &qdrant.RetrievedPoint{Id: id})
+		}
+	}
+	return &qdrant.GetResponse{Result: points}, nil
+}
+
+func (s *existingPointsServer) Upsert(_ context.Context, req *qdrant.UpsertPoints) (*qdrant.PointsOperationResponse, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, point := range req.GetPoints() {
+		s.upserted = append(s.upserted, point.GetId().GetNum())
+	}
+	return &qdrant.PointsOperationResponse{Result: &qdrant.UpdateResult{Status: qdrant.UpdateStatus_Completed}}, nil
+}
+
+// denseCollectionServer serves a collection with an unnamed dense vector of size 2.
+type denseCollectionServer struct {
+	qdrant.UnimplementedCollectionsServer
+}
+
+func (s *denseCollectionServer) Get(_ context.Context, _ *qdrant.GetCollectionInfoRequest) (*qdrant.GetCollectionInfoResponse, error) {
+	return &qdrant.GetCollectionInfoResponse{Result: &qdrant.CollectionInfo{
+		Config: &qdrant.CollectionConfig{Params: &qdrant.CollectionParams{
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: 2, Distance: qdrant.Distance_Cosine}),
+		}},
+	}}, nil
+}
+
+func TestUpsertPointsSkipExisting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	target := &existingPointsServer{existing: map[uint64]bool{1: true, 3: true}}
+	server := grpc.NewServer()
+	qdrant.RegisterPointsServer(server, target)
+	qdrant.RegisterCollectionsServer(server, &denseCollectionServer{})
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	url := "http://" + listener.Addr().String()
+	host, port, useTLS, err := parseQdrantUrl(url)
+	if err != nil {
+		t.Fatalf("parseQdrantUrl() error = %v", err)
+	}
+	client, err := connectToQdrant(&Globals{}, host, port, commons.QdrantConfig{Url: url}, useTLS)
+	if err != nil {
+		t.Fatalf("connectToQdrant() error = %v", err)
+	}
+	defer client.Close()
+
+	var points []*qdrant.PointStruct
+	for id := uint64(1); id <= 4; id++ {
+		points = append(points, &qdrant.PointStruct{Id: qdrant.NewIDNum(id), Vectors: qdrant.NewVectorsDense([]float32{0.1, 0.2})})
+	}
+	request := &qdrant.UpsertPoints{CollectionName: "target", Points: points}
+	err = upsertPoints(context.Background(), client, request, commons.MigrationConfig{SkipExisting: true})
+	if err != nil {
+		t.Fatalf("upsertPoints() error = %v", err)
+	}
+
+	if len(target.upserted) != 2 || target.upserted[0] != 2 || target.upserted[1] != 4 {
+		t.Errorf("upserted points %v, want [2 4]", target.upserted)
+	}
+	if len(request.GetPoints()) != 2 {
+		t.Errorf("request has %d points after writing, want the 2 written ones", len(request.GetPoints()))
+	}
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Tokens longer than this are left out, like FastEmbed does.
+const maxSparseTokenLength = 40
+
+// englishStopwords are the stopwords of NLTK, which FastEmbed's BM25 leaves out as well.
+var englishStopwords = toSet(strings.Fields(`i me my myself we our ours ourselves you you're you've you'll you'd your yours
+	yourself yourselves he him his himself she she's her hers herself it it's its itself they them their theirs themselves
+	what which who whom this that that'll these those am is are was were be been being have has had having do does did doing
+	a an the and but if or because as until while of at by for with about against between into through during before after
+	above below to from up down in out on off over under again further then once here there when where why how all any both
+	each few more most other some such no nor not only own same so than too very s t can will just don don't should should've
+	now d ll m o re ve y ain aren aren't couldn couldn't didn didn't doesn doesn't hadn hadn't hasn hasn't haven haven't isn
+	isn't ma mightn mightn't mustn mustn't needn needn't shan shan't shouldn shouldn't wasn wasn't weren weren't won won't
+	wouldn wouldn't`))
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// generateSparseVectors adds a BM25 sparse vector of the text in --migration.sparse.field to every point,
+// next to the vectors it already has, so dense vectors are copied and sparse ones generated in the same pass.
+func generateSparseVectors(points []*qdrant.PointStruct, config commons.SparseConfig) {
+	if config.Field == "" {
+		return
+	}
+	for _, point := range points {
+		text := payloadField(point.GetPayload(), config.Field).GetStringValue()
+		if text == "" {
+			continue
+		}
+		indices, values := bm25Vector(text, config)
+		if len(indices) == 0 {
+			continue
+		}
+		addPointVector(point, config.Vector, qdrant.NewVectorSparse(indices, values))
+	}
+}
+
+// bm25Vector computes the term frequency part of BM25 for every token of a text, the same way as FastEmbed's
+// Qdrant/bm25 model without stemming. The inverse document frequency is applied by Qdrant with the idf modifier.
+func bm25Vector(text string, config commons.SparseConfig) ([]uint32, []float32) {
+	var tokens []string
+	for _, token := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_'
+	}) {
+		if token == "_" || englishStopwords[token] || len([]rune(token)) > maxSparseTokenLength {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+
+	frequencies := make(map[uint32]float64)
+	for _, token := range tokens {
+		frequencies[sparseTokenIndex(token)]++
+	}
+
+	documentLength := float64(len(tokens))
+	indices := make([]uint32, 0, len(frequencies))
+	for index := range frequencies {
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool { return indices[i] < indices[j] })
+
+	values := make([]float32, len(indices))
+	for i, index := range indices {
+		tf := frequencies[index]
+		values[i] = float32(tf * (config.K + 1) / (tf + config.K*(1-config.B+config.B*documentLength/config.AvgLength)))
+	}
+	return indices, values
+}
+
+// sparseTokenIndex is the absolute value of the signed 32-bit MurmurHash3 of a token, like FastEmbed computes it.
+func sparseTokenIndex(token string) uint32 {
+	hash := int32(murmur3(token))
+	if hash < 0 {
+		return uint32(-int64(hash))
+	}
+	return uint32(hash)
+}
+
+// murmur3 is MurmurHash3 x86 32-bit with seed 0.
+func murmur3(s string) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	data := []byte(s)
+	var h uint32
+
+	blocks := len(data) / 4
+	for i := range blocks {
+		k := binary.LittleEndian.Uint32(data[4*i:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[4*blocks:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
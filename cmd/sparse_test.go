@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"math"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestSparseTokenIndex(t *testing.T) {
+	// Values of mmh3.hash in Python, which FastEmbed takes the absolute value of.
+	tests := []struct {
+		token string
+		want  uint32
+	}{
+		{"", 0},
+		{"hello", 613153351},
+		{"foo", 156908512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			if got := sparseTokenIndex(tt.token); got != tt.want {
+				t.Errorf("sparseTokenIndex(%q) = %d, want %d", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25Vector(t *testing.T) {
+	config := commons.SparseConfig{K: 1.2, B: 0.75, AvgLength: 256}
+	indices, values := bm25Vector("The cat sat on the cat's mat!", config)
+
+	// "the", "on" and "s" are stopwords, which leaves cat, sat, cat and mat.
+	weights := make(map[uint32]float32)
+	for i, index := range indices {
+		weights[index] = values[i]
+	}
+	if len(weights) != 3 {
+		t.Fatalf("got %d tokens, want 3", len(weights))
+	}
+
+	norm := 1.2 * (1 - 0.75 + 0.75*4.0/256)
+	for token, tf := range map[string]float64{"cat": 2, "sat": 1, "mat": 1} {
+		want := tf * 2.2 / (tf + norm)
+		got := weights[sparseTokenIndex(token)]
+		if math.Abs(float64(got)-want) > 1e-6 {
+			t.Errorf("weight of %q = %v, want %v", token, got, want)
+		}
+	}
+
+	for i := 1; i < len(indices); i++ {
+		if indices[i-1] >= indices[i] {
+			t.Errorf("indices aren't sorted: %v", indices)
+		}
+	}
+}
+
+func TestGenerateSparseVectors(t *testing.T) {
+	config := commons.SparseConfig{Field: "meta.text", Vector: "bm25", K: 1.2, B: 0.75, AvgLength: 256}
+	points := []*qdrant.PointStruct{
+		{
+			Vectors: qdrant.NewVectorsDense([]float32{1, 2}),
+			Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"text": "hybrid search"}}),
+		},
+		{
+			Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{"dense": qdrant.NewVectorDense([]float32{3, 4})}),
+			Payload: qdrant.NewValueMap(map[string]any{"meta": map[string]any{"text": "sparse vectors"}}),
+		},
+		{
+			Vectors: qdrant.NewVectorsDense([]float32{5, 6}),
+			Payload: qdrant.NewValueMap(map[string]any{"other": "no text"}),
+		},
+	}
+
+	generateSparseVectors(points, config)
+
+	unnamed := points[0].GetVectors().GetVectors().GetVectors()
+	if got := unnamed[""].GetData(); len(got) != 2 || got[0] != 1 {
+		t.Errorf("unnamed vector = %v, want it kept under the empty name", got)
+	}
+	if got := unnamed["bm25"].GetIndices().GetData(); len(got) != 2 {
+		t.Errorf("sparse vector has %d indices, want 2", len(got))
+	}
+
+	named := points[1].GetVectors().GetVectors().GetVectors()
+	if named["dense"] == nil || named["bm25"] == nil {
+		t.Errorf("vectors = %v, want dense and bm25", named)
+	}
+
+	if points[2].GetVectors().GetVector() == nil {
+		t.Error("point without the text field should keep its unnamed vector")
+	}
+}
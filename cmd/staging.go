@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+const stagingSegmentExt = ".seg"
+
+type stagedSegment struct {
+	seq  uint64
+	path string
+	size int64
+}
+
+// stagingQueue is an on-disk queue of batches between the reader and the writer of a migration.
+// A batch is durable once push returns, so the source offset can move past it before the target has it.
+// Segments left over by an interrupted run are drained first when the queue is reopened.
+type stagingQueue struct {
+	dir     string
+	maxSize int64
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	segments []stagedSegment
+	size     int64
+	nextSeq  uint64
+	closed   bool
+}
+
+// openStagingQueue opens the queue in dir, creating it if needed, and picks up the segments already in it.
+// Pushing waits while the staged segments take maxSize bytes or more. 0 means no limit.
+func openStagingQueue(dir string, maxSize int64) (*stagingQueue, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staging directory: %w", err)
+	}
+
+	q := &stagingQueue{dir: dir, maxSize: maxSize}
+	q.cond = sync.NewCond(&q.mu)
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), stagingSegmentExt), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), stagingSegmentExt) {
+			// Partially written segments were never acknowledged, so their batches will be read again.
+			_ = os.Remove(path)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat staged segment: %w", err)
+		}
+		q.segments = append(q.segments, stagedSegment{seq: seq, path: path, size: info.Size()})
+		q.size += info.Size()
+		q.nextSeq = max(q.nextSeq, seq+1)
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+
+	return q, nil
+}
+
+// pending returns the number of staged segments that were not written to the target yet.
+func (q *stagingQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.segments)
+}
+
+// clear removes all staged segments, e.g. when the migration is restarted from scratch.
+func (q *stagingQueue) clear() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, segment := range q.segments {
+		err := os.Remove(segment.path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove staged segment: %w", err)
+		}
+	}
+	q.segments = nil
+	q.size = 0
+	return nil
+}
+
+// push durably stages a batch, waiting for room if the queue is full.
+func (q *stagingQueue) push(ctx context.Context, points []*qdrant.PointStruct) error {
+	data, err := proto.Marshal(&qdrant.UpsertPoints{Points: points})
+	if err != nil {
+		return fmt.Errorf("failed to encode staged batch: %w", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// An oversized batch is still admitted into an empty queue, so it's not stuck forever.
+	stop := context.AfterFunc(ctx, q.broadcast)
+	defer stop()
+	for q.maxSize > 0 && len(q.segments) > 0 && q.size+int64(len(data)) > q.maxSize {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		q.cond.Wait()
+	}
+
+	seq := q.nextSeq
+	path := filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, stagingSegmentExt))
+	err = writeFileSync(path, data)
+	if err != nil {
+		return fmt.Errorf("failed to stage batch: %w", err)
+	}
+
+	q.nextSeq++
+	q.segments = append(q.segments, stagedSegment{seq: seq, path: path, size: int64(len(data))})
+	q.size += int64(len(data))
+	q.cond.Broadcast()
+
+	return nil
+}
+
+// close signals that no more batches will be pushed, so drain returns once the queue is empty.
+func (q *stagingQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// drain writes the staged batches in order and removes each one once written, until the queue is closed and empty.
+func (q *stagingQueue) drain(ctx context.Context, write func(ctx context.Context, points []*qdrant.PointStruct) error) error {
+	stop := context.AfterFunc(ctx, q.broadcast)
+	defer stop()
+
+	for {
+		q.mu.Lock()
+		for len(q.segments) == 0 && !q.closed && ctx.Err() == nil {
+			q.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+		if len(q.segments) == 0 {
+			q.mu.Unlock()
+			return nil
+		}
+		segment := q.segments[0]
+		q.mu.Unlock()
+
+		data, err := os.ReadFile(segment.path)
+		if err != nil {
+			return fmt.Errorf("failed to read staged batch: %w", err)
+		}
+		var batch qdrant.UpsertPoints
+		err = proto.Unmarshal(data, &batch)
+		if err != nil {
+			return fmt.Errorf("failed to decode staged batch %s: %w", segment.path, err)
+		}
+
+		err = write(ctx, batch.GetPoints())
+		if err != nil {
+			return err
+		}
+
+		err = os.Remove(segment.path)
+		if err != nil {
+			return fmt.Errorf("failed to remove staged batch: %w", err)
+		}
+
+		q.mu.Lock()
+		q.segments = q.segments[1:]
+		q.size -= segment.size
+		q.cond.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+func (q *stagingQueue) broadcast() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// writeFileSync writes a file under a temporary name and renames it once synced,
+// so a crash never leaves a partial file under the final name.
+func writeFileSync(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_stagingQueue(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	queue, err := openStagingQueue(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint64(1); i <= 3; i++ {
+		err = queue.push(ctx, []*qdrant.PointStruct{{Id: qdrant.NewIDNum(i)}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A segment that was being written when the previous run crashed.
+	err = os.WriteFile(filepath.Join(dir, "00000000000000000003.seg.tmp"), []byte("partial"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening the queue simulates a restart with batches that were never written.
+	queue, err = openStagingQueue(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue.pending() != 3 {
+		t.Fatalf("got %d pending batches after reopening, expected 3", queue.pending())
+	}
+	err = queue.push(ctx, []*qdrant.PointStruct{{Id: qdrant.NewIDNum(4)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.close()
+
+	var ids []uint64
+	err = queue.drain(ctx, func(_ context.Context, points []*qdrant.PointStruct) error {
+		for _, point := range points {
+			ids = append(ids, point.GetId().GetNum())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range ids {
+		if id != uint64(i+1) {
+			t.Fatalf("drained ids %v, expected them in staging order", ids)
+		}
+	}
+	if len(ids) != 4 {
+		t.Fatalf("drained %d batches, expected 4", len(ids))
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("staging directory has %d entries after draining, expected none", len(entries))
+	}
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// stallWatchdog cancels a run that made no progress for a while, e.g. because a scroll hangs on a dead connection.
+// Every completed call to Qdrant that reads or writes points counts as progress, and so does the time the run is paused.
+type stallWatchdog struct {
+	timeout  time.Duration
+	progress atomic.Int64
+	stalled  atomic.Bool
+}
+
+// currentWatchdog watches the current run, if --stall-timeout is set.
+var currentWatchdog *stallWatchdog
+
+func newStallWatchdog(timeout time.Duration) *stallWatchdog {
+	w := &stallWatchdog{timeout: timeout}
+	w.touch()
+	return w
+}
+
+func (w *stallWatchdog) touch() {
+	if w == nil {
+		return
+	}
+	w.progress.Store(time.Now().UnixNano())
+}
+
+// idle returns how long the run made no progress for.
+func (w *stallWatchdog) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, w.progress.Load()))
+}
+
+// watch cancels the run once it made no progress for the timeout, until ctx is done.
+func (w *stallWatchdog) watch(ctx context.Context, cancel context.CancelFunc, gate *pauseGate) {
+	ticker := time.NewTicker(min(w.timeout/4, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if gate.isPaused() {
+				w.touch()
+				continue
+			}
+			if w.idle(now) >= w.timeout {
+				pterm.Warning.Printfln("No progress for %s, the connection may be dead or a call may hang", w.timeout)
+				w.stalled.Store(true)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// runWithStallRecovery runs the command, and if it stalls, closes its connections and runs it again,
+// up to --stall-retries times. The new run resumes from the last checkpoint of the stalled one.
+func runWithStallRecovery(ctx *kong.Context, globals *Globals) error {
+	if globals.StallTimeout <= 0 {
+		return ctx.Run(globals)
+	}
+
+	parent := globals.ctx
+	defer func() {
+		globals.ctx = parent
+		currentWatchdog = nil
+	}()
+
+	for attempt := 1; ; attempt++ {
+		runCtx, cancel := context.WithCancel(globals.baseContext())
+		globals.ctx = runCtx
+		watchdog := newStallWatchdog(globals.StallTimeout)
+		currentWatchdog = watchdog
+		go watchdog.watch(runCtx, cancel, globals.getPauseGate())
+
+		err := ctx.Run(globals)
+		cancel()
+		globals.ctx = parent
+		globals.closeClients()
+
+		if !watchdog.stalled.Load() {
+			return err
+		}
+		if attempt > globals.StallRetries {
+			return fmt.Errorf("the migration made no progress for %s after %d reconnect(s)", globals.StallTimeout, globals.StallRetries)
+		}
+
+		pterm.Warning.Printfln("Reconnecting and resuming from the last checkpoint (%d of %d)", attempt, globals.StallRetries)
+		commons.SetResumeCheckpoints(commons.Checkpoints())
+		disableRestart(ctx)
+	}
+}
+
+// disableRestart unsets --migration.restart, so that a run started again continues from its checkpoints.
+func disableRestart(ctx *kong.Context) {
+	for _, flag := range ctx.Flags() {
+		if flag.Name == "migration.restart" && flag.Target.Kind() == reflect.Bool {
+			flag.Target.SetBool(false)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStallWatchdog(t *testing.T) {
+	t.Run("stalled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watchdog := newStallWatchdog(40 * time.Millisecond)
+
+		go watchdog.watch(ctx, cancel, &pauseGate{})
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("watchdog didn't cancel the stalled run")
+		}
+		if !watchdog.stalled.Load() {
+			t.Error("watchdog didn't mark the run as stalled")
+		}
+	})
+
+	t.Run("progressing", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watchdog := newStallWatchdog(40 * time.Millisecond)
+
+		go watchdog.watch(ctx, cancel, &pauseGate{})
+		for i := 0; i < 10; i++ {
+			time.Sleep(10 * time.Millisecond)
+			watchdog.touch()
+		}
+		if watchdog.stalled.Load() || ctx.Err() != nil {
+			t.Error("watchdog canceled a run that made progress")
+		}
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		watchdog := newStallWatchdog(40 * time.Millisecond)
+		gate := &pauseGate{}
+		gate.pause()
+
+		go watchdog.watch(ctx, cancel, gate)
+		time.Sleep(100 * time.Millisecond)
+		if watchdog.stalled.Load() || ctx.Err() != nil {
+			t.Error("watchdog canceled a paused run")
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pterm/pterm"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Number of times in a row a write that exceeded the write rate limit of a target is retried.
+const strictModeRetries = 5
+
+// strictModeLimiters paces the writes to target collections with a write rate limit, by targetCollectionKey.
+var strictModeLimiters sync.Map
+
+// targetStrictMode returns the strict mode of a target collection, or nil if it isn't enabled.
+// The limits that shape writes are reported when the collection is first written to.
+func targetStrictMode(ctx context.Context, client *qdrant.Client, collection string) (*qdrant.StrictModeConfig, error) {
+	info, err := targetCollectionInfo(ctx, client, collection)
+	if err != nil {
+		return nil, err
+	}
+	config := info.GetConfig().GetStrictModeConfig()
+	if !config.GetEnabled() {
+		return nil, nil
+	}
+
+	key := targetCollectionKey{client: client, collection: collection}
+	var limiter *rate.Limiter
+	if perMinute := config.GetWriteRateLimit(); perMinute > 0 {
+		limiter = rate.NewLimiter(rate.Limit(float64(perMinute)/60), 1)
+	}
+	if _, loaded := strictModeLimiters.LoadOrStore(key, limiter); !loaded {
+		if limits := strictModeLimits(config); len(limits) > 0 {
+			pterm.Info.Printfln("Target collection '%s' has strict mode enabled, writing %s", collection, strings.Join(limits, ", "))
+		}
+	}
+	return config, nil
+}
+
+// strictModeLimits describes the limits of strict mode that apply to writes.
+func strictModeLimits(config *qdrant.StrictModeConfig) []string {
+	var limits []string
+	if n := config.GetUpsertMaxBatchsize(); n > 0 {
+		limits = append(limits, fmt.Sprintf("batches of up to %d points", n))
+	}
+	if n := config.GetWriteRateLimit(); n > 0 {
+		limits = append(limits, fmt.Sprintf("up to %d writes per minute", n))
+	}
+	if n := config.GetMaxPointsCount(); n > 0 {
+		limits = append(limits, fmt.Sprintf("up to %d points in total", n))
+	}
+	if n := config.GetMaxCollectionPayloadSizeBytes(); n > 0 {
+		limits = append(limits, fmt.Sprintf("up to %s of payloads in total", commons.ByteSize(n)))
+	}
+	if n := config.GetMaxCollectionVectorSizeBytes(); n > 0 {
+		limits = append(limits, fmt.Sprintf("up to %s of vectors in total", commons.ByteSize(n)))
+	}
+	return limits
+}
+
+// writeInStrictMode writes the points of an upsert in batches no larger than the strict mode of the target collection
+// allows, paced to its write rate limit. Writes the target rejects for exceeding the rate limit anyway are retried,
+// and ones it rejects for other limits fail with the limits of the collection, instead of just the error of the server.
+func writeInStrictMode(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints, write func(*qdrant.UpsertPoints) error) error {
+	collection := request.GetCollectionName()
+	config, err := targetStrictMode(ctx, client, collection)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return write(request)
+	}
+
+	batchSize := len(request.GetPoints())
+	if n := config.GetUpsertMaxBatchsize(); n > 0 && n < uint64(batchSize) {
+		batchSize = int(n)
+	}
+	limiter, _ := strictModeLimiters.Load(targetCollectionKey{client: client, collection: collection})
+	for points := range slices.Chunk(request.GetPoints(), max(batchSize, 1)) {
+		for attempt := 0; ; attempt++ {
+			if limiter := limiter.(*rate.Limiter); limiter != nil {
+				err = limiter.Wait(ctx)
+				if err != nil {
+					return err
+				}
+			}
+			err = write(withPoints(request, points))
+			if err == nil || !isRateLimited(err) || attempt >= strictModeRetries {
+				break
+			}
+			delay := retryDelay(err, attempt)
+			pterm.Warning.Printfln("Target collection '%s' is over its write rate limit, retrying in %s", collection, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err != nil {
+			return explainStrictModeError(err, collection, config)
+		}
+	}
+	return nil
+}
+
+func isRateLimited(err error) bool {
+	s := qdrantStatus(err)
+	return s.Code() == codes.ResourceExhausted && strings.Contains(strings.ToLower(s.Message()), "rate limit")
+}
+
+// explainStrictModeError adds the limits of strict mode to a rejection of the target that was caused by one of them.
+func explainStrictModeError(err error, collection string, config *qdrant.StrictModeConfig) error {
+	message := strings.ToLower(status.Convert(err).Message())
+	if !strings.Contains(message, "strict mode") && !strings.Contains(message, "limit") {
+		return err
+	}
+	limits := strictModeLimits(config)
+	if len(limits) == 0 {
+		return fmt.Errorf("target collection '%s' rejected the write under its strict mode: %w", collection, err)
+	}
+	return fmt.Errorf("target collection '%s' rejected the write under its strict mode, which allows %s: %w", collection, strings.Join(limits, ", "), err)
+}
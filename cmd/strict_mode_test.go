@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func Test_strictModeLimits(t *testing.T) {
+	config := &qdrant.StrictModeConfig{
+		Enabled:                       qdrant.PtrOf(true),
+		UpsertMaxBatchsize:            qdrant.PtrOf(uint64(100)),
+		WriteRateLimit:                qdrant.PtrOf(uint32(600)),
+		MaxCollectionPayloadSizeBytes: qdrant.PtrOf(uint64(1 << 20)),
+	}
+	got := strings.Join(strictModeLimits(config), ", ")
+	want := "batches of up to 100 points, up to 600 writes per minute, up to 1.0MiB of payloads in total"
+	if got != want {
+		t.Errorf("strictModeLimits() = %q, want %q", got, want)
+	}
+
+	if got := strictModeLimits(&qdrant.StrictModeConfig{Enabled: qdrant.PtrOf(true)}); len(got) != 0 {
+		t.Errorf("strictModeLimits() = %v, want none", got)
+	}
+}
+
+func Test_explainStrictModeError(t *testing.T) {
+	config := &qdrant.StrictModeConfig{UpsertMaxBatchsize: qdrant.PtrOf(uint64(10))}
+
+	tests := []struct {
+		name      string
+		err       error
+		explained bool
+	}{
+		{"strict mode", status.Error(codes.InvalidArgument, "Forbidden: Strict mode: upsert limit exceeded: 20 > 10"), true},
+		{"payload size", status.Error(codes.InvalidArgument, "Max payload storage size limit reached"), true},
+		{"unrelated", status.Error(codes.NotFound, "Collection `test` doesn't exist"), false},
+		{"not grpc", errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := explainStrictModeError(tt.err, "test", config)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("explainStrictModeError() = %v, doesn't wrap %v", got, tt.err)
+			}
+			if explained := strings.Contains(got.Error(), "batches of up to 10 points"); explained != tt.explained {
+				t.Errorf("explainStrictModeError() = %v, explained = %v, want %v", got, explained, tt.explained)
+			}
+		})
+	}
+}
+
+func Test_isRateLimited(t *testing.T) {
+	if !isRateLimited(status.Error(codes.ResourceExhausted, "Rate limiting exceeded: Write rate limit exceeded")) {
+		t.Errorf("isRateLimited() = false for a rate limit")
+	}
+	if !isRateLimited(fmt.Errorf("failed to upsert: %w", &qdrant.QdrantResourceExhaustedError{Reason: "Rate limiting exceeded: Write rate limit exceeded", RetryAfterS: 1})) {
+		t.Errorf("isRateLimited() = false for a rate limit with retry-after")
+	}
+	if isRateLimited(status.Error(codes.InvalidArgument, "Forbidden: Strict mode: upsert limit exceeded")) {
+		t.Errorf("isRateLimited() = true for another limit")
+	}
+}
@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// targetShardKeys caches the shard keys of every target collection, by client and collection,
+// so shard keys of new tenants are only created once.
+var targetShardKeys sync.Map
+
+type shardKeySet struct {
+	lock sync.Mutex
+	keys map[string]bool
+}
+
+type shardKeyCacheKey struct {
+	client     *qdrant.Client
+	collection string
+}
+
+// tenantShardKey returns the shard key of the tenant of a point, which is the value of --migration.tenant-field,
+// or nil if the point has no tenant. Strings become keyword shard keys, non-negative integers numeric ones.
+func tenantShardKey(point *qdrant.PointStruct, field string) *qdrant.ShardKey {
+	switch kind := payloadField(point.GetPayload(), field).GetKind().(type) {
+	case *qdrant.Value_StringValue:
+		if kind.StringValue != "" {
+			return qdrant.NewShardKeyKeyword(kind.StringValue)
+		}
+	case *qdrant.Value_IntegerValue:
+		if kind.IntegerValue >= 0 {
+			return qdrant.NewShardKeyNum(uint64(kind.IntegerValue))
+		}
+	}
+	return nil
+}
+
+// pointTenant returns the shard key of the tenant of a point, or the one of --migration.default-tenant
+// if the point has no tenant. It returns nil if neither is set.
+func pointTenant(point *qdrant.PointStruct, migration commons.MigrationConfig) *qdrant.ShardKey {
+	if key := tenantShardKey(point, migration.TenantField); key != nil {
+		return key
+	}
+	return defaultTenantShardKey(migration)
+}
+
+// defaultTenantShardKey returns the shard key of --migration.default-tenant, numeric if it's an integer, or nil if it isn't set.
+func defaultTenantShardKey(migration commons.MigrationConfig) *qdrant.ShardKey {
+	if migration.DefaultTenant == "" {
+		return nil
+	}
+	if number, err := strconv.ParseUint(migration.DefaultTenant, 10, 64); err == nil {
+		return qdrant.NewShardKeyNum(number)
+	}
+	return qdrant.NewShardKeyKeyword(migration.DefaultTenant)
+}
+
+func shardKeyName(key *qdrant.ShardKey) string {
+	if number, ok := key.GetKey().(*qdrant.ShardKey_Number); ok {
+		return strconv.FormatUint(number.Number, 10)
+	}
+	return key.GetKeyword()
+}
+
+// filterTenants leaves out the points of tenants that aren't in --migration.tenants, to migrate tenants one at a time.
+func filterTenants(points []*qdrant.PointStruct, migration commons.MigrationConfig) []*qdrant.PointStruct {
+	if migration.TenantField == "" || len(migration.Tenants) == 0 {
+		return points
+	}
+	result := points[:0:0]
+	for _, point := range points {
+		key := pointTenant(point, migration)
+		if key != nil && slices.Contains(migration.Tenants, shardKeyName(key)) {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// tenantFilter returns a filter for the points of --migration.tenants, to read only those from a Qdrant source,
+// or nil if all tenants are migrated. Points without a tenant match it if --migration.default-tenant is one of them.
+func tenantFilter(migration commons.MigrationConfig) *qdrant.Filter {
+	if migration.TenantField == "" || len(migration.Tenants) == 0 {
+		return nil
+	}
+	var numbers []int64
+	for _, tenant := range migration.Tenants {
+		if number, err := strconv.ParseInt(tenant, 10, 64); err == nil && number >= 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	conditions := []*qdrant.Condition{qdrant.NewMatchKeywords(migration.TenantField, migration.Tenants...)}
+	if len(numbers) > 0 {
+		conditions = append(conditions, qdrant.NewMatchInts(migration.TenantField, numbers...))
+	}
+	if migration.DefaultTenant != "" && slices.Contains(migration.Tenants, migration.DefaultTenant) {
+		conditions = append(conditions, qdrant.NewIsEmpty(migration.TenantField))
+	}
+	return &qdrant.Filter{Should: conditions}
+}
+
+// applyTenantScope keeps the offsets of runs with --migration.tenants apart by their tenants,
+// so a run for some tenants doesn't resume from where a run for others stopped. It's called once per run.
+func applyTenantScope(ctx *kong.Context) {
+	var tenantField bool
+	var tenants []string
+	for _, flag := range ctx.Flags() {
+		switch flag.Name {
+		case "migration.tenant-field":
+			tenantField = flag.Target.String() != ""
+		case "migration.tenants":
+			tenants, _ = flag.Target.Interface().([]string)
+		}
+	}
+	if !tenantField || len(tenants) == 0 {
+		commons.SetOffsetKeyScope("")
+		return
+	}
+	tenants = slices.Clone(tenants)
+	slices.Sort(tenants)
+	commons.SetOffsetKeyScope("/tenants=" + strings.Join(slices.Compact(tenants), ","))
+}
+
+// tenantShardingMethod returns the sharding method to create a target collection with:
+// custom with --migration.tenant-field, so every tenant gets a shard key of its own, or the given one otherwise.
+func tenantShardingMethod(migration commons.MigrationConfig, method *qdrant.ShardingMethod) *qdrant.ShardingMethod {
+	if migration.TenantField != "" {
+		return qdrant.PtrOf(qdrant.ShardingMethod_Custom)
+	}
+	return method
+}
+
+// upsertPoints writes points to the target. With --migration.tenant-field, the points are grouped by tenant,
+// and every group is written to the shard key of its tenant, which is created if the collection doesn't have it yet.
+// Points without a tenant are written to the shard key of --migration.default-tenant, or with the shard key selector
+// of the request. If there's neither, the batch is rejected before anything of it is written.
+// With --migration.payload-only or --migration.vectors-only, only the payloads or vectors of the points are written.
+// With --migration.skip-existing, the points the target already has are left out of the request.
+// Points with NaN or infinite values or wrong dimensions in their vectors are handled per --migration.invalid-vector-policy first.
+func upsertPoints(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints, migration commons.MigrationConfig) (err error) {
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			currentReport.observeWrite(time.Since(start))
+		}
+	}()
+
+	err = validateVectors(ctx, client, request, migration)
+	if err != nil {
+		return err
+	}
+	if len(request.GetPoints()) == 0 {
+		return nil
+	}
+	if isSinkTarget(migration) {
+		// No call to Qdrant is made for points written to a sink, so writing them is the progress of the run.
+		err = writeToSink(request, migration)
+		if err == nil {
+			currentWatchdog.touch()
+		}
+		return err
+	}
+	if migration.SkipExisting {
+		missing, err := filterExistingPoints(ctx, []*qdrant.Client{client}, request.GetCollectionName(), request.GetPoints(), "")
+		if err != nil {
+			return err
+		}
+		currentReport.addSkipped(uint64(len(request.GetPoints()) - len(missing)))
+		request.Points = missing
+		if len(missing) == 0 {
+			return nil
+		}
+	}
+	release, err := acquireWrite(ctx, client, request.GetCollectionName(), migration)
+	if err != nil {
+		return err
+	}
+	defer release()
+	if migration.TenantField == "" {
+		return sendPoints(ctx, client, request, migration)
+	}
+
+	var keys []*qdrant.ShardKey
+	groups := make(map[string][]*qdrant.PointStruct)
+	for _, point := range request.GetPoints() {
+		key := pointTenant(point, migration)
+		name := ""
+		if key != nil {
+			name = shardKeyName(key)
+		} else if request.GetShardKeySelector() == nil {
+			return fmt.Errorf("point %s has no tenant in payload field '%s', set --migration.default-tenant for such points", pointIDToString(point.GetId()), migration.TenantField)
+		}
+		if _, ok := groups[name]; !ok {
+			keys = append(keys, key)
+		}
+		groups[name] = append(groups[name], point)
+	}
+
+	for _, key := range keys {
+		selector := request.GetShardKeySelector()
+		name := ""
+		if key != nil {
+			err := ensureShardKey(ctx, client, request.GetCollectionName(), key)
+			if err != nil {
+				return err
+			}
+			selector = &qdrant.ShardKeySelector{ShardKeys: []*qdrant.ShardKey{key}}
+			name = shardKeyName(key)
+		}
+
+		err := sendPoints(ctx, client, &qdrant.UpsertPoints{
+			CollectionName:   request.GetCollectionName(),
+			Points:           groups[name],
+			Wait:             request.Wait,
+			Ordering:         request.GetOrdering(),
+			ShardKeySelector: selector,
+		}, migration)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureShardKey creates a shard key in the target collection, unless it has it already.
+func ensureShardKey(ctx context.Context, client *qdrant.Client, collection string, key *qdrant.ShardKey) error {
+	cached, _ := targetShardKeys.LoadOrStore(shardKeyCacheKey{client: client, collection: collection}, &shardKeySet{})
+	set := cached.(*shardKeySet)
+	set.lock.Lock()
+	defer set.lock.Unlock()
+
+	if set.keys == nil {
+		existing, err := getShardKeys(ctx, client, collection)
+		if err != nil {
+			return err
+		}
+		set.keys = make(map[string]bool)
+		for _, existingKey := range existing {
+			set.keys[shardKeyName(existingKey)] = true
+		}
+	}
+
+	name := shardKeyName(key)
+	if set.keys[name] {
+		return nil
+	}
+	err := client.CreateShardKey(ctx, collection, &qdrant.CreateShardKey{ShardKey: key})
+	if err != nil {
+		return fmt.Errorf("failed to create shard key '%s': %w", name, err)
+	}
+	pterm.Info.Printfln("Created shard key '%s' in collection '%s'", name, collection)
+	set.keys[name] = true
+	return nil
+}
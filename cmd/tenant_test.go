@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestTenantShardKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		want    string
+		wantNil bool
+	}{
+		{name: "keyword", payload: map[string]any{"org": map[string]any{"id": "acme"}}, want: "acme"},
+		{name: "number", payload: map[string]any{"org": map[string]any{"id": 42}}, want: "42"},
+		{name: "negative number", payload: map[string]any{"org": map[string]any{"id": -1}}, wantNil: true},
+		{name: "empty", payload: map[string]any{"org": map[string]any{"id": ""}}, wantNil: true},
+		{name: "missing", payload: map[string]any{"other": "acme"}, wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tenantShardKey(&qdrant.PointStruct{Payload: qdrant.NewValueMap(tt.payload)}, "org.id")
+			if tt.wantNil {
+				if key != nil {
+					t.Errorf("tenantShardKey() = %v, want nil", key)
+				}
+				return
+			}
+			if got := shardKeyName(key); got != tt.want {
+				t.Errorf("tenantShardKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterTenants(t *testing.T) {
+	points := []*qdrant.PointStruct{
+		{Id: qdrant.NewIDNum(1), Payload: qdrant.NewValueMap(map[string]any{"tenant": "a"})},
+		{Id: qdrant.NewIDNum(2), Payload: qdrant.NewValueMap(map[string]any{"tenant": "b"})},
+		{Id: qdrant.NewIDNum(3), Payload: qdrant.NewValueMap(map[string]any{"tenant": 7})},
+		{Id: qdrant.NewIDNum(4), Payload: qdrant.NewValueMap(map[string]any{})},
+	}
+
+	tests := []struct {
+		name      string
+		migration commons.MigrationConfig
+		want      []uint64
+	}{
+		{name: "no tenant field", migration: commons.MigrationConfig{Tenants: []string{"a"}}, want: []uint64{1, 2, 3, 4}},
+		{name: "all tenants", migration: commons.MigrationConfig{TenantField: "tenant"}, want: []uint64{1, 2, 3, 4}},
+		{name: "some tenants", migration: commons.MigrationConfig{TenantField: "tenant", Tenants: []string{"a", "7"}}, want: []uint64{1, 3}},
+		{name: "default tenant", migration: commons.MigrationConfig{TenantField: "tenant", Tenants: []string{"b", "shared"}, DefaultTenant: "shared"}, want: []uint64{2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterTenants(points, tt.migration)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d points, want %d", len(got), len(tt.want))
+			}
+			for i, point := range got {
+				if point.GetId().GetNum() != tt.want[i] {
+					t.Errorf("point %d = %d, want %d", i, point.GetId().GetNum(), tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTenantFilter(t *testing.T) {
+	if got := tenantFilter(commons.MigrationConfig{TenantField: "tenant"}); got != nil {
+		t.Errorf("tenantFilter() = %v, want nil for all tenants", got)
+	}
+
+	tests := []struct {
+		name      string
+		migration commons.MigrationConfig
+		want      []string
+	}{
+		{
+			name:      "keywords",
+			migration: commons.MigrationConfig{TenantField: "tenant", Tenants: []string{"a", "b"}},
+			want:      []string{"keywords"},
+		},
+		{
+			name:      "numbers",
+			migration: commons.MigrationConfig{TenantField: "tenant", Tenants: []string{"a", "7"}},
+			want:      []string{"keywords", "integers"},
+		},
+		{
+			name:      "default tenant",
+			migration: commons.MigrationConfig{TenantField: "tenant", Tenants: []string{"shared"}, DefaultTenant: "shared"},
+			want:      []string{"keywords", "is_empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tenantFilter(tt.migration)
+			var got []string
+			for _, condition := range filter.GetShould() {
+				switch {
+				case condition.GetIsEmpty() != nil:
+					got = append(got, "is_empty")
+				case condition.GetField().GetMatch().GetKeywords() != nil:
+					got = append(got, "keywords")
+				case condition.GetField().GetMatch().GetIntegers() != nil:
+					got = append(got, "integers")
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("tenantFilter() has conditions %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantShardingMethod(t *testing.T) {
+	auto := qdrant.PtrOf(qdrant.ShardingMethod_Auto)
+	if got := tenantShardingMethod(commons.MigrationConfig{}, auto); got != auto {
+		t.Errorf("tenantShardingMethod() = %v, want the given method", got)
+	}
+	if got := tenantShardingMethod(commons.MigrationConfig{TenantField: "tenant"}, auto); got.String() != "Custom" {
+		t.Errorf("tenantShardingMethod() = %v, want Custom", got)
+	}
+}
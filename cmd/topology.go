@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// applyTopology sets the number of shards and the replication factor of a target collection to create from
+// --migration.shard-number and --migration.replication-factor. Values the request already has, like the ones
+// of a Qdrant source, are kept unless the flags are given. The write consistency factor is lowered to the
+// replication factor if needed, since Qdrant rejects higher ones.
+func applyTopology(request *qdrant.CreateCollection, migration commons.MigrationConfig) {
+	if migration.ShardNumber > 0 {
+		request.ShardNumber = qdrant.PtrOf(migration.ShardNumber)
+	}
+	if migration.ReplicationFactor > 0 {
+		request.ReplicationFactor = qdrant.PtrOf(migration.ReplicationFactor)
+	}
+	if request.ReplicationFactor != nil && request.GetWriteConsistencyFactor() > request.GetReplicationFactor() {
+		request.WriteConsistencyFactor = qdrant.PtrOf(request.GetReplicationFactor())
+	}
+
+	if request.ShardNumber != nil || request.ReplicationFactor != nil {
+		pterm.Info.Printfln("Creating collection '%s' with %s and %s", request.GetCollectionName(),
+			topologyValue(request.ShardNumber, "shard(s)"), topologyValue(request.ReplicationFactor, "replica(s) per shard"))
+	}
+}
+
+func topologyValue(value *uint32, unit string) string {
+	if value == nil || *value == 0 {
+		return "the default number of " + unit
+	}
+	return fmt.Sprintf("%d %s", *value, unit)
+}
+
+// Interval between checks whether the shards of a new collection are ready.
+var readyPollInterval = time.Second
+
+// waitForCollectionReady waits until all shard replicas of a new target collection are active, up to --migration.ready-timeout,
+// so the first batches aren't sent to shards that are still initializing, which is slow on collections with many shards.
+// The progress is shown while it waits. Once the timeout is over, the migration goes on with a warning.
+func waitForCollectionReady(ctx context.Context, client *qdrant.Client, collection string, migration commons.MigrationConfig) error {
+	if migration.ReadyTimeout <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(migration.ReadyTimeout)
+	var spinner *pterm.SpinnerPrinter
+	for {
+		info, err := client.GetCollectionsClient().CollectionClusterInfo(ctx, &qdrant.CollectionClusterInfoRequest{
+			CollectionName: collection,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get cluster info of '%s': %w", collection, err)
+		}
+
+		active, total := activeReplicas(info)
+		if active == total {
+			if spinner != nil {
+				spinner.Success(fmt.Sprintf("All %d shard replica(s) of collection '%s' are active", total, collection))
+			}
+			return nil
+		}
+
+		progress := fmt.Sprintf("Waiting for collection '%s' to be ready: %d of %d shard replica(s) active", collection, active, total)
+		if spinner == nil {
+			spinner, _ = pterm.DefaultSpinner.Start(progress)
+		} else {
+			spinner.UpdateText(progress)
+		}
+		if time.Now().After(deadline) {
+			spinner.Warning(fmt.Sprintf("Only %d of %d shard replica(s) of collection '%s' are active after %s, writing anyway", active, total, collection, migration.ReadyTimeout))
+			return nil
+		}
+
+		select {
+		case <-time.After(readyPollInterval):
+		case <-ctx.Done():
+			_ = spinner.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// activeReplicas counts the active shard replicas of a collection, on the node asked and on all others.
+func activeReplicas(info *qdrant.CollectionClusterInfoResponse) (int, int) {
+	var states []qdrant.ReplicaState
+	for _, shard := range info.GetLocalShards() {
+		states = append(states, shard.GetState())
+	}
+	for _, shard := range info.GetRemoteShards() {
+		states = append(states, shard.GetState())
+	}
+
+	active := 0
+	for _, state := range states {
+		if state == qdrant.ReplicaState_Active {
+			active++
+		}
+	}
+	return active, len(states)
+}
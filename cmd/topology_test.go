@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func TestApplyTopology(t *testing.T) {
+	tests := []struct {
+		name                   string
+		request                *qdrant.CreateCollection
+		migration              commons.MigrationConfig
+		shardNumber            uint32
+		replicationFactor      uint32
+		writeConsistencyFactor uint32
+	}{
+		{
+			name:    "defaults",
+			request: &qdrant.CreateCollection{},
+		},
+		{
+			name:                   "source values",
+			request:                &qdrant.CreateCollection{ShardNumber: qdrant.PtrOf(uint32(6)), ReplicationFactor: qdrant.PtrOf(uint32(2)), WriteConsistencyFactor: qdrant.PtrOf(uint32(2))},
+			shardNumber:            6,
+			replicationFactor:      2,
+			writeConsistencyFactor: 2,
+		},
+		{
+			name:              "flags",
+			request:           &qdrant.CreateCollection{},
+			migration:         commons.MigrationConfig{ShardNumber: 12, ReplicationFactor: 3},
+			shardNumber:       12,
+			replicationFactor: 3,
+		},
+		{
+			name:                   "flags override source values",
+			request:                &qdrant.CreateCollection{ShardNumber: qdrant.PtrOf(uint32(6)), ReplicationFactor: qdrant.PtrOf(uint32(3)), WriteConsistencyFactor: qdrant.PtrOf(uint32(3))},
+			migration:              commons.MigrationConfig{ReplicationFactor: 1},
+			shardNumber:            6,
+			replicationFactor:      1,
+			writeConsistencyFactor: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyTopology(tt.request, tt.migration)
+			if got := tt.request.GetShardNumber(); got != tt.shardNumber {
+				t.Errorf("shard number = %d, want %d", got, tt.shardNumber)
+			}
+			if got := tt.request.GetReplicationFactor(); got != tt.replicationFactor {
+				t.Errorf("replication factor = %d, want %d", got, tt.replicationFactor)
+			}
+			if got := tt.request.GetWriteConsistencyFactor(); got != tt.writeConsistencyFactor {
+				t.Errorf("write consistency factor = %d, want %d", got, tt.writeConsistencyFactor)
+			}
+		})
+	}
+}
+
+func TestActiveReplicas(t *testing.T) {
+	info := &qdrant.CollectionClusterInfoResponse{
+		LocalShards: []*qdrant.LocalShardInfo{
+			{ShardId: 0, State: qdrant.ReplicaState_Active},
+			{ShardId: 1, State: qdrant.ReplicaState_Initializing},
+		},
+		RemoteShards: []*qdrant.RemoteShardInfo{
+			{ShardId: 0, PeerId: 2, State: qdrant.ReplicaState_Active},
+			{ShardId: 1, PeerId: 2, State: qdrant.ReplicaState_Partial},
+		},
+	}
+	active, total := activeReplicas(info)
+	if active != 2 || total != 4 {
+		t.Errorf("activeReplicas() = %d of %d, want 2 of 4", active, total)
+	}
+}
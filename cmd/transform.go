@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// transformPayloads applies the payload conversions and re-embedding of the migration options to a batch of points before it's written.
+// It returns the points to write, which are more than the ones given if texts were chunked,
+// and fewer if some aren't in the sample, belong to other tenants, expired or were set aside as dead letters.
+func transformPayloads(ctx context.Context, points []*qdrant.PointStruct, migration commons.MigrationConfig) ([]*qdrant.PointStruct, error) {
+	// Before anything else, so no work is spent on points that aren't migrated.
+	points = samplePoints(points, migration)
+	points = filterTenants(points, migration)
+	points = applyExpiry(points, migration, time.Now())
+	// Before the payload is changed, so the text field is addressed as the source has it.
+	points = chunkPoints(points, migration)
+	err := embedPoints(ctx, points, migration.Embed)
+	if err != nil {
+		return nil, err
+	}
+	generateSparseVectors(points, migration.Sparse)
+	convertGeoPayloads(points, migration)
+	normalizeDatetimes(points, migration.MappingFile)
+	coercePayloadTypes(points, migration.MappingFile)
+	err = handleBinaryFields(ctx, points, migration)
+	if err != nil {
+		return nil, err
+	}
+	// After the other directives, which address fields by their names in the source.
+	renamePayloadKeys(points, migration.MappingFile)
+	// Before the payload is reshaped, so added fields with dots are nested like the others.
+	err = addPayloadFields(points, migration)
+	if err != nil {
+		return nil, err
+	}
+	reshapePayloads(points, migration)
+	sanitizePayloadKeys(points, migration)
+	trackPayloadTypes(points)
+	return guardPayloadSizes(points, migration)
+}
+
+// payloadField returns the value of a field by its path, e.g. "meta.created", or nil if the payload doesn't have it.
+// Keys that contain dots themselves, like in flat metadata, are matched as well.
+func payloadField(payload map[string]*qdrant.Value, path string) *qdrant.Value {
+	if value, ok := payload[path]; ok {
+		return value
+	}
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return nil
+	}
+	return payloadField(payload[key].GetStructValue().GetFields(), rest)
+}
+
+// setPayloadField replaces the value of an existing field by its path.
+func setPayloadField(payload map[string]*qdrant.Value, path string, value *qdrant.Value) {
+	if _, ok := payload[path]; ok {
+		payload[path] = value
+		return
+	}
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return
+	}
+	if fields := payload[key].GetStructValue().GetFields(); fields != nil {
+		setPayloadField(fields, rest, value)
+	}
+}
+
+// deletePayloadField removes a field by its path.
+func deletePayloadField(payload map[string]*qdrant.Value, path string) {
+	if _, ok := payload[path]; ok {
+		delete(payload, path)
+		return
+	}
+	key, rest, nested := strings.Cut(path, ".")
+	if !nested {
+		return
+	}
+	if fields := payload[key].GetStructValue().GetFields(); fields != nil {
+		deletePayloadField(fields, rest)
+	}
+}
@@ -3,21 +3,37 @@ package cmd
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net"
 	"net/url"
+	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"github.com/pterm/pterm"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
 )
 
 const HTTPS = "https"
 
-func connectToQdrant(globals *Globals, host string, port int, apiKey string, useTLS bool, maxMessageSize int) (*qdrant.Client, error) {
+// Qdrant Cloud clusters have hostnames under this domain, and the console of Qdrant Cloud is at the domain itself.
+const qdrantCloudDomain = "cloud.qdrant.io"
+
+// connectToQdrant connects to a Qdrant endpoint.
+// Endpoint-specific settings in config take precedence over the global ones.
+func connectToQdrant(globals *Globals, host string, port int, config commons.QdrantConfig, useTLS bool) (*qdrant.Client, error) {
 	debugLogger := logging.LoggerFunc(func(ctx context.Context, lvl logging.Level, msg string, fields ...any) {
 		pterm.Debug.Printf(msg, fields...)
 	})
@@ -37,32 +53,231 @@ func connectToQdrant(globals *Globals, host string, port int, apiKey string, use
 		grpcOptions = append(grpcOptions, grpc.WithChainStreamInterceptor(logging.StreamClientInterceptor(debugLogger, loggingOptions)))
 	}
 
-	if maxMessageSize != 0 {
+	grpcOptions = append(grpcOptions, grpc.WithChainUnaryInterceptor(permissionInterceptor(config.Url), transferInterceptor(), retryAfterInterceptor()))
+
+	dialer, err := getEndpointDialer(globals, config)
+	if err != nil {
+		return nil, err
+	}
+	if isUnixSocket(host) {
+		err = checkUnixSocket(config)
+		if err != nil {
+			return nil, err
+		}
+		dialer = unixSocketDialer(globals, host)
+	}
+	if dialer != nil {
+		grpcOptions = append(grpcOptions, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer(ctx, "tcp", addr)
+		}))
+	}
+
+	if globals.GrpcKeepaliveTime > 0 {
+		grpcOptions = append(grpcOptions, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                globals.GrpcKeepaliveTime,
+			Timeout:             globals.GrpcKeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	if globals.GrpcIdleTimeout > 0 {
+		grpcOptions = append(grpcOptions, grpc.WithIdleTimeout(globals.GrpcIdleTimeout))
+	}
+	// Before the call timeout, so that calls held back while paused don't time out.
+	grpcOptions = append(grpcOptions, grpc.WithChainUnaryInterceptor(pauseInterceptor(globals.getPauseGate())))
+	if globals.GrpcCallTimeout > 0 {
+		grpcOptions = append(grpcOptions, grpc.WithChainUnaryInterceptor(callTimeoutInterceptor(globals.GrpcCallTimeout)))
+	}
+
+	readConsistency, err := getReadConsistency(config)
+	if err != nil {
+		return nil, err
+	}
+	if readConsistency != nil {
+		grpcOptions = append(grpcOptions, grpc.WithChainUnaryInterceptor(readConsistencyInterceptor(readConsistency)))
+	}
+
+	if writeOrdering := getWriteOrdering(config); writeOrdering != nil {
+		grpcOptions = append(grpcOptions, grpc.WithChainUnaryInterceptor(writeOrderingInterceptor(writeOrdering)))
+	}
+
+	if config.MaxMessageSize != 0 {
+		grpcOptions = append(grpcOptions, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(config.MaxMessageSize),
+		))
+	}
+	sendMessageSize := maxSendMessageSize(config)
+	if sendMessageSize != 0 {
 		grpcOptions = append(grpcOptions, grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(maxMessageSize),
+			grpc.MaxCallSendMsgSize(sendMessageSize),
 		))
 	}
 
-	tlsConfig := tls.Config{
-		InsecureSkipVerify: globals.SkipTlsVerification,
+	tlsConfig, err := getTLSConfig(globals, config, useTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if isQdrantCloudHost(host) && config.APIKey == "" {
+		return nil, fmt.Errorf("%s is a Qdrant Cloud cluster, which requires an API key: pass one of its database API keys", host)
+	}
+
+	address := grpcHost(host)
+	if isUnixSocket(host) {
+		// The dialer connects to the socket, the address is only the authority of the calls.
+		address = "localhost"
+	}
+	if len(config.Endpoints) > 0 || config.ResolveNodes || isSrvUrl(config.Url) {
+		var endpointOptions []grpc.DialOption
+		address, endpointOptions, err = spreadOverEndpoints(globals, host, port, config, useTLS, dialer)
+		if err != nil {
+			return nil, err
+		}
+		grpcOptions = append(grpcOptions, endpointOptions...)
 	}
 
 	client, err := qdrant.NewClient(&qdrant.Config{
-		Host:                   host,
+		Host:                   address,
 		Port:                   port,
-		APIKey:                 apiKey,
+		APIKey:                 config.APIKey,
 		UseTLS:                 useTLS,
-		TLSConfig:              &tlsConfig,
+		TLSConfig:              tlsConfig,
 		GrpcOptions:            grpcOptions,
 		SkipCompatibilityCheck: true,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
+	globals.trackClient(client)
+	if sendMessageSize != 0 {
+		clientMessageSizes.Store(client, sendMessageSize)
+	}
+	clientRestClients.Store(client, func() (*qdrantRestClient, error) {
+		if isUnixSocket(host) {
+			return nil, fmt.Errorf("the REST endpoint of the unix domain socket %s isn't known", host)
+		}
+		restUrl, err := getQdrantRestUrl(globals.baseContext(), "", host, port, useTLS)
+		if err != nil {
+			return nil, err
+		}
+		return newQdrantRestClient(globals, restUrl, config)
+	})
 
 	return client, nil
 }
 
+// getTLSConfig builds the TLS settings for a Qdrant connection from its custom CA and client certificate, if any.
+func getTLSConfig(globals *Globals, config commons.QdrantConfig, useTLS bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: globals.SkipTlsVerification || config.SkipTlsVerification,
+	}
+
+	if !useTLS {
+		if config.CACert != "" || config.ClientCert != "" {
+			return nil, fmt.Errorf("TLS certificates were given for %s, but the URL doesn't use https", config.Url)
+		}
+		return tlsConfig, nil
+	}
+
+	if config.CACert != "" {
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", config.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.ClientCert != "" || config.ClientKey != "" {
+		if config.ClientCert == "" || config.ClientKey == "" {
+			return nil, fmt.Errorf("both a client certificate and a client key are required for mutual TLS")
+		}
+		certificate, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// getEndpointProxy returns the proxy of an endpoint, falling back to the global one.
+func getEndpointProxy(globals *Globals, config commons.QdrantConfig) string {
+	if config.Proxy != "" {
+		return config.Proxy
+	}
+	return globals.Proxy
+}
+
+// getEndpointDialer returns the dialer for connections to a Qdrant endpoint, or nil to use the default one.
+// A custom dialer is needed to go through a proxy or to limit the bandwidth.
+func getEndpointDialer(globals *Globals, config commons.QdrantConfig) (contextDialer, error) {
+	limiter := globals.getBandwidthLimiter()
+
+	// With a custom dialer, gRPC and net/http no longer honor HTTPS_PROXY, so it has to be handled here.
+	dialer, err := getProxyDialer(getEndpointProxy(globals, config), limiter != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if limiter != nil {
+		if dialer == nil {
+			dialer = (&net.Dialer{}).DialContext
+		}
+		dialer = limitDialer(dialer, limiter)
+	}
+
+	return dialer, nil
+}
+
+// callTimeoutInterceptor sets a deadline on every unary call that doesn't have a shorter one already,
+// so calls on silently dropped connections fail instead of hanging forever.
+func callTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// progressMethods are the calls to Qdrant that read or write points, which are progress for the stall watchdog.
+// Other calls, like the collection info polled by health checks, keep completing while the migration hangs.
+var progressMethods = map[string]bool{
+	qdrant.Points_Scroll_FullMethodName:        true,
+	qdrant.Points_Get_FullMethodName:           true,
+	qdrant.Points_Upsert_FullMethodName:        true,
+	qdrant.Points_UpdateBatch_FullMethodName:   true,
+	qdrant.Points_UpdateVectors_FullMethodName: true,
+	qdrant.Points_SetPayload_FullMethodName:    true,
+}
+
+// transferInterceptor accounts for the sizes of the messages of the calls to Qdrant in the report of the run.
+// Every completed call that reads or writes points is progress for the stall watchdog.
+func transferInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err != nil {
+			return err
+		}
+
+		var sent, received uint64
+		if message, ok := req.(proto.Message); ok {
+			sent = uint64(proto.Size(message))
+		}
+		if message, ok := reply.(proto.Message); ok {
+			received = uint64(proto.Size(message))
+		}
+		currentReport.addTransfer(sent, received)
+		if progressMethods[method] {
+			currentWatchdog.touch()
+		}
+		return nil
+	}
+}
+
 func getPort(u *url.URL) (int, error) {
 	if u.Port() != "" {
 		sourcePort, err := strconv.Atoi(u.Port())
@@ -77,22 +292,105 @@ func getPort(u *url.URL) (int, error) {
 	return 80, nil
 }
 
+// parseQdrantUrl returns the host, port and whether to use TLS of a Qdrant gRPC URL. A URL without a scheme,
+// like localhost:6334, is taken as http. Hosts can be IPv6 literals in brackets, e.g. http://[::1]:6334.
+// URLs with credentials, paths or other schemes are rejected, since gRPC endpoints are addressed by host and port only.
+// For a unix domain socket, the host is the path of the socket, and for a URL resolved by DNS SRV records,
+// it's the name to look up; the port is 0 for both.
 func parseQdrantUrl(urlStr string) (host string, port int, tls bool, err error) {
+	if strings.HasPrefix(strings.ToLower(urlStr), unixScheme+":") {
+		host, err = parseUnixSocketUrl(urlStr)
+		return host, 0, false, err
+	}
+	// Without a scheme, the host would be parsed as the scheme, e.g. the localhost of localhost:6334.
+	if !strings.Contains(urlStr, "://") {
+		urlStr = "http://" + urlStr
+	}
 	parsedUrl, err := url.Parse(urlStr)
 	if err != nil {
 		return "", 0, false, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
+	switch {
+	case !slices.Contains([]string{"http", HTTPS, srvScheme, srvTLSScheme}, parsedUrl.Scheme):
+		return "", 0, false, fmt.Errorf("%s has the scheme %s, but Qdrant URLs need http or https, %s or %s for DNS SRV records, or %s for unix domain sockets",
+			parsedUrl.Redacted(), parsedUrl.Scheme, srvScheme, srvTLSScheme, unixScheme)
+	case parsedUrl.User != nil:
+		return "", 0, false, fmt.Errorf("%s contains credentials, which aren't used: pass the API key with its own option instead", parsedUrl.Redacted())
+	case parsedUrl.Hostname() == "":
+		return "", 0, false, fmt.Errorf("%s has no host", urlStr)
+	case strings.Contains(parsedUrl.Hostname(), ":") && !strings.HasPrefix(parsedUrl.Host, "["):
+		return "", 0, false, fmt.Errorf("%s has an IPv6 address that isn't in brackets, which makes its port ambiguous, e.g. use http://[::1]:6334", urlStr)
+	}
+
 	host = parsedUrl.Hostname()
+	if strings.EqualFold(host, qdrantCloudDomain) {
+		return "", 0, false, fmt.Errorf("%s is the Qdrant Cloud console, not a cluster: use the URL of the cluster from its overview page, e.g. https://xyz-example.eu-central.aws.cloud.qdrant.io:6334", urlStr)
+	}
+	if isQdrantCloudHost(host) {
+		port, message := qdrantCloudPort(parsedUrl)
+		if message != "" {
+			pterm.Warning.Println(message)
+		}
+		return host, port, true, nil
+	}
+
+	if strings.Trim(parsedUrl.Path, "/") != "" || parsedUrl.RawQuery != "" || parsedUrl.Fragment != "" {
+		return "", 0, false, fmt.Errorf("%s has a path, but Qdrant gRPC endpoints are addressed by host and port only, e.g. %s://%s", urlStr, parsedUrl.Scheme, parsedUrl.Host)
+	}
+
+	if isSrvUrl(urlStr) {
+		if parsedUrl.Port() != "" {
+			return "", 0, false, fmt.Errorf("%s has a port, but the SRV records of %s give the ports of the nodes", urlStr, host)
+		}
+		return host, 0, parsedUrl.Scheme == srvTLSScheme, nil
+	}
+
 	tls = parsedUrl.Scheme == HTTPS
 	port, err = getPort(parsedUrl)
 	if err != nil {
 		return "", 0, false, fmt.Errorf("failed to parse port: %w", err)
 	}
+	if port < 1 || port > 65535 {
+		return "", 0, false, fmt.Errorf("port %d of %s is out of range", port, urlStr)
+	}
 
 	return host, port, tls, nil
 }
 
+// grpcHost returns a host as gRPC takes it, with IPv6 literals in brackets.
+func grpcHost(host string) string {
+	if strings.Contains(host, ":") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+func isQdrantCloudHost(host string) bool {
+	return strings.HasSuffix(strings.ToLower(host), "."+qdrantCloudDomain)
+}
+
+// qdrantCloudPort returns the gRPC port of a Qdrant Cloud cluster URL. Clusters only accept TLS, on 6333 for REST and 6334 for gRPC.
+// A URL without a port, with the REST port or of the dashboard is taken to mean the gRPC endpoint, with a message saying so.
+func qdrantCloudPort(u *url.URL) (int, string) {
+	grpcUrl := fmt.Sprintf("https://%s:%d", u.Hostname(), defaultQdrantGrpcPort)
+	switch {
+	case strings.HasPrefix(strings.TrimPrefix(u.Path, "/"), "dashboard"):
+		return defaultQdrantGrpcPort, fmt.Sprintf("%s is the dashboard of a Qdrant Cloud cluster, connecting to its gRPC endpoint %s instead", u.Redacted(), grpcUrl)
+	case u.Port() == strconv.Itoa(defaultQdrantRestPort):
+		return defaultQdrantGrpcPort, fmt.Sprintf("Port %d is the REST port of Qdrant Cloud clusters, connecting to the gRPC endpoint %s instead", defaultQdrantRestPort, grpcUrl)
+	case u.Scheme != HTTPS:
+		return defaultQdrantGrpcPort, fmt.Sprintf("Qdrant Cloud clusters only accept TLS, connecting to %s instead", grpcUrl)
+	case u.Port() == "" || u.Port() == "443":
+		return defaultQdrantGrpcPort, ""
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return defaultQdrantGrpcPort, ""
+	}
+	return port, ""
+}
+
 func validateBatchSize(batchSize int) error {
 	if batchSize < 1 {
 		return fmt.Errorf("batch size must be greater than 0")
@@ -102,9 +400,19 @@ func validateBatchSize(batchSize int) error {
 
 func displayMigrationStart(sourceProvider, sourceCollection, targetCollection string) {
 	pterm.DefaultSection.Println("Starting Migration To Qdrant")
+	displayMigrationRoute(sourceProvider, sourceCollection, "qdrant", targetCollection)
+}
+
+func displayMigrationFromQdrantStart(sourceCollection, targetProvider, targetCollection string) {
+	pterm.DefaultSection.Println("Starting Migration From Qdrant")
+	displayMigrationRoute("qdrant", sourceCollection, targetProvider, targetCollection)
+}
+
+func displayMigrationRoute(sourceProvider, sourceCollection, targetProvider, targetCollection string) {
+	currentReport.setRoute(sourceProvider, sourceCollection, targetProvider, targetCollection)
 
 	from := fmt.Sprintf("%s@%s", sourceCollection, sourceProvider)
-	to := fmt.Sprintf("%s@qdrant", targetCollection)
+	to := fmt.Sprintf("%s@%s", targetCollection, targetProvider)
 
 	table := pterm.TableData{
 		{pterm.FgLightCyan.Sprint("From → To:"), pterm.FgLightGreen.Sprintf("%s  →  %s", from, to)},
@@ -139,3 +447,64 @@ func arbitraryIDToUUID(id string) *qdrant.PointId {
 	deterministicUUID := uuid.NewSHA1(uuid.NameSpaceURL, []byte(id))
 	return qdrant.NewIDUUID(deterministicUUID.String())
 }
+
+// targetCollectionKey identifies a target collection on one of the targets, to cache things about it.
+type targetCollectionKey struct {
+	client     *qdrant.Client
+	collection string
+}
+
+// targetInfos caches the information of target collections that doesn't change while they're written, like their
+// vectors and strict mode, by targetCollectionKey.
+var targetInfos sync.Map
+
+// targetCollectionInfo returns the information of a target collection, as it was when it was first asked for.
+func targetCollectionInfo(ctx context.Context, client *qdrant.Client, collection string) (*qdrant.CollectionInfo, error) {
+	key := targetCollectionKey{client: client, collection: collection}
+	if cached, ok := targetInfos.Load(key); ok {
+		return cached.(*qdrant.CollectionInfo), nil
+	}
+
+	info, err := client.GetCollectionInfo(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target collection information: %w", err)
+	}
+	targetInfos.Store(key, info)
+	return info, nil
+}
+
+// flushTarget waits until all upserts sent with wait=false have been applied to the target collection.
+// Updates are applied in order per shard, so a no-op update with wait=true that reaches every shard acts as a barrier.
+// It then warns if the target has fewer points than the source.
+func flushTarget(ctx context.Context, client *qdrant.Client, collection string, sourcePointCount uint64) error {
+	pterm.Info.Printfln("Waiting for pending upserts to be applied")
+
+	// A filter that matches no point, so the update is sent to all shards without changing anything.
+	marker := qdrant.NewID(uuid.Nil.String())
+	_, err := client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: collection,
+		Payload:        qdrant.NewValueMap(map[string]any{"_migration_flush": true}),
+		PointsSelector: qdrant.NewPointsSelectorFilter(&qdrant.Filter{
+			Must:    []*qdrant.Condition{qdrant.NewHasID(marker)},
+			MustNot: []*qdrant.Condition{qdrant.NewHasID(marker)},
+		}),
+		Wait: qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for pending upserts: %w", err)
+	}
+
+	targetPointCount, err := client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: collection,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count points in target: %w", err)
+	}
+
+	if targetPointCount < sourcePointCount {
+		pterm.Warning.Printfln("Target collection has %d points, but the source has %d", targetPointCount, sourcePointCount)
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/pterm/pterm"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// Anomalies of vectors, as they're reported.
+const (
+	anomalyNaN        = "NaN values"
+	anomalyInf        = "infinite values"
+	anomalyDimensions = "wrong dimensions"
+)
+
+// Vectors that were already warned about, by name and anomaly, so every kind of anomaly is only reported once per vector.
+var vectorAnomalyWarned sync.Map
+
+// validateVectors applies --migration.invalid-vector-policy to the points of an upsert whose vectors have NaN or infinite values,
+// or not the dimensions of the target collection, which Qdrant would otherwise reject with the whole batch.
+// Runs into a sink have no target collection, so only the values of their vectors are checked.
+func validateVectors(ctx context.Context, client *qdrant.Client, request *qdrant.UpsertPoints, migration commons.MigrationConfig) error {
+	if migration.PayloadOnly {
+		return nil
+	}
+	var dimensions map[string]uint64
+	if !isSinkTarget(migration) {
+		var err error
+		dimensions, err = collectionDimensions(ctx, client, request.GetCollectionName())
+		if err != nil {
+			return err
+		}
+	}
+	points, err := guardVectors(request.GetPoints(), dimensions, migration)
+	if err != nil {
+		return err
+	}
+	request.Points = points
+	return nil
+}
+
+// collectionDimensions returns the dimensions of the dense vectors of a collection, by name. The unnamed vector has an empty name.
+func collectionDimensions(ctx context.Context, client *qdrant.Client, collection string) (map[string]uint64, error) {
+	info, err := targetCollectionInfo(ctx, client, collection)
+	if err != nil {
+		return nil, err
+	}
+	dimensions := make(map[string]uint64)
+	config := info.GetConfig().GetParams().GetVectorsConfig()
+	if params := config.GetParams(); params != nil {
+		dimensions[""] = params.GetSize()
+	}
+	for name, params := range config.GetParamsMap().GetMap() {
+		dimensions[name] = params.GetSize()
+	}
+	return dimensions, nil
+}
+
+// guardVectors returns the points to write, after applying --migration.invalid-vector-policy to the ones with invalid vectors.
+// Vectors missing from dimensions, like sparse ones, are only checked for NaN and infinite values.
+func guardVectors(points []*qdrant.PointStruct, dimensions map[string]uint64, migration commons.MigrationConfig) ([]*qdrant.PointStruct, error) {
+	counts := make(map[string]uint64)
+	var deadLettered uint64
+	result := points[:0:0]
+	for _, point := range points {
+		var problems []string
+		for name, vector := range pointVectors(point) {
+			for _, anomaly := range vectorAnomalies(vector, dimensions[name]) {
+				counts[anomaly]++
+				warnVectorAnomaly(name, anomaly, migration.InvalidVectorPolicy)
+				problems = append(problems, fmt.Sprintf("%s in vector '%s'", anomaly, name))
+				if migration.InvalidVectorPolicy == "zero-fill" {
+					zeroFillVector(vector, dimensions[name])
+				}
+			}
+		}
+		if len(problems) == 0 || migration.InvalidVectorPolicy == "zero-fill" {
+			result = append(result, point)
+			continue
+		}
+
+		reason := strings.Join(problems, ", ")
+		if migration.InvalidVectorPolicy != "dead-letter" {
+			currentReport.addVectorAnomalies(counts, deadLettered)
+			return nil, fmt.Errorf("point %s has %s. Set --migration.invalid-vector-policy to dead-letter or zero-fill to migrate the other points", pointIDToString(point.GetId()), reason)
+		}
+		err := writeDeadLetter(migration.DeadLetterFile, point, reason)
+		if err != nil {
+			return nil, err
+		}
+		deadLettered++
+	}
+
+	currentReport.addVectorAnomalies(counts, deadLettered)
+	return result, nil
+}
+
+// pointVectors returns the vectors of a point, by name. The unnamed vector has an empty name.
+func pointVectors(point *qdrant.PointStruct) map[string]*qdrant.Vector {
+	if vector := point.GetVectors().GetVector(); vector != nil {
+		return map[string]*qdrant.Vector{"": vector}
+	}
+	return point.GetVectors().GetVectors().GetVectors()
+}
+
+// vectorAnomalies returns the anomalies of a vector. dimensions is the size of the dense vector in the target, 0 if it isn't known.
+func vectorAnomalies(vector *qdrant.Vector, dimensions uint64) []string {
+	var anomalies []string
+	var hasNaN, hasInf bool
+	for _, value := range vectorValues(vector) {
+		v := float64(value)
+		hasNaN = hasNaN || math.IsNaN(v)
+		hasInf = hasInf || math.IsInf(v, 0)
+	}
+	if hasNaN {
+		anomalies = append(anomalies, anomalyNaN)
+	}
+	if hasInf {
+		anomalies = append(anomalies, anomalyInf)
+	}
+	if dimensions > 0 && !hasDimensions(vector, dimensions) {
+		anomalies = append(anomalies, anomalyDimensions)
+	}
+	return anomalies
+}
+
+func vectorValues(vector *qdrant.Vector) []float32 {
+	if dense := vector.GetDense(); dense != nil {
+		return dense.GetData()
+	}
+	if sparse := vector.GetSparse(); sparse != nil {
+		return sparse.GetValues()
+	}
+	if multi := vector.GetMultiDense(); multi != nil {
+		var values []float32
+		for _, dense := range multi.GetVectors() {
+			values = append(values, dense.GetData()...)
+		}
+		return values
+	}
+	return vector.GetData()
+}
+
+// hasDimensions reports whether a dense or multi vector has the given dimensions. Sparse vectors and inference objects have any.
+func hasDimensions(vector *qdrant.Vector, dimensions uint64) bool {
+	switch {
+	case vector.GetDense() != nil:
+		return uint64(len(vector.GetDense().GetData())) == dimensions
+	case vector.GetMultiDense() != nil:
+		for _, dense := range vector.GetMultiDense().GetVectors() {
+			if uint64(len(dense.GetData())) != dimensions {
+				return false
+			}
+		}
+		return true
+	case vector.GetSparse() != nil, vector.GetIndices() != nil, vector.GetVector() != nil:
+		return true
+	case vector.GetVectorsCount() > 0:
+		return uint64(len(vector.GetData())) == dimensions*uint64(vector.GetVectorsCount())
+	default:
+		return uint64(len(vector.GetData())) == dimensions
+	}
+}
+
+// zeroFillVector replaces the NaN and infinite values of a vector with 0. Dense vectors are padded with zeros or truncated to the dimensions, if given.
+func zeroFillVector(vector *qdrant.Vector, dimensions uint64) {
+	for _, values := range [][]float32{vector.GetData(), vector.GetDense().GetData(), vector.GetSparse().GetValues()} {
+		zeroFillValues(values)
+	}
+	for _, dense := range vector.GetMultiDense().GetVectors() {
+		zeroFillValues(dense.GetData())
+		dense.Data = resizeVector(dense.GetData(), dimensions)
+	}
+	switch {
+	case vector.GetDense() != nil:
+		vector.GetDense().Data = resizeVector(vector.GetDense().GetData(), dimensions)
+	case vector.GetIndices() == nil && vector.GetVectorsCount() == 0 && vector.GetVector() == nil:
+		vector.Data = resizeVector(vector.GetData(), dimensions)
+	}
+}
+
+func zeroFillValues(values []float32) {
+	for i, value := range values {
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			values[i] = 0
+		}
+	}
+}
+
+func resizeVector(values []float32, dimensions uint64) []float32 {
+	if dimensions == 0 || uint64(len(values)) == dimensions {
+		return values
+	}
+	if uint64(len(values)) > dimensions {
+		return values[:dimensions]
+	}
+	return append(values, make([]float32, dimensions-uint64(len(values)))...)
+}
+
+func warnVectorAnomaly(name, anomaly, policy string) {
+	if _, warned := vectorAnomalyWarned.LoadOrStore(name+"/"+anomaly, true); warned {
+		return
+	}
+	pterm.Warning.Printfln("Vector '%s' has %s, applying the %s policy. Further ones aren't reported, but counted in the run report.", name, anomaly, policy)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+func Test_vectorAnomalies(t *testing.T) {
+	nan := float32(math.NaN())
+	inf := float32(math.Inf(1))
+	tests := []struct {
+		name       string
+		vector     *qdrant.Vector
+		dimensions uint64
+		expected   []string
+	}{
+		{name: "valid", vector: qdrant.NewVector(1, 2, 3), dimensions: 3},
+		{name: "NaN", vector: qdrant.NewVector(1, nan, 3), dimensions: 3, expected: []string{anomalyNaN}},
+		{name: "infinite and short", vector: qdrant.NewVector(inf, 2), dimensions: 3, expected: []string{anomalyInf, anomalyDimensions}},
+		{name: "unknown dimensions", vector: qdrant.NewVector(1, 2), dimensions: 0},
+		{name: "sparse", vector: qdrant.NewVectorSparse([]uint32{1, 7}, []float32{nan, 1}), dimensions: 3, expected: []string{anomalyNaN}},
+		{name: "multi", vector: qdrant.NewVectorMulti([][]float32{{1, 2}, {3, 4}}), dimensions: 2},
+		{name: "multi wrong dimensions", vector: qdrant.NewVectorMulti([][]float32{{1, 2}, {3, 4}}), dimensions: 3, expected: []string{anomalyDimensions}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vectorAnomalies(tt.vector, tt.dimensions); !slices.Equal(got, tt.expected) {
+				t.Errorf("vectorAnomalies() got = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func Test_guardVectors(t *testing.T) {
+	dimensions := map[string]uint64{"dense": 3}
+	newPoints := func() []*qdrant.PointStruct {
+		return []*qdrant.PointStruct{
+			{Id: qdrant.NewIDNum(1), Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{"dense": qdrant.NewVector(1, float32(math.NaN()))})},
+			{Id: qdrant.NewIDNum(2), Vectors: qdrant.NewVectorsMap(map[string]*qdrant.Vector{"dense": qdrant.NewVector(1, 2, 3)})},
+		}
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		_, err := guardVectors(newPoints(), dimensions, commons.MigrationConfig{InvalidVectorPolicy: "abort"})
+		if err == nil || !strings.Contains(err.Error(), "point 1 has NaN values in vector 'dense', wrong dimensions in vector 'dense'") {
+			t.Errorf("got error %v, want one naming point 1 and its anomalies", err)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+		points, err := guardVectors(newPoints(), dimensions, commons.MigrationConfig{InvalidVectorPolicy: "dead-letter", DeadLetterFile: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 1 || points[0].Id.GetNum() != 2 {
+			t.Fatalf("got %v, want only point 2", points)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Count(string(data), "\n"); lines != 1 {
+			t.Errorf("dead letter file has %d lines, want 1", lines)
+		}
+	})
+
+	t.Run("zero fill", func(t *testing.T) {
+		points, err := guardVectors(newPoints(), dimensions, commons.MigrationConfig{InvalidVectorPolicy: "zero-fill"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 2 {
+			t.Fatalf("got %d points, want 2", len(points))
+		}
+		got := points[0].Vectors.GetVectors().GetVectors()["dense"].GetData()
+		if !slices.Equal(got, []float32{1, 0, 0}) {
+			t.Errorf("vector = %v, want [1 0 0]", got)
+		}
+	})
+}
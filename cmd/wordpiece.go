@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Longest word that's split into word pieces, longer ones become the unknown token, like in BERT.
+const maxWordPieceChars = 100
+
+// wordPieceTokenizer is the BERT tokenizer of the models of the local embedding provider, read from their tokenizer.json.
+type wordPieceTokenizer struct {
+	vocab       map[string]int64
+	unkID       int64
+	clsID       int64
+	sepID       int64
+	padID       int64
+	prefix      string
+	lowercase   bool
+	stripAccent bool
+	maxLength   int
+}
+
+type tokenizerFile struct {
+	Normalizer *struct {
+		Lowercase    *bool `json:"lowercase"`
+		StripAccents *bool `json:"strip_accents"`
+	} `json:"normalizer"`
+	Truncation *struct {
+		MaxLength int `json:"max_length"`
+	} `json:"truncation"`
+	Model struct {
+		Type                    string           `json:"type"`
+		UnkToken                string           `json:"unk_token"`
+		ContinuingSubwordPrefix string           `json:"continuing_subword_prefix"`
+		Vocab                   map[string]int64 `json:"vocab"`
+	} `json:"model"`
+}
+
+func loadWordPieceTokenizer(path string) (*wordPieceTokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokenizer: %w", err)
+	}
+	var file tokenizerFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tokenizer: %w", err)
+	}
+	if file.Model.Type != "WordPiece" {
+		return nil, fmt.Errorf("unsupported tokenizer model %q, only WordPiece is supported", file.Model.Type)
+	}
+
+	t := &wordPieceTokenizer{
+		vocab:       file.Model.Vocab,
+		prefix:      file.Model.ContinuingSubwordPrefix,
+		lowercase:   true,
+		stripAccent: true,
+		maxLength:   512,
+	}
+	if file.Normalizer != nil {
+		if file.Normalizer.Lowercase != nil {
+			t.lowercase = *file.Normalizer.Lowercase
+		}
+		// Like in BERT, accents are stripped together with lowercasing unless it's set explicitly.
+		t.stripAccent = t.lowercase
+		if file.Normalizer.StripAccents != nil {
+			t.stripAccent = *file.Normalizer.StripAccents
+		}
+	}
+	if file.Truncation != nil && file.Truncation.MaxLength > 0 {
+		t.maxLength = file.Truncation.MaxLength
+	}
+
+	for token, id := range map[string]*int64{file.Model.UnkToken: &t.unkID, "[CLS]": &t.clsID, "[SEP]": &t.sepID, "[PAD]": &t.padID} {
+		tokenID, ok := t.vocab[token]
+		if !ok {
+			return nil, fmt.Errorf("tokenizer has no %s token", token)
+		}
+		*id = tokenID
+	}
+
+	return t, nil
+}
+
+// encode returns the token IDs of a text, with the classification and separator tokens, truncated to the maximum length.
+func (t *wordPieceTokenizer) encode(text string) []int64 {
+	ids := []int64{t.clsID}
+	for _, word := range t.words(text) {
+		ids = append(ids, t.wordPieces(word)...)
+	}
+	if len(ids) > t.maxLength-1 {
+		ids = ids[:t.maxLength-1]
+	}
+	return append(ids, t.sepID)
+}
+
+// words normalizes a text and splits it on whitespace and punctuation. Chinese, Japanese and Korean characters are words of their own.
+func (t *wordPieceTokenizer) words(text string) []string {
+	if t.lowercase {
+		text = strings.ToLower(text)
+	}
+	if t.stripAccent {
+		var stripped strings.Builder
+		for _, r := range norm.NFD.String(text) {
+			if !unicode.Is(unicode.Mn, r) {
+				stripped.WriteRune(r)
+			}
+		}
+		text = stripped.String()
+	}
+
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range text {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+		case unicode.IsSpace(r):
+			flush()
+		case isBertPunctuation(r) || unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hangul, r) || unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			flush()
+			words = append(words, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// wordPieces splits a word into the longest pieces in the vocabulary, from left to right.
+func (t *wordPieceTokenizer) wordPieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordPieceChars {
+		return []int64{t.unkID}
+	}
+
+	var ids []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var id int64
+		found := false
+		for ; end > start; end-- {
+			piece := string(runes[start:end])
+			if start > 0 {
+				piece = t.prefix + piece
+			}
+			if id, found = t.vocab[piece]; found {
+				break
+			}
+		}
+		if !found {
+			return []int64{t.unkID}
+		}
+		ids = append(ids, id)
+		start = end
+	}
+	return ids
+}
+
+// isBertPunctuation treats all non-alphanumeric ASCII characters as punctuation, like BERT does, e.g. "$" and "^".
+func isBertPunctuation(r rune) bool {
+	if (r >= 33 && r <= 47) || (r >= 58 && r <= 64) || (r >= 91 && r <= 96) || (r >= 123 && r <= 126) {
+		return true
+	}
+	return unicode.IsPunct(r)
+}
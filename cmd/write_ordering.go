@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/qdrant/go-client/qdrant"
+
+	"github.com/qdrant/migration/pkg/commons"
+)
+
+// getWriteOrdering returns the write ordering configured for an endpoint, or nil for the default weak ordering.
+func getWriteOrdering(config commons.QdrantConfig) *qdrant.WriteOrdering {
+	switch config.WriteOrdering {
+	case "medium":
+		return &qdrant.WriteOrdering{Type: qdrant.WriteOrderingType_Medium}
+	case "strong":
+		return &qdrant.WriteOrdering{Type: qdrant.WriteOrderingType_Strong}
+	default:
+		return nil
+	}
+}
+
+// writeOrderingInterceptor sets the ordering of every upsert and delete of points that doesn't have one.
+func writeOrderingInterceptor(ordering *qdrant.WriteOrdering) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		switch request := req.(type) {
+		case *qdrant.UpsertPoints:
+			if request.Ordering == nil {
+				request.Ordering = ordering
+			}
+		case *qdrant.UpdateBatchPoints:
+			if request.Ordering == nil {
+				request.Ordering = ordering
+			}
+		case *qdrant.DeletePoints:
+			if request.Ordering == nil {
+				request.Ordering = ordering
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
@@ -67,3 +67,81 @@ teardown() {
   [[ "$output" == *'"count":10'* ]]
 
 }
+
+@test "Migrate Milvus partitions into a payload field" {
+  run curl --request POST \
+  --url "http://localhost:19530/v2/vectordb/collections/create" \
+  --header "Content-Type: application/json" \
+  -d '{
+      "collectionName": "partitioned_collection",
+      "dimension": 5
+  }'
+  [ "$status" -eq 0 ]
+
+  for partition in tenant_a tenant_b; do
+    run curl --request POST \
+    --url "http://localhost:19530/v2/vectordb/partitions/create" \
+    --header "Content-Type: application/json" \
+    -d "{
+        \"collectionName\": \"partitioned_collection\",
+        \"partitionName\": \"$partition\"
+    }"
+    [ "$status" -eq 0 ]
+  done
+
+  run curl --request POST \
+  --url "http://localhost:19530/v2/vectordb/entities/insert" \
+  --header "Content-Type: application/json" \
+  -d '{
+      "data": [
+        {"id": 0, "vector": [0.358, -0.602, 0.184, -0.263, 0.903], "color": "pink_8682"},
+        {"id": 1, "vector": [0.199, 0.060, 0.698, 0.261, 0.839], "color": "red_7025"},
+        {"id": 2, "vector": [0.437, -0.560, 0.646, 0.789, 0.208], "color": "orange_6781"}
+      ],
+      "collectionName": "partitioned_collection",
+      "partitionName": "tenant_a"
+  }'
+  [ "$status" -eq 0 ]
+
+  run curl --request POST \
+  --url "http://localhost:19530/v2/vectordb/entities/insert" \
+  --header "Content-Type: application/json" \
+  -d '{
+      "data": [
+        {"id": 3, "vector": [0.317, 0.972, -0.370, -0.486, 0.958], "color": "pink_9298"},
+        {"id": 4, "vector": [0.445, -0.876, 0.822, 0.464, 0.303], "color": "red_4794"}
+      ],
+      "collectionName": "partitioned_collection",
+      "partitionName": "tenant_b"
+  }'
+  [ "$status" -eq 0 ]
+
+  run curl --request POST \
+  --url "http://localhost:19530/v2/vectordb/collections/load" \
+  --header "Content-Type: application/json" \
+  -d '{
+      "collectionName": "partitioned_collection"
+  }'
+  [ "$status" -eq 0 ]
+
+  echo "Wait for a few seconds to load the vectors"
+  sleep 5
+
+  run docker run --net=host --rm $IMAGE_REF milvus \
+    --milvus.url 'http://localhost:19530' \
+    --milvus.collection 'partitioned_collection' \
+    --milvus.partitions 'tenant_a,tenant_b' \
+    --milvus.partition-field 'tenant' \
+    --qdrant.url 'http://localhost:6334' \
+    --qdrant.collection 'partitioned-collection' \
+    --migration.create-collection \
+    --migration.batch-size 10
+  [ "$status" -eq 0 ]
+
+  run curl --silent --show-error --fail --request POST \
+    --url "http://localhost:6333/collections/partitioned-collection/points/count" \
+    --header 'Content-Type: application/json' \
+    --data '{"exact": true, "filter": {"must": [{"key": "tenant", "match": {"value": "tenant_a"}}]}}'
+  [ "$status" -eq 0 ]
+  [[ "$output" == *'"count":3'* ]]
+}
@@ -0,0 +1,83 @@
+package commons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that can be parsed from flags like "512KB", "256MiB" or "1GB".
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	// Longer suffixes first, so "MiB" is not matched as "B".
+	{"KIB", 1 << 10},
+	{"MIB", 1 << 20},
+	{"GIB", 1 << 30},
+	{"TIB", 1 << 40},
+	{"KB", 1000},
+	{"MB", 1000 * 1000},
+	{"GB", 1000 * 1000 * 1000},
+	{"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+func ParseByteSize(s string) (ByteSize, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	return ByteSize(number * float64(multiplier)), nil
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+func (b ByteSize) String() string {
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(b)/(1<<30))
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(b)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", int64(b))
+	}
+}
+
+// Bandwidth is a number of bytes per second that can be parsed from flags like "50MB/s" or "1GiB/s".
+type Bandwidth int64
+
+func (b *Bandwidth) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(strings.TrimSuffix(strings.TrimSpace(string(text)), "/s"))
+	if err != nil {
+		return fmt.Errorf("invalid bandwidth %q", text)
+	}
+	*b = Bandwidth(size)
+	return nil
+}
+
+func (b Bandwidth) String() string {
+	return ByteSize(b).String() + "/s"
+}
@@ -0,0 +1,43 @@
+package commons
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GeoPointFields combines two numeric payload fields, e.g. the latitude and longitude columns of a SQL table,
+// into one geo point field. It's parsed from flags like "location=lat:lon".
+type GeoPointFields struct {
+	Field string
+	Lat   string
+	Lon   string
+}
+
+func ParseGeoPointFields(s string) (GeoPointFields, error) {
+	field, pair, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return GeoPointFields{}, fmt.Errorf("invalid geo point %q, expected e.g. location=lat:lon", s)
+	}
+	lat, lon, ok := strings.Cut(pair, ":")
+	if !ok || field == "" || lat == "" || lon == "" {
+		return GeoPointFields{}, fmt.Errorf("invalid geo point %q, expected e.g. location=lat:lon", s)
+	}
+	return GeoPointFields{Field: field, Lat: lat, Lon: lon}, nil
+}
+
+func (g *GeoPointFields) UnmarshalText(text []byte) error {
+	fields, err := ParseGeoPointFields(string(text))
+	if err != nil {
+		return err
+	}
+	*g = fields
+	return nil
+}
+
+func (g GeoPointFields) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+func (g GeoPointFields) String() string {
+	return fmt.Sprintf("%s=%s:%s", g.Field, g.Lat, g.Lon)
+}
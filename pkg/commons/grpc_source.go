@@ -0,0 +1,117 @@
+package commons
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GrpcSourceSpec describes how to read points from a gRPC service with reflection: the RPC to call and its request,
+// how to get to the next page of a unary RPC, and where the items and their ID, vectors and payload are in a response.
+type GrpcSourceSpec struct {
+	Path string `yaml:"-"`
+
+	// Method is the full name of the RPC, e.g. vectors.v1.VectorStore/ListVectors.
+	Method string `yaml:"method"`
+	// Request is the JSON of the request message, with the placeholders {limit}, {offset}, {page} and {cursor}.
+	Request string `yaml:"request"`
+	// Metadata of every call, e.g. for authentication. Environment variables like ${API_TOKEN} are expanded.
+	Metadata map[string]string `yaml:"metadata"`
+	// Auth gets access tokens that expire during the run, and refreshes them, instead of a fixed one in Metadata.
+	Auth       *TokenSpec     `yaml:"auth"`
+	Pagination RestPagination `yaml:"pagination"`
+
+	// JSONPaths of the items in a response, and of the ID, vectors and payload of an item, by the field names of the proto.
+	Items   string                `yaml:"items"`
+	ID      string                `yaml:"id"`
+	Vectors map[string]RestVector `yaml:"vectors"`
+	Payload string                `yaml:"payload"`
+}
+
+func LoadGrpcSourceSpec(path string) (GrpcSourceSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return GrpcSourceSpec{}, fmt.Errorf("failed to read gRPC spec: %w", err)
+	}
+
+	var spec GrpcSourceSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return GrpcSourceSpec{}, fmt.Errorf("failed to parse gRPC spec %s: %w", path, err)
+	}
+	spec.Path = path
+
+	if spec.Method == "" || spec.ID == "" {
+		return GrpcSourceSpec{}, fmt.Errorf("gRPC spec %s requires method and id", path)
+	}
+	spec.Method = strings.TrimPrefix(spec.Method, "/")
+	service, method, ok := strings.Cut(spec.Method, "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return GrpcSourceSpec{}, fmt.Errorf("invalid method %q in gRPC spec %s, expected package.Service/Method", spec.Method, path)
+	}
+	if spec.Items == "" {
+		spec.Items = "$"
+	}
+	if spec.Request == "" {
+		spec.Request = "{}"
+	}
+
+	switch spec.Pagination.Style {
+	case "":
+		spec.Pagination.Style = "none"
+	case "offset", "page", "none":
+	case "cursor":
+		if spec.Pagination.Cursor == "" {
+			return GrpcSourceSpec{}, fmt.Errorf("cursor pagination in gRPC spec %s requires the JSONPath of the cursor", path)
+		}
+	default:
+		return GrpcSourceSpec{}, fmt.Errorf("invalid pagination style %q in gRPC spec %s, expected offset, page, cursor or none", spec.Pagination.Style, path)
+	}
+
+	for name, vector := range spec.Vectors {
+		if vector.Path == "" {
+			return GrpcSourceSpec{}, fmt.Errorf("vector '%s' in gRPC spec %s has no path", name, path)
+		}
+		switch vector.Distance {
+		case "":
+			vector.Distance = "cosine"
+		case "cosine", "dot", "euclid", "manhattan":
+		default:
+			return GrpcSourceSpec{}, fmt.Errorf("invalid distance %q of vector '%s' in gRPC spec %s, expected cosine, dot, euclid or manhattan", vector.Distance, name, path)
+		}
+		spec.Vectors[name] = vector
+	}
+
+	for name, value := range spec.Metadata {
+		spec.Metadata[name] = os.ExpandEnv(value)
+	}
+	if spec.Auth != nil {
+		if err := spec.Auth.load(path); err != nil {
+			return GrpcSourceSpec{}, err
+		}
+	}
+
+	return spec, nil
+}
+
+func (s *GrpcSourceSpec) UnmarshalText(text []byte) error {
+	spec, err := LoadGrpcSourceSpec(string(text))
+	if err != nil {
+		return err
+	}
+	*s = spec
+	return nil
+}
+
+// MarshalText returns the path of the spec only, so metadata with credentials doesn't end up in reports.
+func (s GrpcSourceSpec) MarshalText() ([]byte, error) {
+	return []byte(s.Path), nil
+}
+
+func (s GrpcSourceSpec) String() string {
+	return s.Path
+}
@@ -0,0 +1,50 @@
+package commons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGrpcSourceSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    GrpcSourceSpec
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			spec: "method: /vectors.v1.Store/Stream\nid: $.id\n",
+			want: GrpcSourceSpec{Method: "vectors.v1.Store/Stream", Request: "{}", Items: "$", ID: "$.id", Pagination: RestPagination{Style: "none"}},
+		},
+		{name: "missing method", spec: "id: $.id\n", wantErr: true},
+		{name: "method without service", spec: "method: List\nid: $.id\n", wantErr: true},
+		{name: "link pagination", spec: "method: a.B/List\nid: $.id\npagination:\n  style: link\n  next: $.next\n", wantErr: true},
+		{name: "cursor without path", spec: "method: a.B/List\nid: $.id\npagination:\n  style: cursor\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spec.yaml")
+			if err := os.WriteFile(path, []byte(tt.spec), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			spec, err := LoadGrpcSourceSpec(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadGrpcSourceSpec() = %+v, want an error", spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadGrpcSourceSpec() error = %v", err)
+			}
+			tt.want.Path = path
+			if spec.Method != tt.want.Method || spec.Request != tt.want.Request || spec.Items != tt.want.Items ||
+				spec.ID != tt.want.ID || spec.Pagination != tt.want.Pagination || spec.Path != tt.want.Path {
+				t.Errorf("LoadGrpcSourceSpec() = %+v, want %+v", spec, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package commons
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PayloadMapping holds directives for individual payload fields, read from a YAML or JSON mapping file.
+// It's parsed from the path of the file, so the file is validated together with the other flags.
+type PayloadMapping struct {
+	Path   string
+	Fields map[string]FieldMapping
+}
+
+// FieldMapping holds the directives for one payload field. Nested fields are addressed by their path, e.g. "meta.created".
+type FieldMapping struct {
+	// Datetime converts the field into an RFC 3339 timestamp in UTC. It's either "auto", the unit of epoch
+	// timestamps ("seconds", "millis", "micros", "nanos"), or a Go time layout like "2006-01-02 15:04:05".
+	Datetime string `yaml:"datetime"`
+	// Timezone of timestamps without a UTC offset, e.g. "Europe/Berlin". Defaults to UTC.
+	Timezone string `yaml:"timezone"`
+	// Binary handles binary values, like PostgreSQL bytea or Mongo binary data: "skip" drops them, "base64" stores them
+	// as base64 strings, and "offload" uploads them to --migration.blob-store and stores their URL.
+	Binary string `yaml:"binary"`
+	// Type coerces the values of the field, or the items of a list, into "string", "integer", "float" or "bool",
+	// so a field the source stores with mixed types can be indexed. Values that can't be converted are left as they are.
+	Type string `yaml:"type"`
+	// Rename gives the field a new key, at the same level of the payload, e.g. to remove characters that are hard to filter on.
+	Rename string `yaml:"rename"`
+
+	location *time.Location
+}
+
+// Location returns the time zone of timestamps without a UTC offset.
+func (f FieldMapping) Location() *time.Location {
+	if f.location == nil {
+		return time.UTC
+	}
+	return f.location
+}
+
+func LoadPayloadMapping(path string) (PayloadMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PayloadMapping{}, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+
+	var file struct {
+		Fields map[string]FieldMapping `yaml:"fields"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return PayloadMapping{}, fmt.Errorf("failed to parse mapping file %s: %w", path, err)
+	}
+
+	for name, field := range file.Fields {
+		switch field.Datetime {
+		case "", "auto", "seconds", "millis", "micros", "nanos":
+		default:
+			// A layout without any element of the reference time would never parse a timestamp.
+			if time.Unix(0, 0).Format(field.Datetime) == field.Datetime {
+				return PayloadMapping{}, fmt.Errorf("invalid datetime directive of field '%s' in mapping file: %q is neither auto, an epoch unit nor a time layout", name, field.Datetime)
+			}
+		}
+		switch field.Binary {
+		case "", "skip", "base64", "offload":
+		default:
+			return PayloadMapping{}, fmt.Errorf("invalid binary directive of field '%s' in mapping file: %q is neither skip, base64 nor offload", name, field.Binary)
+		}
+		switch field.Type {
+		case "", "string", "integer", "float", "bool":
+		default:
+			return PayloadMapping{}, fmt.Errorf("invalid type directive of field '%s' in mapping file: %q is neither string, integer, float nor bool", name, field.Type)
+		}
+		if strings.ContainsAny(field.Rename, ".[]") {
+			return PayloadMapping{}, fmt.Errorf("invalid rename directive of field '%s' in mapping file: %q is a path, not a key", name, field.Rename)
+		}
+		if field.Timezone != "" {
+			field.location, err = time.LoadLocation(field.Timezone)
+			if err != nil {
+				return PayloadMapping{}, fmt.Errorf("invalid time zone of field '%s' in mapping file: %w", name, err)
+			}
+		}
+		file.Fields[name] = field
+	}
+
+	return PayloadMapping{Path: path, Fields: file.Fields}, nil
+}
+
+func (m *PayloadMapping) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*m = PayloadMapping{}
+		return nil
+	}
+	mapping, err := LoadPayloadMapping(string(text))
+	if err != nil {
+		return err
+	}
+	*m = mapping
+	return nil
+}
+
+func (m PayloadMapping) MarshalText() ([]byte, error) {
+	return []byte(m.Path), nil
+}
+
+func (m PayloadMapping) String() string {
+	return m.Path
+}
@@ -3,6 +3,8 @@ package commons
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,7 +12,95 @@ import (
 	"github.com/qdrant/go-client/qdrant"
 )
 
+// Checkpoint is the position a migration stream has reached, as stored in the offsets collection.
+type Checkpoint struct {
+	Key    string
+	Offset *qdrant.PointId
+	Count  uint64
+}
+
+var (
+	checkpointsLock   sync.Mutex
+	checkpoints       = make(map[string]Checkpoint)
+	resumeCheckpoints = make(map[string]Checkpoint)
+	offsetKeyScope    string
+)
+
+// SetOffsetKeyScope sets a suffix of every offset key of the run, so runs that read a part of the source,
+// e.g. some of its tenants, keep checkpoints of their own instead of moving the ones of other runs.
+func SetOffsetKeyScope(scope string) {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	offsetKeyScope = scope
+}
+
+// OffsetKey returns the key the offset of a stream is stored under, with the scope of the run.
+func OffsetKey(key string) string {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	return key + offsetKeyScope
+}
+
+// Checkpoints returns the last position of every stream read or stored during this run, ordered by key.
+func Checkpoints() []Checkpoint {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	result := make([]Checkpoint, 0, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		result = append(result, checkpoint)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// SetResumeCheckpoints makes GetStartOffset return the given positions instead of the stored ones.
+func SetResumeCheckpoints(resume []Checkpoint) {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	for _, checkpoint := range resume {
+		resumeCheckpoints[checkpoint.Key] = checkpoint
+	}
+}
+
+// GetResumeOffset returns the position of a stream given with SetResumeCheckpoints, for streams whose offsets
+// aren't stored in a Qdrant offsets collection.
+func GetResumeOffset(key string) (*qdrant.PointId, uint64, bool) {
+	key = OffsetKey(key)
+	checkpointsLock.Lock()
+	resume, ok := resumeCheckpoints[key]
+	checkpointsLock.Unlock()
+	if !ok {
+		return nil, 0, false
+	}
+	recordCheckpoint(key, resume.Offset, resume.Count)
+	return resume.Offset, resume.Count, true
+}
+
+// RecordCheckpoint records the position of a stream whose offsets aren't stored in a Qdrant offsets collection,
+// for the report and the resume token of the run.
+func RecordCheckpoint(key string, offset *qdrant.PointId, offsetCount uint64) {
+	recordCheckpoint(OffsetKey(key), offset, offsetCount)
+}
+
+func recordCheckpoint(key string, offset *qdrant.PointId, offsetCount uint64) {
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+
+	checkpoints[key] = Checkpoint{Key: key, Offset: offset, Count: offsetCount}
+}
+
+// PrepareOffsetsCollection creates the offsets collection in the target, unless it exists.
+// Without a target client, e.g. when points are written to a sink, offsets are only kept for the run.
 func PrepareOffsetsCollection(ctx context.Context, migrationOffsetsCollectionName string, targetClient *qdrant.Client) error {
+	if targetClient == nil {
+		return nil
+	}
 	migrationOffsetCollectionExists, err := targetClient.CollectionExists(ctx, migrationOffsetsCollectionName)
 	if err != nil {
 		return fmt.Errorf("failed to check if collection exists: %w", err)
@@ -25,6 +115,30 @@ func PrepareOffsetsCollection(ctx context.Context, migrationOffsetsCollectionNam
 }
 
 func GetStartOffset(ctx context.Context, migrationOffsetsCollectionName string, targetClient *qdrant.Client, sourceCollection string) (*qdrant.PointId, uint64, error) {
+	sourceCollection = OffsetKey(sourceCollection)
+	checkpointsLock.Lock()
+	resume, ok := resumeCheckpoints[sourceCollection]
+	checkpointsLock.Unlock()
+	if ok {
+		recordCheckpoint(sourceCollection, resume.Offset, resume.Count)
+		return resume.Offset, resume.Count, nil
+	}
+
+	if targetClient == nil {
+		return nil, 0, nil
+	}
+
+	offset, offsetCount, err := getStoredStartOffset(ctx, migrationOffsetsCollectionName, targetClient, sourceCollection)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset != nil {
+		recordCheckpoint(sourceCollection, offset, offsetCount)
+	}
+	return offset, offsetCount, nil
+}
+
+func getStoredStartOffset(ctx context.Context, migrationOffsetsCollectionName string, targetClient *qdrant.Client, sourceCollection string) (*qdrant.PointId, uint64, error) {
 	point, err := getOffsetPoint(ctx, migrationOffsetsCollectionName, targetClient, sourceCollection)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get start offset point: %w", err)
@@ -74,6 +188,11 @@ func StoreStartOffset(ctx context.Context, migrationOffsetsCollectionName string
 	if offset == nil {
 		return nil
 	}
+	sourceCollection = OffsetKey(sourceCollection)
+	if targetClient == nil {
+		recordCheckpoint(sourceCollection, offset, offsetCount)
+		return nil
+	}
 	offsetId, err := getOffsetIdAsValue(offset)
 	if err != nil {
 		return err
@@ -99,6 +218,28 @@ func StoreStartOffset(ctx context.Context, migrationOffsetsCollectionName string
 	if err != nil {
 		return fmt.Errorf("failed to store offset: %w", err)
 	}
+
+	recordCheckpoint(sourceCollection, offset, offsetCount)
+	return nil
+}
+
+// DeleteStartOffset removes the stored offset of a stream, for streams that can't be resumed once they were read to the end,
+// so the next run starts from the beginning.
+func DeleteStartOffset(ctx context.Context, migrationOffsetsCollectionName string, targetClient *qdrant.Client, sourceCollection string) error {
+	sourceCollection = OffsetKey(sourceCollection)
+	if targetClient != nil {
+		_, err := targetClient.Delete(ctx, &qdrant.DeletePoints{
+			CollectionName: migrationOffsetsCollectionName,
+			Points:         qdrant.NewPointsSelector(getOffsetPointId(sourceCollection)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete offset: %w", err)
+		}
+	}
+
+	checkpointsLock.Lock()
+	defer checkpointsLock.Unlock()
+	delete(checkpoints, sourceCollection)
 	return nil
 }
 
@@ -0,0 +1,68 @@
+package commons
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// payloadTemplateFuncs are the functions templates of added payload fields can call.
+var payloadTemplateFuncs = template.FuncMap{
+	"now": func() string { return time.Now().UTC().Format(time.RFC3339) },
+	"env": os.Getenv,
+}
+
+// PayloadTemplate is a payload field added to every migrated point, e.g. to stamp provenance onto it.
+// It's parsed from flags like "source=pinecone" or "migrated_at={{now}}". The value is a Go template
+// that can call now and env, and use the ID of the point as {{.id}}.
+type PayloadTemplate struct {
+	Field string
+	Value string
+
+	template *template.Template
+}
+
+func ParsePayloadTemplate(s string) (PayloadTemplate, error) {
+	field, value, ok := strings.Cut(s, "=")
+	field = strings.TrimSpace(field)
+	if !ok || field == "" {
+		return PayloadTemplate{}, fmt.Errorf("invalid payload field %q, expected e.g. source=pinecone or migrated_at={{now}}", s)
+	}
+	parsed, err := template.New(field).Funcs(payloadTemplateFuncs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return PayloadTemplate{}, fmt.Errorf("invalid template of payload field '%s': %w", field, err)
+	}
+	return PayloadTemplate{Field: field, Value: value, template: parsed}, nil
+}
+
+// Render returns the value of the field for the point with the given ID.
+func (p PayloadTemplate) Render(id string) (string, error) {
+	if p.template == nil {
+		return p.Value, nil
+	}
+	var value strings.Builder
+	err := p.template.Execute(&value, map[string]any{"id": id})
+	if err != nil {
+		return "", fmt.Errorf("failed to render payload field '%s': %w", p.Field, err)
+	}
+	return value.String(), nil
+}
+
+func (p *PayloadTemplate) UnmarshalText(text []byte) error {
+	parsed, err := ParsePayloadTemplate(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+func (p PayloadTemplate) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p PayloadTemplate) String() string {
+	return p.Field + "=" + p.Value
+}
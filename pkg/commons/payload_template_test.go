@@ -0,0 +1,70 @@
+package commons
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePayloadTemplate(t *testing.T) {
+	t.Setenv("PAYLOAD_TEMPLATE_TEST", "ci")
+
+	tests := []struct {
+		input     string
+		wantField string
+		want      string
+		wantErr   bool
+	}{
+		{input: "source=pinecone", wantField: "source", want: "pinecone"},
+		{input: "note=a=b, c", wantField: "note", want: "a=b, c"},
+		{input: "empty=", wantField: "empty", want: ""},
+		{input: "origin={{.id}}", wantField: "origin", want: "42"},
+		{input: "runner={{env \"PAYLOAD_TEMPLATE_TEST\"}}", wantField: "runner", want: "ci"},
+		{input: "source", wantErr: true},
+		{input: "=pinecone", wantErr: true},
+		{input: "broken={{now", wantErr: true},
+		{input: "unknown={{later}}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePayloadTemplate(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParsePayloadTemplate(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePayloadTemplate(%q) error = %v", tt.input, err)
+			}
+			value, err := got.Render("42")
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got.Field != tt.wantField || value != tt.want {
+				t.Errorf("ParsePayloadTemplate(%q) renders %s=%s, want %s=%s", tt.input, got.Field, value, tt.wantField, tt.want)
+			}
+			if got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
+
+func TestPayloadTemplateNow(t *testing.T) {
+	field, err := ParsePayloadTemplate("migrated_at={{now}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := field.Render("1")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	stamped, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("Render() = %q, want an RFC 3339 timestamp: %v", value, err)
+	}
+	if time.Since(stamped) > time.Minute {
+		t.Errorf("Render() = %q, want the current time", value)
+	}
+}
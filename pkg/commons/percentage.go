@@ -0,0 +1,32 @@
+package commons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Percentage is a fraction between 0 and 1 that can be parsed from flags like "1%" or "0.5%".
+type Percentage float64
+
+func ParsePercentage(s string) (Percentage, error) {
+	value := strings.TrimSpace(s)
+	number, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, "%")), 64)
+	if err != nil || !strings.HasSuffix(value, "%") || number < 0 || number > 100 {
+		return 0, fmt.Errorf("invalid percentage %q, expected e.g. 1%%", s)
+	}
+	return Percentage(number / 100), nil
+}
+
+func (p *Percentage) UnmarshalText(text []byte) error {
+	percentage, err := ParsePercentage(string(text))
+	if err != nil {
+		return err
+	}
+	*p = percentage
+	return nil
+}
+
+func (p Percentage) String() string {
+	return strconv.FormatFloat(float64(p)*100, 'f', -1, 64) + "%"
+}
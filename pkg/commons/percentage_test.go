@@ -0,0 +1,38 @@
+package commons
+
+import "testing"
+
+func TestParsePercentage(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Percentage
+		wantErr bool
+	}{
+		{input: "1%", want: 0.01},
+		{input: "0.5%", want: 0.005},
+		{input: " 100 % ", want: 1},
+		{input: "0%", want: 0},
+		{input: "1", wantErr: true},
+		{input: "101%", wantErr: true},
+		{input: "-1%", wantErr: true},
+		{input: "abc%", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePercentage(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParsePercentage(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePercentage(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePercentage(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
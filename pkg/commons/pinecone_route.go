@@ -0,0 +1,47 @@
+package commons
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllNamespaces routes the namespaces of a Pinecone index that have no route of their own.
+const AllNamespaces = "*"
+
+// PineconeRoute is where the vectors of a Pinecone namespace are migrated to: a target collection,
+// a tag stored in their payloads, or both. It's parsed from flags like "products=catalog:products",
+// with an empty collection for --qdrant.collection and no tag after the colon, which collection names can't contain.
+type PineconeRoute struct {
+	Namespace  string
+	Collection string
+	Tag        string
+}
+
+func ParsePineconeRoute(s string) (PineconeRoute, error) {
+	namespace, target, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return PineconeRoute{}, fmt.Errorf("invalid route %q, expected e.g. namespace=collection, namespace=collection:tag or namespace=:tag", s)
+	}
+	collection, tag, _ := strings.Cut(target, ":")
+	return PineconeRoute{Namespace: namespace, Collection: collection, Tag: tag}, nil
+}
+
+func (p *PineconeRoute) UnmarshalText(text []byte) error {
+	route, err := ParsePineconeRoute(string(text))
+	if err != nil {
+		return err
+	}
+	*p = route
+	return nil
+}
+
+func (p PineconeRoute) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p PineconeRoute) String() string {
+	if p.Tag == "" {
+		return p.Namespace + "=" + p.Collection
+	}
+	return p.Namespace + "=" + p.Collection + ":" + p.Tag
+}
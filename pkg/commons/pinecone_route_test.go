@@ -0,0 +1,39 @@
+package commons
+
+import "testing"
+
+func TestParsePineconeRoute(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    PineconeRoute
+		wantErr bool
+	}{
+		{input: "products=catalog", want: PineconeRoute{Namespace: "products", Collection: "catalog"}},
+		{input: "products=catalog:products", want: PineconeRoute{Namespace: "products", Collection: "catalog", Tag: "products"}},
+		{input: "products=:products", want: PineconeRoute{Namespace: "products", Tag: "products"}},
+		{input: "*=archive", want: PineconeRoute{Namespace: AllNamespaces, Collection: "archive"}},
+		{input: "=catalog", want: PineconeRoute{Collection: "catalog"}},
+		{input: "products", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParsePineconeRoute(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParsePineconeRoute(%q) = %v, want an error", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePineconeRoute(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePineconeRoute(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+			if got.String() != tt.input {
+				t.Errorf("String() = %q, want %q", got.String(), tt.input)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package commons
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RestSpec describes how to read points from a REST API: the request of a page, how to get to the next page,
+// and where the items and their ID, vectors and payload are in the response. It's read from a YAML file.
+type RestSpec struct {
+	Path string `yaml:"-"`
+
+	// URL of a page, with the placeholders {limit}, {offset}, {page} and {cursor}.
+	URL    string `yaml:"url"`
+	Method string `yaml:"method"`
+	// Headers of every request, e.g. for authentication. Environment variables like ${API_TOKEN} are expanded.
+	Headers map[string]string `yaml:"headers"`
+	// Auth gets access tokens that expire during the run, and refreshes them, instead of a fixed one in Headers.
+	Auth *TokenSpec `yaml:"auth"`
+	// Body of every request, with the same placeholders as the URL.
+	Body       string         `yaml:"body"`
+	Pagination RestPagination `yaml:"pagination"`
+
+	// JSONPaths of the items in a response, of the total number of items, and of the ID, vectors and payload of an item.
+	Items   string                `yaml:"items"`
+	Total   string                `yaml:"total"`
+	ID      string                `yaml:"id"`
+	Vectors map[string]RestVector `yaml:"vectors"`
+	Payload string                `yaml:"payload"`
+}
+
+// RestPagination is how the pages of a REST API are requested.
+type RestPagination struct {
+	// Style is "offset" or "page" to count items or pages, "cursor" to pass on a cursor of the response,
+	// "link" to follow a URL of the response, or "none" for a single request.
+	Style string `yaml:"style"`
+	// Start is the first offset or page. Defaults to 0.
+	Start int `yaml:"start"`
+	// Cursor is the JSONPath of the cursor of the next page, for the cursor style.
+	Cursor string `yaml:"cursor"`
+	// Next is the JSONPath of the URL of the next page, for the link style.
+	Next string `yaml:"next"`
+}
+
+// RestVector is where a named vector is in an item, and the distance to create it with.
+type RestVector struct {
+	Path     string `yaml:"path"`
+	Distance string `yaml:"distance"`
+}
+
+func LoadRestSpec(path string) (RestSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RestSpec{}, fmt.Errorf("failed to read REST spec: %w", err)
+	}
+
+	var spec RestSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return RestSpec{}, fmt.Errorf("failed to parse REST spec %s: %w", path, err)
+	}
+	spec.Path = path
+
+	if spec.URL == "" || spec.Items == "" || spec.ID == "" {
+		return RestSpec{}, fmt.Errorf("REST spec %s requires url, items and id", path)
+	}
+	spec.Method = strings.ToUpper(spec.Method)
+	switch spec.Method {
+	case "":
+		spec.Method = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		return RestSpec{}, fmt.Errorf("invalid method %q in REST spec %s, expected GET or POST", spec.Method, path)
+	}
+
+	switch spec.Pagination.Style {
+	case "offset", "page", "none":
+	case "cursor":
+		if spec.Pagination.Cursor == "" {
+			return RestSpec{}, fmt.Errorf("cursor pagination in REST spec %s requires the JSONPath of the cursor", path)
+		}
+	case "link":
+		if spec.Pagination.Next == "" {
+			return RestSpec{}, fmt.Errorf("link pagination in REST spec %s requires the JSONPath of the next URL", path)
+		}
+	default:
+		return RestSpec{}, fmt.Errorf("invalid pagination style %q in REST spec %s, expected offset, page, cursor, link or none", spec.Pagination.Style, path)
+	}
+
+	for name, vector := range spec.Vectors {
+		if vector.Path == "" {
+			return RestSpec{}, fmt.Errorf("vector '%s' in REST spec %s has no path", name, path)
+		}
+		switch vector.Distance {
+		case "":
+			vector.Distance = "cosine"
+		case "cosine", "dot", "euclid", "manhattan":
+		default:
+			return RestSpec{}, fmt.Errorf("invalid distance %q of vector '%s' in REST spec %s, expected cosine, dot, euclid or manhattan", vector.Distance, name, path)
+		}
+		spec.Vectors[name] = vector
+	}
+
+	for name, value := range spec.Headers {
+		spec.Headers[name] = os.ExpandEnv(value)
+	}
+	if spec.Auth != nil {
+		if err := spec.Auth.load(path); err != nil {
+			return RestSpec{}, err
+		}
+	}
+
+	return spec, nil
+}
+
+func (s *RestSpec) UnmarshalText(text []byte) error {
+	spec, err := LoadRestSpec(string(text))
+	if err != nil {
+		return err
+	}
+	*s = spec
+	return nil
+}
+
+// MarshalText returns the path of the spec only, so headers with credentials don't end up in reports.
+func (s RestSpec) MarshalText() ([]byte, error) {
+	return []byte(s.Path), nil
+}
+
+func (s RestSpec) String() string {
+	return s.Path
+}
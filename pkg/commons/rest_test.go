@@ -0,0 +1,107 @@
+package commons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRestSpec(t *testing.T) {
+	t.Setenv("REST_TEST_TOKEN", "secret")
+
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+		check   func(t *testing.T, spec RestSpec)
+	}{
+		{
+			name: "defaults",
+			spec: `
+url: https://api.example.com/items?offset={offset}
+headers:
+  Authorization: Bearer ${REST_TEST_TOKEN}
+pagination:
+  style: offset
+items: $.items
+id: $.id
+vectors:
+  default:
+    path: $.embedding
+`,
+			check: func(t *testing.T, spec RestSpec) {
+				if spec.Method != "GET" {
+					t.Errorf("Method = %q, want GET", spec.Method)
+				}
+				if spec.Headers["Authorization"] != "Bearer secret" {
+					t.Errorf("Authorization = %q, want the token expanded", spec.Headers["Authorization"])
+				}
+				if spec.Vectors["default"].Distance != "cosine" {
+					t.Errorf("Distance = %q, want cosine", spec.Vectors["default"].Distance)
+				}
+			},
+		},
+		{name: "missing id", spec: "url: https://api.example.com\nitems: $.items\npagination:\n  style: none\n", wantErr: true},
+		{name: "invalid method", spec: "url: https://api.example.com\nmethod: put\nitems: $\nid: $.id\npagination:\n  style: none\n", wantErr: true},
+		{name: "cursor without path", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: cursor\n", wantErr: true},
+		{name: "unknown style", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: scroll\n", wantErr: true},
+		{name: "unknown field", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nlimit: 10\n", wantErr: true},
+		{name: "invalid distance", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nvectors:\n  v:\n    path: $.v\n    distance: hamming\n", wantErr: true},
+		{
+			name: "client credentials",
+			spec: `
+url: https://api.example.com/items
+items: $.items
+id: $.id
+pagination:
+  style: none
+auth:
+  token_url: https://login.example.com/token
+  client_id: migration
+  client_secret: ${REST_TEST_TOKEN}
+  scopes: [api]
+`,
+			check: func(t *testing.T, spec RestSpec) {
+				if spec.Auth.ClientSecret != "secret" {
+					t.Errorf("ClientSecret = %q, want the secret expanded", spec.Auth.ClientSecret)
+				}
+				if spec.Auth.Header != "Authorization" || spec.Auth.Prefix != "Bearer " {
+					t.Errorf("Header = %q, Prefix = %q, want Authorization and Bearer", spec.Auth.Header, spec.Auth.Prefix)
+				}
+			},
+		},
+		{name: "auth without token source", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nauth:\n  header: X-Token\n", wantErr: true},
+		{name: "auth with two token sources", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nauth:\n  token_url: https://login.example.com/token\n  client_id: migration\n  command: gcloud auth print-access-token\n", wantErr: true},
+		{
+			name: "Google credentials",
+			spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nauth:\n  google: true\n",
+			check: func(t *testing.T, spec RestSpec) {
+				if len(spec.Auth.Scopes) != 1 || spec.Auth.Scopes[0] != GoogleCloudPlatformScope {
+					t.Errorf("Scopes = %v, want the cloud-platform scope", spec.Auth.Scopes)
+				}
+			},
+		},
+		{name: "Google credentials and a command", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nauth:\n  google: true\n  command: gcloud auth print-access-token\n", wantErr: true},
+		{name: "token URL without client", spec: "url: https://api.example.com\nitems: $\nid: $.id\npagination:\n  style: none\nauth:\n  token_url: https://login.example.com/token\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spec.yaml")
+			if err := os.WriteFile(path, []byte(tt.spec), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			spec, err := LoadRestSpec(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadRestSpec() = %+v, want an error", spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRestSpec() error = %v", err)
+			}
+			tt.check(t, spec)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package commons
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily window of local time that can be parsed from flags like "22:00-06:00".
+// Windows that end before they start span midnight.
+type TimeWindow struct {
+	// Minutes since midnight.
+	Start int
+	End   int
+	Set   bool
+}
+
+func ParseTimeWindow(s string) (TimeWindow, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q, expected e.g. 22:00-06:00", s)
+	}
+
+	start, err := parseTimeOfDay(from)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	end, err := parseTimeOfDay(to)
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if start == end {
+		return TimeWindow{}, fmt.Errorf("invalid time window %q: it must not start and end at the same time", s)
+	}
+
+	return TimeWindow{Start: start, End: end, Set: true}, nil
+}
+
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w *TimeWindow) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*w = TimeWindow{}
+		return nil
+	}
+	window, err := ParseTimeWindow(string(text))
+	if err != nil {
+		return err
+	}
+	*w = window
+	return nil
+}
+
+func (w TimeWindow) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+func (w TimeWindow) String() string {
+	if !w.Set {
+		return ""
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+}
+
+// Contains reports whether t is within the window.
+func (w TimeWindow) Contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// NextChange returns the next time after t at which the window opens or closes.
+func (w TimeWindow) NextChange(t time.Time) time.Time {
+	next := w.End
+	if !w.Contains(t) {
+		next = w.Start
+	}
+
+	change := time.Date(t.Year(), t.Month(), t.Day(), next/60, next%60, 0, 0, t.Location())
+	if !change.After(t) {
+		change = time.Date(t.Year(), t.Month(), t.Day()+1, next/60, next%60, 0, 0, t.Location())
+	}
+	return change
+}
@@ -0,0 +1,55 @@
+package commons
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindow(t *testing.T) {
+	overnight, err := ParseTimeWindow("22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	daytime, err := ParseTimeWindow("09:30-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2025, 1, 3, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		window     TimeWindow
+		now        time.Time
+		contains   bool
+		nextChange time.Time
+	}{
+		{"overnight before start", overnight, at(21, 59), false, at(22, 0)},
+		{"overnight at start", overnight, at(22, 0), true, time.Date(2025, 1, 4, 6, 0, 0, 0, time.UTC)},
+		{"overnight after midnight", overnight, at(3, 0), true, at(6, 0)},
+		{"overnight at end", overnight, at(6, 0), false, at(22, 0)},
+		{"daytime inside", daytime, at(12, 0), true, at(17, 0)},
+		{"daytime after end", daytime, at(18, 0), false, time.Date(2025, 1, 4, 9, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Contains(tt.now); got != tt.contains {
+				t.Errorf("Contains() = %v, want %v", got, tt.contains)
+			}
+			if got := tt.window.NextChange(tt.now); !got.Equal(tt.nextChange) {
+				t.Errorf("NextChange() = %v, want %v", got, tt.nextChange)
+			}
+		})
+	}
+}
+
+func TestParseTimeWindowInvalid(t *testing.T) {
+	for _, s := range []string{"", "22:00", "22:00-22:00", "24:00-06:00", "10-12"} {
+		if _, err := ParseTimeWindow(s); err == nil {
+			t.Errorf("ParseTimeWindow(%q) succeeded, expected an error", s)
+		}
+	}
+}
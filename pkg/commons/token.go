@@ -0,0 +1,64 @@
+package commons
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TokenSpec is how to get the access tokens of an API that expire during a run, e.g. of Azure AD, GCP or Databricks.
+// Tokens are got with the OAuth2 client credentials flow of TokenURL, printed by Command, or got with the
+// Application Default Credentials of Google Cloud.
+type TokenSpec struct {
+	// TokenURL, ClientID, ClientSecret and Scopes are of an OAuth2 client, e.g. a service principal.
+	TokenURL     string   `yaml:"token_url"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`
+	// Command prints an access token, e.g. gcloud auth print-access-token. It's run with sh.
+	Command string `yaml:"command"`
+	// Google gets tokens with the Application Default Credentials of Google Cloud, for Scopes: the key file of
+	// GOOGLE_APPLICATION_CREDENTIALS, the login of gcloud auth application-default login, or the service account
+	// of the workload identity of a GKE pod or of the VM.
+	Google bool `yaml:"google"`
+	// Header the token is sent in, Authorization by default, after Prefix, "Bearer " by default for Authorization.
+	Header string `yaml:"header"`
+	Prefix string `yaml:"prefix"`
+}
+
+// GoogleCloudPlatformScope is the OAuth2 scope of all Google Cloud APIs, which Application Default Credentials are
+// requested for unless other scopes are given.
+const GoogleCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// load expands the environment variables in the settings of a token, like ${CLIENT_SECRET}, and checks them.
+func (t *TokenSpec) load(path string) error {
+	t.TokenURL = os.ExpandEnv(t.TokenURL)
+	t.ClientID = os.ExpandEnv(t.ClientID)
+	t.ClientSecret = os.ExpandEnv(t.ClientSecret)
+
+	sources := 0
+	for _, set := range []bool{t.TokenURL != "", t.Command != "", t.Google} {
+		if set {
+			sources++
+		}
+	}
+	switch {
+	case sources > 1:
+		return fmt.Errorf("auth in %s has more than one of token_url, command and google, expected one of them", path)
+	case sources == 0:
+		return fmt.Errorf("auth in %s requires token_url, command or google", path)
+	case t.TokenURL != "" && t.ClientID == "":
+		return fmt.Errorf("auth in %s requires the client_id of token_url", path)
+	}
+	if t.Google && len(t.Scopes) == 0 {
+		t.Scopes = []string{GoogleCloudPlatformScope}
+	}
+
+	if t.Header == "" {
+		t.Header = "Authorization"
+	}
+	if t.Prefix == "" && http.CanonicalHeaderKey(t.Header) == "Authorization" {
+		t.Prefix = "Bearer "
+	}
+	return nil
+}